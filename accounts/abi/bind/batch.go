@@ -0,0 +1,232 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bind
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Multicall3Address is the address of the Multicall3 contract, deployed at the
+// same address on most EVM chains.
+var Multicall3Address = common.HexToAddress("0xcA11bde05977b3631167028862bE2a173976CA11")
+
+// multicall3ABI is the subset of the Multicall3 ABI needed for batching reads.
+const multicall3ABI = `[{"inputs":[{"components":[{"internalType":"address","name":"target","type":"address"},{"internalType":"bool","name":"allowFailure","type":"bool"},{"internalType":"bytes","name":"callData","type":"bytes"}],"internalType":"struct Multicall3.Call3[]","name":"calls","type":"tuple[]"}],"name":"aggregate3","outputs":[{"components":[{"internalType":"bool","name":"success","type":"bool"},{"internalType":"bytes","name":"returnData","type":"bytes"}],"internalType":"struct Multicall3.Result[]","name":"returnData","type":"tuple[]"}],"stateMutability":"payable","type":"function"}]`
+
+var multicall3 = func() abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(multicall3ABI))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}()
+
+// multicall3Call is a single call of a Multicall3 aggregate3 invocation.
+type multicall3Call struct {
+	Target       common.Address
+	AllowFailure bool
+	CallData     []byte
+}
+
+// multicall3Result is the outcome of a single call of a Multicall3 aggregate3
+// invocation.
+type multicall3Result struct {
+	Success    bool
+	ReturnData []byte
+}
+
+// BatchCaller is implemented by clients able to send JSON-RPC batch requests,
+// such as rpc.Client.
+type BatchCaller interface {
+	BatchCallContext(ctx context.Context, b []rpc.BatchElem) error
+}
+
+// BatchCall is a contract read queued in a Batch. Its outcome is available after
+// the batch has been executed.
+type BatchCall struct {
+	contract *BoundContract
+	input    []byte
+	unpack   func(output []byte) error
+	err      error
+	done     bool
+}
+
+// Err returns the failure of the call, if any. Calls which revert return the
+// same typed errors as BoundContract.Call.
+func (c *BatchCall) Err() error {
+	if !c.done {
+		return errors.New("batch not executed")
+	}
+	return c.err
+}
+
+// deliver unpacks the output of the call, or records its failure.
+func (c *BatchCall) deliver(output []byte, err error) {
+	c.done = true
+	if err != nil {
+		c.err = c.contract.unpackRevert(err)
+		return
+	}
+	if err := c.unpack(output); err != nil {
+		if len(output) == 0 {
+			err = ErrNoCode
+		}
+		c.err = err
+	}
+}
+
+// Batch collects contract reads across many contracts, to execute them at a
+// single block either as one JSON-RPC batch or through one Multicall3 call.
+type Batch struct {
+	calls []*BatchCall
+}
+
+// Add queues a (constant) contract method invocation with params as input values.
+// The output is set into results after execution, with the same semantics as
+// BoundContract.Call.
+func (b *Batch) Add(contract *BoundContract, results *[]interface{}, method string, params ...interface{}) (*BatchCall, error) {
+	if results == nil {
+		results = new([]interface{})
+	}
+	input, err := contract.abi.Pack(method, params...)
+	if err != nil {
+		return nil, err
+	}
+	return b.AddRaw(contract, input, func(output []byte) error {
+		return contract.unpackResults(results, method, output)
+	}), nil
+}
+
+// AddRaw queues a (constant) contract call with the given raw calldata. After
+// execution, the raw output of successful calls is passed to unpack.
+func (b *Batch) AddRaw(contract *BoundContract, input []byte, unpack func(output []byte) error) *BatchCall {
+	call := &BatchCall{contract: contract, input: input, unpack: unpack}
+	b.calls = append(b.calls, call)
+	return call
+}
+
+// Len returns the number of calls queued in the batch.
+func (b *Batch) Len() int {
+	return len(b.calls)
+}
+
+// ExecuteRPC executes the queued calls as a single JSON-RPC batch request. All
+// calls are executed against the same block: the one selected by the options,
+// or the current head block if none was selected. The returned error reports
+// failures of the whole batch, failures of single calls are reported by them.
+func (b *Batch) ExecuteRPC(client BatchCaller, opts *CallOpts) error {
+	// Don't crash on a lazy user
+	if opts == nil {
+		opts = new(CallOpts)
+	}
+	ctx := ensureContext(opts.Context)
+
+	// Pin the block the calls are executed at, unless explicitly requested
+	var block interface{}
+	switch {
+	case opts.Pending:
+		block = "pending"
+	case opts.BlockHash != (common.Hash{}):
+		block = rpc.BlockNumberOrHashWithHash(opts.BlockHash, false)
+	case opts.BlockNumber != nil:
+		block = hexutil.EncodeBig(opts.BlockNumber)
+	default:
+		var head struct {
+			Hash common.Hash `json:"hash"`
+		}
+		elem := []rpc.BatchElem{{Method: "eth_getBlockByNumber", Args: []interface{}{"latest", false}, Result: &head}}
+		if err := client.BatchCallContext(ctx, elem); err != nil {
+			return err
+		}
+		if elem[0].Error != nil {
+			return elem[0].Error
+		}
+		block = rpc.BlockNumberOrHashWithHash(head.Hash, false)
+	}
+	var (
+		elems   = make([]rpc.BatchElem, len(b.calls))
+		results = make([]hexutil.Bytes, len(b.calls))
+	)
+	for i, call := range b.calls {
+		arg := map[string]interface{}{
+			"to":    call.contract.address,
+			"input": hexutil.Bytes(call.input),
+		}
+		if opts.From != (common.Address{}) {
+			arg["from"] = opts.From
+		}
+		elems[i] = rpc.BatchElem{Method: "eth_call", Args: []interface{}{arg, block}, Result: &results[i]}
+	}
+	if err := client.BatchCallContext(ctx, elems); err != nil {
+		return err
+	}
+	for i, call := range b.calls {
+		call.deliver(results[i], elems[i].Error)
+	}
+	return nil
+}
+
+// ExecuteMulticall executes the queued calls through a single aggregate3 call
+// of the Multicall3 contract at the given address, which guarantees that all
+// calls are executed against the same block. The calls are made by the Multicall3
+// contract, so the From field of the options is not seen by the called contracts.
+// The returned error reports failures of the whole batch, failures of single
+// calls are reported by them.
+func (b *Batch) ExecuteMulticall(caller ContractCaller, multicall common.Address, opts *CallOpts) error {
+	calls := make([]multicall3Call, len(b.calls))
+	for i, call := range b.calls {
+		calls[i] = multicall3Call{Target: call.contract.address, AllowFailure: true, CallData: call.input}
+	}
+	contract := NewBoundContract(multicall, multicall3, caller, nil, nil)
+
+	var out []interface{}
+	if err := contract.Call(opts, &out, "aggregate3", calls); err != nil {
+		return err
+	}
+	results := *abi.ConvertType(out[0], new([]multicall3Result)).(*[]multicall3Result)
+	if len(results) != len(b.calls) {
+		return fmt.Errorf("multicall result count mismatch: have %d, want %d", len(results), len(b.calls))
+	}
+	for i, call := range b.calls {
+		if !results[i].Success {
+			call.deliver(nil, &multicallRevert{data: results[i].ReturnData})
+			continue
+		}
+		call.deliver(results[i].ReturnData, nil)
+	}
+	return nil
+}
+
+// multicallRevert is the failure of a single call of a Multicall3 aggregate,
+// carrying the revert data the same way as the errors returned over RPC.
+type multicallRevert struct {
+	data []byte
+}
+
+func (e *multicallRevert) Error() string          { return "execution reverted" }
+func (e *multicallRevert) ErrorData() interface{} { return hexutil.Encode(e.data) }
+
+// Ensure the multicall failures are decoded as RPC reverts.
+var _ rpc.DataError = (*multicallRevert)(nil)
@@ -0,0 +1,248 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bind_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// getterBin and getterABI are a contract returning ("Hi", 1, keccak256("")).
+	getterBin = `606060405260dc8060106000396000f3606060405260e060020a6000350463993a04b78114601a575b005b600060605260c0604052600260809081527f486900000000000000000000000000000000000000000000000000000000000060a05260017fc5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a47060e0829052610100819052606060c0908152600261012081905281906101409060a09080838184600060046012f1505081517fffff000000000000000000000000000000000000000000000000000000000000169091525050604051610160819003945092505050f3`
+	getterABI = `[{"constant":true,"inputs":[],"name":"getter","outputs":[{"name":"","type":"string"},{"name":"","type":"int256"},{"name":"","type":"bytes32"}],"type":"function"}]`
+
+	// revertBin and revertABI are a contract whose Error() method reverts with
+	// the custom error MyError3(1, 2, 3).
+	revertBin = `0x6080604052348015600f57600080fd5b5060998061001e6000396000f3fe6080604052348015600f57600080fd5b506004361060285760003560e01c8063726c638214602d575b600080fd5b60336035565b005b60405163024876cd60e61b815260016004820152600260248201526003604482015260640160405180910390fdfea264697066735822122093f786a1bc60216540cd999fbb4a6109e0fef20abcff6e9107fb2817ca968f3c64736f6c63430008070033`
+	revertABI = `[{"inputs":[{"internalType":"uint256","name":"a","type":"uint256"},{"internalType":"uint256","name":"b","type":"uint256"},{"internalType":"uint256","name":"c","type":"uint256"}],"name":"MyError3","type":"error"},{"inputs":[],"name":"Error","outputs":[],"stateMutability":"pure","type":"function"}]`
+
+	// aggregate3ABI is the Multicall3 method emulated by multicallCaller.
+	aggregate3ABI = `[{"inputs":[{"components":[{"name":"target","type":"address"},{"name":"allowFailure","type":"bool"},{"name":"callData","type":"bytes"}],"name":"calls","type":"tuple[]"}],"name":"aggregate3","outputs":[{"components":[{"name":"success","type":"bool"},{"name":"returnData","type":"bytes"}],"name":"returnData","type":"tuple[]"}],"stateMutability":"payable","type":"function"}]`
+)
+
+// deployBatchContracts deploys the getter and the reverting test contracts.
+func deployBatchContracts(t *testing.T) (*simulated.Backend, *bind.BoundContract, *bind.BoundContract) {
+	t.Helper()
+
+	backend := simulated.NewBackend(types.GenesisAlloc{
+		crypto.PubkeyToAddress(testKey.PublicKey): {Balance: big.NewInt(10000000000000000)},
+	})
+	t.Cleanup(func() { backend.Close() })
+
+	auth, _ := bind.NewKeyedTransactorWithChainID(testKey, big.NewInt(1337))
+	var contracts []*bind.BoundContract
+	for _, c := range []struct{ bin, abi string }{{getterBin, getterABI}, {revertBin, revertABI}} {
+		parsed, _ := abi.JSON(strings.NewReader(c.abi))
+		_, _, contract, err := bind.DeployContract(auth, parsed, common.FromHex(c.bin), backend.Client())
+		if err != nil {
+			t.Fatalf("Failed to deploy contract: %v", err)
+		}
+		contracts = append(contracts, contract)
+	}
+	backend.Commit()
+	return backend, contracts[0], contracts[1]
+}
+
+// fillBatch queues a few successful and failing calls into a batch, returning
+// it along with a function verifying the outcome of the calls.
+func fillBatch(t *testing.T, getter, reverter *bind.BoundContract) (*bind.Batch, func()) {
+	t.Helper()
+
+	var (
+		batch   = new(bind.Batch)
+		outs    = make([][]interface{}, 3)
+		calls   = make([]*bind.BatchCall, 3)
+		noCode  = bind.NewBoundContract(common.Address{0xff}, abi.ABI{}, nil, nil, nil)
+		missing *bind.BatchCall
+	)
+	for i := range calls {
+		call, err := batch.Add(getter, &outs[i], "getter")
+		if err != nil {
+			t.Fatalf("Failed to queue call: %v", err)
+		}
+		calls[i] = call
+	}
+	failing, err := batch.Add(reverter, nil, "Error")
+	if err != nil {
+		t.Fatalf("Failed to queue call: %v", err)
+	}
+	missing = batch.AddRaw(noCode, []byte{0x99, 0x3a, 0x04, 0xb7}, func(output []byte) error {
+		return errors.New("no output")
+	})
+	if batch.Len() != 5 {
+		t.Fatalf("Batch length mismatch: have %d, want 5", batch.Len())
+	}
+	return batch, func() {
+		for i, call := range calls {
+			if err := call.Err(); err != nil {
+				t.Fatalf("Call %d failed: %v", i, err)
+			}
+			if outs[i][0].(string) != "Hi" || outs[i][1].(*big.Int).Int64() != 1 || outs[i][2].([32]byte) != crypto.Keccak256Hash() {
+				t.Errorf("Call %d output mismatch: %v", i, outs[i])
+			}
+		}
+		var custom *bind.CustomError
+		if err := failing.Err(); !errors.As(err, &custom) || custom.Name != "MyError3" {
+			t.Errorf("Failing call error mismatch: %v", err)
+		}
+		if err := missing.Err(); !errors.Is(err, bind.ErrNoCode) {
+			t.Errorf("Missing contract error mismatch: %v", err)
+		}
+	}
+}
+
+// rpcBatchCaller serves the JSON-RPC batch requests from the backend, checking
+// that all calls are made at the same pinned block.
+type rpcBatchCaller struct {
+	client  simulated.Client
+	batches int
+	blocks  map[common.Hash]bool
+}
+
+func (c *rpcBatchCaller) BatchCallContext(ctx context.Context, batch []rpc.BatchElem) error {
+	c.batches++
+	for i := range batch {
+		elem := &batch[i]
+		switch elem.Method {
+		case "eth_getBlockByNumber":
+			head, err := c.client.HeaderByNumber(ctx, nil)
+			if err != nil {
+				return err
+			}
+			blob, _ := json.Marshal(map[string]common.Hash{"hash": head.Hash()})
+			json.Unmarshal(blob, elem.Result)
+
+		case "eth_call":
+			var (
+				arg   = elem.Args[0].(map[string]interface{})
+				to    = arg["to"].(common.Address)
+				block = elem.Args[1].(rpc.BlockNumberOrHash)
+				hash  = *block.BlockHash
+			)
+			c.blocks[hash] = true
+			msg := ethereum.CallMsg{To: &to, Data: arg["input"].(hexutil.Bytes)}
+			output, err := c.client.(interface {
+				CallContractAtHash(context.Context, ethereum.CallMsg, common.Hash) ([]byte, error)
+			}).CallContractAtHash(ctx, msg, hash)
+			if err != nil {
+				elem.Error = err
+				continue
+			}
+			blob, _ := json.Marshal(hexutil.Bytes(output))
+			json.Unmarshal(blob, elem.Result)
+
+		default:
+			elem.Error = fmt.Errorf("unexpected method %s", elem.Method)
+		}
+	}
+	return nil
+}
+
+func TestBatchExecuteRPC(t *testing.T) {
+	t.Parallel()
+	backend, getter, reverter := deployBatchContracts(t)
+
+	batch, check := fillBatch(t, getter, reverter)
+	client := &rpcBatchCaller{client: backend.Client(), blocks: make(map[common.Hash]bool)}
+	if err := batch.ExecuteRPC(client, nil); err != nil {
+		t.Fatalf("Failed to execute batch: %v", err)
+	}
+	// One request pins the head block, another executes all calls on it
+	if client.batches != 2 || len(client.blocks) != 1 {
+		t.Fatalf("Batch execution mismatch: %d requests, %d blocks", client.batches, len(client.blocks))
+	}
+	check()
+}
+
+// multicallCaller emulates a Multicall3 contract deployed at the Multicall3
+// address, forwarding every other call to the backend.
+type multicallCaller struct {
+	bind.ContractCaller
+	abi   abi.ABI
+	calls int
+}
+
+func (c *multicallCaller) CallContract(ctx context.Context, msg ethereum.CallMsg, number *big.Int) ([]byte, error) {
+	if *msg.To != bind.Multicall3Address {
+		return c.ContractCaller.CallContract(ctx, msg, number)
+	}
+	c.calls++
+
+	args, err := c.abi.Methods["aggregate3"].Inputs.Unpack(msg.Data[4:])
+	if err != nil {
+		return nil, err
+	}
+	calls := *abi.ConvertType(args[0], new([]struct {
+		Target       common.Address
+		AllowFailure bool
+		CallData     []byte
+	})).(*[]struct {
+		Target       common.Address
+		AllowFailure bool
+		CallData     []byte
+	})
+	type result struct {
+		Success    bool
+		ReturnData []byte
+	}
+	results := make([]result, len(calls))
+	for i, call := range calls {
+		output, err := c.ContractCaller.CallContract(ctx, ethereum.CallMsg{To: &call.Target, Data: call.CallData}, number)
+		if err != nil {
+			var dataErr rpc.DataError
+			if !errors.As(err, &dataErr) {
+				return nil, err
+			}
+			output, _ = hexutil.Decode(dataErr.ErrorData().(string))
+		}
+		results[i] = result{Success: err == nil, ReturnData: output}
+	}
+	return c.abi.Methods["aggregate3"].Outputs.Pack(results)
+}
+
+func TestBatchExecuteMulticall(t *testing.T) {
+	t.Parallel()
+	backend, getter, reverter := deployBatchContracts(t)
+
+	parsed, _ := abi.JSON(strings.NewReader(aggregate3ABI))
+	caller := &multicallCaller{ContractCaller: backend.Client(), abi: parsed}
+
+	batch, check := fillBatch(t, getter, reverter)
+	if err := batch.ExecuteMulticall(caller, bind.Multicall3Address, nil); err != nil {
+		t.Fatalf("Failed to execute batch: %v", err)
+	}
+	if caller.calls != 1 {
+		t.Fatalf("Multicall count mismatch: have %d, want 1", caller.calls)
+	}
+	check()
+}
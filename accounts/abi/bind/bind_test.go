@@ -1877,6 +1877,7 @@ var bindTests = []struct {
 		[]string{`[{"inputs":[{"internalType":"uint256","name":"","type":"uint256"}],"name":"MyError","type":"error"},{"inputs":[{"internalType":"uint256","name":"","type":"uint256"}],"name":"MyError1","type":"error"},{"inputs":[{"internalType":"uint256","name":"","type":"uint256"},{"internalType":"uint256","name":"","type":"uint256"}],"name":"MyError2","type":"error"},{"inputs":[{"internalType":"uint256","name":"a","type":"uint256"},{"internalType":"uint256","name":"b","type":"uint256"},{"internalType":"uint256","name":"c","type":"uint256"}],"name":"MyError3","type":"error"},{"inputs":[],"name":"Error","outputs":[],"stateMutability":"pure","type":"function"}]`},
 		`
 			"context"
+			"errors"
 			"math/big"
 	
 			"github.com/ethereum/go-ethereum/accounts/abi/bind"
@@ -1902,11 +1903,21 @@ var bindTests = []struct {
 			if err != nil {
 				t.Error(err)
 			}
-			if err := contract.Error(new(bind.CallOpts)); err == nil {
+			err = contract.Error(new(bind.CallOpts))
+			if err == nil {
 				t.Fatalf("expected contract to throw error")
 			}
-			// TODO (MariusVanDerWijden unpack error using abigen
-			// once that is implemented
+			var custom *bind.CustomError
+			if !errors.As(err, &custom) || custom.Name != "MyError3" || len(custom.Values) != 3 {
+				t.Fatalf("expected custom error MyError3, got %v", err)
+			}
+			var myErr *NewErrorsMyError3
+			if !errors.As(err, &myErr) {
+				t.Fatalf("expected typed error, got %v", err)
+			}
+			if myErr.A.Uint64() != 1 || myErr.B.Uint64() != 2 || myErr.C.Uint64() != 3 {
+				t.Fatalf("typed error mismatch: %v", myErr)
+			}
 	   `,
 		nil,
 		nil,
@@ -2145,3 +2156,214 @@ func TestGolangBindings(t *testing.T) {
 		t.Fatalf("failed to run binding test: %v\n%s", err, out)
 	}
 }
+
+// Tests that the stateless bindings generated for the same contracts as the
+// original bindings compile and interact correctly with the generic runtime.
+func TestGolangBindingsV2(t *testing.T) {
+	t.Parallel()
+	// Skip the test if no Go command can be found
+	gocmd := runtime.GOROOT() + "/bin/go"
+	if !common.FileExist(gocmd) {
+		t.Skip("go sdk not found for testing")
+	}
+	// Create a temporary workspace for the test suite
+	pkg := filepath.Join(t.TempDir(), "bindtestv2")
+	if err := os.MkdirAll(pkg, 0700); err != nil {
+		t.Fatalf("failed to create package: %v", err)
+	}
+	// Generate the bindings for all the contracts
+	for i, tt := range bindTests {
+		types := tt.types
+		if types == nil {
+			types = []string{tt.name}
+		}
+		bind, err := BindV2(types, tt.abi, tt.bytecode, "bindtestv2", tt.libs, tt.aliases)
+		if err != nil {
+			t.Fatalf("test %d: failed to generate binding: %v", i, err)
+		}
+		if err = os.WriteFile(filepath.Join(pkg, strings.ToLower(tt.name)+".go"), []byte(bind), 0600); err != nil {
+			t.Fatalf("test %d: failed to write binding: %v", i, err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(pkg, "bindtestv2_test.go"), []byte(bindTestV2Code), 0600); err != nil {
+		t.Fatalf("failed to write tests: %v", err)
+	}
+	// Convert the package to go modules and use the current source for go-ethereum
+	moder := exec.Command(gocmd, "mod", "init", "bindtestv2")
+	moder.Dir = pkg
+	if out, err := moder.CombinedOutput(); err != nil {
+		t.Fatalf("failed to convert binding test to modules: %v\n%s", err, out)
+	}
+	pwd, _ := os.Getwd()
+	replacer := exec.Command(gocmd, "mod", "edit", "-x", "-require", "github.com/ethereum/go-ethereum@v0.0.0", "-replace", "github.com/ethereum/go-ethereum="+filepath.Join(pwd, "..", "..", "..")) // Repo root
+	replacer.Dir = pkg
+	if out, err := replacer.CombinedOutput(); err != nil {
+		t.Fatalf("failed to replace binding test dependency to current source tree: %v\n%s", err, out)
+	}
+	tidier := exec.Command(gocmd, "mod", "tidy")
+	tidier.Dir = pkg
+	if out, err := tidier.CombinedOutput(); err != nil {
+		t.Fatalf("failed to tidy Go module file: %v\n%s", err, out)
+	}
+	// Test the entire package and report any failures
+	cmd := exec.Command(gocmd, "test", "-v", "-count", "1")
+	cmd.Dir = pkg
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("failed to run binding test: %v\n%s", err, out)
+	}
+}
+
+// bindTestV2Code exercises the stateless bindings of a few of the contracts in
+// bindTests through the generic runtime.
+const bindTestV2Code = `
+package bindtestv2
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	bindv2 "github.com/ethereum/go-ethereum/accounts/abi/bind/v2"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+func newSimulator(t *testing.T) (*bind.TransactOpts, *backends.SimulatedBackend) {
+	key, _ := crypto.GenerateKey()
+	auth, _ := bind.NewKeyedTransactorWithChainID(key, big.NewInt(1337))
+
+	sim := backends.NewSimulatedBackend(types.GenesisAlloc{auth.From: {Balance: big.NewInt(10000000000000000)}}, 10000000)
+	t.Cleanup(func() { sim.Close() })
+	return auth, sim
+}
+
+func TestLinkedCall(t *testing.T) {
+	auth, sim := newSimulator(t)
+
+	// Deploy the contract along with its library
+	params := &bindv2.DeploymentParams{Contracts: []*bindv2.MetaData{UseLibraryMetaData}}
+	res, err := bindv2.LinkAndDeploy(params, bindv2.DefaultDeployer(auth, sim))
+	if err != nil {
+		t.Fatalf("Failed to deploy contracts: %v", err)
+	}
+	sim.Commit()
+	if len(res.Addresses) != 2 {
+		t.Fatalf("Deployed contract count mismatch: have %d, want 2", len(res.Addresses))
+	}
+	// Call the linked contract through the stateless binding
+	c := NewUseLibrary()
+	instance := c.Instance(sim, res.Addresses[UseLibraryMetaData])
+
+	sum, err := bindv2.Call(instance, nil, c.PackAdd(big.NewInt(1), big.NewInt(2)), c.UnpackAdd)
+	if err != nil {
+		t.Fatalf("Failed to call linked contract: %v", err)
+	}
+	if sum.Cmp(big.NewInt(3)) != 0 {
+		t.Fatalf("Add result mismatch: have %v, want 3", sum)
+	}
+}
+
+func TestEvents(t *testing.T) {
+	auth, sim := newSimulator(t)
+
+	addr, _, err := bindv2.DeployContract(auth, common.FromHex(EventerMetaData.Bin), sim, nil)
+	if err != nil {
+		t.Fatalf("Failed to deploy contract: %v", err)
+	}
+	sim.Commit()
+
+	c := NewEventer()
+	instance := c.Instance(sim, addr)
+	for i := 1; i <= 3; i++ {
+		if _, err := bindv2.Transact(instance, auth, c.PackRaiseSimpleEvent(common.Address{byte(i)}, [32]byte{byte(i)}, true, big.NewInt(int64(i)))); err != nil {
+			t.Fatalf("Failed to raise event %d: %v", i, err)
+		}
+	}
+	sim.Commit()
+
+	topics := c.SimpleEventEventTopics([]common.Address{{1}, {3}}, nil, nil)
+	it, err := bindv2.FilterEvents(instance, nil, c.UnpackSimpleEventEvent, topics...)
+	if err != nil {
+		t.Fatalf("Failed to filter events: %v", err)
+	}
+	defer it.Close()
+
+	var values []uint64
+	for it.Next() {
+		ev := it.Value()
+		if !ev.Flag || ev.Raw == nil {
+			t.Errorf("Event content mismatch: %v", ev)
+		}
+		values = append(values, ev.Value.Uint64())
+	}
+	if err := it.Error(); err != nil {
+		t.Fatalf("Event iteration failed: %v", err)
+	}
+	if len(values) != 2 || values[0] != 1 || values[1] != 3 {
+		t.Fatalf("Filtered events mismatch: have %v, want [1 3]", values)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	auth, sim := newSimulator(t)
+
+	addr, _, err := bindv2.DeployContract(auth, common.FromHex(NewErrorsMetaData.Bin), sim, nil)
+	if err != nil {
+		t.Fatalf("Failed to deploy contract: %v", err)
+	}
+	sim.Commit()
+
+	c := NewNewErrors()
+	_, err = bindv2.Call(c.Instance(sim, addr), nil, c.PackError(), func([]byte) (any, error) { return nil, nil })
+
+	var typed *NewErrorsMyError3
+	if !errors.As(err, &typed) || typed.C.Uint64() != 3 {
+		t.Fatalf("Expected typed custom error, got %v", err)
+	}
+	var dataErr rpc.DataError
+	if !errors.As(err, &dataErr) {
+		t.Fatalf("Expected revert with data, got %v", err)
+	}
+	raw, ok := dataErr.ErrorData().(string)
+	if !ok {
+		t.Fatalf("Unexpected revert data: %v", dataErr.ErrorData())
+	}
+	unpacked, err := c.UnpackError(common.FromHex(raw))
+	if err != nil {
+		t.Fatalf("Failed to unpack error: %v", err)
+	}
+	myErr, ok := unpacked.(*NewErrorsMyError3)
+	if !ok {
+		t.Fatalf("Unpacked error type mismatch: %T", unpacked)
+	}
+	if myErr.A.Uint64() != 1 || myErr.B.Uint64() != 2 || myErr.C.Uint64() != 3 {
+		t.Fatalf("Unpacked error mismatch: %v", myErr)
+	}
+	if _, err := c.UnpackMyErrorError(common.FromHex(raw)); !errors.Is(err, bindv2.ErrErrorSignatureMismatch) {
+		t.Fatalf("Expected signature mismatch, got %v", err)
+	}
+}
+`
+
+// Tests that custom error names colliding after normalization are renamed by the
+// original bindings, which used to ignore custom errors, and only rejected by the
+// stateless bindings.
+func TestBindErrorNameCollision(t *testing.T) {
+	t.Parallel()
+
+	abi := `[{"type":"error","name":"_failed","inputs":[]},{"type":"error","name":"failed","inputs":[]}]`
+	bind, err := Bind([]string{"Errors"}, []string{abi}, []string{""}, nil, "bindtest", LangGo, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to generate binding: %v", err)
+	}
+	if !strings.Contains(bind, "type ErrorsFailed struct") || !strings.Contains(bind, "type ErrorsFailed0 struct") {
+		t.Fatalf("colliding errors not renamed:\n%s", bind)
+	}
+	if _, err := BindV2([]string{"Errors"}, []string{abi}, []string{""}, "bindtest", nil, nil); err == nil {
+		t.Fatal("colliding error names accepted")
+	}
+}
@@ -0,0 +1,174 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bind
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var (
+	// revertSelector is the selector of the Error(string) revert reason.
+	revertSelector = crypto.Keccak256([]byte("Error(string)"))[:4]
+
+	// panicSelector is the selector of the Panic(uint256) revert reason.
+	panicSelector = crypto.Keccak256([]byte("Panic(uint256)"))[:4]
+)
+
+// RevertError is returned by contract calls and gas estimations reverting with
+// an Error(string) reason, or with revert data that could not be decoded.
+type RevertError struct {
+	Reason string // Revert reason, empty if the revert data could not be decoded
+	Data   []byte // Raw revert data returned by the contract
+	err    error  // Original error returned by the backend
+}
+
+// Error implements the error interface.
+func (e *RevertError) Error() string {
+	if e.Reason != "" || e.err == nil {
+		return "execution reverted: " + e.Reason
+	}
+	return e.err.Error()
+}
+
+// Unwrap returns the original error returned by the backend.
+func (e *RevertError) Unwrap() error {
+	return e.err
+}
+
+// PanicError is returned by contract calls and gas estimations reverting with
+// a Panic(uint256) code, raised by failing assertions and runtime errors.
+type PanicError struct {
+	Code *big.Int // Panic code of the failure
+	Data []byte   // Raw revert data returned by the contract
+	err  error    // Original error returned by the backend
+}
+
+// Error implements the error interface.
+func (e *PanicError) Error() string {
+	reason, err := abi.UnpackRevert(e.Data)
+	if err != nil {
+		reason = fmt.Sprintf("panic code %#x", e.Code)
+	}
+	return "execution reverted: " + reason
+}
+
+// Unwrap returns the original error returned by the backend.
+func (e *PanicError) Unwrap() error {
+	return e.err
+}
+
+// CustomError is returned by contract calls and gas estimations reverting with
+// an error defined in the ABI of the contract.
+type CustomError struct {
+	Name   string        // Name of the error in the contract ABI
+	Values []interface{} // Decoded arguments of the error
+	Data   []byte        // Raw revert data returned by the contract
+	typed  error         // Typed error produced by the decoder of the generated binding
+	err    error         // Original error returned by the backend
+}
+
+// Error implements the error interface.
+func (e *CustomError) Error() string {
+	values := make([]string, len(e.Values))
+	for i, value := range e.Values {
+		values[i] = fmt.Sprint(value)
+	}
+	return fmt.Sprintf("execution reverted: %s(%s)", e.Name, strings.Join(values, ", "))
+}
+
+// Unwrap returns the typed error of the generated binding, if any, and the
+// original error returned by the backend.
+func (e *CustomError) Unwrap() []error {
+	var errs []error
+	if e.typed != nil {
+		errs = append(errs, e.typed)
+	}
+	if e.err != nil {
+		errs = append(errs, e.err)
+	}
+	return errs
+}
+
+// ErrorDecoder converts the revert data of a contract-defined error into a typed
+// Go error, returning nil if the data doesn't belong to any known error.
+type ErrorDecoder func(data []byte) error
+
+// SetErrorDecoder sets the decoder used to convert contract-defined errors into
+// the typed errors of the generated bindings. The decoded errors are available
+// through errors.As on the CustomError returned by calls and transactions.
+func (c *BoundContract) SetErrorDecoder(decoder ErrorDecoder) {
+	c.decodeError = decoder
+}
+
+// unpackRevert converts an error of a call or gas estimation carrying revert
+// data into a typed revert error. Other errors are returned as is.
+func (c *BoundContract) unpackRevert(err error) error {
+	data, ok := revertData(err)
+	if !ok {
+		return err
+	}
+	if len(data) >= 4 {
+		switch {
+		case bytes.Equal(data[:4], revertSelector):
+			if reason, unpackErr := abi.UnpackRevert(data); unpackErr == nil {
+				return &RevertError{Reason: reason, Data: data, err: err}
+			}
+		case bytes.Equal(data[:4], panicSelector) && len(data) == 4+32:
+			return &PanicError{Code: new(big.Int).SetBytes(data[4:]), Data: data, err: err}
+		default:
+			if abiErr, lookupErr := c.abi.ErrorByID([4]byte(data[:4])); lookupErr == nil {
+				if values, unpackErr := abiErr.Inputs.Unpack(data[4:]); unpackErr == nil {
+					custom := &CustomError{Name: abiErr.Name, Values: values, Data: data, err: err}
+					if c.decodeError != nil {
+						custom.typed = c.decodeError(data)
+					}
+					return custom
+				}
+			}
+		}
+	}
+	return &RevertError{Data: data, err: err}
+}
+
+// revertData extracts the revert data from an error returned by a backend.
+func revertData(err error) ([]byte, bool) {
+	var dataErr rpc.DataError
+	if !errors.As(err, &dataErr) {
+		return nil, false
+	}
+	switch data := dataErr.ErrorData().(type) {
+	case string:
+		blob, err := hexutil.Decode(data)
+		if err != nil {
+			return nil, false
+		}
+		return blob, true
+	case []byte:
+		return data, true
+	default:
+		return nil, false
+	}
+}
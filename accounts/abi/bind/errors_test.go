@@ -0,0 +1,101 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bind
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// testDataError mimics a revert error returned over RPC.
+type testDataError struct {
+	data interface{}
+}
+
+func (e *testDataError) Error() string          { return "execution reverted" }
+func (e *testDataError) ErrorData() interface{} { return e.data }
+
+// testTypedError is a typed error as produced by the generated bindings.
+type testTypedError struct {
+	Amount *big.Int
+}
+
+func (e *testTypedError) Error() string { return "typed" }
+
+func TestUnpackRevert(t *testing.T) {
+	parsed, err := abi.JSON(strings.NewReader(`[{"type":"error","name":"Insufficient","inputs":[{"name":"amount","type":"uint256"}]}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		reason = common.FromHex("0x08c379a00000000000000000000000000000000000000000000000000000000000000020000000000000000000000000000000000000000000000000000000000000000b6e6f7420616c6c6f776564000000000000000000000000000000000000000000")
+		panic  = common.FromHex("0x4e487b710000000000000000000000000000000000000000000000000000000000000012")
+		custom = append(parsed.Errors["Insufficient"].ID.Bytes()[:4], common.LeftPadBytes([]byte{7}, 32)...)
+	)
+	c := NewBoundContract(common.Address{}, parsed, nil, nil, nil)
+	c.SetErrorDecoder(func(data []byte) error {
+		return &testTypedError{Amount: new(big.Int).SetBytes(data[4:])}
+	})
+
+	// Errors without revert data are passed through
+	plain := errors.New("connection refused")
+	if err := c.unpackRevert(plain); err != plain {
+		t.Errorf("plain error modified: %v", err)
+	}
+	// Revert reasons are decoded, retaining the original error
+	orig := &testDataError{data: hexutil.Encode(reason)}
+	var revertErr *RevertError
+	if err := c.unpackRevert(orig); !errors.As(err, &revertErr) || revertErr.Reason != "not allowed" {
+		t.Errorf("revert reason mismatch: %v", err)
+	} else if !errors.Is(err, orig) {
+		t.Errorf("original error not wrapped")
+	} else if err.Error() != "execution reverted: not allowed" {
+		t.Errorf("revert message mismatch: %q", err.Error())
+	}
+	// Panics are decoded along with their code
+	var panicErr *PanicError
+	if err := c.unpackRevert(&testDataError{data: hexutil.Encode(panic)}); !errors.As(err, &panicErr) || panicErr.Code.Uint64() != 0x12 {
+		t.Errorf("panic mismatch: %v", err)
+	} else if err.Error() != "execution reverted: division or modulo by zero" {
+		t.Errorf("panic message mismatch: %q", err.Error())
+	}
+	// Custom errors are decoded both generically and into the typed error
+	var (
+		customErr *CustomError
+		typedErr  *testTypedError
+	)
+	err = c.unpackRevert(&testDataError{data: custom})
+	if !errors.As(err, &customErr) || customErr.Name != "Insufficient" || customErr.Values[0].(*big.Int).Uint64() != 7 {
+		t.Errorf("custom error mismatch: %v", err)
+	}
+	if !errors.As(err, &typedErr) || typedErr.Amount.Uint64() != 7 {
+		t.Errorf("typed error mismatch: %v", err)
+	}
+	if err.Error() != "execution reverted: Insufficient(7)" {
+		t.Errorf("custom error message mismatch: %q", err.Error())
+	}
+	// Unknown revert data is retained as is
+	if err := c.unpackRevert(&testDataError{data: "0xdeadbeef"}); !errors.As(err, &revertErr) || revertErr.Reason != "" || hexutil.Encode(revertErr.Data) != "0xdeadbeef" {
+		t.Errorf("unknown revert mismatch: %v", err)
+	}
+}
@@ -38,8 +38,10 @@ type tmplContract struct {
 	Fallback    *tmplMethod            // Additional special fallback function
 	Receive     *tmplMethod            // Additional special receive function
 	Events      map[string]*tmplEvent  // Contract events accessors
+	Errors      map[string]*tmplError  // Contract custom errors
 	Libraries   map[string]string      // Same as tmplData, but filtered to only keep what the contract needs
 	Library     bool                   // Indicator whether the contract is a library
+	Pattern     string                 // Link pattern of the contract if it is a library
 }
 
 // tmplMethod is a wrapper around an abi.Method that contains a few preprocessed
@@ -57,6 +59,13 @@ type tmplEvent struct {
 	Normalized abi.Event // Normalized version of the parsed fields
 }
 
+// tmplError is a wrapper around an abi.Error that contains a few preprocessed
+// and cached data fields.
+type tmplError struct {
+	Original   abi.Error // Original error as parsed by the abi package
+	Normalized abi.Error // Normalized version of the parsed fields
+}
+
 // tmplField is a wrapper around a struct field with binding language
 // struct type definition and relative filed name.
 type tmplField struct {
@@ -90,6 +99,7 @@ import (
 	"math/big"
 	"strings"
 	"errors"
+	"fmt"
 
 	ethereum "github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
@@ -102,6 +112,7 @@ import (
 // Reference imports to suppress errors if they are not otherwise used.
 var (
 	_ = errors.New
+	_ = fmt.Sprintf
 	_ = big.NewInt
 	_ = strings.NewReader
 	_ = ethereum.NotFound
@@ -167,6 +178,7 @@ var (
 		  if err != nil {
 		    return common.Address{}, nil, nil, err
 		  }
+		  {{if .Errors}}contract.SetErrorDecoder(func(data []byte) error { return unpack{{.Type}}Error(parsed, data) }){{end}}
 		  return address, tx, &{{.Type}}{ {{.Type}}Caller: {{.Type}}Caller{contract: contract}, {{.Type}}Transactor: {{.Type}}Transactor{contract: contract}, {{.Type}}Filterer: {{.Type}}Filterer{contract: contract} }, nil
 		}
 	{{end}}
@@ -272,7 +284,9 @@ var (
 	  if err != nil {
 	    return nil, err
 	  }
-	  return bind.NewBoundContract(address, *parsed, caller, transactor, filterer), nil
+	  contract := bind.NewBoundContract(address, *parsed, caller, transactor, filterer)
+	  {{if .Errors}}contract.SetErrorDecoder(func(data []byte) error { return unpack{{.Type}}Error(parsed, data) }){{end}}
+	  return contract, nil
 	}
 
 	// Call invokes the (constant) contract method with params as input values and
@@ -567,5 +581,46 @@ var (
 		}
 
  	{{end}}
+
+	{{range .Errors}}
+		// {{$contract.Type}}{{.Normalized.Name}} represents a {{.Original.Name}} error raised by the {{$contract.Type}} contract.
+		type {{$contract.Type}}{{.Normalized.Name}} struct { {{range .Normalized.Inputs}}
+			{{capitalise .Name}} {{bindtype .Type $structs}}; {{end}}
+		}
+
+		// Error implements the error interface.
+		//
+		// Solidity: {{.Original.String}}
+		func (e *{{$contract.Type}}{{.Normalized.Name}}) Error() string {
+			return fmt.Sprintf("{{.Original.Name}}({{range $i, $_ := .Normalized.Inputs}}{{if ne $i 0}}, {{end}}{{.Name}}: %v{{end}})"{{range .Normalized.Inputs}}, e.{{capitalise .Name}}{{end}})
+		}
+	{{end}}
+
+	{{if .Errors}}
+		// unpack{{.Type}}Error decodes the revert data of a custom error raised by the
+		// {{.Type}} contract into its typed Go error, returning nil for unknown errors.
+		func unpack{{.Type}}Error(parsed *abi.ABI, data []byte) error {
+			if len(data) < 4 {
+				return nil
+			}
+			e, err := parsed.ErrorByID([4]byte(data[:4]))
+			if err != nil {
+				return nil
+			}
+			values, err := e.Inputs.Unpack(data[4:])
+			if err != nil {
+				return nil
+			}
+			switch e.Name {
+			{{- range .Errors}}
+			case "{{.Original.Name}}":
+				return &{{$contract.Type}}{{.Normalized.Name}}{ {{range $i, $_ := .Normalized.Inputs}}
+					{{capitalise .Name}}: *abi.ConvertType(values[{{$i}}], new({{bindtype .Type $structs}})).(*{{bindtype .Type $structs}}),{{end}}
+				}
+			{{- end}}
+			}
+			return nil
+		}
+	{{end}}
 {{end}}
 `
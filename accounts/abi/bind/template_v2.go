@@ -0,0 +1,301 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bind
+
+// tmplSourceGoV2 is the Go source template that the generated stateless Go
+// contract binding is based on.
+const tmplSourceGoV2 = `
+// Code generated - DO NOT EDIT.
+// This file is a generated binding and any manual changes will be lost.
+
+package {{.Package}}
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/v2"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var (
+	_ = bytes.Equal
+	_ = errors.New
+	_ = fmt.Sprintf
+	_ = big.NewInt
+	_ = common.Big1
+	_ = types.BloomLookup
+	_ = abi.ConvertType
+)
+
+{{$structs := .Structs}}
+{{range $structs}}
+	// {{.Name}} is an auto generated low-level Go binding around an user-defined struct.
+	type {{.Name}} struct {
+	{{range $field := .Fields}}
+	{{$field.Name}} {{$field.Type}}{{end}}
+	}
+{{end}}
+
+{{range $contract := .Contracts}}
+	// {{.Type}}MetaData contains all meta data concerning the {{.Type}} contract.
+	var {{.Type}}MetaData = &bind.MetaData{
+		ABI: "{{.InputABI}}",
+		{{if .Pattern -}}
+		Pattern: "{{.Pattern}}",
+		{{end -}}
+		{{if .InputBin -}}
+		Bin: "0x{{.InputBin}}",
+		{{end -}}
+		{{if .Libraries -}}
+		Deps: []*bind.MetaData{
+			{{range $pattern, $name := .Libraries -}}
+			{{capitalise $name}}MetaData,
+			{{end}}
+		},
+		{{end}}
+	}
+
+	// {{.Type}} is an auto generated Go binding around an Ethereum contract.
+	type {{.Type}} struct {
+		abi abi.ABI
+	}
+
+	// New{{.Type}} creates a new instance of {{.Type}}.
+	func New{{.Type}}() *{{.Type}} {
+		parsed, err := {{.Type}}MetaData.ParseABI()
+		if err != nil {
+			panic(errors.New("invalid ABI: " + err.Error()))
+		}
+		return &{{.Type}}{abi: *parsed}
+	}
+
+	// Instance creates a wrapper for a deployed contract instance at the given address.
+	// Use this to create the instance object passed to abigen v2 library functions Call, Transact, etc.
+	func (_{{$contract.Type}} *{{$contract.Type}}) Instance(backend bind.ContractBackend, addr common.Address) *bind.BoundContract {
+		instance := bind.NewBoundContract(addr, _{{$contract.Type}}.abi, backend)
+		{{- if .Errors}}
+		instance.SetErrorDecoder(func(data []byte) error {
+			if typed, err := _{{$contract.Type}}.UnpackError(data); err == nil {
+				return typed.(error)
+			}
+			return nil
+		})
+		{{- end}}
+		return instance
+	}
+
+	{{if .Constructor.Inputs}}
+	// PackConstructor is the Go binding used to pack the parameters required for
+	// contract deployment.
+	//
+	// Solidity: {{.Constructor.String}}
+	func (_{{$contract.Type}} *{{$contract.Type}}) PackConstructor({{range $i, $_ := .Constructor.Inputs}}{{if ne $i 0}}, {{end}}{{.Name}} {{bindtype .Type $structs}}{{end}}) []byte {
+		enc, err := _{{$contract.Type}}.abi.Pack(""{{range .Constructor.Inputs}}, {{.Name}}{{end}})
+		if err != nil {
+			panic(err)
+		}
+		return enc
+	}
+	{{end}}
+
+	{{range .Calls}}{{template "method" (dict "contract" $contract "structs" $structs "method" .)}}{{end}}
+	{{range .Transacts}}{{template "method" (dict "contract" $contract "structs" $structs "method" .)}}{{end}}
+
+	{{range .Events}}
+		// {{$contract.Type}}{{.Normalized.Name}} represents a {{.Original.Name}} event raised by the {{$contract.Type}} contract.
+		type {{$contract.Type}}{{.Normalized.Name}} struct { {{range .Normalized.Inputs}}
+			{{capitalise .Name}} {{if .Indexed}}{{bindtopictype .Type $structs}}{{else}}{{bindtype .Type $structs}}{{end}}; {{end}}
+			Raw *types.Log // Blockchain specific contextual infos
+		}
+
+		const {{$contract.Type}}{{.Normalized.Name}}EventName = "{{.Original.Name}}"
+
+		// ContractEventName returns the user-defined event name.
+		func ({{$contract.Type}}{{.Normalized.Name}}) ContractEventName() string {
+			return {{$contract.Type}}{{.Normalized.Name}}EventName
+		}
+
+		// {{.Normalized.Name}}EventTopics packs the filters on the indexed fields of the
+		// event 0x{{printf "%x" .Original.ID}} into topic rules.
+		//
+		// Solidity: {{.Original.String}}
+		func (_{{$contract.Type}} *{{$contract.Type}}) {{.Normalized.Name}}EventTopics({{range $i, $_ := .Normalized.Inputs}}{{if .Indexed}}{{.Name}} []{{bindtype .Type $structs}}, {{end}}{{end}}) [][]any {
+			var topics [][]any
+			{{range .Normalized.Inputs}}{{if .Indexed}}
+			var {{.Name}}Rule []any
+			for _, {{.Name}}Item := range {{.Name}} {
+				{{.Name}}Rule = append({{.Name}}Rule, {{.Name}}Item)
+			}
+			topics = append(topics, {{.Name}}Rule)
+			{{end}}{{end}}
+			return topics
+		}
+
+		// Unpack{{.Normalized.Name}}Event is the Go binding that unpacks the event data emitted
+		// by contract.
+		//
+		// Solidity: {{.Original.String}}
+		func (_{{$contract.Type}} *{{$contract.Type}}) Unpack{{.Normalized.Name}}Event(log *types.Log) (*{{$contract.Type}}{{.Normalized.Name}}, error) {
+			event := "{{.Original.Name}}"
+			if len(log.Topics) == 0 || log.Topics[0] != _{{$contract.Type}}.abi.Events[event].ID {
+				return nil, bind.ErrEventSignatureMismatch
+			}
+			out := new({{$contract.Type}}{{.Normalized.Name}})
+			if len(log.Data) > 0 {
+				if err := _{{$contract.Type}}.abi.UnpackIntoInterface(out, event, log.Data); err != nil {
+					return nil, err
+				}
+			}
+			var indexed abi.Arguments
+			for _, arg := range _{{$contract.Type}}.abi.Events[event].Inputs {
+				if arg.Indexed {
+					indexed = append(indexed, arg)
+				}
+			}
+			if err := abi.ParseTopics(out, indexed, log.Topics[1:]); err != nil {
+				return nil, err
+			}
+			out.Raw = log
+			return out, nil
+		}
+	{{end}}
+
+	{{if .Errors}}
+		// UnpackError attempts to decode the provided error data using user-defined
+		// error definitions.
+		func (_{{$contract.Type}} *{{$contract.Type}}) UnpackError(raw []byte) (any, error) {
+			{{- range .Errors}}
+			if len(raw) >= 4 && bytes.Equal(raw[:4], _{{$contract.Type}}.abi.Errors["{{.Original.Name}}"].ID.Bytes()[:4]) {
+				return _{{$contract.Type}}.Unpack{{.Normalized.Name}}Error(raw)
+			}
+			{{- end}}
+			return nil, errors.New("unknown error")
+		}
+	{{end}}
+
+	{{range .Errors}}
+		// {{$contract.Type}}{{.Normalized.Name}} represents a {{.Original.Name}} error raised by the {{$contract.Type}} contract.
+		type {{$contract.Type}}{{.Normalized.Name}} struct { {{range .Normalized.Inputs}}
+			{{capitalise .Name}} {{bindtype .Type $structs}}; {{end}}
+		}
+
+		// Error implements the error interface.
+		//
+		// Solidity: {{.Original.String}}
+		func (e *{{$contract.Type}}{{.Normalized.Name}}) Error() string {
+			return fmt.Sprintf("{{.Original.Name}}({{range $i, $_ := .Normalized.Inputs}}{{if ne $i 0}}, {{end}}{{.Name}}: %v{{end}})"{{range .Normalized.Inputs}}, e.{{capitalise .Name}}{{end}})
+		}
+
+		// {{$contract.Type}}{{.Normalized.Name}}ErrorID returns the hash of canonical representation of the error's signature.
+		//
+		// Solidity: {{.Original.String}}
+		func {{$contract.Type}}{{.Normalized.Name}}ErrorID() common.Hash {
+			return common.HexToHash("{{printf "%#x" .Original.ID}}")
+		}
+
+		// Unpack{{.Normalized.Name}}Error is the Go binding used to decode the provided
+		// error data into the corresponding Go error struct.
+		//
+		// Solidity: {{.Original.String}}
+		func (_{{$contract.Type}} *{{$contract.Type}}) Unpack{{.Normalized.Name}}Error(raw []byte) (*{{$contract.Type}}{{.Normalized.Name}}, error) {
+			errName := "{{.Original.Name}}"
+			if len(raw) < 4 || !bytes.Equal(raw[:4], _{{$contract.Type}}.abi.Errors[errName].ID.Bytes()[:4]) {
+				return nil, bind.ErrErrorSignatureMismatch
+			}
+			{{if .Normalized.Inputs -}}
+			values, err := _{{$contract.Type}}.abi.Errors[errName].Inputs.Unpack(raw[4:])
+			if err != nil {
+				return nil, err
+			}
+			{{- end}}
+			out := new({{$contract.Type}}{{.Normalized.Name}})
+			{{- range $i, $_ := .Normalized.Inputs}}
+			out.{{capitalise .Name}} = *abi.ConvertType(values[{{$i}}], new({{bindtype .Type $structs}})).(*{{bindtype .Type $structs}}){{end}}
+			return out, nil
+		}
+	{{end}}
+{{end}}
+
+{{define "method"}}
+	{{$contract := index . "contract"}}{{$structs := index . "structs"}}{{$method := index . "method"}}
+	{{with $method}}
+	// Pack{{.Normalized.Name}} is the Go binding used to pack the parameters required for calling
+	// the contract method with ID 0x{{printf "%x" .Original.ID}}. This method will panic if any
+	// invalid/nil inputs are passed.
+	//
+	// Solidity: {{.Original.String}}
+	func (_{{$contract.Type}} *{{$contract.Type}}) Pack{{.Normalized.Name}}({{range $i, $_ := .Normalized.Inputs}}{{if ne $i 0}}, {{end}}{{.Name}} {{bindtype .Type $structs}}{{end}}) []byte {
+		enc, err := _{{$contract.Type}}.abi.Pack("{{.Original.Name}}"{{range .Normalized.Inputs}}, {{.Name}}{{end}})
+		if err != nil {
+			panic(err)
+		}
+		return enc
+	}
+
+	// TryPack{{.Normalized.Name}} is the Go binding used to pack the parameters required for calling
+	// the contract method with ID 0x{{printf "%x" .Original.ID}}. This method will return an error
+	// if any inputs are invalid/nil.
+	//
+	// Solidity: {{.Original.String}}
+	func (_{{$contract.Type}} *{{$contract.Type}}) TryPack{{.Normalized.Name}}({{range $i, $_ := .Normalized.Inputs}}{{if ne $i 0}}, {{end}}{{.Name}} {{bindtype .Type $structs}}{{end}}) ([]byte, error) {
+		return _{{$contract.Type}}.abi.Pack("{{.Original.Name}}"{{range .Normalized.Inputs}}, {{.Name}}{{end}})
+	}
+
+	{{if gt (len .Normalized.Outputs) 1}}
+	// {{$contract.Type}}{{.Normalized.Name}}Output serves as a container for the return parameters of contract
+	// method {{.Normalized.Name}}.
+	type {{$contract.Type}}{{.Normalized.Name}}Output struct { {{range $i, $_ := .Normalized.Outputs}}
+		{{if $method.Structured}}{{.Name}}{{else}}Arg{{$i}}{{end}} {{bindtype .Type $structs}}; {{end}}
+	}
+
+	// Unpack{{.Normalized.Name}} is the Go binding that unpacks the parameters returned
+	// from invoking the contract method with ID 0x{{printf "%x" .Original.ID}}.
+	//
+	// Solidity: {{.Original.String}}
+	func (_{{$contract.Type}} *{{$contract.Type}}) Unpack{{.Normalized.Name}}(data []byte) ({{$contract.Type}}{{.Normalized.Name}}Output, error) {
+		out, err := _{{$contract.Type}}.abi.Unpack("{{.Original.Name}}", data)
+		outstruct := new({{$contract.Type}}{{.Normalized.Name}}Output)
+		if err != nil {
+			return *outstruct, err
+		}
+		{{- range $i, $_ := .Normalized.Outputs}}
+		outstruct.{{if $method.Structured}}{{.Name}}{{else}}Arg{{$i}}{{end}} = *abi.ConvertType(out[{{$i}}], new({{bindtype .Type $structs}})).(*{{bindtype .Type $structs}}){{end}}
+		return *outstruct, nil
+	}
+	{{else if .Normalized.Outputs}}
+	// Unpack{{.Normalized.Name}} is the Go binding that unpacks the parameters returned
+	// from invoking the contract method with ID 0x{{printf "%x" .Original.ID}}.
+	//
+	// Solidity: {{.Original.String}}
+	func (_{{$contract.Type}} *{{$contract.Type}}) Unpack{{.Normalized.Name}}(data []byte) ({{bindtype (index .Normalized.Outputs 0).Type $structs}}, error) {
+		out, err := _{{$contract.Type}}.abi.Unpack("{{.Original.Name}}", data)
+		if err != nil {
+			return *new({{bindtype (index .Normalized.Outputs 0).Type $structs}}), err
+		}
+		out0 := *abi.ConvertType(out[0], new({{bindtype (index .Normalized.Outputs 0).Type $structs}})).(*{{bindtype (index .Normalized.Outputs 0).Type $structs}})
+		return out0, nil
+	}
+	{{end}}
+	{{end}}
+{{end}}
+`
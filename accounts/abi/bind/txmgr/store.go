@@ -0,0 +1,108 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package txmgr
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// txPrefix is the database prefix of the in-flight transactions, followed by
+// the sender and the big endian nonce.
+var txPrefix = []byte("txmgr-")
+
+// storedTx is the database representation of an in-flight transaction.
+type storedTx struct {
+	Txs  [][]byte // Binary encodings of all sent versions, the latest last
+	Sent uint64   // Unix time of the last (re)submission
+}
+
+// txDBKey returns the database key of an in-flight transaction.
+func txDBKey(from common.Address, nonce uint64) []byte {
+	key := make([]byte, len(txPrefix)+common.AddressLength+8)
+	copy(key, txPrefix)
+	copy(key[len(txPrefix):], from.Bytes())
+	binary.BigEndian.PutUint64(key[len(txPrefix)+common.AddressLength:], nonce)
+	return key
+}
+
+// writeTx persists all sent versions of an in-flight transaction.
+func writeTx(db ethdb.KeyValueWriter, tx *Tx) error {
+	stored := &storedTx{Sent: uint64(tx.sent.Unix())}
+	for _, version := range tx.txs {
+		blob, err := version.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		stored.Txs = append(stored.Txs, blob)
+	}
+	blob, err := rlp.EncodeToBytes(stored)
+	if err != nil {
+		return err
+	}
+	return db.Put(txDBKey(tx.From, tx.Nonce), blob)
+}
+
+// deleteTx removes a no longer tracked transaction from the database.
+func deleteTx(db ethdb.KeyValueWriter, tx *Tx) {
+	if err := db.Delete(txDBKey(tx.From, tx.Nonce)); err != nil {
+		log.Error("Failed to delete managed transaction", "from", tx.From, "nonce", tx.Nonce, "err", err)
+	}
+}
+
+// loadTxs retrieves all the in-flight transactions from the database.
+func loadTxs(db ethdb.Iteratee) ([]*Tx, error) {
+	it := db.NewIterator(txPrefix, nil)
+	defer it.Release()
+
+	var txs []*Tx
+	for it.Next() {
+		key := it.Key()
+		if len(key) != len(txPrefix)+common.AddressLength+8 {
+			continue
+		}
+		var stored storedTx
+		if err := rlp.DecodeBytes(it.Value(), &stored); err != nil {
+			return nil, fmt.Errorf("invalid managed transaction %x: %v", key, err)
+		}
+		tx := &Tx{
+			From:  common.BytesToAddress(key[len(txPrefix) : len(txPrefix)+common.AddressLength]),
+			Nonce: binary.BigEndian.Uint64(key[len(txPrefix)+common.AddressLength:]),
+			sent:  time.Unix(int64(stored.Sent), 0),
+			res:   make(chan *Result, 1),
+		}
+		for _, blob := range stored.Txs {
+			version := new(types.Transaction)
+			if err := version.UnmarshalBinary(blob); err != nil {
+				return nil, fmt.Errorf("invalid managed transaction %x: %v", key, err)
+			}
+			tx.txs = append(tx.txs, version)
+		}
+		if len(tx.txs) == 0 {
+			return nil, fmt.Errorf("empty managed transaction %x", key)
+		}
+		txs = append(txs, tx)
+	}
+	return txs, it.Error()
+}
@@ -0,0 +1,672 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package txmgr implements a transaction manager, which assigns nonces to the
+// transactions of its senders, tracks them until they are confirmed and replaces
+// the ones stuck in the pool with fee-bumped versions.
+package txmgr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip4844"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/holiman/uint256"
+)
+
+var (
+	// ErrClosed is returned when sending through a closed manager.
+	ErrClosed = errors.New("transaction manager closed")
+
+	// ErrNonceUsed is delivered for transactions whose nonce was consumed by a
+	// transaction not sent through the manager.
+	ErrNonceUsed = errors.New("nonce used by another transaction")
+
+	// errNoSigner is returned if a transaction is sent without a signer.
+	errNoSigner = errors.New("no signer to authorize the transaction with")
+)
+
+// Backend is the chain access needed by the transaction manager.
+type Backend interface {
+	bind.ContractTransactor
+	bind.DeployBackend
+
+	// NonceAt returns the account nonce of the given account at the given block.
+	NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+}
+
+// Config are the configuration parameters of the transaction manager.
+type Config struct {
+	Confirmations    uint64        // Number of blocks (including the inclusion one) to wait before finalizing
+	ResubmitInterval time.Duration // Time without inclusion after which a transaction is replaced
+	PollInterval     time.Duration // Time interval to check the in-flight transactions at
+	FeeBump          uint64        // Percentage to bump the fees of replacements with
+	BlobFeeBump      uint64        // Percentage to bump the blob fees of blob replacements with
+	MaxGasFeeCap     *big.Int      // Maximum fee cap of replacements (nil = unlimited)
+	MaxBlobFeeCap    *big.Int      // Maximum blob fee cap of blob replacements (nil = unlimited)
+}
+
+// DefaultConfig contains the default configurations for the transaction manager.
+// The fee bumps match the minimum replacement bumps of the transaction pools.
+var DefaultConfig = Config{
+	Confirmations:    1,
+	ResubmitInterval: time.Minute,
+	PollInterval:     4 * time.Second,
+	FeeBump:          10,
+	BlobFeeBump:      100,
+}
+
+// sanitize checks the provided user configurations and changes anything that's
+// unreasonable or unworkable.
+func (config *Config) sanitize() Config {
+	conf := *config
+	if conf.Confirmations < 1 {
+		log.Warn("Sanitizing invalid txmgr confirmations", "provided", conf.Confirmations, "updated", DefaultConfig.Confirmations)
+		conf.Confirmations = DefaultConfig.Confirmations
+	}
+	if conf.ResubmitInterval <= 0 {
+		log.Warn("Sanitizing invalid txmgr resubmit interval", "provided", conf.ResubmitInterval, "updated", DefaultConfig.ResubmitInterval)
+		conf.ResubmitInterval = DefaultConfig.ResubmitInterval
+	}
+	if conf.PollInterval <= 0 {
+		log.Warn("Sanitizing invalid txmgr poll interval", "provided", conf.PollInterval, "updated", DefaultConfig.PollInterval)
+		conf.PollInterval = DefaultConfig.PollInterval
+	}
+	if conf.FeeBump < DefaultConfig.FeeBump {
+		log.Warn("Sanitizing invalid txmgr fee bump", "provided", conf.FeeBump, "updated", DefaultConfig.FeeBump)
+		conf.FeeBump = DefaultConfig.FeeBump
+	}
+	if conf.BlobFeeBump < DefaultConfig.BlobFeeBump {
+		log.Warn("Sanitizing invalid txmgr blob fee bump", "provided", conf.BlobFeeBump, "updated", DefaultConfig.BlobFeeBump)
+		conf.BlobFeeBump = DefaultConfig.BlobFeeBump
+	}
+	return conf
+}
+
+// Request is a transaction to be sent through the manager. The sender, value,
+// gas limit and initial fees are taken from the bind.TransactOpts it is sent
+// with.
+type Request struct {
+	To         *common.Address      // Recipient of the transaction, nil for contract creation
+	Data       []byte               // Calldata or deployment code of the transaction
+	AccessList types.AccessList     // Optional EIP-2930 access list
+	Sidecar    *types.BlobTxSidecar // Blobs to attach, making it a blob transaction
+}
+
+// Result is the final outcome of a transaction sent through the manager.
+type Result struct {
+	Tx      *types.Transaction // Version of the transaction which was included
+	Receipt *types.Receipt     // Receipt of the included transaction
+	Err     error              // Failure preventing the transaction from being included
+}
+
+// Tx is the handle of a transaction tracked by the manager.
+type Tx struct {
+	From  common.Address // Sender of the transaction
+	Nonce uint64         // Nonce assigned to the transaction
+
+	txs  []*types.Transaction // All sent versions of the transaction, the latest last
+	sent time.Time            // Time of the last (re)submission
+	res  chan *Result         // Channel to deliver the final outcome on
+}
+
+// Result returns the channel the final outcome of the transaction is delivered
+// on once it has been confirmed, or failed.
+func (tx *Tx) Result() <-chan *Result {
+	return tx.res
+}
+
+// txKey identifies an in-flight transaction.
+type txKey struct {
+	from  common.Address
+	nonce uint64
+}
+
+// Manager sends transactions on behalf of a set of senders, serializing their
+// nonces. In-flight transactions are persisted so they survive restarts, replaced
+// with fee-bumped versions if stuck for too long, and delivered to their handles
+// once confirmed by enough blocks.
+type Manager struct {
+	backend Backend
+	db      ethdb.KeyValueStore
+	config  Config
+
+	signers  map[common.Address]bind.SignerFn // Signers to authorize replacements with
+	locks    map[common.Address]*sync.Mutex   // Per sender locks serializing nonce assignment
+	nonces   map[common.Address]uint64        // Next nonces to assign per sender
+	inflight map[txKey]*Tx                    // Transactions waiting for confirmation
+	closed   bool
+	mu       sync.Mutex
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New creates a transaction manager, loading the in-flight transactions of a
+// previous run from the database. Replacing the loaded transactions needs their
+// senders to be registered.
+func New(backend Backend, db ethdb.KeyValueStore, config Config) (*Manager, error) {
+	m := &Manager{
+		backend:  backend,
+		db:       db,
+		config:   config.sanitize(),
+		signers:  make(map[common.Address]bind.SignerFn),
+		locks:    make(map[common.Address]*sync.Mutex),
+		nonces:   make(map[common.Address]uint64),
+		inflight: make(map[txKey]*Tx),
+		quit:     make(chan struct{}),
+	}
+	txs, err := loadTxs(db)
+	if err != nil {
+		return nil, err
+	}
+	for _, tx := range txs {
+		m.inflight[txKey{tx.From, tx.Nonce}] = tx
+	}
+	if len(txs) > 0 {
+		log.Info("Loaded in-flight transactions", "count", len(txs))
+	}
+	m.wg.Add(1)
+	go m.loop()
+	return m, nil
+}
+
+// Close stops tracking the in-flight transactions. Their outcome is not delivered
+// anymore, but they are retained in the database for the next run.
+func (m *Manager) Close() {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return
+	}
+	m.closed = true
+	m.mu.Unlock()
+
+	close(m.quit)
+	m.wg.Wait()
+}
+
+// Register sets the signer to authorize the replacements of the transactions of
+// the given sender with. Senders are registered implicitly by sending through
+// the manager, this is only needed for transactions loaded from the database.
+func (m *Manager) Register(from common.Address, signer bind.SignerFn) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.signers[from] = signer
+}
+
+// Pending returns the handles of all in-flight transactions, ordered by sender
+// and nonce.
+func (m *Manager) Pending() []*Tx {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	txs := make([]*Tx, 0, len(m.inflight))
+	for _, tx := range m.inflight {
+		txs = append(txs, tx)
+	}
+	sort.Slice(txs, func(i, j int) bool {
+		if cmp := txs[i].From.Cmp(txs[j].From); cmp != 0 {
+			return cmp < 0
+		}
+		return txs[i].Nonce < txs[j].Nonce
+	})
+	return txs
+}
+
+// Send assigns the next nonce of the sender to the requested transaction, signs
+// it and submits it to the network. The sender, signer, value, gas limit and the
+// initial fees are taken from opts, missing ones are estimated. The transaction
+// is tracked until confirmed, its outcome delivered through the returned handle.
+func (m *Manager) Send(opts *bind.TransactOpts, req *Request) (*Tx, error) {
+	if opts.Signer == nil {
+		return nil, errNoSigner
+	}
+	if req.Sidecar != nil && req.To == nil {
+		return nil, errors.New("blob transactions cannot create contracts")
+	}
+	ctx := opts.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	// Serialize the nonce assignment of the sender
+	lock := m.senderLock(opts.From)
+	lock.Lock()
+	defer lock.Unlock()
+
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil, ErrClosed
+	}
+	m.signers[opts.From] = opts.Signer
+	next := m.nonces[opts.From]
+	for key := range m.inflight {
+		if key.from == opts.From && key.nonce >= next {
+			next = key.nonce + 1
+		}
+	}
+	m.mu.Unlock()
+
+	// Skip over nonces used by transactions not sent through the manager
+	pending, err := m.backend.PendingNonceAt(ctx, opts.From)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve account nonce: %v", err)
+	}
+	if pending > next {
+		next = pending
+	}
+	tx, err := m.newTx(ctx, opts, req, next)
+	if err != nil {
+		return nil, err
+	}
+	signed, err := opts.Signer(opts.From, tx)
+	if err != nil {
+		return nil, err
+	}
+	// Persist the transaction before sending, so it's never lost on a crash
+	handle := &Tx{
+		From:  opts.From,
+		Nonce: next,
+		txs:   []*types.Transaction{signed},
+		sent:  time.Now(),
+		res:   make(chan *Result, 1),
+	}
+	if err := writeTx(m.db, handle); err != nil {
+		return nil, err
+	}
+	if err := m.backend.SendTransaction(ctx, signed); err != nil {
+		deleteTx(m.db, handle)
+		return nil, err
+	}
+	m.mu.Lock()
+	m.nonces[opts.From] = next + 1
+	m.inflight[txKey{opts.From, next}] = handle
+	m.mu.Unlock()
+
+	log.Debug("Sent managed transaction", "from", opts.From, "nonce", next, "hash", signed.Hash())
+	return handle, nil
+}
+
+// senderLock returns the lock serializing the nonce assignment of a sender.
+func (m *Manager) senderLock(from common.Address) *sync.Mutex {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	lock, ok := m.locks[from]
+	if !ok {
+		lock = new(sync.Mutex)
+		m.locks[from] = lock
+	}
+	return lock
+}
+
+// newTx creates the unsigned dynamic fee or blob transaction of a request.
+func (m *Manager) newTx(ctx context.Context, opts *bind.TransactOpts, req *Request, nonce uint64) (*types.Transaction, error) {
+	head, err := m.backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	if head.BaseFee == nil {
+		return nil, errors.New("london not activated")
+	}
+	tip := opts.GasTipCap
+	if tip == nil {
+		if tip, err = m.backend.SuggestGasTipCap(ctx); err != nil {
+			return nil, err
+		}
+	}
+	feeCap := opts.GasFeeCap
+	if feeCap == nil {
+		feeCap = new(big.Int).Add(tip, new(big.Int).Mul(head.BaseFee, big.NewInt(2)))
+	}
+	if feeCap.Cmp(tip) < 0 {
+		return nil, fmt.Errorf("maxFeePerGas (%v) < maxPriorityFeePerGas (%v)", feeCap, tip)
+	}
+	value := opts.Value
+	if value == nil {
+		value = new(big.Int)
+	}
+	var (
+		blobFeeCap *big.Int
+		blobHashes []common.Hash
+	)
+	if req.Sidecar != nil {
+		if head.ExcessBlobGas == nil {
+			return nil, errors.New("cancun not activated")
+		}
+		blobFeeCap = new(big.Int).Mul(eip4844.CalcBlobFee(*head.ExcessBlobGas), big.NewInt(2))
+		blobHashes = req.Sidecar.BlobHashes()
+	}
+	gas := opts.GasLimit
+	if gas == 0 {
+		msg := ethereum.CallMsg{
+			From:          opts.From,
+			To:            req.To,
+			GasTipCap:     tip,
+			GasFeeCap:     feeCap,
+			Value:         value,
+			Data:          req.Data,
+			AccessList:    req.AccessList,
+			BlobGasFeeCap: blobFeeCap,
+			BlobHashes:    blobHashes,
+		}
+		if gas, err = m.backend.EstimateGas(ctx, msg); err != nil {
+			return nil, fmt.Errorf("failed to estimate gas needed: %v", err)
+		}
+	}
+	if req.Sidecar != nil {
+		return types.NewTx(&types.BlobTx{
+			Nonce:      nonce,
+			GasTipCap:  uint256.MustFromBig(tip),
+			GasFeeCap:  uint256.MustFromBig(feeCap),
+			Gas:        gas,
+			To:         *req.To,
+			Value:      uint256.MustFromBig(value),
+			Data:       req.Data,
+			AccessList: req.AccessList,
+			BlobFeeCap: uint256.MustFromBig(blobFeeCap),
+			BlobHashes: blobHashes,
+			Sidecar:    req.Sidecar,
+		}), nil
+	}
+	return types.NewTx(&types.DynamicFeeTx{
+		Nonce:      nonce,
+		GasTipCap:  tip,
+		GasFeeCap:  feeCap,
+		Gas:        gas,
+		To:         req.To,
+		Value:      value,
+		Data:       req.Data,
+		AccessList: req.AccessList,
+	}), nil
+}
+
+// loop periodically checks the in-flight transactions for confirmations and
+// replaces the stuck ones.
+func (m *Manager) loop() {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(m.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.poll()
+		case <-m.quit:
+			return
+		}
+	}
+}
+
+// poll runs a single check over all the in-flight transactions.
+func (m *Manager) poll() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-m.quit:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	head, err := m.backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		log.Debug("Failed to retrieve head header", "err", err)
+		return
+	}
+	for _, tx := range m.Pending() {
+		if err := m.check(ctx, head, tx); err != nil {
+			log.Debug("Failed to check managed transaction", "from", tx.From, "nonce", tx.Nonce, "err", err)
+		}
+	}
+}
+
+// check looks up the inclusion of a transaction, delivering it once confirmed,
+// or replaces it if it was stuck for too long. Receipts are looked up anew on
+// every check, so a reorg moving or dropping the inclusion restarts the wait for
+// the confirmations.
+func (m *Manager) check(ctx context.Context, head *types.Header, tx *Tx) error {
+	for i := len(tx.txs) - 1; i >= 0; i-- {
+		receipt, err := m.backend.TransactionReceipt(ctx, tx.txs[i].Hash())
+		if errors.Is(err, ethereum.NotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		depth := head.Number.Uint64() + 1 - receipt.BlockNumber.Uint64()
+		if receipt.BlockNumber.Cmp(head.Number) > 0 || depth < m.config.Confirmations {
+			return nil
+		}
+		// Make sure the receipt isn't a leftover of a reorged block
+		header, err := m.backend.HeaderByNumber(ctx, receipt.BlockNumber)
+		if err != nil {
+			return err
+		}
+		if header.Hash() != receipt.BlockHash {
+			return nil
+		}
+		m.finalize(tx, &Result{Tx: tx.txs[i], Receipt: receipt})
+		return nil
+	}
+	// Not included, fail if the nonce was consumed by someone else deep enough
+	if head.Number.Uint64()+1 >= m.config.Confirmations {
+		number := new(big.Int).SetUint64(head.Number.Uint64() + 1 - m.config.Confirmations)
+		nonce, err := m.backend.NonceAt(ctx, tx.From, number)
+		if err != nil {
+			return err
+		}
+		if nonce > tx.Nonce {
+			m.finalize(tx, &Result{Tx: tx.txs[len(tx.txs)-1], Err: ErrNonceUsed})
+			return nil
+		}
+	}
+	if time.Since(tx.sent) < m.config.ResubmitInterval {
+		return nil
+	}
+	return m.resubmit(ctx, head, tx)
+}
+
+// resubmit replaces a stuck transaction with a fee-bumped version, or resends it
+// as is if the fee caps don't allow a bump.
+func (m *Manager) resubmit(ctx context.Context, head *types.Header, tx *Tx) error {
+	m.mu.Lock()
+	signer := m.signers[tx.From]
+	m.mu.Unlock()
+
+	prev := tx.txs[len(tx.txs)-1]
+	if signer == nil {
+		log.Warn("Stuck transaction of unregistered sender", "from", tx.From, "nonce", tx.Nonce)
+		tx.sent = time.Now()
+		return m.backend.SendTransaction(ctx, prev)
+	}
+	replacement, err := m.bump(ctx, head, prev)
+	if err != nil {
+		return err
+	}
+	if replacement == nil {
+		log.Warn("Stuck transaction at fee cap", "from", tx.From, "nonce", tx.Nonce, "hash", prev.Hash())
+		tx.sent = time.Now()
+		err := m.backend.SendTransaction(ctx, prev)
+		if err != nil && isKnown(err) {
+			return nil
+		}
+		return err
+	}
+	signed, err := signer(tx.From, replacement)
+	if err != nil {
+		return err
+	}
+	tx.txs = append(tx.txs, signed)
+	tx.sent = time.Now()
+	if err := writeTx(m.db, tx); err != nil {
+		return err
+	}
+	log.Info("Replacing stuck transaction", "from", tx.From, "nonce", tx.Nonce, "hash", signed.Hash(),
+		"tip", signed.GasTipCap(), "feecap", signed.GasFeeCap())
+	// An underpriced replacement is a failure, it must not be mistaken for the
+	// replacement being known already.
+	if err := m.backend.SendTransaction(ctx, signed); err != nil && !isSent(err) {
+		return err
+	}
+	return nil
+}
+
+// bump creates the unsigned replacement of a transaction, with its fees bumped
+// by the configured percentages, or up to the current market price if higher.
+// Nil is returned if the fee caps don't allow the minimum bump.
+//
+// The blob pool requires blob replacements to bump all of their fees, including
+// the execution ones, by its larger price bump, so the blob fee bump is applied
+// to all fees of blob transactions.
+func (m *Manager) bump(ctx context.Context, head *types.Header, prev *types.Transaction) (*types.Transaction, error) {
+	suggested, err := m.backend.SuggestGasTipCap(ctx)
+	if err != nil {
+		return nil, err
+	}
+	feeBump := m.config.FeeBump
+	if prev.Type() == types.BlobTxType {
+		feeBump = m.config.BlobFeeBump
+	}
+	var (
+		minTip    = bumpFee(prev.GasTipCap(), feeBump)
+		minFeeCap = bumpFee(prev.GasFeeCap(), feeBump)
+
+		tip    = bigMax(minTip, suggested)
+		feeCap = bigMax(minFeeCap, new(big.Int).Add(tip, new(big.Int).Mul(head.BaseFee, big.NewInt(2))))
+	)
+	if m.config.MaxGasFeeCap != nil && feeCap.Cmp(m.config.MaxGasFeeCap) > 0 {
+		feeCap = new(big.Int).Set(m.config.MaxGasFeeCap)
+		if tip.Cmp(feeCap) > 0 {
+			tip = new(big.Int).Set(feeCap)
+		}
+		if feeCap.Cmp(minFeeCap) < 0 || tip.Cmp(minTip) < 0 {
+			return nil, nil
+		}
+	}
+	if prev.Type() != types.BlobTxType {
+		return types.NewTx(&types.DynamicFeeTx{
+			ChainID:    prev.ChainId(),
+			Nonce:      prev.Nonce(),
+			GasTipCap:  tip,
+			GasFeeCap:  feeCap,
+			Gas:        prev.Gas(),
+			To:         prev.To(),
+			Value:      prev.Value(),
+			Data:       prev.Data(),
+			AccessList: prev.AccessList(),
+		}), nil
+	}
+	minBlobFeeCap := bumpFee(prev.BlobGasFeeCap(), m.config.BlobFeeBump)
+	blobFeeCap := minBlobFeeCap
+	if head.ExcessBlobGas != nil {
+		blobFeeCap = bigMax(minBlobFeeCap, new(big.Int).Mul(eip4844.CalcBlobFee(*head.ExcessBlobGas), big.NewInt(2)))
+	}
+	if m.config.MaxBlobFeeCap != nil && blobFeeCap.Cmp(m.config.MaxBlobFeeCap) > 0 {
+		blobFeeCap = new(big.Int).Set(m.config.MaxBlobFeeCap)
+		if blobFeeCap.Cmp(minBlobFeeCap) < 0 {
+			return nil, nil
+		}
+	}
+	return types.NewTx(&types.BlobTx{
+		ChainID:    uint256.MustFromBig(prev.ChainId()),
+		Nonce:      prev.Nonce(),
+		GasTipCap:  uint256.MustFromBig(tip),
+		GasFeeCap:  uint256.MustFromBig(feeCap),
+		Gas:        prev.Gas(),
+		To:         *prev.To(),
+		Value:      uint256.MustFromBig(prev.Value()),
+		Data:       prev.Data(),
+		AccessList: prev.AccessList(),
+		BlobFeeCap: uint256.MustFromBig(blobFeeCap),
+		BlobHashes: prev.BlobHashes(),
+		Sidecar:    prev.BlobTxSidecar(),
+	}), nil
+}
+
+// finalize stops tracking a transaction and delivers its outcome.
+func (m *Manager) finalize(tx *Tx, res *Result) {
+	m.mu.Lock()
+	delete(m.inflight, txKey{tx.From, tx.Nonce})
+	m.mu.Unlock()
+
+	deleteTx(m.db, tx)
+	if res.Err != nil {
+		log.Warn("Managed transaction failed", "from", tx.From, "nonce", tx.Nonce, "err", res.Err)
+	} else {
+		log.Debug("Managed transaction confirmed", "from", tx.From, "nonce", tx.Nonce, "hash", res.Tx.Hash(), "block", res.Receipt.BlockNumber)
+	}
+	tx.res <- res
+}
+
+// bumpFee increases a fee by the given percentage, by at least one wei so that
+// replacements are always strictly more expensive.
+func bumpFee(fee *big.Int, percent uint64) *big.Int {
+	bumped := new(big.Int).Mul(fee, new(big.Int).SetUint64(100+percent))
+	bumped.Div(bumped, big.NewInt(100))
+	if bumped.Cmp(fee) <= 0 {
+		bumped.Add(fee, common.Big1)
+	}
+	return bumped
+}
+
+// bigMax returns the larger of two big integers.
+func bigMax(a, b *big.Int) *big.Int {
+	if a.Cmp(b) >= 0 {
+		return a
+	}
+	return b
+}
+
+// isKnown reports whether a send failed because the pool already contains the
+// transaction, or a replacement for it.
+func isKnown(err error) bool {
+	return isError(err, txpool.ErrAlreadyKnown, txpool.ErrReplaceUnderpriced, core.ErrNonceTooLow)
+}
+
+// isSent reports whether a send of a replacement failed because the pool already
+// contains it, or the nonce was already included.
+func isSent(err error) bool {
+	return isError(err, txpool.ErrAlreadyKnown, core.ErrNonceTooLow)
+}
+
+// isError reports whether the error is one of the targets, which may have been
+// flattened into a string by the RPC layer.
+func isError(err error, targets ...error) bool {
+	for _, target := range targets {
+		if errors.Is(err, target) || strings.HasPrefix(err.Error(), target.Error()) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,422 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package txmgr
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	emptyBlob          = kzg4844.Blob{}
+	emptyBlobCommit, _ = kzg4844.BlobToCommitment(emptyBlob)
+	emptyBlobProof, _  = kzg4844.ComputeBlobProof(emptyBlob, emptyBlobCommit)
+)
+
+var (
+	testKey, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testAddr   = crypto.PubkeyToAddress(testKey.PublicKey)
+)
+
+// testConfig is a configuration reacting fast enough for tests.
+var testConfig = Config{
+	Confirmations:    1,
+	ResubmitInterval: time.Hour,
+	PollInterval:     10 * time.Millisecond,
+}
+
+func newTestBackend(t *testing.T, options ...func(*node.Config, *ethconfig.Config)) *simulated.Backend {
+	sim := simulated.NewBackend(types.GenesisAlloc{
+		testAddr: {Balance: new(big.Int).Mul(big.NewInt(1000), big.NewInt(params.Ether))},
+	}, options...)
+	t.Cleanup(func() { sim.Close() })
+	return sim
+}
+
+// withCancun is a simulated backend option activating Cancun at genesis, so blob
+// transactions are accepted.
+func withCancun(nodeConf *node.Config, ethConf *ethconfig.Config) {
+	config := *ethConf.Genesis.Config
+	config.CancunTime = new(uint64)
+	ethConf.Genesis.Config = &config
+}
+
+func newTestOpts(t *testing.T) *bind.TransactOpts {
+	opts, err := bind.NewKeyedTransactorWithChainID(testKey, big.NewInt(1337))
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts.Value = big.NewInt(1)
+	return opts
+}
+
+// droppingBackend is a backend silently dropping the first sent transactions,
+// as if they were lost on the way to the network, counting the sends the pool
+// rejected and tracking the last accepted one.
+type droppingBackend struct {
+	simulated.Client
+	drop     atomic.Int32
+	sent     atomic.Int32
+	rejected atomic.Int32
+	last     atomic.Pointer[types.Transaction]
+}
+
+func (b *droppingBackend) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	defer b.sent.Add(1)
+
+	if b.drop.Add(-1) >= 0 {
+		b.last.Store(tx)
+		return nil
+	}
+	if err := b.Client.SendTransaction(ctx, tx); err != nil {
+		b.rejected.Add(1)
+		return err
+	}
+	b.last.Store(tx)
+	return nil
+}
+
+// TransactionReceipt reports receipts as missing until the transaction indexer
+// has started, which it only does once the first block is mined.
+func (b *droppingBackend) TransactionReceipt(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+	receipt, err := b.Client.TransactionReceipt(ctx, hash)
+	if err != nil && strings.Contains(err.Error(), "transaction indexing is in progress") {
+		return nil, ethereum.NotFound
+	}
+	return receipt, err
+}
+
+// waitResult waits for the outcome of a managed transaction, mining a block at
+// every poll if requested.
+func waitResult(t *testing.T, sim *simulated.Backend, tx *Tx, mine bool) *Result {
+	t.Helper()
+
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case res := <-tx.Result():
+			return res
+		case <-time.After(50 * time.Millisecond):
+			if mine {
+				sim.Commit()
+			}
+		case <-timeout:
+			t.Fatalf("Transaction %d not finalized", tx.Nonce)
+			return nil
+		}
+	}
+}
+
+// Tests that concurrently sent transactions get consecutive nonces and are only
+// delivered once confirmed by enough blocks.
+func TestSendConfirm(t *testing.T) {
+	sim := newTestBackend(t)
+
+	config := testConfig
+	config.Confirmations = 2
+	db := memorydb.New()
+	mgr, err := New(sim.Client(), db, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mgr.Close()
+
+	var (
+		txs  = make([]*Tx, 4)
+		wg   sync.WaitGroup
+		opts = newTestOpts(t)
+	)
+	for i := range txs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			to := common.Address{byte(i + 1)}
+			tx, err := mgr.Send(opts, &Request{To: &to})
+			if err != nil {
+				t.Errorf("Failed to send transaction %d: %v", i, err)
+			}
+			txs[i] = tx
+		}(i)
+	}
+	wg.Wait()
+	if t.Failed() {
+		return
+	}
+	nonces := make(map[uint64]bool)
+	for _, tx := range txs {
+		nonces[tx.Nonce] = true
+	}
+	for i := range txs {
+		if !nonces[uint64(i)] {
+			t.Fatalf("Nonce %d not assigned: %v", i, nonces)
+		}
+	}
+	if pending := mgr.Pending(); len(pending) != len(txs) {
+		t.Fatalf("Pending transaction count mismatch: have %d, want %d", len(pending), len(txs))
+	}
+	// Include the transactions, they must not be delivered before confirmed
+	sim.Commit()
+	select {
+	case <-txs[0].Result():
+		t.Fatal("Transaction delivered before confirmation")
+	case <-time.After(100 * time.Millisecond):
+	}
+	sim.Commit()
+	for _, tx := range txs {
+		res := waitResult(t, sim, tx, false)
+		if res.Err != nil {
+			t.Fatalf("Transaction %d failed: %v", tx.Nonce, res.Err)
+		}
+		if res.Receipt.Status != types.ReceiptStatusSuccessful || res.Receipt.BlockNumber.Uint64() != 1 {
+			t.Errorf("Transaction %d receipt mismatch: status %d, block %d", tx.Nonce, res.Receipt.Status, res.Receipt.BlockNumber)
+		}
+	}
+	if pending := mgr.Pending(); len(pending) != 0 {
+		t.Errorf("Confirmed transactions still pending: %d", len(pending))
+	}
+	if txs, _ := loadTxs(db); len(txs) != 0 {
+		t.Errorf("Confirmed transactions still persisted: %d", len(txs))
+	}
+}
+
+// Tests that transactions not included in time are replaced with fee-bumped ones.
+func TestResubmit(t *testing.T) {
+	t.Run("dynamic", func(t *testing.T) { testResubmit(t, false) })
+	t.Run("blob", func(t *testing.T) { testResubmit(t, true) })
+}
+
+func testResubmit(t *testing.T, blob bool) {
+	var sim *simulated.Backend
+	if blob {
+		sim = newTestBackend(t, withCancun)
+	} else {
+		sim = newTestBackend(t)
+	}
+	backend := &droppingBackend{Client: sim.Client()}
+
+	// Plain transactions are dropped to get stuck. Blob transactions are kept in
+	// the pool, so the replacement has to satisfy the blob pool's price bump.
+	req := &Request{To: &common.Address{1}}
+	if blob {
+		req.Sidecar = &types.BlobTxSidecar{
+			Blobs:       []kzg4844.Blob{emptyBlob},
+			Commitments: []kzg4844.Commitment{emptyBlobCommit},
+			Proofs:      []kzg4844.Proof{emptyBlobProof},
+		}
+	} else {
+		backend.drop.Store(1)
+	}
+	config := testConfig
+	config.ResubmitInterval = 100 * time.Millisecond
+	mgr, err := New(backend, memorydb.New(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mgr.Close()
+
+	tx, err := mgr.Send(newTestOpts(t), req)
+	if err != nil {
+		t.Fatalf("Failed to send transaction: %v", err)
+	}
+	orig := backend.last.Load()
+
+	// Wait for the replacement to be sent before mining anything
+	for start := time.Now(); backend.sent.Load() < 2; time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatal("Transaction not resubmitted")
+		}
+	}
+	if rejected := backend.rejected.Load(); rejected != 0 {
+		t.Fatalf("Pool rejected %d sends", rejected)
+	}
+	var replacement *types.Transaction
+	if blob {
+		// The simulated beacon cannot seal Cancun blocks, so check that the pool
+		// swapped the original for the replacement instead of mining it. Stop
+		// the manager first so no further replacement races the lookup.
+		mgr.Close()
+		replacement = backend.last.Load()
+		if _, pending, err := sim.Client().TransactionByHash(context.Background(), replacement.Hash()); err != nil || !pending {
+			t.Fatalf("Replacement not pending: pending %v, err %v", pending, err)
+		}
+	} else {
+		res := waitResult(t, sim, tx, true)
+		if res.Err != nil {
+			t.Fatalf("Transaction failed: %v", res.Err)
+		}
+		replacement = res.Tx
+	}
+	if replacement.Hash() == orig.Hash() {
+		t.Fatal("Original transaction kept")
+	}
+	bump := config.FeeBump
+	if blob {
+		bump = DefaultConfig.BlobFeeBump
+		if replacement.BlobGasFeeCap().Cmp(bumpFee(orig.BlobGasFeeCap(), bump)) < 0 {
+			t.Errorf("Replacement blob fee not bumped: %v -> %v", orig.BlobGasFeeCap(), replacement.BlobGasFeeCap())
+		}
+	}
+	if replacement.GasFeeCap().Cmp(bumpFee(orig.GasFeeCap(), bump)) < 0 ||
+		replacement.GasTipCap().Cmp(bumpFee(orig.GasTipCap(), bump)) < 0 {
+		t.Errorf("Replacement fees not bumped: tip %v -> %v, cap %v -> %v", orig.GasTipCap(), replacement.GasTipCap(), orig.GasFeeCap(), replacement.GasFeeCap())
+	}
+	if replacement.Nonce() != orig.Nonce() || *replacement.To() != *req.To || replacement.Value().Cmp(orig.Value()) != 0 {
+		t.Errorf("Replacement content mismatch")
+	}
+}
+
+// Tests that replacements are not bumped beyond the configured fee cap.
+func TestBumpCap(t *testing.T) {
+	sim := newTestBackend(t)
+	head, _ := sim.Client().HeaderByNumber(context.Background(), nil)
+
+	prev := types.NewTx(&types.DynamicFeeTx{
+		GasTipCap: big.NewInt(params.GWei),
+		GasFeeCap: new(big.Int).Add(big.NewInt(params.GWei), new(big.Int).Mul(head.BaseFee, big.NewInt(2))),
+		Gas:       21000,
+		To:        &common.Address{},
+	})
+	config := testConfig
+	config.MaxGasFeeCap = new(big.Int).Set(prev.GasFeeCap())
+	mgr := &Manager{backend: sim.Client(), config: config.sanitize()}
+
+	if tx, err := mgr.bump(context.Background(), head, prev); err != nil || tx != nil {
+		t.Fatalf("Replacement above fee cap: %v, %v", tx, err)
+	}
+	mgr.config.MaxGasFeeCap = nil
+	tx, err := mgr.bump(context.Background(), head, prev)
+	if err != nil || tx == nil {
+		t.Fatalf("Failed to bump fees: %v", err)
+	}
+	if want := bumpFee(prev.GasFeeCap(), 10); tx.GasFeeCap().Cmp(want) < 0 {
+		t.Errorf("Fee cap mismatch: have %v, want at least %v", tx.GasFeeCap(), want)
+	}
+}
+
+// Tests that in-flight transactions are resumed after a restart.
+func TestPersistence(t *testing.T) {
+	sim := newTestBackend(t)
+	db := memorydb.New()
+
+	// Send a transaction which gets lost, then shut the manager down
+	backend := &droppingBackend{Client: sim.Client()}
+	backend.drop.Store(1)
+
+	mgr, err := New(backend, db, testConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	to := common.Address{1}
+	if _, err := mgr.Send(newTestOpts(t), &Request{To: &to}); err != nil {
+		t.Fatalf("Failed to send transaction: %v", err)
+	}
+	mgr.Close()
+	if _, err := mgr.Send(newTestOpts(t), &Request{To: &to}); err != ErrClosed {
+		t.Fatalf("Closed manager error mismatch: have %v, want %v", err, ErrClosed)
+	}
+	// Restart the manager, the stuck transaction must be replaced
+	config := testConfig
+	config.ResubmitInterval = 100 * time.Millisecond
+	mgr, err = New(sim.Client(), db, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mgr.Close()
+
+	pending := mgr.Pending()
+	if len(pending) != 1 || pending[0].From != testAddr || pending[0].Nonce != 0 {
+		t.Fatalf("Loaded transactions mismatch: %v", pending)
+	}
+	mgr.Register(testAddr, newTestOpts(t).Signer)
+
+	res := waitResult(t, sim, pending[0], true)
+	if res.Err != nil {
+		t.Fatalf("Transaction failed: %v", res.Err)
+	}
+	if res.Tx.Nonce() != 0 || len(pending[0].txs) != 2 {
+		t.Errorf("Replacement mismatch: nonce %d, versions %d", res.Tx.Nonce(), len(pending[0].txs))
+	}
+}
+
+// Tests that the confirmations are counted anew if the including block is
+// reorged out.
+func TestReorg(t *testing.T) {
+	sim := newTestBackend(t)
+	client := sim.Client()
+
+	config := testConfig
+	config.Confirmations = 2
+	config.ResubmitInterval = 200 * time.Millisecond
+	mgr, err := New(client, memorydb.New(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mgr.Close()
+
+	genesis, _ := client.HeaderByNumber(context.Background(), big.NewInt(0))
+
+	to := common.Address{1}
+	tx, err := mgr.Send(newTestOpts(t), &Request{To: &to})
+	if err != nil {
+		t.Fatalf("Failed to send transaction: %v", err)
+	}
+	orphan := sim.Commit()
+
+	// Reorg the including block out, the transaction is resubmitted on the fork
+	time.Sleep(50 * time.Millisecond)
+	if err := sim.Fork(genesis.Hash()); err != nil {
+		t.Fatalf("Failed to fork: %v", err)
+	}
+	sim.Commit()
+	select {
+	case <-tx.Result():
+		t.Fatal("Transaction delivered before confirmation")
+	case <-time.After(100 * time.Millisecond):
+	}
+	res := waitResult(t, sim, tx, true)
+	if res.Err != nil {
+		t.Fatalf("Transaction failed: %v", res.Err)
+	}
+	if res.Receipt.BlockHash == orphan {
+		t.Fatal("Transaction confirmed in reorged block")
+	}
+	header, err := client.HeaderByNumber(context.Background(), res.Receipt.BlockNumber)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header.Hash() != res.Receipt.BlockHash {
+		t.Errorf("Transaction confirmed in non-canonical block")
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bind
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// DeploymentParams contains the contracts to deploy, along with their inputs.
+type DeploymentParams struct {
+	// Contracts to deploy. Their library dependencies are deployed and linked
+	// first, each library only once.
+	Contracts []*MetaData
+
+	// Inputs holds the packed constructor input of the contracts. Contracts
+	// missing from the map are deployed without constructor input.
+	Inputs map[*MetaData][]byte
+
+	// Overrides holds the addresses of libraries which are already deployed.
+	// These are linked against as is, instead of being deployed again.
+	Overrides map[*MetaData]common.Address
+}
+
+// DeploymentResult contains the outcome of LinkAndDeploy.
+type DeploymentResult struct {
+	Txs       map[*MetaData]*types.Transaction // Deployment transactions of the contracts
+	Addresses map[*MetaData]common.Address     // Addresses of the deployed contracts
+}
+
+// DeployFn deploys a contract with the given linked deployer bytecode and packed
+// constructor input, returning its address and the deployment transaction.
+type DeployFn func(input, bytecode []byte) (common.Address, *types.Transaction, error)
+
+// DefaultDeployer returns a DeployFn which sends the deployment transactions
+// through the backend with the given transaction options.
+func DefaultDeployer(opts *TransactOpts, backend ContractBackend) DeployFn {
+	return func(input, bytecode []byte) (common.Address, *types.Transaction, error) {
+		return DeployContract(opts, bytecode, backend, input)
+	}
+}
+
+// depTreeDeployer links and deploys the contracts of a deployment, tracking the
+// already deployed ones.
+type depTreeDeployer struct {
+	deploy    DeployFn
+	inputs    map[*MetaData][]byte
+	txs       map[*MetaData]*types.Transaction
+	addresses map[*MetaData]common.Address
+}
+
+// linkAndDeploy deploys the contract after recursively deploying its library
+// dependencies and linking their addresses into its bytecode.
+func (d *depTreeDeployer) linkAndDeploy(contract *MetaData) (common.Address, error) {
+	if addr, ok := d.addresses[contract]; ok {
+		return addr, nil
+	}
+	bin := strings.TrimPrefix(contract.Bin, "0x")
+	for _, dep := range contract.Deps {
+		addr, err := d.linkAndDeploy(dep)
+		if err != nil {
+			return common.Address{}, err
+		}
+		bin = strings.ReplaceAll(bin, "__$"+dep.Pattern+"$__", hex.EncodeToString(addr.Bytes()))
+	}
+	code, err := hex.DecodeString(bin)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("invalid or unlinked bytecode: %v", err)
+	}
+	addr, tx, err := d.deploy(d.inputs[contract], code)
+	if err != nil {
+		return common.Address{}, err
+	}
+	d.txs[contract] = tx
+	d.addresses[contract] = addr
+	return addr, nil
+}
+
+// LinkAndDeploy deploys the contracts of the deployment along with all their
+// library dependencies, linking the library addresses into the bytecode of the
+// contracts using them. The result contains the contracts deployed before an
+// error was encountered.
+func LinkAndDeploy(params *DeploymentParams, deploy DeployFn) (*DeploymentResult, error) {
+	d := &depTreeDeployer{
+		deploy:    deploy,
+		inputs:    params.Inputs,
+		txs:       make(map[*MetaData]*types.Transaction),
+		addresses: make(map[*MetaData]common.Address),
+	}
+	for contract, addr := range params.Overrides {
+		d.addresses[contract] = addr
+	}
+	result := &DeploymentResult{Txs: d.txs, Addresses: d.addresses}
+	for _, contract := range params.Contracts {
+		if _, err := d.linkAndDeploy(contract); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
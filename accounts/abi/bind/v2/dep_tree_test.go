@@ -0,0 +1,105 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bind
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// placeholder returns the link placeholder of a library in a bytecode.
+func placeholder(pattern string) string {
+	return "__$" + pattern + "$__"
+}
+
+// Tests that libraries are deployed before the contracts depending on them,
+// each only once, and their addresses are linked into the dependent bytecode.
+func TestLinkAndDeploy(t *testing.T) {
+	var (
+		patternA = "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+		patternB = "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+		patternC = "cccccccccccccccccccccccccccccccccc"
+
+		// Library C is pre-deployed, A depends on B and C, the contract on A and B
+		libC     = &MetaData{Pattern: patternC, Bin: "0x0c"}
+		libB     = &MetaData{Pattern: patternB, Bin: "0x0b"}
+		libA     = &MetaData{Pattern: patternA, Bin: "0x0a" + placeholder(patternB) + placeholder(patternC), Deps: []*MetaData{libB, libC}}
+		contract = &MetaData{Bin: "0x01" + placeholder(patternA) + placeholder(patternB), Deps: []*MetaData{libA, libB}}
+
+		addrC = common.Address{0xc}
+	)
+	var (
+		deployed [][]byte
+		inputs   [][]byte
+	)
+	deploy := func(input, bytecode []byte) (common.Address, *types.Transaction, error) {
+		deployed = append(deployed, bytecode)
+		inputs = append(inputs, input)
+		return common.Address{byte(len(deployed))}, new(types.Transaction), nil
+	}
+	params := &DeploymentParams{
+		Contracts: []*MetaData{contract},
+		Inputs:    map[*MetaData][]byte{contract: {0xff}},
+		Overrides: map[*MetaData]common.Address{libC: addrC},
+	}
+	res, err := LinkAndDeploy(params, deploy)
+	if err != nil {
+		t.Fatalf("Failed to deploy: %v", err)
+	}
+	// Libraries are deployed depth first, the overridden one is skipped
+	addrB, addrA, addr := common.Address{1}, common.Address{2}, common.Address{3}
+	want := [][]byte{
+		{0x0b},
+		append(append([]byte{0x0a}, addrB.Bytes()...), addrC.Bytes()...),
+		append(append([]byte{0x01}, addrA.Bytes()...), addrB.Bytes()...),
+	}
+	if len(deployed) != len(want) {
+		t.Fatalf("Deployment count mismatch: have %d, want %d", len(deployed), len(want))
+	}
+	for i := range want {
+		if !bytes.Equal(deployed[i], want[i]) {
+			t.Errorf("Deployment %d bytecode mismatch: have %x, want %x", i, deployed[i], want[i])
+		}
+	}
+	if inputs[0] != nil || inputs[1] != nil || !bytes.Equal(inputs[2], []byte{0xff}) {
+		t.Errorf("Constructor input mismatch: %x", inputs)
+	}
+	for lib, want := range map[*MetaData]common.Address{libA: addrA, libB: addrB, libC: addrC, contract: addr} {
+		if have := res.Addresses[lib]; have != want {
+			t.Errorf("Address mismatch for %s: have %x, want %x", lib.Bin[:4], have, want)
+		}
+	}
+	if len(res.Txs) != 3 || res.Txs[libC] != nil {
+		t.Errorf("Deployment transactions mismatch: %v", res.Txs)
+	}
+}
+
+// Tests that a bytecode with unresolved placeholders is rejected.
+func TestLinkAndDeployUnlinked(t *testing.T) {
+	contract := &MetaData{Bin: "0x01" + placeholder(hex.EncodeToString(make([]byte, 17)))}
+	deploy := func(input, bytecode []byte) (common.Address, *types.Transaction, error) {
+		t.Fatal("Unlinked bytecode deployed")
+		return common.Address{}, nil, nil
+	}
+	if _, err := LinkAndDeploy(&DeploymentParams{Contracts: []*MetaData{contract}}, deploy); err == nil {
+		t.Fatal("Unlinked bytecode accepted")
+	}
+}
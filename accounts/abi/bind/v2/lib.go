@@ -0,0 +1,274 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package bind is the runtime of the stateless contract bindings generated by
+// abigen --v2.
+//
+// The generated code only packs and unpacks contract data, all interaction with
+// the chain is done through the generic functions of this package, which allows
+// the packed calldata to be batched, signed offline or sent through any backend.
+package bind
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	bind1 "github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+)
+
+// The contract interaction primitives are shared with the original bindings.
+type (
+	BoundContract   = bind1.BoundContract
+	CallOpts        = bind1.CallOpts
+	TransactOpts    = bind1.TransactOpts
+	FilterOpts      = bind1.FilterOpts
+	WatchOpts       = bind1.WatchOpts
+	ContractBackend = bind1.ContractBackend
+	DeployBackend   = bind1.DeployBackend
+)
+
+// The typed revert errors are shared with the original bindings.
+type (
+	RevertError  = bind1.RevertError
+	PanicError   = bind1.PanicError
+	CustomError  = bind1.CustomError
+	ErrorDecoder = bind1.ErrorDecoder
+)
+
+// The batching of contract reads is shared with the original bindings.
+type (
+	Batch       = bind1.Batch
+	BatchCall   = bind1.BatchCall
+	BatchCaller = bind1.BatchCaller
+)
+
+// Multicall3Address is the address of the Multicall3 contract, deployed at the
+// same address on most EVM chains.
+var Multicall3Address = bind1.Multicall3Address
+
+var (
+	// ErrEventSignatureMismatch is returned when unpacking a log which was not
+	// emitted by the expected event.
+	ErrEventSignatureMismatch = errors.New("event signature mismatch")
+
+	// ErrErrorSignatureMismatch is returned when unpacking revert data which was
+	// not produced by the expected error.
+	ErrErrorSignatureMismatch = errors.New("error signature mismatch")
+)
+
+// NewBoundContract creates a low level contract interface through which calls,
+// transactions and event filters may be made through.
+func NewBoundContract(address common.Address, abi abi.ABI, backend ContractBackend) *BoundContract {
+	return bind1.NewBoundContract(address, abi, backend, backend, backend)
+}
+
+// MetaData collects all metadata for a bound contract.
+type MetaData struct {
+	ABI     string      // JSON ABI of the contract
+	Bin     string      // Deployer bytecode, containing the link placeholders of the dependencies
+	Pattern string      // Link pattern of the contract, if it is used as a library
+	Deps    []*MetaData // Libraries the contract needs to be linked against
+}
+
+// ParseABI parses the JSON ABI of the contract.
+func (m *MetaData) ParseABI() (*abi.ABI, error) {
+	parsed, err := abi.JSON(strings.NewReader(m.ABI))
+	if err != nil {
+		return nil, err
+	}
+	return &parsed, nil
+}
+
+// ContractEvent is implemented by the event types of the generated bindings.
+type ContractEvent interface {
+	ContractEventName() string
+}
+
+// Call invokes a constant contract method with the packed calldata and unpacks
+// the returned data with the given function.
+func Call[T any](c *BoundContract, opts *CallOpts, calldata []byte, unpack func([]byte) (T, error)) (T, error) {
+	var defaultResult T
+	packed, err := c.CallRaw(opts, calldata)
+	if err != nil {
+		return defaultResult, err
+	}
+	return unpack(packed)
+}
+
+// BatchResult is the typed outcome of a contract call queued in a Batch.
+type BatchResult[T any] struct {
+	call  *BatchCall
+	value T
+}
+
+// Value returns the unpacked result of the call, or its failure. It is only
+// available after the batch has been executed.
+func (r *BatchResult[T]) Value() (T, error) {
+	if err := r.call.Err(); err != nil {
+		var defaultResult T
+		return defaultResult, err
+	}
+	return r.value, nil
+}
+
+// AddBatchCall queues a constant contract method invocation with the packed
+// calldata into the batch, unpacking the returned data with the given function
+// after the batch has been executed.
+func AddBatchCall[T any](b *Batch, c *BoundContract, calldata []byte, unpack func([]byte) (T, error)) *BatchResult[T] {
+	result := new(BatchResult[T])
+	result.call = b.AddRaw(c, calldata, func(output []byte) error {
+		value, err := unpack(output)
+		if err != nil {
+			return err
+		}
+		result.value = value
+		return nil
+	})
+	return result
+}
+
+// Transact sends a transaction to the contract with the packed calldata.
+func Transact(c *BoundContract, opts *TransactOpts, calldata []byte) (*types.Transaction, error) {
+	return c.RawTransact(opts, calldata)
+}
+
+// DeployContract deploys a contract with the given deployer bytecode and packed
+// constructor input, returning the address the contract will be deployed at.
+func DeployContract(opts *TransactOpts, bytecode []byte, backend ContractBackend, constructorInput []byte) (common.Address, *types.Transaction, error) {
+	addr, tx, _, err := bind1.DeployContractRaw(opts, bytecode, backend, constructorInput)
+	if err != nil {
+		return common.Address{}, nil, err
+	}
+	return addr, tx, nil
+}
+
+// FilterEvents retrieves the past events of the given type emitted by the
+// contract, matching the topic filters of the indexed event fields.
+func FilterEvents[Ev ContractEvent](c *BoundContract, opts *FilterOpts, unpack func(*types.Log) (*Ev, error), topics ...[]any) (*EventIterator[Ev], error) {
+	var e Ev
+	logs, sub, err := c.FilterLogs(opts, e.ContractEventName(), topics...)
+	if err != nil {
+		return nil, err
+	}
+	return &EventIterator[Ev]{unpack: unpack, logs: logs, sub: sub}, nil
+}
+
+// WatchEvents subscribes to the future events of the given type emitted by the
+// contract, matching the topic filters of the indexed event fields. The events
+// are unpacked and delivered into the sink until the subscription is torn down.
+func WatchEvents[Ev ContractEvent](c *BoundContract, opts *WatchOpts, unpack func(*types.Log) (*Ev, error), sink chan<- *Ev, topics ...[]any) (event.Subscription, error) {
+	var e Ev
+	logs, sub, err := c.WatchLogs(opts, e.ContractEventName(), topics...)
+	if err != nil {
+		return nil, err
+	}
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case log := <-logs:
+				// New log arrived, parse the event and forward to the user
+				ev, err := unpack(&log)
+				if err != nil {
+					return err
+				}
+				select {
+				case sink <- ev:
+				case err := <-sub.Err():
+					return err
+				case <-quit:
+					return nil
+				}
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
+
+// EventIterator is returned from FilterEvents and is used to iterate over the
+// unpacked events.
+type EventIterator[T any] struct {
+	current *T                           // Event most recently unpacked by Next
+	unpack  func(*types.Log) (*T, error) // Unpacker for the raw logs
+	logs    chan types.Log               // Log channel receiving the found contract events
+	sub     ethereum.Subscription        // Subscription for errors, completion and termination
+	done    bool                         // Whether the subscription completed delivering logs
+	fail    error                        // Occurred error to stop iteration
+}
+
+// Value returns the current event of the iterator.
+func (it *EventIterator[T]) Value() *T {
+	return it.current
+}
+
+// Next advances the iterator to the subsequent event, returning whether there
+// are any more events found. In case of a retrieval or parsing error, false is
+// returned and Error() can be queried for the exact failure.
+func (it *EventIterator[T]) Next() bool {
+	// If the iterator failed, stop iterating
+	if it.fail != nil {
+		return false
+	}
+	// If the iterator completed, deliver directly whatever's available
+	if it.done {
+		select {
+		case log := <-it.logs:
+			return it.unpackLog(log)
+		default:
+			return false
+		}
+	}
+	// Iterator still in progress, wait for either a data or an error event
+	select {
+	case log := <-it.logs:
+		return it.unpackLog(log)
+	case err := <-it.sub.Err():
+		it.done = true
+		it.fail = err
+		return it.Next()
+	}
+}
+
+// unpackLog unpacks the log into the current event, recording any failure.
+func (it *EventIterator[T]) unpackLog(log types.Log) bool {
+	ev, err := it.unpack(&log)
+	if err != nil {
+		it.fail = err
+		return false
+	}
+	it.current = ev
+	return true
+}
+
+// Error returns any retrieval or parsing error occurred during filtering.
+func (it *EventIterator[T]) Error() error {
+	return it.fail
+}
+
+// Close terminates the iteration process, releasing any pending underlying
+// resources.
+func (it *EventIterator[T]) Close() error {
+	it.sub.Unsubscribe()
+	return nil
+}
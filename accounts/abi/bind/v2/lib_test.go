@@ -0,0 +1,71 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bind
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// echoBatchCaller answers every eth_call with the calldata of the call, failing
+// the calls with empty calldata.
+type echoBatchCaller struct{}
+
+func (echoBatchCaller) BatchCallContext(ctx context.Context, batch []rpc.BatchElem) error {
+	for i := range batch {
+		input := batch[i].Args[0].(map[string]interface{})["input"].(hexutil.Bytes)
+		if len(input) == 0 {
+			batch[i].Error = errors.New("empty call")
+			continue
+		}
+		blob, _ := json.Marshal(input)
+		json.Unmarshal(blob, batch[i].Result)
+	}
+	return nil
+}
+
+// Tests that the typed batch results are unpacked after the batch execution.
+func TestAddBatchCall(t *testing.T) {
+	var (
+		batch    = new(Batch)
+		contract = NewBoundContract(common.Address{1}, abi.ABI{}, nil)
+		length   = func(output []byte) (int, error) { return len(output), nil }
+	)
+	ok := AddBatchCall(batch, contract, []byte{1, 2, 3}, length)
+	failed := AddBatchCall(batch, contract, nil, length)
+
+	if _, err := ok.Value(); err == nil {
+		t.Fatal("Result available before execution")
+	}
+	if err := batch.ExecuteRPC(echoBatchCaller{}, &CallOpts{BlockNumber: big.NewInt(1)}); err != nil {
+		t.Fatalf("Failed to execute batch: %v", err)
+	}
+	if n, err := ok.Value(); err != nil || n != 3 {
+		t.Errorf("Result mismatch: have %d/%v, want 3/nil", n, err)
+	}
+	if n, err := failed.Value(); err == nil || n != 0 {
+		t.Errorf("Failure mismatch: have %d/%v", n, err)
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package hdwallet
+
+import (
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// hardenedOffset is the first index of the hardened BIP-32 child keys.
+const hardenedOffset = 0x80000000
+
+// errInvalidKey is returned if a derivation step produces an invalid key, which
+// BIP-32 mandates to skip. The odds of hitting it are below 1 in 2^127.
+var errInvalidKey = errors.New("invalid derived key")
+
+// DeriveKey derives the secp256k1 private key at the given BIP-32 derivation
+// path from a seed.
+func DeriveKey(seed []byte, path accounts.DerivationPath) (*ecdsa.PrivateKey, error) {
+	mac := hmac.New(sha512.New, []byte("Bitcoin seed"))
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+
+	key, chain := new(big.Int).SetBytes(sum[:32]), sum[32:]
+	if key.Sign() == 0 || key.Cmp(crypto.S256().Params().N) >= 0 {
+		return nil, errInvalidKey
+	}
+	for _, index := range path {
+		var err error
+		if key, chain, err = childKey(key, chain, index); err != nil {
+			return nil, err
+		}
+	}
+	blob := make([]byte, 32)
+	key.FillBytes(blob)
+	return crypto.ToECDSA(blob)
+}
+
+// childKey derives the private child key and chain code at the given index from
+// a parent private key and chain code.
+func childKey(key *big.Int, chain []byte, index uint32) (*big.Int, []byte, error) {
+	blob := make([]byte, 32)
+	key.FillBytes(blob)
+
+	mac := hmac.New(sha512.New, chain)
+	if index >= hardenedOffset {
+		mac.Write([]byte{0})
+		mac.Write(blob)
+	} else {
+		priv, err := crypto.ToECDSA(blob)
+		if err != nil {
+			return nil, nil, err
+		}
+		mac.Write(crypto.CompressPubkey(&priv.PublicKey))
+	}
+	binary.Write(mac, binary.BigEndian, index)
+	sum := mac.Sum(nil)
+
+	n := crypto.S256().Params().N
+	child := new(big.Int).SetBytes(sum[:32])
+	if child.Cmp(n) >= 0 {
+		return nil, nil, errInvalidKey
+	}
+	child.Add(child, key).Mod(child, n)
+	if child.Sign() == 0 {
+		return nil, nil, errInvalidKey
+	}
+	return child, sum[32:], nil
+}
@@ -0,0 +1,243 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package hdwallet implements a software hierarchical deterministic wallet,
+// deriving its accounts from a BIP-39 mnemonic stored encrypted on disk.
+package hdwallet
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/google/uuid"
+	"github.com/tyler-smith/go-bip39"
+)
+
+// Scheme is the URL scheme of the mnemonic wallets.
+const Scheme = "hd"
+
+// walletDir is the sub-directory of the keystore directory the wallet files are
+// stored in, so that the keystore doesn't attempt to decode them as keys.
+const walletDir = "mnemonic"
+
+// filePrefix is the name prefix of the wallet files in the wallet directory.
+const filePrefix = "HD--"
+
+// walletVersion is the version of the wallet file format.
+const walletVersion = 1
+
+// HubType is the reflect type of a mnemonic wallet hub.
+var HubType = reflect.TypeOf(&Hub{})
+
+// ErrInvalidMnemonic is returned if a mnemonic is not a valid BIP-39 one.
+var ErrInvalidMnemonic = errors.New("invalid mnemonic")
+
+// walletJSON is the on-disk format of a mnemonic wallet. The mnemonic is
+// encrypted with the Web3 Secret Storage scheme, the pinned accounts are kept
+// in plain so they are available while the wallet is locked.
+type walletJSON struct {
+	ID       string              `json:"id"`
+	Version  int                 `json:"version"`
+	Crypto   keystore.CryptoJSON `json:"crypto"`
+	Accounts []accountJSON       `json:"accounts"`
+}
+
+// accountJSON is a pinned account of a mnemonic wallet.
+type accountJSON struct {
+	Address common.Address          `json:"address"`
+	Path    accounts.DerivationPath `json:"path"`
+}
+
+// secretJSON is the plaintext of the encrypted part of a mnemonic wallet.
+type secretJSON struct {
+	Mnemonic   string `json:"mnemonic"`
+	Passphrase string `json:"passphrase,omitempty"`
+}
+
+// Hub is an accounts.Backend managing the mnemonic wallets stored along with a
+// keystore directory.
+type Hub struct {
+	dir     string // Directory the wallets are stored in
+	scryptN int    // Scrypt N parameter to encrypt new wallets with
+	scryptP int    // Scrypt P parameter to encrypt new wallets with
+
+	wallets     []accounts.Wallet // Wallets loaded from the directory, sorted by URL
+	updateFeed  event.Feed        // Event feed to notify wallet additions
+	updateScope event.SubscriptionScope
+	lock        sync.RWMutex
+}
+
+// NewHub creates a mnemonic wallet hub, loading the wallets stored in the wallet
+// sub-directory of the given keystore directory. The sub-directory is created
+// along with the first wallet, the keystore directory is not modified before.
+func NewHub(keydir string, scryptN, scryptP int) (*Hub, error) {
+	keydir, err := filepath.Abs(keydir)
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(keydir, walletDir)
+	hub := &Hub{
+		dir:     dir,
+		scryptN: scryptN,
+		scryptP: scryptP,
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, file := range files {
+		if file.IsDir() || !strings.HasPrefix(file.Name(), filePrefix) {
+			continue
+		}
+		wallet, err := loadWallet(hub, filepath.Join(dir, file.Name()))
+		if err != nil {
+			log.Warn("Failed to load mnemonic wallet", "path", file.Name(), "err", err)
+			continue
+		}
+		hub.wallets = append(hub.wallets, wallet)
+	}
+	sort.Sort(accounts.WalletsByURL(hub.wallets))
+	return hub, nil
+}
+
+// Wallets implements accounts.Backend, returning all the mnemonic wallets.
+func (h *Hub) Wallets() []accounts.Wallet {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	cpy := make([]accounts.Wallet, len(h.wallets))
+	copy(cpy, h.wallets)
+	return cpy
+}
+
+// Subscribe implements accounts.Backend, creating an async subscription to
+// receive notifications on the addition or opening of mnemonic wallets.
+func (h *Hub) Subscribe(sink chan<- accounts.WalletEvent) event.Subscription {
+	return h.updateScope.Track(h.updateFeed.Subscribe(sink))
+}
+
+// NewWallet generates a new 24 word mnemonic and stores it in a new wallet,
+// encrypted with the given password. The mnemonic is returned for backup.
+func (h *Hub) NewWallet(password string) (*Wallet, string, error) {
+	entropy, err := bip39.NewEntropy(256)
+	if err != nil {
+		return nil, "", err
+	}
+	mnemonic, err := bip39.NewMnemonic(entropy)
+	if err != nil {
+		return nil, "", err
+	}
+	wallet, err := h.Import(mnemonic, "", password)
+	if err != nil {
+		return nil, "", err
+	}
+	return wallet, mnemonic, nil
+}
+
+// Import stores a BIP-39 mnemonic and optional passphrase in a new wallet,
+// encrypted with the given password. The account at the default derivation
+// path is pinned in the wallet.
+func (h *Hub) Import(mnemonic, passphrase, password string) (*Wallet, error) {
+	seed, err := bip39.NewSeedWithErrorChecking(mnemonic, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMnemonic, err)
+	}
+	key, err := DeriveKey(seed, accounts.DefaultBaseDerivationPath)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := json.Marshal(&secretJSON{Mnemonic: mnemonic, Passphrase: passphrase})
+	if err != nil {
+		return nil, err
+	}
+	cryptoJSON, err := keystore.EncryptDataV3(secret, []byte(password), h.scryptN, h.scryptP)
+	if err != nil {
+		return nil, err
+	}
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return nil, err
+	}
+	file := &walletJSON{
+		ID:      id.String(),
+		Version: walletVersion,
+		Crypto:  cryptoJSON,
+		Accounts: []accountJSON{{
+			Address: crypto.PubkeyToAddress(key.PublicKey),
+			Path:    accounts.DefaultBaseDerivationPath,
+		}},
+	}
+	name := fmt.Sprintf("%s%s--%s", filePrefix, time.Now().UTC().Format("2006-01-02T15-04-05.000000000Z"), id)
+	wallet := newWallet(h, filepath.Join(h.dir, name), file)
+	if err := wallet.store(); err != nil {
+		return nil, err
+	}
+	h.lock.Lock()
+	h.wallets = append(h.wallets, wallet)
+	sort.Sort(accounts.WalletsByURL(h.wallets))
+	h.lock.Unlock()
+
+	h.updateFeed.Send(accounts.WalletEvent{Wallet: wallet, Kind: accounts.WalletArrived})
+	return wallet, nil
+}
+
+// loadWallet loads a mnemonic wallet from the given file.
+func loadWallet(hub *Hub, path string) (*Wallet, error) {
+	blob, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	file := new(walletJSON)
+	if err := json.Unmarshal(blob, file); err != nil {
+		return nil, err
+	}
+	if file.Version != walletVersion {
+		return nil, fmt.Errorf("version not supported: %v", file.Version)
+	}
+	return newWallet(hub, path, file), nil
+}
+
+// writeFile atomically replaces the content of a wallet file.
+func writeFile(path string, content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	f.Close()
+	return os.Rename(f.Name(), path)
+}
@@ -0,0 +1,387 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package hdwallet
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/tyler-smith/go-bip39"
+)
+
+// ErrLocked is returned if signing or deriving is requested from a wallet which
+// was not opened.
+var ErrLocked = accounts.NewAuthNeededError("password or open")
+
+// Wallet is a hierarchical deterministic wallet deriving its accounts from an
+// encrypted BIP-39 mnemonic. Accounts are available while the wallet is closed,
+// signing needs it to be opened or the password to be given.
+type Wallet struct {
+	hub  *Hub         // Hub the wallet belongs to
+	url  accounts.URL // Canonical URL of the wallet, pointing to its file
+	file *walletJSON  // Content of the wallet file
+
+	seed     []byte                                     // BIP-39 seed of the opened wallet, nil if closed
+	accounts []accounts.Account                         // Tracked accounts, pinned and self-derived
+	paths    map[common.Address]accounts.DerivationPath // Derivation paths of the tracked accounts
+
+	deriveBases []accounts.DerivationPath // Base paths to discover non-empty accounts from
+	deriveChain ethereum.ChainStateReader // Chain to check the discovered accounts against
+	lock        sync.RWMutex
+}
+
+// newWallet creates a wallet for the given file, tracking its pinned accounts.
+func newWallet(hub *Hub, path string, file *walletJSON) *Wallet {
+	w := &Wallet{
+		hub:   hub,
+		url:   accounts.URL{Scheme: Scheme, Path: path},
+		file:  file,
+		paths: make(map[common.Address]accounts.DerivationPath),
+	}
+	for _, account := range file.Accounts {
+		w.track(account.Address, account.Path)
+	}
+	return w
+}
+
+// URL implements accounts.Wallet, returning the URL of the wallet file.
+func (w *Wallet) URL() accounts.URL {
+	return w.url
+}
+
+// Status implements accounts.Wallet, returning whether the wallet is opened.
+func (w *Wallet) Status() (string, error) {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	if w.seed == nil {
+		return "Locked", nil
+	}
+	return "Unlocked", nil
+}
+
+// Open implements accounts.Wallet, decrypting the mnemonic with the password
+// to allow deriving accounts and signing without passwords.
+func (w *Wallet) Open(passphrase string) error {
+	seed, err := w.decrypt(passphrase)
+	if err != nil {
+		return err
+	}
+	w.lock.Lock()
+	if w.seed != nil {
+		w.lock.Unlock()
+		return accounts.ErrWalletAlreadyOpen
+	}
+	w.seed = seed
+	w.lock.Unlock()
+
+	w.hub.updateFeed.Send(accounts.WalletEvent{Wallet: w, Kind: accounts.WalletOpened})
+	go w.selfDerive()
+	return nil
+}
+
+// Close implements accounts.Wallet, wiping the decrypted seed from memory.
+func (w *Wallet) Close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	zeroBytes(w.seed)
+	w.seed = nil
+	return nil
+}
+
+// Accounts implements accounts.Wallet, returning the pinned and self-derived
+// accounts of the wallet.
+func (w *Wallet) Accounts() []accounts.Account {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	cpy := make([]accounts.Account, len(w.accounts))
+	copy(cpy, w.accounts)
+	return cpy
+}
+
+// Contains implements accounts.Wallet, returning whether an account is tracked
+// by the wallet.
+func (w *Wallet) Contains(account accounts.Account) bool {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	path, ok := w.paths[account.Address]
+	if !ok {
+		return false
+	}
+	return account.URL == (accounts.URL{}) || account.URL == w.accountURL(path)
+}
+
+// Derive implements accounts.Wallet, deriving the account at the given path and
+// optionally pinning it in the wallet file. The wallet must be opened.
+func (w *Wallet) Derive(path accounts.DerivationPath, pin bool) (accounts.Account, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.seed == nil {
+		return accounts.Account{}, ErrLocked
+	}
+	key, err := DeriveKey(w.seed, path)
+	if err != nil {
+		return accounts.Account{}, err
+	}
+	address := crypto.PubkeyToAddress(key.PublicKey)
+	account := w.track(address, path)
+
+	if pin {
+		for _, pinned := range w.file.Accounts {
+			if pinned.Address == address {
+				return account, nil
+			}
+		}
+		w.file.Accounts = append(w.file.Accounts, accountJSON{Address: address, Path: path})
+		if err := w.store(); err != nil {
+			return accounts.Account{}, err
+		}
+	}
+	return account, nil
+}
+
+// SelfDerive implements accounts.Wallet, setting the base paths to discover the
+// non-empty accounts from. Discovery runs whenever the wallet is opened.
+func (w *Wallet) SelfDerive(bases []accounts.DerivationPath, chain ethereum.ChainStateReader) {
+	w.lock.Lock()
+	w.deriveBases = make([]accounts.DerivationPath, len(bases))
+	for i, base := range bases {
+		w.deriveBases[i] = make(accounts.DerivationPath, len(base))
+		copy(w.deriveBases[i], base)
+	}
+	w.deriveChain = chain
+	opened := w.seed != nil
+	w.lock.Unlock()
+
+	if opened {
+		go w.selfDerive()
+	}
+}
+
+// selfDerive discovers the accounts with a non-zero nonce or balance on each
+// base path, tracking them along with the first empty account.
+func (w *Wallet) selfDerive() {
+	w.lock.RLock()
+	bases, chain := w.deriveBases, w.deriveChain
+	w.lock.RUnlock()
+
+	if chain == nil {
+		return
+	}
+	for _, base := range bases {
+		path := make(accounts.DerivationPath, len(base))
+		copy(path, base)
+
+		for {
+			w.lock.RLock()
+			if w.seed == nil {
+				w.lock.RUnlock()
+				return
+			}
+			key, err := DeriveKey(w.seed, path)
+			w.lock.RUnlock()
+			if err != nil {
+				log.Warn("Mnemonic wallet self-derivation failed", "url", w.url, "path", path, "err", err)
+				break
+			}
+			address := crypto.PubkeyToAddress(key.PublicKey)
+			nonce, err := chain.NonceAt(context.Background(), address, nil)
+			if err != nil {
+				log.Warn("Mnemonic wallet nonce retrieval failed", "url", w.url, "err", err)
+				return
+			}
+			balance, err := chain.BalanceAt(context.Background(), address, nil)
+			if err != nil {
+				log.Warn("Mnemonic wallet balance retrieval failed", "url", w.url, "err", err)
+				return
+			}
+			w.lock.Lock()
+			if _, known := w.paths[address]; !known {
+				log.Info("Mnemonic wallet discovered new account", "address", address, "path", path, "balance", balance, "nonce", nonce)
+			}
+			w.track(address, append(accounts.DerivationPath{}, path...))
+			w.lock.Unlock()
+
+			// Stop at the first empty account, keeping it for the next use
+			if nonce == 0 && balance.Sign() == 0 {
+				break
+			}
+			path[len(path)-1]++
+		}
+	}
+}
+
+// SignData implements accounts.Wallet, signing the keccak256 hash of the data
+// with the given account. The wallet must be opened.
+func (w *Wallet) SignData(account accounts.Account, mimeType string, data []byte) ([]byte, error) {
+	return w.signHash(account, nil, crypto.Keccak256(data))
+}
+
+// SignDataWithPassphrase implements accounts.Wallet, signing the keccak256 hash
+// of the data after decrypting the wallet with the passphrase.
+func (w *Wallet) SignDataWithPassphrase(account accounts.Account, passphrase, mimeType string, data []byte) ([]byte, error) {
+	return w.signHash(account, &passphrase, crypto.Keccak256(data))
+}
+
+// SignText implements accounts.Wallet, signing the EIP-191 hash of the text with
+// the given account. The wallet must be opened.
+func (w *Wallet) SignText(account accounts.Account, text []byte) ([]byte, error) {
+	return w.signHash(account, nil, accounts.TextHash(text))
+}
+
+// SignTextWithPassphrase implements accounts.Wallet, signing the EIP-191 hash of
+// the text after decrypting the wallet with the passphrase.
+func (w *Wallet) SignTextWithPassphrase(account accounts.Account, passphrase string, text []byte) ([]byte, error) {
+	return w.signHash(account, &passphrase, accounts.TextHash(text))
+}
+
+// SignTx implements accounts.Wallet, signing the transaction with the given
+// account. The wallet must be opened.
+func (w *Wallet) SignTx(account accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return w.signTx(account, nil, tx, chainID)
+}
+
+// SignTxWithPassphrase implements accounts.Wallet, signing the transaction after
+// decrypting the wallet with the passphrase.
+func (w *Wallet) SignTxWithPassphrase(account accounts.Account, passphrase string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return w.signTx(account, &passphrase, tx, chainID)
+}
+
+// signHash signs a hash with the key of an account.
+func (w *Wallet) signHash(account accounts.Account, passphrase *string, hash []byte) ([]byte, error) {
+	key, err := w.key(account, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroKey(key)
+	return crypto.Sign(hash, key)
+}
+
+// signTx signs a transaction with the key of an account.
+func (w *Wallet) signTx(account accounts.Account, passphrase *string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	key, err := w.key(account, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroKey(key)
+	return types.SignTx(tx, types.LatestSignerForChainID(chainID), key)
+}
+
+// key derives the private key of a tracked account, from the seed of the opened
+// wallet, or by decrypting the wallet with the passphrase if given.
+func (w *Wallet) key(account accounts.Account, passphrase *string) (*ecdsa.PrivateKey, error) {
+	w.lock.RLock()
+	path, ok := w.paths[account.Address]
+	if !ok {
+		w.lock.RUnlock()
+		return nil, accounts.ErrUnknownAccount
+	}
+	if passphrase == nil {
+		defer w.lock.RUnlock()
+		if w.seed == nil {
+			return nil, ErrLocked
+		}
+		return DeriveKey(w.seed, path)
+	}
+	w.lock.RUnlock()
+
+	seed, err := w.decrypt(*passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(seed)
+	return DeriveKey(seed, path)
+}
+
+// decrypt decrypts the mnemonic of the wallet, returning its BIP-39 seed.
+func (w *Wallet) decrypt(passphrase string) ([]byte, error) {
+	w.lock.RLock()
+	cryptoJSON := w.file.Crypto
+	w.lock.RUnlock()
+
+	plain, err := keystore.DecryptDataV3(cryptoJSON, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(plain)
+
+	var secret secretJSON
+	if err := json.Unmarshal(plain, &secret); err != nil {
+		return nil, err
+	}
+	seed, err := bip39.NewSeedWithErrorChecking(secret.Mnemonic, secret.Passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMnemonic, err)
+	}
+	return seed, nil
+}
+
+// track adds an account to the tracked ones if not yet present. The lock must
+// be held, if the wallet is already shared.
+func (w *Wallet) track(address common.Address, path accounts.DerivationPath) accounts.Account {
+	account := accounts.Account{Address: address, URL: w.accountURL(path)}
+	if _, ok := w.paths[address]; !ok {
+		w.paths[address] = path
+		w.accounts = append(w.accounts, account)
+	}
+	return account
+}
+
+// accountURL returns the URL of the account at the given path.
+func (w *Wallet) accountURL(path accounts.DerivationPath) accounts.URL {
+	return accounts.URL{Scheme: w.url.Scheme, Path: fmt.Sprintf("%s/%s", w.url.Path, path)}
+}
+
+// store writes the wallet file to disk. The lock must be held, if the wallet is
+// already shared.
+func (w *Wallet) store() error {
+	blob, err := json.Marshal(w.file)
+	if err != nil {
+		return err
+	}
+	return writeFile(w.url.Path, blob)
+}
+
+// zeroKey zeroes a private key in memory.
+func zeroKey(k *ecdsa.PrivateKey) {
+	b := k.D.Bits()
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+// zeroBytes zeroes a secret in memory.
+func zeroBytes(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
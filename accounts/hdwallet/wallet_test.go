@@ -0,0 +1,186 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package hdwallet
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/tyler-smith/go-bip39"
+)
+
+const testMnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+// Tests the BIP-32 derivation against the first test vector of the spec.
+func TestDeriveKey(t *testing.T) {
+	seed := common.FromHex("000102030405060708090a0b0c0d0e0f")
+	tests := []struct {
+		path string
+		key  string
+	}{
+		{"m", "e8f32e723decf4051aefac8e2c93c9c5b214313817cdb01a1494b917c8436b35"},
+		{"m/0'", "edb2e14f9ee77d26dd93b4ecede8d16ed408ce149b6cd80b0715a2d911a0afea"},
+		{"m/0'/1", "3c6cb8d0f6a264c91ea8b5030fadaa8e538b020f0a387421a12de9319dc93368"},
+		{"m/0'/1/2'", "cbce0d719ecf7431d88e6a89fa1483e02e35092af60c042b1df2ff59fa424dca"},
+		{"m/0'/1/2'/2", "0f479245fb19a38a1954c5c7c0ebab2f9bdfd96a17563ef28a6a4b1a2a764ef4"},
+		{"m/0'/1/2'/2/1000000000", "471b76e389e528d6de6d816857e012c5455051cad6660850e58372a6c3e6e7c8"},
+	}
+	for _, tt := range tests {
+		var path accounts.DerivationPath
+		if tt.path != "m" {
+			var err error
+			if path, err = accounts.ParseDerivationPath(tt.path); err != nil {
+				t.Fatalf("%s: invalid path: %v", tt.path, err)
+			}
+		}
+		key, err := DeriveKey(seed, path)
+		if err != nil {
+			t.Fatalf("%s: failed to derive: %v", tt.path, err)
+		}
+		if have := hex.EncodeToString(crypto.FromECDSA(key)); have != tt.key {
+			t.Errorf("%s: key mismatch: have %s, want %s", tt.path, have, tt.key)
+		}
+	}
+}
+
+// testChain is a chain state reader with a preset of non-empty accounts.
+type testChain struct {
+	nonces map[common.Address]uint64
+}
+
+func (c *testChain) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	return new(big.Int), nil
+}
+
+func (c *testChain) StorageAt(ctx context.Context, account common.Address, key common.Hash, blockNumber *big.Int) ([]byte, error) {
+	return nil, nil
+}
+
+func (c *testChain) CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error) {
+	return nil, nil
+}
+
+func (c *testChain) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+	return c.nonces[account], nil
+}
+
+func TestWallet(t *testing.T) {
+	dir := t.TempDir()
+	hub, err := NewHub(dir, keystore.LightScryptN, keystore.LightScryptP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The keystore directory is not modified until a wallet is stored
+	if files, _ := os.ReadDir(dir); len(files) != 0 {
+		t.Fatalf("keystore directory modified by hub: %v", files)
+	}
+	if _, err := hub.Import("abandon abandon abandon", "", "password"); !errors.Is(err, ErrInvalidMnemonic) {
+		t.Fatalf("invalid mnemonic error mismatch: have %v, want %v", err, ErrInvalidMnemonic)
+	}
+	wallet, err := hub.Import(testMnemonic, "", "password")
+	if err != nil {
+		t.Fatalf("failed to import mnemonic: %v", err)
+	}
+	// The default account is available while the wallet is locked
+	accs := wallet.Accounts()
+	if len(accs) != 1 || accs[0].Address != common.HexToAddress("0x9858EfFD232B4033E47d90003D41EC34EcaEda94") {
+		t.Fatalf("default account mismatch: %v", accs)
+	}
+	if status, _ := wallet.Status(); status != "Locked" {
+		t.Errorf("status mismatch: have %s, want Locked", status)
+	}
+	tx := types.NewTransaction(0, common.Address{1}, big.NewInt(1), 21000, big.NewInt(1), nil)
+	if _, err := wallet.SignTx(accs[0], tx, big.NewInt(1)); err != ErrLocked {
+		t.Errorf("locked signing error mismatch: have %v, want %v", err, ErrLocked)
+	}
+	if _, err := wallet.SignTxWithPassphrase(accs[0], "wrong", tx, big.NewInt(1)); err != keystore.ErrDecrypt {
+		t.Errorf("wrong passphrase error mismatch: have %v, want %v", err, keystore.ErrDecrypt)
+	}
+	signed, err := wallet.SignTxWithPassphrase(accs[0], "password", tx, big.NewInt(1))
+	if err != nil {
+		t.Fatalf("failed to sign with passphrase: %v", err)
+	}
+	if from, _ := types.Sender(types.LatestSignerForChainID(big.NewInt(1)), signed); from != accs[0].Address {
+		t.Errorf("signer mismatch: have %x, want %x", from, accs[0].Address)
+	}
+	// Derive and pin an account after opening the wallet, discovering others
+	if _, err := wallet.Derive(accounts.DefaultBaseDerivationPath, true); err != ErrLocked {
+		t.Errorf("locked derivation error mismatch: have %v, want %v", err, ErrLocked)
+	}
+	if err := wallet.Open("password"); err != nil {
+		t.Fatalf("failed to open wallet: %v", err)
+	}
+	path, _ := accounts.ParseDerivationPath("m/44'/60'/1'/0/0")
+	pinned, err := wallet.Derive(path, true)
+	if err != nil {
+		t.Fatalf("failed to derive account: %v", err)
+	}
+	sig, err := wallet.SignText(pinned, []byte("hello"))
+	if err != nil {
+		t.Fatalf("failed to sign text: %v", err)
+	}
+	if pub, err := crypto.SigToPub(accounts.TextHash([]byte("hello")), sig); err != nil || crypto.PubkeyToAddress(*pub) != pinned.Address {
+		t.Errorf("text signer mismatch: %v", err)
+	}
+	base, _ := accounts.ParseDerivationPath("m/44'/60'/2'/0/0")
+	used, _ := DeriveKey(bip39.NewSeed(testMnemonic, ""), base)
+	chain := &testChain{nonces: map[common.Address]uint64{crypto.PubkeyToAddress(used.PublicKey): 1}}
+	wallet.SelfDerive([]accounts.DerivationPath{base}, chain)
+	for i := 0; len(wallet.Accounts()) < 4; i++ {
+		if i == 100 {
+			t.Fatalf("self-derivation mismatch: %v", wallet.Accounts())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	// Reload the wallets, only the pinned accounts are retained
+	wallet.Close()
+
+	hub, err = NewHub(dir, keystore.LightScryptN, keystore.LightScryptP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wallets := hub.Wallets()
+	if len(wallets) != 1 || wallets[0].URL() != wallet.URL() {
+		t.Fatalf("reloaded wallets mismatch: %v", wallets)
+	}
+	reloaded := wallets[0].Accounts()
+	if len(reloaded) != 2 || reloaded[0] != accs[0] || reloaded[1] != pinned {
+		t.Errorf("reloaded accounts mismatch: %v", reloaded)
+	}
+	// The wallet file must be stored out of the keystore directory itself, not
+	// to be picked up by the keystore
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Name() != walletDir || !files[0].IsDir() {
+		t.Fatalf("keystore directory content mismatch: %v", files)
+	}
+	if accs := keystore.NewKeyStore(dir, keystore.LightScryptN, keystore.LightScryptP).Accounts(); len(accs) != 0 {
+		t.Errorf("keystore picked up wallet: %v", accs)
+	}
+}
@@ -0,0 +1,276 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package keystore
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/google/uuid"
+	bls "github.com/protolambda/bls12-381-util"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	// blsVersion is the version of the EIP-2335 keystore format.
+	blsVersion = 4
+
+	// blsChecksum is the checksum function of EIP-2335 keystores.
+	blsChecksum = "sha256"
+
+	// blsCipher is the cipher function of EIP-2335 keystores.
+	blsCipher = "aes-128-ctr"
+
+	// StandardPBKDF2C is the iteration count of the PBKDF2 key derivation of
+	// EIP-2335 keystores, as used by the reference implementation.
+	StandardPBKDF2C = 1 << 18
+)
+
+// BLSKey is a BLS12-381 secret key, as used by consensus layer validators.
+type BLSKey struct {
+	ID          uuid.UUID      // Version 4 "random" for unique id not derived from key data
+	Path        string         // EIP-2334 derivation path of the key, empty if not derived
+	Description string         // Optional user-facing description of the key
+	SecretKey   *bls.SecretKey // BLS secret key, the actual key material
+}
+
+// NewBLSKey generates a random BLS key. The key is derived as an ERC-2333 master
+// key from a random seed.
+func NewBLSKey() (*BLSKey, error) {
+	seed := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, seed); err != nil {
+		return nil, err
+	}
+	sk, err := DeriveBLSKey(seed, "m")
+	if err != nil {
+		return nil, err
+	}
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return nil, err
+	}
+	return &BLSKey{ID: id, SecretKey: sk}, nil
+}
+
+// PublicKey returns the compressed public key of the BLS key.
+func (k *BLSKey) PublicKey() [48]byte {
+	pk, err := bls.SkToPk(k.SecretKey)
+	if err != nil {
+		panic(err) // Can't happen, the secret key is validated on creation
+	}
+	return pk.Serialize()
+}
+
+// Sign signs a message with the BLS key, using the proof of possession scheme
+// of the consensus layer.
+func (k *BLSKey) Sign(message []byte) [96]byte {
+	return bls.Sign(k.SecretKey, message).Serialize()
+}
+
+// blsKeyJSON is the EIP-2335 JSON encoding of an encrypted BLS key.
+type blsKeyJSON struct {
+	Crypto      blsCryptoJSON `json:"crypto"`
+	Description string        `json:"description"`
+	Pubkey      string        `json:"pubkey"`
+	Path        string        `json:"path"`
+	UUID        string        `json:"uuid"`
+	Version     int           `json:"version"`
+}
+
+// blsCryptoJSON contains the modules protecting the secret of an EIP-2335 key.
+type blsCryptoJSON struct {
+	KDF      blsModuleJSON `json:"kdf"`
+	Checksum blsModuleJSON `json:"checksum"`
+	Cipher   blsModuleJSON `json:"cipher"`
+}
+
+// blsModuleJSON is a single cryptographic module of an EIP-2335 key.
+type blsModuleJSON struct {
+	Function string                 `json:"function"`
+	Params   map[string]interface{} `json:"params"`
+	Message  string                 `json:"message"`
+}
+
+// EncryptBLSKey encrypts a BLS key into an EIP-2335 keystore, deriving the
+// encryption key from the passphrase with scrypt using the given parameters.
+func EncryptBLSKey(key *BLSKey, auth string, scryptN, scryptP int) ([]byte, error) {
+	salt := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		panic("reading from crypto/rand failed: " + err.Error())
+	}
+	derivedKey, err := scrypt.Key(blsPassword(auth), salt, scryptN, scryptR, scryptP, scryptDKLen)
+	if err != nil {
+		return nil, err
+	}
+	kdf := blsModuleJSON{
+		Function: keyHeaderKDF,
+		Params: map[string]interface{}{
+			"dklen": scryptDKLen,
+			"n":     scryptN,
+			"r":     scryptR,
+			"p":     scryptP,
+			"salt":  hex.EncodeToString(salt),
+		},
+	}
+	return encryptBLSKey(key, kdf, derivedKey)
+}
+
+// EncryptBLSKeyPBKDF2 encrypts a BLS key into an EIP-2335 keystore, deriving
+// the encryption key from the passphrase with PBKDF2 using the given number of
+// iterations.
+func EncryptBLSKeyPBKDF2(key *BLSKey, auth string, iterations int) ([]byte, error) {
+	salt := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		panic("reading from crypto/rand failed: " + err.Error())
+	}
+	derivedKey := pbkdf2.Key(blsPassword(auth), salt, iterations, scryptDKLen, sha256.New)
+	kdf := blsModuleJSON{
+		Function: "pbkdf2",
+		Params: map[string]interface{}{
+			"dklen": scryptDKLen,
+			"c":     iterations,
+			"prf":   "hmac-sha256",
+			"salt":  hex.EncodeToString(salt),
+		},
+	}
+	return encryptBLSKey(key, kdf, derivedKey)
+}
+
+// encryptBLSKey encrypts a BLS key with an already derived key, assembling the
+// EIP-2335 keystore.
+func encryptBLSKey(key *BLSKey, kdf blsModuleJSON, derivedKey []byte) ([]byte, error) {
+	iv := make([]byte, aes.BlockSize)
+	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+		panic("reading from crypto/rand failed: " + err.Error())
+	}
+	secret := key.SecretKey.Serialize()
+	cipherText, err := aesCTRXOR(derivedKey[:16], secret[:], iv)
+	if err != nil {
+		return nil, err
+	}
+	checksum := sha256.Sum256(append(derivedKey[16:32:32], cipherText...))
+	pubkey := key.PublicKey()
+
+	kdf.Message = ""
+	return json.Marshal(&blsKeyJSON{
+		Crypto: blsCryptoJSON{
+			KDF: kdf,
+			Checksum: blsModuleJSON{
+				Function: blsChecksum,
+				Params:   map[string]interface{}{},
+				Message:  hex.EncodeToString(checksum[:]),
+			},
+			Cipher: blsModuleJSON{
+				Function: blsCipher,
+				Params:   map[string]interface{}{"iv": hex.EncodeToString(iv)},
+				Message:  hex.EncodeToString(cipherText),
+			},
+		},
+		Description: key.Description,
+		Pubkey:      hex.EncodeToString(pubkey[:]),
+		Path:        key.Path,
+		UUID:        key.ID.String(),
+		Version:     blsVersion,
+	})
+}
+
+// DecryptBLSKey decrypts an EIP-2335 keystore with the given passphrase,
+// returning the contained BLS key.
+func DecryptBLSKey(keyjson []byte, auth string) (*BLSKey, error) {
+	k := new(blsKeyJSON)
+	if err := json.Unmarshal(keyjson, k); err != nil {
+		return nil, err
+	}
+	if k.Version != blsVersion {
+		return nil, fmt.Errorf("version not supported: %v", k.Version)
+	}
+	if k.Crypto.Checksum.Function != blsChecksum {
+		return nil, fmt.Errorf("checksum not supported: %v", k.Crypto.Checksum.Function)
+	}
+	if k.Crypto.Cipher.Function != blsCipher {
+		return nil, fmt.Errorf("cipher not supported: %v", k.Crypto.Cipher.Function)
+	}
+	if _, ok := k.Crypto.KDF.Params["salt"].(string); !ok {
+		return nil, fmt.Errorf("invalid KDF salt")
+	}
+	cipherText, err := hex.DecodeString(k.Crypto.Cipher.Message)
+	if err != nil {
+		return nil, err
+	}
+	ivHex, ok := k.Crypto.Cipher.Params["iv"].(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid cipher IV")
+	}
+	iv, err := hex.DecodeString(ivHex)
+	if err != nil {
+		return nil, err
+	}
+	checksum, err := hex.DecodeString(k.Crypto.Checksum.Message)
+	if err != nil {
+		return nil, err
+	}
+	derivedKey, err := getKDFKey(CryptoJSON{KDF: k.Crypto.KDF.Function, KDFParams: k.Crypto.KDF.Params}, string(blsPassword(auth)))
+	if err != nil {
+		return nil, err
+	}
+	if len(derivedKey) < 32 {
+		return nil, fmt.Errorf("derived key too short: %d bytes", len(derivedKey))
+	}
+	calculated := sha256.Sum256(append(derivedKey[16:32:32], cipherText...))
+	if !bytes.Equal(calculated[:], checksum) {
+		return nil, ErrDecrypt
+	}
+	plainText, err := aesCTRXOR(derivedKey[:16], cipherText, iv)
+	if err != nil {
+		return nil, err
+	}
+	if len(plainText) != 32 {
+		return nil, fmt.Errorf("invalid secret key length: %d bytes", len(plainText))
+	}
+	sk := new(bls.SecretKey)
+	if err := sk.Deserialize((*[32]byte)(plainText)); err != nil {
+		return nil, fmt.Errorf("invalid key: %w", err)
+	}
+	key := &BLSKey{Path: k.Path, Description: k.Description, SecretKey: sk}
+	if key.ID, err = uuid.Parse(k.UUID); err != nil {
+		return nil, fmt.Errorf("invalid UUID: %w", err)
+	}
+	if pubkey := key.PublicKey(); k.Pubkey != "" && !strings.EqualFold(k.Pubkey, hex.EncodeToString(pubkey[:])) {
+		return nil, fmt.Errorf("key content mismatch: have pubkey %x, want %s", pubkey, k.Pubkey)
+	}
+	return key, nil
+}
+
+// blsPassword processes a passphrase as mandated by EIP-2335: it is normalized
+// to NFKD form and stripped of the C0, C1 and Delete control codes.
+func blsPassword(auth string) []byte {
+	return []byte(strings.Map(func(r rune) rune {
+		if r < 0x20 || (r >= 0x7f && r <= 0x9f) {
+			return -1
+		}
+		return r
+	}, norm.NFKD.String(auth)))
+}
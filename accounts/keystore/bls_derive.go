@@ -0,0 +1,179 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package keystore
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	bls "github.com/protolambda/bls12-381-util"
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/text/unicode/norm"
+)
+
+// BLSPurpose is the purpose index all EIP-2334 derivation paths start with.
+const BLSPurpose = 12381
+
+// DefaultBLSSigningPath is the EIP-2334 derivation path of the signing key of
+// the first validator.
+const DefaultBLSSigningPath = "m/12381/3600/0/0/0"
+
+// blsOrder is the order r of the BLS12-381 curve subgroups.
+var blsOrder, _ = new(big.Int).SetString("73eda753299d7d483339d80809a1d80553bda402fffe5bfeffffffff00000001", 16)
+
+// ParseBLSPath converts an EIP-2334 derivation path (e.g. m/12381/3600/0/0/0)
+// into the list of child indices to derive through.
+func ParseBLSPath(path string) ([]uint32, error) {
+	components := strings.Split(path, "/")
+	if components[0] != "m" {
+		return nil, errors.New("derivation path must start with m")
+	}
+	components = components[1:]
+	if len(components) > 0 && components[0] != strconv.Itoa(BLSPurpose) {
+		return nil, fmt.Errorf("derivation path purpose must be %d", BLSPurpose)
+	}
+	indices := make([]uint32, len(components))
+	for i, component := range components {
+		index, err := strconv.ParseUint(component, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid component %q: %v", component, err)
+		}
+		indices[i] = uint32(index)
+	}
+	return indices, nil
+}
+
+// DeriveBLSKey derives the BLS secret key at the given EIP-2334 path from a
+// seed, following the ERC-2333 key tree. The seed must be at least 32 bytes.
+func DeriveBLSKey(seed []byte, path string) (*bls.SecretKey, error) {
+	indices, err := ParseBLSPath(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(seed) < 32 {
+		return nil, errors.New("seed must be at least 32 bytes")
+	}
+	sk := deriveMasterSK(seed)
+	for _, index := range indices {
+		sk = deriveChildSK(sk, index)
+	}
+	var blob [32]byte
+	sk.FillBytes(blob[:])
+
+	key := new(bls.SecretKey)
+	if err := key.Deserialize(&blob); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// NewBLSKeyFromMnemonic derives the BLS key at the given EIP-2334 path from a
+// BIP-39 mnemonic and an optional passphrase.
+func NewBLSKeyFromMnemonic(mnemonic, passphrase, path string) (*BLSKey, error) {
+	sk, err := DeriveBLSKey(mnemonicSeed(mnemonic, passphrase), path)
+	if err != nil {
+		return nil, err
+	}
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return nil, err
+	}
+	return &BLSKey{ID: id, Path: path, SecretKey: sk}, nil
+}
+
+// mnemonicSeed converts a BIP-39 mnemonic and passphrase into the seed of the
+// key derivation.
+func mnemonicSeed(mnemonic, passphrase string) []byte {
+	mnemonic = strings.Join(strings.Fields(norm.NFKD.String(mnemonic)), " ")
+	salt := "mnemonic" + norm.NFKD.String(passphrase)
+	return pbkdf2.Key([]byte(mnemonic), []byte(salt), 2048, 64, sha512.New)
+}
+
+// deriveMasterSK derives the ERC-2333 master secret key from a seed.
+func deriveMasterSK(seed []byte) *big.Int {
+	return hkdfModR(seed, nil)
+}
+
+// deriveChildSK derives the ERC-2333 child secret key at the given index from
+// a parent secret key, through its compressed Lamport public key.
+func deriveChildSK(parent *big.Int, index uint32) *big.Int {
+	return hkdfModR(parentSKToLamportPK(parent, index), nil)
+}
+
+// hkdfModR derives a secret key from the input key material, as specified by
+// the KeyGen algorithm of the BLS signature draft.
+func hkdfModR(ikm []byte, info []byte) *big.Int {
+	const length = 48 // ceil((3 * ceil(log2(r))) / 16)
+
+	var (
+		salt = []byte("BLS-SIG-KEYGEN-SALT-")
+		sk   = new(big.Int)
+		okm  = make([]byte, length)
+	)
+	for sk.Sign() == 0 {
+		hash := sha256.Sum256(salt)
+		salt = hash[:]
+
+		prk := hkdf.Extract(sha256.New, append(ikm[:len(ikm):len(ikm)], 0), salt)
+		if _, err := io.ReadFull(hkdf.Expand(sha256.New, prk, append(info[:len(info):len(info)], 0, length)), okm); err != nil {
+			panic(err) // Can't happen, the output is way below the HKDF limit
+		}
+		sk.SetBytes(okm).Mod(sk, blsOrder)
+	}
+	return sk
+}
+
+// parentSKToLamportPK computes the compressed Lamport public key of a parent
+// secret key, used as the input key material of a child.
+func parentSKToLamportPK(parent *big.Int, index uint32) []byte {
+	salt := make([]byte, 4)
+	binary.BigEndian.PutUint32(salt, index)
+
+	ikm := make([]byte, 32)
+	parent.FillBytes(ikm)
+	notIKM := make([]byte, 32)
+	for i := range ikm {
+		notIKM[i] = ^ikm[i]
+	}
+	hasher := sha256.New()
+	for _, lamport := range [][]byte{ikmToLamportSK(ikm, salt), ikmToLamportSK(notIKM, salt)} {
+		for i := 0; i < len(lamport); i += 32 {
+			chunk := sha256.Sum256(lamport[i : i+32])
+			hasher.Write(chunk[:])
+		}
+	}
+	return hasher.Sum(nil)
+}
+
+// ikmToLamportSK expands the input key material into the 255 32-byte chunks of
+// a Lamport secret key.
+func ikmToLamportSK(ikm, salt []byte) []byte {
+	okm := make([]byte, 255*32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, ikm, salt, nil), okm); err != nil {
+		panic(err) // Can't happen, the output is exactly at the HKDF limit
+	}
+	return okm
+}
@@ -0,0 +1,181 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package keystore
+
+import (
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	bls "github.com/protolambda/bls12-381-util"
+)
+
+// EIP-2335 test vectors, protecting the same secret with scrypt and PBKDF2.
+const (
+	blsTestPassword = "\U0001d531\U0001d522\U0001d530\U0001d531\U0001d52d\U0001d51e\U0001d530\U0001d530\U0001d534\U0001d52c\U0001d52f\U0001d521\U0001f511"
+	blsTestSecret   = "000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f"
+	blsTestPubkey   = "9612d7a727c9d0a22e185a1c768478dfe919cada9266988cb32359c11f2b7b27f4ae4040902382ae2910c15e2b420d07"
+
+	blsTestScrypt = `{
+    "crypto": {
+        "kdf": {
+            "function": "scrypt",
+            "params": {"dklen": 32, "n": 262144, "p": 1, "r": 8, "salt": "d4e56740f876aef8c010b86a40d5f56745a118d0906a34e69aec8c0db1cb8fa3"},
+            "message": ""
+        },
+        "checksum": {
+            "function": "sha256",
+            "params": {},
+            "message": "d2217fe5f3e9a1e34581ef8a78f7c9928e436d36dacc5e846690a5581e8ea484"
+        },
+        "cipher": {
+            "function": "aes-128-ctr",
+            "params": {"iv": "264daa3f303d7259501c93d997d84fe6"},
+            "message": "06ae90d55fe0a6e9c5c3bc5b170827b2e5cce3929ed3f116c2811e6366dfe20f"
+        }
+    },
+    "description": "This is a test keystore that uses scrypt to secure the secret.",
+    "pubkey": "9612d7a727c9d0a22e185a1c768478dfe919cada9266988cb32359c11f2b7b27f4ae4040902382ae2910c15e2b420d07",
+    "path": "m/12381/60/3141592653/589793238",
+    "uuid": "1d85ae20-35c5-4611-98e8-aa14a633906f",
+    "version": 4
+}`
+	blsTestPBKDF2 = `{
+    "crypto": {
+        "kdf": {
+            "function": "pbkdf2",
+            "params": {"dklen": 32, "c": 262144, "prf": "hmac-sha256", "salt": "d4e56740f876aef8c010b86a40d5f56745a118d0906a34e69aec8c0db1cb8fa3"},
+            "message": ""
+        },
+        "checksum": {
+            "function": "sha256",
+            "params": {},
+            "message": "8a9f5d9912ed7e75ea794bc5a89bca5f193721d30868ade6f73043c6ea6febf1"
+        },
+        "cipher": {
+            "function": "aes-128-ctr",
+            "params": {"iv": "264daa3f303d7259501c93d997d84fe6"},
+            "message": "cee03fde2af33149775b7223e7845e4fb2c8ae1792e5f99fe9ecf474cc8c16ad"
+        }
+    },
+    "description": "This is a test keystore that uses PBKDF2 to secure the secret.",
+    "pubkey": "9612d7a727c9d0a22e185a1c768478dfe919cada9266988cb32359c11f2b7b27f4ae4040902382ae2910c15e2b420d07",
+    "path": "m/12381/60/0/0",
+    "uuid": "64625def-3331-4eea-ab6f-782f3ed16a83",
+    "version": 4
+}`
+)
+
+func TestDecryptBLSKey(t *testing.T) {
+	for name, keyjson := range map[string]string{"scrypt": blsTestScrypt, "pbkdf2": blsTestPBKDF2} {
+		key, err := DecryptBLSKey([]byte(keyjson), blsTestPassword)
+		if err != nil {
+			t.Fatalf("%s: failed to decrypt: %v", name, err)
+		}
+		secret := key.SecretKey.Serialize()
+		if hex.EncodeToString(secret[:]) != blsTestSecret {
+			t.Errorf("%s: secret mismatch: have %x, want %s", name, secret, blsTestSecret)
+		}
+		if _, err := DecryptBLSKey([]byte(keyjson), "testpassword"); err != ErrDecrypt {
+			t.Errorf("%s: wrong password error mismatch: have %v, want %v", name, err, ErrDecrypt)
+		}
+	}
+}
+
+func TestEncryptBLSKey(t *testing.T) {
+	var blob [32]byte
+	copy(blob[:], common.FromHex(blsTestSecret))
+	sk := new(bls.SecretKey)
+	if err := sk.Deserialize(&blob); err != nil {
+		t.Fatal(err)
+	}
+	key := &BLSKey{Path: DefaultBLSSigningPath, Description: "test", SecretKey: sk}
+	if pubkey := key.PublicKey(); hex.EncodeToString(pubkey[:]) != blsTestPubkey {
+		t.Fatalf("pubkey mismatch: have %x, want %s", pubkey, blsTestPubkey)
+	}
+	scryptJSON, err := EncryptBLSKey(key, "foo\x7fbar", LightScryptN, LightScryptP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pbkdf2JSON, err := EncryptBLSKeyPBKDF2(key, "foobar", 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, keyjson := range [][]byte{scryptJSON, pbkdf2JSON} {
+		// Control codes are stripped from the password
+		dec, err := DecryptBLSKey(keyjson, "foobar")
+		if err != nil {
+			t.Fatalf("failed to decrypt: %v", err)
+		}
+		if dec.SecretKey.Serialize() != blob || dec.Path != key.Path || dec.Description != key.Description || dec.ID != key.ID {
+			t.Errorf("decrypted key mismatch: %+v", dec)
+		}
+	}
+}
+
+// ERC-2333 test vectors.
+func TestDeriveBLSKey(t *testing.T) {
+	tests := []struct {
+		seed   string
+		master string
+		index  uint32
+		child  string
+	}{
+		{
+			seed:   "c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04",
+			master: "6083874454709270928345386274498605044986640685124978867557563392430687146096",
+			index:  0,
+			child:  "20397789859736650942317412262472558107875392172444076792671091975210932703118",
+		},
+		{
+			seed:   "3141592653589793238462643383279502884197169399375105820974944592",
+			master: "29757020647961307431480504535336562678282505419141012933316116377660817309383",
+			index:  3141592653,
+			child:  "25457201688850691947727629385191704516744796114925897962676248250929345014287",
+		},
+	}
+	for i, tt := range tests {
+		master := deriveMasterSK(common.FromHex(tt.seed))
+		if master.String() != tt.master {
+			t.Errorf("test %d: master key mismatch: have %v, want %s", i, master, tt.master)
+		}
+		child := deriveChildSK(master, tt.index)
+		if child.String() != tt.child {
+			t.Errorf("test %d: child key mismatch: have %v, want %s", i, child, tt.child)
+		}
+	}
+	// Paths are derived through all their indices
+	seed := common.FromHex(tests[0].seed)
+	sk, err := DeriveBLSKey(seed, "m/12381/0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := sk.Serialize()
+	want := deriveChildSK(deriveChildSK(deriveMasterSK(seed), BLSPurpose), 0)
+	if new(big.Int).SetBytes(secret[:]).Cmp(want) != 0 {
+		t.Errorf("path derivation mismatch: have %x, want %x", secret, want)
+	}
+	for _, path := range []string{"", "12381/0", "m/44/60", "m/12381/-1", "m/12381/0'"} {
+		if _, err := DeriveBLSKey(seed, path); err == nil {
+			t.Errorf("invalid path %q accepted", path)
+		}
+	}
+	if _, err := DeriveBLSKey(seed[:31], "m"); err == nil {
+		t.Error("short seed accepted")
+	}
+}
@@ -0,0 +1,193 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package web3signer
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// maxResponseSize is the maximum size of a response read from the remote signer.
+const maxResponseSize = 1024 * 1024
+
+// RemoteError is returned if the remote signer rejects a request.
+type RemoteError struct {
+	StatusCode int    // HTTP status code of the response
+	Message    string // Error message sent by the remote signer, if any
+}
+
+func (e *RemoteError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("remote signer error: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("remote signer error: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// client is a thin wrapper around the HTTP endpoints of the signing protocol.
+type client struct {
+	endpoint *url.URL
+	http     *http.Client
+}
+
+// newClient creates a client for the remote signer configured by config.
+func newClient(config *Config) (*client, error) {
+	endpoint, err := url.Parse(config.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if endpoint.Scheme != "http" && endpoint.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported endpoint scheme %q", endpoint.Scheme)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if config.TLSCert != "" || config.TLSKey != "" || config.TLSCA != "" {
+		if endpoint.Scheme != "https" {
+			return nil, errors.New("TLS configured for non-https endpoint")
+		}
+		if transport.TLSClientConfig, err = loadTLSConfig(config); err != nil {
+			return nil, err
+		}
+	}
+	return &client{
+		endpoint: endpoint,
+		http:     &http.Client{Transport: transport, Timeout: config.Timeout},
+	}, nil
+}
+
+// loadTLSConfig assembles the TLS configuration from the client certificate
+// and CA files.
+func loadTLSConfig(config *Config) (*tls.Config, error) {
+	conf := &tls.Config{MinVersion: tls.VersionTLS12}
+	if config.TLSCert != "" || config.TLSKey != "" {
+		cert, err := tls.LoadX509KeyPair(config.TLSCert, config.TLSKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %v", err)
+		}
+		conf.Certificates = []tls.Certificate{cert}
+	}
+	if config.TLSCA != "" {
+		blob, err := os.ReadFile(config.TLSCA)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load CA certificates: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(blob) {
+			return nil, fmt.Errorf("no CA certificates found in %s", config.TLSCA)
+		}
+		conf.RootCAs = pool
+	}
+	return conf, nil
+}
+
+// upcheck checks whether the remote signer is up and running.
+func (c *client) upcheck(ctx context.Context) error {
+	_, err := c.do(ctx, http.MethodGet, "upcheck", nil)
+	return err
+}
+
+// publicKeys retrieves the hex encoded secp256k1 public keys held by the remote
+// signer.
+func (c *client) publicKeys(ctx context.Context) ([]string, error) {
+	res, err := c.do(ctx, http.MethodGet, "api/v1/eth1/publicKeys", nil)
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	if err := json.Unmarshal(res, &keys); err != nil {
+		return nil, fmt.Errorf("invalid public key list: %v", err)
+	}
+	return keys, nil
+}
+
+// sign requests the remote signer to sign keccak256(data) with the key of the
+// given identifier.
+func (c *client) sign(ctx context.Context, identifier string, data []byte) ([]byte, error) {
+	req := struct {
+		Data hexutil.Bytes `json:"data"`
+	}{data}
+	res, err := c.do(ctx, http.MethodPost, "api/v1/eth1/sign/"+url.PathEscape(identifier), &req)
+	if err != nil {
+		return nil, err
+	}
+	// The signature is sent in plain text, but tolerate a JSON string too
+	sig, err := hexutil.Decode(strings.Trim(strings.TrimSpace(string(res)), `"`))
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature: %v", err)
+	}
+	return sig, nil
+}
+
+// do sends a request to the given path relative to the endpoint, returning the
+// body of a successful response.
+func (c *client) do(ctx context.Context, method string, path string, body interface{}) ([]byte, error) {
+	var reader io.Reader
+	if body != nil {
+		blob, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(blob)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.endpoint.JoinPath(path).String(), reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	res, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	blob, err := io.ReadAll(io.LimitReader(res.Body, maxResponseSize))
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return nil, &RemoteError{StatusCode: res.StatusCode, Message: errorMessage(blob)}
+	}
+	return blob, nil
+}
+
+// errorMessage extracts the error message from the body of a failed response,
+// which is either plain text or a JSON object with a message field.
+func errorMessage(body []byte) string {
+	var obj struct {
+		Message string `json:"message"`
+		Error   string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &obj); err == nil {
+		if obj.Message != "" {
+			return obj.Message
+		}
+		return obj.Error
+	}
+	return strings.TrimSpace(string(body))
+}
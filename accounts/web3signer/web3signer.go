@@ -0,0 +1,373 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package web3signer implements an account backend for remote signers speaking
+// the Web3Signer HTTP signing protocol.
+//
+// The protocol signs keccak256 of the submitted payload, so the wallet submits
+// the signing preimage of transactions, texts and typed data, and verifies the
+// returned signatures locally before using them.
+package web3signer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// Scheme is the URL scheme of the remote signer wallets.
+const Scheme = "web3signer"
+
+// defaultTimeout is the timeout of requests to the remote signer if none is
+// configured.
+const defaultTimeout = 10 * time.Second
+
+// BackendType is the reflect type of a remote signer backend.
+var BackendType = reflect.TypeOf(&Backend{})
+
+// ErrInvalidSignature is returned if the remote signer returns a signature not
+// matching the requested account and payload.
+var ErrInvalidSignature = errors.New("invalid signature from remote signer")
+
+// Config contains the settings of the connection to a remote signer.
+type Config struct {
+	Endpoint string        // HTTP(S) URL of the remote signer, e.g. https://localhost:9000
+	TLSCert  string        // PEM file of the client certificate to authenticate with
+	TLSKey   string        // PEM file of the client certificate's private key
+	TLSCA    string        // PEM file of the CAs to verify the server with, instead of the system's
+	Timeout  time.Duration // Timeout of the requests to the remote signer
+}
+
+// Backend is an accounts.Backend exposing the keys held by a remote signer as
+// a single wallet.
+type Backend struct {
+	wallets []accounts.Wallet
+}
+
+// NewBackend creates a backend for the remote signer of the given config,
+// checking that it is reachable.
+func NewBackend(config Config) (*Backend, error) {
+	wallet, err := NewWallet(config)
+	if err != nil {
+		return nil, err
+	}
+	return &Backend{wallets: []accounts.Wallet{wallet}}, nil
+}
+
+// Wallets implements accounts.Backend, returning the remote signer wallet.
+func (b *Backend) Wallets() []accounts.Wallet {
+	return b.wallets
+}
+
+// Subscribe implements accounts.Backend. The remote signer wallet is static, so
+// no events are ever sent.
+func (b *Backend) Subscribe(sink chan<- accounts.WalletEvent) event.Subscription {
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	})
+}
+
+// Wallet is an accounts.Wallet whose accounts are the secp256k1 keys held by a
+// remote signer.
+type Wallet struct {
+	client  *client
+	url     accounts.URL
+	timeout time.Duration
+
+	keys     map[common.Address]string // Identifiers of the discovered accounts
+	accounts []accounts.Account        // Accounts discovered on the remote signer
+	lock     sync.RWMutex
+}
+
+// NewWallet creates a wallet for the remote signer of the given config, checking
+// that it is reachable.
+func NewWallet(config Config) (*Wallet, error) {
+	if config.Timeout == 0 {
+		config.Timeout = defaultTimeout
+	}
+	client, err := newClient(&config)
+	if err != nil {
+		return nil, err
+	}
+	w := &Wallet{
+		client:  client,
+		url:     accounts.URL{Scheme: Scheme, Path: client.endpoint.Host + client.endpoint.Path},
+		timeout: config.Timeout,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), w.timeout)
+	defer cancel()
+
+	if err := client.upcheck(ctx); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// URL implements accounts.Wallet, returning the URL of the remote signer.
+func (w *Wallet) URL() accounts.URL {
+	return w.url
+}
+
+// Status implements accounts.Wallet, returning whether the remote signer is
+// reachable.
+func (w *Wallet) Status() (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), w.timeout)
+	defer cancel()
+
+	if err := w.client.upcheck(ctx); err != nil {
+		return "Offline", err
+	}
+	return "Online", nil
+}
+
+// Open implements accounts.Wallet, but is a noop for remote signers.
+func (w *Wallet) Open(passphrase string) error { return nil }
+
+// Close implements accounts.Wallet, but is a noop for remote signers.
+func (w *Wallet) Close() error { return nil }
+
+// Accounts implements accounts.Wallet, retrieving the accounts currently held
+// by the remote signer. If it can't be reached, the last known ones are returned.
+func (w *Wallet) Accounts() []accounts.Account {
+	if err := w.refresh(); err != nil {
+		log.Error("Failed to list remote signer accounts", "url", w.url, "err", err)
+	}
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	cpy := make([]accounts.Account, len(w.accounts))
+	copy(cpy, w.accounts)
+	return cpy
+}
+
+// Contains implements accounts.Wallet, returning whether a particular account is
+// or is not held by the remote signer.
+func (w *Wallet) Contains(account accounts.Account) bool {
+	if account.URL != (accounts.URL{}) && account.URL != w.url {
+		return false
+	}
+	_, err := w.identifier(account.Address)
+	return err == nil
+}
+
+// Derive implements accounts.Wallet, but is not supported by remote signers.
+func (w *Wallet) Derive(path accounts.DerivationPath, pin bool) (accounts.Account, error) {
+	return accounts.Account{}, accounts.ErrNotSupported
+}
+
+// SelfDerive implements accounts.Wallet, but is not supported by remote signers.
+func (w *Wallet) SelfDerive(bases []accounts.DerivationPath, chain ethereum.ChainStateReader) {
+	log.Error("Operation SelfDerive not supported on remote signers")
+}
+
+// SignData implements accounts.Wallet, requesting the remote signer to sign
+// keccak256(data).
+func (w *Wallet) SignData(account accounts.Account, mimeType string, data []byte) ([]byte, error) {
+	return w.sign(account, data)
+}
+
+// SignDataWithPassphrase implements accounts.Wallet, but is not supported by
+// remote signers.
+func (w *Wallet) SignDataWithPassphrase(account accounts.Account, passphrase, mimeType string, data []byte) ([]byte, error) {
+	return nil, accounts.ErrNotSupported
+}
+
+// SignText implements accounts.Wallet, requesting the remote signer to sign the
+// hash of the given text as specified by accounts.TextHash.
+func (w *Wallet) SignText(account accounts.Account, text []byte) ([]byte, error) {
+	_, msg := accounts.TextAndHash(text)
+	return w.sign(account, []byte(msg))
+}
+
+// SignTextWithPassphrase implements accounts.Wallet, but is not supported by
+// remote signers.
+func (w *Wallet) SignTextWithPassphrase(account accounts.Account, passphrase string, text []byte) ([]byte, error) {
+	return nil, accounts.ErrNotSupported
+}
+
+// SignTx implements accounts.Wallet, requesting the remote signer to sign the
+// given transaction with the signer of the chain ID.
+func (w *Wallet) SignTx(account accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	signer := types.LatestSignerForChainID(chainID)
+	payload, err := signingPayload(tx, chainID)
+	if err != nil {
+		return nil, err
+	}
+	// Make sure the remote signer will sign what the chain expects
+	if crypto.Keccak256Hash(payload) != signer.Hash(tx) {
+		return nil, fmt.Errorf("transaction type %d not supported for chain ID %v", tx.Type(), chainID)
+	}
+	sig, err := w.sign(account, payload)
+	if err != nil {
+		return nil, err
+	}
+	return tx.WithSignature(signer, sig)
+}
+
+// SignTxWithPassphrase implements accounts.Wallet, but is not supported by
+// remote signers.
+func (w *Wallet) SignTxWithPassphrase(account accounts.Account, passphrase string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return nil, accounts.ErrNotSupported
+}
+
+// sign requests the remote signer to sign keccak256(payload) with the account,
+// returning the signature in [R || S || V] format where V is 0 or 1.
+func (w *Wallet) sign(account accounts.Account, payload []byte) ([]byte, error) {
+	if account.URL != (accounts.URL{}) && account.URL != w.url {
+		return nil, accounts.ErrUnknownAccount
+	}
+	id, err := w.identifier(account.Address)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), w.timeout)
+	defer cancel()
+
+	sig, err := w.client.sign(ctx, id, payload)
+	if err != nil {
+		return nil, err
+	}
+	if len(sig) != crypto.SignatureLength {
+		return nil, fmt.Errorf("%w: length %d", ErrInvalidSignature, len(sig))
+	}
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27 // Transform V from Ethereum-legacy to 0/1
+	}
+	pubkey, err := crypto.SigToPub(crypto.Keccak256(payload), sig)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	if signer := crypto.PubkeyToAddress(*pubkey); signer != account.Address {
+		return nil, fmt.Errorf("%w: signed by %v", ErrInvalidSignature, signer)
+	}
+	return sig, nil
+}
+
+// identifier returns the key identifier of an account on the remote signer,
+// refreshing the account list if it isn't known yet.
+func (w *Wallet) identifier(addr common.Address) (string, error) {
+	w.lock.RLock()
+	id, ok := w.keys[addr]
+	w.lock.RUnlock()
+	if ok {
+		return id, nil
+	}
+	if err := w.refresh(); err != nil {
+		return "", err
+	}
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	if id, ok = w.keys[addr]; !ok {
+		return "", accounts.ErrUnknownAccount
+	}
+	return id, nil
+}
+
+// refresh retrieves the public keys held by the remote signer.
+func (w *Wallet) refresh() error {
+	ctx, cancel := context.WithTimeout(context.Background(), w.timeout)
+	defer cancel()
+
+	ids, err := w.client.publicKeys(ctx)
+	if err != nil {
+		return err
+	}
+	keys := make(map[common.Address]string, len(ids))
+	accs := make([]accounts.Account, 0, len(ids))
+	for _, id := range ids {
+		addr, err := pubkeyToAddress(id)
+		if err != nil {
+			log.Warn("Invalid remote signer public key", "key", id, "err", err)
+			continue
+		}
+		if _, ok := keys[addr]; ok {
+			continue
+		}
+		keys[addr] = id
+		accs = append(accs, accounts.Account{Address: addr, URL: w.url})
+	}
+	w.lock.Lock()
+	w.keys, w.accounts = keys, accs
+	w.lock.Unlock()
+	return nil
+}
+
+// pubkeyToAddress converts a hex encoded public key in compressed, uncompressed
+// or raw (without the 0x04 prefix) format to its address.
+func pubkeyToAddress(id string) (common.Address, error) {
+	blob, err := hexutil.Decode(id)
+	if err != nil {
+		return common.Address{}, err
+	}
+	switch len(blob) {
+	case 33:
+		pubkey, err := crypto.DecompressPubkey(blob)
+		if err != nil {
+			return common.Address{}, err
+		}
+		return crypto.PubkeyToAddress(*pubkey), nil
+	case 64:
+		blob = append([]byte{0x04}, blob...)
+	}
+	pubkey, err := crypto.UnmarshalPubkey(blob)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(*pubkey), nil
+}
+
+// signingPayload returns the preimage of the transaction's signing hash for the
+// given chain ID.
+func signingPayload(tx *types.Transaction, chainID *big.Int) ([]byte, error) {
+	var fields []interface{}
+	switch tx.Type() {
+	case types.LegacyTxType:
+		fields = []interface{}{tx.Nonce(), tx.GasPrice(), tx.Gas(), tx.To(), tx.Value(), tx.Data()}
+		if chainID != nil && chainID.Sign() != 0 {
+			fields = append(fields, chainID, uint(0), uint(0))
+		}
+		return rlp.EncodeToBytes(fields)
+	case types.AccessListTxType:
+		fields = []interface{}{chainID, tx.Nonce(), tx.GasPrice(), tx.Gas(), tx.To(), tx.Value(), tx.Data(), tx.AccessList()}
+	case types.DynamicFeeTxType:
+		fields = []interface{}{chainID, tx.Nonce(), tx.GasTipCap(), tx.GasFeeCap(), tx.Gas(), tx.To(), tx.Value(), tx.Data(), tx.AccessList()}
+	case types.BlobTxType:
+		fields = []interface{}{chainID, tx.Nonce(), tx.GasTipCap(), tx.GasFeeCap(), tx.Gas(), tx.To(), tx.Value(), tx.Data(), tx.AccessList(), tx.BlobGasFeeCap(), tx.BlobHashes()}
+	default:
+		return nil, types.ErrTxTypeNotSupported
+	}
+	blob, err := rlp.EncodeToBytes(fields)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{tx.Type()}, blob...), nil
+}
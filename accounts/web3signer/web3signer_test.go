@@ -0,0 +1,273 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package web3signer
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// testSigner is a mock remote signer implementing the eth1 signing endpoints.
+type testSigner struct {
+	keys map[string]*ecdsa.PrivateKey
+}
+
+func newTestSigner(n int) *testSigner {
+	s := &testSigner{keys: make(map[string]*ecdsa.PrivateKey)}
+	for i := 0; i < n; i++ {
+		key, _ := crypto.GenerateKey()
+		s.keys[hexutil.Encode(crypto.FromECDSAPub(&key.PublicKey)[1:])] = key
+	}
+	return s
+}
+
+func (s *testSigner) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/upcheck":
+		fmt.Fprint(w, "OK")
+
+	case r.Method == http.MethodGet && r.URL.Path == "/api/v1/eth1/publicKeys":
+		keys := make([]string, 0, len(s.keys))
+		for id := range s.keys {
+			keys = append(keys, id)
+		}
+		json.NewEncoder(w).Encode(keys)
+
+	case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/api/v1/eth1/sign/"):
+		key, ok := s.keys[strings.TrimPrefix(r.URL.Path, "/api/v1/eth1/sign/")]
+		if !ok {
+			http.Error(w, "Public Key not found", http.StatusNotFound)
+			return
+		}
+		var req struct {
+			Data hexutil.Bytes `json:"data"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		sig, _ := crypto.Sign(crypto.Keccak256(req.Data), key)
+		sig[crypto.RecoveryIDOffset] += 27
+		fmt.Fprint(w, hexutil.Encode(sig))
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *testSigner) address() common.Address {
+	for _, key := range s.keys {
+		return crypto.PubkeyToAddress(key.PublicKey)
+	}
+	return common.Address{}
+}
+
+func TestSigning(t *testing.T) {
+	signer := newTestSigner(2)
+	server := httptest.NewServer(signer)
+	defer server.Close()
+
+	backend, err := NewBackend(Config{Endpoint: server.URL})
+	if err != nil {
+		t.Fatalf("failed to create backend: %v", err)
+	}
+	wallet := backend.Wallets()[0]
+	if len(wallet.Accounts()) != 2 {
+		t.Fatalf("account count mismatch: have %d, want 2", len(wallet.Accounts()))
+	}
+	account := accounts.Account{Address: signer.address()}
+	if !wallet.Contains(account) {
+		t.Fatalf("wallet doesn't contain %v", account.Address)
+	}
+	// Sign transactions of all types and check the recovered sender
+	chainID := big.NewInt(1337)
+	to := common.Address{0xaa}
+	txs := []types.TxData{
+		&types.LegacyTx{Nonce: 1, GasPrice: big.NewInt(1), Gas: 21000, To: &to, Value: big.NewInt(1)},
+		&types.AccessListTx{ChainID: chainID, Nonce: 2, GasPrice: big.NewInt(1), Gas: 21000, To: &to, AccessList: types.AccessList{{Address: to}}},
+		&types.DynamicFeeTx{ChainID: chainID, Nonce: 3, GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(2), Gas: 21000, Data: []byte{1}},
+		&types.BlobTx{Nonce: 4, Gas: 21000, To: to, BlobHashes: []common.Hash{{0x01}}},
+	}
+	for i, data := range txs {
+		tx, err := wallet.SignTx(account, types.NewTx(data), chainID)
+		if err != nil {
+			t.Fatalf("tx %d: failed to sign: %v", i, err)
+		}
+		if from, err := types.Sender(types.LatestSignerForChainID(chainID), tx); err != nil || from != account.Address {
+			t.Errorf("tx %d: sender mismatch: have %v, want %v (%v)", i, from, account.Address, err)
+		}
+	}
+	// Unprotected legacy transactions are signed with the homestead rules
+	tx, err := wallet.SignTx(account, types.NewTx(txs[0]), nil)
+	if err != nil {
+		t.Fatalf("failed to sign unprotected tx: %v", err)
+	}
+	if from, _ := types.Sender(types.HomesteadSigner{}, tx); from != account.Address {
+		t.Errorf("unprotected sender mismatch: have %v, want %v", from, account.Address)
+	}
+	// Sign text and typed data
+	sig, err := wallet.SignText(account, []byte("hello"))
+	if err != nil {
+		t.Fatalf("failed to sign text: %v", err)
+	}
+	if pub, err := crypto.SigToPub(accounts.TextHash([]byte("hello")), sig); err != nil || crypto.PubkeyToAddress(*pub) != account.Address {
+		t.Errorf("text signer mismatch: %v", err)
+	}
+	typed := apitypes.TypedData{
+		Types: apitypes.Types{
+			"EIP712Domain": {{Name: "name", Type: "string"}},
+			"Mail":         {{Name: "contents", Type: "string"}},
+		},
+		PrimaryType: "Mail",
+		Domain:      apitypes.TypedDataDomain{Name: "test"},
+		Message:     apitypes.TypedDataMessage{"contents": "hello"},
+	}
+	hash, preimage, err := apitypes.TypedDataAndHash(typed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sig, err = wallet.SignData(account, accounts.MimetypeTypedData, []byte(preimage)); err != nil {
+		t.Fatalf("failed to sign typed data: %v", err)
+	}
+	if pub, err := crypto.SigToPub(hash, sig); err != nil || crypto.PubkeyToAddress(*pub) != account.Address {
+		t.Errorf("typed data signer mismatch: %v", err)
+	}
+	// Check the failure cases
+	if _, err := wallet.SignText(accounts.Account{Address: common.Address{1}}, []byte("hello")); err != accounts.ErrUnknownAccount {
+		t.Errorf("unknown account error mismatch: have %v, want %v", err, accounts.ErrUnknownAccount)
+	}
+	for id := range signer.keys {
+		delete(signer.keys, id) // The wallet still tracks the keys
+	}
+	var remoteErr *RemoteError
+	if _, err := wallet.SignText(account, []byte("hello")); !errors.As(err, &remoteErr) || remoteErr.StatusCode != http.StatusNotFound || remoteErr.Message != "Public Key not found" {
+		t.Errorf("remote error mismatch: %v", err)
+	}
+}
+
+func TestInvalidSignature(t *testing.T) {
+	signer := newTestSigner(1)
+	other, _ := crypto.GenerateKey()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Sign everything with the wrong key
+		if r.Method == http.MethodPost {
+			sig, _ := crypto.Sign(make([]byte, 32), other)
+			fmt.Fprint(w, hexutil.Encode(sig))
+			return
+		}
+		signer.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	wallet, err := NewWallet(Config{Endpoint: server.URL})
+	if err != nil {
+		t.Fatalf("failed to create wallet: %v", err)
+	}
+	if _, err := wallet.SignText(accounts.Account{Address: signer.address()}, []byte("hello")); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("error mismatch: have %v, want %v", err, ErrInvalidSignature)
+	}
+}
+
+func TestClientCertificate(t *testing.T) {
+	dir := t.TempDir()
+	cert, certFile, keyFile := makeCertificate(t, dir)
+
+	server := httptest.NewUnstartedServer(newTestSigner(1))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: x509.NewCertPool()}
+	server.TLS.ClientCAs.AddCert(cert)
+	server.StartTLS()
+	defer server.Close()
+
+	caFile := filepath.Join(dir, "ca.pem")
+	writePEM(t, caFile, "CERTIFICATE", server.Certificate().Raw)
+
+	if _, err := NewWallet(Config{Endpoint: server.URL, TLSCA: caFile}); err == nil {
+		t.Fatal("connected without client certificate")
+	}
+	wallet, err := NewWallet(Config{Endpoint: server.URL, TLSCA: caFile, TLSCert: certFile, TLSKey: keyFile})
+	if err != nil {
+		t.Fatalf("failed to connect with client certificate: %v", err)
+	}
+	if len(wallet.Accounts()) != 1 {
+		t.Errorf("account count mismatch: have %d, want 1", len(wallet.Accounts()))
+	}
+}
+
+// makeCertificate creates a self-signed client certificate, storing it and its
+// key in the given directory.
+func makeCertificate(t *testing.T, dir string) (*x509.Certificate, string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "client"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile := filepath.Join(dir, "client.pem"), filepath.Join(dir, "client.key")
+	writePEM(t, certFile, "CERTIFICATE", der)
+	writePEM(t, keyFile, "EC PRIVATE KEY", keyDer)
+	return cert, certFile, keyFile
+}
+
+func writePEM(t *testing.T, path string, kind string, blob []byte) {
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: kind, Bytes: blob}), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
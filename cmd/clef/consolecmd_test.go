@@ -122,3 +122,35 @@ func TestListWallets(t *testing.T) {
 		}
 	})
 }
+
+// TestPolicyDryrun tests clef policy-dryrun
+func TestPolicyDryrun(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	policyPath := filepath.Join(dir, "policy.yaml")
+	os.WriteFile(policyPath, []byte(`
+default: reject
+rules:
+  - name: office-hours
+    action: approve
+    to: [0xd9145cce52d386f254917e481eb44e9943f39138]
+    time: {start: "09:00", end: "17:00"}
+`), 0600)
+	requestPath := filepath.Join(dir, "request.json")
+	os.WriteFile(requestPath, []byte(`{"transaction": {
+		"from": "0x8a8eafb1cf62bfbeb1741769dae1a9dd47996192",
+		"to": "0xd9145cce52d386f254917e481eb44e9943f39138",
+		"gas": "0x5208", "value": "0x1", "nonce": "0x0"
+	}}`), 0600)
+
+	clef := runClef(t, "policy-dryrun", "--time", "2024-03-04T10:00:00Z", policyPath, requestPath)
+	if out := string(clef.Output()); !strings.Contains(out, `"rule": "office-hours"`) || !strings.Contains(out, `"action": "approve"`) {
+		t.Logf("Output\n%v", out)
+		t.Error("Failure")
+	}
+	clef = runClef(t, "policy-dryrun", "--time", "2024-03-04T20:00:00Z", policyPath, requestPath)
+	if out := string(clef.Output()); strings.Contains(out, `"rule"`) || !strings.Contains(out, `"action": "reject"`) {
+		t.Logf("Output\n%v", out)
+		t.Error("Failure")
+	}
+}
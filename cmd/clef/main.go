@@ -37,6 +37,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/accounts/web3signer"
 	"github.com/ethereum/go-ethereum/cmd/utils"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
@@ -52,6 +53,7 @@ import (
 	"github.com/ethereum/go-ethereum/signer/core"
 	"github.com/ethereum/go-ethereum/signer/core/apitypes"
 	"github.com/ethereum/go-ethereum/signer/fourbyte"
+	"github.com/ethereum/go-ethereum/signer/policy"
 	"github.com/ethereum/go-ethereum/signer/rules"
 	"github.com/ethereum/go-ethereum/signer/storage"
 	"github.com/mattn/go-colorable"
@@ -126,6 +128,23 @@ var (
 		Name:  "rules",
 		Usage: "Path to the rule file to auto-authorize requests with",
 	}
+	policyFlag = &cli.StringFlag{
+		Name:  "policy",
+		Usage: "Path to the declarative policy file (YAML or JSON) to auto-authorize requests with",
+	}
+	quorumFlag = &cli.StringFlag{
+		Name:  "quorum",
+		Usage: "Path to the quorum file (JSON) listing the approvers which must approve signing requests",
+	}
+	attestPolicyFlag = &cli.BoolFlag{
+		Name:  "policy",
+		Usage: "Attest a policy file instead of a rule file",
+	}
+	dryrunTimeFlag = &cli.TimestampFlag{
+		Name:   "time",
+		Usage:  "Time to evaluate the request at (RFC 3339), now if unset",
+		Layout: time.RFC3339,
+	}
 	stdiouiFlag = &cli.BoolFlag{
 		Name: "stdio-ui",
 		Usage: "Use STDIN/STDOUT as a channel for an external UI. " +
@@ -152,18 +171,19 @@ the rule-engine to work.`,
 	attestCommand = &cli.Command{
 		Action:    attestFile,
 		Name:      "attest",
-		Usage:     "Attest that a js-file is to be used",
+		Usage:     "Attest that a js-file or policy file is to be used",
 		ArgsUsage: "<sha256sum>",
 		Flags: []cli.Flag{
 			logLevelFlag,
 			configdirFlag,
 			signerSecretFlag,
+			attestPolicyFlag,
 		},
 		Description: `
 The attest command stores the sha256 of the rule.js-file that you want to use for automatic processing of
-incoming requests.
+incoming requests. With --policy, the sha256 of the declarative policy file is stored instead.
 
-Whenever you make an edit to the rule file, you need to use attestation to tell
+Whenever you make an edit to the rule or policy file, you need to use attestation to tell
 Clef that the file is 'safe' to execute.`,
 	}
 	setCredentialCommand = &cli.Command{
@@ -213,6 +233,24 @@ which can be used in lieu of an external UI.
 		Usage:  "Generate documentation about json-rpc format",
 		Description: `
 The gendoc generates example structures of the json-rpc communication types.
+`}
+	policyDryrunCommand = &cli.Command{
+		Action:    policyDryrun,
+		Name:      "policy-dryrun",
+		Usage:     "Evaluate a request against a policy file",
+		ArgsUsage: "<policyfile> <requestfile>",
+		Flags: []cli.Flag{
+			logLevelFlag,
+			customDBFlag,
+			dryrunTimeFlag,
+		},
+		Description: `
+The policy-dryrun command evaluates an approval request against a policy file,
+printing the decision and the name of the rule it matched. Nothing is signed.
+
+The request file contains a JSON encoded transaction, data or listing approval
+request, as sent to external UIs (see the gendoc command). Daily limits are
+evaluated as if nothing was spent yet.
 `}
 	listAccountsCommand = &cli.Command{
 		Action: listAccounts,
@@ -271,6 +309,10 @@ func init() {
 		utils.LightKDFFlag,
 		utils.NoUSBFlag,
 		utils.SmartCardDaemonPathFlag,
+		utils.Web3SignerFlag,
+		utils.Web3SignerTLSCertFlag,
+		utils.Web3SignerTLSKeyFlag,
+		utils.Web3SignerTLSCAFlag,
 		utils.HTTPListenAddrFlag,
 		utils.HTTPVirtualHostsFlag,
 		utils.IPCDisabledFlag,
@@ -281,6 +323,8 @@ func init() {
 		customDBFlag,
 		auditLogFlag,
 		ruleFlag,
+		policyFlag,
+		quorumFlag,
 		stdiouiFlag,
 		testFlag,
 		advancedMode,
@@ -294,6 +338,7 @@ func init() {
 		newAccountCommand,
 		importRawCommand,
 		gendocCommand,
+		policyDryrunCommand,
 		listAccountsCommand,
 		listWalletsCommand,
 	}
@@ -393,11 +438,71 @@ func attestFile(ctx *cli.Context) error {
 	// Initialize the encrypted storages
 	configStorage := storage.NewAESEncryptedStorage(filepath.Join(vaultLocation, "config.json"), confKey)
 	val := ctx.Args().First()
+	if ctx.Bool(attestPolicyFlag.Name) {
+		configStorage.Put("policy_sha256", val)
+		log.Info("Policy attestation updated", "sha256", val)
+		return nil
+	}
 	configStorage.Put("ruleset_sha256", val)
 	log.Info("Ruleset attestation updated", "sha256", val)
 	return nil
 }
 
+func policyDryrun(c *cli.Context) error {
+	if c.NArg() != 2 {
+		utils.Fatalf("This command requires a policy file and a request file.")
+	}
+	pol, err := policy.Load(c.Args().Get(0))
+	if err != nil {
+		utils.Fatalf("Invalid policy: %v", err)
+	}
+	blob, err := os.ReadFile(c.Args().Get(1))
+	if err != nil {
+		utils.Fatalf("Failed to read request: %v", err)
+	}
+	db, err := fourbyte.NewWithFile(c.String(customDBFlag.Name))
+	if err != nil {
+		utils.Fatalf(err.Error())
+	}
+	var (
+		engine = policy.NewEngine(nil, pol, db, storage.NewEphemeralStorage())
+		now    = time.Now()
+		fields map[string]json.RawMessage
+	)
+	if ts := c.Timestamp(dryrunTimeFlag.Name); ts != nil {
+		now = *ts
+	}
+	if err := json.Unmarshal(blob, &fields); err != nil {
+		utils.Fatalf("Invalid request: %v", err)
+	}
+	var decision *policy.Decision
+	switch {
+	case fields["transaction"] != nil:
+		req := new(core.SignTxRequest)
+		if err = json.Unmarshal(blob, req); err == nil {
+			decision, err = engine.EvaluateTx(req, now)
+		}
+	case fields["content_type"] != nil:
+		req := new(core.SignDataRequest)
+		if err = json.Unmarshal(blob, req); err == nil {
+			decision, err = engine.EvaluateData(req, now)
+		}
+	case fields["accounts"] != nil:
+		req := new(core.ListRequest)
+		if err = json.Unmarshal(blob, req); err == nil {
+			decision, err = engine.EvaluateListing(req, now)
+		}
+	default:
+		err = errors.New("unknown request type")
+	}
+	if err != nil {
+		utils.Fatalf("Failed to evaluate request: %v", err)
+	}
+	out, _ := json.MarshalIndent(decision, "", "  ")
+	fmt.Println(string(out))
+	return nil
+}
+
 func initInternalApi(c *cli.Context) (*core.UIServerAPI, core.UIClientAPI, error) {
 	if err := initialize(c); err != nil {
 		return nil, nil, err
@@ -640,6 +745,17 @@ func signer(c *cli.Context) error {
 		log.Info("Using CLI as UI-channel")
 		ui = core.NewCommandlineUI()
 	}
+	// Do we have a quorum of approvers? It replaces the manual approval of signing
+	// requests, which are left to it by the rules and policy.
+	var quorum *core.QuorumUI
+	if quorumFile := c.String(quorumFlag.Name); quorumFile != "" {
+		var err error
+		if quorum, err = loadQuorum(quorumFile, ui); err != nil {
+			utils.Fatalf("Failed to load quorum: %v", err)
+		}
+		ui = quorum
+		log.Info("Quorum approval configured", "file", quorumFile)
+	}
 	// 4bytedb data
 	fourByteLocal := c.String(customDBFlag.Name)
 	db, err := fourbyte.NewWithFile(fourByteLocal)
@@ -650,8 +766,9 @@ func signer(c *cli.Context) error {
 	log.Info("Loaded 4byte database", "embeds", embeds, "locals", locals, "local", fourByteLocal)
 
 	var (
-		api       core.ExternalAPI
-		pwStorage storage.Storage = &storage.NoStorage{}
+		api          core.ExternalAPI
+		pwStorage    storage.Storage = &storage.NoStorage{}
+		policyEngine *policy.Engine
 	)
 	configDir := c.String(configdirFlag.Name)
 	if stretchedKey, err := readMasterKey(c, ui); err != nil {
@@ -692,6 +809,32 @@ func signer(c *cli.Context) error {
 				}
 			}
 		}
+		// Do we have a policy file? It takes precedence over the rules, which
+		// handle the requests the policy leaves for manual processing.
+		if policyFile := c.String(policyFlag.Name); policyFile != "" {
+			blob, err := os.ReadFile(policyFile)
+			if err != nil {
+				log.Warn("Could not load policy, disabling", "file", policyFile, "err", err)
+			} else {
+				shasum := sha256.Sum256(blob)
+				foundShaSum := hex.EncodeToString(shasum[:])
+				storedShasum, _ := configStorage.Get("policy_sha256")
+				if storedShasum != foundShaSum {
+					log.Warn("Policy hash not attested, disabling", "hash", foundShaSum, "attested", storedShasum)
+				} else {
+					pol, err := policy.Parse(blob)
+					if err != nil {
+						utils.Fatalf("Invalid policy: %v", err)
+					}
+					policyKey := crypto.Keccak256([]byte("policystorage"), stretchedKey)
+					policyStorage := storage.NewAESEncryptedStorage(filepath.Join(vaultLocation, "policystorage.json"), policyKey)
+
+					policyEngine = policy.NewEngine(ui, pol, db, policyStorage)
+					ui = policyEngine
+					log.Info("Policy engine configured", "file", policyFile, "rules", len(pol.Rules))
+				}
+			}
+		}
 	}
 	var (
 		chainId  = c.Int64(chainIdFlag.Name)
@@ -705,6 +848,19 @@ func signer(c *cli.Context) error {
 		"light-kdf", lightKdf, "advanced", advanced)
 	am := core.StartClefAccountManager(ksLoc, nousb, lightKdf, scpath)
 	defer am.Close()
+	if endpoint := c.String(utils.Web3SignerFlag.Name); endpoint != "" {
+		backend, err := web3signer.NewBackend(web3signer.Config{
+			Endpoint: endpoint,
+			TLSCert:  c.String(utils.Web3SignerTLSCertFlag.Name),
+			TLSKey:   c.String(utils.Web3SignerTLSKeyFlag.Name),
+			TLSCA:    c.String(utils.Web3SignerTLSCAFlag.Name),
+		})
+		if err != nil {
+			utils.Fatalf("Failed to connect to remote signer: %v", err)
+		}
+		log.Info("Using remote signer", "url", endpoint)
+		am.AddBackend(backend)
+	}
 	apiImpl := core.NewSignerAPI(am, chainId, nousb, ui, db, advanced, pwStorage)
 
 	// Establish the bidirectional communication, by creating a new UI backend and registering
@@ -714,10 +870,17 @@ func signer(c *cli.Context) error {
 
 	// Audit logging
 	if logfile := c.String(auditLogFlag.Name); logfile != "" {
-		api, err = core.NewAuditLogger(logfile, api)
+		auditLogger, err := core.NewAuditLogger(logfile, api)
 		if err != nil {
 			utils.Fatalf(err.Error())
 		}
+		if policyEngine != nil {
+			policyEngine.SetAuditLogger(auditLogger.Logger())
+		}
+		if quorum != nil {
+			quorum.SetAuditLogger(auditLogger.Logger())
+		}
+		api = auditLogger
 		log.Info("Audit logs configured", "file", logfile)
 	}
 	// register signer API with server
@@ -797,6 +960,46 @@ func signer(c *cli.Context) error {
 
 // DefaultConfigDir is the default config directory to use for the vaults and other
 // persistence requirements.
+// quorumConfig is the JSON representation of the quorum of approvers.
+type quorumConfig struct {
+	Threshold int    `json:"threshold"`
+	Timeout   string `json:"timeout"`
+	Approvers []struct {
+		Name    string          `json:"name"`
+		Address *common.Address `json:"address"` // Address approval messages are signed with
+		UI      string          `json:"ui"`      // Endpoint of the approver's UI
+	} `json:"approvers"`
+}
+
+// loadQuorum reads the quorum file and connects to the UIs of the approvers,
+// creating the UI approving signing requests.
+func loadQuorum(path string, ui core.UIClientAPI) (*core.QuorumUI, error) {
+	blob, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var config quorumConfig
+	if err := json.Unmarshal(blob, &config); err != nil {
+		return nil, err
+	}
+	timeout := 10 * time.Minute
+	if config.Timeout != "" {
+		if timeout, err = time.ParseDuration(config.Timeout); err != nil {
+			return nil, fmt.Errorf("invalid timeout: %v", err)
+		}
+	}
+	approvers := make([]core.Approver, len(config.Approvers))
+	for i, approver := range config.Approvers {
+		approvers[i] = core.Approver{Name: approver.Name, Address: approver.Address}
+		if approver.UI != "" {
+			if approvers[i].UI, err = core.DialUI(approver.UI); err != nil {
+				return nil, fmt.Errorf("failed to connect to UI of %q: %v", approver.Name, err)
+			}
+		}
+	}
+	return core.NewQuorumUI(ui, approvers, config.Threshold, timeout)
+}
+
 func DefaultConfigDir() string {
 	// Try to place the data folder in the user's home dir
 	home := flags.HomeDir()
@@ -0,0 +1,326 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	bls "github.com/protolambda/bls12-381-util"
+	"github.com/urfave/cli/v2"
+)
+
+type outputBLSGenerate struct {
+	PublicKey string
+	Path      string `json:",omitempty"`
+}
+
+type outputBLSInspect struct {
+	PublicKey   string
+	Path        string `json:",omitempty"`
+	Description string `json:",omitempty"`
+	UUID        string
+	SecretKey   string `json:",omitempty"`
+}
+
+type outputBLSVerify struct {
+	Success bool
+}
+
+var (
+	mnemonicFileFlag = &cli.StringFlag{
+		Name:  "mnemonicfile",
+		Usage: "file containing the BIP-39 mnemonic to derive the key from",
+	}
+	blsPathFlag = &cli.StringFlag{
+		Name:  "path",
+		Usage: "EIP-2334 derivation path of the key derived from the mnemonic",
+		Value: keystore.DefaultBLSSigningPath,
+	}
+	pbkdf2Flag = &cli.BoolFlag{
+		Name:  "pbkdf2",
+		Usage: "use PBKDF2 instead of scrypt to derive the encryption key",
+	}
+	hexMessageFlag = &cli.BoolFlag{
+		Name:  "hex",
+		Usage: "the message is hex encoded (e.g. a signing root)",
+	}
+)
+
+var commandBLS = &cli.Command{
+	Name:  "bls",
+	Usage: "manage EIP-2335 BLS12-381 keyfiles",
+	Description: `
+Manage EIP-2335 keyfiles holding the BLS12-381 keys of consensus layer validators.`,
+	Subcommands: []*cli.Command{
+		commandBLSGenerate,
+		commandBLSInspect,
+		commandBLSSign,
+		commandBLSVerify,
+	},
+}
+
+var commandBLSGenerate = &cli.Command{
+	Name:      "generate",
+	Usage:     "generate new BLS keyfile",
+	ArgsUsage: "[ <keyfile> ]",
+	Description: `
+Generate a new EIP-2335 keyfile holding a random BLS key.
+
+If you want to derive the key from a BIP-39 mnemonic as specified by ERC-2333,
+it can be specified by setting --mnemonicfile with the location of the file
+containing the mnemonic, and --path with the EIP-2334 path of the key.
+`,
+	Flags: []cli.Flag{
+		passphraseFlag,
+		jsonFlag,
+		mnemonicFileFlag,
+		blsPathFlag,
+		lightKDFFlag,
+		pbkdf2Flag,
+	},
+	Action: func(ctx *cli.Context) error {
+		// Check if keyfile path given and make sure it doesn't already exist.
+		keyfilepath := ctx.Args().First()
+		if keyfilepath == "" {
+			keyfilepath = defaultKeyfileName
+		}
+		if _, err := os.Stat(keyfilepath); err == nil {
+			utils.Fatalf("Keyfile already exists at %s.", keyfilepath)
+		} else if !os.IsNotExist(err) {
+			utils.Fatalf("Error checking if keyfile exists: %v", err)
+		}
+
+		var key *keystore.BLSKey
+		var err error
+		if file := ctx.String(mnemonicFileFlag.Name); file != "" {
+			// Derive the key from the mnemonic.
+			mnemonic, err := os.ReadFile(file)
+			if err != nil {
+				utils.Fatalf("Can't read mnemonic file: %v", err)
+			}
+			key, err = keystore.NewBLSKeyFromMnemonic(strings.TrimSpace(string(mnemonic)), "", ctx.String(blsPathFlag.Name))
+			if err != nil {
+				utils.Fatalf("Failed to derive key: %v", err)
+			}
+		} else {
+			// If not derived, generate random.
+			key, err = keystore.NewBLSKey()
+			if err != nil {
+				utils.Fatalf("Failed to generate random key: %v", err)
+			}
+		}
+
+		// Encrypt key with passphrase.
+		passphrase := getPassphrase(ctx, true)
+		var keyjson []byte
+		switch {
+		case ctx.Bool(pbkdf2Flag.Name):
+			keyjson, err = keystore.EncryptBLSKeyPBKDF2(key, passphrase, keystore.StandardPBKDF2C)
+		case ctx.Bool(lightKDFFlag.Name):
+			keyjson, err = keystore.EncryptBLSKey(key, passphrase, keystore.LightScryptN, keystore.LightScryptP)
+		default:
+			keyjson, err = keystore.EncryptBLSKey(key, passphrase, keystore.StandardScryptN, keystore.StandardScryptP)
+		}
+		if err != nil {
+			utils.Fatalf("Error encrypting key: %v", err)
+		}
+
+		// Store the file to disk.
+		if err := os.MkdirAll(filepath.Dir(keyfilepath), 0700); err != nil {
+			utils.Fatalf("Could not create directory %s", filepath.Dir(keyfilepath))
+		}
+		if err := os.WriteFile(keyfilepath, keyjson, 0600); err != nil {
+			utils.Fatalf("Failed to write keyfile to %s: %v", keyfilepath, err)
+		}
+
+		// Output some information.
+		pubkey := key.PublicKey()
+		out := outputBLSGenerate{
+			PublicKey: hexutil.Encode(pubkey[:]),
+			Path:      key.Path,
+		}
+		if ctx.Bool(jsonFlag.Name) {
+			mustPrintJSON(out)
+		} else {
+			fmt.Println("Public key:", out.PublicKey)
+			if out.Path != "" {
+				fmt.Println("Path:      ", out.Path)
+			}
+		}
+		return nil
+	},
+}
+
+var commandBLSInspect = &cli.Command{
+	Name:      "inspect",
+	Usage:     "inspect a BLS keyfile",
+	ArgsUsage: "<keyfile>",
+	Description: `
+Print various information about the BLS keyfile.
+
+Secret key information can be printed by using the --private flag;
+make sure to use this feature with great caution!`,
+	Flags: []cli.Flag{
+		passphraseFlag,
+		jsonFlag,
+		privateFlag,
+	},
+	Action: func(ctx *cli.Context) error {
+		key := loadBLSKey(ctx)
+
+		// Output all relevant information we can retrieve.
+		pubkey := key.PublicKey()
+		out := outputBLSInspect{
+			PublicKey:   hexutil.Encode(pubkey[:]),
+			Path:        key.Path,
+			Description: key.Description,
+			UUID:        key.ID.String(),
+		}
+		showPrivate := ctx.Bool(privateFlag.Name)
+		if showPrivate {
+			secret := key.SecretKey.Serialize()
+			out.SecretKey = hexutil.Encode(secret[:])
+		}
+
+		if ctx.Bool(jsonFlag.Name) {
+			mustPrintJSON(out)
+		} else {
+			fmt.Println("Public key:    ", out.PublicKey)
+			if out.Path != "" {
+				fmt.Println("Path:          ", out.Path)
+			}
+			if out.Description != "" {
+				fmt.Println("Description:   ", out.Description)
+			}
+			fmt.Println("UUID:          ", out.UUID)
+			if showPrivate {
+				fmt.Println("Secret key:    ", out.SecretKey)
+			}
+		}
+		return nil
+	},
+}
+
+var commandBLSSign = &cli.Command{
+	Name:      "sign",
+	Usage:     "sign a message with a BLS keyfile",
+	ArgsUsage: "<keyfile> <message>",
+	Description: `
+Sign the message with a BLS keyfile, using the proof of possession scheme of the
+consensus layer.
+
+To sign a message contained in a file, use the --msgfile flag. To sign a hex
+encoded message (e.g. a signing root), use the --hex flag.
+`,
+	Flags: []cli.Flag{
+		passphraseFlag,
+		jsonFlag,
+		msgfileFlag,
+		hexMessageFlag,
+	},
+	Action: func(ctx *cli.Context) error {
+		message := getBLSMessage(ctx, 1)
+		key := loadBLSKey(ctx)
+
+		signature := key.Sign(message)
+		out := outputSign{Signature: hexutil.Encode(signature[:])}
+		if ctx.Bool(jsonFlag.Name) {
+			mustPrintJSON(out)
+		} else {
+			fmt.Println("Signature:", out.Signature)
+		}
+		return nil
+	},
+}
+
+var commandBLSVerify = &cli.Command{
+	Name:      "verify",
+	Usage:     "verify the BLS signature of a signed message",
+	ArgsUsage: "<pubkey> <signature> <message>",
+	Description: `
+Verify the BLS signature of the message.
+It is possible to refer to a file containing the message.`,
+	Flags: []cli.Flag{
+		jsonFlag,
+		msgfileFlag,
+		hexMessageFlag,
+	},
+	Action: func(ctx *cli.Context) error {
+		pubkeyBlob, err := hexutil.Decode(ctx.Args().First())
+		if err != nil || len(pubkeyBlob) != 48 {
+			utils.Fatalf("Invalid public key: %s", ctx.Args().First())
+		}
+		signatureBlob, err := hexutil.Decode(ctx.Args().Get(1))
+		if err != nil || len(signatureBlob) != 96 {
+			utils.Fatalf("Invalid signature: %s", ctx.Args().Get(1))
+		}
+		message := getBLSMessage(ctx, 2)
+
+		pubkey := new(bls.Pubkey)
+		if err := pubkey.Deserialize((*[48]byte)(pubkeyBlob)); err != nil {
+			utils.Fatalf("Invalid public key: %v", err)
+		}
+		signature := new(bls.Signature)
+		if err := signature.Deserialize((*[96]byte)(signatureBlob)); err != nil {
+			utils.Fatalf("Invalid signature: %v", err)
+		}
+		out := outputBLSVerify{Success: bls.Verify(pubkey, message, signature)}
+		if ctx.Bool(jsonFlag.Name) {
+			mustPrintJSON(out)
+		} else if out.Success {
+			fmt.Println("Signature verification successful!")
+		} else {
+			fmt.Println("Signature verification failed!")
+		}
+		return nil
+	},
+}
+
+// loadBLSKey loads and decrypts the BLS keyfile given as the first argument.
+func loadBLSKey(ctx *cli.Context) *keystore.BLSKey {
+	keyfilepath := ctx.Args().First()
+	keyjson, err := os.ReadFile(keyfilepath)
+	if err != nil {
+		utils.Fatalf("Failed to read the keyfile at '%s': %v", keyfilepath, err)
+	}
+	passphrase := getPassphrase(ctx, false)
+	key, err := keystore.DecryptBLSKey(keyjson, passphrase)
+	if err != nil {
+		utils.Fatalf("Error decrypting key: %v", err)
+	}
+	return key
+}
+
+// getBLSMessage retrieves the message to sign or verify, decoding it from hex
+// if requested.
+func getBLSMessage(ctx *cli.Context, msgarg int) []byte {
+	message := getMessage(ctx, msgarg)
+	if !ctx.Bool(hexMessageFlag.Name) {
+		return message
+	}
+	blob, err := hexutil.Decode(strings.TrimSpace(string(message)))
+	if err != nil {
+		utils.Fatalf("Invalid hex message: %v", err)
+	}
+	return blob
+}
@@ -0,0 +1,77 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBLSSignVerify(t *testing.T) {
+	t.Parallel()
+	tmpdir := t.TempDir()
+
+	keyfile := filepath.Join(tmpdir, "the-keyfile")
+	mnemonic := filepath.Join(tmpdir, "the-mnemonic")
+	message := "0x0102030405060708091011121314151617181920212223242526272829303132"
+
+	if err := os.WriteFile(mnemonic, []byte("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	// Derive the key from the mnemonic.
+	generate := runEthkey(t, "bls", "generate", "--lightkdf", "--mnemonicfile", mnemonic, keyfile)
+	generate.Expect(`
+!! Unsupported terminal, password will be echoed.
+Password: {{.InputLine "foobar"}}
+Repeat password: {{.InputLine "foobar"}}
+`)
+	_, matches := generate.ExpectRegexp(`Public key: (0x[0-9a-f]{96})\nPath:       m/12381/3600/0/0/0\n`)
+	pubkey := matches[1]
+	generate.ExpectExit()
+
+	// Inspect the key.
+	inspect := runEthkey(t, "bls", "inspect", keyfile)
+	inspect.Expect(`
+!! Unsupported terminal, password will be echoed.
+Password: {{.InputLine "foobar"}}
+`)
+	_, matches = inspect.ExpectRegexp(`Public key:     (0x[0-9a-f]{96})\nPath:           m/12381/3600/0/0/0\nUUID:           [0-9a-f-]{36}\n`)
+	inspect.ExpectExit()
+	if matches[1] != pubkey {
+		t.Errorf("inspected public key mismatch: have %s, want %s", matches[1], pubkey)
+	}
+
+	// Sign a message.
+	sign := runEthkey(t, "bls", "sign", "--hex", keyfile, message)
+	sign.Expect(`
+!! Unsupported terminal, password will be echoed.
+Password: {{.InputLine "foobar"}}
+`)
+	_, matches = sign.ExpectRegexp(`Signature: (0x[0-9a-f]{192})\n`)
+	signature := matches[1]
+	sign.ExpectExit()
+
+	// Verify the message, and a different one.
+	verify := runEthkey(t, "bls", "verify", "--hex", pubkey, signature, message)
+	verify.Expect("Signature verification successful!\n")
+	verify.ExpectExit()
+
+	verify = runEthkey(t, "bls", "verify", pubkey, signature, message)
+	verify.Expect("Signature verification failed!\n")
+	verify.ExpectExit()
+}
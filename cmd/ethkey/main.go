@@ -38,6 +38,7 @@ func init() {
 		commandChangePassphrase,
 		commandSignMessage,
 		commandVerifyMessage,
+		commandBLS,
 	}
 }
 
@@ -26,11 +26,17 @@ import (
 	"github.com/urfave/cli/v2"
 )
 
+var CompileForkFlag = &cli.StringFlag{
+	Name:  "fork",
+	Usage: "Fork whose instruction set the code targets (default: all instructions)",
+}
+
 var compileCommand = &cli.Command{
 	Action:    compileCmd,
 	Name:      "compile",
 	Usage:     "Compiles easm source to evm binary",
 	ArgsUsage: "<file>",
+	Flags:     []cli.Flag{CompileForkFlag},
 }
 
 func compileCmd(ctx *cli.Context) error {
@@ -46,7 +52,7 @@ func compileCmd(ctx *cli.Context) error {
 		return err
 	}
 
-	bin, err := compiler.Compile(fn, src, debug)
+	bin, err := compiler.Compile(fn, src, ctx.String(CompileForkFlag.Name), debug)
 	if err != nil {
 		return err
 	}
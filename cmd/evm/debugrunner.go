@@ -0,0 +1,121 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ethereum/go-ethereum/cmd/evm/internal/debugger"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/internal/flags"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	DebugScriptFlag = &cli.StringFlag{
+		Name:     "script",
+		Usage:    "File containing debugger commands to execute instead of reading them from stdin",
+		Category: flags.VMCategory,
+	}
+	BreakpointFlag = &cli.StringSliceFlag{
+		Name:     "break",
+		Usage:    "Initial breakpoints, as 'pc:<n>', 'op:<NAME>' or 'addr:<address>'",
+		Category: flags.VMCategory,
+	}
+	SourceMapFlag = &cli.StringFlag{
+		Name:     "srcmap",
+		Usage:    "solc standard-JSON output file with source maps of the debugged contract",
+		Category: flags.VMCategory,
+	}
+	ContractFlag = &cli.StringFlag{
+		Name:     "contract",
+		Usage:    "Name of the debugged contract in the solc output ('<file>:<name>' or '<name>')",
+		Category: flags.VMCategory,
+	}
+	SourceRootFlag = &cli.StringFlag{
+		Name:     "srcroot",
+		Usage:    "Directory the Solidity sources are resolved against (defaults to the directory of --srcmap)",
+		Category: flags.VMCategory,
+	}
+	NoStopFlag = &cli.BoolFlag{
+		Name:     "nostop",
+		Usage:    "Do not pause before the first instruction, only on breakpoints",
+		Category: flags.VMCategory,
+	}
+)
+
+var debugCommand = &cli.Command{
+	Action:    debugCmd,
+	Name:      "debug",
+	Usage:     "Interactively debug evm code",
+	ArgsUsage: "<code>",
+	Description: `The debug command runs EVM code like 'run', pausing on breakpoints to step
+through the execution and inspect the stack, memory and storage. Commands are
+read from stdin or from the file given via --script, so sessions can be run
+non-interactively. If solc standard-JSON output is given via --srcmap, the
+Solidity source line of every instruction is displayed, and the contract's
+bytecode is used when no code is specified.`,
+	Flags: flags.Merge(vmFlags, []cli.Flag{
+		DebugScriptFlag,
+		BreakpointFlag,
+		SourceMapFlag,
+		ContractFlag,
+		SourceRootFlag,
+		NoStopFlag,
+	}),
+}
+
+func debugCmd(ctx *cli.Context) error {
+	var in io.Reader = os.Stdin
+	if script := ctx.String(DebugScriptFlag.Name); script != "" {
+		f, err := os.Open(script)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	dbg := debugger.New(in, os.Stdout, !ctx.Bool(NoStopFlag.Name))
+
+	for _, spec := range ctx.StringSlice(BreakpointFlag.Name) {
+		b, err := debugger.ParseBreakpoint(spec)
+		if err != nil {
+			return err
+		}
+		dbg.AddBreakpoint(b)
+	}
+	if path := ctx.String(SourceMapFlag.Name); path != "" {
+		srcmap, err := debugger.LoadSourceMap(path, ctx.String(ContractFlag.Name), ctx.String(SourceRootFlag.Name), ctx.Bool(CreateFlag.Name))
+		if err != nil {
+			return err
+		}
+		dbg.SetSourceMap(srcmap)
+
+		// Debug the compiled contract, unless some other code was requested
+		if !ctx.IsSet(CodeFlag.Name) && !ctx.IsSet(CodeFileFlag.Name) && ctx.Args().Len() == 0 {
+			if len(srcmap.Code) == 0 {
+				return fmt.Errorf("solc output has no bytecode for %q", ctx.String(ContractFlag.Name))
+			}
+			if err := ctx.Set(CodeFlag.Name, hexutil.Encode(srcmap.Code)[2:]); err != nil {
+				return err
+			}
+		}
+	}
+	return runEVM(ctx, dbg)
+}
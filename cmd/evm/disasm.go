@@ -17,6 +17,7 @@
 package main
 
 import (
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"os"
@@ -26,11 +27,17 @@ import (
 	"github.com/urfave/cli/v2"
 )
 
+var DisasmSourceFlag = &cli.BoolFlag{
+	Name:  "source",
+	Usage: "Output easm source which compiles back to the same binary",
+}
+
 var disasmCommand = &cli.Command{
 	Action:    disasmCmd,
 	Name:      "disasm",
 	Usage:     "Disassembles evm binary",
 	ArgsUsage: "<file>",
+	Flags:     []cli.Flag{DisasmSourceFlag},
 }
 
 func disasmCmd(ctx *cli.Context) error {
@@ -50,6 +57,14 @@ func disasmCmd(ctx *cli.Context) error {
 	}
 
 	code := strings.TrimSpace(in)
+	if ctx.Bool(DisasmSourceFlag.Name) {
+		bin, err := hex.DecodeString(code)
+		if err != nil {
+			return err
+		}
+		fmt.Print(asm.DisassembleSource(bin))
+		return nil
+	}
 	fmt.Printf("%v\n", code)
 	return asm.PrintDisassembled(code)
 }
@@ -0,0 +1,114 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/cmd/evm/internal/fuzzer"
+	"github.com/ethereum/go-ethereum/tests"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	FuzzReferenceFlag = &cli.StringFlag{
+		Name:  "ref",
+		Usage: "Command of the reference t8n implementation, e.g. 'evm t8n' (required)",
+	}
+	FuzzForkFlag = &cli.StringFlag{
+		Name:  "fork",
+		Usage: "Fork to execute the cases under (post-merge forks only)",
+		Value: "Cancun",
+	}
+	FuzzSeedFlag = &cli.Int64Flag{
+		Name:  "seed",
+		Usage: "Seed of the first case, subsequent cases use incrementing seeds",
+	}
+	FuzzIterationsFlag = &cli.IntFlag{
+		Name:  "iterations",
+		Usage: "Number of cases to run (0 = unlimited)",
+		Value: 1000,
+	}
+	FuzzMaxCodeFlag = &cli.IntFlag{
+		Name:  "maxcode",
+		Usage: "Maximum size of generated contracts",
+		Value: 256,
+	}
+	FuzzOutDirFlag = &cli.StringFlag{
+		Name:  "outdir",
+		Usage: "Directory failing cases are saved to as state tests",
+		Value: "fuzz-failures",
+	}
+	FuzzNoShrinkFlag = &cli.BoolFlag{
+		Name:  "noshrink",
+		Usage: "Save failing cases without minimising them",
+	}
+)
+
+var fuzzCommand = &cli.Command{
+	Action: fuzzCmd,
+	Name:   "fuzz",
+	Usage:  "Differentially fuzzes the evm against a reference t8n implementation",
+	Description: `The fuzz command generates random contracts and state, executes a transaction
+calling them both on the local interpreter and through the reference t8n tool,
+and compares the EIP-3155 traces and post-state roots. Failing cases are shrunk
+and saved as state tests, which can be replayed with 'evm statetest'.`,
+	Flags: []cli.Flag{
+		FuzzReferenceFlag,
+		FuzzForkFlag,
+		FuzzSeedFlag,
+		FuzzIterationsFlag,
+		FuzzMaxCodeFlag,
+		FuzzOutDirFlag,
+		FuzzNoShrinkFlag,
+	},
+}
+
+func fuzzCmd(ctx *cli.Context) error {
+	ref := strings.Fields(ctx.String(FuzzReferenceFlag.Name))
+	if len(ref) == 0 {
+		return errors.New("reference command required (--ref)")
+	}
+	fork := ctx.String(FuzzForkFlag.Name)
+	if _, _, err := tests.GetChainConfig(fork); err != nil {
+		return err
+	}
+	cfg := fuzzer.Config{
+		Seed:       ctx.Int64(FuzzSeedFlag.Name),
+		Iterations: ctx.Int(FuzzIterationsFlag.Name),
+		Fork:       fork,
+		MaxCode:    ctx.Int(FuzzMaxCodeFlag.Name),
+		OutDir:     ctx.String(FuzzOutDirFlag.Name),
+		NoShrink:   ctx.Bool(FuzzNoShrinkFlag.Name),
+	}
+	failures, err := fuzzer.New(cfg, fuzzer.Local{}, &fuzzer.Binary{Command: ref}).Run()
+	for _, failure := range failures {
+		fmt.Printf("seed %d: %v\n", failure.Seed, failure.Diff)
+		if failure.Path != "" {
+			fmt.Printf("  saved to %s\n", failure.Path)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("%d failing cases", len(failures))
+	}
+	return nil
+}
@@ -20,18 +20,33 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/asm"
+	"github.com/ethereum/go-ethereum/tests"
 )
 
-func Compile(fn string, src []byte, debug bool) (string, error) {
+// Compile compiles the easm source of file fn. If fork is non-empty, only the
+// instructions available in the fork are accepted.
+func Compile(fn string, src []byte, fork string, debug bool) (string, error) {
 	compiler := asm.NewCompiler(debug)
+	compiler.SetFile(fn)
+	if fork != "" {
+		config, _, err := tests.GetChainConfig(fork)
+		if err != nil {
+			return "", err
+		}
+		isMerge := config.TerminalTotalDifficulty != nil && config.TerminalTotalDifficulty.Sign() == 0
+		if err := compiler.SetRules(config.Rules(common.Big0, isMerge, 0)); err != nil {
+			return "", err
+		}
+	}
 	compiler.Feed(asm.Lex(src, debug))
 
 	bin, compileErrors := compiler.Compile()
 	if len(compileErrors) > 0 {
 		// report errors
 		for _, err := range compileErrors {
-			fmt.Printf("%s:%v\n", fn, err)
+			fmt.Println(err)
 		}
 		return "", errors.New("compiling failed")
 	}
@@ -0,0 +1,492 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+// Package debugger implements an interactive, line oriented EVM debugger which
+// hooks into the interpreter as a vm.EVMLogger.
+package debugger
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// Breakpoint kinds.
+const (
+	BreakPC      = "pc"
+	BreakOp      = "op"
+	BreakAddress = "addr"
+)
+
+// Breakpoint pauses execution when the interpreter is about to execute an
+// instruction matching it. Address breakpoints trigger on the first instruction
+// of every call frame executing in the context of that address.
+type Breakpoint struct {
+	ID      int
+	Kind    string
+	PC      uint64
+	Op      vm.OpCode
+	Address common.Address
+}
+
+func (b *Breakpoint) String() string {
+	switch b.Kind {
+	case BreakPC:
+		return fmt.Sprintf("#%d pc %d", b.ID, b.PC)
+	case BreakOp:
+		return fmt.Sprintf("#%d op %v", b.ID, b.Op)
+	default:
+		return fmt.Sprintf("#%d addr %v", b.ID, b.Address)
+	}
+}
+
+// ParseBreakpoint parses a breakpoint specification of the form "pc <n>",
+// "op <NAME>" or "addr <0x...>", where the kind may also be separated by a
+// colon. A bare number is taken as a pc.
+func ParseBreakpoint(spec string) (*Breakpoint, error) {
+	fields := strings.Fields(strings.Replace(spec, ":", " ", 1))
+	if len(fields) == 1 {
+		fields = []string{BreakPC, fields[0]}
+	}
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("invalid breakpoint %q", spec)
+	}
+	b := &Breakpoint{Kind: fields[0]}
+	switch fields[0] {
+	case BreakPC:
+		pc, err := strconv.ParseUint(fields[1], 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pc %q", fields[1])
+		}
+		b.PC = pc
+	case BreakOp:
+		name := strings.ToUpper(fields[1])
+		op := vm.StringToOp(name)
+		if op == vm.STOP && name != "STOP" {
+			return nil, fmt.Errorf("unknown opcode %q", fields[1])
+		}
+		b.Op = op
+	case BreakAddress:
+		if !common.IsHexAddress(fields[1]) {
+			return nil, fmt.Errorf("invalid address %q", fields[1])
+		}
+		b.Address = common.HexToAddress(fields[1])
+	default:
+		return nil, fmt.Errorf("unknown breakpoint kind %q", fields[0])
+	}
+	return b, nil
+}
+
+// Debugger is a vm.EVMLogger which pauses the interpreter on breakpoints and
+// reads commands from an input stream until told to resume. Since commands are
+// consumed from a plain reader, a session can be scripted for use in CI.
+type Debugger struct {
+	in  *bufio.Scanner
+	out io.Writer
+
+	env     *vm.EVM
+	srcmap  *SourceMap
+	breaks  []*Breakpoint
+	breakID int
+
+	stepping  bool // pause on the next instruction
+	stepDepth int  // if non-zero, only pause at or above this call depth
+	steps     int  // remaining instructions to step before pausing
+	detached  bool // input exhausted or quit, run to completion
+	entered   bool // a new call frame was just entered
+
+	slots map[common.Address]map[common.Hash]struct{} // storage slots accessed per contract
+
+	// Current interpreter state, valid while paused
+	pc    uint64
+	op    vm.OpCode
+	gas   uint64
+	cost  uint64
+	depth int
+	scope *vm.ScopeContext
+}
+
+// New creates a debugger reading commands from in and writing to out. If
+// stopOnEntry is set, execution pauses before the first instruction.
+func New(in io.Reader, out io.Writer, stopOnEntry bool) *Debugger {
+	return &Debugger{
+		in:       bufio.NewScanner(in),
+		out:      out,
+		stepping: stopOnEntry,
+		slots:    make(map[common.Address]map[common.Hash]struct{}),
+	}
+}
+
+// SetSourceMap configures the source map used to display Solidity sources. It
+// applies to every frame executing the map's bytecode.
+func (d *Debugger) SetSourceMap(m *SourceMap) {
+	d.srcmap = m
+}
+
+// AddBreakpoint registers a new breakpoint and returns it with its ID set.
+func (d *Debugger) AddBreakpoint(b *Breakpoint) *Breakpoint {
+	d.breakID++
+	b.ID = d.breakID
+	d.breaks = append(d.breaks, b)
+	return b
+}
+
+// DeleteBreakpoint removes the breakpoint with the given ID.
+func (d *Debugger) DeleteBreakpoint(id int) bool {
+	for i, b := range d.breaks {
+		if b.ID == id {
+			d.breaks = append(d.breaks[:i], d.breaks[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (d *Debugger) CaptureTxStart(gasLimit uint64) {}
+
+func (d *Debugger) CaptureTxEnd(restGas uint64) {}
+
+func (d *Debugger) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	d.env = env
+	d.entered = true
+	if create {
+		fmt.Fprintf(d.out, "create from %v, gas %d, value %v\n", from, gas, value)
+	} else {
+		fmt.Fprintf(d.out, "call %v -> %v, gas %d, value %v\n", from, to, gas, value)
+	}
+}
+
+func (d *Debugger) CaptureEnd(output []byte, gasUsed uint64, err error) {
+	fmt.Fprintf(d.out, "execution finished, gas used %d\n", gasUsed)
+	fmt.Fprintf(d.out, "output: %#x\n", output)
+	if err != nil {
+		fmt.Fprintf(d.out, "error: %v\n", err)
+	}
+}
+
+func (d *Debugger) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	d.entered = true
+	if !d.detached {
+		fmt.Fprintf(d.out, "enter %v %v -> %v, gas %d\n", typ, from, to, gas)
+	}
+}
+
+func (d *Debugger) CaptureExit(output []byte, gasUsed uint64, err error) {
+	if !d.detached {
+		fmt.Fprintf(d.out, "exit, gas used %d, output %#x", gasUsed, output)
+		if err != nil {
+			fmt.Fprintf(d.out, ", error: %v", err)
+		}
+		fmt.Fprintln(d.out)
+	}
+}
+
+func (d *Debugger) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+	if !d.detached {
+		fmt.Fprintf(d.out, "fault at pc %d (%v): %v\n", pc, op, err)
+	}
+}
+
+// CaptureState is invoked before every instruction and runs the command loop
+// whenever execution should pause.
+func (d *Debugger) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if d.detached {
+		return
+	}
+	if (op == vm.SLOAD || op == vm.SSTORE) && len(scope.Stack.Data()) > 0 {
+		addr := scope.Contract.Address()
+		if d.slots[addr] == nil {
+			d.slots[addr] = make(map[common.Hash]struct{})
+		}
+		d.slots[addr][common.Hash(scope.Stack.Back(0).Bytes32())] = struct{}{}
+	}
+	d.pc, d.op, d.gas, d.cost, d.depth, d.scope = pc, op, gas, cost, depth, scope
+
+	var hit *Breakpoint
+	for _, b := range d.breaks {
+		if d.matches(b) {
+			hit = b
+			break
+		}
+	}
+	d.entered = false
+	if hit == nil && !d.shouldStep() {
+		return
+	}
+	if hit != nil {
+		fmt.Fprintf(d.out, "breakpoint %v\n", hit)
+	}
+	d.stepping, d.stepDepth, d.steps = false, 0, 0
+	d.printLocation()
+	d.repl()
+}
+
+func (d *Debugger) matches(b *Breakpoint) bool {
+	switch b.Kind {
+	case BreakPC:
+		return b.PC == d.pc
+	case BreakOp:
+		return b.Op == d.op
+	case BreakAddress:
+		return d.entered && b.Address == d.scope.Contract.Address()
+	}
+	return false
+}
+
+func (d *Debugger) shouldStep() bool {
+	if !d.stepping || (d.stepDepth > 0 && d.depth > d.stepDepth) {
+		return false
+	}
+	if d.steps > 1 {
+		d.steps--
+		return false
+	}
+	return true
+}
+
+// repl reads and executes commands until one of them resumes execution.
+func (d *Debugger) repl() {
+	for {
+		fmt.Fprint(d.out, "(evm) ")
+		if !d.in.Scan() {
+			// Input exhausted, nothing more can drive the session.
+			fmt.Fprintln(d.out)
+			d.detached = true
+			return
+		}
+		line := strings.TrimSpace(d.in.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if resume := d.execute(line); resume {
+			return
+		}
+	}
+}
+
+// execute runs a single command, returning true if execution should resume.
+func (d *Debugger) execute(line string) bool {
+	fields := strings.Fields(line)
+	cmd, args := fields[0], fields[1:]
+	switch cmd {
+	case "s", "step":
+		n := 1
+		if len(args) > 0 {
+			v, err := strconv.Atoi(args[0])
+			if err != nil || v < 1 {
+				fmt.Fprintf(d.out, "invalid step count %q\n", args[0])
+				return false
+			}
+			n = v
+		}
+		d.stepping, d.steps = true, n
+		return true
+
+	case "n", "next":
+		d.stepping, d.stepDepth = true, d.depth
+		return true
+
+	case "c", "continue":
+		return true
+
+	case "q", "quit":
+		d.detached = true
+		d.env.Cancel()
+		return true
+
+	case "b", "break":
+		b, err := ParseBreakpoint(strings.Join(args, " "))
+		if err != nil {
+			fmt.Fprintln(d.out, err)
+			return false
+		}
+		fmt.Fprintf(d.out, "breakpoint %v set\n", d.AddBreakpoint(b))
+
+	case "d", "delete":
+		if len(args) != 1 {
+			fmt.Fprintln(d.out, "usage: delete <id>")
+			return false
+		}
+		id, err := strconv.Atoi(strings.TrimPrefix(args[0], "#"))
+		if err != nil || !d.DeleteBreakpoint(id) {
+			fmt.Fprintf(d.out, "no breakpoint %s\n", args[0])
+		}
+
+	case "i", "info", "breakpoints":
+		if len(d.breaks) == 0 {
+			fmt.Fprintln(d.out, "no breakpoints")
+		}
+		for _, b := range d.breaks {
+			fmt.Fprintln(d.out, b)
+		}
+
+	case "w", "where":
+		d.printLocation()
+
+	case "st", "stack":
+		d.printStack()
+
+	case "m", "memory":
+		d.printMemory(args)
+
+	case "sl", "storage":
+		d.printStorage(args)
+
+	case "l", "list", "source":
+		d.printSource(true)
+
+	case "h", "help":
+		fmt.Fprint(d.out, helpText)
+
+	default:
+		fmt.Fprintf(d.out, "unknown command %q, try 'help'\n", cmd)
+	}
+	return false
+}
+
+const helpText = `commands:
+  step [n]           execute n instructions (default 1)
+  next               step over calls
+  continue           run until the next breakpoint
+  break pc|op|addr X set a breakpoint
+  delete <id>        remove a breakpoint
+  info               list breakpoints
+  where              show the current position
+  stack              show the stack
+  memory [off [len]] show memory
+  storage [slot...]  show storage slots of the current contract
+  list               show the current source line with context
+  quit               abort execution
+`
+
+func (d *Debugger) printLocation() {
+	fmt.Fprintf(d.out, "[%d] %v pc=%d op=%v gas=%d cost=%d\n",
+		d.depth, d.scope.Contract.Address(), d.pc, d.op, d.gas, d.cost)
+	d.printSource(false)
+}
+
+func (d *Debugger) printStack() {
+	data := d.scope.Stack.Data()
+	if len(data) == 0 {
+		fmt.Fprintln(d.out, "stack empty")
+		return
+	}
+	for i := len(data) - 1; i >= 0; i-- {
+		fmt.Fprintf(d.out, "%3d: %#x\n", len(data)-1-i, data[i].ToBig())
+	}
+}
+
+func (d *Debugger) printMemory(args []string) {
+	mem := d.scope.Memory.Data()
+	start, length := uint64(0), uint64(len(mem))
+	if len(args) > 0 {
+		v, err := strconv.ParseUint(args[0], 0, 64)
+		if err != nil {
+			fmt.Fprintf(d.out, "invalid offset %q\n", args[0])
+			return
+		}
+		start = v
+		length = 32
+	}
+	if len(args) > 1 {
+		v, err := strconv.ParseUint(args[1], 0, 64)
+		if err != nil {
+			fmt.Fprintf(d.out, "invalid length %q\n", args[1])
+			return
+		}
+		length = v
+	}
+	if start >= uint64(len(mem)) {
+		fmt.Fprintf(d.out, "memory size %d\n", len(mem))
+		return
+	}
+	end := start + length
+	if end > uint64(len(mem)) || end < start {
+		end = uint64(len(mem))
+	}
+	for off := start; off < end; off += 32 {
+		stop := off + 32
+		if stop > end {
+			stop = end
+		}
+		fmt.Fprintf(d.out, "0x%04x: %s\n", off, hex.EncodeToString(mem[off:stop]))
+	}
+}
+
+func (d *Debugger) printStorage(args []string) {
+	addr := d.scope.Contract.Address()
+	if len(args) > 0 {
+		for _, arg := range args {
+			slot := common.HexToHash(arg)
+			fmt.Fprintf(d.out, "%v: %v\n", slot, d.env.StateDB.GetState(addr, slot))
+		}
+		return
+	}
+	// Without explicit slots, show those accessed so far by the contract.
+	slots := make([]common.Hash, 0, len(d.slots[addr]))
+	for slot := range d.slots[addr] {
+		slots = append(slots, slot)
+	}
+	if len(slots) == 0 {
+		fmt.Fprintln(d.out, "no storage slots accessed, use 'storage <slot>'")
+		return
+	}
+	sort.Slice(slots, func(i, j int) bool { return slots[i].Cmp(slots[j]) < 0 })
+	for _, slot := range slots {
+		fmt.Fprintf(d.out, "%v: %v\n", slot, d.env.StateDB.GetState(addr, slot))
+	}
+}
+
+func (d *Debugger) printSource(context bool) {
+	if d.srcmap == nil || !bytes.Equal(d.scope.Contract.Code, d.srcmap.Code) {
+		if context {
+			fmt.Fprintln(d.out, "no source available")
+		}
+		return
+	}
+	file, loc := d.srcmap.Lookup(d.pc)
+	if file == nil {
+		if context {
+			fmt.Fprintln(d.out, "no source available")
+		}
+		return
+	}
+	line, text := file.Line(loc.Offset)
+	if !context {
+		fmt.Fprintf(d.out, "%s:%d: %s\n", file.Name, line, strings.TrimSpace(text))
+		return
+	}
+	for n := line - 2; n <= line+2; n++ {
+		if n < 1 || n > len(file.lines) {
+			continue
+		}
+		_, text := file.Line(file.lines[n-1])
+		marker := "  "
+		if n == line {
+			marker = "=>"
+		}
+		fmt.Fprintf(d.out, "%s %4d  %s\n", marker, n, text)
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package debugger
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/core/vm/runtime"
+)
+
+// PUSH1 1, PUSH1 2, ADD, PUSH1 0, SSTORE, PUSH1 1, PUSH1 0, MSTORE, PUSH1 32, PUSH1 0, RETURN
+var testCode = common.FromHex("6001600201600055600160005260206000f3")
+
+func runScript(t *testing.T, script string, stopOnEntry bool, setup func(*Debugger)) string {
+	t.Helper()
+
+	var out bytes.Buffer
+	dbg := New(strings.NewReader(script), &out, stopOnEntry)
+	if setup != nil {
+		setup(dbg)
+	}
+	_, _, err := runtime.Execute(testCode, nil, &runtime.Config{EVMConfig: vm.Config{Tracer: dbg}})
+	if err != nil {
+		t.Fatalf("execution failed: %v", err)
+	}
+	return out.String()
+}
+
+func TestDebuggerScript(t *testing.T) {
+	out := runScript(t, "step 2\nstack\nbreak op SSTORE\ncontinue\nstorage 0x0\ncontinue\n", true, nil)
+	for _, want := range []string{
+		"pc=0 op=PUSH1",
+		"pc=4 op=ADD",
+		"  0: 0x2\n  1: 0x1\n",
+		"breakpoint #1 op SSTORE",
+		"pc=7 op=SSTORE",
+		"0x0000000000000000000000000000000000000000000000000000000000000000: 0x0000000000000000000000000000000000000000000000000000000000000000",
+		"output: 0x0000000000000000000000000000000000000000000000000000000000000001",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q\n%s", want, out)
+		}
+	}
+}
+
+func TestDebuggerBreakpoints(t *testing.T) {
+	out := runScript(t, "memory\n", false, func(d *Debugger) {
+		b, err := ParseBreakpoint("pc:15")
+		if err != nil {
+			t.Fatal(err)
+		}
+		d.AddBreakpoint(b)
+	})
+	if strings.Contains(out, "pc=0 ") {
+		t.Errorf("paused on entry despite stopOnEntry being unset\n%s", out)
+	}
+	if !strings.Contains(out, "breakpoint #1 pc 15") || !strings.Contains(out, "0x0000: 0000000000000000000000000000000000000000000000000000000000000001") {
+		t.Errorf("unexpected output\n%s", out)
+	}
+	for _, spec := range []string{"op FOO", "addr 0x12", "pc x", "line 1"} {
+		if _, err := ParseBreakpoint(spec); err == nil {
+			t.Errorf("breakpoint %q accepted", spec)
+		}
+	}
+}
+
+func TestDecodeSourceMap(t *testing.T) {
+	locs, err := DecodeSourceMap("1:2:0:-;:9;4::1:i;;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []SourceLocation{
+		{Offset: 1, Length: 2, File: 0, Jump: '-'},
+		{Offset: 1, Length: 9, File: 0, Jump: '-'},
+		{Offset: 4, Length: 9, File: 1, Jump: 'i'},
+		{Offset: 4, Length: 9, File: 1, Jump: 'i'},
+		{Offset: 4, Length: 9, File: 1, Jump: 'i'},
+	}
+	if len(locs) != len(want) {
+		t.Fatalf("have %d locations, want %d", len(locs), len(want))
+	}
+	for i := range want {
+		if locs[i] != want[i] {
+			t.Errorf("location %d: have %+v, want %+v", i, locs[i], want[i])
+		}
+	}
+}
+
+func TestLoadSourceMap(t *testing.T) {
+	dir := t.TempDir()
+	src := "contract A {\n  function f() {\n    x = 1;\n  }\n}\n"
+	if err := os.WriteFile(filepath.Join(dir, "a.sol"), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Code is PUSH2 0x0102, STOP; the second instruction maps to "x = 1".
+	output := `{
+		"sources": {"a.sol": {"id": 0}},
+		"contracts": {"a.sol": {"A": {"evm": {"deployedBytecode": {
+			"object": "61010200",
+			"sourceMap": "0:48:0:-;34:6"
+		}}}}}
+	}`
+	path := filepath.Join(dir, "out.json")
+	if err := os.WriteFile(path, []byte(output), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m, err := LoadSourceMap(path, "A", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, loc := m.Lookup(3)
+	if file == nil {
+		t.Fatal("no source for pc 3")
+	}
+	if line, text := file.Line(loc.Offset); line != 3 || text != "    x = 1;" {
+		t.Errorf("have line %d %q, want 3 %q", line, text, "    x = 1;")
+	}
+	if file, _ := m.Lookup(1); file != nil {
+		t.Error("push data resolved to a source location")
+	}
+	if _, err := LoadSourceMap(path, "B", "", false); err == nil {
+		t.Error("missing contract accepted")
+	}
+}
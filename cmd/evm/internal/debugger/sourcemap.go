@@ -0,0 +1,244 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package debugger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// SourceLocation is a single decoded entry of a solc source map. Offset and
+// Length are byte positions within the source file identified by File. A File
+// of -1 means the instruction does not map to any user source.
+type SourceLocation struct {
+	Offset int
+	Length int
+	File   int
+	Jump   byte // 'i' (into a function), 'o' (out of a function) or '-'
+}
+
+// DecodeSourceMap expands the compressed solc source map format
+// (s:l:f:j[:m];...) into one location per instruction.
+func DecodeSourceMap(srcmap string) ([]SourceLocation, error) {
+	if srcmap == "" {
+		return nil, nil
+	}
+	var (
+		entries = strings.Split(srcmap, ";")
+		locs    = make([]SourceLocation, 0, len(entries))
+		last    = SourceLocation{File: -1, Jump: '-'}
+	)
+	for i, entry := range entries {
+		cur := last
+		fields := strings.Split(entry, ":")
+		for j, field := range fields {
+			if field == "" {
+				continue
+			}
+			switch j {
+			case 0, 1, 2:
+				n, err := strconv.Atoi(field)
+				if err != nil {
+					return nil, fmt.Errorf("invalid source map entry %d: %v", i, err)
+				}
+				switch j {
+				case 0:
+					cur.Offset = n
+				case 1:
+					cur.Length = n
+				case 2:
+					cur.File = n
+				}
+			case 3:
+				cur.Jump = field[0]
+			}
+		}
+		locs = append(locs, cur)
+		last = cur
+	}
+	return locs, nil
+}
+
+// instructionOffsets returns, for every byte offset in code that starts an
+// instruction, the index of that instruction. Push data bytes are skipped,
+// mirroring how solc numbers instructions in its source maps.
+func instructionOffsets(code []byte) map[uint64]int {
+	offsets := make(map[uint64]int)
+	for pc, idx := uint64(0), 0; pc < uint64(len(code)); idx++ {
+		offsets[pc] = idx
+		op := vm.OpCode(code[pc])
+		pc++
+		if op.IsPush() {
+			pc += uint64(op - vm.PUSH0)
+		}
+	}
+	return offsets
+}
+
+// SourceFile is a Solidity source file referenced by a source map.
+type SourceFile struct {
+	Name    string
+	Content string
+	lines   []int // byte offset of the start of each line
+}
+
+// Line returns the 1-based line number and the text of the line containing
+// the given byte offset.
+func (f *SourceFile) Line(offset int) (int, string) {
+	if f.lines == nil {
+		f.lines = []int{0}
+		for i, c := range f.Content {
+			if c == '\n' {
+				f.lines = append(f.lines, i+1)
+			}
+		}
+	}
+	n := sort.Search(len(f.lines), func(i int) bool { return f.lines[i] > offset })
+	if n == 0 {
+		return 0, ""
+	}
+	start := f.lines[n-1]
+	end := len(f.Content)
+	if n < len(f.lines) {
+		end = f.lines[n] - 1
+	}
+	return n, strings.TrimRight(f.Content[start:end], "\r")
+}
+
+// SourceMap resolves program counters of a single contract's bytecode to
+// Solidity source positions.
+type SourceMap struct {
+	Code    []byte // bytecode the map applies to
+	locs    []SourceLocation
+	offsets map[uint64]int
+	files   map[int]*SourceFile
+}
+
+// Lookup returns the source file and location for the instruction at pc, or
+// nil if it does not map to any known source.
+func (m *SourceMap) Lookup(pc uint64) (*SourceFile, *SourceLocation) {
+	idx, ok := m.offsets[pc]
+	if !ok || idx >= len(m.locs) {
+		return nil, nil
+	}
+	loc := &m.locs[idx]
+	file, ok := m.files[loc.File]
+	if !ok {
+		return nil, loc
+	}
+	return file, loc
+}
+
+// solcOutput is the subset of the solc standard-JSON output used to construct
+// source maps.
+type solcOutput struct {
+	Contracts map[string]map[string]struct {
+		EVM struct {
+			Bytecode         solcBytecode `json:"bytecode"`
+			DeployedBytecode solcBytecode `json:"deployedBytecode"`
+		} `json:"evm"`
+	} `json:"contracts"`
+	Sources map[string]struct {
+		ID int `json:"id"`
+	} `json:"sources"`
+}
+
+type solcBytecode struct {
+	Object    string `json:"object"`
+	SourceMap string `json:"sourceMap"`
+}
+
+// LoadSourceMap reads a solc standard-JSON output file and constructs the source
+// map for the given contract, which is either a plain contract name or in the
+// form "file.sol:Name". If deploy is set, the creation bytecode map is returned,
+// otherwise the runtime one. Source files are loaded relative to root, which
+// defaults to the directory holding the output file.
+func LoadSourceMap(path, contract, root string, deploy bool) (*SourceMap, error) {
+	blob, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var out solcOutput
+	if err := json.Unmarshal(blob, &out); err != nil {
+		return nil, fmt.Errorf("invalid solc output: %v", err)
+	}
+	if root == "" {
+		root = filepath.Dir(path)
+	}
+	wantFile, wantName := "", contract
+	if i := strings.LastIndexByte(contract, ':'); i >= 0 {
+		wantFile, wantName = contract[:i], contract[i+1:]
+	}
+	var (
+		bytecode solcBytecode
+		found    int
+	)
+	for file, contracts := range out.Contracts {
+		if wantFile != "" && file != wantFile {
+			continue
+		}
+		for name, c := range contracts {
+			if wantName != "" && name != wantName {
+				continue
+			}
+			if deploy {
+				bytecode = c.EVM.Bytecode
+			} else {
+				bytecode = c.EVM.DeployedBytecode
+			}
+			found++
+		}
+	}
+	switch {
+	case found == 0:
+		return nil, fmt.Errorf("contract %q not found in solc output", contract)
+	case found > 1:
+		return nil, fmt.Errorf("contract %q is ambiguous, use <file>:<name>", contract)
+	case bytecode.SourceMap == "":
+		return nil, errors.New("solc output has no source map, compile with evm.bytecode.sourceMap selected")
+	}
+	locs, err := DecodeSourceMap(bytecode.SourceMap)
+	if err != nil {
+		return nil, err
+	}
+	code := common.FromHex(bytecode.Object)
+	files := make(map[int]*SourceFile)
+	for name, src := range out.Sources {
+		content, err := os.ReadFile(filepath.Join(root, name))
+		if err != nil {
+			// Missing sources (e.g. remapped imports) only lose their source
+			// view, the remaining files are still usable.
+			continue
+		}
+		files[src.ID] = &SourceFile{Name: name, Content: string(content)}
+	}
+	return &SourceMap{
+		Code:    code,
+		locs:    locs,
+		offsets: instructionOffsets(code),
+		files:   files,
+	}, nil
+}
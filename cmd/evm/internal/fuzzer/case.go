@@ -0,0 +1,219 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package fuzzer
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Case is a single fuzzing input: a prestate, a block environment and one
+// transaction, executed under a given fork.
+type Case struct {
+	Fork string
+	Env  Env
+	Pre  types.GenesisAlloc
+	Tx   Tx
+}
+
+// Env is the block environment a case is executed in.
+type Env struct {
+	Coinbase  common.Address
+	GasLimit  uint64
+	Number    uint64
+	Timestamp uint64
+	BaseFee   *big.Int
+	Random    common.Hash
+}
+
+// Tx is the legacy transaction executed by a case. It is signed with Key by
+// whichever implementation runs it.
+type Tx struct {
+	Key      *ecdsa.PrivateKey
+	Nonce    uint64
+	To       common.Address
+	Value    *big.Int
+	Gas      uint64
+	GasPrice *big.Int
+	Data     []byte
+}
+
+// Copy returns a deep copy of the case, so it can be mutated while shrinking.
+func (c *Case) Copy() *Case {
+	cpy := &Case{Fork: c.Fork, Env: c.Env, Tx: c.Tx, Pre: make(types.GenesisAlloc, len(c.Pre))}
+	for addr, acc := range c.Pre {
+		storage := make(map[common.Hash]common.Hash, len(acc.Storage))
+		for k, v := range acc.Storage {
+			storage[k] = v
+		}
+		cpy.Pre[addr] = types.Account{
+			Code:    common.CopyBytes(acc.Code),
+			Storage: storage,
+			Balance: new(big.Int).Set(acc.Balance),
+			Nonce:   acc.Nonce,
+		}
+	}
+	cpy.Tx.Data = common.CopyBytes(c.Tx.Data)
+	return cpy
+}
+
+// Sender returns the address the case's transaction is sent from.
+func (c *Case) Sender() common.Address {
+	return crypto.PubkeyToAddress(c.Tx.Key.PublicKey)
+}
+
+// stateTestJSON mirrors the GeneralStateTest format understood by the tests
+// package and by `evm statetest`.
+type stateTestJSON struct {
+	Env  stateTestEnv               `json:"env"`
+	Pre  types.GenesisAlloc         `json:"pre"`
+	Tx   stateTestTx                `json:"transaction"`
+	Post map[string][]stateTestPost `json:"post"`
+}
+
+type stateTestEnv struct {
+	Coinbase      common.Address        `json:"currentCoinbase"`
+	Difficulty    *math.HexOrDecimal256 `json:"currentDifficulty"`
+	Random        *math.HexOrDecimal256 `json:"currentRandom"`
+	GasLimit      math.HexOrDecimal64   `json:"currentGasLimit"`
+	Number        math.HexOrDecimal64   `json:"currentNumber"`
+	Timestamp     math.HexOrDecimal64   `json:"currentTimestamp"`
+	BaseFee       *math.HexOrDecimal256 `json:"currentBaseFee"`
+	ExcessBlobGas math.HexOrDecimal64   `json:"currentExcessBlobGas"`
+}
+
+type stateTestTx struct {
+	GasPrice  *math.HexOrDecimal256 `json:"gasPrice"`
+	Nonce     math.HexOrDecimal64   `json:"nonce"`
+	To        common.Address        `json:"to"`
+	Data      []hexutil.Bytes       `json:"data"`
+	GasLimit  []math.HexOrDecimal64 `json:"gasLimit"`
+	Value     []string              `json:"value"`
+	SecretKey hexutil.Bytes         `json:"secretKey"`
+}
+
+type stateTestPost struct {
+	Root    common.Hash `json:"hash"`
+	Logs    common.Hash `json:"logs"`
+	Indexes struct {
+		Data  int `json:"data"`
+		Gas   int `json:"gas"`
+		Value int `json:"value"`
+	} `json:"indexes"`
+}
+
+// StateTest encodes the case as a GeneralStateTest, with the given post state
+// root and logs hash as the expected outcome.
+func (c *Case) StateTest(root, logs common.Hash) ([]byte, error) {
+	test := stateTestJSON{
+		Env: stateTestEnv{
+			Coinbase:   c.Env.Coinbase,
+			Difficulty: (*math.HexOrDecimal256)(new(big.Int)),
+			Random:     (*math.HexOrDecimal256)(c.Env.Random.Big()),
+			GasLimit:   math.HexOrDecimal64(c.Env.GasLimit),
+			Number:     math.HexOrDecimal64(c.Env.Number),
+			Timestamp:  math.HexOrDecimal64(c.Env.Timestamp),
+			BaseFee:    (*math.HexOrDecimal256)(c.Env.BaseFee),
+		},
+		Pre: c.Pre,
+		Tx: stateTestTx{
+			GasPrice:  (*math.HexOrDecimal256)(c.Tx.GasPrice),
+			Nonce:     math.HexOrDecimal64(c.Tx.Nonce),
+			To:        c.Tx.To,
+			Data:      []hexutil.Bytes{c.Tx.Data},
+			GasLimit:  []math.HexOrDecimal64{math.HexOrDecimal64(c.Tx.Gas)},
+			Value:     []string{hexutil.EncodeBig(c.Tx.Value)},
+			SecretKey: crypto.FromECDSA(c.Tx.Key),
+		},
+		Post: map[string][]stateTestPost{
+			c.Fork: {{Root: root, Logs: logs}},
+		},
+	}
+	return json.MarshalIndent(test, "", "  ")
+}
+
+// t8nInput is the combined stdin document accepted by `evm t8n` when alloc, env
+// and txs are all read from stdin.
+type t8nInput struct {
+	Alloc types.GenesisAlloc `json:"alloc"`
+	Env   t8nEnv             `json:"env"`
+	Txs   []t8nTx            `json:"txs"`
+}
+
+type t8nEnv struct {
+	Coinbase              common.Address        `json:"currentCoinbase"`
+	Random                *math.HexOrDecimal256 `json:"currentRandom"`
+	GasLimit              math.HexOrDecimal64   `json:"currentGasLimit"`
+	Number                math.HexOrDecimal64   `json:"currentNumber"`
+	Timestamp             math.HexOrDecimal64   `json:"currentTimestamp"`
+	BaseFee               *math.HexOrDecimal256 `json:"currentBaseFee"`
+	ExcessBlobGas         math.HexOrDecimal64   `json:"currentExcessBlobGas"`
+	Withdrawals           []*types.Withdrawal   `json:"withdrawals"`
+	ParentBeaconBlockRoot common.Hash           `json:"parentBeaconBlockRoot"`
+}
+
+type t8nTx struct {
+	Type      hexutil.Uint64 `json:"type"`
+	Nonce     hexutil.Uint64 `json:"nonce"`
+	GasPrice  *hexutil.Big   `json:"gasPrice"`
+	Gas       hexutil.Uint64 `json:"gas"`
+	To        common.Address `json:"to"`
+	Value     *hexutil.Big   `json:"value"`
+	Input     hexutil.Bytes  `json:"input"`
+	V         *hexutil.Big   `json:"v"`
+	R         *hexutil.Big   `json:"r"`
+	S         *hexutil.Big   `json:"s"`
+	SecretKey common.Hash    `json:"secretKey"`
+	Protected bool           `json:"protected"`
+}
+
+// T8nInput encodes the case as a t8n stdin document.
+func (c *Case) T8nInput() ([]byte, error) {
+	zero := new(hexutil.Big)
+	return json.Marshal(t8nInput{
+		Alloc: c.Pre,
+		Env: t8nEnv{
+			Coinbase:    c.Env.Coinbase,
+			Random:      (*math.HexOrDecimal256)(c.Env.Random.Big()),
+			GasLimit:    math.HexOrDecimal64(c.Env.GasLimit),
+			Number:      math.HexOrDecimal64(c.Env.Number),
+			Timestamp:   math.HexOrDecimal64(c.Env.Timestamp),
+			BaseFee:     (*math.HexOrDecimal256)(c.Env.BaseFee),
+			Withdrawals: []*types.Withdrawal{},
+		},
+		Txs: []t8nTx{{
+			Nonce:     hexutil.Uint64(c.Tx.Nonce),
+			GasPrice:  (*hexutil.Big)(c.Tx.GasPrice),
+			Gas:       hexutil.Uint64(c.Tx.Gas),
+			To:        c.Tx.To,
+			Value:     (*hexutil.Big)(c.Tx.Value),
+			Input:     c.Tx.Data,
+			V:         zero,
+			R:         zero,
+			S:         zero,
+			SecretKey: common.BytesToHash(crypto.FromECDSA(c.Tx.Key)),
+			Protected: true,
+		}},
+	})
+}
@@ -0,0 +1,192 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+// Package fuzzer implements differential fuzzing of the EVM interpreter against
+// an external t8n implementation, comparing EIP-3155 execution traces.
+package fuzzer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Config configures a fuzzing session.
+type Config struct {
+	Seed       int64  // seed of the first iteration, incremented per iteration
+	Iterations int    // number of cases to run, 0 for unlimited
+	Fork       string // fork to execute cases under
+	MaxCode    int    // maximum size of generated contracts
+	OutDir     string // directory failing cases are written to
+	NoShrink   bool   // skip minimising failing cases
+}
+
+// Failure is a case on which the implementations diverged.
+type Failure struct {
+	Seed int64
+	Case *Case
+	Diff *Diff
+	Path string // location the case was saved to
+}
+
+// Fuzzer generates cases and checks them on two executors.
+type Fuzzer struct {
+	cfg   Config
+	local Executor
+	ref   Executor
+}
+
+// New creates a fuzzer comparing the local executor with a reference.
+func New(cfg Config, local, ref Executor) *Fuzzer {
+	return &Fuzzer{cfg: cfg, local: local, ref: ref}
+}
+
+// Run executes the configured number of iterations, returning the failures
+// found. Execution errors of either side abort the session.
+func (f *Fuzzer) Run() ([]*Failure, error) {
+	var failures []*Failure
+	for i := 0; f.cfg.Iterations == 0 || i < f.cfg.Iterations; i++ {
+		seed := f.cfg.Seed + int64(i)
+		c := NewGenerator(seed, f.cfg.Fork, f.cfg.MaxCode).Case()
+
+		diff, err := f.Check(c)
+		if err != nil {
+			return failures, fmt.Errorf("seed %d: %w", seed, err)
+		}
+		if diff == nil {
+			if (i+1)%100 == 0 {
+				log.Info("Fuzzing in progress", "iterations", i+1, "failures", len(failures))
+			}
+			continue
+		}
+		log.Warn("Found divergence", "seed", seed, "diff", diff)
+		if !f.cfg.NoShrink {
+			c, diff = f.Shrink(c, diff)
+		}
+		failure := &Failure{Seed: seed, Case: c, Diff: diff}
+		if f.cfg.OutDir != "" {
+			if failure.Path, err = f.save(failure); err != nil {
+				return failures, err
+			}
+			log.Warn("Saved failing case", "seed", seed, "path", failure.Path)
+		}
+		failures = append(failures, failure)
+	}
+	return failures, nil
+}
+
+// Check runs the case on both executors and returns the first divergence, if
+// any.
+func (f *Fuzzer) Check(c *Case) (*Diff, error) {
+	local, err := f.local.Execute(c)
+	if err != nil {
+		return nil, fmt.Errorf("local execution failed: %w", err)
+	}
+	ref, err := f.ref.Execute(c)
+	if err != nil {
+		return nil, fmt.Errorf("reference execution failed: %w", err)
+	}
+	return Compare(local, ref), nil
+}
+
+// save writes a failing case as a GeneralStateTest. The expected post state is
+// that of the local execution, since it's unknown which side is at fault.
+func (f *Fuzzer) save(failure *Failure) (string, error) {
+	if err := os.MkdirAll(f.cfg.OutDir, 0755); err != nil {
+		return "", err
+	}
+	local, err := f.local.Execute(failure.Case)
+	if err != nil {
+		return "", err
+	}
+	logs, err := logsHash(failure.Case)
+	if err != nil {
+		return "", err
+	}
+	test, err := failure.Case.StateTest(local.Root, logs)
+	if err != nil {
+		return "", err
+	}
+	name := fmt.Sprintf("fuzz-%d", failure.Seed)
+	path := filepath.Join(f.cfg.OutDir, name+".json")
+	blob := append([]byte(fmt.Sprintf("{\n\"%s\": ", name)), test...)
+	blob = append(blob, "\n}\n"...)
+	return path, os.WriteFile(path, blob, 0644)
+}
+
+// Shrink minimises a failing case while it keeps diverging: it drops unrelated
+// accounts and storage, truncates the calldata and contract code, and replaces
+// instructions by JUMPDEST, which is free of side effects.
+func (f *Fuzzer) Shrink(c *Case, diff *Diff) (*Case, *Diff) {
+	try := func(candidate *Case) bool {
+		d, err := f.Check(candidate)
+		if err != nil || d == nil {
+			return false
+		}
+		c, diff = candidate, d
+		return true
+	}
+	// Drop accounts other than the sender and the target
+	for addr := range c.Pre {
+		if addr == c.Sender() || addr == c.Tx.To {
+			continue
+		}
+		candidate := c.Copy()
+		delete(candidate.Pre, addr)
+		try(candidate)
+	}
+	// Drop storage slots
+	for addr, acc := range c.Pre {
+		for slot := range acc.Storage {
+			candidate := c.Copy()
+			delete(candidate.Pre[addr].Storage, slot)
+			try(candidate)
+		}
+	}
+	// Truncate the calldata
+	for len(c.Tx.Data) > 0 {
+		candidate := c.Copy()
+		candidate.Tx.Data = candidate.Tx.Data[:len(candidate.Tx.Data)/2]
+		if !try(candidate) {
+			break
+		}
+	}
+	// Truncate the code of every remaining contract by bisection, then blank
+	// out individual bytes.
+	for addr := range c.Pre {
+		for lo := 0; len(c.Pre[addr].Code) > lo; {
+			mid := lo + (len(c.Pre[addr].Code)-lo)/2
+			candidate := c.Copy()
+			acc := candidate.Pre[addr]
+			acc.Code = acc.Code[:mid]
+			candidate.Pre[addr] = acc
+			if !try(candidate) {
+				lo = mid + 1
+			}
+		}
+		for i := range c.Pre[addr].Code {
+			if c.Pre[addr].Code[i] == 0x5b {
+				continue
+			}
+			candidate := c.Copy()
+			candidate.Pre[addr].Code[i] = 0x5b // JUMPDEST
+			try(candidate)
+		}
+	}
+	return c, diff
+}
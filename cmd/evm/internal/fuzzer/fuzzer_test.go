@@ -0,0 +1,109 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package fuzzer
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/tests"
+)
+
+// buggyExecutor wraps the local executor, reporting a wrong gas cost for
+// every execution of a given opcode.
+type buggyExecutor struct {
+	op vm.OpCode
+}
+
+func (e *buggyExecutor) Execute(c *Case) (*Result, error) {
+	res, err := Local{}.Execute(c)
+	if err != nil {
+		return nil, err
+	}
+	for i := range res.Trace {
+		if op := res.Trace[i].Op; op != nil && vm.OpCode(*op) == e.op {
+			res.Trace[i].Cost++
+		}
+	}
+	return res, nil
+}
+
+func TestFuzzerAgreement(t *testing.T) {
+	f := New(Config{Seed: 1, Iterations: 20, Fork: "Shanghai"}, Local{}, Local{})
+	failures, err := f.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failures) != 0 {
+		t.Fatalf("identical executors diverged: %v", failures[0].Diff)
+	}
+}
+
+func TestFuzzerTraces(t *testing.T) {
+	// Generated cases should get reasonably far into execution on average,
+	// otherwise the generator only exercises stack underflows.
+	var steps int
+	for seed := int64(0); seed < 20; seed++ {
+		res, err := Local{}.Execute(NewGenerator(seed, "Cancun", 128).Case())
+		if err != nil {
+			t.Fatal(err)
+		}
+		steps += len(res.Trace)
+	}
+	if steps < 20*10 {
+		t.Errorf("generated programs are too short-lived: %d steps in 20 cases", steps)
+	}
+}
+
+func TestFuzzerShrinkAndSave(t *testing.T) {
+	dir := t.TempDir()
+	f := New(Config{Seed: 1, Iterations: 50, Fork: "Shanghai", MaxCode: 64, OutDir: dir}, Local{}, &buggyExecutor{op: vm.ADD})
+	failures, err := f.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failures) == 0 {
+		t.Fatal("divergence not detected")
+	}
+	for _, failure := range failures {
+		if op := failure.Diff.Local.Op; op == nil || vm.OpCode(*op) != vm.ADD {
+			t.Errorf("seed %d: unexpected divergence %v", failure.Seed, failure.Diff)
+		}
+		if len(failure.Case.Pre) != 2 {
+			t.Errorf("seed %d: unrelated accounts kept: %d", failure.Seed, len(failure.Case.Pre))
+		}
+		// The saved file must be a valid state test matching the local result.
+		blob, err := os.ReadFile(failure.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var file map[string]*tests.StateTest
+		if err := json.Unmarshal(blob, &file); err != nil {
+			t.Fatalf("invalid state test: %v", err)
+		}
+		for name, test := range file {
+			for _, sub := range test.Subtests() {
+				if err := test.Run(sub, vm.Config{}, false, rawdb.HashScheme, func(err error, st *tests.StateTestState) {}); err != nil {
+					t.Errorf("%s: %v", name, err)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,205 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package fuzzer
+
+import (
+	"math/big"
+	"math/rand"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	// senderKey is the key every generated transaction is signed with.
+	senderKey, _ = crypto.HexToECDSA("45a915e4d060149eb4365960e6a7a45f334393093061116b197e3240065ff2d8")
+
+	// targetAddr is the address of the contract called by the transaction.
+	targetAddr = common.HexToAddress("0x00000000000000000000000000000000000f0000")
+
+	// coinbaseAddr is the coinbase of the block environment.
+	coinbaseAddr = common.HexToAddress("0x2adc25665018aa1fe0e6bc666dac8fc2697ff9ba")
+)
+
+// validOps lists the opcodes known to the interpreter, in any fork. Programs are
+// generated from these, leaving fork activation to the implementations under
+// test. BLOCKHASH is excluded as state tests and t8n derive block hashes
+// differently.
+var validOps = func() []vm.OpCode {
+	var ops []vm.OpCode
+	for i := 0; i < 256; i++ {
+		op := vm.OpCode(i)
+		if op == vm.BLOCKHASH || strings.Contains(op.String(), "not defined") {
+			continue
+		}
+		ops = append(ops, op)
+	}
+	return ops
+}()
+
+// Generator produces random cases from a deterministic source.
+type Generator struct {
+	rand    *rand.Rand
+	fork    string
+	maxCode int
+}
+
+// NewGenerator creates a generator for the given fork, seeded with seed. The
+// generated contracts contain at most maxCode bytes.
+func NewGenerator(seed int64, fork string, maxCode int) *Generator {
+	if maxCode <= 0 {
+		maxCode = 256
+	}
+	return &Generator{rand: rand.New(rand.NewSource(seed)), fork: fork, maxCode: maxCode}
+}
+
+// Case generates a new random case: a target contract and a handful of helper
+// contracts with random code and storage, called by a transaction with random
+// calldata.
+func (g *Generator) Case() *Case {
+	var (
+		sender = crypto.PubkeyToAddress(senderKey.PublicKey)
+		pre    = make(types.GenesisAlloc)
+		others []common.Address
+	)
+	pre[sender] = types.Account{Balance: new(big.Int).Lsh(common.Big1, 100)}
+
+	for i := g.rand.Intn(3); i > 0; i-- {
+		others = append(others, common.BigToAddress(big.NewInt(int64(0xf0001+i))))
+	}
+	pre[targetAddr] = g.account(others)
+	for _, addr := range others {
+		pre[addr] = g.account(others)
+	}
+	data := make([]byte, g.rand.Intn(68))
+	g.rand.Read(data)
+
+	return &Case{
+		Fork: g.fork,
+		Env: Env{
+			Coinbase:  coinbaseAddr,
+			GasLimit:  30_000_000,
+			Number:    1,
+			Timestamp: 1000,
+			BaseFee:   big.NewInt(7),
+			Random:    common.BigToHash(big.NewInt(g.rand.Int63())),
+		},
+		Pre: pre,
+		Tx: Tx{
+			Key:      senderKey,
+			To:       targetAddr,
+			Value:    big.NewInt(int64(g.rand.Intn(3))),
+			Gas:      100_000 + uint64(g.rand.Intn(2_000_000)),
+			GasPrice: big.NewInt(10),
+			Data:     data,
+		},
+	}
+}
+
+func (g *Generator) account(callees []common.Address) types.Account {
+	storage := make(map[common.Hash]common.Hash)
+	for i := g.rand.Intn(4); i > 0; i-- {
+		storage[common.BigToHash(big.NewInt(int64(g.rand.Intn(8))))] = common.BigToHash(big.NewInt(g.rand.Int63()))
+	}
+	return types.Account{
+		Code:    g.code(callees),
+		Storage: storage,
+		Balance: big.NewInt(int64(g.rand.Intn(1000))),
+		Nonce:   1,
+	}
+}
+
+// code generates a random program. Since almost all opcodes consume stack
+// items, the generator tracks the approximate stack height and pushes small
+// values ahead of instructions which would otherwise underflow. Addresses of
+// the other generated contracts are pushed now and then so that calls reach
+// real code.
+func (g *Generator) code(callees []common.Address) []byte {
+	var (
+		code   []byte
+		height int
+	)
+	push := func() {
+		if len(callees) > 0 && g.rand.Intn(20) == 0 {
+			code = append(code, byte(vm.PUSH20))
+			code = append(code, callees[g.rand.Intn(len(callees))].Bytes()...)
+		} else if g.rand.Intn(10) == 0 {
+			size := 1 + g.rand.Intn(32)
+			code = append(code, byte(vm.PUSH1)+byte(size-1))
+			for i := 0; i < size; i++ {
+				code = append(code, byte(g.rand.Intn(256)))
+			}
+		} else {
+			code = append(code, byte(vm.PUSH1), byte(g.rand.Intn(64)))
+		}
+		height++
+	}
+	for len(code) < g.maxCode {
+		if g.rand.Intn(3) == 0 {
+			push()
+			continue
+		}
+		op := validOps[g.rand.Intn(len(validOps))]
+		if op.IsPush() && op != vm.PUSH0 {
+			// Explicit pushes are generated above, avoid swallowing the
+			// following instructions as push data.
+			continue
+		}
+		if halts(op) && g.rand.Intn(10) != 0 {
+			continue
+		}
+		need := stackNeed(op)
+		for height < need {
+			push()
+		}
+		code = append(code, byte(op))
+		height += 1 - need
+	}
+	return code[:g.maxCode]
+}
+
+// halts reports whether an opcode ends the execution of a frame, possibly by
+// jumping to an arbitrary destination.
+func halts(op vm.OpCode) bool {
+	switch op {
+	case vm.STOP, vm.RETURN, vm.REVERT, vm.INVALID, vm.SELFDESTRUCT, vm.JUMP, vm.JUMPI:
+		return true
+	}
+	return false
+}
+
+// stackNeed returns an upper bound of the stack items consumed by an opcode.
+func stackNeed(op vm.OpCode) int {
+	switch {
+	case op >= vm.DUP1 && op <= vm.DUP16:
+		return int(op-vm.DUP1) + 1
+	case op >= vm.SWAP1 && op <= vm.SWAP16:
+		return int(op-vm.SWAP1) + 2
+	case op >= vm.LOG0 && op <= vm.LOG4:
+		return int(op-vm.LOG0) + 2
+	case op == vm.CALL || op == vm.CALLCODE:
+		return 7
+	case op == vm.DELEGATECALL || op == vm.STATICCALL:
+		return 6
+	case op == vm.EXTCODECOPY || op == vm.CREATE2:
+		return 4
+	}
+	return 3
+}
@@ -0,0 +1,276 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package fuzzer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/tracers/logger"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/tests"
+)
+
+// Result is the outcome of executing a case on one implementation.
+type Result struct {
+	Root  common.Hash // post state root
+	Trace []TraceLine // EIP-3155 trace of the transaction
+}
+
+// TraceLine is the subset of an EIP-3155 trace line that is compared between
+// implementations. Opcode steps carry Pc/Op/Gas/Cost/Depth/Stack, while the
+// summary line emitted at the end of the execution carries Output/GasUsed.
+// Error messages are implementation specific, so only their presence counts.
+type TraceLine struct {
+	Pc      *uint64                 `json:"pc,omitempty"`
+	Op      *uint64                 `json:"op,omitempty"`
+	Gas     math.HexOrDecimal64     `json:"gas,omitempty"`
+	Cost    math.HexOrDecimal64     `json:"gasCost,omitempty"`
+	Depth   int                     `json:"depth,omitempty"`
+	Stack   []*math.HexOrDecimal256 `json:"stack,omitempty"`
+	Output  *string                 `json:"output,omitempty"`
+	GasUsed math.HexOrDecimal64     `json:"gasUsed,omitempty"`
+	Failed  bool                    `json:"-"`
+}
+
+func (l *TraceLine) UnmarshalJSON(input []byte) error {
+	type traceLine TraceLine
+	var dec struct {
+		traceLine
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return err
+	}
+	*l = TraceLine(dec.traceLine)
+	l.Failed = dec.Error != ""
+	if l.Output != nil {
+		out := strings.ToLower(strings.TrimPrefix(*l.Output, "0x"))
+		l.Output = &out
+	}
+	return nil
+}
+
+func (l *TraceLine) equal(o *TraceLine) bool {
+	a, _ := json.Marshal(l)
+	b, _ := json.Marshal(o)
+	return bytes.Equal(a, b) && l.Failed == o.Failed
+}
+
+func (l *TraceLine) String() string {
+	b, _ := json.Marshal(l)
+	if l.Failed {
+		return string(b) + " (failed)"
+	}
+	return string(b)
+}
+
+// ParseTrace decodes a stream of EIP-3155 JSON lines.
+func ParseTrace(data []byte) ([]TraceLine, error) {
+	var (
+		lines   []TraceLine
+		scanner = bufio.NewScanner(bytes.NewReader(data))
+	)
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	for scanner.Scan() {
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 || raw[0] != '{' {
+			continue
+		}
+		var line TraceLine
+		if err := json.Unmarshal(raw, &line); err != nil {
+			return nil, fmt.Errorf("invalid trace line %q: %v", raw, err)
+		}
+		// Ignore anything which is neither a step nor the execution summary,
+		// such as the state root line some implementations emit.
+		if line.Op == nil && line.Output == nil {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// Executor runs a case and returns its result.
+type Executor interface {
+	Execute(c *Case) (*Result, error)
+}
+
+// Local executes cases on the go-ethereum interpreter, tracing them with the
+// EIP-3155 logger.JSONLogger.
+type Local struct{}
+
+// Execute implements Executor.
+func (Local) Execute(c *Case) (*Result, error) {
+	blob, err := c.StateTest(common.Hash{}, common.Hash{})
+	if err != nil {
+		return nil, err
+	}
+	var test tests.StateTest
+	if err := json.Unmarshal(blob, &test); err != nil {
+		return nil, err
+	}
+	var (
+		buf    bytes.Buffer
+		tracer = logger.NewJSONLogger(&logger.Config{DisableStorage: true}, &buf)
+	)
+	st, root, _ := test.RunNoVerify(tests.StateSubtest{Fork: c.Fork}, vm.Config{Tracer: tracer}, false, rawdb.HashScheme)
+	defer st.Close()
+
+	trace, err := ParseTrace(buf.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Root: root, Trace: trace}, nil
+}
+
+// logsHash computes the hash of the logs produced by a local execution, in the
+// form used by state tests.
+func logsHash(c *Case) (common.Hash, error) {
+	blob, err := c.StateTest(common.Hash{}, common.Hash{})
+	if err != nil {
+		return common.Hash{}, err
+	}
+	var test tests.StateTest
+	if err := json.Unmarshal(blob, &test); err != nil {
+		return common.Hash{}, err
+	}
+	st, _, _ := test.RunNoVerify(tests.StateSubtest{Fork: c.Fork}, vm.Config{}, false, rawdb.HashScheme)
+	defer st.Close()
+
+	enc, err := rlp.EncodeToBytes(st.StateDB.Logs())
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return crypto.Keccak256Hash(enc), nil
+}
+
+// Binary executes cases through an external t8n implementation. The case is
+// fed on stdin, the result is read from stdout and the EIP-3155 trace of the
+// transaction from the output directory, as specified by the t8n interface.
+type Binary struct {
+	Command []string // executable and leading arguments, e.g. ["evm", "t8n"]
+}
+
+// Execute implements Executor.
+func (b *Binary) Execute(c *Case) (*Result, error) {
+	if len(b.Command) == 0 {
+		return nil, errors.New("no reference command configured")
+	}
+	input, err := c.T8nInput()
+	if err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp("", "evmfuzz-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	args := append(append([]string{}, b.Command[1:]...),
+		"--input.alloc=stdin", "--input.env=stdin", "--input.txs=stdin",
+		"--output.result=stdout", "--output.alloc=", "--output.body=",
+		"--output.basedir="+dir, "--state.fork="+c.Fork, "--trace",
+	)
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(b.Command[0], args...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("reference failed: %v: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	var output struct {
+		Result struct {
+			StateRoot common.Hash `json:"stateRoot"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &output); err != nil {
+		return nil, fmt.Errorf("invalid reference output: %v", err)
+	}
+	traces, err := filepath.Glob(filepath.Join(dir, "trace-0-*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	var trace []TraceLine
+	if len(traces) > 0 {
+		data, err := os.ReadFile(traces[0])
+		if err != nil {
+			return nil, err
+		}
+		if trace, err = ParseTrace(data); err != nil {
+			return nil, err
+		}
+	}
+	return &Result{Root: output.Result.StateRoot, Trace: trace}, nil
+}
+
+// Diff describes the first divergence between two results.
+type Diff struct {
+	Step  int // index of the first differing trace line, -1 if only the roots differ
+	Local *TraceLine
+	Ref   *TraceLine
+
+	LocalRoot common.Hash
+	RefRoot   common.Hash
+}
+
+func (d *Diff) String() string {
+	if d.Step < 0 {
+		return fmt.Sprintf("state root mismatch: local %v, reference %v", d.LocalRoot, d.RefRoot)
+	}
+	local, ref := "<missing>", "<missing>"
+	if d.Local != nil {
+		local = d.Local.String()
+	}
+	if d.Ref != nil {
+		ref = d.Ref.String()
+	}
+	return fmt.Sprintf("trace mismatch at step %d:\n  local:     %s\n  reference: %s", d.Step, local, ref)
+}
+
+// Compare returns the first divergence between two results, or nil if they are
+// identical.
+func Compare(local, ref *Result) *Diff {
+	for i := 0; i < len(local.Trace) || i < len(ref.Trace); i++ {
+		var a, b *TraceLine
+		if i < len(local.Trace) {
+			a = &local.Trace[i]
+		}
+		if i < len(ref.Trace) {
+			b = &ref.Trace[i]
+		}
+		if a == nil || b == nil || !a.equal(b) {
+			return &Diff{Step: i, Local: a, Ref: b, LocalRoot: local.Root, RefRoot: ref.Root}
+		}
+	}
+	if local.Root != ref.Root {
+		return &Diff{Step: -1, LocalRoot: local.Root, RefRoot: ref.Root}
+	}
+	return nil
+}
@@ -222,14 +222,22 @@ func BuildBlock(ctx *cli.Context) error {
 	if err != nil {
 		return err
 	}
-	block := inputData.ToBlock()
-	block, err = inputData.SealBlock(block)
+	block, err := buildBlock(inputData)
 	if err != nil {
 		return err
 	}
 	return dispatchBlock(ctx, baseDir, block)
 }
 
+// buildBlock assembles and, if requested, seals the block described by the
+// decoded input.
+func buildBlock(inputData *bbInput) (*types.Block, error) {
+	if inputData.Header == nil {
+		return nil, NewError(ErrorConfig, errors.New("missing header"))
+	}
+	return inputData.SealBlock(inputData.ToBlock())
+}
+
 func readInput(ctx *cli.Context) (*bbInput, error) {
 	var (
 		headerStr      = ctx.String(InputHeaderFlag.Name)
@@ -280,14 +288,21 @@ func readInput(ctx *cli.Context) (*bbInput, error) {
 		}
 		inputData.TxRlp = txs
 	}
-	// Deserialize rlp txs and ommers
+	if err := inputData.decodeRlp(); err != nil {
+		return nil, err
+	}
+	return inputData, nil
+}
+
+// decodeRlp deserializes the RLP encoded transactions and ommers of the input.
+func (inputData *bbInput) decodeRlp() error {
 	var (
 		ommers = []*types.Header{}
 		txs    = []*types.Transaction{}
 	)
 	if inputData.TxRlp != "" {
 		if err := rlp.DecodeBytes(common.FromHex(inputData.TxRlp), &txs); err != nil {
-			return nil, NewError(ErrorRlp, fmt.Errorf("unable to decode transaction from rlp data: %v", err))
+			return NewError(ErrorRlp, fmt.Errorf("unable to decode transaction from rlp data: %v", err))
 		}
 		inputData.Txs = txs
 	}
@@ -299,27 +314,29 @@ func readInput(ctx *cli.Context) (*bbInput, error) {
 		}
 		var ommer *extblock
 		if err := rlp.DecodeBytes(common.FromHex(str), &ommer); err != nil {
-			return nil, NewError(ErrorRlp, fmt.Errorf("unable to decode ommer from rlp data: %v", err))
+			return NewError(ErrorRlp, fmt.Errorf("unable to decode ommer from rlp data: %v", err))
 		}
 		ommers = append(ommers, ommer.Header)
 	}
 	inputData.Ommers = ommers
+	return nil
+}
 
-	return inputData, nil
+// blockInfo is the output of the block builder.
+type blockInfo struct {
+	Rlp  hexutil.Bytes `json:"rlp"`
+	Hash common.Hash   `json:"hash"`
+}
+
+func newBlockInfo(block *types.Block) *blockInfo {
+	raw, _ := rlp.EncodeToBytes(block)
+	return &blockInfo{Rlp: raw, Hash: block.Hash()}
 }
 
 // dispatchBlock writes the output data to either stderr or stdout, or to the specified
 // files
 func dispatchBlock(ctx *cli.Context, baseDir string, block *types.Block) error {
-	raw, _ := rlp.EncodeToBytes(block)
-	type blockInfo struct {
-		Rlp  hexutil.Bytes `json:"rlp"`
-		Hash common.Hash   `json:"hash"`
-	}
-	enc := blockInfo{
-		Rlp:  raw,
-		Hash: block.Hash(),
-	}
+	enc := newBlockInfo(block)
 	b, err := json.MarshalIndent(enc, "", "  ")
 	if err != nil {
 		return NewError(ErrorJson, fmt.Errorf("failed marshalling output: %v", err))
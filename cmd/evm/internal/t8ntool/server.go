@@ -0,0 +1,210 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package t8ntool
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/tests"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	ServerHTTPFlag = &cli.StringFlag{
+		Name:  "http",
+		Usage: "Listen address of the HTTP JSON-RPC endpoint (e.g. 127.0.0.1:8545). If unset, requests are read from stdin",
+	}
+)
+
+// T8nArgs are the parameters of an evm_t8n request. Besides the alloc, env and
+// txs (or txsRlp) sections of the t8n stdin input, they carry the values that
+// are otherwise configured via command line flags.
+type T8nArgs struct {
+	input
+	Fork    string       `json:"fork"`
+	ChainID *int64       `json:"chainid"`
+	Reward  *int64       `json:"reward"`
+	Trace   *traceConfig `json:"trace"`
+	BaseDir string       `json:"basedir"` // directory trace files are written to
+}
+
+// T8nOutput is the result of an evm_t8n request, identical to the stdout
+// output of the t8n command with all outputs directed to stdout.
+type T8nOutput struct {
+	Alloc  Alloc            `json:"alloc"`
+	Result *ExecutionResult `json:"result"`
+	Body   hexutil.Bytes    `json:"body"`
+}
+
+// B11rArgs are the parameters of an evm_b11r request, in the format of the
+// b11r stdin input.
+type B11rArgs struct {
+	bbInput
+}
+
+// T9nArgs are the parameters of an evm_t9n request.
+type T9nArgs struct {
+	TxRlp   string `json:"txsRlp"`
+	Fork    string `json:"fork"`
+	ChainID *int64 `json:"chainid"`
+}
+
+// API exposes the transition tool, block builder and transaction validator over
+// RPC. Fork configurations are resolved once and reused across requests, as is
+// the KZG trusted setup, which is loaded lazily on first use.
+type API struct {
+	lock    sync.Mutex
+	configs map[string]*forkConfig
+}
+
+type forkConfig struct {
+	config    *params.ChainConfig
+	extraEips []int
+}
+
+// NewAPI creates the RPC service of the server mode.
+func NewAPI() *API {
+	return &API{configs: make(map[string]*forkConfig)}
+}
+
+// chainConfig returns a private copy of the chain configuration of a fork,
+// with the given chain id set.
+func (api *API) chainConfig(fork string, chainID *int64) (*params.ChainConfig, []int, error) {
+	if fork == "" {
+		fork = ForknameFlag.Value
+	}
+	api.lock.Lock()
+	cfg, ok := api.configs[fork]
+	if !ok {
+		config, extraEips, err := tests.GetChainConfig(fork)
+		if err != nil {
+			api.lock.Unlock()
+			return nil, nil, NewError(ErrorConfig, fmt.Errorf("failed constructing chain configuration: %v", err))
+		}
+		cfg = &forkConfig{config: config, extraEips: extraEips}
+		api.configs[fork] = cfg
+	}
+	api.lock.Unlock()
+
+	config := *cfg.config
+	config.ChainID = big.NewInt(ChainIDFlag.Value)
+	if chainID != nil {
+		config.ChainID = big.NewInt(*chainID)
+	}
+	return &config, cfg.extraEips, nil
+}
+
+// T8n executes a state transition.
+func (api *API) T8n(args T8nArgs) (*T8nOutput, error) {
+	chainConfig, extraEips, err := api.chainConfig(args.Fork, args.ChainID)
+	if err != nil {
+		return nil, err
+	}
+	reward := RewardFlag.Value
+	if args.Reward != nil {
+		reward = *args.Reward
+	}
+	trace := new(traceConfig)
+	if args.Trace != nil {
+		trace = args.Trace
+	}
+	if (trace.JSON || trace.Tracer != "") && args.BaseDir == "" {
+		return nil, NewError(ErrorConfig, errors.New("tracing requires basedir"))
+	}
+	if args.BaseDir != "" {
+		if err := os.MkdirAll(args.BaseDir, 0755); err != nil {
+			return nil, NewError(ErrorIO, fmt.Errorf("failed creating output basedir: %v", err))
+		}
+	}
+	result, alloc, body, err := applyTransition(&args.input, stdinSelector, chainConfig, extraEips, reward, trace.tracerFn(args.BaseDir))
+	if err != nil {
+		return nil, err
+	}
+	return &T8nOutput{Alloc: alloc, Result: result, Body: body}, nil
+}
+
+// B11r builds a block.
+func (api *API) B11r(args B11rArgs) (*blockInfo, error) {
+	if err := args.decodeRlp(); err != nil {
+		return nil, err
+	}
+	block, err := buildBlock(&args.bbInput)
+	if err != nil {
+		return nil, err
+	}
+	return newBlockInfo(block), nil
+}
+
+// T9n validates a list of RLP encoded transactions.
+func (api *API) T9n(args T9nArgs) ([]result, error) {
+	chainConfig, _, err := api.chainConfig(args.Fork, args.ChainID)
+	if err != nil {
+		return nil, err
+	}
+	return validateTransactions(common.FromHex(args.TxRlp), chainConfig)
+}
+
+// Server runs the transition tool as a long-lived JSON-RPC server, answering
+// evm_t8n, evm_b11r and evm_t9n requests either over HTTP or as newline
+// separated JSON documents on stdin/stdout.
+func Server(ctx *cli.Context) error {
+	srv := rpc.NewServer()
+	defer srv.Stop()
+	if err := srv.RegisterName("evm", NewAPI()); err != nil {
+		return err
+	}
+	addr := ctx.String(ServerHTTPFlag.Name)
+	if addr == "" {
+		log.Info("Serving transition tool requests on stdin")
+		srv.ServeCodec(rpc.NewCodec(stdioConn{os.Stdin, os.Stdout}), 0)
+		return nil
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	log.Info("Serving transition tool requests", "endpoint", "http://"+listener.Addr().String())
+	return (&http.Server{Handler: srv, ReadHeaderTimeout: 5 * time.Second}).Serve(listener)
+}
+
+// stdioConn is an rpc.Conn reading requests from stdin and writing responses
+// to stdout. Reaching the end of stdin terminates the server.
+type stdioConn struct {
+	in  io.Reader
+	out io.Writer
+}
+
+func (c stdioConn) Read(b []byte) (int, error)  { return c.in.Read(b) }
+func (c stdioConn) Write(b []byte) (int, error) { return c.out.Write(b) }
+func (c stdioConn) Close() error                { return nil }
+
+func (c stdioConn) SetWriteDeadline(time.Time) error {
+	return errors.New("deadline not supported")
+}
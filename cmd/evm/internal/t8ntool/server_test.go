@@ -0,0 +1,204 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package t8ntool
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+func newTestClient(t *testing.T) *rpc.Client {
+	t.Helper()
+	srv := rpc.NewServer()
+	if err := srv.RegisterName("evm", NewAPI()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(srv.Stop)
+	client := rpc.DialInProc(srv)
+	t.Cleanup(client.Close)
+	return client
+}
+
+func readTestFile(t *testing.T, path string) json.RawMessage {
+	t.Helper()
+	blob, err := os.ReadFile(filepath.Join("..", "..", "testdata", path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return blob
+}
+
+// checkJSON verifies that have and want are the same JSON document, ignoring
+// formatting.
+func checkJSON(t *testing.T, have, want json.RawMessage) {
+	t.Helper()
+	var h, w interface{}
+	if err := json.Unmarshal(have, &h); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(want, &w); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(h, w) {
+		t.Errorf("output mismatch\nhave: %s\nwant: %s", have, want)
+	}
+}
+
+func TestServerT8n(t *testing.T) {
+	client := newTestClient(t)
+
+	// The same request is repeated to check that no state leaks between calls.
+	for i := 0; i < 2; i++ {
+		args := map[string]interface{}{
+			"alloc": readTestFile(t, "1/alloc.json"),
+			"env":   readTestFile(t, "1/env.json"),
+			"txs":   readTestFile(t, "1/txs.json"),
+			"fork":  "Byzantium",
+		}
+		var out map[string]json.RawMessage
+		if err := client.Call(&out, "evm_t8n", args); err != nil {
+			t.Fatal(err)
+		}
+		var exp map[string]json.RawMessage
+		if err := json.Unmarshal(readTestFile(t, "1/exp.json"), &exp); err != nil {
+			t.Fatal(err)
+		}
+		checkJSON(t, out["alloc"], exp["alloc"])
+		checkJSON(t, out["result"], exp["result"])
+	}
+}
+
+func TestServerT8nErrors(t *testing.T) {
+	client := newTestClient(t)
+
+	tests := []struct {
+		args map[string]interface{}
+		code int
+	}{
+		{
+			args: map[string]interface{}{"env": readTestFile(t, "1/env.json"), "fork": "Foobar"},
+			code: ErrorConfig,
+		},
+		{
+			args: map[string]interface{}{"alloc": readTestFile(t, "1/alloc.json"), "fork": "Byzantium"},
+			code: ErrorConfig,
+		},
+		{
+			args: map[string]interface{}{"env": readTestFile(t, "1/env.json"), "fork": "Byzantium", "trace": map[string]bool{"trace": true}},
+			code: ErrorConfig,
+		},
+	}
+	for i, tt := range tests {
+		err := client.Call(nil, "evm_t8n", tt.args)
+		var rpcErr rpc.Error
+		if !errors.As(err, &rpcErr) {
+			t.Errorf("test %d: expected rpc error, got %v", i, err)
+			continue
+		}
+		if rpcErr.ErrorCode() != tt.code {
+			t.Errorf("test %d: error code mismatch: have %d, want %d (%v)", i, rpcErr.ErrorCode(), tt.code, err)
+		}
+	}
+}
+
+func TestServerT8nTrace(t *testing.T) {
+	client := newTestClient(t)
+	dir := t.TempDir()
+
+	args := map[string]interface{}{
+		"alloc":   readTestFile(t, "1/alloc.json"),
+		"env":     readTestFile(t, "1/env.json"),
+		"txs":     readTestFile(t, "1/txs.json"),
+		"fork":    "Byzantium",
+		"trace":   map[string]bool{"trace": true},
+		"basedir": dir,
+	}
+	if err := client.Call(nil, "evm_t8n", args); err != nil {
+		t.Fatal(err)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "trace-*.jsonl"))
+	if len(files) == 0 {
+		t.Fatal("no trace files written")
+	}
+}
+
+func TestServerB11r(t *testing.T) {
+	client := newTestClient(t)
+
+	var txs string
+	if err := json.Unmarshal(readTestFile(t, "20/txs.rlp"), &txs); err != nil {
+		t.Fatal(err)
+	}
+	args := map[string]interface{}{
+		"header": readTestFile(t, "20/header.json"),
+		"ommers": readTestFile(t, "20/ommers.json"),
+		"txs":    txs,
+	}
+	var out json.RawMessage
+	if err := client.Call(&out, "evm_b11r", args); err != nil {
+		t.Fatal(err)
+	}
+	checkJSON(t, out, readTestFile(t, "20/exp.json"))
+}
+
+func TestServerT9n(t *testing.T) {
+	client := newTestClient(t)
+
+	var txs string
+	if err := json.Unmarshal(readTestFile(t, "15/signed_txs.rlp"), &txs); err != nil {
+		t.Fatal(err)
+	}
+	for fork, exp := range map[string]string{"Homestead": "15/exp.json", "London": "15/exp2.json"} {
+		var out json.RawMessage
+		if err := client.Call(&out, "evm_t9n", map[string]interface{}{"txsRlp": txs, "fork": fork}); err != nil {
+			t.Fatal(err)
+		}
+		checkJSON(t, out, readTestFile(t, exp))
+	}
+}
+
+func TestServerStdio(t *testing.T) {
+	srv := rpc.NewServer()
+	defer srv.Stop()
+	if err := srv.RegisterName("evm", NewAPI()); err != nil {
+		t.Fatal(err)
+	}
+	var (
+		in  = strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"evm_t9n","params":[{"txsRlp":"0xc0"}]}` + "\n")
+		out = new(bytes.Buffer)
+	)
+	srv.ServeCodec(rpc.NewCodec(stdioConn{in, out}), 0)
+
+	var resp struct {
+		ID     int             `json:"id"`
+		Result json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid response %q: %v", out.String(), err)
+	}
+	if resp.ID != 1 || string(resp.Result) != "[]" {
+		t.Errorf("unexpected response %s", out.String())
+	}
+}
@@ -106,17 +106,29 @@ func Transaction(ctx *cli.Context) error {
 			return NewError(ErrorIO, errors.New("only rlp supported"))
 		}
 	}
+	results, err := validateTransactions(body, chainConfig)
+	if err != nil {
+		return err
+	}
+	out, err := json.MarshalIndent(results, "", "  ")
+	fmt.Println(string(out))
+	return err
+}
+
+// validateTransactions checks the validity of every transaction in the RLP
+// encoded list, returning one result per transaction.
+func validateTransactions(body []byte, chainConfig *params.ChainConfig) ([]result, error) {
 	signer := types.MakeSigner(chainConfig, new(big.Int), 0)
 	// We now have the transactions in 'body', which is supposed to be an
 	// rlp list of transactions
-	it, err := rlp.NewListIterator([]byte(body))
+	it, err := rlp.NewListIterator(body)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	var results []result
+	results := []result{}
 	for it.Next() {
 		if err := it.Err(); err != nil {
-			return NewError(ErrorIO, err)
+			return nil, NewError(ErrorIO, err)
 		}
 		var tx types.Transaction
 		err := rlp.DecodeBytes(it.Value(), &tx)
@@ -171,7 +183,5 @@ func Transaction(ctx *cli.Context) error {
 		}
 		results = append(results, r)
 	}
-	out, err := json.MarshalIndent(results, "", "  ")
-	fmt.Println(string(out))
-	return err
+	return results, nil
 }
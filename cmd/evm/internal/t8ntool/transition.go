@@ -67,6 +67,12 @@ func (n *NumberedError) ExitCode() int {
 	return n.errorCode
 }
 
+// ErrorCode implements rpc.Error, reporting the exit code as the error code of
+// failed server mode requests.
+func (n *NumberedError) ErrorCode() int {
+	return n.errorCode
+}
+
 // compile-time conformance test
 var (
 	_ cli.ExitCoder = (*NumberedError)(nil)
@@ -79,54 +85,73 @@ type input struct {
 	TxRlp string             `json:"txsRlp,omitempty"`
 }
 
-func Transition(ctx *cli.Context) error {
-	var getTracer = func(txIndex int, txHash common.Hash) (vm.EVMLogger, error) { return nil, nil }
-
-	baseDir, err := createBasedir(ctx)
-	if err != nil {
-		return NewError(ErrorIO, fmt.Errorf("failed creating output basedir: %v", err))
-	}
+// traceConfig configures the tracing of a state transition.
+type traceConfig struct {
+	JSON             bool            `json:"trace"`
+	Tracer           string          `json:"tracer"`
+	TracerConfig     json.RawMessage `json:"tracerConfig"`
+	EnableMemory     bool            `json:"memory"`
+	DisableStack     bool            `json:"nostack"`
+	EnableReturnData bool            `json:"returndata"`
+}
 
-	if ctx.Bool(TraceFlag.Name) { // JSON opcode tracing
+// tracerFn returns the per-transaction tracer constructor for the given trace
+// config, emitting the traces to files in baseDir.
+func (cfg *traceConfig) tracerFn(baseDir string) func(txIndex int, txHash common.Hash) (vm.EVMLogger, error) {
+	if cfg.JSON { // JSON opcode tracing
 		// Configure the EVM logger
 		logConfig := &logger.Config{
-			DisableStack:     ctx.Bool(TraceDisableStackFlag.Name),
-			EnableMemory:     ctx.Bool(TraceEnableMemoryFlag.Name),
-			EnableReturnData: ctx.Bool(TraceEnableReturnDataFlag.Name),
+			DisableStack:     cfg.DisableStack,
+			EnableMemory:     cfg.EnableMemory,
+			EnableReturnData: cfg.EnableReturnData,
 			Debug:            true,
 		}
-		getTracer = func(txIndex int, txHash common.Hash) (vm.EVMLogger, error) {
+		return func(txIndex int, txHash common.Hash) (vm.EVMLogger, error) {
 			traceFile, err := os.Create(path.Join(baseDir, fmt.Sprintf("trace-%d-%v.jsonl", txIndex, txHash.String())))
 			if err != nil {
 				return nil, NewError(ErrorIO, fmt.Errorf("failed creating trace-file: %v", err))
 			}
 			return &traceWriter{logger.NewJSONLogger(logConfig, traceFile), traceFile}, nil
 		}
-	} else if ctx.IsSet(TraceTracerFlag.Name) {
-		var config json.RawMessage
-		if ctx.IsSet(TraceTracerConfigFlag.Name) {
-			config = []byte(ctx.String(TraceTracerConfigFlag.Name))
-		}
-		getTracer = func(txIndex int, txHash common.Hash) (vm.EVMLogger, error) {
+	}
+	if cfg.Tracer != "" {
+		return func(txIndex int, txHash common.Hash) (vm.EVMLogger, error) {
 			traceFile, err := os.Create(path.Join(baseDir, fmt.Sprintf("trace-%d-%v.json", txIndex, txHash.String())))
 			if err != nil {
 				return nil, NewError(ErrorIO, fmt.Errorf("failed creating trace-file: %v", err))
 			}
-			tracer, err := tracers.DefaultDirectory.New(ctx.String(TraceTracerFlag.Name), nil, config)
+			tracer, err := tracers.DefaultDirectory.New(cfg.Tracer, nil, cfg.TracerConfig)
 			if err != nil {
 				return nil, NewError(ErrorConfig, fmt.Errorf("failed instantiating tracer: %w", err))
 			}
 			return &traceWriter{tracer, traceFile}, nil
 		}
 	}
+	return func(txIndex int, txHash common.Hash) (vm.EVMLogger, error) { return nil, nil }
+}
+
+func Transition(ctx *cli.Context) error {
+	baseDir, err := createBasedir(ctx)
+	if err != nil {
+		return NewError(ErrorIO, fmt.Errorf("failed creating output basedir: %v", err))
+	}
+	traceCfg := &traceConfig{
+		JSON:             ctx.Bool(TraceFlag.Name),
+		EnableMemory:     ctx.Bool(TraceEnableMemoryFlag.Name),
+		DisableStack:     ctx.Bool(TraceDisableStackFlag.Name),
+		EnableReturnData: ctx.Bool(TraceEnableReturnDataFlag.Name),
+	}
+	if !traceCfg.JSON && ctx.IsSet(TraceTracerFlag.Name) {
+		traceCfg.Tracer = ctx.String(TraceTracerFlag.Name)
+		if ctx.IsSet(TraceTracerConfigFlag.Name) {
+			traceCfg.TracerConfig = []byte(ctx.String(TraceTracerConfigFlag.Name))
+		}
+	}
 	// We need to load three things: alloc, env and transactions. May be either in
 	// stdin input or in files.
 	// Check if anything needs to be read from stdin
 	var (
-		prestate Prestate
-		txIt     txIterator // txs to apply
-		allocStr = ctx.String(InputAllocFlag.Name)
-
+		allocStr  = ctx.String(InputAllocFlag.Name)
 		envStr    = ctx.String(InputEnvFlag.Name)
 		txStr     = ctx.String(InputTxsFlag.Name)
 		inputData = &input{}
@@ -143,8 +168,6 @@ func Transition(ctx *cli.Context) error {
 			return err
 		}
 	}
-	prestate.Pre = inputData.Alloc
-
 	// Set the block environment
 	if envStr != stdinSelector {
 		var env stEnv
@@ -153,44 +176,58 @@ func Transition(ctx *cli.Context) error {
 		}
 		inputData.Env = &env
 	}
-	prestate.Env = *inputData.Env
-
-	vmConfig := vm.Config{}
 	// Construct the chainconfig
-	var chainConfig *params.ChainConfig
-	if cConf, extraEips, err := tests.GetChainConfig(ctx.String(ForknameFlag.Name)); err != nil {
+	chainConfig, extraEips, err := tests.GetChainConfig(ctx.String(ForknameFlag.Name))
+	if err != nil {
 		return NewError(ErrorConfig, fmt.Errorf("failed constructing chain configuration: %v", err))
-	} else {
-		chainConfig = cConf
-		vmConfig.ExtraEips = extraEips
 	}
 	// Set the chain id
 	chainConfig.ChainID = big.NewInt(ctx.Int64(ChainIDFlag.Name))
 
-	if txIt, err = loadTransactions(txStr, inputData, prestate.Env, chainConfig); err != nil {
+	// Run the test and aggregate the result
+	result, collector, body, err := applyTransition(inputData, txStr, chainConfig, extraEips, ctx.Int64(RewardFlag.Name), traceCfg.tracerFn(baseDir))
+	if err != nil {
 		return err
 	}
+	// Dump the execution result
+	return dispatchOutput(ctx, baseDir, result, collector, body)
+}
+
+// applyTransition executes the state transition described by the input data,
+// returning the execution result, the post-state alloc and the RLP encoded
+// transactions that were included. Unless txStr is stdinSelector, transactions
+// are read from the file it names.
+func applyTransition(inputData *input, txStr string, chainConfig *params.ChainConfig, extraEips []int, reward int64,
+	getTracer func(txIndex int, txHash common.Hash) (vm.EVMLogger, error)) (*ExecutionResult, Alloc, hexutil.Bytes, error) {
+	if inputData.Env == nil {
+		return nil, nil, nil, NewError(ErrorConfig, errors.New("missing env section"))
+	}
+	prestate := Prestate{Pre: inputData.Alloc, Env: *inputData.Env}
+	vmConfig := vm.Config{ExtraEips: extraEips}
+
+	txIt, err := loadTransactions(txStr, inputData, prestate.Env, chainConfig)
+	if err != nil {
+		return nil, nil, nil, err
+	}
 	if err := applyLondonChecks(&prestate.Env, chainConfig); err != nil {
-		return err
+		return nil, nil, nil, err
 	}
 	if err := applyShanghaiChecks(&prestate.Env, chainConfig); err != nil {
-		return err
+		return nil, nil, nil, err
 	}
 	if err := applyMergeChecks(&prestate.Env, chainConfig); err != nil {
-		return err
+		return nil, nil, nil, err
 	}
 	if err := applyCancunChecks(&prestate.Env, chainConfig); err != nil {
-		return err
+		return nil, nil, nil, err
 	}
-	// Run the test and aggregate the result
-	s, result, body, err := prestate.Apply(vmConfig, chainConfig, txIt, ctx.Int64(RewardFlag.Name), getTracer)
+	s, result, body, err := prestate.Apply(vmConfig, chainConfig, txIt, reward, getTracer)
 	if err != nil {
-		return err
+		return nil, nil, nil, err
 	}
-	// Dump the execution result
 	collector := make(Alloc)
 	s.DumpToCollector(collector, nil)
-	return dispatchOutput(ctx, baseDir, result, collector, body)
+	return result, collector, body, nil
 }
 
 func applyLondonChecks(env *stEnv, chainConfig *params.ChainConfig) error {
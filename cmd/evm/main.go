@@ -177,6 +177,22 @@ var transactionCommand = &cli.Command{
 	},
 }
 
+var serverCommand = &cli.Command{
+	Name:   "server",
+	Usage:  "Serves t8n, b11r and t9n requests over JSON-RPC",
+	Action: t8ntool.Server,
+	Description: `The server command runs the transition tool as a long-lived process, avoiding
+the startup cost of spawning one process per invocation. Requests for the
+evm_t8n, evm_b11r and evm_t9n methods are read as newline separated JSON-RPC
+documents from stdin, or served over HTTP if --http is set. Their parameters
+are the stdin inputs of the respective commands, extended with the values
+otherwise given as flags (fork, chainid, reward, trace, basedir), and their
+results equal the stdout outputs of the commands.`,
+	Flags: []cli.Flag{
+		t8ntool.ServerHTTPFlag,
+	},
+}
+
 var blockBuilderCommand = &cli.Command{
 	Name:    "block-builder",
 	Aliases: []string{"b11r"},
@@ -229,11 +245,14 @@ func init() {
 		compileCommand,
 		disasmCommand,
 		runCommand,
+		debugCommand,
+		fuzzCommand,
 		blockTestCommand,
 		stateTestCommand,
 		stateTransitionCommand,
 		transactionCommand,
 		blockBuilderCommand,
+		serverCommand,
 	}
 	app.Before = func(ctx *cli.Context) error {
 		flags.MigrateGlobalFlags(ctx)
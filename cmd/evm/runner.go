@@ -107,6 +107,13 @@ func timedExec(bench bool, execFunc func() ([]byte, uint64, error)) (output []by
 }
 
 func runCmd(ctx *cli.Context) error {
+	return runEVM(ctx, nil)
+}
+
+// runEVM executes the code configured by the vm flags. If custom is non-nil,
+// it is installed as the EVM tracer instead of the one selected by the trace
+// flags, and is responsible for reporting the execution result.
+func runEVM(ctx *cli.Context, custom vm.EVMLogger) error {
 	logconfig := &logger.Config{
 		EnableMemory:     !ctx.Bool(DisableMemoryFlag.Name),
 		DisableStack:     ctx.Bool(DisableStackFlag.Name),
@@ -126,7 +133,9 @@ func runCmd(ctx *cli.Context) error {
 		blobHashes  []common.Hash  // TODO (MariusVanDerWijden) implement blob hashes in state tests
 		blobBaseFee = new(big.Int) // TODO (MariusVanDerWijden) implement blob fee in state tests
 	)
-	if ctx.Bool(MachineFlag.Name) {
+	if custom != nil {
+		tracer = custom
+	} else if ctx.Bool(MachineFlag.Name) {
 		tracer = logger.NewJSONLogger(logconfig, os.Stdout)
 	} else if ctx.Bool(DebugFlag.Name) {
 		debugLogger = logger.NewStructLogger(logconfig)
@@ -205,7 +214,7 @@ func runCmd(ctx *cli.Context) error {
 		if err != nil {
 			return err
 		}
-		bin, err := compiler.Compile(fn, src, false)
+		bin, err := compiler.Compile(fn, src, "", false)
 		if err != nil {
 			return err
 		}
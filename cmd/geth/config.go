@@ -28,9 +28,11 @@ import (
 
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/accounts/external"
+	"github.com/ethereum/go-ethereum/accounts/hdwallet"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
 	"github.com/ethereum/go-ethereum/accounts/scwallet"
 	"github.com/ethereum/go-ethereum/accounts/usbwallet"
+	"github.com/ethereum/go-ethereum/accounts/web3signer"
 	"github.com/ethereum/go-ethereum/cmd/utils"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
@@ -177,6 +179,15 @@ func makeFullNode(ctx *cli.Context) (*node.Node, ethapi.Backend) {
 		v := ctx.Uint64(utils.OverrideVerkle.Name)
 		cfg.Eth.OverrideVerkle = &v
 	}
+	// In replica mode, only serve the chain of the primary node over RPC.
+	if ctx.IsSet(utils.ReplicaFlag.Name) {
+		backend := utils.RegisterReplicaService(stack, utils.MakeReplicaConfig(ctx, stack, &cfg.Eth))
+		filterSystem := utils.RegisterFilterAPI(stack, backend, &cfg.Eth)
+		if ctx.IsSet(utils.GraphQLEnabledFlag.Name) {
+			utils.RegisterGraphQLService(stack, backend, filterSystem, &cfg.Node)
+		}
+		return stack, backend
+	}
 	backend, eth := utils.RegisterEthService(stack, &cfg.Eth)
 
 	// Create gauge with geth system and build information
@@ -343,6 +354,24 @@ func setAccountManagerBackends(conf *node.Config, am *accounts.Manager, keydir s
 	// we can have both, but it's very confusing for the user to see the same
 	// accounts in both externally and locally, plus very racey.
 	am.AddBackend(keystore.NewKeyStore(keydir, scryptN, scryptP))
+	if hdhub, err := hdwallet.NewHub(keydir, scryptN, scryptP); err != nil {
+		log.Warn(fmt.Sprintf("Failed to start mnemonic wallet hub, disabling: %v", err))
+	} else {
+		am.AddBackend(hdhub)
+	}
+	if len(conf.Web3Signer) > 0 {
+		log.Info("Using remote signer", "url", conf.Web3Signer)
+		backend, err := web3signer.NewBackend(web3signer.Config{
+			Endpoint: conf.Web3Signer,
+			TLSCert:  conf.Web3SignerTLSCert,
+			TLSKey:   conf.Web3SignerTLSKey,
+			TLSCA:    conf.Web3SignerTLSCA,
+		})
+		if err != nil {
+			return fmt.Errorf("error connecting to remote signer: %v", err)
+		}
+		am.AddBackend(backend)
+	}
 	if conf.USB {
 		// Start a USB hub for Ledger hardware wallets
 		if ledgerhub, err := usbwallet.NewLedgerHub(); err != nil {
@@ -18,10 +18,14 @@ package main
 
 import (
 	"bytes"
+	"encoding/csv"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"syscall"
@@ -51,6 +55,33 @@ var (
 		Name:  "remove.chain",
 		Usage: "If set, selects the state data for removal",
 	}
+	zstdDictSizeFlag = &cli.IntFlag{
+		Name:  "dictsize",
+		Usage: "Size of the zstd dictionary trained on the table items (0 = no dictionary)",
+		Value: rawdb.ZstdDictSize,
+	}
+	freezerChecksumsFlag = &cli.BoolFlag{
+		Name:  "checksums",
+		Usage: "Add checksums of the items to the index of the table",
+	}
+	freezerRepairFlag = &cli.BoolFlag{
+		Name:  "repair",
+		Usage: "Truncate the freezer to the first corrupted item",
+	}
+	inspectTopFlag = &cli.IntFlag{
+		Name:  "top",
+		Usage: "Number of largest contracts to display",
+		Value: 20,
+	}
+	inspectSortFlag = &cli.StringFlag{
+		Name:  "sort",
+		Usage: "Contract ranking criterion (size, slots, trie, code)",
+		Value: "size",
+	}
+	inspectCSVFlag = &cli.StringFlag{
+		Name:  "csv",
+		Usage: "Write the statistics of all contracts to the given CSV file",
+	}
 
 	removedbCommand = &cli.Command{
 		Action:    removeDB,
@@ -68,6 +99,7 @@ Remove blockchain and state databases`,
 		ArgsUsage: "",
 		Subcommands: []*cli.Command{
 			dbInspectCmd,
+			dbInspectContractsCmd,
 			dbStatCmd,
 			dbCompactCmd,
 			dbGetCmd,
@@ -75,6 +107,9 @@ Remove blockchain and state databases`,
 			dbPutCmd,
 			dbGetSlotsCmd,
 			dbDumpFreezerIndex,
+			dbFreezerRecompressCmd,
+			dbFreezerScrubCmd,
+			dbCheckpointCmd,
 			dbImportCmd,
 			dbExportCmd,
 			dbMetadataCmd,
@@ -91,6 +126,28 @@ Remove blockchain and state databases`,
 		Usage:       "Inspect the storage size for each type of data in the database",
 		Description: `This commands iterates the entire database. If the optional 'prefix' and 'start' arguments are provided, then the iteration is limited to the given subset of data.`,
 	}
+	dbInspectContractsCmd = &cli.Command{
+		Action:    inspectContracts,
+		Name:      "inspect-contracts",
+		ArgsUsage: "<start (optional)>",
+		Flags: flags.Merge([]cli.Flag{
+			utils.SyncModeFlag,
+			inspectTopFlag,
+			inspectSortFlag,
+			inspectCSVFlag,
+		}, utils.NetworkFlags, utils.DatabaseFlags),
+		Usage: "Inspect the storage size of each contract in the database",
+		Description: `This command iterates the storage snapshot and the path-scheme storage trie
+nodes, reporting the number of slots, the storage trie node bytes and the code
+size of each contract. The largest contracts are displayed, ranked by the
+criterion given with --sort, and the statistics of all contracts can be written
+to a CSV file with --csv. If the optional 'start' account hash is provided, the
+iteration starts there.
+
+Code sizes require the account snapshot, and trie node bytes are only reported
+for the path scheme, since hash-scheme nodes cannot be attributed to contracts
+without iterating the tries.`,
+	}
 	dbCheckStateContentCmd = &cli.Command{
 		Action:    checkStateContent,
 		Name:      "check-state-content",
@@ -174,6 +231,55 @@ WARNING: This is a low-level operation which may cause database corruption!`,
 		}, utils.NetworkFlags, utils.DatabaseFlags),
 		Description: "This command displays information about the freezer index.",
 	}
+	dbFreezerRecompressCmd = &cli.Command{
+		Action:    freezerRecompress,
+		Name:      "freezer-recompress",
+		Usage:     "Re-encode a specific freezer table with a different compression codec",
+		ArgsUsage: "<freezer-type> <table-type> <codec (snappy|zstd, optional)>",
+		Flags: flags.Merge([]cli.Flag{
+			utils.SyncModeFlag,
+			zstdDictSizeFlag,
+			freezerChecksumsFlag,
+		}, utils.NetworkFlags, utils.DatabaseFlags),
+		Description: `This command rewrites all items of a freezer table with the given codec, or
+the current one if omitted. Zstd tables are compressed with a dictionary trained
+on items sampled from the table, which is stored in the table metadata. With
+--checksums, the checksums of the items are added to the table index and are
+verified on every read. Interrupted conversions resume where they left off.
+Tables converted to zstd or with checksums can't be read by older versions of
+geth.`,
+	}
+	dbFreezerScrubCmd = &cli.Command{
+		Action:    freezerScrub,
+		Name:      "freezer-scrub",
+		Usage:     "Verify the integrity of all items in the freezer",
+		ArgsUsage: "<freezer-type (optional)>",
+		Flags: flags.Merge([]cli.Flag{
+			utils.SyncModeFlag,
+			freezerRepairFlag,
+		}, utils.NetworkFlags, utils.DatabaseFlags),
+		Description: `This command reads all items of all tables of the given freezer, or of all
+freezers if omitted, and reports the ranges of corrupted items. Items are
+verified against their checksums for tables which have them, and otherwise
+only for being decompressible. With --repair, the freezer is truncated to the
+first corrupted item. For the chain freezer, the head of the chain is rewound
+to the block before it, and the removed blocks are synced again on the next
+start.`,
+	}
+	dbCheckpointCmd = &cli.Command{
+		Action:    dbCheckpoint,
+		Name:      "checkpoint",
+		Usage:     "Create a consistent copy of the database",
+		ArgsUsage: "<target datadir>",
+		Flags: flags.Merge([]cli.Flag{
+			utils.SyncModeFlag,
+		}, utils.NetworkFlags, utils.DatabaseFlags),
+		Description: `This command copies the chain database, including the ancient store, into the
+given directory, which must not exist yet. The copy is laid out as a data
+directory which a separate node can be started from. The same operation is
+available on a running node via admin_checkpoint. Immutable freezer files are
+hard-linked into the copy if it is on the same file system.`,
+	}
 	dbImportCmd = &cli.Command{
 		Action:    importLDBdata,
 		Name:      "import",
@@ -321,6 +427,121 @@ func inspect(ctx *cli.Context) error {
 	return rawdb.InspectDatabase(db, prefix, start)
 }
 
+// contractRanking returns the ranking key of a contract for the given criterion.
+func contractRanking(criterion string) (func(*rawdb.ContractStat) uint64, error) {
+	switch criterion {
+	case "size":
+		return func(s *rawdb.ContractStat) uint64 { return uint64(s.Size()) }, nil
+	case "slots":
+		return func(s *rawdb.ContractStat) uint64 { return s.Slots }, nil
+	case "trie":
+		return func(s *rawdb.ContractStat) uint64 { return uint64(s.TrieSize) }, nil
+	case "code":
+		return func(s *rawdb.ContractStat) uint64 { return uint64(s.CodeSize) }, nil
+	default:
+		return nil, fmt.Errorf("unknown ranking criterion %q", criterion)
+	}
+}
+
+func inspectContracts(ctx *cli.Context) error {
+	var start common.Hash
+	if ctx.NArg() > 1 {
+		return fmt.Errorf("max 1 argument: %v", ctx.Command.ArgsUsage)
+	}
+	if ctx.NArg() == 1 {
+		d, err := hexutil.Decode(ctx.Args().First())
+		if err != nil {
+			return fmt.Errorf("failed to hex-decode 'start': %v", err)
+		}
+		start = common.BytesToHash(d)
+	}
+	rank, err := contractRanking(ctx.String(inspectSortFlag.Name))
+	if err != nil {
+		return err
+	}
+	var (
+		top  = ctx.Int(inspectTopFlag.Name)
+		csvw *csv.Writer
+	)
+	if path := ctx.String(inspectCSVFlag.Name); path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		csvw = csv.NewWriter(f)
+		csvw.Write([]string{"account", "address", "slots", "slot_bytes", "trie_nodes", "trie_bytes", "code_bytes"})
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, true)
+	defer db.Close()
+
+	// Keep the largest contracts, sorted in descending order
+	var largest []*rawdb.ContractStat
+	err = rawdb.InspectContracts(db, start, func(stat *rawdb.ContractStat) error {
+		if csvw != nil {
+			var addr string
+			if stat.Address != nil {
+				addr = stat.Address.Hex()
+			}
+			err := csvw.Write([]string{
+				stat.Account.Hex(),
+				addr,
+				strconv.FormatUint(stat.Slots, 10),
+				strconv.FormatUint(uint64(stat.SlotSize), 10),
+				strconv.FormatUint(stat.TrieNodes, 10),
+				strconv.FormatUint(uint64(stat.TrieSize), 10),
+				strconv.FormatUint(uint64(stat.CodeSize), 10),
+			})
+			if err != nil {
+				return err
+			}
+		}
+		key := rank(stat)
+		if len(largest) == top && (top == 0 || key <= rank(largest[top-1])) {
+			return nil
+		}
+		i := sort.Search(len(largest), func(i int) bool { return rank(largest[i]) < key })
+		if len(largest) < top {
+			largest = append(largest, nil)
+		}
+		copy(largest[i+1:], largest[i:])
+		largest[i] = stat
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if csvw != nil {
+		csvw.Flush()
+		if err := csvw.Error(); err != nil {
+			return err
+		}
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Account", "Address", "Slots", "Slot size", "Trie nodes", "Trie size", "Code size", "Total"})
+	for _, stat := range largest {
+		addr := "-"
+		if stat.Address != nil {
+			addr = stat.Address.Hex()
+		}
+		table.Append([]string{
+			stat.Account.Hex(),
+			addr,
+			strconv.FormatUint(stat.Slots, 10),
+			stat.SlotSize.String(),
+			strconv.FormatUint(stat.TrieNodes, 10),
+			stat.TrieSize.String(),
+			stat.CodeSize.String(),
+			stat.Size().String(),
+		})
+	}
+	table.Render()
+	return nil
+}
+
 func checkStateContent(ctx *cli.Context) error {
 	var (
 		prefix []byte
@@ -419,6 +640,32 @@ func dbCompact(ctx *cli.Context) error {
 	return nil
 }
 
+// dbCheckpoint creates a checkpoint of the chain database.
+func dbCheckpoint(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("required arguments: %v", ctx.Command.ArgsUsage)
+	}
+	target := ctx.Args().Get(0)
+	if common.FileExist(target) {
+		return fmt.Errorf("directory %s already exists", target)
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, false)
+	defer db.Close()
+
+	cp, ok := db.(ethdb.Checkpointer)
+	if !ok {
+		return errors.New("database does not support checkpoints")
+	}
+	rel, err := filepath.Rel(stack.DataDir(), stack.ResolvePath("chaindata"))
+	if err != nil {
+		return err
+	}
+	return cp.Checkpoint(filepath.Join(target, rel))
+}
+
 // dbGet shows the value of a given database key
 func dbGet(ctx *cli.Context) error {
 	if ctx.NArg() != 1 {
@@ -598,6 +845,84 @@ func freezerInspect(ctx *cli.Context) error {
 	return rawdb.InspectFreezerTable(ancient, freezer, table, start, end)
 }
 
+func freezerRecompress(ctx *cli.Context) error {
+	if ctx.NArg() != 2 && ctx.NArg() != 3 {
+		return fmt.Errorf("required arguments: %v", ctx.Command.ArgsUsage)
+	}
+	var (
+		freezer = ctx.Args().Get(0)
+		table   = ctx.Args().Get(1)
+		codec   = ctx.Args().Get(2)
+	)
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	ancient := stack.ResolveAncient("chaindata", ctx.String(utils.AncientFlag.Name))
+	start := time.Now()
+	if err := rawdb.RecompressFreezerTable(ancient, freezer, table, codec, ctx.Int(zstdDictSizeFlag.Name), ctx.Bool(freezerChecksumsFlag.Name)); err != nil {
+		return err
+	}
+	log.Info("Recompressed freezer table", "freezer", freezer, "table", table, "codec", codec, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+func freezerScrub(ctx *cli.Context) error {
+	if ctx.NArg() > 1 {
+		return fmt.Errorf("required arguments: %v", ctx.Command.ArgsUsage)
+	}
+	freezers := []string{rawdb.ChainFreezerName, rawdb.StateFreezerName}
+	if ctx.NArg() == 1 {
+		freezers = []string{ctx.Args().Get(0)}
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	var (
+		ancient = stack.ResolveAncient("chaindata", ctx.String(utils.AncientFlag.Name))
+		repair  = ctx.Bool(freezerRepairFlag.Name)
+		table   = tablewriter.NewWriter(os.Stdout)
+		found   bool
+	)
+	table.SetHeader([]string{"Freezer", "Table", "First", "Last", "Error"})
+	for _, freezer := range freezers {
+		// The chain freezer is repaired through the full database, which
+		// rewinds the key-value store along with it.
+		var (
+			start       = time.Now()
+			repairChain = repair && freezer == rawdb.ChainFreezerName
+		)
+		corruptions, err := rawdb.ScrubFreezer(ancient, freezer, repair && !repairChain)
+		if errors.Is(err, fs.ErrNotExist) && ctx.NArg() == 0 {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if repairChain && len(corruptions) > 0 {
+			db := utils.MakeChainDatabase(ctx, stack, false)
+			err := rawdb.RewindChainFreezer(db, rawdb.FirstCorruptedItem(corruptions))
+			db.Close()
+			if err != nil {
+				return err
+			}
+		}
+		log.Info("Scrubbed freezer", "freezer", freezer, "corrupted", len(corruptions), "elapsed", common.PrettyDuration(time.Since(start)))
+		for _, c := range corruptions {
+			table.Append([]string{freezer, c.Table, strconv.FormatUint(c.Start, 10), strconv.FormatUint(c.End, 10), c.Err.Error()})
+			found = true
+		}
+	}
+	if !found {
+		fmt.Println("No corrupted items found")
+		return nil
+	}
+	table.Render()
+	if !repair {
+		return errors.New("corrupted items found")
+	}
+	return nil
+}
+
 func importLDBdata(ctx *cli.Context) error {
 	start := 0
 	switch ctx.NArg() {
@@ -64,8 +64,15 @@ var (
 		utils.PasswordFileFlag,
 		utils.BootnodesFlag,
 		utils.MinFreeDiskSpaceFlag,
+		utils.ReplicaFlag,
+		utils.ReplicaAncientFlag,
+		utils.ReplicaRefreshFlag,
 		utils.KeyStoreDirFlag,
 		utils.ExternalSignerFlag,
+		utils.Web3SignerFlag,
+		utils.Web3SignerTLSCertFlag,
+		utils.Web3SignerTLSKeyFlag,
+		utils.Web3SignerTLSCAFlag,
 		utils.NoUSBFlag, // deprecated
 		utils.USBFlag,
 		utils.SmartCardDaemonPathFlag,
@@ -94,6 +101,9 @@ var (
 		utils.TxLookupLimitFlag, // deprecated
 		utils.TransactionHistoryFlag,
 		utils.StateHistoryFlag,
+		utils.StatePruningFlag,
+		utils.StatePruningRateFlag,
+		utils.StatePruningIntervalFlag,
 		utils.LightServeFlag,    // deprecated
 		utils.LightIngressFlag,  // deprecated
 		utils.LightEgressFlag,   // deprecated
@@ -112,6 +122,7 @@ var (
 		utils.CacheGCFlag,
 		utils.CacheSnapshotFlag,
 		utils.CacheNoPrefetchFlag,
+		utils.ParallelWorkersFlag,
 		utils.CachePreimagesFlag,
 		utils.CacheLogSizeFlag,
 		utils.FDLimitFlag,
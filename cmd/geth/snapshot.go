@@ -22,6 +22,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/ethereum/go-ethereum/cmd/utils"
@@ -159,6 +160,39 @@ block is used.
 				Description: `
 The export-preimages command exports hash preimages to a flat file, in exactly
 the expected order for the overlay tree migration.
+`,
+			},
+			{
+				Action:    snapshotExportState,
+				Name:      "export",
+				Usage:     "Export the state of a block into a binary file",
+				ArgsUsage: "<dumpfile> [? <blockHash> | <blockNum>]",
+				Flags:     flags.Merge(utils.NetworkFlags, utils.DatabaseFlags),
+				Description: `
+geth snapshot export <dumpfile> [? <blockHash> | <blockNum>]
+streams the accounts, storage slots and contract codes of the state of the given
+block from the snapshot into a compact binary file, split into chunks protected
+by hashes. If the file name ends with .gz, the output is gzipped. The default
+exported block is the HEAD block.
+
+The file can be imported into another node using 'geth snapshot import'.
+`,
+			},
+			{
+				Action:    snapshotImportState,
+				Name:      "import",
+				Usage:     "Import the state of a block from a binary file",
+				ArgsUsage: "<dumpfile> <blockHash>",
+				Flags:     flags.Merge(utils.NetworkFlags, utils.DatabaseFlags),
+				Description: `
+geth snapshot import <dumpfile> <blockHash>
+rebuilds the snapshot and the state trie, in the configured state scheme, from a
+file created by 'geth snapshot export'. The file must contain the state of the
+block with the given trusted hash, the root of the rebuilt state is verified
+against its header.
+
+Any existing snapshot is replaced. In path mode (--state.scheme=path), the
+database must not contain any state yet.
 `,
 			},
 		},
@@ -689,3 +723,86 @@ func checkAccount(ctx *cli.Context) error {
 	log.Info("Checked the snapshot journalled storage", "time", common.PrettyDuration(time.Since(start)))
 	return nil
 }
+
+// snapshotExportState exports the state of a block from the snapshot.
+func snapshotExportState(ctx *cli.Context) error {
+	if ctx.NArg() < 1 || ctx.NArg() > 2 {
+		return errors.New("need <dumpfile> [<blockHash> | <blockNum>] args")
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chaindb := utils.MakeChainDatabase(ctx, stack, true)
+	defer chaindb.Close()
+
+	headBlock := rawdb.ReadHeadBlock(chaindb)
+	if headBlock == nil {
+		log.Error("Failed to load head block")
+		return errors.New("no head block")
+	}
+	header := headBlock.Header()
+	if ctx.NArg() == 2 {
+		arg := ctx.Args().Get(1)
+		if hashish(arg) {
+			hash := common.HexToHash(arg)
+			if number := rawdb.ReadHeaderNumber(chaindb, hash); number != nil {
+				header = rawdb.ReadHeader(chaindb, hash, *number)
+			} else {
+				header = nil
+			}
+		} else {
+			number, err := strconv.ParseUint(arg, 10, 64)
+			if err != nil {
+				return err
+			}
+			header = rawdb.ReadHeader(chaindb, rawdb.ReadCanonicalHash(chaindb, number), number)
+		}
+		if header == nil {
+			return fmt.Errorf("block %s not found", arg)
+		}
+	}
+	triedb := utils.MakeTrieDatabase(ctx, chaindb, false, true, false)
+	defer triedb.Close()
+
+	snapConfig := snapshot.Config{
+		CacheSize:  256,
+		Recovery:   false,
+		NoBuild:    true,
+		AsyncBuild: false,
+	}
+	snaptree, err := snapshot.New(snapConfig, chaindb, triedb, headBlock.Root())
+	if err != nil {
+		log.Error("Failed to open snapshot tree", "err", err)
+		return err
+	}
+	return utils.ExportSnapshotState(chaindb, snaptree, ctx.Args().First(), header)
+}
+
+// snapshotImportState rebuilds the state of a trusted block from an export.
+func snapshotImportState(ctx *cli.Context) error {
+	if ctx.NArg() != 2 {
+		return errors.New("need <dumpfile> <blockHash> args")
+	}
+	trusted, err := parseRoot(ctx.Args().Get(1))
+	if err != nil {
+		log.Error("Failed to resolve trusted block hash", "err", err)
+		return err
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chaindb := utils.MakeChainDatabase(ctx, stack, false)
+	defer chaindb.Close()
+
+	scheme, err := rawdb.ParseStateScheme(ctx.String(utils.StateSchemeFlag.Name), chaindb)
+	if err != nil {
+		return err
+	}
+	header, err := utils.ImportSnapshotState(chaindb, ctx.Args().First(), scheme, trusted)
+	if err != nil {
+		log.Error("Failed to import state", "err", err)
+		return err
+	}
+	log.Info("Imported state", "number", header.Number, "hash", trusted, "root", header.Root)
+	return nil
+}
@@ -661,6 +661,68 @@ func ExportSnapshotPreimages(chaindb ethdb.Database, snaptree *snapshot.Tree, fn
 	return nil
 }
 
+// ExportSnapshotState exports the state of the given block from the snapshot
+// into a chunked binary file, truncating any data already present in it.
+func ExportSnapshotState(chaindb ethdb.Database, snaptree *snapshot.Tree, fn string, header *types.Header) error {
+	log.Info("Exporting state", "file", fn, "number", header.Number, "root", header.Root)
+
+	// Open the file handle and potentially wrap with a gzip stream
+	fh, err := os.OpenFile(fn, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+
+	var (
+		writer io.Writer = fh
+		gz     *gzip.Writer
+	)
+	if strings.HasSuffix(fn, ".gz") {
+		gz = gzip.NewWriter(writer)
+		writer = gz
+	}
+	buf := bufio.NewWriter(writer)
+	if err := snapshot.Export(buf, snaptree, header, chaindb); err != nil {
+		return err
+	}
+	if err := buf.Flush(); err != nil {
+		return err
+	}
+	// Close the gzip stream and the file explicitly, as the export is incomplete
+	// if the final flush or the gzip footer can't be written.
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			return err
+		}
+	}
+	if err := fh.Close(); err != nil {
+		return err
+	}
+	log.Info("Exported state", "file", fn)
+	return nil
+}
+
+// ImportSnapshotState rebuilds the snapshot and the state tries from a file
+// created by ExportSnapshotState, verifying it against the trusted block hash.
+func ImportSnapshotState(chaindb ethdb.Database, fn string, scheme string, trusted common.Hash) (*types.Header, error) {
+	log.Info("Importing state", "file", fn)
+
+	// Open the file handle and potentially unwrap the gzip stream
+	fh, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+
+	var reader io.Reader = bufio.NewReader(fh)
+	if strings.HasSuffix(fn, ".gz") {
+		if reader, err = gzip.NewReader(reader); err != nil {
+			return nil, err
+		}
+	}
+	return snapshot.Import(reader, chaindb, scheme, trusted)
+}
+
 // exportHeader is used in the export/import flow. When we do an export,
 // the first element we output is the exportHeader.
 // Whenever a backwards-incompatible change is made, the Version header
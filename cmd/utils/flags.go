@@ -50,6 +50,7 @@ import (
 	"github.com/ethereum/go-ethereum/eth/ethconfig"
 	"github.com/ethereum/go-ethereum/eth/filters"
 	"github.com/ethereum/go-ethereum/eth/gasprice"
+	"github.com/ethereum/go-ethereum/eth/replica"
 	"github.com/ethereum/go-ethereum/eth/tracers"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/ethdb/remotedb"
@@ -103,11 +104,32 @@ var (
 		Value:    node.DefaultConfig.DBEngine,
 		Category: flags.EthCategory,
 	}
+	FreezerChecksumsFlag = &cli.BoolFlag{
+		Name:     "db.freezer.checksums",
+		Usage:    "Store checksums of the items in newly created ancient stores",
+		Category: flags.EthCategory,
+	}
 	AncientFlag = &flags.DirectoryFlag{
 		Name:     "datadir.ancient",
 		Usage:    "Root directory for ancient data (default = inside chaindata)",
 		Category: flags.EthCategory,
 	}
+	ReplicaFlag = &flags.DirectoryFlag{
+		Name:     "replica",
+		Usage:    "Data directory of a primary node to follow read-only, serving its chain without p2p networking",
+		Category: flags.EthCategory,
+	}
+	ReplicaAncientFlag = &flags.DirectoryFlag{
+		Name:     "replica.ancient",
+		Usage:    "Root directory for ancient data of the primary node (default = inside its chaindata)",
+		Category: flags.EthCategory,
+	}
+	ReplicaRefreshFlag = &cli.DurationFlag{
+		Name:     "replica.refresh",
+		Usage:    "Interval of refreshing the view of the primary node's database",
+		Value:    replica.Defaults.Refresh,
+		Category: flags.EthCategory,
+	}
 	MinFreeDiskSpaceFlag = &flags.DirectoryFlag{
 		Name:     "datadir.minfreedisk",
 		Usage:    "Minimum free disk space in MB, once reached triggers auto shut down (default = --cache.gc converted to MB, 0 = disabled)",
@@ -269,6 +291,22 @@ var (
 		Usage:    "Scheme to use for storing ethereum state ('hash' or 'path')",
 		Category: flags.StateCategory,
 	}
+	StatePruningFlag = &cli.BoolFlag{
+		Name:     "state.pruning",
+		Usage:    "Periodically delete the stale state from the database while running, only relevant in state.scheme=hash (requires snapshots, uses 2GB of memory while pruning)",
+		Category: flags.StateCategory,
+	}
+	StatePruningRateFlag = &cli.IntFlag{
+		Name:     "state.pruning.rate",
+		Usage:    "Maximum number of trie nodes deleted per second by the online state pruning (0 = unlimited)",
+		Category: flags.StateCategory,
+	}
+	StatePruningIntervalFlag = &cli.DurationFlag{
+		Name:     "state.pruning.interval",
+		Usage:    "Time between two consecutive online state prunings",
+		Value:    ethconfig.Defaults.OnlinePruningInterval,
+		Category: flags.StateCategory,
+	}
 	StateHistoryFlag = &cli.Uint64Flag{
 		Name:     "history.state",
 		Usage:    "Number of recent blocks to retain state history for (default = 90,000 blocks, 0 = entire chain)",
@@ -401,6 +439,11 @@ var (
 		Usage:    "Disable heuristic state prefetch during block import (less CPU and disk IO, more time waiting for data)",
 		Category: flags.PerfCategory,
 	}
+	ParallelWorkersFlag = &cli.IntFlag{
+		Name:     "parallel.workers",
+		Usage:    "Number of goroutines executing block transactions optimistically in parallel (0 = sequential)",
+		Category: flags.PerfCategory,
+	}
 	CachePreimagesFlag = &cli.BoolFlag{
 		Name:     "cache.preimages",
 		Usage:    "Enable recording the SHA3/keccak preimages of trie keys",
@@ -484,6 +527,30 @@ var (
 		Value:    "",
 		Category: flags.AccountCategory,
 	}
+	Web3SignerFlag = &cli.StringFlag{
+		Name:     "web3signer",
+		Usage:    "Remote signer speaking the Web3Signer HTTP signing protocol (url)",
+		Value:    "",
+		Category: flags.AccountCategory,
+	}
+	Web3SignerTLSCertFlag = &cli.PathFlag{
+		Name:      "web3signer.tls.cert",
+		Usage:     "Client certificate to authenticate to the remote signer with (PEM file)",
+		TakesFile: true,
+		Category:  flags.AccountCategory,
+	}
+	Web3SignerTLSKeyFlag = &cli.PathFlag{
+		Name:      "web3signer.tls.key",
+		Usage:     "Private key of the remote signer client certificate (PEM file)",
+		TakesFile: true,
+		Category:  flags.AccountCategory,
+	}
+	Web3SignerTLSCAFlag = &cli.PathFlag{
+		Name:      "web3signer.tls.ca",
+		Usage:     "CA certificates to verify the remote signer with (PEM file)",
+		TakesFile: true,
+		Category:  flags.AccountCategory,
+	}
 	InsecureUnlockAllowedFlag = &cli.BoolFlag{
 		Name:     "allow-insecure-unlock",
 		Usage:    "Allow insecure account unlocking when account-related RPCs are exposed by http",
@@ -926,6 +993,7 @@ var (
 		AncientFlag,
 		RemoteDBFlag,
 		DBEngineFlag,
+		FreezerChecksumsFlag,
 		StateSchemeFlag,
 		HttpHeaderFlag,
 	}
@@ -1334,8 +1402,8 @@ func SetP2PConfig(ctx *cli.Context, cfg *p2p.Config) {
 		cfg.NetRestrict = list
 	}
 
-	if ctx.Bool(DeveloperFlag.Name) {
-		// --dev mode can't use p2p networking.
+	if ctx.Bool(DeveloperFlag.Name) || ctx.IsSet(ReplicaFlag.Name) {
+		// --dev and --replica modes can't use p2p networking.
 		cfg.MaxPeers = 0
 		cfg.ListenAddr = ""
 		cfg.NoDial = true
@@ -1367,6 +1435,19 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 		cfg.ExternalSigner = ctx.String(ExternalSignerFlag.Name)
 	}
 
+	if ctx.IsSet(Web3SignerFlag.Name) {
+		cfg.Web3Signer = ctx.String(Web3SignerFlag.Name)
+	}
+	if ctx.IsSet(Web3SignerTLSCertFlag.Name) {
+		cfg.Web3SignerTLSCert = ctx.String(Web3SignerTLSCertFlag.Name)
+	}
+	if ctx.IsSet(Web3SignerTLSKeyFlag.Name) {
+		cfg.Web3SignerTLSKey = ctx.String(Web3SignerTLSKeyFlag.Name)
+	}
+	if ctx.IsSet(Web3SignerTLSCAFlag.Name) {
+		cfg.Web3SignerTLSCA = ctx.String(Web3SignerTLSCAFlag.Name)
+	}
+
 	if ctx.IsSet(KeyStoreDirFlag.Name) {
 		cfg.KeyStoreDir = ctx.String(KeyStoreDirFlag.Name)
 	}
@@ -1393,6 +1474,9 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 		log.Info(fmt.Sprintf("Using %s as db engine", dbEngine))
 		cfg.DBEngine = dbEngine
 	}
+	if ctx.IsSet(FreezerChecksumsFlag.Name) {
+		cfg.FreezerChecksums = ctx.Bool(FreezerChecksumsFlag.Name)
+	}
 	// deprecation notice for log debug flags (TODO: find a more appropriate place to put these?)
 	if ctx.IsSet(LogBacktraceAtFlag.Name) {
 		log.Warn("log.backtrace flag is deprecated")
@@ -1586,7 +1670,8 @@ func CheckExclusive(ctx *cli.Context, args ...interface{}) {
 func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	// Avoid conflicting network flags
 	CheckExclusive(ctx, MainnetFlag, DeveloperFlag, GoerliFlag, SepoliaFlag, HoleskyFlag)
-	CheckExclusive(ctx, DeveloperFlag, ExternalSignerFlag) // Can't use both ephemeral unlocked and external signer
+	CheckExclusive(ctx, DeveloperFlag, ExternalSignerFlag)  // Can't use both ephemeral unlocked and external signer
+	CheckExclusive(ctx, ExternalSignerFlag, Web3SignerFlag) // Can't use both external signers
 
 	// Set configurations from CLI flags
 	setEtherbase(ctx, cfg)
@@ -1642,6 +1727,9 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.IsSet(CacheNoPrefetchFlag.Name) {
 		cfg.NoPrefetch = ctx.Bool(CacheNoPrefetchFlag.Name)
 	}
+	if ctx.IsSet(ParallelWorkersFlag.Name) {
+		cfg.ParallelWorkers = ctx.Int(ParallelWorkersFlag.Name)
+	}
 	// Read the value from the flag no matter if it's set or not.
 	cfg.Preimages = ctx.Bool(CachePreimagesFlag.Name)
 	if cfg.NoPruning && !cfg.Preimages {
@@ -1654,6 +1742,15 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.IsSet(StateSchemeFlag.Name) {
 		cfg.StateScheme = ctx.String(StateSchemeFlag.Name)
 	}
+	if ctx.IsSet(StatePruningFlag.Name) {
+		cfg.OnlinePruning = ctx.Bool(StatePruningFlag.Name)
+	}
+	if ctx.IsSet(StatePruningRateFlag.Name) {
+		cfg.OnlinePruningRate = ctx.Int(StatePruningRateFlag.Name)
+	}
+	if ctx.IsSet(StatePruningIntervalFlag.Name) {
+		cfg.OnlinePruningInterval = ctx.Duration(StatePruningIntervalFlag.Name)
+	}
 	// Parse transaction history flag, if user is still using legacy config
 	// file with 'TxLookupLimit' configured, copy the value to 'TransactionHistory'.
 	if cfg.TransactionHistory == ethconfig.Defaults.TransactionHistory && cfg.TxLookupLimit != ethconfig.Defaults.TxLookupLimit {
@@ -1869,6 +1966,43 @@ func RegisterEthService(stack *node.Node, cfg *ethconfig.Config) (ethapi.Backend
 	return backend.APIBackend, backend
 }
 
+// MakeReplicaConfig creates the configuration of a replica following the primary
+// node given on the command line.
+func MakeReplicaConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) *replica.Config {
+	datadir := ctx.String(ReplicaFlag.Name)
+	if datadir == stack.DataDir() {
+		Fatalf("Replica can't follow its own data directory %s", datadir)
+	}
+	// The primary's database lives in the instance directory of the same client.
+	primary := &node.Config{DataDir: datadir, Name: stack.Config().Name}
+	config := &replica.Config{
+		Directory:         primary.ResolvePath("chaindata"),
+		AncientsDirectory: ctx.String(ReplicaAncientFlag.Name),
+		Refresh:           ctx.Duration(ReplicaRefreshFlag.Name),
+		DatabaseCache:     cfg.DatabaseCache,
+		DatabaseHandles:   cfg.DatabaseHandles,
+		TrieCleanCache:    cfg.TrieCleanCache,
+		GPO:               cfg.GPO,
+		RPCGasCap:         cfg.RPCGasCap,
+		RPCEVMTimeout:     cfg.RPCEVMTimeout,
+		RPCTxFeeCap:       cfg.RPCTxFeeCap,
+	}
+	if ctx.IsSet(NetworkIdFlag.Name) {
+		config.NetworkId = cfg.NetworkId
+	}
+	return config
+}
+
+// RegisterReplicaService adds a replica following the database of a primary
+// node to the stack.
+func RegisterReplicaService(stack *node.Node, cfg *replica.Config) ethapi.Backend {
+	backend, err := replica.New(stack, cfg)
+	if err != nil {
+		Fatalf("Failed to register the replica service: %v", err)
+	}
+	return backend.APIBackend
+}
+
 // RegisterEthStatsService configures the Ethereum Stats daemon and adds it to the node.
 func RegisterEthStatsService(stack *node.Node, backend ethapi.Backend, url string) {
 	if err := ethstats.New(stack, backend, backend.Engine(), url); err != nil {
@@ -2101,6 +2235,7 @@ func MakeChain(ctx *cli.Context, stack *node.Node, readonly bool) (*core.BlockCh
 		Preimages:           ctx.Bool(CachePreimagesFlag.Name),
 		StateScheme:         scheme,
 		StateHistory:        ctx.Uint64(StateHistoryFlag.Name),
+		ParallelWorkers:     ctx.Int(ParallelWorkersFlag.Name),
 	}
 	if cache.TrieDirtyDisabled && !cache.Preimages {
 		cache.Preimages = true
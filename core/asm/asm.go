@@ -15,11 +15,33 @@
 // along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
 
 // Package asm provides support for dealing with EVM assembly instructions (e.g., disassembling them).
+//
+// The assembler accepts one instruction per line; ";;" starts a comment:
+//
+//	#include "lib.easm"         ;; compile another file in place
+//	#define SLOT 0x01           ;; named constant (number, string or label)
+//	#macro store(slot, value)   ;; macro with parameters
+//	    push value
+//	    push slot
+//	    sstore
+//	#end
+//
+//	    store(SLOT, 42)         ;; macro invocation
+//	    push 0                  ;; smallest push fitting the value, PUSH0 if available
+//	    push2 0x01              ;; push of explicit size
+//	    jump @loop              ;; push of a label followed by the jump
+//	loop:                       ;; label definition, emits a JUMPDEST
+//	    #bytes 0xfe00           ;; raw data
+//
+// Labels defined in a macro are local to each expansion. The pushes of labels
+// are sized to fit the label's position.
 package asm
 
 import (
 	"encoding/hex"
 	"fmt"
+	"math/big"
+	"strings"
 
 	"github.com/ethereum/go-ethereum/core/vm"
 )
@@ -134,3 +156,51 @@ func Disassemble(script []byte) ([]string, error) {
 	}
 	return instrs, nil
 }
+
+// DisassembleSource returns assembler source which compiles back to the given
+// code. JUMPDESTs are turned into labels, which are referenced by the pushes
+// of jump destinations. Pushes keep their explicit sizes, and bytes not making
+// up valid instructions are emitted as data.
+func DisassembleSource(code []byte) string {
+	type instr struct {
+		pc  uint64
+		op  vm.OpCode
+		arg []byte
+	}
+	var (
+		instrs []instr
+		dests  = make(map[uint64]bool)
+		it     = NewInstructionIterator(code)
+	)
+	for it.Next() {
+		instrs = append(instrs, instr{it.PC(), it.Op(), it.Arg()})
+		if it.Op() == vm.JUMPDEST {
+			dests[it.PC()] = true
+		}
+	}
+	var out strings.Builder
+	for i, in := range instrs {
+		switch {
+		case in.op == vm.JUMPDEST:
+			fmt.Fprintf(&out, "label_%04x:\n", in.pc)
+		case in.op.IsPush() && in.op != vm.PUSH0:
+			dest := new(big.Int).SetBytes(in.arg)
+			isJump := i+1 < len(instrs) && (instrs[i+1].op == vm.JUMP || instrs[i+1].op == vm.JUMPI)
+			if isJump && dest.IsUint64() && dests[dest.Uint64()] {
+				fmt.Fprintf(&out, "\t%v @label_%04x\n", in.op, dest.Uint64())
+			} else {
+				fmt.Fprintf(&out, "\t%v %#x\n", in.op, in.arg)
+			}
+		case toBinary(in.op.String()) != in.op:
+			// Undefined opcode
+			fmt.Fprintf(&out, "\t#bytes %#x\n", []byte{byte(in.op)})
+		default:
+			fmt.Fprintf(&out, "\t%v\n", in.op)
+		}
+	}
+	if it.Error() != nil {
+		// Truncated push at the end of the code
+		fmt.Fprintf(&out, "\t#bytes %#x\n", code[it.PC():])
+	}
+	return out.String()
+}
@@ -17,6 +17,8 @@
 package asm
 
 import (
+	"bytes"
+	"math/rand"
 	"testing"
 
 	"encoding/hex"
@@ -56,3 +58,40 @@ func TestInstructionIterator(t *testing.T) {
 		}
 	}
 }
+
+func TestDisassembleSource(t *testing.T) {
+	code, _ := hex.DecodeString("6003565b5f6100015c00ef61")
+	want := `	PUSH1 @label_0003
+	JUMP
+label_0003:
+	PUSH0
+	PUSH2 0x0001
+	TLOAD
+	STOP
+	#bytes 0xef
+	#bytes 0x61
+`
+	if have := DisassembleSource(code); have != want {
+		t.Errorf("have:\n%s\nwant:\n%s", have, want)
+	}
+}
+
+// Tests that disassembled source compiles back to the original code.
+func TestDisassembleRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		code := make([]byte, rng.Intn(512))
+		rng.Read(code)
+
+		src := DisassembleSource(code)
+		c := NewCompiler(false)
+		c.Feed(Lex([]byte(src), false))
+		out, errs := c.Compile()
+		if len(errs) != 0 {
+			t.Fatalf("code %x: compile errors %v\nsource:\n%s", code, errs, src)
+		}
+		if have, _ := hex.DecodeString(out); !bytes.Equal(have, code) {
+			t.Fatalf("round trip mismatch\nhave: %x\nwant: %x\nsource:\n%s", have, code, src)
+		}
+	}
+}
@@ -18,25 +18,38 @@ package asm
 
 import (
 	"encoding/hex"
-	"errors"
 	"fmt"
 	"math/big"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
 )
 
+// maxMacroDepth is the maximum nesting depth of macro expansions, which
+// protects against recursive macros.
+const maxMacroDepth = 64
+
 // Compiler contains information about the parsed source
 // and holds the tokens for the program.
 type Compiler struct {
 	tokens []token
 	out    []byte
+	errors []error
 
-	labels map[string]int
+	file   string         // name of the fed source, used to resolve includes
+	jt     *vm.JumpTable  // instruction set of the target fork, nil for all
+	instrs []*instruction // instructions produced by the first pass
 
-	pc, pos int
+	labels    map[string]int
+	consts    map[string]token
+	macros    map[string]*macro
+	including map[string]bool // files currently being included
+	expanded  int             // number of macro expansions so far
 
 	debug bool
 }
@@ -44,196 +57,627 @@ type Compiler struct {
 // NewCompiler returns a new allocated compiler.
 func NewCompiler(debug bool) *Compiler {
 	return &Compiler{
-		labels: make(map[string]int),
-		debug:  debug,
+		labels:    make(map[string]int),
+		consts:    make(map[string]token),
+		macros:    make(map[string]*macro),
+		including: make(map[string]bool),
+		debug:     debug,
+	}
+}
+
+// SetFile sets the name of the source file being compiled. It is used in
+// error messages and to resolve the paths of included files.
+func (c *Compiler) SetFile(name string) {
+	c.file = name
+}
+
+// SetRules restricts the compiler to the instructions available under the
+// given chain rules. Without rules, all known instructions are accepted.
+func (c *Compiler) SetRules(rules params.Rules) error {
+	jt, err := vm.LookupInstructionSet(rules)
+	if err != nil {
+		return err
 	}
+	c.jt = &jt
+	return nil
 }
 
 // Feed feeds tokens into ch and are interpreted by
 // the compiler.
-//
-// feed is the first pass in the compile stage as it collects the used labels in the
-// program and keeps a program counter which is used to determine the locations of the
-// jump dests. The labels can than be used in the second stage to push labels and
-// determine the right position.
 func (c *Compiler) Feed(ch <-chan token) {
-	var prev token
 	for i := range ch {
-		switch i.typ {
-		case number:
-			num := math.MustParseBig256(i.text).Bytes()
-			if len(num) == 0 {
-				num = []byte{0}
-			}
-			c.pc += len(num)
-		case stringValue:
-			c.pc += len(i.text) - 2
-		case element:
-			c.pc++
-		case labelDef:
-			c.labels[i.text] = c.pc
-			c.pc++
-		case label:
-			c.pc += 4
-			if prev.typ == element && isJump(prev.text) {
-				c.pc++
-			}
-		}
 		c.tokens = append(c.tokens, i)
-		prev = i
-	}
-	if c.debug {
-		fmt.Fprintln(os.Stderr, "found", len(c.labels), "labels")
 	}
 }
 
 // Compile compiles the current tokens and returns a binary string that can be interpreted
 // by the EVM and an error if it failed.
 //
-// compile is the second stage in the compile phase which compiles the tokens to EVM
-// instructions.
+// compile works in two passes. The first pass expands includes and macros,
+// substitutes constants and turns the lines into instructions. The second pass
+// lays out the instructions, sizing the pushes of labels, and emits the code.
 func (c *Compiler) Compile() (string, []error) {
-	var errors []error
-	// continue looping over the tokens until
-	// the stack has been exhausted.
-	for c.pos < len(c.tokens) {
-		if err := c.compileLine(); err != nil {
-			errors = append(errors, err)
+	c.including[c.file] = true
+	c.compileLines(splitLines(c.tokens), &scope{file: c.file})
+	delete(c.including, c.file)
+
+	if c.debug {
+		fmt.Fprintln(os.Stderr, "found", len(c.labels), "labels")
+	}
+	for _, in := range c.instrs {
+		if in.kind == pushInstr && in.label != "" {
+			if _, ok := c.labels[in.label]; !ok {
+				c.errors = append(c.errors, in.errorf("undefined label %q", in.tok.text))
+			}
 		}
 	}
-
+	if len(c.errors) == 0 {
+		c.layout()
+		c.emit()
+	}
 	// turn the binary to hex
 	h := hex.EncodeToString(c.out)
-	return h, errors
+	return h, c.errors
+}
+
+// line is a single line of source, without the line delimiting tokens.
+type line struct {
+	lineno int
+	tokens []token
+}
+
+// splitLines groups a token stream by lines, dropping empty lines.
+func splitLines(tokens []token) []line {
+	var (
+		lines []line
+		cur   line
+	)
+	for _, tok := range tokens {
+		switch tok.typ {
+		case lineStart:
+			cur = line{lineno: tok.lineno}
+		case lineEnd, eof:
+			if len(cur.tokens) > 0 {
+				lines = append(lines, cur)
+			}
+			cur = line{}
+		default:
+			cur.tokens = append(cur.tokens, tok)
+		}
+	}
+	return lines
+}
+
+// macro is a macro definition.
+type macro struct {
+	name   string
+	params []string
+	body   []line
+	file   string
+}
+
+// scope is the context lines are compiled in: the file they originate from
+// and, within a macro expansion, the bound arguments and local labels.
+type scope struct {
+	file   string
+	args   map[string]token  // macro parameter -> argument
+	labels map[string]string // macro-local label -> unique label
+	depth  int
+}
+
+// resolve substitutes macro arguments and constants. It reports whether the
+// token was substituted, in which case it has been resolved in the scope of
+// the substituted value already.
+func (s *scope) resolve(tok token, consts map[string]token) (token, bool) {
+	if tok.typ != element {
+		return tok, false
+	}
+	val, ok := s.args[tok.text]
+	if !ok {
+		val, ok = consts[tok.text]
+	}
+	if !ok {
+		return tok, false
+	}
+	val.lineno = tok.lineno
+	return val, true
+}
+
+// label returns the unique name of a label referenced in the scope.
+func (s *scope) label(name string) string {
+	if unique, ok := s.labels[name]; ok {
+		return unique
+	}
+	return name
+}
+
+// errorf creates a positioned error.
+func (s *scope) errorf(tok token, format string, args ...interface{}) error {
+	return compileError{msg: fmt.Sprintf(format, args...), file: s.file, lineno: tok.lineno + 1}
 }
 
-// next returns the next token and increments the
-// position.
-func (c *Compiler) next() token {
-	token := c.tokens[c.pos]
-	c.pos++
-	return token
+// syntaxErr creates a positioned syntax error.
+func (s *scope) syntaxErr(tok token, got, want string) error {
+	err := compileErr(tok, got, want).(compileError)
+	err.file = s.file
+	return err
+}
+
+// compileLines compiles a sequence of lines, collecting macro definitions.
+func (c *Compiler) compileLines(lines []line, s *scope) {
+	for i := 0; i < len(lines); i++ {
+		if isDirective(lines[i], "#macro") {
+			end := i + 1
+			for end < len(lines) && !isDirective(lines[end], "#end") {
+				end++
+			}
+			if end == len(lines) {
+				c.errors = append(c.errors, s.errorf(lines[i].tokens[0], "unterminated macro definition"))
+				return
+			}
+			if err := c.defineMacro(lines[i], lines[i+1:end], s); err != nil {
+				c.errors = append(c.errors, err)
+			}
+			i = end
+			continue
+		}
+		if err := c.compileLine(lines[i], s); err != nil {
+			c.errors = append(c.errors, err)
+		}
+	}
+}
+
+func isDirective(l line, name string) bool {
+	return l.tokens[0].typ == directive && strings.EqualFold(l.tokens[0].text, name)
 }
 
 // compileLine compiles a single line instruction e.g.
 // "push 1", "jump @label".
-func (c *Compiler) compileLine() error {
-	n := c.next()
-	if n.typ != lineStart {
-		return compileErr(n, n.typ.String(), lineStart.String())
+func (c *Compiler) compileLine(l line, s *scope) error {
+	first := l.tokens[0]
+	if arg, ok := s.args[first.text]; ok && first.typ == element && arg.typ == element {
+		// Macro arguments may name instructions.
+		first = arg
 	}
-
-	lvalue := c.next()
-	switch lvalue.typ {
-	case eof:
+	switch first.typ {
+	case labelDef:
+		if len(l.tokens) > 1 {
+			return s.syntaxErr(l.tokens[1], l.tokens[1].text, lineEnd.String())
+		}
+		name := s.label(first.text)
+		if _, ok := c.labels[name]; ok {
+			return s.errorf(first, "label %q already defined", first.text)
+		}
+		c.labels[name] = 0
+		c.instrs = append(c.instrs, &instruction{kind: labelInstr, label: name})
 		return nil
+	case directive:
+		return c.compileDirective(l, s)
 	case element:
-		if err := c.compileElement(lvalue); err != nil {
-			return err
+		if m, ok := c.macros[first.text]; ok {
+			return c.expandMacro(m, l, s)
 		}
-	case labelDef:
-		c.compileLabel()
-	case lineEnd:
-		return nil
+		return c.compileInstruction(first, l.tokens[1:], s)
 	default:
-		return compileErr(lvalue, lvalue.text, fmt.Sprintf("%v or %v", labelDef, element))
+		return s.syntaxErr(first, first.text, fmt.Sprintf("%v or %v", labelDef, element))
 	}
+}
 
-	if n := c.next(); n.typ != lineEnd {
-		return compileErr(n, n.text, lineEnd.String())
-	}
+// compileInstruction compiles an opcode and its operands.
+func (c *Compiler) compileInstruction(element token, operands []token, s *scope) error {
+	name := strings.ToUpper(element.text)
+	switch {
+	case isPush(name):
+		size := pushSize(name)
+		if len(operands) == 0 {
+			return s.errorf(element, "missing value of %s", name)
+		}
+		if len(operands) > 1 {
+			return s.syntaxErr(operands[1], operands[1].text, lineEnd.String())
+		}
+		return c.compilePush(size, operands[0], s)
 
+	case isJump(name):
+		if len(operands) > 1 {
+			return s.syntaxErr(operands[1], operands[1].text, lineEnd.String())
+		}
+		// jump without argument is supported, it just takes the destination from the stack.
+		if len(operands) == 1 {
+			if err := c.compilePush(-1, operands[0], s); err != nil {
+				return err
+			}
+		}
+	}
+	op := toBinary(name)
+	if op == vm.STOP && name != "STOP" {
+		return s.errorf(element, "unknown instruction %s", element.text)
+	}
+	if !c.available(op) {
+		return s.errorf(element, "instruction %v not available in the selected fork", op)
+	}
+	if !isJump(name) && len(operands) > 0 {
+		return s.syntaxErr(operands[0], operands[0].text, lineEnd.String())
+	}
+	c.instrs = append(c.instrs, &instruction{kind: opInstr, op: op})
 	return nil
 }
 
-// parseNumber compiles the number to bytes
-func parseNumber(tok token) ([]byte, error) {
-	if tok.typ != number {
-		panic("parseNumber of non-number token")
+// compilePush compiles a push of a number, string or label. A negative size
+// selects the smallest push fitting the value.
+func (c *Compiler) compilePush(size int, operand token, s *scope) error {
+	operand, resolved := s.resolve(operand, c.consts)
+
+	in := &instruction{kind: pushInstr, size: size, auto: size < 0, tok: operand, file: s.file}
+	switch operand.typ {
+	case number:
+		num, ok := math.ParseBig256(operand.text)
+		if !ok {
+			return s.errorf(operand, "invalid number %s", operand.text)
+		}
+		in.value = num.Bytes()
+	case stringValue:
+		// strings are quoted, remove them.
+		in.value = []byte(operand.text[1 : len(operand.text)-1])
+	case label:
+		in.label = operand.text
+		if !resolved {
+			in.label = s.label(operand.text)
+		}
+	case element:
+		return s.errorf(operand, "undefined constant %s", operand.text)
+	default:
+		return s.syntaxErr(operand, operand.text, "number, string or label")
 	}
-	num, ok := math.ParseBig256(tok.text)
-	if !ok {
-		return nil, errors.New("invalid number")
+	if len(in.value) > 32 {
+		return s.errorf(operand, "string or number size > 32 bytes")
 	}
-	bytes := num.Bytes()
-	if len(bytes) == 0 {
-		bytes = []byte{0}
+	if in.label == "" {
+		if in.auto {
+			in.size = c.minPushSize(len(in.value))
+		} else if len(in.value) > in.size {
+			return s.errorf(operand, "value %s exceeds PUSH%d", operand.text, in.size)
+		}
+	} else if in.auto {
+		in.size = c.minPushSize(0)
+	}
+	if in.size == 0 && !c.available(vm.PUSH0) {
+		return s.errorf(operand, "instruction PUSH0 not available in the selected fork")
 	}
-	return bytes, nil
+	c.instrs = append(c.instrs, in)
+	return nil
 }
 
-// compileElement compiles the element (push & label or both)
-// to a binary representation and may error if incorrect statements
-// where fed.
-func (c *Compiler) compileElement(element token) error {
-	switch {
-	case isJump(element.text):
-		return c.compileJump(element.text)
-	case isPush(element.text):
-		return c.compilePush()
-	default:
-		c.outputOpcode(toBinary(element.text))
+// compileDirective compiles the #define, #include and #bytes directives.
+func (c *Compiler) compileDirective(l line, s *scope) error {
+	dir, args := l.tokens[0], l.tokens[1:]
+	switch strings.ToLower(dir.text) {
+	case "#define":
+		if len(args) != 2 || args[0].typ != element {
+			return s.errorf(dir, "usage: #define NAME value")
+		}
+		name := args[0].text
+		if _, ok := c.consts[name]; ok {
+			return s.errorf(args[0], "constant %s already defined", name)
+		}
+		val, resolved := s.resolve(args[1], c.consts)
+		switch val.typ {
+		case number, stringValue:
+		case label:
+			if !resolved {
+				val.text = s.label(val.text)
+			}
+		case element:
+			return s.errorf(val, "undefined constant %s", val.text)
+		default:
+			return s.syntaxErr(val, val.text, "number, string or label")
+		}
+		c.consts[name] = val
+		return nil
+
+	case "#include":
+		if len(args) != 1 || args[0].typ != stringValue {
+			return s.errorf(dir, "usage: #include \"file\"")
+		}
+		return c.include(args[0], s)
+
+	case "#bytes":
+		if len(args) == 0 {
+			return s.errorf(dir, "usage: #bytes value...")
+		}
+		var data []byte
+		for _, arg := range args {
+			arg, _ = s.resolve(arg, c.consts)
+			switch arg.typ {
+			case number:
+				b, err := parseData(arg.text)
+				if err != nil {
+					return s.errorf(arg, "invalid number %s", arg.text)
+				}
+				data = append(data, b...)
+			case stringValue:
+				data = append(data, arg.text[1:len(arg.text)-1]...)
+			default:
+				return s.syntaxErr(arg, arg.text, "number or string")
+			}
+		}
+		c.instrs = append(c.instrs, &instruction{kind: dataInstr, value: data})
 		return nil
+
+	case "#macro":
+		return s.errorf(dir, "nested macro definition")
+	case "#end":
+		return s.errorf(dir, "#end without #macro")
+	default:
+		return s.errorf(dir, "unknown directive %s", dir.text)
 	}
 }
 
-func (c *Compiler) compileJump(jumpType string) error {
-	rvalue := c.next()
-	switch rvalue.typ {
-	case number:
-		numBytes, err := parseNumber(rvalue)
+// include compiles the file referenced by an #include directive. Relative
+// paths are resolved against the directory of the including file.
+func (c *Compiler) include(path token, s *scope) error {
+	name := path.text[1 : len(path.text)-1]
+	if !filepath.IsAbs(name) {
+		name = filepath.Join(filepath.Dir(s.file), name)
+	}
+	if c.including[name] {
+		return s.errorf(path, "include cycle: %s", name)
+	}
+	src, err := os.ReadFile(name)
+	if err != nil {
+		return s.errorf(path, "include failed: %v", err)
+	}
+	var tokens []token
+	for tok := range Lex(src, c.debug) {
+		tokens = append(tokens, tok)
+	}
+	c.including[name] = true
+	c.compileLines(splitLines(tokens), &scope{file: name})
+	delete(c.including, name)
+	return nil
+}
+
+// defineMacro parses the header of a macro definition, e.g.
+// "#macro name(a, b)", and registers the macro.
+func (c *Compiler) defineMacro(header line, body []line, s *scope) error {
+	tokens := header.tokens[1:]
+	if len(tokens) == 0 || tokens[0].typ != element {
+		return s.errorf(header.tokens[0], "usage: #macro name(params...)")
+	}
+	name := tokens[0]
+	if upper := strings.ToUpper(name.text); isPush(upper) || toBinary(upper) != vm.STOP || upper == "STOP" {
+		return s.errorf(name, "macro name %s is an instruction", name.text)
+	}
+	if _, ok := c.macros[name.text]; ok {
+		return s.errorf(name, "macro %s already defined", name.text)
+	}
+	m := &macro{name: name.text, body: body, file: s.file}
+	if len(tokens) > 1 {
+		params, rest, err := parseList(tokens[1:], s)
 		if err != nil {
 			return err
 		}
-		c.outputBytes(numBytes)
+		if len(rest) > 0 {
+			return s.syntaxErr(rest[0], rest[0].text, lineEnd.String())
+		}
+		seen := make(map[string]bool)
+		for _, param := range params {
+			if param.typ != element {
+				return s.syntaxErr(param, param.text, "parameter name")
+			}
+			if seen[param.text] {
+				return s.errorf(param, "duplicate parameter %s", param.text)
+			}
+			seen[param.text] = true
+			m.params = append(m.params, param.text)
+		}
+	}
+	c.macros[m.name] = m
+	return nil
+}
 
-	case stringValue:
-		// strings are quoted, remove them.
-		str := rvalue.text[1 : len(rvalue.text)-2]
-		c.outputBytes([]byte(str))
+// expandMacro compiles the body of a macro for an invocation, e.g.
+// "name(1, @label)". Labels defined in the body are renamed so that each
+// expansion has its own copy.
+func (c *Compiler) expandMacro(m *macro, l line, s *scope) error {
+	var (
+		call = l.tokens[0]
+		args []token
+		rest = l.tokens[1:]
+		err  error
+	)
+	if len(rest) > 0 {
+		if args, rest, err = parseList(rest, s); err != nil {
+			return err
+		}
+	}
+	if len(rest) > 0 {
+		return s.syntaxErr(rest[0], rest[0].text, lineEnd.String())
+	}
+	if len(args) != len(m.params) {
+		return s.errorf(call, "macro %s expects %d arguments, got %d", m.name, len(m.params), len(args))
+	}
+	if s.depth >= maxMacroDepth {
+		return s.errorf(call, "macro expansion of %s exceeds depth %d", m.name, maxMacroDepth)
+	}
+	c.expanded++
+	inner := &scope{
+		file:   m.file,
+		args:   make(map[string]token),
+		labels: make(map[string]string),
+		depth:  s.depth + 1,
+	}
+	for i, param := range m.params {
+		arg, resolved := s.resolve(args[i], c.consts)
+		if arg.typ == label && !resolved {
+			arg.text = s.label(arg.text)
+		}
+		inner.args[param] = arg
+	}
+	for _, l := range m.body {
+		if l.tokens[0].typ == labelDef {
+			name := l.tokens[0].text
+			inner.labels[name] = fmt.Sprintf("%s.%d.%s", m.name, c.expanded, name)
+		}
+	}
+	c.compileLines(m.body, inner)
+	return nil
+}
 
-	case label:
-		c.outputOpcode(vm.PUSH4)
-		pos := big.NewInt(int64(c.labels[rvalue.text])).Bytes()
-		pos = append(make([]byte, 4-len(pos)), pos...)
-		c.outputBytes(pos)
+// parseList parses a parenthesized, comma separated list of single tokens.
+func parseList(tokens []token, s *scope) (items, rest []token, err error) {
+	if tokens[0].typ != openParen {
+		return nil, nil, s.syntaxErr(tokens[0], tokens[0].text, openParen.String())
+	}
+	for i := 1; i < len(tokens); i++ {
+		if tokens[i].typ == closeParen && len(items) == 0 {
+			return items, tokens[i+1:], nil
+		}
+		items = append(items, tokens[i])
+		if i+1 == len(tokens) {
+			break
+		}
+		switch next := tokens[i+1]; next.typ {
+		case comma:
+			i++
+		case closeParen:
+			return items, tokens[i+2:], nil
+		default:
+			return nil, nil, s.syntaxErr(next, next.text, fmt.Sprintf("%v or %v", comma, closeParen))
+		}
+	}
+	last := tokens[len(tokens)-1]
+	return nil, nil, s.syntaxErr(last, last.text, closeParen.String())
+}
+
+// parseData parses the number operand of #bytes. Hexadecimal numbers keep
+// their leading zeros.
+func parseData(text string) ([]byte, error) {
+	if strings.HasPrefix(text, "0x") || strings.HasPrefix(text, "0X") {
+		digits := text[2:]
+		if len(digits)%2 == 1 {
+			digits = "0" + digits
+		}
+		return hex.DecodeString(digits)
+	}
+	num, ok := new(big.Int).SetString(text, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid number %s", text)
+	}
+	if num.Sign() == 0 {
+		return []byte{0}, nil
+	}
+	return num.Bytes(), nil
+}
 
-	case lineEnd:
-		// push without argument is supported, it just takes the destination from the stack.
-		c.pos--
+// available returns whether an opcode is part of the target instruction set.
+func (c *Compiler) available(op vm.OpCode) bool {
+	if c.jt == nil || op == vm.STOP || op == vm.INVALID {
+		return true
+	}
+	return c.jt[op].HasCost()
+}
+
+// minPushSize returns the immediate size of the smallest push of a value with
+// the given length, using PUSH0 for zero if available.
+func (c *Compiler) minPushSize(n int) int {
+	if n == 0 && !c.available(vm.PUSH0) {
+		return 1
+	}
+	return n
+}
+
+type instrKind int
 
+const (
+	opInstr    instrKind = iota // an opcode
+	pushInstr                   // a push of a value or label
+	labelInstr                  // a label definition, i.e. a JUMPDEST
+	dataInstr                   // raw bytes
+)
+
+// instruction is a compiled instruction, before layout.
+type instruction struct {
+	kind  instrKind
+	op    vm.OpCode
+	size  int    // immediate size of a push
+	auto  bool   // whether the push of a label is sized automatically
+	value []byte // push value or raw bytes
+	label string // pushed or defined label
+
+	tok  token  // operand of a push, for error reporting
+	file string // file of the push, for error reporting
+}
+
+func (in *instruction) len() int {
+	switch in.kind {
+	case pushInstr:
+		return 1 + in.size
+	case dataInstr:
+		return len(in.value)
 	default:
-		return compileErr(rvalue, rvalue.text, "number, string or label")
+		return 1
 	}
-	// push the operation
-	c.outputOpcode(toBinary(jumpType))
-	return nil
 }
 
-func (c *Compiler) compilePush() error {
-	// handle pushes. pushes are read from left to right.
-	var value []byte
-	rvalue := c.next()
-	switch rvalue.typ {
-	case number:
-		value = math.MustParseBig256(rvalue.text).Bytes()
-		if len(value) == 0 {
-			value = []byte{0}
+func (in *instruction) errorf(format string, args ...interface{}) error {
+	return (&scope{file: in.file}).errorf(in.tok, format, args...)
+}
+
+// layout assigns the positions of labels. The pushes of labels start out
+// minimal and are grown until every label fits; since sizes only grow, this
+// terminates.
+func (c *Compiler) layout() {
+	for {
+		pc := 0
+		for _, in := range c.instrs {
+			if in.kind == labelInstr {
+				c.labels[in.label] = pc
+			}
+			pc += in.len()
+		}
+		changed := false
+		for _, in := range c.instrs {
+			if in.kind != pushInstr || in.label == "" || !in.auto {
+				continue
+			}
+			pos := big.NewInt(int64(c.labels[in.label])).Bytes()
+			if size := c.minPushSize(len(pos)); size > in.size {
+				in.size = size
+				changed = true
+			}
+		}
+		if !changed {
+			return
 		}
-	case stringValue:
-		value = []byte(rvalue.text[1 : len(rvalue.text)-1])
-	case label:
-		value = big.NewInt(int64(c.labels[rvalue.text])).Bytes()
-		value = append(make([]byte, 4-len(value)), value...)
-	default:
-		return compileErr(rvalue, rvalue.text, "number, string or label")
 	}
-	if len(value) > 32 {
-		return fmt.Errorf("%d: string or number size > 32 bytes", rvalue.lineno+1)
+}
+
+// emit writes the laid out instructions.
+func (c *Compiler) emit() {
+	for _, in := range c.instrs {
+		switch in.kind {
+		case opInstr:
+			c.outputOpcode(in.op)
+		case labelInstr:
+			c.compileLabel()
+		case dataInstr:
+			c.outputBytes(in.value)
+		case pushInstr:
+			value := in.value
+			if in.label != "" {
+				value = big.NewInt(int64(c.labels[in.label])).Bytes()
+				if len(value) > in.size {
+					c.errors = append(c.errors, in.errorf("label %s exceeds PUSH%d", in.tok.text, in.size))
+				}
+			}
+			if in.size == 0 {
+				c.outputOpcode(vm.PUSH0)
+				continue
+			}
+			c.outputOpcode(vm.PUSH1 - 1 + vm.OpCode(in.size))
+			c.outputBytes(append(make([]byte, in.size-len(value)), value...))
+		}
 	}
-	c.outputOpcode(vm.OpCode(int(vm.PUSH1) - 1 + len(value)))
-	c.outputBytes(value)
-	return nil
 }
 
 // compileLabel pushes a jumpdest to the binary slice.
@@ -241,6 +685,7 @@ func (c *Compiler) compileLabel() {
 	c.outputOpcode(vm.JUMPDEST)
 }
 
+// outputOpcode pushes the opcode op to the binary stack.
 func (c *Compiler) outputOpcode(op vm.OpCode) {
 	if c.debug {
 		fmt.Printf("%d: %v\n", len(c.out), op)
@@ -248,7 +693,7 @@ func (c *Compiler) outputOpcode(op vm.OpCode) {
 	c.out = append(c.out, byte(op))
 }
 
-// output pushes the value v to the binary stack.
+// outputBytes pushes the value b to the binary stack.
 func (c *Compiler) outputBytes(b []byte) {
 	if c.debug {
 		fmt.Printf("%d: %x\n", len(c.out), b)
@@ -257,9 +702,25 @@ func (c *Compiler) outputBytes(b []byte) {
 }
 
 // isPush returns whether the string op is either any of
-// push(N).
+// push(N) or an automatically sized push.
 func isPush(op string) bool {
-	return strings.EqualFold(op, "PUSH")
+	return strings.EqualFold(op, "PUSH") || pushSize(op) > 0
+}
+
+// pushSize returns the immediate size of a PUSH1 to PUSH32 instruction, -1
+// for a plain PUSH and 0 for anything else.
+func pushSize(op string) int {
+	if strings.EqualFold(op, "PUSH") {
+		return -1
+	}
+	if len(op) < 5 || !strings.EqualFold(op[:4], "PUSH") {
+		return 0
+	}
+	n, err := strconv.Atoi(op[4:])
+	if err != nil || n < 1 || n > 32 {
+		return 0
+	}
+	return n
 }
 
 // isJump returns whether the string op is jump(i)
@@ -275,12 +736,21 @@ func toBinary(text string) vm.OpCode {
 type compileError struct {
 	got  string
 	want string
+	msg  string
 
+	file   string
 	lineno int
 }
 
 func (err compileError) Error() string {
-	return fmt.Sprintf("%d: syntax error: unexpected %v, expected %v", err.lineno, err.got, err.want)
+	pos := strconv.Itoa(err.lineno)
+	if err.file != "" {
+		pos = err.file + ":" + pos
+	}
+	if err.msg != "" {
+		return fmt.Sprintf("%s: %s", pos, err.msg)
+	}
+	return fmt.Sprintf("%s: syntax error: unexpected %v, expected %v", pos, err.got, err.want)
 }
 
 func compileErr(c token, got, want string) error {
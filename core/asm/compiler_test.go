@@ -17,7 +17,12 @@
 package asm
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/ethereum/go-ethereum/params"
 )
 
 func TestCompiler(t *testing.T) {
@@ -30,14 +35,14 @@ func TestCompiler(t *testing.T) {
 	label:
 	PUSH @label
 `,
-			output: "5a5b6300000001",
+			output: "5a5b6001",
 		},
 		{
 			input: `
 	PUSH @label
 	label:
 `,
-			output: "63000000055b",
+			output: "60025b",
 		},
 		{
 			input: `
@@ -45,14 +50,14 @@ func TestCompiler(t *testing.T) {
 	JUMP
 	label:
 `,
-			output: "6300000006565b",
+			output: "6003565b",
 		},
 		{
 			input: `
 	JUMP @label
 	label:
 `,
-			output: "6300000006565b",
+			output: "6003565b",
 		},
 		{
 			input: `
@@ -60,7 +65,7 @@ func TestCompiler(t *testing.T) {
 label: ;; comment
 	ADD ;; comment
 `,
-			output: "6300000006565b01",
+			output: "6003565b01",
 		},
 	}
 	for _, test := range tests {
@@ -77,3 +82,194 @@ label: ;; comment
 		}
 	}
 }
+
+func compile(t *testing.T, file, src string, rules *params.Rules) (string, []error) {
+	t.Helper()
+	c := NewCompiler(false)
+	c.SetFile(file)
+	if rules != nil {
+		if err := c.SetRules(*rules); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c.Feed(Lex([]byte(src), false))
+	return c.Compile()
+}
+
+func TestCompilerFeatures(t *testing.T) {
+	tests := []struct {
+		name, input, output string
+	}{
+		{
+			name: "constants",
+			input: `
+#define SLOT 0x01
+#define GREETING "hi"
+#define TARGET @end
+	push SLOT
+	push GREETING
+	jump TARGET
+end:
+`,
+			output: "6001616869" + "6008565b",
+		},
+		{
+			name: "push sizing",
+			input: `
+	push 0
+	push 0x0100
+	push2 1
+	push32 0
+	push0
+`,
+			output: "5f" + "610100" + "610001" + "7f" + strings.Repeat("00", 32) + "5f",
+		},
+		{
+			name: "macros",
+			input: `
+#macro store(slot, value)
+	push value
+	push slot
+	sstore
+#end
+#macro loop()
+start:
+	jump @start
+#end
+	store(1, 2)
+	loop()
+	loop
+`,
+			output: "60026001" + "55" + "5b600556" + "5b600956",
+		},
+		{
+			name: "nested macros with instruction and label arguments",
+			input: `
+#macro binop(op, a, b)
+	push b
+	push a
+	op
+#end
+#macro jumpto(dest)
+	binop(add, 1, 2)
+	jump dest
+#end
+	jumpto(@dest)
+dest:
+`,
+			output: "60026001" + "01" + "6008565b",
+		},
+		{
+			name: "raw bytes",
+			input: `
+	#bytes 0x00fe "ab" 1
+`,
+			output: "00fe616201",
+		},
+		{
+			name: "large label offsets",
+			input: `
+	jump @end
+	#bytes 0x` + strings.Repeat("00", 300) + `
+end:
+`,
+			output: "61013056" + strings.Repeat("00", 300) + "5b",
+		},
+	}
+	for _, test := range tests {
+		output, errs := compile(t, "", test.input, nil)
+		if len(errs) != 0 {
+			t.Errorf("%s: compile error: %v", test.name, errs)
+			continue
+		}
+		if output != test.output {
+			t.Errorf("%s: incorrect output\ngot:  %s\nwant: %s", test.name, output, test.output)
+		}
+	}
+}
+
+func TestCompilerForks(t *testing.T) {
+	var (
+		london   = params.Rules{IsHomestead: true, IsEIP150: true, IsEIP155: true, IsEIP158: true, IsByzantium: true, IsConstantinople: true, IsPetersburg: true, IsIstanbul: true, IsBerlin: true, IsLondon: true}
+		shanghai = london
+		cancun   = london
+	)
+	shanghai.IsMerge, shanghai.IsShanghai = true, true
+	cancun.IsMerge, cancun.IsShanghai, cancun.IsCancun = true, true, true
+
+	// PUSH0 is used for zero from Shanghai on
+	if out, errs := compile(t, "", "push 0", &london); len(errs) != 0 || out != "6000" {
+		t.Errorf("london: have %s %v, want 6000", out, errs)
+	}
+	if out, errs := compile(t, "", "push 0", &shanghai); len(errs) != 0 || out != "5f" {
+		t.Errorf("shanghai: have %s %v, want 5f", out, errs)
+	}
+	if _, errs := compile(t, "", "push0", &london); len(errs) != 1 {
+		t.Errorf("london: expected PUSH0 to be rejected, have %v", errs)
+	}
+	// Transient storage is available from Cancun on
+	if _, errs := compile(t, "", "push 0\ntload", &shanghai); len(errs) != 1 || !strings.Contains(errs[0].Error(), "not available") {
+		t.Errorf("shanghai: expected TLOAD to be rejected, have %v", errs)
+	}
+	if out, errs := compile(t, "", "push 0\ntload", &cancun); len(errs) != 0 || out != "5f5c" {
+		t.Errorf("cancun: have %s %v, want 5f5c", out, errs)
+	}
+}
+
+func TestCompilerInclude(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "lib"), 0755)
+	os.WriteFile(filepath.Join(dir, "lib", "math.easm"), []byte(`
+#include "consts.easm"
+#macro double(x)
+	push x
+	dup1
+	add
+#end
+`), 0644)
+	os.WriteFile(filepath.Join(dir, "lib", "consts.easm"), []byte("#define TWO 2\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "cycle.easm"), []byte(`#include "cycle.easm"`), 0644)
+
+	out, errs := compile(t, filepath.Join(dir, "main.easm"), "#include \"lib/math.easm\"\ndouble(TWO)\n", nil)
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+	if out != "60028001" {
+		t.Errorf("have %s, want 60028001", out)
+	}
+	_, errs = compile(t, filepath.Join(dir, "main.easm"), "#include \"cycle.easm\"\n", nil)
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "include cycle") {
+		t.Errorf("expected include cycle error, have %v", errs)
+	}
+}
+
+func TestCompilerErrors(t *testing.T) {
+	tests := []struct {
+		input, err string
+	}{
+		{"foo", "1: unknown instruction foo"},
+		{"push", "1: missing value of PUSH"},
+		{"push1 0x0100", "1: value 0x0100 exceeds PUSH1"},
+		{"push FOO", "1: undefined constant FOO"},
+		{"jump @nowhere", "1: undefined label \"nowhere\""},
+		{"a:\na:", "2: label \"a\" already defined"},
+		{"#define A 1\n#define A 2", "2: constant A already defined"},
+		{"#macro m(a)\n#end\nm(1, 2)", "3: macro m expects 1 arguments, got 2"},
+		{"#macro m\nm\n#end\nm", "2: macro expansion of m exceeds depth 64"},
+		{"#macro add\n#end", "1: macro name add is an instruction"},
+		{"#macro m\npush 1", "1: unterminated macro definition"},
+		{"#end", "1: #end without #macro"},
+		{"#foo", "1: unknown directive #foo"},
+		{"add 1", "1: syntax error: unexpected 1, expected lineEnd"},
+	}
+	for _, test := range tests {
+		_, errs := compile(t, "", test.input, nil)
+		if len(errs) == 0 {
+			t.Errorf("input %q: expected error %q", test.input, test.err)
+			continue
+		}
+		if errs[0].Error() != test.err {
+			t.Errorf("input %q: have error %q, want %q", test.input, errs[0], test.err)
+		}
+	}
+}
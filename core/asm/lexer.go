@@ -54,6 +54,10 @@ const (
 	labelDef                          // label definition is emitted when a new label is found
 	number                            // number is emitted when a number is found
 	stringValue                       // stringValue is emitted when a string has been found
+	directive                         // directive is emitted when a #directive is found
+	openParen                         // emitted when an opening parenthesis is found
+	closeParen                        // emitted when a closing parenthesis is found
+	comma                             // emitted when a comma is found
 )
 
 const (
@@ -198,6 +202,14 @@ func lexLine(l *lexer) stateFn {
 			return lexLabel
 		case r == '"':
 			return lexInsideString
+		case r == '#':
+			return lexDirective
+		case r == '(':
+			l.emit(openParen)
+		case r == ')':
+			l.emit(closeParen)
+		case r == ',':
+			l.emit(comma)
 		default:
 			return nil
 		}
@@ -225,6 +237,16 @@ func lexLabel(l *lexer) stateFn {
 	return lexLine
 }
 
+// lexDirective parses a directive such as #define, including
+// the leading hash.
+func lexDirective(l *lexer) stateFn {
+	l.acceptRun(alpha)
+
+	l.emit(directive)
+
+	return lexLine
+}
+
 // lexInsideString lexes the inside of a string until
 // the state function finds the closing quote.
 // It returns the lex text state function.
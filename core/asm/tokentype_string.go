@@ -17,11 +17,15 @@ func _() {
 	_ = x[labelDef-6]
 	_ = x[number-7]
 	_ = x[stringValue-8]
+	_ = x[directive-9]
+	_ = x[openParen-10]
+	_ = x[closeParen-11]
+	_ = x[comma-12]
 }
 
-const _tokenType_name = "eoflineStartlineEndinvalidStatementelementlabellabelDefnumberstringValue"
+const _tokenType_name = "eoflineStartlineEndinvalidStatementelementlabellabelDefnumberstringValuedirectiveopenParencloseParencomma"
 
-var _tokenType_index = [...]uint8{0, 3, 12, 19, 35, 42, 47, 55, 61, 72}
+var _tokenType_index = [...]uint8{0, 3, 12, 19, 35, 42, 47, 55, 61, 72, 81, 90, 100, 105}
 
 func (i tokenType) String() string {
 	if i < 0 || i >= tokenType(len(_tokenType_index)-1) {
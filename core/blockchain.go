@@ -36,6 +36,7 @@ import (
 	"github.com/ethereum/go-ethereum/consensus/misc/eip4844"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/state/pruner"
 	"github.com/ethereum/go-ethereum/core/state/snapshot"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
@@ -143,6 +144,11 @@ type CacheConfig struct {
 	Preimages           bool          // Whether to store preimage of trie key to the disk
 	StateHistory        uint64        // Number of blocks from head whose state histories are reserved.
 	StateScheme         string        // Scheme used to store ethereum states and merkle tree nodes on top
+	ParallelWorkers     int           // Number of goroutines executing the transactions of a block, sequential if <= 1
+
+	OnlinePruning         bool          // Whether to prune the stale state in the background (hash scheme only)
+	OnlinePruningRate     int           // Maximum number of trie nodes deleted per second by the online pruning
+	OnlinePruningInterval time.Duration // Time between two consecutive online prunings
 
 	SnapshotNoBuild bool // Whether the background generation is allowed
 	SnapshotWait    bool // Wait for snapshot construction on startup. TODO(karalabe): This is a dirty hack for testing, nuke it
@@ -219,6 +225,7 @@ type BlockChain struct {
 	triedb        *triedb.Database                 // The database handler for maintaining trie nodes.
 	stateCache    state.Database                   // State database to reuse between imports (contains state cache)
 	txIndexer     *txIndexer                       // Transaction indexer, might be nil if not enabled
+	pruner        *pruner.OnlinePruner             // Online state pruner, might be nil if not enabled
 
 	hc            *HeaderChain
 	rmLogsFeed    event.Feed
@@ -308,7 +315,11 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, genesis *Genesis
 	bc.stateCache = state.NewDatabaseWithNodeDB(bc.db, bc.triedb)
 	bc.validator = NewBlockValidator(chainConfig, bc, engine)
 	bc.prefetcher = newStatePrefetcher(chainConfig, bc, engine)
-	bc.processor = NewStateProcessor(chainConfig, bc, engine)
+	if cacheConfig.ParallelWorkers > 1 {
+		bc.processor = NewParallelStateProcessor(chainConfig, bc, engine, cacheConfig.ParallelWorkers)
+	} else {
+		bc.processor = NewStateProcessor(chainConfig, bc, engine)
+	}
 
 	var err error
 	bc.hc, err = NewHeaderChain(db, chainConfig, engine, bc.insertStopped)
@@ -468,6 +479,21 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, genesis *Genesis
 	if txLookupLimit != nil {
 		bc.txIndexer = newTxIndexer(*txLookupLimit, bc)
 	}
+	// Start the online state pruning if it's enabled. It can't be used in archive
+	// mode, where all states are retained.
+	if bc.cacheConfig.OnlinePruning && !bc.cacheConfig.TrieDirtyDisabled {
+		config := pruner.OnlineConfig{
+			Rate:     bc.cacheConfig.OnlinePruningRate,
+			Interval: bc.cacheConfig.OnlinePruningInterval,
+		}
+		p, err := pruner.NewOnlinePruner(bc.db, bc.triedb, bc.snaps, config)
+		if err != nil {
+			log.Warn("Failed to enable online state pruning", "err", err)
+		} else {
+			bc.pruner = p
+			bc.pruner.Start()
+		}
+	}
 	return bc, nil
 }
 
@@ -960,6 +986,10 @@ func (bc *BlockChain) stopWithoutSaving() {
 	if bc.txIndexer != nil {
 		bc.txIndexer.close()
 	}
+	// Interrupt the online state pruning, it's resumed at the next start.
+	if bc.pruner != nil {
+		bc.pruner.Stop()
+	}
 	// Unsubscribe all subscriptions registered from blockchain.
 	bc.scope.Close()
 
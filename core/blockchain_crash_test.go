@@ -0,0 +1,281 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Tests that the node recovers to a consistent state after crashing at any write
+// operation during the import of a chain.
+
+package core
+
+import (
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/faultdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// crashTest is a chain import to crash at every write operation.
+type crashTest struct {
+	scheme    string       // State scheme of the chain
+	archive   bool         // Whether the state of every block is persisted
+	snapshots bool         // Whether the state snapshots are enabled
+	snap      bool         // Whether the blocks are imported as by snap sync
+	ops       faultdb.Op   // Kinds of write operations to crash at
+	mode      faultdb.Mode // Mode of the fault crashing the database
+	gspec     *Genesis     // Genesis of the chain to import
+	blocks    []*types.Block
+	receipts  []types.Receipts
+}
+
+// newCrashTest creates a crash test importing a chain of blocks transferring
+// funds to fresh accounts.
+func newCrashTest(scheme string, n int) *crashTest {
+	var (
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		gspec  = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  types.GenesisAlloc{addr: {Balance: big.NewInt(params.Ether)}},
+		}
+		signer = types.LatestSigner(gspec.Config)
+	)
+	_, blocks, receipts := GenerateChainWithGenesis(gspec, ethash.NewFaker(), n, func(i int, gen *BlockGen) {
+		to := common.BigToAddress(big.NewInt(int64(0x100 + i)))
+		tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(addr), to, big.NewInt(params.GWei), params.TxGas, gen.BaseFee(), nil), signer, key)
+		gen.AddTx(tx)
+	})
+	return &crashTest{
+		scheme:   scheme,
+		ops:      faultdb.OpAll,
+		mode:     faultdb.Crash,
+		gspec:    gspec,
+		blocks:   blocks,
+		receipts: receipts,
+	}
+}
+
+func (tt *crashTest) cacheConfig() *CacheConfig {
+	config := &CacheConfig{
+		TrieCleanLimit:    256,
+		TrieDirtyLimit:    256,
+		TrieDirtyDisabled: tt.archive,
+		TrieTimeLimit:     5 * time.Minute,
+		StateScheme:       tt.scheme,
+	}
+	if tt.snapshots {
+		config.SnapshotLimit = 256
+		config.SnapshotWait = true
+	}
+	return config
+}
+
+// open opens the persistent database in the given directory.
+func (tt *crashTest) open(t *testing.T, dir string) ethdb.Database {
+	t.Helper()
+
+	db, err := rawdb.Open(rawdb.OpenOptions{
+		Directory:         dir,
+		AncientsDirectory: filepath.Join(dir, "ancient"),
+		Ephemeral:         true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to open persistent database: %v", err)
+	}
+	return db
+}
+
+// insert imports the chain into the database, either fully or as by snap sync
+// with the older half of the blocks written into the ancient store. As by snap
+// sync, only the blocks above the current snap block are imported.
+func (tt *crashTest) insert(chain *BlockChain) error {
+	if !tt.snap {
+		_, err := chain.InsertChain(tt.blocks)
+		return err
+	}
+	headers := make([]*types.Header, len(tt.blocks))
+	for i, block := range tt.blocks {
+		headers[i] = block.Header()
+	}
+	if _, err := chain.InsertHeaderChain(headers); err != nil {
+		return err
+	}
+	from := chain.CurrentSnapBlock().Number.Uint64()
+	if from == uint64(len(tt.blocks)) {
+		return nil
+	}
+	_, err := chain.InsertReceiptChain(tt.blocks[from:], tt.receipts[from:], uint64(len(tt.blocks)/2))
+	return err
+}
+
+// run commits the genesis and imports the chain into a fresh database in the
+// given directory, crashing it at the given write operation. It returns the
+// directory of the database as persisted at the crash, along with the number
+// of write operations issued by the genesis commit, the import and the shutdown
+// of the chain.
+func (tt *crashTest) run(t *testing.T, dir string, point int) (string, int) {
+	crash := filepath.Join(dir, "crash")
+	db := faultdb.New(tt.open(t, dir), faultdb.Config{Ops: tt.ops, Point: point, Mode: tt.mode, Dir: crash})
+	defer db.Close()
+
+	// A failed write may make the chain fail arbitrarily, but the database
+	// then discards all writes, so that it is not modified anymore.
+	chain, err := NewBlockChain(db, tt.cacheConfig(), tt.gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err == nil {
+		err = tt.insert(chain)
+		chain.Stop()
+	}
+	if !db.Crashed() {
+		if err != nil {
+			t.Fatalf("Failed to import chain: %v", err)
+		}
+		return dir, db.Count()
+	}
+	if tt.mode == faultdb.Crash || tt.mode == faultdb.PowerLoss {
+		return crash, db.Count()
+	}
+	return dir, db.Count()
+}
+
+// verify reopens the database after a crash, checking that the chain recovers
+// to a consistent head, from which the import can be completed.
+func (tt *crashTest) verify(t *testing.T, dir string, point int) {
+	db := tt.open(t, dir)
+	defer db.Close()
+
+	chain, err := NewBlockChain(db, tt.cacheConfig(), tt.gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("Crash at op %d: failed to recover chain: %v", point, err)
+	}
+	defer chain.Stop()
+
+	var (
+		header = chain.CurrentHeader().Number.Uint64()
+		snap   = chain.CurrentSnapBlock().Number.Uint64()
+		full   = chain.CurrentBlock().Number.Uint64()
+	)
+	if full > snap || snap > header {
+		t.Fatalf("Crash at op %d: inconsistent heads: header %d, snap block %d, block %d", point, header, snap, full)
+	}
+	if header > uint64(len(tt.blocks)) {
+		t.Fatalf("Crash at op %d: header head %d above chain length %d", point, header, len(tt.blocks))
+	}
+	for n := uint64(1); n <= header; n++ {
+		block := tt.blocks[n-1]
+		if hash := rawdb.ReadCanonicalHash(db, n); hash != block.Hash() {
+			t.Fatalf("Crash at op %d: canonical hash #%d mismatch: have %x, want %x", point, n, hash, block.Hash())
+		}
+		if chain.GetHeader(block.Hash(), n) == nil {
+			t.Fatalf("Crash at op %d: canonical header #%d missing", point, n)
+		}
+		if chain.GetTd(block.Hash(), n) == nil {
+			t.Fatalf("Crash at op %d: canonical total difficulty #%d missing", point, n)
+		}
+		if n > snap {
+			continue
+		}
+		if chain.GetBlock(block.Hash(), n) == nil {
+			t.Fatalf("Crash at op %d: canonical block #%d missing below snap head %d", point, n, snap)
+		}
+		if chain.GetReceiptsByHash(block.Hash()) == nil {
+			t.Fatalf("Crash at op %d: canonical receipts #%d missing below snap head %d", point, n, snap)
+		}
+	}
+	if !chain.HasState(chain.CurrentBlock().Root) {
+		t.Fatalf("Crash at op %d: state of head block #%d missing", point, full)
+	}
+	// Complete the import from the recovered head
+	if err := tt.insert(chain); err != nil {
+		t.Fatalf("Crash at op %d: failed to complete import: %v", point, err)
+	}
+	head := uint64(len(tt.blocks))
+	if tt.snap {
+		if have := chain.CurrentSnapBlock().Number.Uint64(); have != head {
+			t.Fatalf("Crash at op %d: snap head mismatch after import: have %d, want %d", point, have, head)
+		}
+		return
+	}
+	if have := chain.CurrentBlock().Number.Uint64(); have != head {
+		t.Fatalf("Crash at op %d: head mismatch after import: have %d, want %d", point, have, head)
+	}
+	if !chain.HasState(chain.CurrentBlock().Root) {
+		t.Fatalf("Crash at op %d: state of head block missing after import", point)
+	}
+}
+
+// test crashes the import at every write operation, checking the recovery.
+func (tt *crashTest) test(t *testing.T) {
+	_, ops := tt.run(t, t.TempDir(), -1)
+	if ops == 0 {
+		t.Fatal("No write operations to crash at")
+	}
+	for point := 0; point < ops; point++ {
+		dir, _ := tt.run(t, t.TempDir(), point)
+		tt.verify(t, dir, point)
+	}
+}
+
+func TestCrashRecoveryHashScheme(t *testing.T) { testCrashRecovery(t, rawdb.HashScheme) }
+func TestCrashRecoveryPathScheme(t *testing.T) { testCrashRecovery(t, rawdb.PathScheme) }
+
+func testCrashRecovery(t *testing.T, scheme string) {
+	for _, mode := range []faultdb.Mode{faultdb.Crash, faultdb.PowerLoss} {
+		name := "Crash"
+		if mode == faultdb.PowerLoss {
+			name = "PowerLoss"
+		}
+		t.Run(name, func(t *testing.T) {
+			t.Run("Full", func(t *testing.T) {
+				tt := newCrashTest(scheme, 8)
+				tt.mode = mode
+				tt.test(t)
+			})
+			t.Run("Archive", func(t *testing.T) {
+				tt := newCrashTest(scheme, 8)
+				tt.mode = mode
+				tt.archive = true
+				tt.test(t)
+			})
+			t.Run("Snapshots", func(t *testing.T) {
+				tt := newCrashTest(scheme, 8)
+				tt.mode = mode
+				tt.snapshots = true
+				tt.test(t)
+			})
+			t.Run("SnapSync", func(t *testing.T) {
+				tt := newCrashTest(scheme, 8)
+				tt.mode = mode
+				tt.snap = true
+				tt.test(t)
+			})
+		})
+	}
+	t.Run("SnapSyncAncientFailure", func(t *testing.T) {
+		tt := newCrashTest(scheme, 8)
+		tt.snap = true
+		tt.ops = faultdb.OpAppend | faultdb.OpTruncate | faultdb.OpSync
+		tt.mode = faultdb.Fail
+		tt.test(t)
+	})
+}
@@ -0,0 +1,173 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state/pruner"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// countTrieNodes returns the number of hash-scheme trie nodes in the database.
+func countTrieNodes(db ethdb.Database) int {
+	it := db.NewIterator(nil, nil)
+	defer it.Release()
+
+	var count int
+	for it.Next() {
+		if len(it.Key()) == common.HashLength {
+			count++
+		}
+	}
+	return count
+}
+
+// checkState iterates the whole state of the given root, including the storage
+// tries, failing on any missing trie node.
+func checkState(t *testing.T, chain *BlockChain, number uint64, root common.Hash) {
+	t.Helper()
+
+	tr, err := trie.New(trie.StateTrieID(root), chain.triedb)
+	if err != nil {
+		t.Fatalf("State #%d unavailable: %v", number, err)
+	}
+	it, err := tr.NodeIterator(nil)
+	if err != nil {
+		t.Fatalf("State #%d unavailable: %v", number, err)
+	}
+	for it.Next(true) {
+		if !it.Leaf() {
+			continue
+		}
+		var account types.StateAccount
+		if err := rlp.DecodeBytes(it.LeafBlob(), &account); err != nil {
+			t.Fatalf("State #%d: invalid account: %v", number, err)
+		}
+		if account.Root == types.EmptyRootHash {
+			continue
+		}
+		st, err := trie.New(trie.StorageTrieID(root, common.BytesToHash(it.LeafKey()), account.Root), chain.triedb)
+		if err != nil {
+			t.Fatalf("State #%d: storage unavailable: %v", number, err)
+		}
+		sit, err := st.NodeIterator(nil)
+		if err != nil {
+			t.Fatalf("State #%d: storage unavailable: %v", number, err)
+		}
+		for sit.Next(true) {
+		}
+		if err := sit.Error(); err != nil {
+			t.Fatalf("State #%d: storage incomplete: %v", number, err)
+		}
+	}
+	if err := it.Error(); err != nil {
+		t.Fatalf("State #%d incomplete: %v", number, err)
+	}
+}
+
+// Tests that the online pruning deletes stale trie nodes while blocks are being
+// imported, retaining the recent states.
+func TestOnlinePruning(t *testing.T) {
+	var (
+		key, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr     = crypto.PubkeyToAddress(key.PublicKey)
+		contract = common.HexToAddress("0xc0de")
+		gspec    = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc: types.GenesisAlloc{
+				addr: {Balance: big.NewInt(params.Ether)},
+				// Stores the call value into the slot of the block number
+				contract: {Code: []byte{byte(vm.CALLVALUE), byte(vm.NUMBER), byte(vm.SSTORE), byte(vm.STOP)}},
+			},
+		}
+		signer = types.LatestSigner(gspec.Config)
+	)
+	_, blocks, _ := GenerateChainWithGenesis(gspec, ethash.NewFaker(), 2*TriesInMemory+64, func(i int, gen *BlockGen) {
+		for _, to := range []common.Address{common.BigToAddress(big.NewInt(int64(0x1000 + i))), contract} {
+			tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(addr), to, big.NewInt(1), 100000, gen.BaseFee(), nil), signer, key)
+			gen.AddTx(tx)
+		}
+	})
+	// Flush the state after every block, so that stale states pile up on disk.
+	// The clean cache is disabled, so that the deleted nodes are not retained.
+	db := rawdb.NewMemoryDatabase()
+	config := &CacheConfig{
+		TrieDirtyLimit: 256,
+		TrieTimeLimit:  1,
+		SnapshotLimit:  256,
+		SnapshotWait:   true,
+		StateScheme:    rawdb.HashScheme,
+	}
+	chain, err := NewBlockChain(db, config, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	split := 2 * TriesInMemory
+	if _, err := chain.InsertChain(blocks[:split]); err != nil {
+		t.Fatalf("Failed to import chain: %v", err)
+	}
+	p, err := pruner.NewOnlinePruner(db, chain.triedb, chain.snaps, pruner.OnlineConfig{BloomSize: 256})
+	if err != nil {
+		t.Fatalf("Failed to create pruner: %v", err)
+	}
+	before := countTrieNodes(db)
+
+	// Prune while importing the remaining blocks
+	errc := make(chan error, 1)
+	go func() { errc <- p.Prune() }()
+	if _, err := chain.InsertChain(blocks[split:]); err != nil {
+		t.Fatalf("Failed to import chain while pruning: %v", err)
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("Failed to prune state: %v", err)
+	}
+	if after := countTrieNodes(db); after >= before {
+		t.Fatalf("No trie nodes pruned: %d before, %d after", before, after)
+	}
+	if rawdb.ReadOnlinePruningMarker(db) != nil {
+		t.Fatal("Pruning marker left after completion")
+	}
+	// All recent states and the genesis state must be complete
+	head := chain.CurrentBlock().Number.Uint64()
+	for number := head - TriesInMemory + 1; number <= head; number++ {
+		checkState(t, chain, number, chain.GetHeaderByNumber(number).Root)
+	}
+	checkState(t, chain, 0, chain.Genesis().Root())
+
+	// An interrupted pruning is resumed from the persisted marker
+	rawdb.WriteOnlinePruningMarker(db, common.Hash{0x80}.Bytes())
+	if err := p.Prune(); err != nil {
+		t.Fatalf("Failed to resume pruning: %v", err)
+	}
+	if rawdb.ReadOnlinePruningMarker(db) != nil {
+		t.Fatal("Pruning marker left after resumption")
+	}
+	checkState(t, chain, head, chain.CurrentBlock().Root)
+}
@@ -0,0 +1,312 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+var (
+	parallelTxMeter     = metrics.NewRegisteredMeter("chain/parallel/txs", nil)
+	parallelReexecMeter = metrics.NewRegisteredMeter("chain/parallel/reexecs", nil)
+)
+
+// parallelOutcome is the result of an execution of a transaction.
+type parallelOutcome struct {
+	result    *ExecutionResult
+	err       error
+	aborted   bool // Whether the execution panicked on an inconsistent state
+	reads     map[mvKey][]mvVersion
+	writes    *parallelWrites
+	logs      []*types.Log
+	preimages map[common.Hash][]byte
+}
+
+// parallelTask tracks the executions of a transaction.
+type parallelTask struct {
+	tx  *types.Transaction
+	msg *Message
+
+	inc      int  // Incarnation of the latest scheduled execution
+	queued   bool // Whether the task is waiting for a worker
+	executed bool // Whether the latest incarnation finished executing
+	outcome  *parallelOutcome
+}
+
+// parallelExecutor executes the transactions of a block following Block-STM:
+// all transactions are executed speculatively by a pool of workers against a
+// multi-version memory holding the writes of the preceding transactions. The
+// transactions are then committed in order, validating that the values read by
+// each are still current, and re-executing it otherwise. Transactions which read
+// values changed by a re-execution are rescheduled.
+type parallelExecutor struct {
+	p     *StateProcessor
+	block *types.Block
+	cfg   vm.Config
+	mv    *mvMemory
+	tasks []*parallelTask
+
+	lock  sync.Mutex
+	cond  *sync.Cond
+	queue chan int
+	wg    sync.WaitGroup
+
+	reexecs int // Number of transactions re-executed during commit
+}
+
+// applyParallel applies the transactions of the block to the state using the
+// parallel executor, returning the receipts and logs.
+func (p *StateProcessor) applyParallel(block *types.Block, statedb *state.StateDB, cfg vm.Config, gp *GasPool, usedGas *uint64) (types.Receipts, []*types.Log, error) {
+	e, err := p.newParallelExecutor(block, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	e.start(statedb, p.workers)
+	defer e.stop()
+
+	return e.commit(statedb, gp, usedGas)
+}
+
+// newParallelExecutor creates an executor for the transactions of the block,
+// with all of them scheduled for execution.
+func (p *StateProcessor) newParallelExecutor(block *types.Block, cfg vm.Config) (*parallelExecutor, error) {
+	var (
+		header = block.Header()
+		signer = types.MakeSigner(p.config, header.Number, header.Time)
+		txs    = block.Transactions()
+		e      = &parallelExecutor{
+			p:     p,
+			block: block,
+			cfg:   cfg,
+			mv:    newMVMemory(len(txs)),
+			tasks: make([]*parallelTask, len(txs)),
+			queue: make(chan int, len(txs)),
+		}
+	)
+	e.cond = sync.NewCond(&e.lock)
+
+	for i, tx := range txs {
+		msg, err := TransactionToMessage(tx, signer, header.BaseFee)
+		if err != nil {
+			return nil, fmt.Errorf("could not apply tx %d [%v]: %w", i, tx.Hash().Hex(), err)
+		}
+		e.tasks[i] = &parallelTask{tx: tx, msg: msg, queued: true}
+		e.queue <- i
+	}
+	return e, nil
+}
+
+// start launches the workers executing the scheduled transactions on top of the
+// given state.
+func (e *parallelExecutor) start(statedb *state.StateDB, workers int) {
+	if workers > len(e.tasks) {
+		workers = len(e.tasks)
+	}
+	for i := 0; i < workers; i++ {
+		e.wg.Add(1)
+		go e.work(statedb.Copy())
+	}
+}
+
+// stop terminates the workers, waiting for any running execution to finish.
+func (e *parallelExecutor) stop() {
+	close(e.queue)
+	e.wg.Wait()
+}
+
+// newEVM creates an EVM for executing the block's transactions. The block
+// context is not shared, as its block hash cache is not thread safe.
+func (e *parallelExecutor) newEVM() *vm.EVM {
+	context := NewEVMBlockContext(e.block.Header(), e.p.bc, nil)
+	return vm.NewEVM(context, vm.TxContext{}, nil, e.p.config, e.cfg)
+}
+
+// execute runs the transaction against the multi-version memory, reading values
+// not written by preceding transactions from the given base state.
+func (e *parallelExecutor) execute(index int, base *state.StateDB, evm *vm.EVM, gp *GasPool) *parallelOutcome {
+	var (
+		task   = e.tasks[index]
+		reader = newMVReader(e.mv, base, index)
+		view   = state.NewWithReader(reader)
+	)
+	view.SetTxContext(task.tx.Hash(), index)
+	evm.Reset(NewEVMTxContext(task.msg), view)
+	result, err := ApplyMessage(evm, task.msg, gp)
+	view.Finalise(true)
+
+	return &parallelOutcome{
+		result:    result,
+		err:       err,
+		reads:     reader.reads,
+		writes:    &parallelWrites{changes: view.Changes(), credits: view.Credits()},
+		logs:      view.Logs(),
+		preimages: view.Preimages(),
+	}
+}
+
+// speculate executes the transaction speculatively. As the values read may be
+// inconsistent, any panic is caught and the execution marked as aborted.
+func (e *parallelExecutor) speculate(index int, base *state.StateDB, evm *vm.EVM) (outcome *parallelOutcome) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Debug("Speculative transaction execution aborted", "index", index, "err", r)
+			outcome = &parallelOutcome{aborted: true, writes: new(parallelWrites)}
+		}
+	}()
+	return e.execute(index, base, evm, new(GasPool).AddGas(e.block.GasLimit()))
+}
+
+// work executes the scheduled transactions until the queue is closed.
+func (e *parallelExecutor) work(base *state.StateDB) {
+	defer e.wg.Done()
+
+	evm := e.newEVM()
+	for index := range e.queue {
+		e.lock.Lock()
+		task := e.tasks[index]
+		task.queued = false
+		if task.executed {
+			e.lock.Unlock()
+			continue
+		}
+		inc := task.inc
+		e.lock.Unlock()
+
+		outcome := e.speculate(index, base, evm)
+
+		e.lock.Lock()
+		if task.inc == inc {
+			e.store(index, outcome)
+		}
+		e.lock.Unlock()
+	}
+}
+
+// store records the outcome of the latest incarnation of a transaction and
+// publishes its writes, returning the keys whose values changed. The lock must
+// be held.
+func (e *parallelExecutor) store(index int, outcome *parallelOutcome) map[mvKey]struct{} {
+	task := e.tasks[index]
+	task.outcome = outcome
+	task.executed = true
+
+	changed := make(map[mvKey]struct{})
+	for _, key := range e.mv.written[index] {
+		changed[key] = struct{}{}
+	}
+	entries := outcome.writes.entries()
+	for key := range entries {
+		changed[key] = struct{}{}
+	}
+	e.mv.write(mvVersion{tx: index, inc: task.inc}, entries)
+	e.cond.Broadcast()
+	return changed
+}
+
+// reschedule schedules the re-execution of the transactions after the given
+// one which read any of the changed keys. The lock must be held.
+func (e *parallelExecutor) reschedule(index int, changed map[mvKey]struct{}) {
+	for i := index + 1; i < len(e.tasks); i++ {
+		task := e.tasks[i]
+		if !task.executed || task.outcome.aborted {
+			continue
+		}
+		for key := range changed {
+			if _, ok := task.outcome.reads[key]; ok {
+				task.inc++
+				task.executed = false
+				if !task.queued {
+					task.queued = true
+					e.queue <- i
+				}
+				break
+			}
+		}
+	}
+}
+
+// commit applies the transactions in order to the state, once their execution
+// is validated. The state is the base state of the speculative executions and
+// must not be modified by anyone else meanwhile.
+func (e *parallelExecutor) commit(statedb *state.StateDB, gp *GasPool, usedGas *uint64) (types.Receipts, []*types.Log, error) {
+	var (
+		base        = statedb.Copy()
+		evm         = e.newEVM()
+		blockNumber = e.block.Number()
+		blockHash   = e.block.Hash()
+		receipts    = make(types.Receipts, 0, len(e.tasks))
+		allLogs     []*types.Log
+	)
+	for i, task := range e.tasks {
+		e.lock.Lock()
+		for !task.executed {
+			e.cond.Wait()
+		}
+		outcome := task.outcome
+		if outcome.aborted || !e.mv.validate(i, outcome.reads) {
+			// All preceding transactions are committed, so the re-execution is
+			// guaranteed to be valid.
+			task.inc++
+			e.lock.Unlock()
+
+			outcome = e.execute(i, base, evm, new(GasPool).AddGas(e.block.GasLimit()))
+			e.reexecs++
+
+			e.lock.Lock()
+			e.reschedule(i, e.store(i, outcome))
+		}
+		e.lock.Unlock()
+
+		// Enforce the block gas limit, reproducing the error of sequential
+		// execution if it is exceeded.
+		if gp.Gas() < task.msg.GasLimit {
+			outcome = e.execute(i, base, evm, new(GasPool).AddGas(gp.Gas()))
+		}
+		if outcome.err != nil {
+			return nil, nil, fmt.Errorf("could not apply tx %d [%v]: %w", i, task.tx.Hash().Hex(), outcome.err)
+		}
+		gp.SubGas(outcome.result.UsedGas)
+		*usedGas += outcome.result.UsedGas
+
+		statedb.SetTxContext(task.tx.Hash(), i)
+		outcome.writes.apply(statedb)
+		for _, l := range outcome.logs {
+			statedb.AddLog(l)
+		}
+		for hash, preimage := range outcome.preimages {
+			statedb.AddPreimage(hash, preimage)
+		}
+		statedb.Finalise(true)
+
+		receipt := newReceipt(task.tx, task.msg, outcome.result, nil, *usedGas, evm.Context.BlobBaseFee, statedb, blockNumber, blockHash)
+		receipts = append(receipts, receipt)
+		allLogs = append(allLogs, receipt.Logs...)
+	}
+	parallelTxMeter.Mark(int64(len(e.tasks)))
+	parallelReexecMeter.Mark(int64(e.reexecs))
+	log.Debug("Executed transactions in parallel", "number", blockNumber, "txs", len(e.tasks), "reexecs", e.reexecs)
+
+	return receipts, allLogs, nil
+}
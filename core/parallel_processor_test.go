@@ -0,0 +1,266 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/beacon"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+var (
+	// parallelCounter increments slot 0 and logs the new value.
+	parallelCounter     = common.HexToAddress("0xc0")
+	parallelCounterCode = hexutil.MustDecode("0x600054600101806000556000526020600060a000")
+
+	// parallelCoinbaseReader stores the balance of the coinbase in slot 0.
+	parallelCoinbaseReader     = common.HexToAddress("0xc1")
+	parallelCoinbaseReaderCode = hexutil.MustDecode("0x413160005500")
+
+	// parallelReverter reverts.
+	parallelReverter     = common.HexToAddress("0xc2")
+	parallelReverterCode = hexutil.MustDecode("0x600080fd")
+
+	// parallelDestructible self-destructs, sending its balance to the caller.
+	parallelDestructible     = common.HexToAddress("0xc3")
+	parallelDestructibleCode = hexutil.MustDecode("0x33ff")
+
+	// parallelInitCode sets slot 0 and deploys a self-destructing contract.
+	parallelInitCode = hexutil.MustDecode("0x60016000556133ff6000526002601ef3")
+
+	parallelCoinbase = common.HexToAddress("0xc0ffee")
+)
+
+// generateParallelChain generates blocks whose transactions conflict in all the
+// ways tracked by the parallel executor: balances of senders funded in the same
+// block, shared storage, coinbase balance reads, account creations and deletions.
+func generateParallelChain(t *testing.T, config *params.ChainConfig, engine consensus.Engine, n int) (*Genesis, []*types.Block) {
+	t.Helper()
+
+	keys := make([]*ecdsa.PrivateKey, 8)
+	addrs := make([]common.Address, len(keys))
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		addrs[i] = crypto.PubkeyToAddress(keys[i].PublicKey)
+	}
+	gspec := &Genesis{
+		Config: config,
+		Alloc: types.GenesisAlloc{
+			addrs[0]:               {Balance: big.NewInt(params.Ether)},
+			addrs[1]:               {Balance: big.NewInt(params.Ether)},
+			parallelCounter:        {Code: parallelCounterCode},
+			parallelCoinbaseReader: {Code: parallelCoinbaseReaderCode},
+			parallelReverter:       {Code: parallelReverterCode},
+			parallelDestructible:   {Code: parallelDestructibleCode, Balance: big.NewInt(params.GWei), Storage: map[common.Hash]common.Hash{{}: common.HexToHash("0x01")}},
+		},
+	}
+	if config.TerminalTotalDifficultyPassed {
+		gspec.Difficulty = common.Big0
+	}
+	signer := types.LatestSigner(config)
+	_, blocks, _ := GenerateChainWithGenesis(gspec, engine, n, func(i int, gen *BlockGen) {
+		gen.SetCoinbase(parallelCoinbase)
+
+		send := func(from int, to *common.Address, value *big.Int, gas uint64, data []byte) {
+			tx := types.MustSignNewTx(keys[from], signer, &types.DynamicFeeTx{
+				ChainID:   config.ChainID,
+				Nonce:     gen.TxNonce(addrs[from]),
+				To:        to,
+				Value:     value,
+				Gas:       gas,
+				GasFeeCap: new(big.Int).Add(gen.BaseFee(), big.NewInt(params.GWei)),
+				GasTipCap: big.NewInt(params.GWei),
+				Data:      data,
+			})
+			gen.AddTx(tx)
+		}
+		// Fund the next sender, which spends in the same block
+		next := (i + 2) % len(keys)
+		send(i%2, &addrs[next], big.NewInt(params.Ether/10), params.TxGas, nil)
+		send(next, &addrs[(next+1)%len(keys)], big.NewInt(params.GWei), params.TxGas, nil)
+
+		for j := 0; j < 2; j++ {
+			send(j, &parallelCounter, nil, 100000, nil)
+			send(j, &parallelCoinbaseReader, nil, 100000, nil)
+			send(j, &parallelReverter, nil, 100000, nil)
+		}
+		// Create a contract, then destroy it, along with the genesis one
+		send(0, nil, nil, 100000, parallelInitCode)
+		created := crypto.CreateAddress(addrs[0], gen.TxNonce(addrs[0])-1)
+		send(1, &created, nil, 100000, nil)
+		send(1, &parallelDestructible, nil, 100000, nil)
+
+		// Touch an empty account and credit a fresh one
+		empty := common.BigToAddress(big.NewInt(int64(0x100 + i)))
+		send(0, &empty, common.Big0, params.TxGas, nil)
+		send(1, &empty, big.NewInt(1), params.TxGas, nil)
+	})
+	return gspec, blocks
+}
+
+func testParallelProcessor(t *testing.T, config *params.ChainConfig, engine consensus.Engine) {
+	gspec, blocks := generateParallelChain(t, config, engine, 8)
+
+	newChain := func(workers int) *BlockChain {
+		cacheConfig := DefaultCacheConfigWithScheme(rawdb.HashScheme)
+		cacheConfig.ParallelWorkers = workers
+		chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), cacheConfig, gspec, nil, engine, vm.Config{}, nil, nil)
+		if err != nil {
+			t.Fatalf("failed to create chain: %v", err)
+		}
+		return chain
+	}
+	sequential := newChain(0)
+	defer sequential.Stop()
+	if _, err := sequential.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to import blocks sequentially: %v", err)
+	}
+	for _, workers := range []int{2, 4, 16} {
+		parallel := newChain(workers)
+		if _, err := parallel.InsertChain(blocks); err != nil {
+			t.Fatalf("failed to import blocks with %d workers: %v", workers, err)
+		}
+		for _, block := range blocks {
+			want, _ := json.Marshal(sequential.GetReceiptsByHash(block.Hash()))
+			have, _ := json.Marshal(parallel.GetReceiptsByHash(block.Hash()))
+			if string(have) != string(want) {
+				t.Fatalf("block %d receipts mismatch with %d workers:\nhave %s\nwant %s", block.NumberU64(), workers, have, want)
+			}
+		}
+		parallel.Stop()
+	}
+}
+
+func TestParallelProcessor(t *testing.T) {
+	testParallelProcessor(t, params.TestChainConfig, ethash.NewFaker())
+}
+
+func TestParallelProcessorCancun(t *testing.T) {
+	testParallelProcessor(t, params.MergedTestChainConfig, beacon.NewFaker())
+}
+
+// Tests that invalid transactions are reported the same way as by sequential
+// execution.
+func TestParallelProcessorInvalidTx(t *testing.T) {
+	var (
+		key, _ = crypto.GenerateKey()
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		config = params.TestChainConfig
+		signer = types.LatestSigner(config)
+		gspec  = &Genesis{
+			Config: config,
+			Alloc:  types.GenesisAlloc{addr: {Balance: big.NewInt(params.Ether)}},
+		}
+		engine = ethash.NewFaker()
+	)
+	block := GenerateBadBlock(gspec.ToBlock(), engine, types.Transactions{
+		types.MustSignNewTx(key, signer, &types.LegacyTx{Nonce: 0, To: &parallelCounter, Gas: params.TxGas, GasPrice: big.NewInt(params.GWei)}),
+		types.MustSignNewTx(key, signer, &types.LegacyTx{Nonce: 2, To: &parallelCounter, Gas: params.TxGas, GasPrice: big.NewInt(params.GWei)}),
+	}, config)
+
+	var errs []string
+	for _, workers := range []int{0, 4} {
+		cacheConfig := DefaultCacheConfigWithScheme(rawdb.HashScheme)
+		cacheConfig.ParallelWorkers = workers
+		chain, _ := NewBlockChain(rawdb.NewMemoryDatabase(), cacheConfig, gspec, nil, engine, vm.Config{}, nil, nil)
+		_, err := chain.InsertChain(types.Blocks{block})
+		if err == nil {
+			t.Fatalf("invalid block imported with %d workers", workers)
+		}
+		errs = append(errs, err.Error())
+		chain.Stop()
+	}
+	if errs[0] != errs[1] {
+		t.Fatalf("error mismatch:\nhave %s\nwant %s", errs[1], errs[0])
+	}
+}
+
+// Tests that conflicts are detected and resolved by executing all transactions
+// speculatively in reverse order, so that each one misses the writes of the
+// preceding ones, before committing.
+func TestParallelProcessorConflicts(t *testing.T) {
+	var (
+		config        = params.TestChainConfig
+		engine        = ethash.NewFaker()
+		gspec, blocks = generateParallelChain(t, config, engine, 4)
+	)
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), DefaultCacheConfigWithScheme(rawdb.HashScheme), gspec, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to import blocks: %v", err)
+	}
+	processor := NewParallelStateProcessor(config, chain, engine, 4)
+	for _, block := range blocks {
+		parent := chain.GetHeaderByHash(block.ParentHash())
+		statedb, err := chain.StateAt(parent.Root)
+		if err != nil {
+			t.Fatalf("failed to open state: %v", err)
+		}
+		e, err := processor.newParallelExecutor(block, vm.Config{})
+		if err != nil {
+			t.Fatalf("failed to create executor: %v", err)
+		}
+		var (
+			base = statedb.Copy()
+			evm  = e.newEVM()
+		)
+		for i := len(e.tasks) - 1; i >= 0; i-- {
+			e.lock.Lock()
+			e.store(i, e.speculate(i, base, evm))
+			e.lock.Unlock()
+		}
+		e.start(statedb, 4)
+		var (
+			gp      = new(GasPool).AddGas(block.GasLimit())
+			usedGas uint64
+		)
+		receipts, _, err := e.commit(statedb, gp, &usedGas)
+		e.stop()
+		if err != nil {
+			t.Fatalf("block %d: failed to commit: %v", block.NumberU64(), err)
+		}
+		if e.reexecs == 0 {
+			t.Errorf("block %d: no transaction re-executed", block.NumberU64())
+		}
+		if usedGas != block.GasUsed() {
+			t.Errorf("block %d: gas used mismatch: have %d, want %d", block.NumberU64(), usedGas, block.GasUsed())
+		}
+		if hash := types.DeriveSha(receipts, trie.NewStackTrie(nil)); hash != block.ReceiptHash() {
+			t.Errorf("block %d: receipt hash mismatch: have %x, want %x", block.NumberU64(), hash, block.ReceiptHash())
+		}
+		engine.Finalize(chain, block.Header(), statedb, block.Transactions(), block.Uncles(), block.Withdrawals())
+		if root := statedb.IntermediateRoot(true); root != block.Root() {
+			t.Errorf("block %d: state root mismatch: have %x, want %x", block.NumberU64(), root, block.Root())
+		}
+	}
+}
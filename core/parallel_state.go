@@ -0,0 +1,328 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/holiman/uint256"
+)
+
+// mvKind is the type of a location tracked in the multi-version memory.
+type mvKind byte
+
+const (
+	mvAccount mvKind = iota // Account data: balance, nonce and code
+	mvReset                 // Storage wipe, by deleting or recreating an account
+	mvSlot                  // Storage slot
+)
+
+// mvKey is a location in the state whose accesses are tracked for conflicts.
+type mvKey struct {
+	kind mvKind
+	addr common.Address
+	slot common.Hash
+}
+
+// mvVersion identifies the execution of a transaction which wrote a value.
+type mvVersion struct {
+	tx  int // Index of the transaction in the block, -1 for the base state
+	inc int // Incarnation, the number of the transaction's (re-)execution
+}
+
+// baseVersion is the version of values read from the state of the block before
+// any transaction was executed.
+var baseVersion = mvVersion{tx: -1}
+
+// mvAccountData is the value of an account after executing a transaction.
+type mvAccountData struct {
+	deleted bool                // Whether the account was deleted
+	delta   *uint256.Int        // Balance credited without reading the account, all other fields are unset if non-nil
+	account *types.StateAccount // Value of the account, nil if deleted or credited
+	code    []byte              // Code set by the transaction, nil if unchanged
+}
+
+// mvEntry is a value written into the multi-version memory.
+type mvEntry struct {
+	version mvVersion
+	account *mvAccountData // Value of account keys
+	value   common.Hash    // Value of slot keys
+}
+
+// mvMemory is a multi-version store of the values written by the transactions
+// of a block. Each transaction sees the values written by the latest executions
+// of the transactions preceding it, on top of the base state.
+type mvMemory struct {
+	lock    sync.RWMutex
+	entries map[mvKey][]mvEntry // Values of each key ordered by transaction index
+	written [][]mvKey           // Keys written by the last execution of each transaction
+}
+
+// newMVMemory creates an empty multi-version memory for n transactions.
+func newMVMemory(n int) *mvMemory {
+	return &mvMemory{
+		entries: make(map[mvKey][]mvEntry),
+		written: make([][]mvKey, n),
+	}
+}
+
+// write replaces the values written by a previous execution of a transaction.
+func (mv *mvMemory) write(version mvVersion, writes map[mvKey]mvEntry) {
+	mv.lock.Lock()
+	defer mv.lock.Unlock()
+
+	// Remove the values the transaction wrote previously but does not anymore
+	for _, key := range mv.written[version.tx] {
+		if _, ok := writes[key]; ok {
+			continue
+		}
+		entries := mv.entries[key]
+		if i := mv.search(entries, version.tx); i < len(entries) && entries[i].version.tx == version.tx {
+			entries = append(entries[:i], entries[i+1:]...)
+		}
+		if len(entries) == 0 {
+			delete(mv.entries, key)
+		} else {
+			mv.entries[key] = entries
+		}
+	}
+	keys := make([]mvKey, 0, len(writes))
+	for key, entry := range writes {
+		entry.version = version
+
+		entries := mv.entries[key]
+		if i := mv.search(entries, version.tx); i < len(entries) && entries[i].version.tx == version.tx {
+			entries[i] = entry
+		} else {
+			entries = append(entries, mvEntry{})
+			copy(entries[i+1:], entries[i:])
+			entries[i] = entry
+			mv.entries[key] = entries
+		}
+		keys = append(keys, key)
+	}
+	mv.written[version.tx] = keys
+}
+
+// search returns the position of the transaction in the ordered entries, or the
+// position to insert it at.
+func (mv *mvMemory) search(entries []mvEntry, tx int) int {
+	return sort.Search(len(entries), func(i int) bool {
+		return entries[i].version.tx >= tx
+	})
+}
+
+// read returns the entries determining the value of a key for a transaction,
+// along with their versions. For slots and storage wipes, it is the entry of the
+// last preceding transaction writing the key. For accounts, it also includes the
+// balance credits on top of the last full account value. The base state is part
+// of the versions if it determines the value.
+func (mv *mvMemory) read(key mvKey, tx int) ([]mvEntry, []mvVersion) {
+	mv.lock.RLock()
+	defer mv.lock.RUnlock()
+
+	var (
+		entries  = mv.entries[key]
+		consumed []mvEntry
+		versions []mvVersion
+	)
+	for i := mv.search(entries, tx) - 1; i >= 0; i-- {
+		consumed = append(consumed, entries[i])
+		versions = append(versions, entries[i].version)
+		if key.kind != mvAccount || entries[i].account.delta == nil {
+			return consumed, versions
+		}
+	}
+	return consumed, append(versions, baseVersion)
+}
+
+// validate checks whether the values read by an execution of a transaction are
+// still the ones visible to it.
+func (mv *mvMemory) validate(tx int, reads map[mvKey][]mvVersion) bool {
+	for key, versions := range reads {
+		_, current := mv.read(key, tx)
+		if len(current) != len(versions) {
+			return false
+		}
+		for i := range current {
+			if current[i] != versions[i] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// code returns the code of an account for a transaction, as set by the last
+// preceding transaction changing it, or nil if it is unchanged since the base
+// state. The code is not tracked as a read, as it is determined by the code
+// hash of the account.
+func (mv *mvMemory) code(addr common.Address, tx int) ([]byte, bool) {
+	mv.lock.RLock()
+	defer mv.lock.RUnlock()
+
+	entries := mv.entries[mvKey{kind: mvAccount, addr: addr}]
+	for i := mv.search(entries, tx) - 1; i >= 0; i-- {
+		if data := entries[i].account; data.code != nil {
+			return data.code, true
+		} else if data.deleted {
+			return nil, true
+		}
+	}
+	return nil, false
+}
+
+// mvReader implements state.Reader for the speculative execution of a single
+// transaction against a multi-version memory. All values not written by any
+// preceding transaction are read from the base state. It records the versions
+// of the values read, allowing to detect if the execution is invalidated by a
+// later execution of a preceding transaction.
+type mvReader struct {
+	mv    *mvMemory
+	base  *state.StateDB // Exclusively owned copy of the state before the first transaction
+	index int            // Index of the executed transaction
+	reads map[mvKey][]mvVersion
+}
+
+// newMVReader creates the reader for executing the given transaction.
+func newMVReader(mv *mvMemory, base *state.StateDB, index int) *mvReader {
+	return &mvReader{
+		mv:    mv,
+		base:  base,
+		index: index,
+		reads: make(map[mvKey][]mvVersion),
+	}
+}
+
+// Account implements state.Reader, resolving the last full value of the account
+// and applying the credits on top.
+func (r *mvReader) Account(addr common.Address) *types.StateAccount {
+	key := mvKey{kind: mvAccount, addr: addr}
+	entries, versions := r.mv.read(key, r.index)
+	r.reads[key] = versions
+
+	var acct *types.StateAccount
+	if n := len(entries); n > 0 && entries[n-1].account.delta == nil {
+		if data := entries[n-1].account; !data.deleted {
+			acct = data.account.Copy()
+		}
+		entries = entries[:n-1]
+	} else if r.base.Exist(addr) {
+		acct = &types.StateAccount{
+			Nonce:    r.base.GetNonce(addr),
+			Balance:  r.base.GetBalance(addr).Clone(),
+			Root:     r.base.GetStorageRoot(addr),
+			CodeHash: r.base.GetCodeHash(addr).Bytes(),
+		}
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		delta := entries[i].account.delta
+		if acct == nil {
+			acct = types.NewEmptyStateAccount()
+			acct.Balance = delta.Clone()
+			continue
+		}
+		acct.Balance = new(uint256.Int).Add(acct.Balance, delta)
+	}
+	return acct
+}
+
+// Storage implements state.Reader.
+func (r *mvReader) Storage(addr common.Address, slot common.Hash) common.Hash {
+	key := mvKey{kind: mvSlot, addr: addr, slot: slot}
+	resetKey := mvKey{kind: mvReset, addr: addr}
+	written, versions := r.mv.read(key, r.index)
+	reset, resetVersions := r.mv.read(resetKey, r.index)
+	r.reads[key] = versions
+	r.reads[resetKey] = resetVersions
+
+	switch {
+	case len(written) > 0 && (len(reset) == 0 || written[0].version.tx >= reset[0].version.tx):
+		return written[0].value
+	case len(reset) > 0:
+		// Wiped by a preceding transaction and not written since
+		return common.Hash{}
+	default:
+		return r.base.GetState(addr, slot)
+	}
+}
+
+// Code implements state.Reader.
+func (r *mvReader) Code(addr common.Address, codeHash common.Hash) []byte {
+	if code, ok := r.mv.code(addr, r.index); ok {
+		return code
+	}
+	return r.base.GetCode(addr)
+}
+
+// parallelWrites are the state modifications of a transaction execution.
+type parallelWrites struct {
+	changes map[common.Address]*state.AccountChange
+	credits map[common.Address]*uint256.Int
+}
+
+// entries converts the modifications into values of the multi-version memory.
+func (w *parallelWrites) entries() map[mvKey]mvEntry {
+	entries := make(map[mvKey]mvEntry)
+	for addr, change := range w.changes {
+		data := &mvAccountData{deleted: change.Deleted, account: change.Account, code: change.Code}
+		entries[mvKey{kind: mvAccount, addr: addr}] = mvEntry{account: data}
+		if change.Reset {
+			entries[mvKey{kind: mvReset, addr: addr}] = mvEntry{}
+		}
+		for slot, value := range change.Storage {
+			entries[mvKey{kind: mvSlot, addr: addr, slot: slot}] = mvEntry{value: value}
+		}
+	}
+	for addr, delta := range w.credits {
+		entries[mvKey{kind: mvAccount, addr: addr}] = mvEntry{account: &mvAccountData{delta: delta}}
+	}
+	return entries
+}
+
+// apply writes the modifications into the state, so that it is in the same state
+// as after executing the transaction on it.
+func (w *parallelWrites) apply(statedb *state.StateDB) {
+	for addr, change := range w.changes {
+		if change.Deleted {
+			if !statedb.Exist(addr) {
+				statedb.CreateAccount(addr)
+			}
+			statedb.SelfDestruct(addr)
+			continue
+		}
+		if change.Reset {
+			statedb.CreateAccount(addr)
+		}
+		statedb.SetBalance(addr, change.Account.Balance)
+		statedb.SetNonce(addr, change.Account.Nonce)
+		if !bytes.Equal(statedb.GetCodeHash(addr).Bytes(), change.Account.CodeHash) {
+			statedb.SetCode(addr, change.Code)
+		}
+		for slot, value := range change.Storage {
+			statedb.SetState(addr, slot, value)
+		}
+	}
+	for addr, delta := range w.credits {
+		statedb.AddBalance(addr, delta)
+	}
+}
@@ -264,3 +264,32 @@ func WriteStateHistory(db ethdb.AncientWriter, id uint64, meta []byte, accountIn
 		return nil
 	})
 }
+
+// ReadOnlinePruningMarker retrieves the last database key swept by an interrupted
+// online state pruning. A non-nil marker is returned if a pruning is pending.
+func ReadOnlinePruningMarker(db ethdb.KeyValueReader) []byte {
+	data, err := db.Get(onlinePruningKey)
+	if err != nil {
+		return nil
+	}
+	if data == nil {
+		return []byte{}
+	}
+	return data
+}
+
+// WriteOnlinePruningMarker stores the last database key swept by the online state
+// pruning, to resume from after a restart.
+func WriteOnlinePruningMarker(db ethdb.KeyValueWriter, marker []byte) {
+	if err := db.Put(onlinePruningKey, marker); err != nil {
+		log.Crit("Failed to store online pruning marker", "err", err)
+	}
+}
+
+// DeleteOnlinePruningMarker deletes the online state pruning marker, once the
+// pruning is finished.
+func DeleteOnlinePruningMarker(db ethdb.KeyValueWriter) {
+	if err := db.Delete(onlinePruningKey); err != nil {
+		log.Crit("Failed to remove online pruning marker", "err", err)
+	}
+}
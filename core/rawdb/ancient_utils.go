@@ -120,30 +120,66 @@ func inspectFreezers(db ethdb.Database) ([]freezerInfo, error) {
 // be opened. Start and end specify the range for dumping out indexes.
 // Note this function can only be used for debugging purposes.
 func InspectFreezerTable(ancient string, freezerName string, tableName string, start, end int64) error {
-	var (
-		path   string
-		tables map[string]bool
-	)
+	path, tables, err := freezerTables(ancient, freezerName)
+	if err != nil {
+		return err
+	}
+	if err := checkFreezerTable(tables, tableName); err != nil {
+		return err
+	}
+	table, err := newFreezerTable(path, tableName, tables[tableName], true)
+	if err != nil {
+		return err
+	}
+	table.dumpIndexStdout(start, end)
+	return nil
+}
+
+// RecompressFreezerTable re-encodes a specific freezer table with the given
+// compression codec, either "snappy" or "zstd", or the current one if codec is
+// empty. The passed ancient indicates the path of root ancient directory where
+// the chain freezer can be opened. Zstd tables are compressed with a dictionary
+// of dictSize bytes trained on the existing table items, or without a dictionary
+// if dictSize is zero. If checksums is set, item checksums are added to the
+// index of the table.
+func RecompressFreezerTable(ancient string, freezerName string, tableName string, codec string, dictSize int, checksums bool) error {
+	path, tables, err := freezerTables(ancient, freezerName)
+	if err != nil {
+		return err
+	}
+	if err := checkFreezerTable(tables, tableName); err != nil {
+		return err
+	}
+	f, err := NewFreezer(path, "", false, freezerTableSize, tables)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return f.RecompressTable(tableName, codec, dictSize, checksums)
+}
+
+// freezerTables resolves the path and the table configuration of a freezer.
+func freezerTables(ancient string, freezerName string) (string, map[string]bool, error) {
 	switch freezerName {
 	case ChainFreezerName:
-		path, tables = resolveChainFreezerDir(ancient), chainFreezerNoSnappy
+		return resolveChainFreezerDir(ancient), chainFreezerNoSnappy, nil
 	case StateFreezerName:
-		path, tables = filepath.Join(ancient, freezerName), stateFreezerNoSnappy
+		return filepath.Join(ancient, freezerName), stateFreezerNoSnappy, nil
 	default:
-		return fmt.Errorf("unknown freezer, supported ones: %v", freezers)
+		return "", nil, fmt.Errorf("unknown freezer, supported ones: %v", freezers)
 	}
-	noSnappy, exist := tables[tableName]
-	if !exist {
+}
+
+// checkFreezerTable returns an error if the table is not part of the freezer
+// configuration.
+func checkFreezerTable(tables map[string]bool, tableName string) error {
+	if _, exist := tables[tableName]; !exist {
 		var names []string
 		for name := range tables {
 			names = append(names, name)
 		}
 		return fmt.Errorf("unknown table, supported ones: %v", names)
 	}
-	table, err := newFreezerTable(path, tableName, noSnappy, true)
-	if err != nil {
-		return err
-	}
-	table.dumpIndexStdout(start, end)
 	return nil
 }
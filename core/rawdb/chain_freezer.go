@@ -52,11 +52,17 @@ type chainFreezer struct {
 }
 
 // newChainFreezer initializes the freezer for ancient chain data.
-func newChainFreezer(datadir string, namespace string, readonly bool) (*chainFreezer, error) {
+func newChainFreezer(datadir string, namespace string, readonly bool, checksums bool) (*chainFreezer, error) {
 	freezer, err := NewChainFreezer(datadir, namespace, readonly)
 	if err != nil {
 		return nil, err
 	}
+	if checksums && !readonly {
+		if err := freezer.enableChecksums(); err != nil {
+			freezer.Close()
+			return nil, err
+		}
+	}
 	cf := chainFreezer{
 		Freezer: freezer,
 		quit:    make(chan struct{}),
@@ -0,0 +1,200 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// errCheckpointExists is returned if the target directory of a checkpoint
+// already exists.
+var errCheckpointExists = errors.New("checkpoint directory already exists")
+
+// checkpoint copies the files of the table into the given directory. The index
+// and the head data file are copied, while the other data files, which are
+// immutable, are hard-linked if possible.
+func (t *freezerTable) checkpoint(dir string) error {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if t.index == nil || t.head == nil || t.meta == nil {
+		return errClosed
+	}
+	for _, f := range []*os.File{t.index, t.meta} {
+		name := filepath.Base(f.Name())
+		if err := copyFrom(f.Name(), filepath.Join(dir, name), 0, nil); err != nil {
+			return err
+		}
+	}
+	for num := t.tailId; num <= t.headId; num++ {
+		var (
+			name = t.dataFileName(num)
+			src  = filepath.Join(t.path, name)
+			dst  = filepath.Join(dir, name)
+			err  error
+		)
+		if num == t.headId {
+			err = copyFrom(src, dst, 0, nil)
+		} else {
+			err = linkOrCopyFile(src, dst)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkpoint creates a copy of the freezer in the given directory. The write
+// lock is only held to record the item counts, the tables are copied after it
+// is released, so items may be appended meanwhile. The copy is truncated back
+// to the recorded counts, so all tables are captured at the same item count.
+func (f *Freezer) checkpoint(dir string) error {
+	f.writeLock.Lock()
+	if err := f.Sync(); err != nil {
+		f.writeLock.Unlock()
+		return err
+	}
+	frozen := f.frozen.Load()
+	f.writeLock.Unlock()
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	var (
+		tables      = make(map[string]bool, len(f.tables))
+		maxFileSize uint32
+	)
+	for name, table := range f.tables {
+		if err := table.checkpoint(dir); err != nil {
+			return err
+		}
+		tables[name] = table.noCompression
+		maxFileSize = table.maxFileSize
+	}
+	cp, err := NewFreezer(dir, "", false, maxFileSize, tables)
+	if err != nil {
+		return err
+	}
+	defer cp.Close()
+
+	// The tables can only be shorter than recorded if the freezer was truncated
+	// while copying them.
+	if items, _ := cp.Ancients(); items < frozen {
+		return fmt.Errorf("freezer truncated during checkpoint: have %d items, want %d", items, frozen)
+	}
+	_, err = cp.TruncateHead(frozen)
+	return err
+}
+
+// copyFreezerDir copies the table files of a freezer which is not accessible
+// via the database, such as the state freezer owned by the path-based trie
+// database. As the freezer may be written concurrently, the index files are
+// copied before the data files, so that every indexed item is contained in the
+// copy. Items exceeding the ones in the key-value store are truncated by the
+// freezer owner on startup.
+func copyFreezerDir(src, dst string) error {
+	entries, err := os.ReadDir(src)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return err
+	}
+	var index, data []string
+	for _, entry := range entries {
+		name := entry.Name()
+		switch {
+		case entry.IsDir():
+			continue
+		case strings.HasSuffix(name, ".ridx"), strings.HasSuffix(name, ".cidx"), strings.HasSuffix(name, ".meta"):
+			index = append(index, name)
+		case strings.HasSuffix(name, ".rdat"), strings.HasSuffix(name, ".cdat"):
+			data = append(data, name)
+		}
+	}
+	for _, name := range append(index, data...) {
+		if err := copyFrom(filepath.Join(src, name), filepath.Join(dst, name), 0, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Checkpoint creates a consistent copy of the key-value store and the ancient
+// store in the given directory, which can be opened as a separate database. The
+// key-value store is placed in the directory itself, the ancients in the
+// "ancient" sub-directory.
+func (frdb *freezerdb) Checkpoint(dir string) error {
+	cp, ok := frdb.KeyValueStore.(ethdb.Checkpointer)
+	if !ok {
+		return errNotSupported
+	}
+	freezer, ok := frdb.AncientStore.(*chainFreezer)
+	if !ok {
+		return errNotSupported
+	}
+	if common.FileExist(dir) {
+		return errCheckpointExists
+	}
+	var (
+		start   = time.Now()
+		ancient = filepath.Join(dir, "ancient")
+	)
+	// The key-value store must be copied before the chain freezer. Blocks are
+	// only deleted from the store after being frozen, so every block missing
+	// from the copied store is contained in the copied ancients.
+	if err := cp.Checkpoint(dir); err != nil {
+		return err
+	}
+	if err := freezer.checkpoint(filepath.Join(ancient, ChainFreezerName)); err != nil {
+		return err
+	}
+	// The state freezer must be copied after the key-value store, as the state
+	// histories beyond the persisted state are dropped on startup, while missing
+	// ones are not tolerated.
+	if err := copyFreezerDir(filepath.Join(frdb.ancientRoot, StateFreezerName), filepath.Join(ancient, StateFreezerName)); err != nil {
+		return err
+	}
+	log.Info("Created database checkpoint", "dir", dir, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// Checkpoint creates a consistent copy of the key-value store in the given
+// directory.
+func (db *nofreezedb) Checkpoint(dir string) error {
+	cp, ok := db.KeyValueStore.(ethdb.Checkpointer)
+	if !ok {
+		return errNotSupported
+	}
+	if common.FileExist(dir) {
+		return errCheckpointExists
+	}
+	return cp.Checkpoint(dir)
+}
@@ -0,0 +1,191 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+func TestDatabaseCheckpoint(t *testing.T) {
+	var (
+		dir   = t.TempDir()
+		cpdir = filepath.Join(dir, "checkpoint")
+		open  = func(dir string) ethdb.Database {
+			db, err := Open(OpenOptions{Type: dbPebble, Directory: dir, AncientsDirectory: filepath.Join(dir, "ancient"), Ephemeral: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return db
+		}
+		db = open(filepath.Join(dir, "db"))
+	)
+	stateFreezer, err := NewStateFreezer(filepath.Join(dir, "db", "ancient"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stateFreezer.Close()
+
+	write := func(from, to int) {
+		t.Helper()
+		for i := from; i < to; i++ {
+			if err := db.Put([]byte(fmt.Sprintf("key-%d", i)), []byte{byte(i)}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, f := range []ethdb.AncientWriter{db, stateFreezer} {
+			tables := chainFreezerNoSnappy
+			if f == stateFreezer {
+				tables = stateFreezerNoSnappy
+			}
+			_, err := f.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+				for i := from; i < to; i++ {
+					for kind := range tables {
+						if err := op.AppendRaw(kind, uint64(i), []byte{byte(i)}); err != nil {
+							return err
+						}
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	write(0, 100)
+	if err := db.(ethdb.Checkpointer).Checkpoint(cpdir); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.(ethdb.Checkpointer).Checkpoint(cpdir); err == nil {
+		t.Fatal("Checkpoint into existing directory succeeded")
+	}
+	// Modifications after the checkpoint must not leak into it.
+	write(100, 150)
+	if _, err := db.TruncateHead(20); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	cp := open(cpdir)
+	defer cp.Close()
+	if frozen, _ := cp.Ancients(); frozen != 100 {
+		t.Fatalf("Wrong number of ancients in checkpoint: have %d, want 100", frozen)
+	}
+	for i := 0; i < 150; i++ {
+		have, err := cp.Get([]byte(fmt.Sprintf("key-%d", i)))
+		if i >= 100 {
+			if err == nil {
+				t.Fatalf("Key %d unexpectedly present in checkpoint", i)
+			}
+			continue
+		}
+		if err != nil || !bytes.Equal(have, []byte{byte(i)}) {
+			t.Fatalf("Wrong key %d in checkpoint: %x, %v", i, have, err)
+		}
+	}
+	for i := uint64(0); i < 100; i++ {
+		for kind := range chainFreezerNoSnappy {
+			blob, err := cp.Ancient(kind, i)
+			if err != nil || !bytes.Equal(blob, []byte{byte(i)}) {
+				t.Fatalf("Wrong %s item %d in checkpoint: %v", kind, i, err)
+			}
+		}
+	}
+	cpState, err := NewStateFreezer(filepath.Join(cpdir, "ancient"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cpState.Close()
+	if frozen, _ := cpState.Ancients(); frozen < 100 {
+		t.Fatalf("Wrong number of state histories in checkpoint: have %d, want >= 100", frozen)
+	}
+}
+
+func TestFreezerCheckpoint(t *testing.T) {
+	tables := map[string]bool{"raw": true, "compressed": false}
+	f, dir := newFreezerForTesting(t, tables)
+	defer f.Close()
+
+	write := func(f *Freezer, from, to, fill int) {
+		t.Helper()
+		_, err := f.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+			for i := from; i < to; i++ {
+				for kind := range tables {
+					if err := op.AppendRaw(kind, uint64(i), getChunk(32, i+fill)); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(f, 0, 200, 0)
+	if _, err := f.TruncateTail(10); err != nil {
+		t.Fatal(err)
+	}
+	// Items appended to the tables beyond the recorded count, as if written
+	// while copying them, must be truncated from the checkpoint.
+	for _, table := range f.tables {
+		batch := table.newBatch()
+		if err := batch.AppendRaw(200, getChunk(32, 200)); err != nil {
+			t.Fatal(err)
+		}
+		if err := batch.commit(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cpdir := filepath.Join(dir, "checkpoint")
+	if err := f.checkpoint(cpdir); err != nil {
+		t.Fatal(err)
+	}
+	// Truncating the original back into a hard-linked data file and appending
+	// new items must not modify the checkpoint.
+	if _, err := f.TruncateHead(50); err != nil {
+		t.Fatal(err)
+	}
+	write(f, 50, 100, 1)
+
+	cp, err := NewFreezer(cpdir, "", false, 2049, tables)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cp.Close()
+	checkAncientCount(t, cp, "raw", 200)
+	checkAncientCount(t, cp, "compressed", 200)
+	if tail, _ := cp.Tail(); tail != 10 {
+		t.Fatalf("Wrong tail in checkpoint: have %d, want 10", tail)
+	}
+	for i := 10; i < 200; i++ {
+		for kind := range tables {
+			blob, err := cp.Ancient(kind, uint64(i))
+			if err != nil || !bytes.Equal(blob, getChunk(32, i)) {
+				t.Fatalf("Wrong %s item %d in checkpoint: %x, %v", kind, i, blob, err)
+			}
+		}
+	}
+	// The checkpoint is usable independently of the original.
+	write(cp, 200, 250, 0)
+	checkAncientCount(t, f, "raw", 100)
+}
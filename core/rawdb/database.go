@@ -199,8 +199,14 @@ func resolveChainFreezerDir(ancient string) string {
 // storage. The passed ancient indicates the path of root ancient directory
 // where the chain freezer can be opened.
 func NewDatabaseWithFreezer(db ethdb.KeyValueStore, ancient string, namespace string, readonly bool) (ethdb.Database, error) {
+	return newDatabaseWithFreezer(db, ancient, namespace, readonly, false)
+}
+
+// newDatabaseWithFreezer creates a high level database with a chain freezer,
+// enabling checksums if the freezer is newly created and checksums is set.
+func newDatabaseWithFreezer(db ethdb.KeyValueStore, ancient string, namespace string, readonly bool, checksums bool) (ethdb.Database, error) {
 	// Create the idle freezer instance
-	frdb, err := newChainFreezer(resolveChainFreezerDir(ancient), namespace, readonly)
+	frdb, err := newChainFreezer(resolveChainFreezerDir(ancient), namespace, readonly, checksums)
 	if err != nil {
 		printChainMetadata(db)
 		return nil, err
@@ -298,6 +304,27 @@ func NewDatabaseWithFreezer(db ethdb.KeyValueStore, ancient string, namespace st
 	}, nil
 }
 
+// NewDatabaseWithSharedFreezer creates a read-only high level database on top
+// of a given key-value data store, using the chain freezer of another process
+// which keeps writing to it. The freezer is opened without taking its directory
+// lock, and its view is limited to the items present at the time of opening.
+func NewDatabaseWithSharedFreezer(db ethdb.KeyValueStore, ancient string, namespace string) (ethdb.Database, error) {
+	freezer, err := newFreezer(resolveChainFreezerDir(ancient), namespace, true, true, freezerTableSize, chainFreezerNoSnappy)
+	if err != nil {
+		return nil, err
+	}
+	frdb := &chainFreezer{
+		Freezer: freezer,
+		quit:    make(chan struct{}),
+		trigger: make(chan chan struct{}),
+	}
+	return &freezerdb{
+		ancientRoot:   ancient,
+		KeyValueStore: db,
+		AncientStore:  frdb,
+	}, nil
+}
+
 // NewMemoryDatabase creates an ephemeral in-memory key-value database without a
 // freezer moving immutable chain segments into cold storage.
 func NewMemoryDatabase() ethdb.Database {
@@ -366,6 +393,9 @@ type OpenOptions struct {
 	// Ephemeral means that filesystem sync operations should be avoided: data integrity in the face of
 	// a crash is not important. This option should typically be used in tests.
 	Ephemeral bool
+	// Checksums means that a newly created chain freezer stores the checksums
+	// of its items, which are verified on every read.
+	Checksums bool
 }
 
 // openKeyValueDatabase opens a disk-based key-value database, e.g. leveldb or pebble.
@@ -411,7 +441,7 @@ func Open(o OpenOptions) (ethdb.Database, error) {
 	if len(o.AncientsDirectory) == 0 {
 		return kvdb, nil
 	}
-	frdb, err := NewDatabaseWithFreezer(kvdb, o.AncientsDirectory, o.Namespace, o.ReadOnly)
+	frdb, err := newDatabaseWithFreezer(kvdb, o.AncientsDirectory, o.Namespace, o.ReadOnly, o.Checksums)
 	if err != nil {
 		kvdb.Close()
 		return nil, err
@@ -557,6 +587,7 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 				snapshotGeneratorKey, snapshotRecoveryKey, txIndexTailKey, fastTxLookupLimitKey,
 				uncleanShutdownKey, badBlockKey, transitionStatusKey, skeletonSyncStatusKey,
 				persistentStateIDKey, trieJournalKey, snapshotSyncStatusKey, snapSyncStatusFlagKey,
+				onlinePruningKey,
 			} {
 				if bytes.Equal(key, meta) {
 					metadata.Add(size)
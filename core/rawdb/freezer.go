@@ -88,6 +88,15 @@ func NewChainFreezer(datadir string, namespace string, readonly bool) (*Freezer,
 // The 'tables' argument defines the data tables. If the value of a map
 // entry is true, snappy compression is disabled for the table.
 func NewFreezer(datadir string, namespace string, readonly bool, maxTableSize uint32, tables map[string]bool) (*Freezer, error) {
+	return newFreezer(datadir, namespace, readonly, false, maxTableSize, tables)
+}
+
+// newFreezer creates a freezer instance. If shared is set, the freezer is opened
+// read-only without taking the directory lock, as it's in use by another process,
+// and the view of the freezer is limited to the items present in all tables at
+// the time of opening.
+func newFreezer(datadir string, namespace string, readonly bool, shared bool, maxTableSize uint32, tables map[string]bool) (*Freezer, error) {
+	readonly = readonly || shared
 	// Create the initial freezer object
 	var (
 		readMeter  = metrics.NewRegisteredMeter(namespace+"ancient/read", nil)
@@ -101,21 +110,24 @@ func NewFreezer(datadir string, namespace string, readonly bool, maxTableSize ui
 			return nil, errSymlinkDatadir
 		}
 	}
-	flockFile := filepath.Join(datadir, "FLOCK")
-	if err := os.MkdirAll(filepath.Dir(flockFile), 0755); err != nil {
-		return nil, err
-	}
-	// Leveldb uses LOCK as the filelock filename. To prevent the
-	// name collision, we use FLOCK as the lock name.
-	lock := flock.New(flockFile)
-	tryLock := lock.TryLock
-	if readonly {
-		tryLock = lock.TryRLock
-	}
-	if locked, err := tryLock(); err != nil {
-		return nil, err
-	} else if !locked {
-		return nil, errors.New("locking failed")
+	var lock *flock.Flock
+	if !shared {
+		flockFile := filepath.Join(datadir, "FLOCK")
+		if err := os.MkdirAll(filepath.Dir(flockFile), 0755); err != nil {
+			return nil, err
+		}
+		// Leveldb uses LOCK as the filelock filename. To prevent the
+		// name collision, we use FLOCK as the lock name.
+		lock = flock.New(flockFile)
+		tryLock := lock.TryLock
+		if readonly {
+			tryLock = lock.TryRLock
+		}
+		if locked, err := tryLock(); err != nil {
+			return nil, err
+		} else if !locked {
+			return nil, errors.New("locking failed")
+		}
 	}
 	// Open all the supported data tables
 	freezer := &Freezer{
@@ -131,13 +143,17 @@ func NewFreezer(datadir string, namespace string, readonly bool, maxTableSize ui
 			for _, table := range freezer.tables {
 				table.Close()
 			}
-			lock.Unlock()
+			freezer.unlock()
 			return nil, err
 		}
 		freezer.tables[name] = table
 	}
 	var err error
-	if freezer.readonly {
+	if shared {
+		// The tables may be appended to concurrently, use the boundaries
+		// reached by all of them.
+		freezer.limit()
+	} else if freezer.readonly {
 		// In readonly mode only validate, don't truncate.
 		// validate also sets `freezer.frozen`.
 		err = freezer.validate()
@@ -149,7 +165,7 @@ func NewFreezer(datadir string, namespace string, readonly bool, maxTableSize ui
 		for _, table := range freezer.tables {
 			table.Close()
 		}
-		lock.Unlock()
+		freezer.unlock()
 		return nil, err
 	}
 
@@ -172,7 +188,7 @@ func (f *Freezer) Close() error {
 				errs = append(errs, err)
 			}
 		}
-		if err := f.instanceLock.Unlock(); err != nil {
+		if err := f.unlock(); err != nil {
 			errs = append(errs, err)
 		}
 	})
@@ -335,6 +351,33 @@ func (f *Freezer) Sync() error {
 	return nil
 }
 
+// unlock releases the directory lock, if taken.
+func (f *Freezer) unlock() error {
+	if f.instanceLock == nil {
+		return nil
+	}
+	return f.instanceLock.Unlock()
+}
+
+// limit sets the boundaries of the freezer to the items present in all tables.
+// Used instead of `validate` for freezers shared with another process.
+func (f *Freezer) limit() {
+	var head, tail uint64
+	if len(f.tables) > 0 {
+		head = math.MaxUint64
+	}
+	for _, table := range f.tables {
+		if items := table.items.Load(); items < head {
+			head = items
+		}
+		if hidden := table.itemHidden.Load(); hidden > tail {
+			tail = hidden
+		}
+	}
+	f.frozen.Store(head)
+	f.tail.Store(tail)
+}
+
 // validate checks that every table has the same boundary.
 // Used instead of `repair` in readonly mode.
 func (f *Freezer) validate() error {
@@ -396,6 +439,28 @@ func (f *Freezer) repair() error {
 	return nil
 }
 
+// enableChecksums configures all tables to store the checksums of their items,
+// if the freezer is still empty. Tables with items can only be given checksums
+// by migrating them.
+func (f *Freezer) enableChecksums() error {
+	f.writeLock.Lock()
+	defer f.writeLock.Unlock()
+
+	if f.frozen.Load() != 0 || f.tail.Load() != 0 {
+		return nil
+	}
+	for _, table := range f.tables {
+		if table.checksums {
+			continue
+		}
+		if err := table.setChecksums(); err != nil {
+			return err
+		}
+	}
+	f.writeBatch = newFreezerBatch(f)
+	return nil
+}
+
 // convertLegacyFn takes a raw freezer entry in an older format and
 // returns it in the new format.
 type convertLegacyFn = func([]byte) ([]byte, error)
@@ -413,6 +478,63 @@ func (f *Freezer) MigrateTable(kind string, convert convertLegacyFn) error {
 	if !ok {
 		return errUnknownTable
 	}
+	return migrateTable(table, kind, nil, convert)
+}
+
+// RecompressTable re-encodes the entries of a given table with a different
+// compression codec, either "snappy" or "zstd", or the current one if codec is
+// empty. Zstd tables are compressed with a dictionary of dictSize bytes trained
+// on the existing entries. If checksums is set, checksums of the entries are
+// added to the index of the table.
+func (f *Freezer) RecompressTable(kind string, codec string, dictSize int, checksums bool) error {
+	if f.readonly {
+		return errReadOnly
+	}
+	f.writeLock.Lock()
+	defer f.writeLock.Unlock()
+
+	table, ok := f.tables[kind]
+	if !ok {
+		return errUnknownTable
+	}
+	var id uint8
+	switch {
+	case codec == "":
+		if table.zstd != nil {
+			id = codecZstd
+		}
+	case table.noCompression:
+		return errors.New("compression is disabled for the table")
+	default:
+		var err error
+		if id, err = parseCodec(codec); err != nil {
+			return err
+		}
+	}
+	size, err := table.size()
+	if err != nil {
+		return err
+	}
+	if err := recompressTable(table, kind, id, dictSize, checksums); err != nil {
+		return err
+	}
+	// Reopen the table to pick up the rewritten files and codec.
+	table.Close()
+	table.sizeGauge.Dec(int64(size))
+
+	reopened, err := newTable(table.path, kind, table.readMeter, table.writeMeter, table.sizeGauge, table.maxFileSize, table.noCompression, false)
+	if err != nil {
+		return err
+	}
+	f.tables[kind] = reopened
+	f.writeBatch = newFreezerBatch(f)
+	return nil
+}
+
+// migrateTable rewrites all entries of a table, passing them through convert.
+// The rewritten table is created in a separate directory, is initialized by
+// the optional setup function and replaces the original one at the end.
+func migrateTable(table *freezerTable, kind string, setup func(*freezerTable) error, convert convertLegacyFn) error {
 	// forEach iterates every entry in the table serially and in order, calling `fn`
 	// with the item as argument. If `fn` returns an error the iteration stops
 	// and that error will be returned.
@@ -439,11 +561,6 @@ func (f *Freezer) MigrateTable(kind string, convert convertLegacyFn) error {
 		}
 		return nil
 	}
-	// TODO(s1na): This is a sanity-check since as of now no process does tail-deletion. But the migration
-	// process assumes no deletion at tail and needs to be modified to account for that.
-	if table.itemOffset.Load() > 0 || table.itemHidden.Load() > 0 {
-		return errors.New("migration not supported for tail-deleted freezers")
-	}
 	ancientsPath := filepath.Dir(table.index.Name())
 	// Set up new dir for the migrated table, the content of which
 	// we'll at the end move over to the ancients dir.
@@ -452,6 +569,25 @@ func (f *Freezer) MigrateTable(kind string, convert convertLegacyFn) error {
 	if err != nil {
 		return err
 	}
+	if setup != nil {
+		if err := setup(newTable); err != nil {
+			newTable.Close()
+			return err
+		}
+	}
+	// Items deleted from the tail are not carried over, the rewritten table
+	// starts with the first retained item instead.
+	tail := table.itemHidden.Load()
+	if newTable.items.Load() == 0 && tail > 0 {
+		if err := newTable.setTail(tail); err != nil {
+			newTable.Close()
+			return err
+		}
+	}
+	if newTable.itemHidden.Load() != tail {
+		newTable.Close()
+		return errors.New("previous migration with a different tail found")
+	}
 	var (
 		batch  = newTable.newBatch()
 		out    []byte
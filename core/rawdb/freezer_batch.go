@@ -18,6 +18,7 @@ package rawdb
 
 import (
 	"fmt"
+	"hash/crc32"
 
 	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/rlp"
@@ -85,6 +86,7 @@ type freezerTableBatch struct {
 	t *freezerTable
 
 	sb          *snappyBuffer
+	zb          *zstdBuffer
 	encBuffer   writeBuffer
 	dataBuffer  []byte
 	indexBuffer []byte
@@ -95,7 +97,9 @@ type freezerTableBatch struct {
 // newBatch creates a new batch for the freezer table.
 func (t *freezerTable) newBatch() *freezerTableBatch {
 	batch := &freezerTableBatch{t: t}
-	if !t.noCompression {
+	if t.zstd != nil {
+		batch.zb = &zstdBuffer{enc: t.zstd.enc}
+	} else if !t.noCompression {
 		batch.sb = new(snappyBuffer)
 	}
 	batch.reset()
@@ -123,11 +127,7 @@ func (batch *freezerTableBatch) Append(item uint64, data interface{}) error {
 	if err := rlp.Encode(&batch.encBuffer, data); err != nil {
 		return err
 	}
-	encItem := batch.encBuffer.data
-	if batch.sb != nil {
-		encItem = batch.sb.compress(encItem)
-	}
-	return batch.appendItem(encItem)
+	return batch.appendItem(batch.compress(batch.encBuffer.data))
 }
 
 // AppendRaw injects a binary blob at the end of the freezer table. The item number is a
@@ -138,11 +138,19 @@ func (batch *freezerTableBatch) AppendRaw(item uint64, blob []byte) error {
 		return fmt.Errorf("%w: have %d want %d", errOutOrderInsertion, item, batch.curItem)
 	}
 
-	encItem := blob
-	if batch.sb != nil {
-		encItem = batch.sb.compress(blob)
+	return batch.appendItem(batch.compress(blob))
+}
+
+// compress compresses an item with the codec of the table.
+func (batch *freezerTableBatch) compress(data []byte) []byte {
+	switch {
+	case batch.zb != nil:
+		return batch.zb.compress(data)
+	case batch.sb != nil:
+		return batch.sb.compress(data)
+	default:
+		return data
 	}
-	return batch.appendItem(encItem)
 }
 
 func (batch *freezerTableBatch) appendItem(data []byte) error {
@@ -166,7 +174,10 @@ func (batch *freezerTableBatch) appendItem(data []byte) error {
 
 	// Put index entry to buffer.
 	entry := indexEntry{filenum: batch.t.headId, offset: uint32(itemOffset + itemSize)}
-	batch.indexBuffer = entry.append(batch.indexBuffer)
+	if batch.t.checksums {
+		entry.checksum = crc32.Checksum(data, checksumTable)
+	}
+	batch.indexBuffer = batch.t.appendIndex(batch.indexBuffer, &entry)
 	batch.curItem++
 
 	return batch.maybeCommit()
@@ -0,0 +1,223 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/klauspost/compress/dict"
+	"github.com/klauspost/compress/zstd"
+)
+
+// The compression codecs of freezer tables, as recorded in the table metadata.
+// Compressed tables without a codec in their metadata use snappy.
+const (
+	codecSnappy uint8 = iota
+	codecZstd
+)
+
+const (
+	// ZstdDictSize is the default size of trained zstd dictionaries.
+	ZstdDictSize = 112 * 1024
+
+	// zstdDictSamples is the number of items sampled to train a dictionary.
+	zstdDictSamples = 8192
+)
+
+// parseCodec converts the name of a codec to its metadata value.
+func parseCodec(name string) (uint8, error) {
+	switch name {
+	case "snappy":
+		return codecSnappy, nil
+	case "zstd":
+		return codecZstd, nil
+	default:
+		return 0, fmt.Errorf("unknown codec %q, supported ones: [snappy zstd]", name)
+	}
+}
+
+// zstdCodec compresses the items of a freezer table with zstd, optionally using
+// a dictionary trained on items of the table. As items are compressed one by one,
+// a dictionary is what allows zstd to exploit the redundancy across items.
+//
+// Both the encoder and the decoder are only used through EncodeAll and DecodeAll,
+// which are safe for concurrent use.
+type zstdCodec struct {
+	dict []byte
+	enc  *zstd.Encoder
+	dec  *zstd.Decoder
+}
+
+// newZstdCodec creates a zstd codec using the given dictionary, which may be
+// empty for compressing without a dictionary.
+func newZstdCodec(dict []byte) (*zstdCodec, error) {
+	// Items are compressed as single segment frames, which always carry the
+	// decompressed size in the header. Checksums are omitted, snappy items
+	// don't carry them either.
+	var (
+		eopts = []zstd.EOption{
+			zstd.WithEncoderConcurrency(1),
+			zstd.WithEncoderCRC(false),
+			zstd.WithSingleSegment(true),
+		}
+		dopts = []zstd.DOption{zstd.WithDecoderConcurrency(0)}
+	)
+	if len(dict) > 0 {
+		eopts = append(eopts, zstd.WithEncoderDict(dict))
+		dopts = append(dopts, zstd.WithDecoderDicts(dict))
+	}
+	enc, err := zstd.NewWriter(nil, eopts...)
+	if err != nil {
+		return nil, err
+	}
+	dec, err := zstd.NewReader(nil, dopts...)
+	if err != nil {
+		return nil, err
+	}
+	return &zstdCodec{dict: dict, enc: enc, dec: dec}, nil
+}
+
+// decodedLen returns the decompressed size of an item, which is stored in the
+// frame header.
+func (c *zstdCodec) decodedLen(item []byte) int {
+	var header zstd.Header
+	if err := header.Decode(item); err != nil || !header.HasFCS {
+		return len(item)
+	}
+	return int(header.FrameContentSize)
+}
+
+// decode decompresses an item.
+func (c *zstdCodec) decode(item []byte) ([]byte, error) {
+	return c.dec.DecodeAll(item, nil)
+}
+
+// close releases the resources held by the encoder and decoder.
+func (c *zstdCodec) close() {
+	c.enc.Close()
+	c.dec.Close()
+}
+
+// zstdBuffer compresses items with zstd into a reusable buffer.
+type zstdBuffer struct {
+	enc *zstd.Encoder
+	dst []byte
+}
+
+// compress zstd-compresses the data.
+func (z *zstdBuffer) compress(data []byte) []byte {
+	z.dst = z.enc.EncodeAll(data, z.dst[:0])
+	return z.dst
+}
+
+// setZstd configures the table to compress items with zstd and the given
+// dictionary. It is only permitted on empty tables, as the codec does not
+// work retroactively.
+func (t *freezerTable) setZstd(dict []byte) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.noCompression {
+		return errors.New("compression is disabled for the table")
+	}
+	if t.items.Load() != 0 || t.itemHidden.Load() != 0 {
+		return errors.New("codec can only be changed on empty tables")
+	}
+	codec, err := newZstdCodec(dict)
+	if err != nil {
+		return err
+	}
+	if t.zstd != nil {
+		t.zstd.close()
+	}
+	t.zstd = codec
+	return writeMetadata(t.meta, t.metadata(0))
+}
+
+// sampleItems retrieves up to n items sampled evenly across the table.
+func sampleItems(t *freezerTable, n uint64) ([][]byte, error) {
+	var (
+		tail  = t.itemHidden.Load()
+		head  = t.items.Load()
+		step  = uint64(1)
+		items [][]byte
+	)
+	if head-tail > n {
+		step = (head - tail + n - 1) / n
+	}
+	for i := tail; i < head; i += step {
+		item, err := t.Retrieve(i)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// trainZstdDict trains a zstd dictionary of the given size on sample items.
+func trainZstdDict(samples [][]byte, size int) ([]byte, error) {
+	return dict.BuildZstdDict(samples, dict.Options{
+		MaxDictSize: size,
+		HashBytes:   6,
+		ZstdLevel:   zstd.SpeedDefault,
+	})
+}
+
+// recompressTable re-encodes all items of a table with the given codec, and
+// optionally adds checksums to the index. Zstd tables are compressed with a
+// dictionary of the given size trained on the existing items, or without a
+// dictionary if the size is zero or the table holds too little data to train
+// one.
+func recompressTable(table *freezerTable, kind string, codec uint8, dictSize int, checksums bool) error {
+	checksums = checksums || table.checksums
+
+	setup := func(newTable *freezerTable) error {
+		// A non-empty table is the leftover of an interrupted migration,
+		// which is resumed with the configuration it was started with.
+		if newTable.items.Load() > 0 {
+			if (newTable.zstd != nil) != (codec == codecZstd) || newTable.checksums != checksums {
+				return errors.New("previous migration with a different configuration found")
+			}
+			return nil
+		}
+		if checksums {
+			if err := newTable.setChecksums(); err != nil {
+				return err
+			}
+		}
+		if codec != codecZstd {
+			return nil
+		}
+		var dict []byte
+		if dictSize > 0 {
+			samples, err := sampleItems(table, zstdDictSamples)
+			if err != nil {
+				return err
+			}
+			log.Info("Training zstd dictionary", "table", kind, "samples", len(samples), "size", dictSize)
+			if dict, err = trainZstdDict(samples, dictSize); err != nil {
+				log.Warn("Failed to train zstd dictionary, compressing without", "table", kind, "err", err)
+				dict = nil
+			}
+		}
+		return newTable.setZstd(dict)
+	}
+	return migrateTable(table, kind, setup, func(blob []byte) ([]byte, error) { return blob, nil })
+}
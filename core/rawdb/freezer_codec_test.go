@@ -0,0 +1,389 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// makeReceiptItems generates n receipt lists in storage encoding, resembling
+// the contents of the receipts table: logs of a few popular contracts with
+// recurring topics.
+func makeReceiptItems(n int) [][]byte {
+	var (
+		rng       = rand.New(rand.NewSource(1))
+		addresses = make([]common.Address, 16)
+		topics    = make([]common.Hash, 32)
+		items     = make([][]byte, n)
+		gas       uint64
+	)
+	for i := range addresses {
+		rng.Read(addresses[i][:])
+	}
+	for i := range topics {
+		rng.Read(topics[i][:])
+	}
+	for i := range items {
+		receipts := make([]*types.ReceiptForStorage, rng.Intn(8))
+		for j := range receipts {
+			gas += uint64(21000 + rng.Intn(100000))
+			receipt := &types.Receipt{Status: types.ReceiptStatusSuccessful, CumulativeGasUsed: gas}
+			for k := rng.Intn(4); k > 0; k-- {
+				log := &types.Log{
+					Address: addresses[rng.Intn(len(addresses))],
+					Topics:  []common.Hash{topics[rng.Intn(len(topics))], {}},
+					Data:    make([]byte, 32*rng.Intn(3)),
+				}
+				// Addresses in topics and small amounts in data are common
+				rng.Read(log.Topics[1][12:])
+				for l := 0; l < len(log.Data); l += 32 {
+					rng.Read(log.Data[l+24 : l+32])
+				}
+				receipt.Logs = append(receipt.Logs, log)
+			}
+			receipts[j] = (*types.ReceiptForStorage)(receipt)
+		}
+		items[i], _ = rlp.EncodeToBytes(receipts)
+	}
+	return items
+}
+
+func checkTableItems(t *testing.T, table *freezerTable, items [][]byte, from uint64) {
+	t.Helper()
+
+	for i := from; i < uint64(len(items)); i++ {
+		have, err := table.Retrieve(i)
+		if err != nil {
+			t.Fatalf("Failed to retrieve item %d: %v", i, err)
+		}
+		if !bytes.Equal(have, items[i]) {
+			t.Fatalf("Item %d mismatch: have %x, want %x", i, have, items[i])
+		}
+	}
+}
+
+func TestFreezerTableZstd(t *testing.T) {
+	var (
+		dir   = t.TempDir()
+		items = makeReceiptItems(200)
+	)
+	table, err := newTable(dir, "receipts", metrics.NilMeter{}, metrics.NilMeter{}, metrics.NilGauge{}, 4096, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dict, err := trainZstdDict(items, 8192)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := table.setZstd(dict); err != nil {
+		t.Fatal(err)
+	}
+	batch := table.newBatch()
+	for i, item := range items[:100] {
+		if err := batch.AppendRaw(uint64(i), item); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := batch.commit(); err != nil {
+		t.Fatal(err)
+	}
+	if err := table.setZstd(nil); err == nil {
+		t.Fatal("Changed codec of non-empty table")
+	}
+	checkTableItems(t, table, items[:100], 0)
+	table.Close()
+
+	// Reopen the table and ensure the codec is restored from the metadata.
+	table, err = newTable(dir, "receipts", metrics.NilMeter{}, metrics.NilMeter{}, metrics.NilGauge{}, 4096, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table.zstd == nil || !bytes.Equal(table.zstd.dict, dict) {
+		t.Fatal("Codec not restored")
+	}
+	batch = table.newBatch()
+	for i, item := range items[100:] {
+		if err := batch.AppendRaw(uint64(100+i), item); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := batch.commit(); err != nil {
+		t.Fatal(err)
+	}
+	checkTableItems(t, table, items, 0)
+
+	// Deleting items from the tail must retain the codec.
+	if err := table.truncateTail(50); err != nil {
+		t.Fatal(err)
+	}
+	table.Close()
+
+	table, err = newTable(dir, "receipts", metrics.NilMeter{}, metrics.NilMeter{}, metrics.NilGauge{}, 4096, false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer table.Close()
+	if table.zstd == nil {
+		t.Fatal("Codec lost after tail truncation")
+	}
+	checkTableItems(t, table, items, 50)
+
+	// Check the maxBytes limit is applied to decompressed sizes.
+	limit := uint64(len(items[60]) + len(items[61]))
+	if have, err := table.RetrieveItems(60, 10, limit); err != nil {
+		t.Fatal(err)
+	} else if len(have) != 2 {
+		t.Fatalf("Wrong number of items retrieved: have %d, want 2", len(have))
+	}
+}
+
+func TestFreezerTableZstdUncompressed(t *testing.T) {
+	table, err := newTable(t.TempDir(), "hashes", metrics.NilMeter{}, metrics.NilMeter{}, metrics.NilGauge{}, 4096, true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer table.Close()
+	if err := table.setZstd(nil); err == nil {
+		t.Fatal("Configured zstd for uncompressed table")
+	}
+}
+
+func TestFreezerRecompress(t *testing.T) {
+	var (
+		tables = map[string]bool{"receipts": false, "hashes": true}
+		items  = makeReceiptItems(300)
+	)
+	f, dir := newFreezerForTesting(t, tables)
+
+	write := func(from, to int) {
+		t.Helper()
+		_, err := f.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+			for i := from; i < to; i++ {
+				if err := op.AppendRaw("receipts", uint64(i), items[i]); err != nil {
+					return err
+				}
+				if err := op.AppendRaw("hashes", uint64(i), common.Hash{byte(i)}.Bytes()); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(0, 200)
+
+	if err := f.RecompressTable("hashes", "zstd", 0, false); err == nil {
+		t.Fatal("Recompressed uncompressed table")
+	}
+	if err := f.RecompressTable("receipts", "lz4", 0, false); err == nil {
+		t.Fatal("Recompressed with unknown codec")
+	}
+	snappySize, _ := f.tables["receipts"].size()
+	if err := f.RecompressTable("receipts", "zstd", 8192, false); err != nil {
+		t.Fatal(err)
+	}
+	if f.tables["receipts"].zstd == nil {
+		t.Fatal("Table not switched to zstd")
+	}
+	zstdSize, _ := f.tables["receipts"].size()
+	if zstdSize >= snappySize {
+		t.Errorf("Zstd table not smaller than snappy: %d >= %d", zstdSize, snappySize)
+	}
+	checkTableItems(t, f.tables["receipts"], items[:200], 0)
+
+	// Further appends use the new codec, also after reopening.
+	write(200, 250)
+	f.Close()
+
+	f, err := NewFreezer(dir, "", false, 2049, tables)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	write(250, 300)
+	checkTableItems(t, f.tables["receipts"], items, 0)
+
+	// Convert back to snappy.
+	if err := f.RecompressTable("receipts", "snappy", 0, false); err != nil {
+		t.Fatal(err)
+	}
+	if f.tables["receipts"].zstd != nil {
+		t.Fatal("Table not switched to snappy")
+	}
+	checkTableItems(t, f.tables["receipts"], items, 0)
+
+	// Add checksums to an uncompressed table, keeping its format.
+	if err := f.RecompressTable("hashes", "", 0, true); err != nil {
+		t.Fatal(err)
+	}
+	if !f.tables["hashes"].checksums {
+		t.Fatal("Checksums not enabled")
+	}
+	for i := 0; i < 300; i++ {
+		if blob, err := f.Ancient("hashes", uint64(i)); err != nil || blob[0] != byte(i) {
+			t.Fatalf("Wrong item %d: %x, %v", i, blob, err)
+		}
+	}
+}
+
+func TestFreezerRecompressTail(t *testing.T) {
+	var (
+		tables = map[string]bool{"receipts": false}
+		items  = makeReceiptItems(200)
+	)
+	f, dir := newFreezerForTesting(t, tables)
+
+	_, err := f.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		for i, item := range items {
+			if err := op.AppendRaw("receipts", uint64(i), item); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.TruncateTail(123); err != nil {
+		t.Fatal(err)
+	}
+	if f.tables["receipts"].itemOffset.Load() == 0 {
+		t.Fatal("No data files deleted from the tail")
+	}
+	if err := f.RecompressTable("receipts", "zstd", 0, true); err != nil {
+		t.Fatal(err)
+	}
+	check := func() {
+		t.Helper()
+		if tail, _ := f.Tail(); tail != 123 {
+			t.Fatalf("Wrong tail: have %d, want 123", tail)
+		}
+		if frozen, _ := f.Ancients(); frozen != 200 {
+			t.Fatalf("Wrong head: have %d, want 200", frozen)
+		}
+		if _, err := f.Ancient("receipts", 122); err == nil {
+			t.Fatal("Retrieved deleted item")
+		}
+		checkTableItems(t, f.tables["receipts"], items, 123)
+	}
+	check()
+
+	// The tail must survive reopening the freezer.
+	f.Close()
+	if f, err = NewFreezer(dir, "", false, 2049, tables); err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	check()
+}
+
+func TestSampleItems(t *testing.T) {
+	table, err := newTable(t.TempDir(), "receipts", metrics.NilMeter{}, metrics.NilMeter{}, metrics.NilGauge{}, freezerTableSize, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer table.Close()
+
+	batch := table.newBatch()
+	for i, item := range makeReceiptItems(199) {
+		if err := batch.AppendRaw(uint64(i), item); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := batch.commit(); err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range []uint64{1, 10, 100, 199, 500} {
+		samples, err := sampleItems(table, n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := n
+		if want > 199 {
+			want = 199
+		}
+		if uint64(len(samples)) > want || len(samples) < int(want)/2 {
+			t.Errorf("Wrong number of samples for %d: have %d", n, len(samples))
+		}
+	}
+}
+
+// BenchmarkFreezerTableCodecs compares the stored size and the read latency of
+// receipt-like items across the compression codecs.
+func BenchmarkFreezerTableCodecs(b *testing.B) {
+	var (
+		items = makeReceiptItems(10000)
+		raw   int
+	)
+	for _, item := range items {
+		raw += len(item)
+	}
+	dict, err := trainZstdDict(items[:zstdDictSamples], ZstdDictSize)
+	if err != nil {
+		b.Fatal(err)
+	}
+	codecs := []struct {
+		name  string
+		setup func(*freezerTable) error
+	}{
+		{"snappy", func(t *freezerTable) error { return nil }},
+		{"zstd", func(t *freezerTable) error { return t.setZstd(nil) }},
+		{"zstd-dict", func(t *freezerTable) error { return t.setZstd(dict) }},
+	}
+	for _, codec := range codecs {
+		b.Run(codec.name, func(b *testing.B) {
+			table, err := newTable(b.TempDir(), "receipts", metrics.NilMeter{}, metrics.NilMeter{}, metrics.NilGauge{}, freezerTableSize, false, false)
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer table.Close()
+			if err := codec.setup(table); err != nil {
+				b.Fatal(err)
+			}
+			batch := table.newBatch()
+			for i, item := range items {
+				if err := batch.AppendRaw(uint64(i), item); err != nil {
+					b.Fatal(err)
+				}
+			}
+			if err := batch.commit(); err != nil {
+				b.Fatal(err)
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := table.Retrieve(uint64(i % len(items))); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.StopTimer()
+
+			size, _ := table.size()
+			b.ReportMetric(float64(size)/float64(len(items)), "bytes/item")
+			b.ReportMetric(float64(raw)/float64(size), "ratio")
+		})
+	}
+}
@@ -24,7 +24,11 @@ import (
 	"github.com/ethereum/go-ethereum/rlp"
 )
 
-const freezerVersion = 1 // The initial version tag of freezer table metadata
+const (
+	freezerVersion         = 1 // The initial version tag of freezer table metadata
+	freezerVersionCodec    = 2 // The version tag of metadata carrying a compression codec
+	freezerVersionChecksum = 3 // The version tag of tables with item checksums in the index
+)
 
 // freezerTableMeta wraps all the metadata of the freezer table.
 type freezerTableMeta struct {
@@ -36,6 +40,17 @@ type freezerTableMeta struct {
 	// plus the number of items hidden in the table, so it should never
 	// be lower than the "actual tail".
 	VirtualTail uint64
+
+	// Codec is the compression codec of the table, and Dict the dictionary
+	// the codec was configured with. Both are only present in metadata of
+	// version 2 and above, which can't be decoded by older versions.
+	Codec uint8  `rlp:"optional"`
+	Dict  []byte `rlp:"optional"`
+
+	// Checksums indicates that the index entries carry a checksum of the
+	// items, which changes the index format. Only present in metadata of
+	// version 3 and above.
+	Checksums bool `rlp:"optional"`
 }
 
 // newMetadata initializes the metadata object with the given virtual tail.
@@ -17,8 +17,11 @@
 package rawdb
 
 import (
+	"bytes"
 	"os"
 	"testing"
+
+	"github.com/ethereum/go-ethereum/rlp"
 )
 
 func TestReadWriteFreezerTableMeta(t *testing.T) {
@@ -58,3 +61,31 @@ func TestInitializeFreezerTableMeta(t *testing.T) {
 		t.Fatalf("Unexpected virtual tail field")
 	}
 }
+
+func TestFreezerTableMetaCodec(t *testing.T) {
+	// Metadata without a codec must keep the version 1 encoding.
+	legacy, _ := rlp.EncodeToBytes([]uint64{freezerVersion, 100})
+	enc, _ := rlp.EncodeToBytes(newMetadata(100))
+	if !bytes.Equal(enc, legacy) {
+		t.Fatalf("Unexpected version 1 encoding: have %x, want %x", enc, legacy)
+	}
+	f, err := os.CreateTemp(os.TempDir(), "*")
+	if err != nil {
+		t.Fatalf("Failed to create file %v", err)
+	}
+	m := newMetadata(100)
+	m.Version, m.Codec, m.Dict = freezerVersionCodec, codecZstd, []byte{1, 2, 3}
+	if err := writeMetadata(f, m); err != nil {
+		t.Fatalf("Failed to write metadata %v", err)
+	}
+	meta, err := loadMetadata(f, 50)
+	if err != nil {
+		t.Fatalf("Failed to read metadata %v", err)
+	}
+	if meta.Version != freezerVersionCodec || meta.VirtualTail != 100 {
+		t.Fatalf("Unexpected metadata %+v", meta)
+	}
+	if meta.Codec != codecZstd || !bytes.Equal(meta.Dict, []byte{1, 2, 3}) {
+		t.Fatalf("Unexpected codec %d, dict %x", meta.Codec, meta.Dict)
+	}
+}
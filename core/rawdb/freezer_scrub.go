@@ -0,0 +1,282 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/golang/snappy"
+)
+
+const (
+	scrubBatchItems = 1024            // Number of items read at once while scrubbing
+	scrubBatchBytes = 4 * 1024 * 1024 // Number of bytes read at once while scrubbing
+)
+
+// FreezerCorruption describes a range of corrupted items in a freezer table.
+type FreezerCorruption struct {
+	Table string // Name of the table
+	Start uint64 // Number of the first corrupted item
+	End   uint64 // Number of the last corrupted item
+	Err   error  // Error reported for the first corrupted item
+}
+
+// verifyItem checks that a stored item can be decompressed.
+func (t *freezerTable) verifyItem(item []byte) error {
+	var err error
+	switch {
+	case t.zstd != nil:
+		_, err = t.zstd.decode(item)
+	case !t.noCompression:
+		_, err = snappy.Decode(nil, item)
+	}
+	return err
+}
+
+// scrub reads all items of the table, verifying their checksums if the table
+// has any and their compression, and returns the ranges of corrupted items.
+func (t *freezerTable) scrub() ([]FreezerCorruption, error) {
+	var (
+		tail = t.itemHidden.Load()
+		head = t.items.Load()
+
+		corruptions []FreezerCorruption
+		start       = time.Now()
+		logged      = time.Now()
+	)
+	report := func(item uint64, err error) {
+		if n := len(corruptions); n > 0 && corruptions[n-1].End+1 == item {
+			corruptions[n-1].End = item
+			return
+		}
+		t.logger.Warn("Found corrupted freezer item", "item", item, "err", err)
+		corruptions = append(corruptions, FreezerCorruption{Table: t.name, Start: item, End: item, Err: err})
+	}
+	check := func(item uint64, blob []byte) {
+		if err := t.verifyItem(blob); err != nil {
+			report(item, err)
+		}
+	}
+	for i := tail; i < head; {
+		if time.Since(logged) > 8*time.Second {
+			t.logger.Info("Scrubbing freezer table", "item", i, "head", head, "elapsed", time.Since(start))
+			logged = time.Now()
+		}
+		data, sizes, err := t.retrieveItems(i, scrubBatchItems, scrubBatchBytes)
+		if errors.Is(err, errClosed) {
+			return nil, err
+		}
+		if err != nil {
+			// Locate the corrupted items of the batch one by one.
+			end := i + scrubBatchItems
+			if end > head {
+				end = head
+			}
+			for ; i < end; i++ {
+				data, sizes, err := t.retrieveItems(i, 1, 0)
+				if errors.Is(err, errClosed) {
+					return nil, err
+				}
+				if err != nil {
+					report(i, err)
+					continue
+				}
+				check(i, data[:sizes[0]])
+			}
+			continue
+		}
+		var offset int
+		for _, size := range sizes {
+			check(i, data[offset:offset+size])
+			offset += size
+			i++
+		}
+	}
+	return corruptions, nil
+}
+
+// scrub verifies all items of all tables concurrently, and returns the ranges
+// of corrupted items ordered by table and item number.
+func (f *Freezer) scrub() ([]FreezerCorruption, error) {
+	f.writeLock.RLock()
+	defer f.writeLock.RUnlock()
+
+	var (
+		wg          sync.WaitGroup
+		lock        sync.Mutex
+		corruptions []FreezerCorruption
+		errs        []error
+	)
+	for _, table := range f.tables {
+		wg.Add(1)
+		go func(table *freezerTable) {
+			defer wg.Done()
+
+			result, err := table.scrub()
+			lock.Lock()
+			defer lock.Unlock()
+			if err != nil {
+				errs = append(errs, err)
+			}
+			corruptions = append(corruptions, result...)
+		}(table)
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("%v", errs)
+	}
+	sort.Slice(corruptions, func(i, j int) bool {
+		if corruptions[i].Table != corruptions[j].Table {
+			return corruptions[i].Table < corruptions[j].Table
+		}
+		return corruptions[i].Start < corruptions[j].Start
+	})
+	return corruptions, nil
+}
+
+// Scrub verifies all items of the freezer and returns the ranges of corrupted
+// items. Items are checked against the checksums in the index if the table
+// has them, and for being correctly compressed. If repair is set, the freezer
+// is truncated to the first corrupted item, dropping all items after it.
+func (f *Freezer) Scrub(repair bool) ([]FreezerCorruption, error) {
+	if repair && f.readonly {
+		return nil, errReadOnly
+	}
+	corruptions, err := f.scrub()
+	if err != nil || !repair || len(corruptions) == 0 {
+		return corruptions, err
+	}
+	first := FirstCorruptedItem(corruptions)
+	log.Warn("Truncating freezer to remove corrupted items", "items", f.frozen.Load(), "limit", first)
+	if _, err := f.TruncateHead(first); err != nil {
+		return corruptions, err
+	}
+	return corruptions, nil
+}
+
+// FirstCorruptedItem returns the number of the first corrupted item of all the
+// given corruptions, which must not be empty.
+func FirstCorruptedItem(corruptions []FreezerCorruption) uint64 {
+	first := corruptions[0].Start
+	for _, c := range corruptions[1:] {
+		if c.Start < first {
+			first = c.Start
+		}
+	}
+	return first
+}
+
+// ScrubFreezer verifies all items of the given freezer, and returns the ranges
+// of corrupted items. The passed ancient indicates the path of root ancient
+// directory where the chain freezer can be opened. If repair is set, the
+// freezer is truncated to the first corrupted item. The chain freezer can't be
+// repaired this way, as the key-value store has to be rewound along with it,
+// see RewindChainFreezer.
+func ScrubFreezer(ancient string, freezerName string, repair bool) ([]FreezerCorruption, error) {
+	if repair && freezerName == ChainFreezerName {
+		return nil, errors.New("chain freezer can't be repaired without the key-value store")
+	}
+	path, tables, err := freezerTables(ancient, freezerName)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	f, err := NewFreezer(path, "", !repair, freezerTableSize, tables)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return f.Scrub(repair)
+}
+
+// RewindChainFreezer removes the blocks from the given number onwards from the
+// chain freezer of the database. The head markers and the canonical chain of
+// the key-value store are rewound to the block before first, so that the
+// database stays contiguous and the removed blocks are synced again.
+func RewindChainFreezer(db ethdb.Database, first uint64) error {
+	if first == 0 {
+		return errors.New("genesis block can't be removed")
+	}
+	frozen, err := db.Ancients()
+	if err != nil {
+		return err
+	}
+	if first >= frozen {
+		return nil
+	}
+	hash := ReadCanonicalHash(db, first-1)
+	if hash == (common.Hash{}) {
+		return fmt.Errorf("canonical hash of block %d not found", first-1)
+	}
+	head := frozen - 1
+	if number := ReadHeaderNumber(db, ReadHeadHeaderHash(db)); number != nil && *number > head {
+		head = *number
+	}
+	log.Warn("Rewinding chain to remove corrupted ancient blocks", "head", head, "target", first-1)
+
+	// Rewind the head markers first, the removed blocks are not referenced
+	// anymore even if the cleanup below is interrupted.
+	batch := db.NewBatch()
+	WriteHeadHeaderHash(batch, hash)
+	WriteHeadFastBlockHash(batch, hash)
+	WriteHeadBlockHash(batch, hash)
+	if number := ReadHeaderNumber(db, ReadFinalizedBlockHash(db)); number != nil && *number >= first {
+		WriteFinalizedBlockHash(batch, hash)
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	batch.Reset()
+
+	// Remove the canonical blocks above the new head, following the rules of
+	// the blockchain rewinding: blocks in the key-value store are deleted, and
+	// of frozen blocks only the hash to number mapping is left to delete.
+	for number := head; number >= first; number-- {
+		hash := ReadCanonicalHash(db, number)
+		if hash != (common.Hash{}) {
+			if number >= frozen {
+				DeleteBlock(batch, hash, number)
+				DeleteCanonicalHash(batch, number)
+			} else {
+				DeleteHeaderNumber(batch, hash)
+			}
+		}
+		if batch.ValueSize() > ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	_, err = db.TruncateHead(first)
+	return err
+}
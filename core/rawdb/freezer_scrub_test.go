@@ -0,0 +1,295 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"errors"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// corruptItem flips a bit in the stored data of an item.
+func corruptItem(t *testing.T, table *freezerTable, item uint64) {
+	t.Helper()
+
+	indices, err := table.getIndices(item, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start, _, filenum := indices[0].bounds(indices[1])
+	f, err := os.OpenFile(table.files[filenum].Name(), os.O_RDWR, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	b := make([]byte, 1)
+	if _, err := f.ReadAt(b, int64(start)); err != nil {
+		t.Fatal(err)
+	}
+	b[0] ^= 0x01
+	if _, err := f.WriteAt(b, int64(start)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFreezerTableChecksums(t *testing.T) {
+	dir := t.TempDir()
+	open := func() *freezerTable {
+		table, err := newTable(dir, "table", metrics.NilMeter{}, metrics.NilMeter{}, metrics.NilGauge{}, 50, true, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return table
+	}
+	table := open()
+	if err := table.setChecksums(); err != nil {
+		t.Fatal(err)
+	}
+	writeChunks(t, table, 30, 15)
+	table.Close()
+
+	// Reopen and check the index format is detected.
+	table = open()
+	defer table.Close()
+	if !table.checksums {
+		t.Fatal("Checksums not restored")
+	}
+	if err := table.setChecksums(); err == nil {
+		t.Fatal("Enabled checksums on non-empty table")
+	}
+	stat, _ := table.index.Stat()
+	if stat.Size() != 31*indexEntryChecksumSize {
+		t.Fatalf("Wrong index size: have %d, want %d", stat.Size(), 31*indexEntryChecksumSize)
+	}
+	for i := uint64(0); i < 30; i++ {
+		if _, err := table.Retrieve(i); err != nil {
+			t.Fatalf("Failed to retrieve item %d: %v", i, err)
+		}
+	}
+	// Truncations must keep the index consistent.
+	if err := table.truncateHead(20); err != nil {
+		t.Fatal(err)
+	}
+	if err := table.truncateTail(10); err != nil {
+		t.Fatal(err)
+	}
+	batch := table.newBatch()
+	for i := 20; i < 25; i++ {
+		if err := batch.AppendRaw(uint64(i), getChunk(15, i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := batch.commit(); err != nil {
+		t.Fatal(err)
+	}
+	for i := uint64(10); i < 25; i++ {
+		if blob, err := table.Retrieve(i); err != nil {
+			t.Fatalf("Failed to retrieve item %d: %v", i, err)
+		} else if blob[0] != byte(i) {
+			t.Fatalf("Wrong item %d: %x", i, blob)
+		}
+	}
+	// Corrupted items must be detected on read.
+	corruptItem(t, table, 22)
+	if _, err := table.Retrieve(22); !errors.Is(err, errChecksumMismatch) {
+		t.Fatalf("Wrong error for corrupted item: %v", err)
+	}
+	if _, err := table.RetrieveItems(20, 5, 0); !errors.Is(err, errChecksumMismatch) {
+		t.Fatalf("Wrong error for corrupted range: %v", err)
+	}
+}
+
+func TestFreezerScrub(t *testing.T) {
+	tables := map[string]bool{"plain": true, "summed": true, "compressed": false}
+	f, dir := newFreezerForTesting(t, tables)
+	if err := f.tables["summed"].setChecksums(); err != nil {
+		t.Fatal(err)
+	}
+	// Recreate the write batch for the new index format.
+	f.writeBatch = newFreezerBatch(f)
+
+	_, err := f.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		for i := 0; i < 100; i++ {
+			for kind := range tables {
+				if err := op.AppendRaw(kind, uint64(i), getChunk(32, i)); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	corruptions, err := f.Scrub(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(corruptions) != 0 {
+		t.Fatalf("Unexpected corruptions: %v", corruptions)
+	}
+	for _, item := range []uint64{60, 61, 62, 80} {
+		corruptItem(t, f.tables["summed"], item)
+	}
+	// Corruption of a snappy item is caught by decoding, if the damage hits
+	// the preamble.
+	corruptItem(t, f.tables["compressed"], 70)
+	// Corruption of items in tables without checksums can't be detected.
+	corruptItem(t, f.tables["plain"], 50)
+
+	corruptions, err = f.Scrub(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []FreezerCorruption{
+		{Table: "compressed", Start: 70, End: 70},
+		{Table: "summed", Start: 60, End: 62},
+		{Table: "summed", Start: 80, End: 80},
+	}
+	if len(corruptions) != len(want) {
+		t.Fatalf("Wrong corruptions: have %v, want %v", corruptions, want)
+	}
+	for i, c := range corruptions {
+		if c.Table != want[i].Table || c.Start != want[i].Start || c.End != want[i].End || c.Err == nil {
+			t.Errorf("Wrong corruption %d: have %v, want %v", i, c, want[i])
+		}
+	}
+	f.Close()
+
+	// Repair the freezer by truncation.
+	f, err = NewFreezer(dir, "", false, 2049, tables)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.Scrub(true); err != nil {
+		t.Fatal(err)
+	}
+	checkAncientCount(t, f, "summed", 60)
+	if corruptions, err := f.Scrub(false); err != nil || len(corruptions) != 0 {
+		t.Fatalf("Corruptions left after repair: %v, %v", corruptions, err)
+	}
+}
+
+func TestRewindChainFreezer(t *testing.T) {
+	var (
+		dir     = t.TempDir()
+		ancient = t.TempDir()
+		blocks  []*types.Block
+	)
+	for i := 0; i < 15; i++ {
+		blocks = append(blocks, types.NewBlockWithHeader(&types.Header{
+			Number: big.NewInt(int64(i)),
+			Extra:  []byte{byte(i)},
+		}))
+	}
+	open := func() ethdb.Database {
+		t.Helper()
+		db, err := Open(OpenOptions{Type: "pebble", Directory: dir, AncientsDirectory: ancient, Cache: 16, Handles: 16, Ephemeral: true, Checksums: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return db
+	}
+	// Create a chain of which the first ten blocks are frozen.
+	db := open()
+	if _, err := WriteAncientBlocks(db, blocks[:10], make([]types.Receipts, 10), big.NewInt(0)); err != nil {
+		t.Fatal(err)
+	}
+	for _, block := range blocks {
+		if block.NumberU64() >= 10 {
+			WriteBlock(db, block)
+		}
+		if block.NumberU64() >= 10 || block.NumberU64() == 0 {
+			WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		}
+		WriteHeaderNumber(db, block.Hash(), block.NumberU64())
+	}
+	WriteHeadHeaderHash(db, blocks[14].Hash())
+	WriteHeadFastBlockHash(db, blocks[14].Hash())
+	WriteHeadBlockHash(db, blocks[14].Hash())
+	db.Close()
+
+	// The newly created freezer has checksums, which catch the corruption.
+	f, err := NewChainFreezer(resolveChainFreezerDir(ancient), "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, table := range f.tables {
+		if !table.checksums {
+			t.Fatalf("Checksums not enabled for table %s", name)
+		}
+	}
+	corruptItem(t, f.tables[ChainFreezerHeaderTable], 7)
+	corruptItem(t, f.tables[ChainFreezerBodiesTable], 5)
+	f.Close()
+
+	if _, err := ScrubFreezer(ancient, ChainFreezerName, true); err == nil {
+		t.Fatal("Repaired chain freezer without the key-value store")
+	}
+	corruptions, err := ScrubFreezer(ancient, ChainFreezerName, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(corruptions) != 2 || FirstCorruptedItem(corruptions) != 5 {
+		t.Fatalf("Wrong corruptions: %v", corruptions)
+	}
+	db = open()
+	if err := RewindChainFreezer(db, FirstCorruptedItem(corruptions)); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	// The repaired database must be accepted as a whole.
+	kvdb, err := NewPebbleDBDatabase(dir, 16, 16, "", false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err = NewDatabaseWithFreezer(kvdb, ancient, "", false)
+	if err != nil {
+		kvdb.Close()
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if frozen, _ := db.Ancients(); frozen != 5 {
+		t.Fatalf("Wrong number of frozen blocks: have %d, want 5", frozen)
+	}
+	for _, hash := range []common.Hash{ReadHeadHeaderHash(db), ReadHeadFastBlockHash(db), ReadHeadBlockHash(db)} {
+		if hash != blocks[4].Hash() {
+			t.Fatalf("Wrong head: have %x, want %x", hash, blocks[4].Hash())
+		}
+	}
+	for _, block := range blocks[5:] {
+		if hash := ReadCanonicalHash(db, block.NumberU64()); hash != (common.Hash{}) {
+			t.Fatalf("Canonical hash of block %d left", block.NumberU64())
+		}
+		if ReadHeaderNumber(db, block.Hash()) != nil {
+			t.Fatalf("Number of block %d left", block.NumberU64())
+		}
+	}
+	if ReadHeader(db, blocks[4].Hash(), 4) == nil {
+		t.Fatal("Head header missing")
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"hash/crc32"
 	"io"
 	"os"
 	"path/filepath"
@@ -44,22 +45,38 @@ var (
 
 	// errNotSupported is returned if the database doesn't support the required operation.
 	errNotSupported = errors.New("this operation is not supported")
+
+	// errChecksumMismatch is returned if the data of an item doesn't match the
+	// checksum stored in the index.
+	errChecksumMismatch = errors.New("checksum mismatch")
 )
 
+// checksumTable is the crc32 polynomial used for item checksums.
+var checksumTable = crc32.MakeTable(crc32.Castagnoli)
+
 // indexEntry contains the number/id of the file that the data resides in, as well as the
-// offset within the file to the end of the data.
+// offset within the file to the end of the data. Tables with checksums additionally
+// store the crc32 checksum of the data.
 // In serialized form, the filenum is stored as uint16.
 type indexEntry struct {
-	filenum uint32 // stored as uint16 ( 2 bytes )
-	offset  uint32 // stored as uint32 ( 4 bytes )
+	filenum  uint32 // stored as uint16 ( 2 bytes )
+	offset   uint32 // stored as uint32 ( 4 bytes )
+	checksum uint32 // stored as uint32 ( 4 bytes ), only in tables with checksums
 }
 
-const indexEntrySize = 6
+const (
+	indexEntrySize         = 6  // Size of an index entry
+	indexEntryChecksumSize = 10 // Size of an index entry including the checksum
+)
 
-// unmarshalBinary deserializes binary b into the rawIndex entry.
+// unmarshalBinary deserializes binary b into the rawIndex entry. The checksum
+// is decoded if b holds an index entry with checksum.
 func (i *indexEntry) unmarshalBinary(b []byte) {
 	i.filenum = uint32(binary.BigEndian.Uint16(b[:2]))
 	i.offset = binary.BigEndian.Uint32(b[2:6])
+	if len(b) == indexEntryChecksumSize {
+		i.checksum = binary.BigEndian.Uint32(b[6:10])
+	}
 }
 
 // append adds the encoded entry to the end of b.
@@ -71,6 +88,12 @@ func (i *indexEntry) append(b []byte) []byte {
 	return out
 }
 
+// appendWithChecksum adds the encoded entry including the checksum to the end of b.
+func (i *indexEntry) appendWithChecksum(b []byte) []byte {
+	out := i.append(b)
+	return binary.BigEndian.AppendUint32(out, i.checksum)
+}
+
 // bounds returns the start- and end- offsets, and the file number of where to
 // read there data item marked by the two index entries. The two entries are
 // assumed to be sequential.
@@ -85,7 +108,7 @@ func (i *indexEntry) bounds(end *indexEntry) (startOffset, endOffset, fileId uin
 }
 
 // freezerTable represents a single chained data table within the freezer (e.g. blocks).
-// It consists of a data file (snappy encoded arbitrary data blobs) and an indexEntry
+// It consists of a data file (snappy or zstd encoded arbitrary data blobs) and an indexEntry
 // file (uncompressed 64 bit indices into the data file).
 type freezerTable struct {
 	items      atomic.Uint64 // Number of items stored in the table (including items removed from tail)
@@ -98,7 +121,10 @@ type freezerTable struct {
 	// should never be lower than itemOffset.
 	itemHidden atomic.Uint64
 
-	noCompression bool // if true, disables snappy compression. Note: does not work retroactively
+	noCompression bool       // if true, disables snappy compression. Note: does not work retroactively
+	zstd          *zstdCodec // if set, items are compressed with zstd instead of snappy
+	checksums     bool       // if true, index entries carry the checksum of the item
+	entrySize     int64      // size of the index entries, depending on checksums
 	readonly      bool
 	maxFileSize   uint32 // Max file size for data-files
 	name          string
@@ -176,6 +202,7 @@ func newTable(path string, name string, readMeter metrics.Meter, writeMeter metr
 		path:          path,
 		logger:        log.New("database", path, "table", name),
 		noCompression: noCompression,
+		entrySize:     indexEntrySize,
 		readonly:      readonly,
 		maxFileSize:   maxFilesize,
 	}
@@ -197,11 +224,25 @@ func newTable(path string, name string, readMeter metrics.Meter, writeMeter metr
 // repair cross-checks the head and the index file and truncates them to
 // be in sync with each other after a potential crash / data loss.
 func (t *freezerTable) repair() error {
+	// Determine the index format from the metadata, if the table exists already
+	stat, err := t.meta.Stat()
+	if err != nil {
+		return err
+	}
+	if stat.Size() > 0 {
+		meta, err := readMetadata(t.meta)
+		if err != nil {
+			return err
+		}
+		if meta.Checksums {
+			t.checksums, t.entrySize = true, indexEntryChecksumSize
+		}
+	}
 	// Create a temporary offset buffer to init files with and read indexEntry into
-	buffer := make([]byte, indexEntrySize)
+	buffer := make([]byte, t.entrySize)
 
 	// If we've just created the files, initialize the index with the 0 indexEntry
-	stat, err := t.index.Stat()
+	stat, err = t.index.Stat()
 	if err != nil {
 		return err
 	}
@@ -210,10 +251,10 @@ func (t *freezerTable) repair() error {
 			return err
 		}
 	}
-	// Ensure the index is a multiple of indexEntrySize bytes
-	if overflow := stat.Size() % indexEntrySize; overflow != 0 {
+	// Ensure the index is a multiple of the index entry size
+	if overflow := stat.Size() % t.entrySize; overflow != 0 {
 		if t.readonly {
-			return fmt.Errorf("index file(path: %s, name: %s) size is not a multiple of %d", t.path, t.name, indexEntrySize)
+			return fmt.Errorf("index file(path: %s, name: %s) size is not a multiple of %d", t.path, t.name, t.entrySize)
 		}
 		if err := truncateFreezerFile(t.index, stat.Size()-overflow); err != nil {
 			return err
@@ -252,18 +293,32 @@ func (t *freezerTable) repair() error {
 	}
 	t.itemHidden.Store(meta.VirtualTail)
 
+	// Set up the compression codec recorded in the metadata
+	switch meta.Codec {
+	case codecSnappy:
+	case codecZstd:
+		if t.noCompression {
+			return errors.New("zstd codec configured for uncompressed table")
+		}
+		if t.zstd, err = newZstdCodec(meta.Dict); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown freezer table codec %d", meta.Codec)
+	}
+
 	// Read the last index, use the default value in case the freezer is empty
-	if offsetsSize == indexEntrySize {
+	if offsetsSize == t.entrySize {
 		lastIndex = indexEntry{filenum: t.tailId, offset: 0}
 	} else {
-		t.index.ReadAt(buffer, offsetsSize-indexEntrySize)
+		t.index.ReadAt(buffer, offsetsSize-t.entrySize)
 		lastIndex.unmarshalBinary(buffer)
 	}
 	// Print an error log if the index is corrupted due to an incorrect
 	// last index item. While it is theoretically possible to have a zero offset
 	// by storing all zero-size items, it is highly unlikely to occur in practice.
-	if lastIndex.offset == 0 && offsetsSize/indexEntrySize > 1 {
-		log.Error("Corrupted index file detected", "lastOffset", lastIndex.offset, "indexes", offsetsSize/indexEntrySize)
+	if lastIndex.offset == 0 && offsetsSize/t.entrySize > 1 {
+		log.Error("Corrupted index file detected", "lastOffset", lastIndex.offset, "indexes", offsetsSize/t.entrySize)
 	}
 	if t.readonly {
 		t.head, err = t.openFile(lastIndex.filenum, openFreezerFileForReadOnly)
@@ -295,19 +350,19 @@ func (t *freezerTable) repair() error {
 		}
 		// Truncate the index to point within the head file
 		if contentExp > contentSize {
-			t.logger.Warn("Truncating dangling indexes", "indexes", offsetsSize/indexEntrySize, "indexed", contentExp, "stored", contentSize)
-			if err := truncateFreezerFile(t.index, offsetsSize-indexEntrySize); err != nil {
+			t.logger.Warn("Truncating dangling indexes", "indexes", offsetsSize/t.entrySize, "indexed", contentExp, "stored", contentSize)
+			if err := truncateFreezerFile(t.index, offsetsSize-t.entrySize); err != nil {
 				return err
 			}
-			offsetsSize -= indexEntrySize
+			offsetsSize -= t.entrySize
 
 			// Read the new head index, use the default value in case
 			// the freezer is already empty.
 			var newLastIndex indexEntry
-			if offsetsSize == indexEntrySize {
+			if offsetsSize == t.entrySize {
 				newLastIndex = indexEntry{filenum: t.tailId, offset: 0}
 			} else {
-				t.index.ReadAt(buffer, offsetsSize-indexEntrySize)
+				t.index.ReadAt(buffer, offsetsSize-t.entrySize)
 				newLastIndex.unmarshalBinary(buffer)
 			}
 			// We might have slipped back into an earlier head-file here
@@ -342,7 +397,7 @@ func (t *freezerTable) repair() error {
 		}
 	}
 	// Update the item and byte counters and return
-	t.items.Store(t.itemOffset.Load() + uint64(offsetsSize/indexEntrySize-1)) // last indexEntry points to the end of the data file
+	t.items.Store(t.itemOffset.Load() + uint64(offsetsSize/t.entrySize-1)) // last indexEntry points to the end of the data file
 	t.headBytes = contentSize
 	t.headId = lastIndex.filenum
 
@@ -415,7 +470,7 @@ func (t *freezerTable) truncateHead(items uint64) error {
 	// Truncate the index file first, the tail position is also considered
 	// when calculating the new freezer table length.
 	length := items - t.itemOffset.Load()
-	if err := truncateFreezerFile(t.index, int64(length+1)*indexEntrySize); err != nil {
+	if err := truncateFreezerFile(t.index, int64(length+1)*t.entrySize); err != nil {
 		return err
 	}
 	if err := t.index.Sync(); err != nil {
@@ -426,8 +481,8 @@ func (t *freezerTable) truncateHead(items uint64) error {
 	if length == 0 {
 		expected = indexEntry{filenum: t.tailId, offset: 0}
 	} else {
-		buffer := make([]byte, indexEntrySize)
-		if _, err := t.index.ReadAt(buffer, int64(length*indexEntrySize)); err != nil {
+		buffer := make([]byte, t.entrySize)
+		if _, err := t.index.ReadAt(buffer, int64(length)*t.entrySize); err != nil {
 			return err
 		}
 		expected.unmarshalBinary(buffer)
@@ -436,6 +491,13 @@ func (t *freezerTable) truncateHead(items uint64) error {
 	if expected.filenum != t.headId {
 		// If already open for reading, force-reopen for writing
 		t.releaseFile(expected.filenum)
+
+		// Data files other than the head may be hard-linked into checkpoints,
+		// replace the file with a copy before modifying it.
+		name := filepath.Join(t.path, t.dataFileName(expected.filenum))
+		if err := copyFrom(name, name, 0, nil); err != nil {
+			return err
+		}
 		newHead, err := t.openFile(expected.filenum, openFreezerFileForAppend)
 		if err != nil {
 			return err
@@ -496,13 +558,13 @@ func (t *freezerTable) truncateTail(items uint64) error {
 	// Load the new tail index by the given new tail position
 	var (
 		newTailId uint32
-		buffer    = make([]byte, indexEntrySize)
+		buffer    = make([]byte, t.entrySize)
 	)
 	if t.items.Load() == items {
 		newTailId = t.headId
 	} else {
 		offset := items - t.itemOffset.Load()
-		if _, err := t.index.ReadAt(buffer, int64((offset+1)*indexEntrySize)); err != nil {
+		if _, err := t.index.ReadAt(buffer, int64(offset+1)*t.entrySize); err != nil {
 			return err
 		}
 		var newTail indexEntry
@@ -517,7 +579,7 @@ func (t *freezerTable) truncateTail(items uint64) error {
 	}
 	// Update the virtual tail marker and hidden these entries in table.
 	t.itemHidden.Store(items)
-	if err := writeMetadata(t.meta, newMetadata(items)); err != nil {
+	if err := writeMetadata(t.meta, t.metadata(items)); err != nil {
 		return err
 	}
 	// Hidden items still fall in the current tail file, no data file
@@ -536,7 +598,7 @@ func (t *freezerTable) truncateTail(items uint64) error {
 	)
 	// Hidden items exceed the current tail file, drop the relevant data files.
 	for current := items - 1; current >= deleted; current -= 1 {
-		if _, err := t.index.ReadAt(buffer, int64((current-deleted+1)*indexEntrySize)); err != nil {
+		if _, err := t.index.ReadAt(buffer, int64(current-deleted+1)*t.entrySize); err != nil {
 			return err
 		}
 		var pre indexEntry
@@ -556,12 +618,12 @@ func (t *freezerTable) truncateTail(items uint64) error {
 		return err
 	}
 	// Truncate the deleted index entries from the index file.
-	err = copyFrom(t.index.Name(), t.index.Name(), indexEntrySize*(newDeleted-deleted+1), func(f *os.File) error {
+	err = copyFrom(t.index.Name(), t.index.Name(), uint64(t.entrySize)*(newDeleted-deleted+1), func(f *os.File) error {
 		tailIndex := indexEntry{
 			filenum: newTailId,
 			offset:  uint32(newDeleted),
 		}
-		_, err := f.Write(tailIndex.append(nil))
+		_, err := f.Write(t.appendIndex(nil, &tailIndex))
 		return err
 	})
 	if err != nil {
@@ -625,6 +687,10 @@ func (t *freezerTable) Close() error {
 	t.meta = nil
 	t.head = nil
 
+	if t.zstd != nil {
+		t.zstd.close()
+	}
+
 	if errs != nil {
 		return fmt.Errorf("%v", errs)
 	}
@@ -635,13 +701,7 @@ func (t *freezerTable) Close() error {
 func (t *freezerTable) openFile(num uint32, opener func(string) (*os.File, error)) (f *os.File, err error) {
 	var exist bool
 	if f, exist = t.files[num]; !exist {
-		var name string
-		if t.noCompression {
-			name = fmt.Sprintf("%s.%04d.rdat", t.name, num)
-		} else {
-			name = fmt.Sprintf("%s.%04d.cdat", t.name, num)
-		}
-		f, err = opener(filepath.Join(t.path, name))
+		f, err = opener(filepath.Join(t.path, t.dataFileName(num)))
 		if err != nil {
 			return nil, err
 		}
@@ -650,6 +710,14 @@ func (t *freezerTable) openFile(num uint32, opener func(string) (*os.File, error
 	return f, err
 }
 
+// dataFileName returns the name of the data file with the given number.
+func (t *freezerTable) dataFileName(num uint32) string {
+	if t.noCompression {
+		return fmt.Sprintf("%s.%04d.rdat", t.name, num)
+	}
+	return fmt.Sprintf("%s.%04d.cdat", t.name, num)
+}
+
 // releaseFile closes a file, and removes it from the open file cache.
 // Assumes that the caller holds the write lock
 func (t *freezerTable) releaseFile(num uint32) {
@@ -696,8 +764,8 @@ func (t *freezerTable) getIndices(from, count uint64) ([]*indexEntry, error) {
 	from = from - t.itemOffset.Load()
 
 	// For reading N items, we need N+1 indices.
-	buffer := make([]byte, (count+1)*indexEntrySize)
-	if _, err := t.index.ReadAt(buffer, int64(from*indexEntrySize)); err != nil {
+	buffer := make([]byte, int64(count+1)*t.entrySize)
+	if _, err := t.index.ReadAt(buffer, int64(from)*t.entrySize); err != nil {
 		return nil, err
 	}
 	var (
@@ -706,8 +774,8 @@ func (t *freezerTable) getIndices(from, count uint64) ([]*indexEntry, error) {
 	)
 	for i := from; i <= from+count; i++ {
 		index := new(indexEntry)
-		index.unmarshalBinary(buffer[offset:])
-		offset += indexEntrySize
+		index.unmarshalBinary(buffer[offset : offset+int(t.entrySize)])
+		offset += int(t.entrySize)
 		indices = append(indices, index)
 	}
 	if from == 0 {
@@ -722,6 +790,88 @@ func (t *freezerTable) getIndices(from, count uint64) ([]*indexEntry, error) {
 	return indices, nil
 }
 
+// metadata returns the metadata of the table with the given virtual tail.
+func (t *freezerTable) metadata(tail uint64) *freezerTableMeta {
+	meta := newMetadata(tail)
+	if t.zstd != nil {
+		meta.Version = freezerVersionCodec
+		meta.Codec = codecZstd
+		meta.Dict = t.zstd.dict
+	}
+	if t.checksums {
+		meta.Version = freezerVersionChecksum
+		meta.Checksums = true
+	}
+	return meta
+}
+
+// appendIndex adds the index entry to the end of b, in the format of the table.
+func (t *freezerTable) appendIndex(b []byte, entry *indexEntry) []byte {
+	if t.checksums {
+		return entry.appendWithChecksum(b)
+	}
+	return entry.append(b)
+}
+
+// setChecksums configures the table to store checksums of the items in the
+// index. It is only permitted on empty tables, as the index format does not
+// change retroactively.
+func (t *freezerTable) setChecksums() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.items.Load() != 0 || t.itemHidden.Load() != 0 {
+		return errors.New("checksums can only be enabled on empty tables")
+	}
+	t.checksums, t.entrySize = true, indexEntryChecksumSize
+	if err := writeMetadata(t.meta, t.metadata(0)); err != nil {
+		return err
+	}
+	if err := t.meta.Sync(); err != nil {
+		return err
+	}
+	// Rewrite the 0 indexEntry in the new format.
+	if err := truncateFreezerFile(t.index, 0); err != nil {
+		return err
+	}
+	if _, err := t.index.Write(t.appendIndex(nil, &indexEntry{filenum: t.tailId})); err != nil {
+		return err
+	}
+	return t.index.Sync()
+}
+
+// setTail marks the first tail items of an empty table as deleted, so that the
+// next appended item is the one at position tail. It is used to recreate a
+// tail-deleted table in a new location.
+func (t *freezerTable) setTail(tail uint64) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.items.Load() != 0 || t.itemHidden.Load() != 0 {
+		return errors.New("tail can only be set on empty tables")
+	}
+	if err := writeMetadata(t.meta, t.metadata(tail)); err != nil {
+		return err
+	}
+	if err := t.meta.Sync(); err != nil {
+		return err
+	}
+	// Rewrite the 0 indexEntry to record the deleted items.
+	if err := truncateFreezerFile(t.index, 0); err != nil {
+		return err
+	}
+	if _, err := t.index.Write(t.appendIndex(nil, &indexEntry{filenum: t.tailId, offset: uint32(tail)})); err != nil {
+		return err
+	}
+	if err := t.index.Sync(); err != nil {
+		return err
+	}
+	t.itemOffset.Store(tail)
+	t.itemHidden.Store(tail)
+	t.items.Store(tail)
+	return nil
+}
+
 // Retrieve looks up the data offset of an item with the given number and retrieves
 // the raw binary blob from the data file.
 func (t *freezerTable) Retrieve(item uint64) ([]byte, error) {
@@ -752,19 +902,29 @@ func (t *freezerTable) RetrieveItems(start, count, maxBytes uint64) ([][]byte, e
 		item := diskData[offset : offset+diskSize]
 		offset += diskSize
 		decompressedSize := diskSize
-		if !t.noCompression {
+		switch {
+		case t.zstd != nil:
+			decompressedSize = t.zstd.decodedLen(item)
+		case !t.noCompression:
 			decompressedSize, _ = snappy.DecodedLen(item)
 		}
 		if i > 0 && maxBytes != 0 && uint64(outputSize+decompressedSize) > maxBytes {
 			break
 		}
-		if !t.noCompression {
+		switch {
+		case t.zstd != nil:
+			data, err := t.zstd.decode(item)
+			if err != nil {
+				return nil, err
+			}
+			output = append(output, data)
+		case !t.noCompression:
 			data, err := snappy.Decode(nil, item)
 			if err != nil {
 				return nil, err
 			}
 			output = append(output, data)
-		} else {
+		default:
 			output = append(output, item)
 		}
 		outputSize += decompressedSize
@@ -866,6 +1026,16 @@ func (t *freezerTable) retrieveItems(start, count, maxBytes uint64) ([]byte, []i
 		}
 	}
 
+	// Verify the items against their checksums
+	if t.checksums {
+		offset := 0
+		for i, size := range sizes {
+			if crc32.Checksum(output[offset:offset+size], checksumTable) != indices[i+1].checksum {
+				return nil, nil, fmt.Errorf("%w: item %d", errChecksumMismatch, start+uint64(i))
+			}
+			offset += size
+		}
+	}
 	// Update metrics.
 	t.readMeter.Mark(int64(totalSize))
 	return output, sizes, nil
@@ -970,13 +1140,13 @@ func (t *freezerTable) dumpIndex(w io.Writer, start, stop int64) {
 	fmt.Fprintf(w, "Version %d count %d, deleted %d, hidden %d\n", meta.Version,
 		t.items.Load(), t.itemOffset.Load(), t.itemHidden.Load())
 
-	buf := make([]byte, indexEntrySize)
+	buf := make([]byte, t.entrySize)
 
 	fmt.Fprintf(w, "| number | fileno | offset |\n")
 	fmt.Fprintf(w, "|--------|--------|--------|\n")
 
 	for i := uint64(start); ; i++ {
-		if _, err := t.index.ReadAt(buf, int64((i+1)*indexEntrySize)); err != nil {
+		if _, err := t.index.ReadAt(buf, int64(i+1)*t.entrySize); err != nil {
 			break
 		}
 		var entry indexEntry
@@ -334,6 +334,67 @@ func TestFreezerConcurrentReadonly(t *testing.T) {
 	}
 }
 
+func TestFreezerShared(t *testing.T) {
+	t.Parallel()
+
+	tables := map[string]bool{"a": true, "b": false}
+	f, dir := newFreezerForTesting(t, tables)
+	defer f.Close()
+
+	var item = make([]byte, 1024)
+	write := func(from, to uint64) {
+		t.Helper()
+		_, err := f.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+			for i := from; i < to; i++ {
+				for kind := range tables {
+					if err := op.AppendRaw(kind, i, item); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		})
+		require.NoError(t, err)
+	}
+	write(0, 8)
+
+	// Simulate a write in progress, which only reached one of the tables.
+	batch := f.tables["a"].newBatch()
+	require.NoError(t, batch.AppendRaw(8, item))
+	require.NoError(t, batch.commit())
+
+	// A shared freezer can be opened while the owner holds the lock, and only
+	// uses the items present in all tables.
+	shared, err := newFreezer(dir, "", false, true, 2049, tables)
+	if err != nil {
+		t.Fatal("can't open shared freezer", err)
+	}
+	defer shared.Close()
+	if frozen, _ := shared.Ancients(); frozen != 8 {
+		t.Fatalf("unexpected number of items in shared freezer, want: 8, have: %d", frozen)
+	}
+	if _, err := shared.ModifyAncients(func(ethdb.AncientWriteOp) error { return nil }); err != errReadOnly {
+		t.Fatalf("unexpected error modifying shared freezer: %v", err)
+	}
+	// Items appended afterwards by the owner are not part of the view.
+	require.NoError(t, f.tables["a"].truncateHead(8))
+	write(8, 16)
+	if frozen, _ := shared.Ancients(); frozen != 8 {
+		t.Fatalf("unexpected number of items in shared freezer, want: 8, have: %d", frozen)
+	}
+	if blob, err := shared.Ancient("b", 7); err != nil || !bytes.Equal(blob, item) {
+		t.Fatalf("failed to read item from shared freezer: %v", err)
+	}
+	reopened, err := newFreezer(dir, "", false, true, 2049, tables)
+	if err != nil {
+		t.Fatal("can't reopen shared freezer", err)
+	}
+	defer reopened.Close()
+	if frozen, _ := reopened.Ancients(); frozen != 16 {
+		t.Fatalf("unexpected number of items in reopened freezer, want: 16, have: %d", frozen)
+	}
+}
+
 func newFreezerForTesting(t *testing.T, tables map[string]bool) (*Freezer, string) {
 	t.Helper()
 
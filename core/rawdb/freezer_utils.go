@@ -129,3 +129,12 @@ func grow(buf []byte, n int) []byte {
 	buf = buf[:len(buf)+n]
 	return buf
 }
+
+// linkOrCopyFile creates a hard link of 'srcPath' at 'destPath', falling back
+// to copying the file if linking is not possible, e.g. across file systems.
+func linkOrCopyFile(srcPath, destPath string) error {
+	if err := os.Link(srcPath, destPath); err == nil {
+		return nil
+	}
+	return copyFrom(srcPath, destPath, 0, nil)
+}
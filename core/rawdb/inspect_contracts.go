@@ -0,0 +1,172 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// ContractStat is the storage footprint of a single account.
+type ContractStat struct {
+	Account   common.Hash        // Hash of the account address
+	Address   *common.Address    // Address of the account, nil if its preimage is unknown
+	Slots     uint64             // Number of storage slots in the snapshot
+	SlotSize  common.StorageSize // Size of the storage slots in the snapshot
+	TrieNodes uint64             // Number of storage trie nodes (path scheme only)
+	TrieSize  common.StorageSize // Size of the storage trie nodes (path scheme only)
+	CodeSize  common.StorageSize // Size of the contract code (requires the account snapshot)
+}
+
+// Size returns the total size of the data owned by the account.
+func (s *ContractStat) Size() common.StorageSize {
+	return s.SlotSize + s.TrieSize + s.CodeSize
+}
+
+// contractCursor iterates the entries of a key range laid out by account hash,
+// skipping the keys of other data sharing the prefix.
+type contractCursor struct {
+	it    ethdb.Iterator
+	valid func(key []byte) bool
+	owner common.Hash // Account hash of the current entry
+	done  bool
+}
+
+func newContractCursor(db ethdb.Iteratee, prefix []byte, start []byte, valid func([]byte) bool) *contractCursor {
+	c := &contractCursor{it: db.NewIterator(prefix, start), valid: valid}
+	c.next()
+	return c
+}
+
+// next moves the cursor to the next valid entry.
+func (c *contractCursor) next() {
+	for c.it.Next() {
+		if key := c.it.Key(); c.valid(key) {
+			c.owner = common.BytesToHash(key[1 : 1+common.HashLength])
+			return
+		}
+	}
+	c.done = true
+}
+
+// at returns whether the cursor is at an entry of the given account.
+func (c *contractCursor) at(owner common.Hash) bool {
+	return !c.done && c.owner == owner
+}
+
+// size returns the size of the current entry.
+func (c *contractCursor) size() common.StorageSize {
+	return common.StorageSize(len(c.it.Key()) + len(c.it.Value()))
+}
+
+// InspectContracts iterates the storage snapshot, the path-scheme storage trie
+// nodes and the account snapshot, invoking the callback in account hash order
+// for each account holding storage or code. The iteration starts at the given
+// account hash, and is aborted if the callback returns an error.
+//
+// Code sizes are only available if the account snapshot is present, and trie
+// node sizes if the state is stored in the path scheme, as hash-scheme nodes
+// cannot be attributed to their owners without iterating the tries.
+func InspectContracts(db ethdb.KeyValueStore, start common.Hash, fn func(*ContractStat) error) error {
+	var (
+		accounts = newContractCursor(db, SnapshotAccountPrefix, start[:], func(key []byte) bool {
+			return len(key) == len(SnapshotAccountPrefix)+common.HashLength
+		})
+		slots = newContractCursor(db, SnapshotStoragePrefix, start[:], func(key []byte) bool {
+			return len(key) == len(SnapshotStoragePrefix)+2*common.HashLength
+		})
+		nodes = newContractCursor(db, trieNodeStoragePrefix, start[:], IsStorageTrieNode)
+
+		count     uint64
+		contracts uint64
+		begin     = time.Now()
+		logged    = time.Now()
+	)
+	defer func() {
+		accounts.it.Release()
+		slots.it.Release()
+		nodes.it.Release()
+	}()
+	for {
+		// Find the lowest account hash of the three cursors
+		var (
+			owner common.Hash
+			found bool
+		)
+		for _, c := range []*contractCursor{accounts, slots, nodes} {
+			if !c.done && (!found || bytes.Compare(c.owner[:], owner[:]) < 0) {
+				owner, found = c.owner, true
+			}
+		}
+		if !found {
+			break
+		}
+		stat := &ContractStat{Account: owner}
+		if accounts.at(owner) {
+			account, err := types.FullAccount(accounts.it.Value())
+			if err != nil {
+				return fmt.Errorf("invalid account %x: %v", owner, err)
+			}
+			if !bytes.Equal(account.CodeHash, types.EmptyCodeHash[:]) {
+				stat.CodeSize = common.StorageSize(len(ReadCode(db, common.BytesToHash(account.CodeHash))))
+			}
+			accounts.next()
+		}
+		for ; slots.at(owner); slots.next() {
+			stat.Slots++
+			stat.SlotSize += slots.size()
+		}
+		for ; nodes.at(owner); nodes.next() {
+			stat.TrieNodes++
+			stat.TrieSize += nodes.size()
+		}
+		count++
+		if stat.Slots > 0 || stat.TrieNodes > 0 || stat.CodeSize > 0 {
+			if preimage := ReadPreimage(db, owner); len(preimage) == common.AddressLength {
+				addr := common.BytesToAddress(preimage)
+				stat.Address = &addr
+			}
+			if err := fn(stat); err != nil {
+				return err
+			}
+			contracts++
+		}
+		if count%1000 == 0 && time.Since(logged) > 8*time.Second {
+			// The account hashes are evenly distributed, so the position in the
+			// key space approximates the progress.
+			done := binary.BigEndian.Uint64(owner[:8])
+			log.Info("Inspecting contracts", "accounts", count, "contracts", contracts, "at", owner,
+				"progress", fmt.Sprintf("%.2f%%", float64(done)/float64(^uint64(0))*100),
+				"elapsed", common.PrettyDuration(time.Since(begin)))
+			logged = time.Now()
+		}
+	}
+	for _, c := range []*contractCursor{accounts, slots, nodes} {
+		if err := c.it.Error(); err != nil {
+			return err
+		}
+	}
+	log.Info("Inspected contracts", "accounts", count, "contracts", contracts, "elapsed", common.PrettyDuration(time.Since(begin)))
+	return nil
+}
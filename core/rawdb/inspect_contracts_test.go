@@ -0,0 +1,93 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/holiman/uint256"
+)
+
+func TestInspectContracts(t *testing.T) {
+	var (
+		db   = NewMemoryDatabase()
+		code = []byte{0x60, 0x00, 0x60, 0x00, 0xf3}
+
+		eoa      = common.Hash{0x01} // Account without storage nor code
+		contract = common.Hash{0x02} // Account with storage, nodes and code
+		orphan   = common.Hash{0x03} // Storage without account snapshot
+		addr     = common.Address{0xaa}
+		named    = crypto.Keccak256Hash(addr[:]) // Account with a known preimage
+	)
+	account := func(codeHash []byte) []byte {
+		return types.SlimAccountRLP(types.StateAccount{
+			Balance:  uint256.NewInt(1),
+			Root:     types.EmptyRootHash,
+			CodeHash: codeHash,
+		})
+	}
+	WriteAccountSnapshot(db, eoa, account(types.EmptyCodeHash[:]))
+	WriteAccountSnapshot(db, contract, account(crypto.Keccak256(code)))
+	WriteCode(db, crypto.Keccak256Hash(code), code)
+	for i := byte(0); i < 3; i++ {
+		WriteStorageSnapshot(db, contract, common.Hash{i}, []byte{0x01, i})
+	}
+	WriteStorageTrieNode(db, contract, nil, []byte{0xc0, 0x01})
+	WriteStorageTrieNode(db, contract, []byte{0x01}, []byte{0xc0})
+	WriteStorageSnapshot(db, orphan, common.Hash{}, []byte{0x01})
+	WriteAccountSnapshot(db, named, account(types.EmptyCodeHash[:]))
+	WriteStorageSnapshot(db, named, common.Hash{}, []byte{0x01})
+	WritePreimages(db, map[common.Hash][]byte{named: addr[:]})
+
+	var stats []ContractStat
+	collect := func(stat *ContractStat) error {
+		stats = append(stats, *stat)
+		return nil
+	}
+	if err := InspectContracts(db, common.Hash{}, collect); err != nil {
+		t.Fatal(err)
+	}
+	var (
+		slotSize = common.StorageSize(1 + 2*common.HashLength + 2)
+		nodeSize = common.StorageSize(1 + common.HashLength)
+	)
+	want := []ContractStat{
+		{Account: contract, Slots: 3, SlotSize: 3 * slotSize, TrieNodes: 2, TrieSize: 2*nodeSize + 4, CodeSize: 5},
+		{Account: orphan, Slots: 1, SlotSize: slotSize - 1},
+	}
+	namedStat := ContractStat{Account: named, Address: &addr, Slots: 1, SlotSize: slotSize - 1}
+	if named[0] < orphan[0] {
+		want = append([]ContractStat{namedStat}, want...)
+	} else {
+		want = append(want, namedStat)
+	}
+	if !reflect.DeepEqual(stats, want) {
+		t.Fatalf("stats mismatch:\nhave %+v\nwant %+v", stats, want)
+	}
+	// Check that the iteration can be resumed
+	stats = nil
+	if err := InspectContracts(db, orphan, collect); err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) == 0 || stats[0].Account != orphan {
+		t.Fatalf("iteration not resumed at %x: %+v", orphan, stats)
+	}
+}
@@ -136,6 +136,15 @@ type (
 	touchChange struct {
 		account *common.Address
 	}
+	// Changes to the deferred credits
+	creditChange struct {
+		account *common.Address
+		prev    *uint256.Int
+	}
+	creditLoadChange struct {
+		account *common.Address
+		credit  *uint256.Int
+	}
 	// Changes to the access list
 	accessListAddAccountChange struct {
 		address *common.Address
@@ -204,6 +213,27 @@ func (ch touchChange) dirtied() *common.Address {
 	return ch.account
 }
 
+func (ch creditChange) revert(s *StateDB) {
+	if ch.prev == nil {
+		delete(s.credits, *ch.account)
+	} else {
+		s.credits[*ch.account] = ch.prev
+	}
+}
+
+func (ch creditChange) dirtied() *common.Address {
+	return ch.account
+}
+
+func (ch creditLoadChange) revert(s *StateDB) {
+	delete(s.stateObjects, *ch.account)
+	s.credits[*ch.account] = ch.credit
+}
+
+func (ch creditLoadChange) dirtied() *common.Address {
+	return nil
+}
+
 func (ch balanceChange) revert(s *StateDB) {
 	s.getStateObject(*ch.account).setBalance(ch.prev)
 }
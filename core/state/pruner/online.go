@@ -0,0 +1,507 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pruner
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state/snapshot"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/ethereum/go-ethereum/triedb"
+)
+
+const (
+	// onlineBloomSize is the default memory allowance (MB) of the bloom filter
+	// tracking the live state during an online pruning.
+	onlineBloomSize = 2048
+
+	// onlineSweepBatch is the number of stale trie nodes deleted at once by the
+	// online pruning, between which the progress is persisted.
+	onlineSweepBatch = 1024
+
+	// onlineRetryDelay is the time to wait before retrying a failed pruning,
+	// e.g. because the snapshot is not yet generated.
+	onlineRetryDelay = time.Minute
+)
+
+// errHoldReleased is returned if the snapshot disk layer is released while being
+// regenerated, as the diff layers above it outgrew the memory allowance.
+var errHoldReleased = errors.New("snapshot disk layer released")
+
+// OnlineConfig includes the configurations for the online pruning.
+type OnlineConfig struct {
+	BloomSize uint64        // Megabytes of memory allocated to the bloom filter
+	Rate      int           // Maximum number of trie nodes deleted per second, unlimited if 0
+	Interval  time.Duration // Time between the end of a pruning and the start of the next
+}
+
+// liveSet is a concurrency-safe bloom filter tracking the trie nodes of the live
+// state, also used to serialize deletions with the trie nodes flushed to disk.
+type liveSet struct {
+	bloom *stateBloom
+	lock  sync.Mutex
+}
+
+// Put implements the KeyValueWriter interface, marking the key live.
+func (s *liveSet) Put(key []byte, value []byte) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.bloom.Put(key, value)
+}
+
+// Delete implements the KeyValueWriter interface. It's not supported.
+func (s *liveSet) Delete(key []byte) error { panic("not supported") }
+
+// contain reports whether the key may be live.
+func (s *liveSet) contain(key []byte) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.bloom.Contain(key)
+}
+
+// OnlinePruner deletes the stale trie nodes of a hash-based state database in
+// the background, while the chain keeps progressing. A pruning works as:
+//
+//   - track the trie nodes flushed to disk from now on, which are all live
+//   - hold the snapshot disk layer and regenerate its state into a bloom filter,
+//     retrying later if the diff layers above it outgrow the memory allowance
+//   - walk the tries of the snapshot diff layers (the last TriesInMemory blocks
+//     and their siblings) along the paths modified since the disk layer
+//   - iterate the database, deleting the trie nodes not in the bloom filter
+//
+// The progress of the deletion is persisted, so that an interrupted pruning is
+// resumed after a restart, with the bloom filter regenerated first.
+//
+// Contract codes are not pruned, as they are written outside of the trie
+// database and cannot be tracked.
+type OnlinePruner struct {
+	config   OnlineConfig
+	db       ethdb.Database
+	triedb   *triedb.Database
+	snaptree *snapshot.Tree
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewOnlinePruner creates an online pruner for the hash-based state database.
+func NewOnlinePruner(db ethdb.Database, triedb *triedb.Database, snaptree *snapshot.Tree, config OnlineConfig) (*OnlinePruner, error) {
+	if triedb.Scheme() != rawdb.HashScheme {
+		return nil, errors.New("online pruning is only supported in hash scheme")
+	}
+	if snaptree == nil {
+		return nil, errors.New("online pruning requires the snapshot")
+	}
+	if config.BloomSize == 0 {
+		config.BloomSize = onlineBloomSize
+	}
+	if config.BloomSize < 256 {
+		log.Warn("Sanitizing bloomfilter size", "provided(MB)", config.BloomSize, "updated(MB)", 256)
+		config.BloomSize = 256
+	}
+	return &OnlinePruner{
+		config:   config,
+		db:       db,
+		triedb:   triedb,
+		snaptree: snaptree,
+		quit:     make(chan struct{}),
+	}, nil
+}
+
+// Start launches the background pruning, resuming any interrupted pruning first
+// and then pruning once per configured interval.
+func (p *OnlinePruner) Start() {
+	p.wg.Add(1)
+	go p.loop()
+}
+
+// Stop interrupts the running pruning and waits for the pruner to exit. The
+// pruning is resumed at the next start.
+func (p *OnlinePruner) Stop() {
+	close(p.quit)
+	p.wg.Wait()
+}
+
+func (p *OnlinePruner) loop() {
+	defer p.wg.Done()
+
+	delay := p.config.Interval
+	if rawdb.ReadOnlinePruningMarker(p.db) != nil {
+		delay = 0
+	}
+	for {
+		select {
+		case <-time.After(delay):
+		case <-p.quit:
+			return
+		}
+		delay = p.config.Interval
+		if err := p.Prune(); err != nil {
+			if errors.Is(err, snapshot.ErrAborted) {
+				return
+			}
+			log.Warn("Online state pruning failed", "err", err)
+			delay = onlineRetryDelay
+		}
+	}
+}
+
+// Prune runs a full pruning, deleting all trie nodes on disk which belong to
+// neither the states of the snapshot layers, nor the genesis state. It returns
+// snapshot.ErrAborted if the pruner is stopped meanwhile.
+func (p *OnlinePruner) Prune() error {
+	start := time.Now()
+	bloom, err := newStateBloomWithSize(p.config.BloomSize)
+	if err != nil {
+		return err
+	}
+	live := &liveSet{bloom: bloom}
+
+	// Mark all trie nodes flushed from now on as live. These are either part
+	// of the marked states, or of states created after the pruning started.
+	hook := func(hash common.Hash) { live.Put(hash.Bytes(), nil) }
+	if err := p.triedb.SetFlushHook(hook); err != nil {
+		return err
+	}
+	defer p.triedb.SetFlushHook(nil)
+
+	if err := p.mark(live); err != nil {
+		return err
+	}
+	if err := extractGenesis(p.db, live); err != nil {
+		return err
+	}
+	log.Info("Marked live state for pruning", "elapsed", common.PrettyDuration(time.Since(start)))
+
+	if err := p.sweep(live); err != nil {
+		return err
+	}
+	log.Info("Online state pruning successful", "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// mark regenerates the state of the snapshot disk layer into the live set and
+// walks the tries of the diff layers above it, marking their trie nodes which
+// may have been modified since.
+func (p *OnlinePruner) mark(live *liveSet) error {
+	released, err := p.snaptree.Hold()
+	if err != nil {
+		return err
+	}
+	base := p.snaptree.DiskRoot()
+	log.Info("Regenerating snapshot state for pruning", "root", base)
+
+	// The regeneration is aborted if the disk layer is released, as it's then
+	// modified while being iterated. The pruning is retried later.
+	var (
+		abort = make(chan struct{})
+		done  = make(chan struct{})
+	)
+	go func() {
+		defer close(abort)
+		select {
+		case <-p.quit:
+		case <-released:
+		case <-done:
+		}
+	}()
+	err = snapshot.GenerateTrieWithAbort(p.snaptree, base, p.db, live, abort)
+	close(done)
+	diskRoot, diffs := p.snaptree.Diffs()
+	p.snaptree.Unhold()
+
+	select {
+	case <-p.quit:
+		return snapshot.ErrAborted
+	default:
+	}
+	select {
+	case <-released:
+		return errHoldReleased
+	default:
+	}
+	if err != nil {
+		return err
+	}
+	if diskRoot != base {
+		return fmt.Errorf("snapshot disk layer changed: %x != %x", diskRoot, base)
+	}
+	// Every trie node not in the disk layer state is on the path to an entry
+	// modified by the diff layers, hence walk the tries along these paths.
+	// Nodes marked by any previous walk are skipped along with their subtree,
+	// as all paths beneath them have been walked already.
+	var (
+		accounts = make(map[common.Hash]struct{})
+		slots    = make(map[common.Hash][]common.Hash)
+	)
+	for _, diff := range diffs {
+		for _, hash := range diff.Destructs {
+			accounts[hash] = struct{}{}
+		}
+		for _, hash := range diff.Accounts {
+			accounts[hash] = struct{}{}
+		}
+		for account, hashes := range diff.Storage {
+			accounts[account] = struct{}{}
+			slots[account] = append(slots[account], hashes...)
+		}
+	}
+	var (
+		accountKeys = sortedKeys(accounts)
+		storageKeys = make(map[common.Hash][]common.Hash, len(slots))
+		marked      = make(map[common.Hash]struct{})
+	)
+	for account, hashes := range slots {
+		set := make(map[common.Hash]struct{}, len(hashes))
+		for _, hash := range hashes {
+			set[hash] = struct{}{}
+		}
+		storageKeys[account] = sortedKeys(set)
+	}
+	for _, diff := range diffs {
+		select {
+		case <-p.quit:
+			return snapshot.ErrAborted
+		default:
+		}
+		walked := make(map[common.Hash]struct{})
+		err := p.walk(trie.StateTrieID(diff.Root), accountKeys, marked, walked, func(key common.Hash, blob []byte) error {
+			keys := storageKeys[key]
+			if len(keys) == 0 {
+				return nil
+			}
+			var account types.StateAccount
+			if err := rlp.DecodeBytes(blob, &account); err != nil {
+				return err
+			}
+			if account.Root == types.EmptyRootHash {
+				return nil
+			}
+			return p.walk(trie.StorageTrieID(diff.Root, key, account.Root), keys, marked, walked, nil)
+		})
+		// States whose trie nodes were garbage collected meanwhile are unusable,
+		// there is no need to retain them.
+		var missing *trie.MissingNodeError
+		if errors.As(err, &missing) {
+			log.Debug("Skipping unavailable state", "root", diff.Root, "err", err)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		for hash := range walked {
+			marked[hash] = struct{}{}
+			live.Put(hash.Bytes(), nil)
+		}
+	}
+	log.Info("Marked recent states for pruning", "layers", len(diffs), "accounts", len(accountKeys), "nodes", len(marked))
+	return nil
+}
+
+// walk iterates the trie nodes on the paths to the given sorted keys, adding
+// them into the walked set, unless already marked. The callback is invoked for
+// the leaves reached.
+func (p *OnlinePruner) walk(id *trie.ID, keys []common.Hash, marked, walked map[common.Hash]struct{}, onLeaf func(key common.Hash, blob []byte) error) error {
+	t, err := trie.New(id, p.triedb)
+	if err != nil {
+		return err
+	}
+	it, err := t.NodeIterator(nil)
+	if err != nil {
+		return err
+	}
+	for descend := true; it.Next(descend); {
+		descend = false
+		if hash := it.Hash(); hash != (common.Hash{}) {
+			if _, ok := marked[hash]; ok {
+				continue
+			}
+			if _, ok := walked[hash]; ok {
+				continue
+			}
+			walked[hash] = struct{}{}
+		}
+		if it.Leaf() {
+			if onLeaf != nil {
+				if err := onLeaf(common.BytesToHash(it.LeafKey()), it.LeafBlob()); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		descend = onPath(keys, it.Path())
+	}
+	return it.Error()
+}
+
+// sweep iterates the database, deleting the trie nodes which are not live. The
+// deletions are throttled to the configured rate, and the progress is persisted
+// along with them.
+func (p *OnlinePruner) sweep(live *liveSet) error {
+	var (
+		start   = time.Now()
+		logged  = time.Now()
+		count   int
+		skipped int
+		size    common.StorageSize
+		keys    [][]byte
+		batch   = p.db.NewBatch()
+		from    = rawdb.ReadOnlinePruningMarker(p.db)
+		scanned int
+	)
+	if from == nil {
+		rawdb.WriteOnlinePruningMarker(p.db, []byte{})
+	} else if len(from) > 0 {
+		log.Info("Resuming online state pruning", "from", common.BytesToHash(from))
+	}
+	iter := p.db.NewIterator(nil, from)
+	defer func() { iter.Release() }()
+
+	// flush deletes the collected nodes which are still not live, blocking the
+	// trie nodes from being flushed to disk meanwhile, as these may be resurrected
+	// nodes identical to the deleted ones.
+	flush := func(last []byte) error {
+		var (
+			began   = time.Now()
+			deleted int
+		)
+		live.lock.Lock()
+		for _, key := range keys {
+			if live.bloom.Contain(key) {
+				skipped++
+				continue
+			}
+			batch.Delete(key)
+			deleted++
+		}
+		rawdb.WriteOnlinePruningMarker(batch, last)
+		err := batch.Write()
+		live.lock.Unlock()
+
+		if err != nil {
+			return err
+		}
+		batch.Reset()
+		keys = keys[:0]
+		count += deleted
+
+		if p.config.Rate > 0 {
+			wait := time.Duration(deleted)*time.Second/time.Duration(p.config.Rate) - time.Since(began)
+			select {
+			case <-time.After(wait):
+			case <-p.quit:
+				return snapshot.ErrAborted
+			}
+		}
+		return nil
+	}
+	for iter.Next() {
+		scanned++
+		if scanned%1000 == 0 {
+			select {
+			case <-p.quit:
+				return snapshot.ErrAborted
+			default:
+			}
+		}
+		key := iter.Key()
+		if len(key) != common.HashLength {
+			continue
+		}
+		if live.contain(key) {
+			skipped++
+			continue
+		}
+		keys = append(keys, common.CopyBytes(key))
+		size += common.StorageSize(len(key) + len(iter.Value()))
+
+		if len(keys) >= onlineSweepBatch {
+			last := common.CopyBytes(key)
+			if err := flush(last); err != nil {
+				return err
+			}
+			// Recreate the iterator after every batch commit in order
+			// to allow the underlying compactor to delete the entries.
+			iter.Release()
+			iter = p.db.NewIterator(nil, last)
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Pruning state data online", "nodes", count, "skipped", skipped, "size", size,
+				"at", common.BytesToHash(key), "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return err
+	}
+	if err := flush(nil); err != nil {
+		return err
+	}
+	rawdb.DeleteOnlinePruningMarker(p.db)
+	log.Info("Pruned state data online", "nodes", count, "size", size, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// sortedKeys returns the hashes in the set in ascending order.
+func sortedKeys(set map[common.Hash]struct{}) []common.Hash {
+	keys := make([]common.Hash, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return bytes.Compare(keys[i][:], keys[j][:]) < 0
+	})
+	return keys
+}
+
+// onPath reports whether any of the sorted keys starts with the given path in
+// hex-nibble encoding, with or without terminator.
+func onPath(keys []common.Hash, path []byte) bool {
+	if len(path) > 0 && path[len(path)-1] == 16 {
+		path = path[:len(path)-1]
+	}
+	compare := func(key common.Hash) int {
+		for i, nibble := range path {
+			n := key[i/2] >> 4
+			if i%2 == 1 {
+				n = key[i/2] & 0x0f
+			}
+			if n != nibble {
+				if n < nibble {
+					return -1
+				}
+				return 1
+			}
+		}
+		return 0
+	}
+	i := sort.Search(len(keys), func(i int) bool { return compare(keys[i]) >= 0 })
+	return i < len(keys) && compare(keys[i]) == 0
+}
@@ -0,0 +1,129 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/holiman/uint256"
+)
+
+// Reader provides the state of accounts to a StateDB created with
+// NewWithReader, in place of the tries and snapshots of a database.
+type Reader interface {
+	// Account returns the account at the given address, or nil if it does
+	// not exist.
+	Account(addr common.Address) *types.StateAccount
+
+	// Storage returns the value of a storage slot of the account.
+	Storage(addr common.Address, slot common.Hash) common.Hash
+
+	// Code returns the code of the account, which has the given hash.
+	Code(addr common.Address, codeHash common.Hash) []byte
+}
+
+// AccountChange is the modification of an account made in a StateDB created
+// with NewWithReader.
+type AccountChange struct {
+	Deleted bool                        // Whether the account was deleted
+	Reset   bool                        // Whether the storage was wiped, by deleting or recreating the account
+	Account *types.StateAccount         // Value of the account, nil if deleted
+	Code    []byte                      // Code of the account, nil if unchanged
+	Storage map[common.Hash]common.Hash // Values of the modified storage slots
+}
+
+// NewWithReader creates a state reading all accounts from the given reader,
+// for executing transactions whose modifications are retrieved with Changes
+// and Credits. The state can't be hashed or committed.
+//
+// Balance credits to accounts not accessed otherwise are deferred and not
+// applied to the accounts, so that they don't depend on the credited balance.
+func NewWithReader(reader Reader) *StateDB {
+	return &StateDB{
+		reader:               reader,
+		credits:              make(map[common.Address]*uint256.Int),
+		accounts:             make(map[common.Hash][]byte),
+		storages:             make(map[common.Hash]map[common.Hash][]byte),
+		accountsOrigin:       make(map[common.Address][]byte),
+		storagesOrigin:       make(map[common.Address]map[common.Hash][]byte),
+		stateObjects:         make(map[common.Address]*stateObject),
+		stateObjectsPending:  make(map[common.Address]struct{}),
+		stateObjectsDirty:    make(map[common.Address]struct{}),
+		stateObjectsDestruct: make(map[common.Address]*types.StateAccount),
+		logs:                 make(map[common.Hash][]*types.Log),
+		preimages:            make(map[common.Hash][]byte),
+		journal:              newJournal(),
+		accessList:           newAccessList(),
+		transientStorage:     newTransientStorage(),
+		hasher:               crypto.NewKeccakState(),
+	}
+}
+
+// loadReaderObject loads an account from the reader, applying any deferred
+// credit to it.
+func (s *StateDB) loadReaderObject(addr common.Address) *stateObject {
+	data := s.reader.Account(addr)
+	credit, ok := s.credits[addr]
+	if !ok {
+		if data == nil {
+			return nil
+		}
+		obj := newObject(s, addr, data)
+		s.setStateObject(obj)
+		return obj
+	}
+	// The account is credited, so it exists from now on. The load is reverted
+	// along with the credit, which is restored then.
+	obj := newObject(s, addr, data)
+	obj.data.Balance = new(uint256.Int).Add(obj.data.Balance, credit)
+	delete(s.credits, addr)
+	s.journal.append(creditLoadChange{account: &addr, credit: credit})
+	s.setStateObject(obj)
+	return obj
+}
+
+// Changes returns the modifications of the accounts made by the transactions
+// executed on the state, excluding the deferred credits. It must be called
+// after Finalise.
+func (s *StateDB) Changes() map[common.Address]*AccountChange {
+	changes := make(map[common.Address]*AccountChange, len(s.stateObjectsDirty))
+	for addr := range s.stateObjectsDirty {
+		obj := s.stateObjects[addr]
+		if obj.deleted {
+			changes[addr] = &AccountChange{Deleted: true, Reset: true}
+			continue
+		}
+		_, reset := s.stateObjectsDestruct[addr]
+		change := &AccountChange{
+			Reset:   reset,
+			Account: obj.data.Copy(),
+			Storage: obj.pendingStorage.Copy(),
+		}
+		if obj.dirtyCode {
+			change.Code = obj.code
+		}
+		changes[addr] = change
+	}
+	return changes
+}
+
+// Credits returns the deferred balance credits to accounts which weren't
+// accessed otherwise.
+func (s *StateDB) Credits() map[common.Address]*uint256.Int {
+	return s.credits
+}
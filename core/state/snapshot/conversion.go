@@ -64,6 +64,12 @@ func GenerateStorageTrieRoot(account common.Hash, it StorageIterator) (common.Ha
 // accounts as well as the corresponding storages and regenerate the whole state
 // (account trie + all storage tries).
 func GenerateTrie(snaptree *Tree, root common.Hash, src ethdb.Database, dst ethdb.KeyValueWriter) error {
+	return GenerateTrieWithAbort(snaptree, root, src, dst, nil)
+}
+
+// GenerateTrieWithAbort is the same as GenerateTrie, but aborts the generation
+// with ErrAborted once the given channel is closed.
+func GenerateTrieWithAbort(snaptree *Tree, root common.Hash, src ethdb.Database, dst ethdb.KeyValueWriter, abort chan struct{}) error {
 	// Traverse all state by snapshot, re-generate the whole state trie
 	acctIt, err := snaptree.AccountIterator(root, common.Hash{})
 	if err != nil {
@@ -73,6 +79,11 @@ func GenerateTrie(snaptree *Tree, root common.Hash, src ethdb.Database, dst ethd
 
 	scheme := snaptree.triedb.Scheme()
 	got, err := generateTrieRoot(dst, scheme, acctIt, common.Hash{}, stackTrieGenerate, func(dst ethdb.KeyValueWriter, accountHash, codeHash common.Hash, stat *generateStats) (common.Hash, error) {
+		select {
+		case <-abort:
+			return common.Hash{}, ErrAborted
+		default:
+		}
 		// Migrate the code first, commit the contract code into the tmp db.
 		if codeHash != types.EmptyCodeHash {
 			code := rawdb.ReadCode(src, codeHash)
@@ -50,6 +50,11 @@ var (
 	// smaller number to be on the safe side.
 	aggregatorItemLimit = aggregatorMemoryLimit / 42
 
+	// holdMemoryLimit is the maximum size of the bottom-most diff layer while
+	// the disk layer is held. Beyond it, the hold is released and the layer is
+	// flushed into the disk layer.
+	holdMemoryLimit = uint64(256 * 1024 * 1024)
+
 	// bloomTargetError is the target false positive rate when the aggregator
 	// layer is at its fullest. The actual value will probably move around up
 	// and down from this number, it's mostly a ballpark figure.
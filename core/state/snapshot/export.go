@@ -0,0 +1,561 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// The export file consists of the magic followed by a sequence of chunks, each
+// being a 4-byte big-endian payload length, the payload and its keccak256 hash.
+// The first chunk contains the exportHeader, the following ones lists of state
+// entries ordered by account and slot hash, and a zero length terminates the
+// file. Slots belong to the last preceding account, codes follow the first
+// account referencing them.
+const (
+	exportVersion = 1
+	maxChunkSize  = 16 * 1024 * 1024 // Maximum payload size accepted on import
+)
+
+var (
+	exportMagic     = []byte("gethsnap")
+	importStaging   = []byte("snapshot-import-") // Prefix of the entries staged during an import
+	exportChunkSize = 4 * 1024 * 1024            // Approximate payload size of the entry chunks, var for testing
+)
+
+// Kinds of the exported state entries.
+const (
+	exportAccount = iota // Account hash and slim account data
+	exportSlot           // Slot hash and value of the preceding account
+	exportCode           // Code hash and code
+)
+
+var (
+	errExportTruncated = errors.New("export file truncated")
+	errExportCorrupted = errors.New("export chunk hash mismatch")
+)
+
+// exportHeader is the first chunk of an export file.
+type exportHeader struct {
+	Version uint64
+	Header  *types.Header // Header of the block whose state is exported
+}
+
+// exportEntry is a state entry in an export file.
+type exportEntry struct {
+	Kind  uint8
+	Key   common.Hash
+	Value []byte
+}
+
+// writeChunk writes a length-prefixed payload along with its hash.
+func writeChunk(w io.Writer, payload []byte) error {
+	var size [4]byte
+	binary.BigEndian.PutUint32(size[:], uint32(len(payload)))
+	if _, err := w.Write(size[:]); err != nil {
+		return err
+	}
+	if _, err := w.Write(payload); err != nil {
+		return err
+	}
+	_, err := w.Write(crypto.Keccak256(payload))
+	return err
+}
+
+// readChunk reads the next chunk, verifying its hash. It returns nil at the end
+// of the file.
+func readChunk(r io.Reader) ([]byte, error) {
+	var size [4]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return nil, errExportTruncated
+	}
+	n := binary.BigEndian.Uint32(size[:])
+	if n == 0 {
+		return nil, nil
+	}
+	if n > maxChunkSize {
+		return nil, fmt.Errorf("export chunk too large: %d bytes", n)
+	}
+	payload := make([]byte, n+common.HashLength)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, errExportTruncated
+	}
+	payload, hash := payload[:n], payload[n:]
+	if !bytes.Equal(crypto.Keccak256(payload), hash) {
+		return nil, errExportCorrupted
+	}
+	return payload, nil
+}
+
+// exportWriter accumulates state entries into chunks.
+type exportWriter struct {
+	w       io.Writer
+	entries []exportEntry
+	size    int
+	chunks  uint64
+}
+
+func (ew *exportWriter) add(kind uint8, key common.Hash, value []byte) error {
+	ew.entries = append(ew.entries, exportEntry{Kind: kind, Key: key, Value: value})
+	if ew.size += common.HashLength + len(value); ew.size >= exportChunkSize {
+		return ew.flush()
+	}
+	return nil
+}
+
+func (ew *exportWriter) flush() error {
+	if len(ew.entries) == 0 {
+		return nil
+	}
+	payload, err := rlp.EncodeToBytes(ew.entries)
+	if err != nil {
+		return err
+	}
+	ew.entries, ew.size = ew.entries[:0], 0
+	ew.chunks++
+	return writeChunk(ew.w, payload)
+}
+
+// Export streams the accounts, storage slots and contract codes of the state of
+// the given block from the snapshot into a chunked binary file, which can be
+// imported to rebuild the state by Import. Codes are read from the given
+// database.
+func Export(w io.Writer, snaptree *Tree, header *types.Header, codedb ethdb.KeyValueReader) error {
+	if _, err := w.Write(exportMagic); err != nil {
+		return err
+	}
+	payload, err := rlp.EncodeToBytes(&exportHeader{Version: exportVersion, Header: header})
+	if err != nil {
+		return err
+	}
+	if err := writeChunk(w, payload); err != nil {
+		return err
+	}
+	accIt, err := snaptree.AccountIterator(header.Root, common.Hash{})
+	if err != nil {
+		return err
+	}
+	defer accIt.Release()
+
+	var (
+		ew     = &exportWriter{w: w}
+		codes  = make(map[common.Hash]struct{})
+		start  = time.Now()
+		logged = time.Now()
+
+		accounts, slots uint64
+	)
+	for accIt.Next() {
+		account, err := types.FullAccount(accIt.Account())
+		if err != nil {
+			return err
+		}
+		if err := ew.add(exportAccount, accIt.Hash(), common.CopyBytes(accIt.Account())); err != nil {
+			return err
+		}
+		accounts++
+
+		codeHash := common.BytesToHash(account.CodeHash)
+		if _, ok := codes[codeHash]; !ok && codeHash != types.EmptyCodeHash {
+			code := rawdb.ReadCode(codedb, codeHash)
+			if len(code) == 0 {
+				return fmt.Errorf("missing code %x of account %x", codeHash, accIt.Hash())
+			}
+			if err := ew.add(exportCode, codeHash, code); err != nil {
+				return err
+			}
+			codes[codeHash] = struct{}{}
+		}
+		if account.Root != types.EmptyRootHash {
+			stIt, err := snaptree.StorageIterator(header.Root, accIt.Hash(), common.Hash{})
+			if err != nil {
+				return err
+			}
+			for stIt.Next() {
+				if err := ew.add(exportSlot, stIt.Hash(), common.CopyBytes(stIt.Slot())); err != nil {
+					stIt.Release()
+					return err
+				}
+				slots++
+			}
+			err = stIt.Error()
+			stIt.Release()
+			if err != nil {
+				return err
+			}
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Exporting state", "at", accIt.Hash(), "accounts", accounts, "slots", slots, "codes", len(codes),
+				"elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if err := accIt.Error(); err != nil {
+		return err
+	}
+	if err := ew.flush(); err != nil {
+		return err
+	}
+	if _, err := w.Write(make([]byte, 4)); err != nil {
+		return err
+	}
+	log.Info("Exported state", "number", header.Number, "root", header.Root, "accounts", accounts, "slots", slots,
+		"codes", len(codes), "chunks", ew.chunks, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// stagingWriter writes the entries of an import into the staging namespace, so
+// they don't touch the live state until verified.
+type stagingWriter struct {
+	batch ethdb.Batch
+}
+
+func (w *stagingWriter) Put(key []byte, value []byte) error {
+	return w.batch.Put(append(common.CopyBytes(importStaging), key...), value)
+}
+
+func (w *stagingWriter) Delete(key []byte) error {
+	return w.batch.Delete(append(common.CopyBytes(importStaging), key...))
+}
+
+// stateImporter rebuilds the snapshot and the tries from the exported entries,
+// staging them until the state root is verified.
+type stateImporter struct {
+	db     ethdb.KeyValueStore
+	batch  ethdb.Batch
+	staged ethdb.KeyValueWriter // Writer staging the entries into the batch
+	scheme string
+
+	accTrie  *trie.StackTrie
+	account  common.Hash         // Account whose slots are being imported
+	data     *types.StateAccount // Data of the account, nil before the first one
+	stTrie   *trie.StackTrie     // Storage trie of the account, nil if no slots
+	codes    map[common.Hash]struct{}
+	missing  map[common.Hash]common.Hash // Codes referenced but not imported yet, mapped to an account
+	accounts uint64
+	slots    uint64
+}
+
+func newStateImporter(db ethdb.KeyValueStore, scheme string) *stateImporter {
+	im := &stateImporter{
+		db:      db,
+		batch:   db.NewBatch(),
+		scheme:  scheme,
+		codes:   make(map[common.Hash]struct{}),
+		missing: make(map[common.Hash]common.Hash),
+	}
+	im.staged = &stagingWriter{batch: im.batch}
+	im.accTrie = trie.NewStackTrie(func(path []byte, hash common.Hash, blob []byte) {
+		rawdb.WriteTrieNode(im.staged, common.Hash{}, path, hash, blob, scheme)
+	})
+	return im
+}
+
+// process imports a state entry.
+func (im *stateImporter) process(entry *exportEntry) error {
+	switch entry.Kind {
+	case exportAccount:
+		if err := im.finishAccount(); err != nil {
+			return err
+		}
+		data, err := types.FullAccount(entry.Value)
+		if err != nil {
+			return fmt.Errorf("invalid account %x: %v", entry.Key, err)
+		}
+		im.account, im.data, im.stTrie = entry.Key, data, nil
+		rawdb.WriteAccountSnapshot(im.staged, entry.Key, entry.Value)
+
+		codeHash := common.BytesToHash(data.CodeHash)
+		if _, ok := im.codes[codeHash]; !ok && codeHash != types.EmptyCodeHash {
+			im.missing[codeHash] = entry.Key
+		}
+		im.accounts++
+
+	case exportSlot:
+		if im.data == nil {
+			return fmt.Errorf("slot %x without account", entry.Key)
+		}
+		if im.stTrie == nil {
+			owner := im.account
+			im.stTrie = trie.NewStackTrie(func(path []byte, hash common.Hash, blob []byte) {
+				rawdb.WriteTrieNode(im.staged, owner, path, hash, blob, im.scheme)
+			})
+		}
+		if err := im.stTrie.Update(entry.Key[:], entry.Value); err != nil {
+			return fmt.Errorf("invalid slot %x of account %x: %v", entry.Key, im.account, err)
+		}
+		rawdb.WriteStorageSnapshot(im.staged, im.account, entry.Key, entry.Value)
+		im.slots++
+
+	case exportCode:
+		if crypto.Keccak256Hash(entry.Value) != entry.Key {
+			return fmt.Errorf("code hash mismatch: %x", entry.Key)
+		}
+		rawdb.WriteCode(im.staged, entry.Key, entry.Value)
+		im.codes[entry.Key] = struct{}{}
+		delete(im.missing, entry.Key)
+
+	default:
+		return fmt.Errorf("unknown entry kind %d", entry.Kind)
+	}
+	if im.batch.ValueSize() > ethdb.IdealBatchSize {
+		if err := im.batch.Write(); err != nil {
+			return err
+		}
+		im.batch.Reset()
+	}
+	return nil
+}
+
+// finishAccount verifies the storage root of the current account and inserts it
+// into the account trie.
+func (im *stateImporter) finishAccount() error {
+	if im.data == nil {
+		return nil
+	}
+	root := types.EmptyRootHash
+	if im.stTrie != nil {
+		root = im.stTrie.Hash()
+	}
+	if root != im.data.Root {
+		return fmt.Errorf("storage root mismatch of account %x: have %x, want %x", im.account, root, im.data.Root)
+	}
+	blob, err := rlp.EncodeToBytes(im.data)
+	if err != nil {
+		return err
+	}
+	if err := im.accTrie.Update(im.account[:], blob); err != nil {
+		return fmt.Errorf("invalid account %x: %v", im.account, err)
+	}
+	im.data = nil
+	return nil
+}
+
+// finish completes the import, returning the root of the state.
+func (im *stateImporter) finish() (common.Hash, error) {
+	if err := im.finishAccount(); err != nil {
+		return common.Hash{}, err
+	}
+	for codeHash, account := range im.missing {
+		return common.Hash{}, fmt.Errorf("missing code %x of account %x", codeHash, account)
+	}
+	root := im.accTrie.Hash()
+	if err := im.batch.Write(); err != nil {
+		return common.Hash{}, err
+	}
+	im.batch.Reset()
+	return root, nil
+}
+
+// iterateStaged calls fn with the keys (without the staging prefix) and values
+// of all the staged entries, writing the batch whenever it grows too large.
+func iterateStaged(db ethdb.KeyValueStore, fn func(batch ethdb.Batch, key, value []byte) error) error {
+	var (
+		batch = db.NewBatch()
+		it    = db.NewIterator(importStaging, nil)
+	)
+	defer it.Release()
+
+	for it.Next() {
+		if err := fn(batch, it.Key()[len(importStaging):], it.Value()); err != nil {
+			return err
+		}
+		if batch.ValueSize() > ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+	return batch.Write()
+}
+
+// dropStaged deletes all the staged entries, e.g. of a failed import.
+func dropStaged(db ethdb.KeyValueStore) error {
+	return iterateStaged(db, func(batch ethdb.Batch, key, value []byte) error {
+		return batch.Delete(append(common.CopyBytes(importStaging), key...))
+	})
+}
+
+// commitStaged moves all the staged entries into the live database.
+func commitStaged(db ethdb.KeyValueStore) error {
+	return iterateStaged(db, func(batch ethdb.Batch, key, value []byte) error {
+		if err := batch.Put(key, value); err != nil {
+			return err
+		}
+		return batch.Delete(append(common.CopyBytes(importStaging), key...))
+	})
+}
+
+// wipeSnapshot deletes all the entries of the snapshot in the database.
+func wipeSnapshot(db ethdb.KeyValueStore) error {
+	batch := db.NewBatch()
+	for _, prefix := range []struct {
+		prefix []byte
+		keylen int
+	}{
+		{rawdb.SnapshotAccountPrefix, len(rawdb.SnapshotAccountPrefix) + common.HashLength},
+		{rawdb.SnapshotStoragePrefix, len(rawdb.SnapshotStoragePrefix) + 2*common.HashLength},
+	} {
+		it := db.NewIterator(prefix.prefix, nil)
+		for it.Next() {
+			if len(it.Key()) != prefix.keylen {
+				continue
+			}
+			batch.Delete(it.Key())
+			if batch.ValueSize() > ethdb.IdealBatchSize {
+				if err := batch.Write(); err != nil {
+					it.Release()
+					return err
+				}
+				batch.Reset()
+			}
+		}
+		it.Release()
+		if err := it.Error(); err != nil {
+			return err
+		}
+	}
+	return batch.Write()
+}
+
+// Import rebuilds the snapshot and the tries of the given scheme from a file
+// created by Export, verifying that the file contains the state of the block
+// with the trusted hash. On success, the snapshot is marked as complete and the
+// header of the block returned.
+//
+// The entries are staged in a separate namespace until the state root of the
+// whole file is verified, so a truncated, corrupted or foreign file leaves the
+// database untouched. Only then is any existing snapshot replaced. With the path
+// scheme, the database must not contain any state yet, as nodes are stored by
+// path.
+func Import(r io.Reader, db ethdb.KeyValueStore, scheme string, trusted common.Hash) (*types.Header, error) {
+	// Drop the leftovers of any previously interrupted import
+	if err := dropStaged(db); err != nil {
+		return nil, err
+	}
+	header, err := importStaged(r, db, scheme, trusted)
+	if err != nil {
+		if derr := dropStaged(db); derr != nil {
+			log.Error("Failed to drop staged state", "err", derr)
+		}
+		return nil, err
+	}
+	// The state is verified, drop the previous snapshot and move the new one in
+	// place. The generator marker is only written once the new one is complete.
+	rawdb.DeleteSnapshotRoot(db)
+	rawdb.DeleteSnapshotGenerator(db)
+	rawdb.DeleteSnapshotJournal(db)
+	if err := wipeSnapshot(db); err != nil {
+		return nil, err
+	}
+	if err := commitStaged(db); err != nil {
+		return nil, err
+	}
+	batch := db.NewBatch()
+	rawdb.WriteSnapshotRoot(batch, header.Root)
+	journalProgress(batch, nil, nil)
+	if err := batch.Write(); err != nil {
+		return nil, err
+	}
+	return header, nil
+}
+
+// importStaged reads the export file into the staging namespace, returning the
+// header of the block once its state root is verified.
+func importStaged(r io.Reader, db ethdb.KeyValueStore, scheme string, trusted common.Hash) (*types.Header, error) {
+	if scheme == rawdb.PathScheme {
+		if blob, _ := rawdb.ReadAccountTrieNode(db, nil); len(blob) != 0 {
+			return nil, errors.New("database already contains path-based state")
+		}
+	}
+	magic := make([]byte, len(exportMagic))
+	if _, err := io.ReadFull(r, magic); err != nil || !bytes.Equal(magic, exportMagic) {
+		return nil, errors.New("not a state export file")
+	}
+	payload, err := readChunk(r)
+	if err != nil {
+		return nil, err
+	}
+	var head exportHeader
+	if err := rlp.DecodeBytes(payload, &head); err != nil {
+		return nil, fmt.Errorf("invalid export header: %v", err)
+	}
+	if head.Version != exportVersion {
+		return nil, fmt.Errorf("unsupported export version %d", head.Version)
+	}
+	if hash := head.Header.Hash(); hash != trusted {
+		return nil, fmt.Errorf("untrusted header: have %x, want %x", hash, trusted)
+	}
+	log.Info("Importing state", "number", head.Header.Number, "hash", trusted, "root", head.Header.Root, "scheme", scheme)
+
+	var (
+		im     = newStateImporter(db, scheme)
+		start  = time.Now()
+		logged = time.Now()
+	)
+	for {
+		payload, err := readChunk(r)
+		if err != nil {
+			return nil, err
+		}
+		if payload == nil {
+			break
+		}
+		var entries []exportEntry
+		if err := rlp.DecodeBytes(payload, &entries); err != nil {
+			return nil, fmt.Errorf("invalid export chunk: %v", err)
+		}
+		for i := range entries {
+			if err := im.process(&entries[i]); err != nil {
+				return nil, err
+			}
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Importing state", "at", im.account, "accounts", im.accounts, "slots", im.slots, "codes", len(im.codes),
+				"elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	root, err := im.finish()
+	if err != nil {
+		return nil, err
+	}
+	if root != head.Header.Root {
+		return nil, fmt.Errorf("state root mismatch: have %x, want %x", root, head.Header.Root)
+	}
+	log.Info("Imported state", "number", head.Header.Number, "root", root, "accounts", im.accounts, "slots", im.slots,
+		"codes", len(im.codes), "elapsed", common.PrettyDuration(time.Since(start)))
+	return head.Header, nil
+}
@@ -0,0 +1,204 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/ethereum/go-ethereum/triedb"
+	"github.com/ethereum/go-ethereum/triedb/hashdb"
+	"github.com/ethereum/go-ethereum/triedb/pathdb"
+	"github.com/holiman/uint256"
+)
+
+// makeExportState creates a state with accounts with and without storage and
+// code, generates its snapshot and exports it.
+func makeExportState(t *testing.T) (*types.Header, []byte) {
+	t.Helper()
+
+	helper := newHelper(rawdb.HashScheme)
+	for i := 0; i < 64; i++ {
+		var (
+			key  = fmt.Sprintf("acc-%d", i)
+			acc  = &types.StateAccount{Balance: uint256.NewInt(uint64(i)), Root: types.EmptyRootHash, CodeHash: types.EmptyCodeHash.Bytes()}
+			keys []string
+			vals []string
+		)
+		for j := 0; j < i%8*10; j++ {
+			keys = append(keys, fmt.Sprintf("key-%d", j))
+			vals = append(vals, fmt.Sprintf("val-%d-%d", i, j))
+		}
+		if len(keys) > 0 {
+			acc.Root = helper.makeStorageTrie(hashData([]byte(key)), keys, vals, true)
+		}
+		if i%3 == 0 {
+			code := []byte{byte(i % 9)} // Shared by multiple accounts
+			acc.CodeHash = crypto.Keccak256(code)
+			rawdb.WriteCode(helper.diskdb, common.BytesToHash(acc.CodeHash), code)
+		}
+		helper.addTrieAccount(key, acc)
+	}
+	root, snap := helper.CommitAndGenerate()
+	select {
+	case <-snap.genPending:
+	case <-time.After(3 * time.Second):
+		t.Fatal("Snapshot generation failed")
+	}
+	var (
+		snaps  = &Tree{diskdb: helper.diskdb, triedb: helper.triedb, layers: map[common.Hash]snapshot{root: snap}}
+		header = &types.Header{Number: big.NewInt(1), Root: root, Difficulty: common.Big0}
+		buf    bytes.Buffer
+	)
+	if err := Export(&buf, snaps, header, helper.diskdb); err != nil {
+		t.Fatalf("Failed to export state: %v", err)
+	}
+	return header, buf.Bytes()
+}
+
+func TestExportImport(t *testing.T) {
+	defer func(size int) { exportChunkSize = size }(exportChunkSize)
+	exportChunkSize = 1024
+
+	header, blob := makeExportState(t)
+	for _, scheme := range []string{rawdb.HashScheme, rawdb.PathScheme} {
+		db := rawdb.NewMemoryDatabase()
+		imported, err := Import(bytes.NewReader(blob), db, scheme, header.Hash())
+		if err != nil {
+			t.Fatalf("%s: failed to import state: %v", scheme, err)
+		}
+		if imported.Hash() != header.Hash() {
+			t.Fatalf("%s: wrong header imported", scheme)
+		}
+		config := &triedb.Config{HashDB: &hashdb.Config{}}
+		if scheme == rawdb.PathScheme {
+			config = &triedb.Config{PathDB: &pathdb.Config{}}
+		}
+		tdb := triedb.NewDatabase(db, config)
+
+		// Check that all the tries and codes are complete
+		accTrie, err := trie.NewStateTrie(trie.StateTrieID(header.Root), tdb)
+		if err != nil {
+			t.Fatalf("%s: failed to open account trie: %v", scheme, err)
+		}
+		accIt := trie.NewIterator(accTrie.MustNodeIterator(nil))
+		var accounts, slots int
+		for accIt.Next() {
+			acc, err := types.FullAccount(accIt.Value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			accounts++
+			if !bytes.Equal(acc.CodeHash, types.EmptyCodeHash.Bytes()) && !rawdb.HasCode(db, common.BytesToHash(acc.CodeHash)) {
+				t.Errorf("%s: missing code of account %x", scheme, accIt.Key)
+			}
+			id := trie.StorageTrieID(header.Root, common.BytesToHash(accIt.Key), acc.Root)
+			stTrie, err := trie.NewStateTrie(id, tdb)
+			if err != nil {
+				t.Fatalf("%s: failed to open storage trie: %v", scheme, err)
+			}
+			stIt := trie.NewIterator(stTrie.MustNodeIterator(nil))
+			for stIt.Next() {
+				slots++
+			}
+			if stIt.Err != nil {
+				t.Fatalf("%s: failed to iterate storage trie: %v", scheme, stIt.Err)
+			}
+		}
+		if accIt.Err != nil {
+			t.Fatalf("%s: failed to iterate account trie: %v", scheme, accIt.Err)
+		}
+		if accounts != 64 || slots != 8*(0+10+20+30+40+50+60+70) {
+			t.Errorf("%s: wrong state size: %d accounts, %d slots", scheme, accounts, slots)
+		}
+		// Check that the snapshot is complete and usable
+		snaps, err := New(Config{CacheSize: 16, NoBuild: true}, db, tdb, header.Root)
+		if err != nil {
+			t.Fatalf("%s: failed to load snapshot: %v", scheme, err)
+		}
+		if err := snaps.Verify(header.Root); err != nil {
+			t.Fatalf("%s: failed to verify snapshot: %v", scheme, err)
+		}
+	}
+}
+
+func TestImportInvalid(t *testing.T) {
+	defer func(size int) { exportChunkSize = size }(exportChunkSize)
+	exportChunkSize = 1024
+
+	header, blob := makeExportState(t)
+
+	corrupted := common.CopyBytes(blob)
+	corrupted[len(corrupted)/2] ^= 0xff
+
+	tests := []struct {
+		name    string
+		blob    []byte
+		trusted common.Hash
+		err     error
+	}{
+		{"untrusted", blob, common.Hash{0x1}, nil},
+		{"truncated", blob[:len(blob)-4], header.Hash(), errExportTruncated},
+		{"corrupted", corrupted, header.Hash(), errExportCorrupted},
+	}
+	for _, scheme := range []string{rawdb.HashScheme, rawdb.PathScheme} {
+		for _, tt := range tests {
+			db := rawdb.NewMemoryDatabase()
+			if scheme == rawdb.HashScheme {
+				// Failed imports must leave a previously imported state intact
+				if _, err := Import(bytes.NewReader(blob), db, scheme, header.Hash()); err != nil {
+					t.Fatalf("%s: failed to import state: %v", scheme, err)
+				}
+			}
+			before := dumpDatabase(t, db)
+
+			_, err := Import(bytes.NewReader(tt.blob), db, scheme, tt.trusted)
+			if err == nil || (tt.err != nil && err != tt.err) {
+				t.Errorf("%s/%s: wrong error: have %v, want %v", scheme, tt.name, err, tt.err)
+			}
+			if after := dumpDatabase(t, db); !reflect.DeepEqual(before, after) {
+				t.Errorf("%s/%s: database modified by failed import: %d entries before, %d after", scheme, tt.name, len(before), len(after))
+			}
+		}
+	}
+}
+
+// dumpDatabase returns all the entries of the database.
+func dumpDatabase(t *testing.T, db ethdb.KeyValueStore) map[string]string {
+	t.Helper()
+
+	entries := make(map[string]string)
+	it := db.NewIterator(nil, nil)
+	defer it.Release()
+	for it.Next() {
+		entries[string(it.Key())] = string(it.Value())
+	}
+	if err := it.Error(); err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}
@@ -91,6 +91,10 @@ var (
 	// while the generation is not finished yet.
 	ErrNotConstructed = errors.New("snapshot is not constructed")
 
+	// ErrAborted is returned if a long running operation on the snapshot was
+	// interrupted by the caller.
+	ErrAborted = errors.New("snapshot operation aborted")
+
 	// errSnapshotCycle is returned if a snapshot is attempted to be inserted
 	// that forms a cycle in the snapshot tree.
 	errSnapshotCycle = errors.New("snapshot cycle")
@@ -166,11 +170,13 @@ type Config struct {
 // storage data to avoid expensive multi-level trie lookups; and to allow sorted,
 // cheap iteration of the account/storage tries for sync aid.
 type Tree struct {
-	config Config                   // Snapshots configurations
-	diskdb ethdb.KeyValueStore      // Persistent database to store the snapshot
-	triedb *triedb.Database         // In-memory cache to access the trie through
-	layers map[common.Hash]snapshot // Collection of all known layers
-	lock   sync.RWMutex
+	config   Config                   // Snapshots configurations
+	diskdb   ethdb.KeyValueStore      // Persistent database to store the snapshot
+	triedb   *triedb.Database         // In-memory cache to access the trie through
+	layers   map[common.Hash]snapshot // Collection of all known layers
+	held     bool                     // Whether the disk layer is held from modifications
+	released chan struct{}            // Channel closed when the hold is released by a flush
+	lock     sync.RWMutex
 
 	// Test hooks
 	onFlatten func() // Hook invoked when the bottom most diff layers are flattened
@@ -500,11 +506,12 @@ func (t *Tree) cap(diff *diffLayer, layers int) *diskLayer {
 			t.onFlatten()
 		}
 		diff.parent = flattened
-		if flattened.memory < aggregatorMemoryLimit {
-			// Accumulator layer is smaller than the limit, so we can abort, unless
-			// there's a snapshot being generated currently. In that case, the trie
-			// will move from underneath the generator so we **must** merge all the
-			// partial data down into the snapshot and restart the generation.
+		if flattened.memory < aggregatorMemoryLimit || (t.held && flattened.memory < holdMemoryLimit) {
+			// Accumulator layer is smaller than the limit, or than the larger one
+			// of the held disk layer, so we can abort, unless there's a snapshot
+			// being generated currently. In that case, the trie will move from
+			// underneath the generator so we **must** merge all the partial data
+			// down into the snapshot and restart the generation.
 			if flattened.parent.(*diskLayer).genAbort == nil {
 				return nil
 			}
@@ -515,6 +522,13 @@ func (t *Tree) cap(diff *diffLayer, layers int) *diskLayer {
 	// If the bottom-most layer is larger than our memory cap, persist to disk
 	bottom := diff.parent.(*diffLayer)
 
+	// The held disk layer is modified, notify the holder it's released.
+	if t.held {
+		log.Warn("Releasing held snapshot disk layer", "memory", common.StorageSize(bottom.memory))
+		t.held = false
+		close(t.released)
+	}
+
 	bottom.lock.RLock()
 	base := diffToDisk(bottom)
 	bottom.lock.RUnlock()
@@ -860,6 +874,87 @@ func (t *Tree) generating() (bool, error) {
 	return layer.genMarker != nil, nil
 }
 
+// Hold prevents the diff layers from being merged into the disk layer, so that
+// the disk layer can be iterated while the chain progresses. In the meantime,
+// the accumulator layer grows up to holdMemoryLimit, beyond which it's merged
+// into the disk layer regardless, releasing the hold and closing the returned
+// channel. The snapshot must be fully generated.
+//
+// The disk layer is still replaced by full commits or rebuilds of the snapshot,
+// which render it stale.
+func (t *Tree) Hold() (<-chan struct{}, error) {
+	generating, err := t.generating()
+	if err != nil {
+		return nil, err
+	}
+	if generating {
+		return nil, ErrNotConstructed
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.held = true
+	t.released = make(chan struct{})
+	return t.released, nil
+}
+
+// Unhold allows the diff layers to be merged into the disk layer again.
+func (t *Tree) Unhold() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.held = false
+}
+
+// Diff is the set of state entries modified by a diff layer.
+type Diff struct {
+	Root      common.Hash                   // Root hash of the diff layer
+	Parent    common.Hash                   // Root hash of the parent layer
+	Destructs []common.Hash                 // Hashes of the deleted (and potentially recreated) accounts
+	Accounts  []common.Hash                 // Hashes of the modified accounts
+	Storage   map[common.Hash][]common.Hash // Hashes of the modified storage slots, keyed by account
+}
+
+// Diffs returns the entries modified by each of the diff layers in the tree,
+// including the ones not descending from the current head, along with the root
+// of the disk layer they are based on.
+func (t *Tree) Diffs() (common.Hash, []Diff) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	var diffs []Diff
+	for root, layer := range t.layers {
+		diff, ok := layer.(*diffLayer)
+		if !ok {
+			continue
+		}
+		diff.lock.RLock()
+		d := Diff{
+			Root:      root,
+			Parent:    diff.parent.Root(),
+			Destructs: make([]common.Hash, 0, len(diff.destructSet)),
+			Accounts:  make([]common.Hash, 0, len(diff.accountData)),
+			Storage:   make(map[common.Hash][]common.Hash, len(diff.storageData)),
+		}
+		for hash := range diff.destructSet {
+			d.Destructs = append(d.Destructs, hash)
+		}
+		for hash := range diff.accountData {
+			d.Accounts = append(d.Accounts, hash)
+		}
+		for account, slots := range diff.storageData {
+			hashes := make([]common.Hash, 0, len(slots))
+			for hash := range slots {
+				hashes = append(hashes, hash)
+			}
+			d.Storage[account] = hashes
+		}
+		diff.lock.RUnlock()
+		diffs = append(diffs, d)
+	}
+	return t.diskRoot(), diffs
+}
+
 // DiskRoot is a external helper function to return the disk layer root.
 func (t *Tree) DiskRoot() common.Hash {
 	t.lock.Lock()
@@ -489,3 +489,71 @@ func TestReadStateDuringFlattening(t *testing.T) {
 		t.Fatal("Unexpected blocker")
 	}
 }
+
+// Tests that the bottom-most diff layer doesn't grow beyond the memory limit of
+// a held disk layer, releasing the hold instead.
+func TestHoldMemoryLimit(t *testing.T) {
+	defer func(memcap uint64) { aggregatorMemoryLimit = memcap }(aggregatorMemoryLimit)
+	aggregatorMemoryLimit = 0
+	defer func(memcap uint64) { holdMemoryLimit = memcap }(holdMemoryLimit)
+	holdMemoryLimit = 4096
+
+	// Create an empty base layer and a snapshot tree out of it
+	base := &diskLayer{
+		diskdb: rawdb.NewMemoryDatabase(),
+		root:   common.HexToHash("0x01"),
+		cache:  fastcache.New(1024 * 500),
+	}
+	snaps := &Tree{
+		layers: map[common.Hash]snapshot{
+			base.root: base,
+		},
+	}
+	released, err := snaps.Hold()
+	if err != nil {
+		t.Fatalf("failed to hold disk layer: %v", err)
+	}
+	// Stack diff layers on top, capping the tree after each, until the hold is
+	// released by the bottom-most layer outgrowing the limit
+	parent := base.root
+	for i := 0; ; i++ {
+		if i == 1000 {
+			t.Fatal("hold not released")
+		}
+		root := common.BytesToHash([]byte{0x02, byte(i >> 8), byte(i)})
+		accounts := map[common.Hash][]byte{randomHash(): randomAccount()}
+		if err := snaps.Update(root, parent, nil, accounts, nil); err != nil {
+			t.Fatalf("failed to create diff layer %d: %v", i, err)
+		}
+		if err := snaps.Cap(root, 1); err != nil {
+			t.Fatalf("failed to cap diff layer %d: %v", i, err)
+		}
+		parent = root
+
+		var bottom *diffLayer
+		for _, layer := range snaps.layers {
+			if diff, ok := layer.(*diffLayer); ok {
+				if _, ok := diff.parent.(*diskLayer); ok {
+					bottom = diff
+				}
+			}
+		}
+		if bottom != nil && bottom.memory >= holdMemoryLimit {
+			t.Fatalf("layer %d: bottom-most diff layer exceeds limit: have %d, limit %d", i, bottom.memory, holdMemoryLimit)
+		}
+		select {
+		case <-released:
+			if snaps.DiskRoot() == base.root {
+				t.Fatalf("layer %d: hold released without flushing the disk layer", i)
+			}
+			if snaps.held {
+				t.Fatalf("layer %d: disk layer still held after release", i)
+			}
+			return
+		default:
+			if root := snaps.DiskRoot(); root != base.root {
+				t.Fatalf("layer %d: held disk layer modified: have %x, want %x", i, root, base.root)
+			}
+		}
+	}
+}
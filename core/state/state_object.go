@@ -188,6 +188,11 @@ func (s *stateObject) GetCommittedState(key common.Hash) common.Hash {
 	if _, destructed := s.db.stateObjectsDestruct[s.address]; destructed {
 		return common.Hash{}
 	}
+	if s.db.reader != nil {
+		value := s.db.reader.Storage(s.address, key)
+		s.originStorage[key] = value
+		return value
+	}
 	// If no live objects are available, attempt to use snapshots
 	var (
 		enc   []byte
@@ -476,6 +481,10 @@ func (s *stateObject) Code() []byte {
 	if bytes.Equal(s.CodeHash(), types.EmptyCodeHash.Bytes()) {
 		return nil
 	}
+	if s.db.reader != nil {
+		s.code = s.db.reader.Code(s.address, common.BytesToHash(s.CodeHash()))
+		return s.code
+	}
 	code, err := s.db.db.ContractCode(s.address, common.BytesToHash(s.CodeHash()))
 	if err != nil {
 		s.db.setError(fmt.Errorf("can't load code hash %x: %v", s.CodeHash(), err))
@@ -494,6 +503,9 @@ func (s *stateObject) CodeSize() int {
 	if bytes.Equal(s.CodeHash(), types.EmptyCodeHash.Bytes()) {
 		return 0
 	}
+	if s.db.reader != nil {
+		return len(s.Code())
+	}
 	size, err := s.db.db.ContractCodeSize(s.address, common.BytesToHash(s.CodeHash()))
 	if err != nil {
 		s.db.setError(fmt.Errorf("can't load code size %x: %v", s.CodeHash(), err))
@@ -65,6 +65,10 @@ type StateDB struct {
 	hasher     crypto.KeccakState
 	snaps      *snapshot.Tree    // Nil if snapshot is not available
 	snap       snapshot.Snapshot // Nil if snapshot is not available
+	reader     Reader            // Nil if the state is read from the database
+
+	// Balance credits to accounts not loaded yet, only deferred with a reader.
+	credits map[common.Address]*uint256.Int
 
 	// originalRoot is the pre-state root, before any changes were made.
 	// It will be updated when the Commit is called.
@@ -374,6 +378,17 @@ func (s *StateDB) HasSelfDestructed(addr common.Address) bool {
 
 // AddBalance adds amount to the account associated with addr.
 func (s *StateDB) AddBalance(addr common.Address, amount *uint256.Int) {
+	// Defer credits to accounts not loaded yet if supported. Zero credits are
+	// not deferred, as they touch the account depending on its emptiness.
+	if s.credits != nil && !amount.IsZero() && s.stateObjects[addr] == nil {
+		prev := s.credits[addr]
+		s.journal.append(creditChange{account: &addr, prev: prev})
+		if prev == nil {
+			prev = new(uint256.Int)
+		}
+		s.credits[addr] = new(uint256.Int).Add(prev, amount)
+		return
+	}
 	stateObject := s.getOrNewStateObject(addr)
 	if stateObject != nil {
 		stateObject.AddBalance(amount)
@@ -562,6 +577,9 @@ func (s *StateDB) getDeletedStateObject(addr common.Address) *stateObject {
 	if obj := s.stateObjects[addr]; obj != nil {
 		return obj
 	}
+	if s.reader != nil {
+		return s.loadReaderObject(addr)
+	}
 	// If no live objects are available, attempt to use snapshots
 	var data *types.StateAccount
 	if s.snap != nil {
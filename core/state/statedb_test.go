@@ -1189,3 +1189,68 @@ func TestDeleteStorage(t *testing.T) {
 		t.Fatalf("difference found:\nfast: %v\nslow: %v\n", fastRes, slowRes)
 	}
 }
+
+// mapReader is a state.Reader serving accounts from a map.
+type mapReader map[common.Address]*types.StateAccount
+
+func (r mapReader) Account(addr common.Address) *types.StateAccount {
+	if acct, ok := r[addr]; ok {
+		return acct.Copy()
+	}
+	return nil
+}
+
+func (r mapReader) Storage(addr common.Address, slot common.Hash) common.Hash {
+	return common.Hash{}
+}
+
+func (r mapReader) Code(addr common.Address, codeHash common.Hash) []byte {
+	return nil
+}
+
+func TestStateDBReaderCredits(t *testing.T) {
+	var (
+		funded  = common.HexToAddress("0x01")
+		fresh   = common.HexToAddress("0x02")
+		touched = common.HexToAddress("0x03")
+		reader  = mapReader{funded: {Balance: uint256.NewInt(10), Root: types.EmptyRootHash, CodeHash: types.EmptyCodeHash.Bytes()}}
+		state   = NewWithReader(reader)
+	)
+	// Credits to accounts not loaded are deferred.
+	state.AddBalance(funded, uint256.NewInt(1))
+	state.AddBalance(fresh, uint256.NewInt(2))
+	state.AddBalance(touched, uint256.NewInt(3))
+	if len(state.stateObjects) != 0 {
+		t.Fatal("Credited accounts loaded")
+	}
+	// Loading an account applies the credit, reverting restores it.
+	id := state.Snapshot()
+	if balance := state.GetBalance(funded); balance.Uint64() != 11 {
+		t.Fatalf("Wrong balance: have %d, want 11", balance)
+	}
+	if !state.Exist(fresh) {
+		t.Fatal("Credited account doesn't exist")
+	}
+	state.SubBalance(fresh, uint256.NewInt(2))
+	state.RevertToSnapshot(id)
+	if len(state.stateObjects) != 0 {
+		t.Fatal("Load of credited accounts not reverted")
+	}
+	// Reverting the credit removes it.
+	id = state.Snapshot()
+	state.AddBalance(touched, uint256.NewInt(4))
+	state.RevertToSnapshot(id)
+	if balance := state.GetBalance(touched); balance.Uint64() != 3 {
+		t.Fatalf("Wrong balance: have %d, want 3", balance)
+	}
+	state.Finalise(true)
+
+	credits := state.Credits()
+	if len(credits) != 2 || credits[funded].Uint64() != 1 || credits[fresh].Uint64() != 2 {
+		t.Fatalf("Wrong credits: %v", credits)
+	}
+	changes := state.Changes()
+	if len(changes) != 1 || changes[touched] == nil || changes[touched].Account.Balance.Uint64() != 3 {
+		t.Fatalf("Wrong changes: %v", changes)
+	}
+}
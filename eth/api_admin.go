@@ -22,10 +22,12 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/rlp"
 )
 
@@ -141,3 +143,21 @@ func (api *AdminAPI) ImportChain(file string) (bool, error) {
 	}
 	return true, nil
 }
+
+// Checkpoint creates a consistent copy of the chain database in the given
+// directory, laid out as a data directory from which a separate node can be
+// started. State held in memory is not part of the copy, which thus recovers
+// as if the node was not shut down cleanly.
+func (api *AdminAPI) Checkpoint(dir string) (bool, error) {
+	if _, err := os.Stat(dir); err == nil {
+		return false, fmt.Errorf("directory %s already exists", dir)
+	}
+	cp, ok := api.eth.ChainDb().(ethdb.Checkpointer)
+	if !ok {
+		return false, errors.New("database does not support checkpoints")
+	}
+	if err := cp.Checkpoint(filepath.Join(dir, api.eth.chainDbDir)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
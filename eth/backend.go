@@ -21,6 +21,7 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"path/filepath"
 	"runtime"
 	"sync"
 
@@ -77,7 +78,8 @@ type Ethereum struct {
 	merger             *consensus.Merger
 
 	// DB interfaces
-	chainDb ethdb.Database // Block chain database
+	chainDb    ethdb.Database // Block chain database
+	chainDbDir string         // Path of the chain database relative to the data directory
 
 	eventMux       *event.TypeMux
 	engine         consensus.Engine
@@ -156,8 +158,15 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	if networkID == 0 {
 		networkID = chainConfig.ChainID.Uint64()
 	}
+	// The relative location of the chain database is needed to lay out
+	// checkpoints like a data directory.
+	chainDbDir, err := filepath.Rel(stack.DataDir(), stack.ResolvePath("chaindata"))
+	if err != nil {
+		chainDbDir = "chaindata"
+	}
 	eth := &Ethereum{
 		config:            config,
+		chainDbDir:        chainDbDir,
 		merger:            consensus.NewMerger(chainDb),
 		chainDb:           chainDb,
 		eventMux:          stack.EventMux(),
@@ -203,6 +212,11 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 			Preimages:           config.Preimages,
 			StateHistory:        config.StateHistory,
 			StateScheme:         scheme,
+			ParallelWorkers:     config.ParallelWorkers,
+
+			OnlinePruning:         config.OnlinePruning,
+			OnlinePruningRate:     config.OnlinePruningRate,
+			OnlinePruningInterval: config.OnlinePruningInterval,
 		}
 	)
 	// Override the chain config with provided settings.
@@ -67,6 +67,8 @@ var Defaults = Config{
 	RPCEVMTimeout:      5 * time.Second,
 	GPO:                FullNodeGPO,
 	RPCTxFeeCap:        1, // 1 ether
+
+	OnlinePruningInterval: 24 * time.Hour,
 }
 
 //go:generate go run github.com/fjl/gencodec -type Config -formats toml -out gen_config.go
@@ -90,6 +92,8 @@ type Config struct {
 	NoPruning  bool // Whether to disable pruning and flush everything to disk
 	NoPrefetch bool // Whether to disable prefetching and only load state on demand
 
+	ParallelWorkers int `toml:",omitempty"` // Number of goroutines executing block transactions optimistically in parallel
+
 	// Deprecated, use 'TransactionHistory' instead.
 	TxLookupLimit      uint64 `toml:",omitempty"` // The maximum number of blocks from head whose tx indices are reserved.
 	TransactionHistory uint64 `toml:",omitempty"` // The maximum number of blocks from head whose tx indices are reserved.
@@ -100,6 +104,12 @@ type Config struct {
 	// consistent with persistent state.
 	StateScheme string `toml:",omitempty"`
 
+	// Online pruning deletes the stale state from the database in the background
+	// while the node is running. It's only supported in the hash scheme.
+	OnlinePruning         bool          `toml:",omitempty"`
+	OnlinePruningRate     int           `toml:",omitempty"` // Maximum number of trie nodes deleted per second, 0 = unlimited
+	OnlinePruningInterval time.Duration `toml:",omitempty"` // Time between two consecutive online prunings
+
 	// RequiredBlocks is a set of block number -> hash mappings which must be in the
 	// canonical chain of all remote peers. Setting the option makes geth verify the
 	// presence of these blocks for every new peer connection.
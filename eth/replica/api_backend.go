@@ -0,0 +1,406 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package replica
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/bloombits"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/gasprice"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// errReadOnly is returned for requests which would modify the chain or the
+// transaction pool, which a replica does not maintain.
+var errReadOnly = errors.New("not supported by read-only replica")
+
+// chainContext implements core.ChainContext on a view of the primary's database.
+type chainContext struct {
+	engine consensus.Engine
+	view   *view
+}
+
+func (c *chainContext) Engine() consensus.Engine {
+	return c.engine
+}
+
+func (c *chainContext) GetHeader(hash common.Hash, number uint64) *types.Header {
+	return c.view.header(hash, number)
+}
+
+// Backend implements ethapi.Backend and filters.Backend on a replica. All blocks
+// above the replica's head are hidden, even if they are present in the primary's
+// database already.
+type Backend struct {
+	replica       *Replica
+	accman        *accounts.Manager
+	extRPCEnabled bool
+	gpo           *gasprice.Oracle
+}
+
+// ChainConfig returns the active chain configuration.
+func (b *Backend) ChainConfig() *params.ChainConfig {
+	return b.replica.chainConfig
+}
+
+func (b *Backend) CurrentBlock() *types.Header {
+	return b.replica.CurrentHeader()
+}
+
+func (b *Backend) CurrentHeader() *types.Header {
+	return b.replica.CurrentHeader()
+}
+
+func (b *Backend) SetHead(number uint64) {
+	log.Warn("Ignoring head rewind on read-only replica", "number", number)
+}
+
+// resolve returns the header with the given number in the view, resolving the
+// special block numbers.
+func (b *Backend) resolve(v *view, number rpc.BlockNumber) (*types.Header, error) {
+	switch number {
+	case rpc.PendingBlockNumber:
+		return nil, errors.New("pending block is not available")
+	case rpc.SafeBlockNumber:
+		return nil, errors.New("safe block not found")
+	case rpc.LatestBlockNumber:
+		return v.head, nil
+	case rpc.FinalizedBlockNumber:
+		hash := rawdb.ReadFinalizedBlockHash(v.db)
+		if num := rawdb.ReadHeaderNumber(v.db, hash); num != nil {
+			if header := v.canonical(*num); header != nil && header.Hash() == hash {
+				return header, nil
+			}
+		}
+		return nil, errors.New("finalized block not found")
+	}
+	return v.canonical(uint64(number)), nil
+}
+
+// headerByHash returns the header with the given hash in the view, if it is not
+// above the head of the view.
+func (b *Backend) headerByHash(v *view, hash common.Hash) *types.Header {
+	number := rawdb.ReadHeaderNumber(v.db, hash)
+	if number == nil || *number > v.head.Number.Uint64() {
+		return nil
+	}
+	return v.header(hash, *number)
+}
+
+// headerByNumberOrHash returns the header identified by the given selector.
+func (b *Backend) headerByNumberOrHash(v *view, blockNrOrHash rpc.BlockNumberOrHash) (*types.Header, error) {
+	if blockNr, ok := blockNrOrHash.Number(); ok {
+		return b.resolve(v, blockNr)
+	}
+	if hash, ok := blockNrOrHash.Hash(); ok {
+		header := b.headerByHash(v, hash)
+		if header == nil {
+			return nil, errors.New("header for hash not found")
+		}
+		if blockNrOrHash.RequireCanonical && rawdb.ReadCanonicalHash(v.db, header.Number.Uint64()) != hash {
+			return nil, errors.New("hash is not currently canonical")
+		}
+		return header, nil
+	}
+	return nil, errors.New("invalid arguments; neither block nor hash specified")
+}
+
+func (b *Backend) HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error) {
+	return b.resolve(b.replica.view(), number)
+}
+
+func (b *Backend) HeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*types.Header, error) {
+	return b.headerByNumberOrHash(b.replica.view(), blockNrOrHash)
+}
+
+func (b *Backend) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	return b.headerByHash(b.replica.view(), hash), nil
+}
+
+func (b *Backend) BlockByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Block, error) {
+	v := b.replica.view()
+	header, err := b.resolve(v, number)
+	if header == nil || err != nil {
+		return nil, err
+	}
+	return rawdb.ReadBlock(v.db, header.Hash(), header.Number.Uint64()), nil
+}
+
+func (b *Backend) BlockByHash(ctx context.Context, hash common.Hash) (*types.Block, error) {
+	v := b.replica.view()
+	header := b.headerByHash(v, hash)
+	if header == nil {
+		return nil, nil
+	}
+	return rawdb.ReadBlock(v.db, hash, header.Number.Uint64()), nil
+}
+
+func (b *Backend) BlockByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*types.Block, error) {
+	v := b.replica.view()
+	header, err := b.headerByNumberOrHash(v, blockNrOrHash)
+	if header == nil || err != nil {
+		return nil, err
+	}
+	block := rawdb.ReadBlock(v.db, header.Hash(), header.Number.Uint64())
+	if block == nil {
+		return nil, errors.New("header found, but block body is missing")
+	}
+	return block, nil
+}
+
+// GetBody returns body of a block. It does not resolve special block numbers.
+func (b *Backend) GetBody(ctx context.Context, hash common.Hash, number rpc.BlockNumber) (*types.Body, error) {
+	if number < 0 || hash == (common.Hash{}) {
+		return nil, errors.New("invalid arguments; expect hash and no special block numbers")
+	}
+	v := b.replica.view()
+	if uint64(number) <= v.head.Number.Uint64() {
+		if body := rawdb.ReadBody(v.db, hash, uint64(number)); body != nil {
+			return body, nil
+		}
+	}
+	return nil, errors.New("block body not found")
+}
+
+func (b *Backend) PendingBlockAndReceipts() (*types.Block, types.Receipts) {
+	return nil, nil
+}
+
+// stateAt opens the state of the given header in the view.
+func (b *Backend) stateAt(v *view, header *types.Header) (*state.StateDB, *types.Header, error) {
+	if header == nil {
+		return nil, nil, errors.New("header not found")
+	}
+	statedb, err := state.New(header.Root, v.state, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return statedb, header, nil
+}
+
+func (b *Backend) StateAndHeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*state.StateDB, *types.Header, error) {
+	v := b.replica.view()
+	header, err := b.resolve(v, number)
+	if err != nil {
+		return nil, nil, err
+	}
+	return b.stateAt(v, header)
+}
+
+func (b *Backend) StateAndHeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*state.StateDB, *types.Header, error) {
+	v := b.replica.view()
+	header, err := b.headerByNumberOrHash(v, blockNrOrHash)
+	if err != nil {
+		return nil, nil, err
+	}
+	return b.stateAt(v, header)
+}
+
+func (b *Backend) GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
+	v := b.replica.view()
+	header := b.headerByHash(v, hash)
+	if header == nil {
+		return nil, nil
+	}
+	return rawdb.ReadReceipts(v.db, hash, header.Number.Uint64(), header.Time, b.replica.chainConfig), nil
+}
+
+func (b *Backend) GetLogs(ctx context.Context, hash common.Hash, number uint64) ([][]*types.Log, error) {
+	v := b.replica.view()
+	if number > v.head.Number.Uint64() {
+		return nil, nil
+	}
+	return rawdb.ReadLogs(v.db, hash, number), nil
+}
+
+func (b *Backend) GetTd(ctx context.Context, hash common.Hash) *big.Int {
+	v := b.replica.view()
+	if header := b.headerByHash(v, hash); header != nil {
+		return rawdb.ReadTd(v.db, hash, header.Number.Uint64())
+	}
+	return nil
+}
+
+func (b *Backend) GetEVM(ctx context.Context, msg *core.Message, state *state.StateDB, header *types.Header, vmConfig *vm.Config, blockCtx *vm.BlockContext) *vm.EVM {
+	if vmConfig == nil {
+		vmConfig = new(vm.Config)
+	}
+	txContext := core.NewEVMTxContext(msg)
+	var context vm.BlockContext
+	if blockCtx != nil {
+		context = *blockCtx
+	} else {
+		context = core.NewEVMBlockContext(header, &chainContext{engine: b.replica.engine, view: b.replica.view()}, nil)
+	}
+	return vm.NewEVM(context, txContext, state, b.ChainConfig(), *vmConfig)
+}
+
+func (b *Backend) SubscribeRemovedLogsEvent(ch chan<- core.RemovedLogsEvent) event.Subscription {
+	return b.replica.SubscribeRemovedLogsEvent(ch)
+}
+
+func (b *Backend) SubscribePendingLogsEvent(ch chan<- []*types.Log) event.Subscription {
+	return b.replica.scope.Track(new(event.Feed).Subscribe(ch))
+}
+
+func (b *Backend) SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription {
+	return b.replica.SubscribeChainEvent(ch)
+}
+
+func (b *Backend) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription {
+	return b.replica.SubscribeChainHeadEvent(ch)
+}
+
+func (b *Backend) SubscribeChainSideEvent(ch chan<- core.ChainSideEvent) event.Subscription {
+	return b.replica.SubscribeChainSideEvent(ch)
+}
+
+func (b *Backend) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscription {
+	return b.replica.SubscribeLogsEvent(ch)
+}
+
+// SendTx rejects the transaction, as the replica is not connected to the network.
+func (b *Backend) SendTx(ctx context.Context, signedTx *types.Transaction) error {
+	return errReadOnly
+}
+
+func (b *Backend) GetPoolTransactions() (types.Transactions, error) {
+	return nil, nil
+}
+
+func (b *Backend) GetPoolTransaction(hash common.Hash) *types.Transaction {
+	return nil
+}
+
+// GetTransaction retrieves the lookup along with the transaction itself associate
+// with the given transaction hash. Transactions included above the head of the
+// replica are not returned.
+func (b *Backend) GetTransaction(ctx context.Context, txHash common.Hash) (bool, *types.Transaction, common.Hash, uint64, uint64, error) {
+	v := b.replica.view()
+	tx, blockHash, blockNumber, index := rawdb.ReadTransaction(v.db, txHash)
+	if tx == nil || blockNumber > v.head.Number.Uint64() {
+		return false, nil, common.Hash{}, 0, 0, nil
+	}
+	return true, tx, blockHash, blockNumber, index, nil
+}
+
+// GetPoolNonce returns the nonce of the account in the head state, as the
+// replica has no transaction pool.
+func (b *Backend) GetPoolNonce(ctx context.Context, addr common.Address) (uint64, error) {
+	statedb, _, err := b.StateAndHeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if err != nil {
+		return 0, err
+	}
+	return statedb.GetNonce(addr), nil
+}
+
+func (b *Backend) Stats() (runnable int, blocked int) {
+	return 0, 0
+}
+
+func (b *Backend) TxPoolContent() (map[common.Address][]*types.Transaction, map[common.Address][]*types.Transaction) {
+	return make(map[common.Address][]*types.Transaction), make(map[common.Address][]*types.Transaction)
+}
+
+func (b *Backend) TxPoolContentFrom(addr common.Address) ([]*types.Transaction, []*types.Transaction) {
+	return nil, nil
+}
+
+func (b *Backend) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subscription {
+	return b.replica.scope.Track(b.replica.txsFeed.Subscribe(ch))
+}
+
+// SyncProgress returns an empty progress, as the replica is always in sync with
+// the persisted chain of the primary.
+func (b *Backend) SyncProgress() ethereum.SyncProgress {
+	return ethereum.SyncProgress{}
+}
+
+func (b *Backend) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	return b.gpo.SuggestTipCap(ctx)
+}
+
+func (b *Backend) FeeHistory(ctx context.Context, blockCount uint64, lastBlock rpc.BlockNumber, rewardPercentiles []float64) (firstBlock *big.Int, reward [][]*big.Int, baseFee []*big.Int, gasUsedRatio []float64, err error) {
+	return b.gpo.FeeHistory(ctx, blockCount, lastBlock, rewardPercentiles)
+}
+
+// ChainDb returns the current view of the primary's database. It must not be
+// retained, as views are closed after having been replaced.
+func (b *Backend) ChainDb() ethdb.Database {
+	return b.replica.view().db
+}
+
+func (b *Backend) AccountManager() *accounts.Manager {
+	return b.accman
+}
+
+func (b *Backend) ExtRPCEnabled() bool {
+	return b.extRPCEnabled
+}
+
+func (b *Backend) UnprotectedAllowed() bool {
+	return false
+}
+
+func (b *Backend) RPCGasCap() uint64 {
+	return b.replica.config.RPCGasCap
+}
+
+func (b *Backend) RPCEVMTimeout() time.Duration {
+	return b.replica.config.RPCEVMTimeout
+}
+
+func (b *Backend) RPCTxFeeCap() float64 {
+	return b.replica.config.RPCTxFeeCap
+}
+
+// BloomStatus returns the section size and the number of bloom bit sections
+// indexed by the primary below the replica's head.
+func (b *Backend) BloomStatus() (uint64, uint64) {
+	v := b.replica.view()
+	sections := v.bloomSections()
+	if limit := (v.head.Number.Uint64() + 1) / params.BloomBitsBlocks; sections > limit {
+		sections = limit
+	}
+	return params.BloomBitsBlocks, sections
+}
+
+func (b *Backend) ServiceFilter(ctx context.Context, session *bloombits.MatcherSession) {
+	for i := 0; i < bloomFilterThreads; i++ {
+		go session.Multiplex(bloomRetrievalBatch, bloomRetrievalWait, b.replica.bloomRequests)
+	}
+}
+
+func (b *Backend) Engine() consensus.Engine {
+	return b.replica.engine
+}
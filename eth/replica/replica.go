@@ -0,0 +1,542 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package replica implements a read-only node serving the chain of another node,
+// the primary, by following its database.
+//
+// The replica periodically opens a read-only view of the primary's pebble
+// database and freezer, without running p2p networking or block import. As the
+// state of recent blocks is held in the primary's memory, the replica's head is
+// the most recent block whose state the primary has persisted: with the hash
+// scheme this is every block for archive nodes, and otherwise the blocks flushed
+// periodically; with the path scheme it is the bottom-most state layer.
+//
+// The replica thus lags behind the primary, by at least 128 blocks with the path
+// scheme, and up to hours of blocks with the hash scheme unless the primary is
+// an archive node. The journals of the in-memory state are of no help, as the
+// primary only writes them on shutdown. The lag is reported by Lag, the metrics
+// and the refresh logs.
+package replica
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/bloombits"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/eth/gasprice"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/pebble"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/triedb"
+	"github.com/ethereum/go-ethereum/triedb/hashdb"
+	"github.com/ethereum/go-ethereum/triedb/pathdb"
+)
+
+const (
+	// maxStateLookback is the maximum number of blocks below the primary's head
+	// searched for a block with persisted state. It covers the blocks processed
+	// by a non-archive hash scheme primary between two flushes of its state.
+	maxStateLookback = 16384
+
+	// maxNotifiedBlocks is the maximum number of new blocks for which chain
+	// events are sent on a refresh. Only the most recent ones are announced if
+	// the primary advanced further.
+	maxNotifiedBlocks = 1024
+
+	// viewRetention is the time views are kept open after being replaced, so
+	// that requests in flight can complete.
+	viewRetention = 30 * time.Second
+
+	// openRetries is the number of attempts to open the initial view, which may
+	// fail transiently while the primary is writing.
+	openRetries = 8
+)
+
+var (
+	headGauge = metrics.NewRegisteredGauge("replica/head", nil)
+	lagGauge  = metrics.NewRegisteredGauge("replica/lag", nil)
+)
+
+// Config contains the configuration options of a replica.
+type Config struct {
+	Directory         string        // Chain database directory of the primary
+	AncientsDirectory string        // Ancient store directory of the primary, inside the database by default
+	Refresh           time.Duration // Interval of refreshing the view of the primary's database
+
+	NetworkId       uint64 // Network ID to report, the chain ID if zero
+	DatabaseCache   int    // Database cache in megabytes, shared by all views
+	DatabaseHandles int    // Database file handles, shared by all views
+	TrieCleanCache  int
+
+	GPO gasprice.Config
+
+	RPCGasCap     uint64        // Gas limit of eth_call and eth_estimateGas
+	RPCEVMTimeout time.Duration // Timeout of eth_call
+	RPCTxFeeCap   float64       // Transaction fee cap in ether
+}
+
+// Defaults contains the default settings of a replica.
+var Defaults = Config{
+	Refresh:         4 * time.Second,
+	DatabaseCache:   512,
+	DatabaseHandles: 512,
+	TrieCleanCache:  ethconfig.Defaults.TrieCleanCache,
+	GPO:             ethconfig.Defaults.GPO,
+	RPCGasCap:       ethconfig.Defaults.RPCGasCap,
+	RPCEVMTimeout:   ethconfig.Defaults.RPCEVMTimeout,
+	RPCTxFeeCap:     ethconfig.Defaults.RPCTxFeeCap,
+}
+
+// view is a read-only snapshot of the primary's database.
+type view struct {
+	db      ethdb.Database
+	links   string // Directory of the hard-linked sstables of the view
+	state   state.Database
+	head    *types.Header // Most recent block with available state
+	primary *types.Header // Head block of the primary
+
+	retired time.Time // Time the view was replaced by a newer one
+}
+
+// lag returns the number of blocks the head of the view is behind the primary.
+func (v *view) lag() uint64 {
+	return v.primary.Number.Uint64() - v.head.Number.Uint64()
+}
+
+// header retrieves a header of the view's database.
+func (v *view) header(hash common.Hash, number uint64) *types.Header {
+	return rawdb.ReadHeader(v.db, hash, number)
+}
+
+// canonical retrieves the canonical header with the given number, if it is not
+// above the head of the view.
+func (v *view) canonical(number uint64) *types.Header {
+	if number > v.head.Number.Uint64() {
+		return nil
+	}
+	hash := rawdb.ReadCanonicalHash(v.db, number)
+	if hash == (common.Hash{}) {
+		return nil
+	}
+	return v.header(hash, number)
+}
+
+// close closes the database of the view and removes its links.
+func (v *view) close() {
+	if err := v.db.Close(); err != nil {
+		log.Warn("Failed to close replica view", "err", err)
+	}
+	os.RemoveAll(v.links)
+}
+
+// Replica follows the database of a primary node, serving its chain over RPC.
+type Replica struct {
+	config      *Config
+	chainConfig *params.ChainConfig
+	engine      consensus.Engine
+	workdir     string              // Directory holding the hard-linked sstables of the views
+	views       uint64              // Number of views opened, used for naming their directories
+	cache       *pebble.SharedCache // Database cache and file handles shared by all views
+
+	lock    sync.RWMutex
+	current *view
+	retired []*view
+
+	APIBackend *Backend
+
+	chainFeed     event.Feed
+	chainHeadFeed event.Feed
+	chainSideFeed event.Feed
+	logsFeed      event.Feed
+	rmLogsFeed    event.Feed
+	txsFeed       event.Feed
+	scope         event.SubscriptionScope
+
+	bloomRequests chan chan *bloombits.Retrieval
+	quit          chan struct{}
+	wg            sync.WaitGroup
+}
+
+// New creates a replica following the primary's database given in the config,
+// and registers it with the node.
+func New(stack *node.Node, config *Config) (*Replica, error) {
+	if config.Refresh <= 0 {
+		log.Warn("Sanitizing invalid replica refresh interval", "provided", config.Refresh, "updated", Defaults.Refresh)
+		config.Refresh = Defaults.Refresh
+	}
+	r := &Replica{
+		config:        config,
+		workdir:       stack.ResolvePath("replica"),
+		bloomRequests: make(chan chan *bloombits.Retrieval),
+		quit:          make(chan struct{}),
+	}
+	// Remove the leftovers of an unclean shutdown
+	if err := os.RemoveAll(r.workdir); err != nil {
+		return nil, err
+	}
+	r.cache = pebble.NewSharedCache(config.DatabaseCache, config.DatabaseHandles)
+
+	var (
+		v   *view
+		err error
+	)
+	for i := 0; i < openRetries; i++ {
+		if v, err = r.openView(); err == nil {
+			break
+		}
+		log.Debug("Failed to open primary database, retrying", "err", err)
+		time.Sleep(100 * time.Millisecond)
+	}
+	if err != nil {
+		r.cache.Close()
+		return nil, fmt.Errorf("failed to open primary database: %v", err)
+	}
+	genesis := rawdb.ReadCanonicalHash(v.db, 0)
+	if r.chainConfig = rawdb.ReadChainConfig(v.db, genesis); r.chainConfig == nil {
+		v.close()
+		r.cache.Close()
+		return nil, errors.New("primary database is not initialized")
+	}
+	// The consensus engine is only used to retrieve block authors, don't tie it
+	// to any database view.
+	if r.engine, err = ethconfig.CreateConsensusEngine(r.chainConfig, rawdb.NewMemoryDatabase()); err != nil {
+		v.close()
+		r.cache.Close()
+		return nil, err
+	}
+	r.current = v
+	r.report(v)
+	log.Info("Opened primary database", "directory", config.Directory, "head", v.head.Number, "hash", v.head.Hash(), "lag", v.lag())
+
+	r.APIBackend = &Backend{
+		replica:       r,
+		accman:        stack.AccountManager(),
+		extRPCEnabled: stack.Config().ExtRPCEnabled(),
+	}
+	gpoParams := config.GPO
+	if gpoParams.Default == nil {
+		gpoParams.Default = ethconfig.Defaults.Miner.GasPrice
+	}
+	r.APIBackend.gpo = gasprice.NewOracle(r.APIBackend, gpoParams)
+
+	stack.RegisterAPIs(r.APIs(stack))
+	stack.RegisterLifecycle(r)
+	return r, nil
+}
+
+// APIs returns the RPC services offered by the replica.
+func (r *Replica) APIs(stack *node.Node) []rpc.API {
+	networkID := r.config.NetworkId
+	if networkID == 0 {
+		networkID = r.chainConfig.ChainID.Uint64()
+	}
+	return append(ethapi.GetAPIs(r.APIBackend), rpc.API{
+		Namespace: "net",
+		Service:   ethapi.NewNetAPI(stack.Server(), networkID),
+	})
+}
+
+// Start implements node.Lifecycle, starting the refresh loop.
+func (r *Replica) Start() error {
+	r.startBloomHandlers(params.BloomBitsBlocks)
+
+	r.wg.Add(1)
+	go r.loop()
+	return nil
+}
+
+// Stop implements node.Lifecycle, terminating the refresh loop and closing all
+// views of the primary's database.
+func (r *Replica) Stop() error {
+	close(r.quit)
+	r.wg.Wait()
+	r.scope.Close()
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for _, v := range r.retired {
+		v.close()
+	}
+	r.retired = nil
+	r.current.close()
+	r.cache.Close()
+	return os.RemoveAll(r.workdir)
+}
+
+// loop periodically refreshes the view of the primary's database.
+func (r *Replica) loop() {
+	defer r.wg.Done()
+
+	timer := time.NewTimer(r.config.Refresh)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			if err := r.Refresh(); err != nil {
+				log.Warn("Failed to refresh primary database", "err", err)
+			}
+			timer.Reset(r.config.Refresh)
+		case <-r.quit:
+			return
+		}
+	}
+}
+
+// view returns the current view of the primary's database.
+func (r *Replica) view() *view {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return r.current
+}
+
+// CurrentHeader returns the head of the replica.
+func (r *Replica) CurrentHeader() *types.Header {
+	return r.view().head
+}
+
+// Lag returns the number of blocks the replica's head is behind the primary's
+// head, as the primary has not persisted their state yet.
+func (r *Replica) Lag() uint64 {
+	return r.view().lag()
+}
+
+// ChainConfig returns the chain configuration of the primary.
+func (r *Replica) ChainConfig() *params.ChainConfig {
+	return r.chainConfig
+}
+
+// Refresh opens a new view of the primary's database, and announces the blocks
+// the primary advanced by.
+func (r *Replica) Refresh() error {
+	next, err := r.openView()
+	if err != nil {
+		return err
+	}
+	r.lock.Lock()
+	prev := r.current
+	prev.retired = time.Now()
+	r.current = next
+	r.retired = append(r.retired, prev)
+
+	// Close the views which have been replaced long enough ago
+	var closed int
+	for _, v := range r.retired {
+		if time.Since(v.retired) < viewRetention {
+			break
+		}
+		v.close()
+		closed++
+	}
+	r.retired = r.retired[closed:]
+	r.lock.Unlock()
+
+	r.report(next)
+	if prev.head.Hash() != next.head.Hash() {
+		log.Debug("Refreshed primary database", "number", next.head.Number, "hash", next.head.Hash(), "lag", next.lag())
+		r.notify(prev, next)
+	}
+	return nil
+}
+
+// report updates the metrics tracking the head of the replica.
+func (r *Replica) report(v *view) {
+	headGauge.Update(v.head.Number.Int64())
+	lagGauge.Update(int64(v.lag()))
+}
+
+// openView opens a read-only view of the primary's database as of now.
+func (r *Replica) openView() (*view, error) {
+	links := filepath.Join(r.workdir, strconv.FormatUint(r.views, 10))
+	r.views++
+
+	kvdb, err := pebble.NewShared(r.config.Directory, links, r.cache, "")
+	if err != nil {
+		os.RemoveAll(links)
+		return nil, err
+	}
+	// The freezer must be opened after the key-value store: blocks are only
+	// deleted from the latter once they were moved into the former.
+	ancients := r.config.AncientsDirectory
+	if ancients == "" {
+		ancients = filepath.Join(r.config.Directory, "ancient")
+	}
+	db, err := rawdb.NewDatabaseWithSharedFreezer(kvdb, ancients, "")
+	if err != nil {
+		kvdb.Close()
+		os.RemoveAll(links)
+		return nil, err
+	}
+	config := &triedb.Config{Preimages: true}
+	if rawdb.ReadStateScheme(db) == rawdb.PathScheme {
+		config.PathDB = &pathdb.Config{
+			CleanCacheSize: r.config.TrieCleanCache * 1024 * 1024,
+			ReadOnly:       true,
+		}
+	} else {
+		config.HashDB = &hashdb.Config{
+			CleanCacheSize: r.config.TrieCleanCache * 1024 * 1024,
+		}
+	}
+	v := &view{
+		db:    db,
+		links: links,
+		state: state.NewDatabaseWithNodeDB(db, triedb.NewDatabase(db, config)),
+	}
+	if v.primary, v.head, err = v.findHead(); err != nil {
+		v.close()
+		return nil, err
+	}
+	return v, nil
+}
+
+// findHead returns the head block of the primary along with its most recent
+// block with available state, or the head block if no state is available at all.
+func (v *view) findHead() (*types.Header, *types.Header, error) {
+	hash := rawdb.ReadHeadBlockHash(v.db)
+	number := rawdb.ReadHeaderNumber(v.db, hash)
+	if number == nil {
+		return nil, nil, errors.New("head block not found")
+	}
+	head := v.header(hash, *number)
+	if head == nil {
+		return nil, nil, fmt.Errorf("head block #%d [%x] not found", *number, hash)
+	}
+	for header, i := head, 0; header != nil && i < maxStateLookback; i++ {
+		if _, err := v.state.OpenTrie(header.Root); err == nil {
+			return head, header, nil
+		}
+		if header.Number.Sign() == 0 {
+			break
+		}
+		header = v.header(header.ParentHash, header.Number.Uint64()-1)
+	}
+	log.Warn("No state available in primary database", "head", head.Number)
+	return head, head, nil
+}
+
+// notify sends the chain events for the blocks the head moved by between two
+// views, including the removal of blocks in case of a reorg.
+func (r *Replica) notify(prev, next *view) {
+	var (
+		oldHead = prev.head
+		newHead = next.head
+		added   []*types.Header
+		removed []*types.Header
+	)
+	for newHead != nil && oldHead != nil && newHead.Number.Cmp(oldHead.Number) > 0 {
+		added = append(added, newHead)
+		newHead = next.header(newHead.ParentHash, newHead.Number.Uint64()-1)
+	}
+	for oldHead != nil && newHead != nil && oldHead.Number.Cmp(newHead.Number) > 0 {
+		removed = append(removed, oldHead)
+		oldHead = prev.header(oldHead.ParentHash, oldHead.Number.Uint64()-1)
+	}
+	for oldHead != nil && newHead != nil && oldHead.Hash() != newHead.Hash() && len(added) < maxStateLookback {
+		added = append(added, newHead)
+		removed = append(removed, oldHead)
+		if newHead.Number.Sign() == 0 {
+			break
+		}
+		newHead = next.header(newHead.ParentHash, newHead.Number.Uint64()-1)
+		oldHead = prev.header(oldHead.ParentHash, oldHead.Number.Uint64()-1)
+	}
+	for _, header := range removed {
+		block := rawdb.ReadBlock(prev.db, header.Hash(), header.Number.Uint64())
+		if block == nil {
+			continue
+		}
+		var logs []*types.Log
+		for _, receipt := range rawdb.ReadReceipts(prev.db, block.Hash(), block.NumberU64(), block.Time(), r.chainConfig) {
+			for _, l := range receipt.Logs {
+				removed := *l
+				removed.Removed = true
+				logs = append(logs, &removed)
+			}
+		}
+		if len(logs) > 0 {
+			r.rmLogsFeed.Send(core.RemovedLogsEvent{Logs: logs})
+		}
+		r.chainSideFeed.Send(core.ChainSideEvent{Block: block})
+	}
+	if len(added) > maxNotifiedBlocks {
+		added = added[:maxNotifiedBlocks]
+	}
+	var head *types.Block
+	for i := len(added) - 1; i >= 0; i-- {
+		block := rawdb.ReadBlock(next.db, added[i].Hash(), added[i].Number.Uint64())
+		if block == nil {
+			continue
+		}
+		var logs []*types.Log
+		for _, receipt := range rawdb.ReadReceipts(next.db, block.Hash(), block.NumberU64(), block.Time(), r.chainConfig) {
+			logs = append(logs, receipt.Logs...)
+		}
+		r.chainFeed.Send(core.ChainEvent{Block: block, Hash: block.Hash(), Logs: logs})
+		if len(logs) > 0 {
+			r.logsFeed.Send(logs)
+		}
+		head = block
+	}
+	if head != nil {
+		r.chainHeadFeed.Send(core.ChainHeadEvent{Block: head})
+	}
+}
+
+// SubscribeChainEvent registers a subscription of ChainEvent.
+func (r *Replica) SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription {
+	return r.scope.Track(r.chainFeed.Subscribe(ch))
+}
+
+// SubscribeChainHeadEvent registers a subscription of ChainHeadEvent.
+func (r *Replica) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription {
+	return r.scope.Track(r.chainHeadFeed.Subscribe(ch))
+}
+
+// SubscribeChainSideEvent registers a subscription of ChainSideEvent.
+func (r *Replica) SubscribeChainSideEvent(ch chan<- core.ChainSideEvent) event.Subscription {
+	return r.scope.Track(r.chainSideFeed.Subscribe(ch))
+}
+
+// SubscribeLogsEvent registers a subscription of []*types.Log.
+func (r *Replica) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscription {
+	return r.scope.Track(r.logsFeed.Subscribe(ch))
+}
+
+// SubscribeRemovedLogsEvent registers a subscription of RemovedLogsEvent.
+func (r *Replica) SubscribeRemovedLogsEvent(ch chan<- core.RemovedLogsEvent) event.Subscription {
+	return r.scope.Track(r.rmLogsFeed.Subscribe(ch))
+}
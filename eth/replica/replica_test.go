@@ -0,0 +1,230 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package replica
+
+import (
+	"context"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/beacon"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var (
+	testKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testAddr    = crypto.PubkeyToAddress(testKey.PublicKey)
+	testBalance = big.NewInt(1e18)
+	testTarget  = common.HexToAddress("0xdeadbeef")
+)
+
+// newPrimary creates a chain in a pebble database in the given directory,
+// returning it along with blocks transferring one wei each to the target
+// address. The state of every block is persisted for archive nodes.
+func newPrimary(t *testing.T, dir string, n int, archive bool) (*core.BlockChain, []*types.Block) {
+	t.Helper()
+
+	db, err := rawdb.Open(rawdb.OpenOptions{
+		Type:              "pebble",
+		Directory:         dir,
+		AncientsDirectory: filepath.Join(dir, "ancient"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		gspec = &core.Genesis{
+			Config:     params.MergedTestChainConfig,
+			Difficulty: common.Big0,
+			Alloc:      types.GenesisAlloc{testAddr: {Balance: testBalance}},
+		}
+		signer = types.LatestSigner(gspec.Config)
+	)
+	_, blocks, _ := core.GenerateChainWithGenesis(gspec, beacon.NewFaker(), n, func(i int, gen *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(testAddr), testTarget, big.NewInt(1), params.TxGas, gen.BaseFee(), nil), signer, testKey)
+		gen.AddTx(tx)
+	})
+	cacheConfig := &core.CacheConfig{
+		TrieCleanLimit:    16,
+		TrieDirtyLimit:    16,
+		TrieTimeLimit:     time.Hour,
+		TrieDirtyDisabled: archive,
+		StateScheme:       rawdb.HashScheme,
+	}
+	chain, err := core.NewBlockChain(db, cacheConfig, gspec, nil, beacon.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return chain, blocks
+}
+
+func TestReplicaFollowsPrimary(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "chaindata")
+	chain, blocks := newPrimary(t, dir, 20, true)
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks[:10]); err != nil {
+		t.Fatal(err)
+	}
+	stack, err := node.New(&node.Config{DataDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stack.Close()
+
+	config := Defaults
+	config.Directory = dir
+	r, err := New(stack, &config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Stop()
+
+	var (
+		ctx     = context.Background()
+		backend = r.APIBackend
+	)
+	check := func(head uint64) {
+		t.Helper()
+		if have := r.CurrentHeader().Number.Uint64(); have != head {
+			t.Fatalf("Wrong replica head: have %d, want %d", have, head)
+		}
+		if lag := r.Lag(); lag != 0 {
+			t.Fatalf("Replica of archive node lagging by %d blocks", lag)
+		}
+		statedb, _, err := backend.StateAndHeaderByNumber(ctx, rpc.LatestBlockNumber)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if have := statedb.GetBalance(testTarget).Uint64(); have != head {
+			t.Fatalf("Wrong balance at head: have %d, want %d", have, head)
+		}
+		block, err := backend.BlockByNumber(ctx, rpc.BlockNumber(head))
+		if err != nil || block == nil || block.Hash() != blocks[head-1].Hash() {
+			t.Fatalf("Wrong head block: %v, %v", block, err)
+		}
+		if block, _ := backend.BlockByNumber(ctx, rpc.BlockNumber(head+1)); block != nil {
+			t.Fatalf("Block #%d above head returned", head+1)
+		}
+		tx := blocks[head-1].Transactions()[0]
+		if found, _, _, number, _, _ := backend.GetTransaction(ctx, tx.Hash()); !found || number != head {
+			t.Fatalf("Transaction of head block not found")
+		}
+	}
+	check(10)
+
+	// Advance the primary, the replica must not notice it until refreshed.
+	if _, err := chain.InsertChain(blocks[10:]); err != nil {
+		t.Fatal(err)
+	}
+	check(10)
+
+	heads := make(chan core.ChainHeadEvent, 1)
+	sub := r.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	chains := make(chan core.ChainEvent, 20)
+	sub = r.SubscribeChainEvent(chains)
+	defer sub.Unsubscribe()
+
+	if err := r.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	check(20)
+
+	if ev := <-heads; ev.Block.Hash() != blocks[19].Hash() {
+		t.Fatalf("Wrong chain head event: have #%d, want #20", ev.Block.NumberU64())
+	}
+	for i := 10; i < 20; i++ {
+		ev := <-chains
+		if ev.Hash != blocks[i].Hash() {
+			t.Fatalf("Wrong chain event %d: have #%d, want #%d", i, ev.Block.NumberU64(), i+1)
+		}
+	}
+}
+
+// Tests that the replica of a non-archive node stays at the most recent block
+// with persisted state, reporting the blocks above as lag.
+func TestReplicaStateLag(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "chaindata")
+	chain, blocks := newPrimary(t, dir, 10, false)
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatal(err)
+	}
+	stack, err := node.New(&node.Config{DataDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stack.Close()
+
+	config := Defaults
+	config.Directory = dir
+	r, err := New(stack, &config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Stop()
+
+	if head := r.CurrentHeader().Number.Uint64(); head != 0 {
+		t.Fatalf("Replica head above persisted state: have %d, want 0", head)
+	}
+	if lag := r.Lag(); lag != 10 {
+		t.Fatalf("Wrong replica lag: have %d, want 10", lag)
+	}
+}
+
+func TestReplicaRejectsWrites(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "chaindata")
+	chain, blocks := newPrimary(t, dir, 1, true)
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatal(err)
+	}
+	stack, err := node.New(&node.Config{DataDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stack.Close()
+
+	config := Defaults
+	config.Directory = dir
+	r, err := New(stack, &config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Stop()
+
+	if err := r.APIBackend.SendTx(context.Background(), blocks[0].Transactions()[0]); err != errReadOnly {
+		t.Fatalf("Wrong error sending transaction: have %v, want %v", err, errReadOnly)
+	}
+	if err := r.APIBackend.ChainDb().Put([]byte("key"), []byte("value")); err == nil {
+		t.Fatal("Write to primary database succeeded")
+	}
+}
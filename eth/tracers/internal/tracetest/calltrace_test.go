@@ -18,9 +18,11 @@ package tracetest
 
 import (
 	"encoding/json"
+	"fmt"
 	"math/big"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -28,10 +30,13 @@ import (
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/asm"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/eth/tracers/native"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/tests"
@@ -399,3 +404,208 @@ func TestInternals(t *testing.T) {
 		})
 	}
 }
+
+// selectorDB is a static 4byte database.
+type selectorDB map[string]string
+
+func (db selectorDB) Selector(id []byte) (string, error) {
+	if sig, ok := db[hexutil.Encode(id)]; ok {
+		return sig, nil
+	}
+	return "", fmt.Errorf("signature %x not found", id)
+}
+
+func TestCallTracerDecoding(t *testing.T) {
+	var (
+		token     = common.HexToAddress("0x00000000000000000000000000000000deadbeef")
+		checker   = common.HexToAddress("0x00000000000000000000000000000000000000bb")
+		origin    = common.HexToAddress("0x00000000000000000000000000000000feed")
+		recipient = common.HexToAddress("0x0000000000000000000000000000000000001234")
+
+		transferTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+		checkSel      = crypto.Keccak256([]byte("check()"))[:4]
+		errorSel      = crypto.Keccak256([]byte("InsufficientBalance(uint256,uint256)"))[:4]
+	)
+	native.SetSelectorDatabase(selectorDB{
+		hexutil.Encode(checkSel): "check()",
+		hexutil.Encode(errorSel): "InsufficientBalance(uint256,uint256)",
+	})
+	defer native.SetSelectorDatabase(nil)
+
+	// The token emits a Transfer event, calls the checker and returns true.
+	// The checker always reverts with a custom error.
+	tokenCode := compileAsm(t, fmt.Sprintf(`
+	push 36
+	calldataload
+	push 0
+	mstore
+	push 4
+	calldataload
+	caller
+	push %#x
+	push 32
+	push 0
+	log3
+	push %#x
+	push 224
+	shl
+	push 0
+	mstore
+	push 0
+	push 0
+	push 4
+	push 0
+	push 0
+	push %#x
+	gas
+	call
+	pop
+	push 1
+	push 0
+	mstore
+	push 32
+	push 0
+	return
+`, transferTopic, checkSel, checker))
+	checkerCode := compileAsm(t, fmt.Sprintf(`
+	push %#x
+	push 224
+	shl
+	push 0
+	mstore
+	push 1
+	push 4
+	mstore
+	push 2
+	push 36
+	mstore
+	push 68
+	push 0
+	revert
+`, errorSel))
+
+	tokenABI := `[
+		{"type":"function","name":"transfer","inputs":[{"name":"to","type":"address"},{"name":"amount","type":"uint256"}],"outputs":[{"name":"ok","type":"bool"}]},
+		{"type":"event","name":"Transfer","inputs":[{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":true},{"name":"value","type":"uint256","indexed":false}]}
+	]`
+	cfg := fmt.Sprintf(`{"withLog": true, "fourByte": true, "abis": {"%v": %s}}`, token, tokenABI)
+	tracer, err := tracers.DefaultDirectory.New("callTracer", nil, json.RawMessage(cfg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	input, _ := hexutil.Decode("0xa9059cbb" +
+		"0000000000000000000000000000000000000000000000000000000000001234" +
+		"0000000000000000000000000000000000000000000000000000000000000064")
+
+	state := tests.MakePreState(rawdb.NewMemoryDatabase(),
+		types.GenesisAlloc{
+			token:   types.Account{Code: tokenCode},
+			checker: types.Account{Code: checkerCode},
+			origin:  types.Account{Balance: big.NewInt(500000000000000)},
+		}, false, rawdb.HashScheme)
+	defer state.Close()
+
+	context := vm.BlockContext{
+		CanTransfer: core.CanTransfer,
+		Transfer:    core.Transfer,
+		BlockNumber: big.NewInt(8000000),
+		Time:        5,
+		Difficulty:  big.NewInt(0x30000),
+		GasLimit:    uint64(6000000),
+	}
+	evm := vm.NewEVM(context, vm.TxContext{Origin: origin, GasPrice: big.NewInt(1)}, state.StateDB, params.MainnetChainConfig, vm.Config{Tracer: tracer})
+	msg := &core.Message{
+		To:        &token,
+		From:      origin,
+		Value:     big.NewInt(0),
+		GasLimit:  200000,
+		GasPrice:  big.NewInt(0),
+		GasFeeCap: big.NewInt(0),
+		GasTipCap: big.NewInt(0),
+		Data:      input,
+	}
+	st := core.NewStateTransition(evm, msg, new(core.GasPool).AddGas(msg.GasLimit))
+	if _, err := st.TransitionDb(); err != nil {
+		t.Fatal(err)
+	}
+	res, err := tracer.GetResult()
+	if err != nil {
+		t.Fatal(err)
+	}
+	type decodedArg struct {
+		Name  string      `json:"name"`
+		Type  string      `json:"type"`
+		Value interface{} `json:"value"`
+	}
+	type decoded struct {
+		Method    string       `json:"method"`
+		Name      string       `json:"name"`
+		Event     string       `json:"event"`
+		Signature string       `json:"signature"`
+		Inputs    []decodedArg `json:"inputs"`
+		Outputs   []decodedArg `json:"outputs"`
+	}
+	var have struct {
+		Decoded *decoded
+		Logs    []struct{ Decoded *decoded }
+		Calls   []struct {
+			Decoded      *decoded
+			DecodedError *decoded
+		}
+	}
+	if err := json.Unmarshal(res, &have); err != nil {
+		t.Fatal(err)
+	}
+	want := decoded{
+		Method:    "transfer",
+		Signature: "transfer(address,uint256)",
+		Inputs: []decodedArg{
+			{Name: "to", Type: "address", Value: strings.ToLower(recipient.Hex())},
+			{Name: "amount", Type: "uint256", Value: "100"},
+		},
+		Outputs: []decodedArg{{Name: "ok", Type: "bool", Value: true}},
+	}
+	if have.Decoded == nil || !reflect.DeepEqual(*have.Decoded, want) {
+		t.Errorf("call decoding mismatch\nhave: %+v\nwant: %+v\ntrace: %s", have.Decoded, want, res)
+	}
+	wantLog := decoded{
+		Event:     "Transfer",
+		Signature: "Transfer(address,address,uint256)",
+		Inputs: []decodedArg{
+			{Name: "from", Type: "address", Value: strings.ToLower(origin.Hex())},
+			{Name: "to", Type: "address", Value: strings.ToLower(recipient.Hex())},
+			{Name: "value", Type: "uint256", Value: "100"},
+		},
+	}
+	if len(have.Logs) != 1 || have.Logs[0].Decoded == nil || !reflect.DeepEqual(*have.Logs[0].Decoded, wantLog) {
+		t.Errorf("log decoding mismatch\ntrace: %s", res)
+	}
+	if len(have.Calls) != 1 {
+		t.Fatalf("expected one subcall, trace: %s", res)
+	}
+	if d := have.Calls[0].Decoded; d == nil || d.Method != "check" || len(d.Inputs) != 0 {
+		t.Errorf("4byte call decoding mismatch, trace: %s", res)
+	}
+	wantErr := decoded{
+		Name:      "InsufficientBalance",
+		Signature: "InsufficientBalance(uint256,uint256)",
+		Inputs:    []decodedArg{{Name: "arg0", Type: "uint256", Value: "1"}, {Name: "arg1", Type: "uint256", Value: "2"}},
+	}
+	if d := have.Calls[0].DecodedError; d == nil || !reflect.DeepEqual(*d, wantErr) {
+		t.Errorf("error decoding mismatch\nhave: %+v\nwant: %+v\ntrace: %s", d, wantErr, res)
+	}
+}
+
+func compileAsm(t *testing.T, src string) []byte {
+	t.Helper()
+	c := asm.NewCompiler(false)
+	if err := c.SetRules(params.MainnetChainConfig.Rules(big.NewInt(8000000), false, 5)); err != nil {
+		t.Fatal(err)
+	}
+	c.Feed(asm.Lex([]byte(src), false))
+	bin, errs := c.Compile()
+	if len(errs) > 0 {
+		t.Fatalf("failed to compile test contract: %v", errs)
+	}
+	return common.FromHex(bin)
+}
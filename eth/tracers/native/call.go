@@ -43,6 +43,7 @@ type callLog struct {
 	// Position of the log relative to subcalls within the same trace
 	// See https://github.com/ethereum/go-ethereum/pull/28389 for details
 	Position hexutil.Uint `json:"position"`
+	Decoded  *decodedLog  `json:"decoded,omitempty" rlp:"-"`
 }
 
 type callFrame struct {
@@ -57,6 +58,8 @@ type callFrame struct {
 	RevertReason string          `json:"revertReason,omitempty"`
 	Calls        []callFrame     `json:"calls,omitempty" rlp:"optional"`
 	Logs         []callLog       `json:"logs,omitempty" rlp:"optional"`
+	Decoded      *decodedCall    `json:"decoded,omitempty" rlp:"-"`
+	DecodedError *decodedError   `json:"decodedError,omitempty" rlp:"-"`
 	// Placed at end on purpose. The RLP will be decoded to 0 instead of
 	// nil if there are non-empty elements after in the struct.
 	Value *big.Int `json:"value,omitempty" rlp:"optional"`
@@ -108,11 +111,14 @@ type callTracer struct {
 	gasLimit  uint64
 	interrupt atomic.Bool // Atomic flag to signal execution interruption
 	reason    error       // Textual reason for the interruption
+	decoder   *abiDecoder // ABI decoder of the result, nil if disabled
 }
 
 type callTracerConfig struct {
-	OnlyTopCall bool `json:"onlyTopCall"` // If true, call tracer won't collect any subcalls
-	WithLog     bool `json:"withLog"`     // If true, call tracer will collect event logs
+	OnlyTopCall bool                               `json:"onlyTopCall"` // If true, call tracer won't collect any subcalls
+	WithLog     bool                               `json:"withLog"`     // If true, call tracer will collect event logs
+	ABIs        map[common.Address]json.RawMessage `json:"abis"`        // ABIs used to decode calls, errors and logs, per contract address
+	FourByte    bool                               `json:"fourByte"`    // If true, selectors without ABI are decoded using the 4byte database
 }
 
 // newCallTracer returns a native go tracer which tracks
@@ -124,9 +130,17 @@ func newCallTracer(ctx *tracers.Context, cfg json.RawMessage) (tracers.Tracer, e
 			return nil, err
 		}
 	}
+	t := &callTracer{callstack: make([]callFrame, 1), config: config}
+	if len(config.ABIs) > 0 || config.FourByte {
+		decoder, err := newABIDecoder(config.ABIs, config.FourByte)
+		if err != nil {
+			return nil, err
+		}
+		t.decoder = decoder
+	}
 	// First callframe contains tx context info
 	// and is populated on start and end.
-	return &callTracer{callstack: make([]callFrame, 1), config: config}, nil
+	return t, nil
 }
 
 // CaptureStart implements the EVMLogger interface to initialize the tracing operation.
@@ -261,7 +275,9 @@ func (t *callTracer) GetResult() (json.RawMessage, error) {
 	if len(t.callstack) != 1 {
 		return nil, errors.New("incorrect number of top-level calls")
 	}
-
+	if t.decoder != nil {
+		t.decoder.decodeFrame(&t.callstack[0])
+	}
 	res, err := json.Marshal(t.callstack[0])
 	if err != nil {
 		return nil, err
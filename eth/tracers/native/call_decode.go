@@ -0,0 +1,357 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package native
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// SelectorDatabase resolves 4-byte selectors to textual signatures, such as
+// "transfer(address,uint256)". It is implemented by signer/fourbyte.Database.
+type SelectorDatabase interface {
+	Selector(id []byte) (string, error)
+}
+
+var (
+	selectorDB     SelectorDatabase
+	selectorDBLock sync.RWMutex
+)
+
+// SetSelectorDatabase sets the database used by the callTracer to decode
+// calls and errors of contracts without a configured ABI, if the tracer is
+// configured with fourByte.
+func SetSelectorDatabase(db SelectorDatabase) {
+	selectorDBLock.Lock()
+	defer selectorDBLock.Unlock()
+	selectorDB = db
+}
+
+// decodedCall is the ABI decoded input and output of a call frame.
+type decodedCall struct {
+	Method    string       `json:"method"`
+	Signature string       `json:"signature"`
+	Inputs    []decodedArg `json:"inputs"`
+	Outputs   []decodedArg `json:"outputs,omitempty"`
+}
+
+// decodedError is an ABI decoded revert reason, either one of the builtin
+// Error(string) and Panic(uint256) or a custom error.
+type decodedError struct {
+	Name      string       `json:"name"`
+	Signature string       `json:"signature"`
+	Inputs    []decodedArg `json:"inputs"`
+}
+
+// decodedLog is an ABI decoded event.
+type decodedLog struct {
+	Event     string       `json:"event"`
+	Signature string       `json:"signature"`
+	Inputs    []decodedArg `json:"inputs"`
+}
+
+// decodedArg is a single decoded argument or return value.
+type decodedArg struct {
+	Name  string      `json:"name"`
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+// abiDecoder decodes call frames using the ABIs configured per address, and
+// falls back to the selector database if enabled.
+type abiDecoder struct {
+	abis map[common.Address]*abi.ABI
+	db   SelectorDatabase
+
+	methods map[[4]byte]*abi.Method // methods resolved from the database
+	errors  map[[4]byte]*abi.Error  // errors resolved from the database
+}
+
+func newABIDecoder(abis map[common.Address]json.RawMessage, fourByte bool) (*abiDecoder, error) {
+	d := &abiDecoder{
+		abis:    make(map[common.Address]*abi.ABI),
+		methods: make(map[[4]byte]*abi.Method),
+		errors:  make(map[[4]byte]*abi.Error),
+	}
+	for addr, raw := range abis {
+		// Accept the ABI both as JSON and as a string containing the JSON.
+		var str string
+		if err := json.Unmarshal(raw, &str); err == nil {
+			raw = json.RawMessage(str)
+		}
+		parsed, err := abi.JSON(bytes.NewReader(raw))
+		if err != nil {
+			return nil, fmt.Errorf("invalid ABI for %v: %v", addr, err)
+		}
+		d.abis[addr] = &parsed
+	}
+	if fourByte {
+		selectorDBLock.RLock()
+		d.db = selectorDB
+		selectorDBLock.RUnlock()
+		if d.db == nil {
+			return nil, errors.New("no selector database available")
+		}
+	}
+	return d, nil
+}
+
+// decodeFrame decodes the input, output, revert reason and logs of a frame
+// and its subcalls.
+func (d *abiDecoder) decodeFrame(f *callFrame) {
+	if f.To != nil && f.Type != vm.CREATE && f.Type != vm.CREATE2 && len(f.Input) >= 4 {
+		if method, fromABI := d.method(*f.To, f.Input[:4]); method != nil {
+			if inputs, err := decodeArgs(method.Inputs, f.Input[4:]); err == nil {
+				f.Decoded = &decodedCall{Method: method.RawName, Signature: method.Sig, Inputs: inputs}
+				// Return values are only known from ABIs, as the signature
+				// of a selector doesn't include them.
+				if fromABI && !f.failed() && len(method.Outputs) > 0 {
+					f.Decoded.Outputs, _ = decodeArgs(method.Outputs, f.Output)
+				}
+			}
+		}
+	}
+	if f.failed() && len(f.Output) >= 4 {
+		var to common.Address
+		if f.To != nil {
+			to = *f.To
+		}
+		f.DecodedError = d.decodeError(to, f.Output)
+	}
+	for i := range f.Logs {
+		f.Logs[i].Decoded = d.decodeLog(f, &f.Logs[i])
+	}
+	for i := range f.Calls {
+		d.decodeFrame(&f.Calls[i])
+	}
+}
+
+// method looks up the method of a selector, reporting whether it was found in
+// the ABI of the contract.
+func (d *abiDecoder) method(addr common.Address, id []byte) (*abi.Method, bool) {
+	if contract, ok := d.abis[addr]; ok {
+		if method, err := contract.MethodById(id); err == nil {
+			return method, true
+		}
+	}
+	if d.db == nil {
+		return nil, false
+	}
+	sel := [4]byte(id)
+	if method, ok := d.methods[sel]; ok {
+		return method, false
+	}
+	var method *abi.Method
+	if name, args, err := d.lookup(id); err == nil {
+		m := abi.NewMethod(name, name, abi.Function, "", false, false, args, nil)
+		method = &m
+	}
+	d.methods[sel] = method
+	return method, false
+}
+
+// decodeError decodes the revert data of a failed frame.
+func (d *abiDecoder) decodeError(addr common.Address, data []byte) *decodedError {
+	sel := [4]byte(data[:4])
+
+	var e *abi.Error
+	switch sel {
+	case revertSelector:
+		e = &builtinErrors[0]
+	case panicSelector:
+		e = &builtinErrors[1]
+	default:
+		if contract, ok := d.abis[addr]; ok {
+			e, _ = contract.ErrorByID(sel)
+		}
+		if e == nil && d.db != nil {
+			var ok bool
+			if e, ok = d.errors[sel]; !ok {
+				if name, args, err := d.lookup(data[:4]); err == nil {
+					err := abi.NewError(name, args)
+					e = &err
+				}
+				d.errors[sel] = e
+			}
+		}
+	}
+	if e == nil {
+		return nil
+	}
+	inputs, err := decodeArgs(e.Inputs, data[4:])
+	if err != nil {
+		return nil
+	}
+	return &decodedError{Name: e.Name, Signature: e.Sig, Inputs: inputs}
+}
+
+// decodeLog decodes a log emitted in a frame. The event is looked up in the
+// ABI of the emitting address, then in the ABI of the executing code, which
+// differ for delegate calls.
+func (d *abiDecoder) decodeLog(f *callFrame, log *callLog) *decodedLog {
+	if len(log.Topics) == 0 {
+		return nil
+	}
+	var event *abi.Event
+	if contract, ok := d.abis[log.Address]; ok {
+		event, _ = contract.EventByID(log.Topics[0])
+	}
+	if event == nil && f.To != nil {
+		if contract, ok := d.abis[*f.To]; ok {
+			event, _ = contract.EventByID(log.Topics[0])
+		}
+	}
+	if event == nil {
+		return nil
+	}
+	data, err := decodeArgs(event.Inputs.NonIndexed(), log.Data)
+	if err != nil {
+		return nil
+	}
+	var (
+		topics = log.Topics[1:]
+		inputs = make([]decodedArg, 0, len(event.Inputs))
+	)
+	for _, arg := range event.Inputs {
+		if !arg.Indexed {
+			inputs = append(inputs, data[0])
+			data = data[1:]
+			continue
+		}
+		if len(topics) == 0 {
+			return nil
+		}
+		topic := topics[0]
+		topics = topics[1:]
+
+		decoded := decodedArg{Name: arg.Name, Type: arg.Type.String()}
+		switch arg.Type.T {
+		case abi.StringTy, abi.BytesTy, abi.SliceTy, abi.ArrayTy, abi.TupleTy:
+			// Only the hash of dynamic values is stored in the topic.
+			decoded.Value = topic
+		default:
+			value, err := abi.Arguments{{Type: arg.Type}}.Unpack(topic[:])
+			if err != nil {
+				return nil
+			}
+			decoded.Value = formatValue(arg.Type, reflect.ValueOf(value[0]))
+		}
+		inputs = append(inputs, decoded)
+	}
+	return &decodedLog{Event: event.RawName, Signature: event.Sig, Inputs: inputs}
+}
+
+// lookup resolves a selector to the name and arguments of a function or error
+// through the selector database.
+func (d *abiDecoder) lookup(id []byte) (string, abi.Arguments, error) {
+	sig, err := d.db.Selector(id)
+	if err != nil {
+		return "", nil, err
+	}
+	sel, err := abi.ParseSelector(sig)
+	if err != nil {
+		return "", nil, err
+	}
+	args := make(abi.Arguments, len(sel.Inputs))
+	for i, input := range sel.Inputs {
+		typ, err := abi.NewType(input.Type, "", input.Components)
+		if err != nil {
+			return "", nil, err
+		}
+		// The database doesn't know argument names.
+		args[i] = abi.Argument{Type: typ}
+	}
+	return sel.Name, args, nil
+}
+
+// builtinErrors are the errors emitted by the Solidity compiler for failed
+// requires and asserts.
+var builtinErrors = func() []abi.Error {
+	str, _ := abi.NewType("string", "", nil)
+	uint256, _ := abi.NewType("uint256", "", nil)
+	return []abi.Error{
+		abi.NewError("Error", abi.Arguments{{Name: "message", Type: str}}),
+		abi.NewError("Panic", abi.Arguments{{Name: "code", Type: uint256}}),
+	}
+}()
+
+var (
+	revertSelector = [4]byte(builtinErrors[0].ID[:4])
+	panicSelector  = [4]byte(builtinErrors[1].ID[:4])
+)
+
+// decodeArgs unpacks ABI encoded values.
+func decodeArgs(args abi.Arguments, data []byte) ([]decodedArg, error) {
+	values, err := args.Unpack(data)
+	if err != nil {
+		return nil, err
+	}
+	decoded := make([]decodedArg, len(args))
+	for i, arg := range args {
+		decoded[i] = decodedArg{
+			Name:  arg.Name,
+			Type:  arg.Type.String(),
+			Value: formatValue(arg.Type, reflect.ValueOf(values[i])),
+		}
+	}
+	return decoded, nil
+}
+
+// formatValue converts an unpacked value into its JSON representation:
+// integers as decimal strings, byte arrays as hex and tuples as objects.
+func formatValue(typ abi.Type, v reflect.Value) interface{} {
+	switch typ.T {
+	case abi.IntTy, abi.UintTy:
+		switch n := v.Interface().(type) {
+		case *big.Int:
+			return n.String()
+		default:
+			return fmt.Sprint(n)
+		}
+	case abi.BytesTy:
+		return hexutil.Bytes(v.Bytes())
+	case abi.FixedBytesTy, abi.FunctionTy:
+		b := make([]byte, v.Len())
+		reflect.Copy(reflect.ValueOf(b), v)
+		return hexutil.Bytes(b)
+	case abi.SliceTy, abi.ArrayTy:
+		out := make([]interface{}, v.Len())
+		for i := range out {
+			out[i] = formatValue(*typ.Elem, v.Index(i))
+		}
+		return out
+	case abi.TupleTy:
+		out := make(map[string]interface{}, len(typ.TupleElems))
+		for i, elem := range typ.TupleElems {
+			out[typ.TupleRawNames[i]] = formatValue(*elem, v.Field(i))
+		}
+		return out
+	default:
+		// bool, string and address marshal as expected
+		return v.Interface()
+	}
+}
@@ -27,6 +27,8 @@ func (c callFrame) MarshalJSON() ([]byte, error) {
 		RevertReason string          `json:"revertReason,omitempty"`
 		Calls        []callFrame     `json:"calls,omitempty" rlp:"optional"`
 		Logs         []callLog       `json:"logs,omitempty" rlp:"optional"`
+		Decoded      *decodedCall    `json:"decoded,omitempty" rlp:"-"`
+		DecodedError *decodedError   `json:"decodedError,omitempty" rlp:"-"`
 		Value        *hexutil.Big    `json:"value,omitempty" rlp:"optional"`
 		TypeString   string          `json:"type"`
 	}
@@ -42,6 +44,8 @@ func (c callFrame) MarshalJSON() ([]byte, error) {
 	enc.RevertReason = c.RevertReason
 	enc.Calls = c.Calls
 	enc.Logs = c.Logs
+	enc.Decoded = c.Decoded
+	enc.DecodedError = c.DecodedError
 	enc.Value = (*hexutil.Big)(c.Value)
 	enc.TypeString = c.TypeString()
 	return json.Marshal(&enc)
@@ -61,6 +65,8 @@ func (c *callFrame) UnmarshalJSON(input []byte) error {
 		RevertReason *string         `json:"revertReason,omitempty"`
 		Calls        []callFrame     `json:"calls,omitempty" rlp:"optional"`
 		Logs         []callLog       `json:"logs,omitempty" rlp:"optional"`
+		Decoded      *decodedCall    `json:"decoded,omitempty" rlp:"-"`
+		DecodedError *decodedError   `json:"decodedError,omitempty" rlp:"-"`
 		Value        *hexutil.Big    `json:"value,omitempty" rlp:"optional"`
 	}
 	var dec callFrame0
@@ -100,6 +106,12 @@ func (c *callFrame) UnmarshalJSON(input []byte) error {
 	if dec.Logs != nil {
 		c.Logs = dec.Logs
 	}
+	if dec.Decoded != nil {
+		c.Decoded = dec.Decoded
+	}
+	if dec.DecodedError != nil {
+		c.DecodedError = dec.DecodedError
+	}
 	if dec.Value != nil {
 		c.Value = (*big.Int)(dec.Value)
 	}
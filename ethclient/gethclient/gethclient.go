@@ -125,6 +125,99 @@ func (ec *Client) GetProof(ctx context.Context, account common.Address, keys []s
 	return &result, err
 }
 
+// ProofRequest selects an account and its storage slots to prove with GetMultiProof.
+type ProofRequest struct {
+	Address     common.Address `json:"address"`
+	StorageKeys []string       `json:"storageKeys"`
+}
+
+// MultiProofResult is the result of a GetMultiProof operation. Each proof
+// contains the nodes of a trie on the paths to all the requested keys, each node
+// only once.
+type MultiProofResult struct {
+	AccountProof []string             `json:"accountProof"`
+	Accounts     []MultiAccountResult `json:"accounts"`
+}
+
+// MultiAccountResult provides the values of an account and its storage slots,
+// along with the multiproof of its storage trie.
+type MultiAccountResult struct {
+	Address      common.Address       `json:"address"`
+	Balance      *big.Int             `json:"balance"`
+	CodeHash     common.Hash          `json:"codeHash"`
+	Nonce        uint64               `json:"nonce"`
+	StorageHash  common.Hash          `json:"storageHash"`
+	StorageProof []string             `json:"storageProof"`
+	Storage      []MultiStorageResult `json:"storage"`
+}
+
+// MultiStorageResult provides the value of a storage slot.
+type MultiStorageResult struct {
+	Key   string   `json:"key"`
+	Value *big.Int `json:"value"`
+}
+
+// GetMultiProof returns the account and storage values of the specified accounts
+// including a Merkle-multiproof of the account trie and of each storage trie.
+// The block number can be nil, in which case the value is taken from the latest
+// known block.
+func (ec *Client) GetMultiProof(ctx context.Context, requests []ProofRequest, blockNumber *big.Int) (*MultiProofResult, error) {
+	type storageResult struct {
+		Key   string       `json:"key"`
+		Value *hexutil.Big `json:"value"`
+	}
+
+	type accountResult struct {
+		Address      common.Address  `json:"address"`
+		Balance      *hexutil.Big    `json:"balance"`
+		CodeHash     common.Hash     `json:"codeHash"`
+		Nonce        hexutil.Uint64  `json:"nonce"`
+		StorageHash  common.Hash     `json:"storageHash"`
+		StorageProof []string        `json:"storageProof"`
+		Storage      []storageResult `json:"storage"`
+	}
+
+	type multiProofResult struct {
+		AccountProof []string        `json:"accountProof"`
+		Accounts     []accountResult `json:"accounts"`
+	}
+
+	// Avoid keys being 'null'.
+	for i := range requests {
+		if requests[i].StorageKeys == nil {
+			requests[i].StorageKeys = []string{}
+		}
+	}
+	var res multiProofResult
+	if err := ec.c.CallContext(ctx, &res, "eth_getMultiProof", requests, toBlockNumArg(blockNumber)); err != nil {
+		return nil, err
+	}
+	// Turn hexutils back to normal datatypes
+	result := &MultiProofResult{
+		AccountProof: res.AccountProof,
+		Accounts:     make([]MultiAccountResult, 0, len(res.Accounts)),
+	}
+	for _, acc := range res.Accounts {
+		storage := make([]MultiStorageResult, 0, len(acc.Storage))
+		for _, st := range acc.Storage {
+			storage = append(storage, MultiStorageResult{
+				Key:   st.Key,
+				Value: st.Value.ToInt(),
+			})
+		}
+		result.Accounts = append(result.Accounts, MultiAccountResult{
+			Address:      acc.Address,
+			Balance:      acc.Balance.ToInt(),
+			CodeHash:     acc.CodeHash,
+			Nonce:        uint64(acc.Nonce),
+			StorageHash:  acc.StorageHash,
+			StorageProof: acc.StorageProof,
+			Storage:      storage,
+		})
+	}
+	return result, nil
+}
+
 // CallContract executes a message call transaction, which is directly executed in the VM
 // of the node, but never mined into the blockchain.
 //
@@ -35,7 +35,10 @@ import (
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/ethereum/go-ethereum/trie/trienode"
 )
 
 var (
@@ -123,6 +126,9 @@ func TestGethClient(t *testing.T) {
 		}, {
 			"TestGetProofCanonicalizeKeys",
 			func(t *testing.T) { testGetProofCanonicalizeKeys(t, client) },
+		}, {
+			"TestGetMultiProof",
+			func(t *testing.T) { testGetMultiProof(t, client) },
 		}, {
 			"TestGCStats",
 			func(t *testing.T) { testGCStats(t, client) },
@@ -254,6 +260,73 @@ func testGetProof(t *testing.T, client *rpc.Client, addr common.Address) {
 	}
 }
 
+func testGetMultiProof(t *testing.T, client *rpc.Client) {
+	ec := New(client)
+	ethcl := ethclient.NewClient(client)
+	missing := common.HexToAddress("0x0001")
+	result, err := ec.GetMultiProof(context.Background(), []ProofRequest{
+		{Address: testAddr, StorageKeys: []string{testSlot.String(), "0x01"}},
+		{Address: testContract},
+		{Address: missing, StorageKeys: []string{testSlot.String()}},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header, err := ethcl.HeaderByNumber(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decodeProof := func(proof []string) *trienode.ProofSet {
+		var list trienode.ProofList
+		for _, node := range proof {
+			list = append(list, common.FromHex(node))
+		}
+		return list.Set()
+	}
+	// Verify the accounts against the state root
+	keys := [][]byte{
+		crypto.Keccak256(testAddr.Bytes()),
+		crypto.Keccak256(testContract.Bytes()),
+		crypto.Keccak256(missing.Bytes()),
+	}
+	values, err := trie.VerifyMultiProof(header.Root, keys, decodeProof(result.AccountProof))
+	if err != nil {
+		t.Fatalf("invalid account proof: %v", err)
+	}
+	if values[2] != nil {
+		t.Fatalf("missing account proven to exist")
+	}
+	for i, acc := range result.Accounts[:2] {
+		var data types.StateAccount
+		if err := rlp.DecodeBytes(values[i], &data); err != nil {
+			t.Fatalf("invalid account %d: %v", i, err)
+		}
+		if data.Nonce != acc.Nonce || data.Balance.ToBig().Cmp(acc.Balance) != 0 || data.Root != acc.StorageHash || !bytes.Equal(data.CodeHash, acc.CodeHash[:]) {
+			t.Fatalf("account %d mismatch: have %+v, proven %+v", i, acc, data)
+		}
+	}
+	// Verify the storage against the storage root
+	acc := result.Accounts[0]
+	if len(acc.Storage) != 2 || acc.Storage[0].Key != testSlot.String() || acc.Storage[1].Key != "0x1" {
+		t.Fatalf("invalid storage result: %+v", acc.Storage)
+	}
+	values, err = trie.VerifyMultiProof(acc.StorageHash, [][]byte{crypto.Keccak256(testSlot[:]), crypto.Keccak256(common.HexToHash("0x01").Bytes())}, decodeProof(acc.StorageProof))
+	if err != nil {
+		t.Fatalf("invalid storage proof: %v", err)
+	}
+	var value []byte
+	rlp.DecodeBytes(values[0], &value)
+	if have, want := common.BytesToHash(value), common.BigToHash(acc.Storage[0].Value); have != want || want != testValue {
+		t.Fatalf("storage value mismatch: have %x, proven %x, want %x", want, have, testValue)
+	}
+	if values[1] != nil || acc.Storage[1].Value.Sign() != 0 {
+		t.Fatalf("missing slot proven to exist")
+	}
+	if len(result.Accounts[2].StorageProof) != 0 || result.Accounts[2].Storage[0].Value.Sign() != 0 {
+		t.Fatalf("invalid storage of missing account: %+v", result.Accounts[2])
+	}
+}
+
 func testGetProofCanonicalizeKeys(t *testing.T, client *rpc.Client) {
 	ec := New(client)
 
@@ -55,6 +55,14 @@ type Compacter interface {
 	Compact(start []byte, limit []byte) error
 }
 
+// Checkpointer wraps the Checkpoint method of a backing data store.
+type Checkpointer interface {
+	// Checkpoint creates a consistent copy of the data store in the given
+	// directory, which must not exist yet. The copy can be opened as a separate
+	// data store, while the original one remains in use.
+	Checkpoint(dir string) error
+}
+
 // KeyValueStore contains all the methods required to allow handling different
 // key-value data stores backing the high level database.
 type KeyValueStore interface {
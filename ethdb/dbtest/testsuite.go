@@ -19,6 +19,7 @@ package dbtest
 import (
 	"bytes"
 	"crypto/rand"
+	"path/filepath"
 	"reflect"
 	"sort"
 	"testing"
@@ -504,6 +505,59 @@ func BenchDatabaseSuite(b *testing.B, New func() ethdb.KeyValueStore) {
 	})
 }
 
+// TestCheckpointSuite runs a suite of tests against a KeyValueStore database
+// implementation supporting checkpoints. The open function opens the database
+// in the given directory.
+func TestCheckpointSuite(t *testing.T, open func(dir string) ethdb.KeyValueStore) {
+	var (
+		dir      = t.TempDir()
+		db       = open(filepath.Join(dir, "db"))
+		cpdir    = filepath.Join(dir, "checkpoint")
+		keys, vs = makeDataset(1000, 32, 64, false)
+	)
+	defer db.Close()
+
+	for i := 0; i < 500; i++ {
+		if err := db.Put(keys[i], vs[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Delete(keys[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.(ethdb.Checkpointer).Checkpoint(cpdir); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.(ethdb.Checkpointer).Checkpoint(cpdir); err == nil {
+		t.Fatal("Checkpoint into existing directory succeeded")
+	}
+	// Modifications after the checkpoint must not affect it.
+	for i := 500; i < 1000; i++ {
+		if err := db.Put(keys[i], vs[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Delete(keys[1]); err != nil {
+		t.Fatal(err)
+	}
+	cp := open(cpdir)
+	defer cp.Close()
+
+	for i := 0; i < 1000; i++ {
+		have, err := cp.Get(keys[i])
+		switch {
+		case i == 0 || i >= 500:
+			if err == nil {
+				t.Fatalf("Key %d unexpectedly present in checkpoint", i)
+			}
+		case err != nil:
+			t.Fatalf("Key %d missing from checkpoint: %v", i, err)
+		case !bytes.Equal(have, vs[i]):
+			t.Fatalf("Key %d has wrong value: have %x, want %x", i, have, vs[i])
+		}
+	}
+}
+
 func iterateKeys(it ethdb.Iterator) []string {
 	keys := []string{}
 	for it.Next() {
@@ -0,0 +1,318 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package faultdb implements a database wrapper injecting faults into the write
+// operations, simulating crashes for testing the recovery of the data stored.
+package faultdb
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// ErrFault is returned by the write operation on which a fault is injected in
+// Fail mode.
+var ErrFault = errors.New("injected fault")
+
+// Op is a set of write operation kinds on which faults can be injected.
+type Op uint8
+
+const (
+	OpPut      Op = 1 << iota // Key-value store insertions
+	OpDelete                  // Key-value store deletions
+	OpBatch                   // Batch writes
+	OpAppend                  // Ancient store modifications
+	OpTruncate                // Ancient store head and tail truncations
+	OpSync                    // Ancient store flushes
+
+	OpAll = OpPut | OpDelete | OpBatch | OpAppend | OpTruncate | OpSync
+)
+
+// Mode defines how a fault is injected into a write operation.
+type Mode int
+
+const (
+	// Drop silently discards the faulted write, reporting success.
+	Drop Mode = iota
+
+	// Fail rejects the faulted write with ErrFault.
+	Fail
+
+	// Crash checkpoints the database into the configured directory before the
+	// faulted write, capturing the data persisted up to a crash at that point.
+	// All writes, including the faulted one, are then let through, so that the
+	// running process keeps a consistent view of the database.
+	Crash
+
+	// PowerLoss checkpoints the database like Crash, but the ancient data
+	// appended through the wrapper since the last successful Sync is then
+	// truncated from the copy, as lost by a power failure at that point. The
+	// key-value store has no sync operation, its writes are considered durable
+	// once issued.
+	PowerLoss
+)
+
+// Config defines where and how a fault is injected.
+type Config struct {
+	Ops   Op     // Kinds of write operations counted as injection points
+	Point int    // Zero-based index of the counted operation to fault, negative to disable
+	Mode  Mode   // Mode of the injected fault
+	Dir   string // Directory to checkpoint the database into in Crash and PowerLoss modes
+}
+
+// Database is a wrapper around a database which counts the write operations of
+// the configured kinds, injecting a fault into the one at the configured point.
+// The database is then considered crashed: unless in Crash or PowerLoss mode,
+// all subsequent writes, regardless of their kind, are silently discarded, while
+// reads are still served from the wrapped database.
+type Database struct {
+	ethdb.Database
+	config Config
+
+	count   int    // Number of counted write operations issued so far
+	crashed bool   // Whether the fault has been injected
+	dirty   bool   // Whether ancient data was appended since the last flush
+	flushed uint64 // Number of ancient items flushed to disk, if dirty
+	lock    sync.Mutex
+}
+
+// New wraps a database, injecting a fault as configured. Crash and PowerLoss
+// modes require the database to support checkpoints.
+func New(db ethdb.Database, config Config) *Database {
+	return &Database{
+		Database: db,
+		config:   config,
+	}
+}
+
+// Count returns the number of write operations of the counted kinds issued so
+// far, including the faulted one.
+func (db *Database) Count() int {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	return db.count
+}
+
+// Crashed returns whether the fault has been injected.
+func (db *Database) Crashed() bool {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	return db.crashed
+}
+
+// fault registers a write operation of the given kind, returning whether it must
+// be discarded and the error to report for it.
+func (db *Database) fault(op Op) (bool, error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if db.crashed {
+		return !db.config.Mode.persists(), nil
+	}
+	if db.config.Ops&op == 0 {
+		return false, nil
+	}
+	db.count++
+	if db.config.Point < 0 || db.count <= db.config.Point {
+		return false, nil
+	}
+	db.crashed = true
+
+	switch db.config.Mode {
+	case Fail:
+		return true, ErrFault
+	case Crash, PowerLoss:
+		cp, ok := db.Database.(ethdb.Checkpointer)
+		if !ok {
+			return true, errors.New("checkpoints not supported")
+		}
+		if err := cp.Checkpoint(db.config.Dir); err != nil {
+			return true, err
+		}
+		if db.config.Mode == PowerLoss {
+			if err := db.discardUnsynced(); err != nil {
+				return true, err
+			}
+		}
+		return false, nil
+	default:
+		return true, nil
+	}
+}
+
+// persists returns whether the writes following the fault are let through.
+func (mode Mode) persists() bool {
+	return mode == Crash || mode == PowerLoss
+}
+
+// discardUnsynced truncates the ancient data not flushed to disk from the
+// checkpoint, which is laid out as by the database created with rawdb.Open.
+func (db *Database) discardUnsynced() error {
+	if !db.dirty {
+		return nil
+	}
+	cp, err := rawdb.Open(rawdb.OpenOptions{
+		Directory:         db.config.Dir,
+		AncientsDirectory: filepath.Join(db.config.Dir, "ancient"),
+		Ephemeral:         true,
+	})
+	if err != nil {
+		return err
+	}
+	defer cp.Close()
+
+	_, err = cp.TruncateHead(db.flushed)
+	return err
+}
+
+// Put inserts the given value into the key-value store, unless faulted.
+func (db *Database) Put(key []byte, value []byte) error {
+	if drop, err := db.fault(OpPut); drop {
+		return err
+	}
+	return db.Database.Put(key, value)
+}
+
+// Delete removes the key from the key-value store, unless faulted.
+func (db *Database) Delete(key []byte) error {
+	if drop, err := db.fault(OpDelete); drop {
+		return err
+	}
+	return db.Database.Delete(key)
+}
+
+// NewBatch creates a write-only key-value store that buffers changes to the
+// database until a final write is called, which may be faulted.
+func (db *Database) NewBatch() ethdb.Batch {
+	return &batch{Batch: db.Database.NewBatch(), db: db}
+}
+
+// NewBatchWithSize creates a write-only database batch with pre-allocated buffer.
+func (db *Database) NewBatchWithSize(size int) ethdb.Batch {
+	return &batch{Batch: db.Database.NewBatchWithSize(size), db: db}
+}
+
+// ModifyAncients runs a write operation on the ancient store, unless faulted. A
+// discarded operation is not run at all.
+func (db *Database) ModifyAncients(fn func(ethdb.AncientWriteOp) error) (int64, error) {
+	if drop, err := db.fault(OpAppend); drop {
+		return 0, err
+	}
+	frozen, _ := db.Database.Ancients()
+	size, err := db.Database.ModifyAncients(fn)
+
+	db.lock.Lock()
+	if !db.dirty {
+		db.dirty, db.flushed = true, frozen
+	}
+	db.lock.Unlock()
+	return size, err
+}
+
+// TruncateHead discards all but the first n ancient data from the ancient store,
+// unless faulted.
+func (db *Database) TruncateHead(n uint64) (uint64, error) {
+	if drop, err := db.fault(OpTruncate); drop {
+		if err != nil {
+			return 0, err
+		}
+		return db.Database.Ancients()
+	}
+	old, err := db.Database.TruncateHead(n)
+	if err != nil {
+		return old, err
+	}
+	// The truncation is flushed to disk, so no unflushed items are left if all
+	// of them were truncated.
+	db.lock.Lock()
+	if db.dirty && n <= db.flushed {
+		db.dirty = false
+	}
+	db.lock.Unlock()
+	return old, nil
+}
+
+// TruncateTail discards the first n ancient data from the ancient store, unless
+// faulted.
+func (db *Database) TruncateTail(n uint64) (uint64, error) {
+	if drop, err := db.fault(OpTruncate); drop {
+		if err != nil {
+			return 0, err
+		}
+		return db.Database.Tail()
+	}
+	return db.Database.TruncateTail(n)
+}
+
+// Sync flushes all in-memory ancient store data to disk, unless faulted.
+func (db *Database) Sync() error {
+	if drop, err := db.fault(OpSync); drop {
+		return err
+	}
+	if err := db.Database.Sync(); err != nil {
+		return err
+	}
+	db.lock.Lock()
+	db.dirty = false
+	db.lock.Unlock()
+	return nil
+}
+
+// MigrateTable processes the entries in a given table in sequence, unless the
+// database crashed.
+func (db *Database) MigrateTable(kind string, convert func([]byte) ([]byte, error)) error {
+	if db.discarding() {
+		return nil
+	}
+	return db.Database.MigrateTable(kind, convert)
+}
+
+// Compact flattens the underlying data store for the given key range, unless the
+// database crashed.
+func (db *Database) Compact(start []byte, limit []byte) error {
+	if db.discarding() {
+		return nil
+	}
+	return db.Database.Compact(start, limit)
+}
+
+// discarding returns whether the database crashed and discards all writes.
+func (db *Database) discarding() bool {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	return db.crashed && !db.config.Mode.persists()
+}
+
+// batch is a write-only batch whose write may be faulted.
+type batch struct {
+	ethdb.Batch
+	db *Database
+}
+
+// Write flushes any accumulated data to disk, unless faulted.
+func (b *batch) Write() error {
+	if drop, err := b.db.fault(OpBatch); drop {
+		return err
+	}
+	return b.Batch.Write()
+}
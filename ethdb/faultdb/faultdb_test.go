@@ -0,0 +1,192 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package faultdb
+
+import (
+	"errors"
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/dbtest"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+)
+
+func TestFaultDB(t *testing.T) {
+	t.Run("DatabaseSuite", func(t *testing.T) {
+		dbtest.TestDatabaseSuite(t, func() ethdb.KeyValueStore {
+			return New(rawdb.NewMemoryDatabase(), Config{Ops: OpAll, Point: -1})
+		})
+	})
+}
+
+// write issues a put, a batch write and an ancient append, returning the errors.
+func write(db *Database, n byte) []error {
+	var errs []error
+	errs = append(errs, db.Put([]byte{'p', n}, []byte{n}))
+
+	batch := db.NewBatch()
+	batch.Put([]byte{'b', n}, []byte{n})
+	errs = append(errs, batch.Write())
+
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(int64(n))})
+	_, err := rawdb.WriteAncientBlocks(db, []*types.Block{block}, []types.Receipts{nil}, big.NewInt(0))
+	return append(errs, err)
+}
+
+func TestFaultInjection(t *testing.T) {
+	for _, mode := range []Mode{Drop, Fail} {
+		for point := 0; point < 6; point++ {
+			inner, err := rawdb.NewDatabaseWithFreezer(memorydb.New(), t.TempDir(), "", false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			db := New(inner, Config{Ops: OpPut | OpBatch | OpAppend, Point: point, Mode: mode})
+
+			errs := append(write(db, 0), write(db, 1)...)
+			for i, err := range errs {
+				var want error
+				if i == point && mode == Fail {
+					want = ErrFault
+				}
+				if !errors.Is(err, want) {
+					t.Fatalf("mode %d point %d: op %d error mismatch: have %v, want %v", mode, point, i, err, want)
+				}
+			}
+			if !db.Crashed() {
+				t.Fatalf("mode %d point %d: database not crashed", mode, point)
+			}
+			if db.Count() != point+1 {
+				t.Fatalf("mode %d point %d: count mismatch: have %d, want %d", mode, point, db.Count(), point+1)
+			}
+			// Check that only the writes preceding the fault reached the database
+			if have := persisted(inner); have != point {
+				t.Fatalf("mode %d point %d: persisted writes mismatch: have %d, want %d", mode, point, have, point)
+			}
+			inner.Close()
+		}
+	}
+}
+
+// persisted returns the number of writes issued by write found in the database.
+func persisted(db ethdb.Database) int {
+	var count int
+	for n := byte(0); n < 2; n++ {
+		if ok, _ := db.Has([]byte{'p', n}); ok {
+			count++
+		}
+		if ok, _ := db.Has([]byte{'b', n}); ok {
+			count++
+		}
+	}
+	ancients, _ := db.Ancients()
+	return count + int(ancients)
+}
+
+func TestCrashCheckpoint(t *testing.T) {
+	for point := 0; point < 6; point++ {
+		var (
+			dir   = t.TempDir()
+			crash = filepath.Join(t.TempDir(), "crash")
+		)
+		inner, err := rawdb.Open(rawdb.OpenOptions{Type: "pebble", Directory: dir, AncientsDirectory: filepath.Join(dir, "ancient")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		db := New(inner, Config{Ops: OpAll, Point: point, Mode: Crash, Dir: crash})
+		errs := append(write(db, 0), write(db, 1)...)
+		for i, err := range errs {
+			if err != nil {
+				t.Fatalf("point %d: op %d failed: %v", point, i, err)
+			}
+		}
+		if !db.Crashed() {
+			t.Fatalf("point %d: database not crashed", point)
+		}
+		// Check that all writes reached the database, but only the ones preceding
+		// the fault the checkpoint
+		if have := persisted(inner); have != 6 {
+			t.Fatalf("point %d: persisted writes mismatch: have %d, want %d", point, have, 6)
+		}
+		inner.Close()
+
+		cp, err := rawdb.Open(rawdb.OpenOptions{Type: "pebble", Directory: crash, AncientsDirectory: filepath.Join(crash, "ancient")})
+		if err != nil {
+			t.Fatalf("point %d: failed to open checkpoint: %v", point, err)
+		}
+		if have := persisted(cp); have != point {
+			t.Fatalf("point %d: checkpointed writes mismatch: have %d, want %d", point, have, point)
+		}
+		cp.Close()
+	}
+}
+
+func TestPowerLossCheckpoint(t *testing.T) {
+	for _, sync := range []bool{false, true} {
+		var (
+			dir   = t.TempDir()
+			crash = filepath.Join(t.TempDir(), "crash")
+		)
+		inner, err := rawdb.Open(rawdb.OpenOptions{Type: "pebble", Directory: dir, AncientsDirectory: filepath.Join(dir, "ancient")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Crash at the put following the writes, with the first ancient append
+		// optionally flushed to disk
+		point := 6
+		if sync {
+			point++
+		}
+		db := New(inner, Config{Ops: OpAll, Point: point, Mode: PowerLoss, Dir: crash})
+		errs := write(db, 0)
+		if sync {
+			errs = append(errs, db.Sync())
+		}
+		errs = append(errs, write(db, 1)...)
+		errs = append(errs, db.Put([]byte{'x'}, nil))
+		for i, err := range errs {
+			if err != nil {
+				t.Fatalf("sync %t: op %d failed: %v", sync, i, err)
+			}
+		}
+		if !db.Crashed() {
+			t.Fatalf("sync %t: database not crashed", sync)
+		}
+		if have := persisted(inner); have != 6 {
+			t.Fatalf("sync %t: persisted writes mismatch: have %d, want %d", sync, have, 6)
+		}
+		inner.Close()
+
+		// Check that the key-value store writes are checkpointed, but only the
+		// flushed ancient data
+		cp, err := rawdb.Open(rawdb.OpenOptions{Type: "pebble", Directory: crash, AncientsDirectory: filepath.Join(crash, "ancient")})
+		if err != nil {
+			t.Fatalf("sync %t: failed to open checkpoint: %v", sync, err)
+		}
+		want := 4
+		if sync {
+			want++
+		}
+		if have := persisted(cp); have != want {
+			t.Fatalf("sync %t: checkpointed writes mismatch: have %d, want %d", sync, have, want)
+		}
+		cp.Close()
+	}
+}
@@ -22,6 +22,7 @@ package leveldb
 
 import (
 	"fmt"
+	"os"
 	"strings"
 	"sync"
 	"time"
@@ -265,6 +266,55 @@ func (db *Database) Compact(start []byte, limit []byte) error {
 	return db.db.CompactRange(util.Range{Start: start, Limit: limit})
 }
 
+// Checkpoint creates a consistent copy of the database in the given directory,
+// by writing the contents of a database snapshot into a new database. As all
+// entries are copied, this takes time proportional to the size of the database.
+func (db *Database) Checkpoint(dir string) error {
+	if _, err := os.Stat(dir); err == nil {
+		return fmt.Errorf("checkpoint directory %s already exists", dir)
+	}
+	snap, err := db.db.GetSnapshot()
+	if err != nil {
+		return err
+	}
+	defer snap.Release()
+
+	cp, err := leveldb.OpenFile(dir, &opt.Options{
+		ErrorIfExist: true,
+		Filter:       filter.NewBloomFilter(10),
+	})
+	if err != nil {
+		return err
+	}
+	var (
+		it    = snap.NewIterator(nil, nil)
+		batch = new(leveldb.Batch)
+		size  int
+	)
+	defer it.Release()
+
+	for it.Next() {
+		batch.Put(it.Key(), it.Value())
+		if size += len(it.Key()) + len(it.Value()); size >= ethdb.IdealBatchSize {
+			if err := cp.Write(batch, nil); err != nil {
+				cp.Close()
+				return err
+			}
+			batch.Reset()
+			size = 0
+		}
+	}
+	if err := it.Error(); err != nil {
+		cp.Close()
+		return err
+	}
+	if err := cp.Write(batch, &opt.WriteOptions{Sync: true}); err != nil {
+		cp.Close()
+		return err
+	}
+	return cp.Close()
+}
+
 // Path returns the path to the database directory.
 func (db *Database) Path() string {
 	return db.fn
@@ -37,6 +37,15 @@ func TestLevelDB(t *testing.T) {
 			}
 		})
 	})
+	t.Run("CheckpointSuite", func(t *testing.T) {
+		dbtest.TestCheckpointSuite(t, func(dir string) ethdb.KeyValueStore {
+			db, err := New(dir, 16, 16, "", false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return db
+		})
+	})
 }
 
 func BenchmarkLevelDB(b *testing.B) {
@@ -19,14 +19,21 @@ package pebble
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
 	"runtime"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/cockroachdb/pebble"
 	"github.com/cockroachdb/pebble/bloom"
+	"github.com/cockroachdb/pebble/vfs"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
@@ -45,6 +52,10 @@ const (
 	// metricsGatheringInterval specifies the interval to retrieve pebble database
 	// compaction, io and pause stats to report to the user.
 	metricsGatheringInterval = 3 * time.Second
+
+	// sharedOpenRetries is the number of attempts to open a database in use by
+	// another process.
+	sharedOpenRetries = 8
 )
 
 // Database is a persistent key-value store based on the pebble storage engine.
@@ -136,6 +147,13 @@ func (l panicLogger) Fatalf(format string, args ...interface{}) {
 // New returns a wrapped pebble DB object. The namespace is the prefix that the
 // metrics reporting should use for surfacing internal stats.
 func New(file string, cache int, handles int, namespace string, readonly bool, ephemeral bool) (*Database, error) {
+	return newDatabase(file, cache, handles, namespace, readonly, ephemeral, vfs.Default, nil)
+}
+
+// newDatabase opens a pebble DB object on the given file system. If a shared
+// cache is given, it is used instead of allocating a dedicated cache and file
+// handles for the database.
+func newDatabase(file string, cache int, handles int, namespace string, readonly bool, ephemeral bool, fs vfs.FS, shared *SharedCache) (*Database, error) {
 	// Ensure we have some minimal caching and file guarantees
 	if cache < minCache {
 		cache = minCache
@@ -144,7 +162,9 @@ func New(file string, cache int, handles int, namespace string, readonly bool, e
 		handles = minHandles
 	}
 	logger := log.New("database", file)
-	logger.Info("Allocated cache and file handles", "cache", common.StorageSize(cache*1024*1024), "handles", handles)
+	if shared == nil {
+		logger.Info("Allocated cache and file handles", "cache", common.StorageSize(cache*1024*1024), "handles", handles)
+	}
 
 	// The max memtable size is limited by the uint32 offsets stored in
 	// internal/arenaskl.node, DeferredBatchOp, and flushableBatchEntry.
@@ -179,10 +199,6 @@ func New(file string, cache int, handles int, namespace string, readonly bool, e
 		writeOptions: &pebble.WriteOptions{Sync: !ephemeral},
 	}
 	opt := &pebble.Options{
-		// Pebble has a single combined cache area and the write
-		// buffers are taken from this too. Assign all available
-		// memory allowance for cache.
-		Cache:        pebble.NewCache(int64(cache * 1024 * 1024)),
 		MaxOpenFiles: handles,
 
 		// The size of memory table(as well as the write buffer).
@@ -212,6 +228,7 @@ func New(file string, cache int, handles int, namespace string, readonly bool, e
 			{TargetFileSize: 2 * 1024 * 1024, FilterPolicy: bloom.FilterPolicy(10)},
 		},
 		ReadOnly: readonly,
+		FS:       fs,
 		EventListener: &pebble.EventListener{
 			CompactionBegin: db.onCompactionBegin,
 			CompactionEnd:   db.onCompactionEnd,
@@ -220,6 +237,13 @@ func New(file string, cache int, handles int, namespace string, readonly bool, e
 		},
 		Logger: panicLogger{}, // TODO(karalabe): Delete when this is upstreamed in Pebble
 	}
+	// Pebble has a single combined cache area and the write buffers are taken
+	// from this too. Assign all available memory allowance for cache.
+	if shared != nil {
+		opt.Cache, opt.TableCache = shared.cache, shared.tables
+	} else {
+		opt.Cache = pebble.NewCache(int64(cache * 1024 * 1024))
+	}
 	// Disable seek compaction explicitly. Check https://github.com/ethereum/go-ethereum/pull/20130
 	// for more details.
 	opt.Experimental.ReadSamplingMultiplier = -1
@@ -436,6 +460,130 @@ func (d *Database) Compact(start []byte, limit []byte) error {
 	return d.db.Compact(start, limit, true) // Parallelization is preferred
 }
 
+// Checkpoint creates a consistent copy of the database in the given directory,
+// using pebble's checkpoint facility. Immutable sstables are hard-linked into the
+// checkpoint if the directory is on the same filesystem, and copied otherwise.
+func (d *Database) Checkpoint(dir string) error {
+	d.quitLock.RLock()
+	defer d.quitLock.RUnlock()
+	if d.closed {
+		return pebble.ErrClosed
+	}
+	return d.db.Checkpoint(dir, pebble.WithFlushedWAL())
+}
+
+// sharedFS is the file system of a database in use by another process. The
+// directory lock is not taken, and sstables are opened from a separate directory
+// of hard links, so they remain accessible after being deleted by the owner of
+// the database.
+type sharedFS struct {
+	vfs.FS
+	links string
+}
+
+// Lock implements vfs.FS, skipping the directory lock.
+func (fs *sharedFS) Lock(name string) (io.Closer, error) {
+	return io.NopCloser(nil), nil
+}
+
+// Open implements vfs.FS, opening linked sstables if present.
+func (fs *sharedFS) Open(name string, opts ...vfs.OpenOption) (vfs.File, error) {
+	if strings.HasSuffix(name, ".sst") {
+		if f, err := fs.FS.Open(fs.PathJoin(fs.links, fs.PathBase(name)), opts...); err == nil {
+			return f, nil
+		}
+	}
+	return fs.FS.Open(name, opts...)
+}
+
+// SharedCache is a block cache and file handle budget shared by the views
+// opened with NewShared, bounding their combined resource usage regardless of
+// the number of views open at a time.
+type SharedCache struct {
+	cache   *pebble.Cache
+	tables  *pebble.TableCache
+	size    int // Size of the cache in megabytes
+	handles int // Number of files kept open at most
+}
+
+// NewSharedCache creates a cache of the given size in megabytes, keeping at most
+// the given number of files open.
+func NewSharedCache(cache int, handles int) *SharedCache {
+	if cache < minCache {
+		cache = minCache
+	}
+	if handles < minHandles {
+		handles = minHandles
+	}
+	log.Info("Allocated shared database cache and file handles", "cache", common.StorageSize(cache*1024*1024), "handles", handles)
+
+	c := pebble.NewCache(int64(cache * 1024 * 1024))
+	return &SharedCache{
+		cache:   c,
+		tables:  pebble.NewTableCache(c, runtime.GOMAXPROCS(0), handles),
+		size:    cache,
+		handles: handles,
+	}
+}
+
+// Close releases the cache. It is only freed once the views still using it are
+// closed too.
+func (c *SharedCache) Close() {
+	c.tables.Unref()
+	c.cache.Unref()
+}
+
+// NewShared opens a read-only view of a database in use by another process,
+// as of the time of opening. The sstables of the view are hard-linked into the
+// links directory, which must be on the same file system as the database and
+// should be removed by the caller after closing the view. The cache is shared
+// with the other views of the database.
+//
+// As the owner of the database may delete sstables obsoleted by compactions in
+// the meantime, opening is retried a few times if files went missing.
+func NewShared(file string, links string, cache *SharedCache, namespace string) (*Database, error) {
+	if err := os.MkdirAll(links, 0755); err != nil {
+		return nil, err
+	}
+	shared := &sharedFS{FS: vfs.Default, links: links}
+	for i := 0; ; i++ {
+		db, err := newDatabase(file, cache.size, cache.handles, namespace, true, false, shared, cache)
+		if err != nil {
+			return nil, err
+		}
+		if err = db.linkTables(links); err == nil {
+			return db, nil
+		}
+		db.Close()
+		if !errors.Is(err, fs.ErrNotExist) || i == sharedOpenRetries-1 {
+			return nil, err
+		}
+		db.log.Debug("Retrying to open shared database", "err", err)
+	}
+}
+
+// linkTables hard-links all sstables of the current version of the database
+// into the given directory.
+func (d *Database) linkTables(links string) error {
+	levels, err := d.db.SSTables()
+	if err != nil {
+		return err
+	}
+	for _, tables := range levels {
+		for _, table := range tables {
+			name := fmt.Sprintf("%06d.sst", uint64(table.BackingSSTNum))
+			dst := filepath.Join(links, name)
+			if _, err := os.Stat(dst); err == nil {
+				continue
+			}
+			if err := os.Link(filepath.Join(d.fn, name), dst); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 // Path returns the path to the database directory.
 func (d *Database) Path() string {
 	return d.fn
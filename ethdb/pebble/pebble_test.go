@@ -17,6 +17,9 @@
 package pebble
 
 import (
+	"bytes"
+	"fmt"
+	"path/filepath"
 	"testing"
 
 	"github.com/cockroachdb/pebble"
@@ -39,6 +42,15 @@ func TestPebbleDB(t *testing.T) {
 			}
 		})
 	})
+	t.Run("CheckpointSuite", func(t *testing.T) {
+		dbtest.TestCheckpointSuite(t, func(dir string) ethdb.KeyValueStore {
+			db, err := New(dir, 16, 16, "", false, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return db
+		})
+	})
 }
 
 func BenchmarkPebbleDB(b *testing.B) {
@@ -54,3 +66,75 @@ func BenchmarkPebbleDB(b *testing.B) {
 		}
 	})
 }
+
+func TestSharedDatabase(t *testing.T) {
+	var (
+		dir  = t.TempDir()
+		path = filepath.Join(dir, "db")
+	)
+	db, err := New(path, 16, 16, "", false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	write := func(from, to int) {
+		t.Helper()
+		for i := from; i < to; i++ {
+			if err := db.Put([]byte(fmt.Sprintf("key-%05d", i)), bytes.Repeat([]byte{byte(i)}, 1024)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	check := func(view *Database, items int) {
+		t.Helper()
+		for i := 0; i < items; i++ {
+			have, err := view.Get([]byte(fmt.Sprintf("key-%05d", i)))
+			if err != nil || !bytes.Equal(have, bytes.Repeat([]byte{byte(i)}, 1024)) {
+				t.Fatalf("Wrong item %d in view: %v", i, err)
+			}
+		}
+		if has, _ := view.Has([]byte(fmt.Sprintf("key-%05d", items))); has {
+			t.Fatal("View contains later item")
+		}
+	}
+	// Write enough data to have some of it flushed into sstables, while the
+	// rest only resides in the write-ahead log.
+	write(0, 10000)
+	cache := NewSharedCache(16, 16)
+	defer cache.Close()
+
+	view, err := NewShared(path, filepath.Join(dir, "links"), cache, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer view.Close()
+	if err := view.Put([]byte("key"), nil); err == nil {
+		t.Fatal("Wrote into shared database")
+	}
+	// Delete the data and compact it away before reading through the view,
+	// which must be unaffected.
+	write(10000, 20000)
+	for i := 0; i < 10000; i++ {
+		if err := db.Delete([]byte(fmt.Sprintf("key-%05d", i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Compact(nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	check(view, 10000)
+
+	// A new view observes the changes.
+	next, err := NewShared(path, filepath.Join(dir, "links2"), cache, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer next.Close()
+	if has, _ := next.Has([]byte("key-00000")); has {
+		t.Fatal("New view contains deleted item")
+	}
+	if has, _ := next.Has([]byte("key-19999")); !has {
+		t.Fatal("New view misses item")
+	}
+}
@@ -27,6 +27,7 @@ import (
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/hdwallet"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
 	"github.com/ethereum/go-ethereum/accounts/scwallet"
 	"github.com/ethereum/go-ethereum/common"
@@ -403,6 +404,37 @@ func (s *PersonalAccountAPI) ImportRawKey(privkey string, password string) (comm
 	return acc.Address, err
 }
 
+// ImportMnemonic stores the given BIP-39 mnemonic and optional passphrase into
+// a new HD wallet in the key directory, encrypting it with the password.
+func (s *PersonalAccountAPI) ImportMnemonic(mnemonic string, passphrase *string, password string) (rawWallet, error) {
+	hub, err := fetchHub(s.am)
+	if err != nil {
+		return rawWallet{}, err
+	}
+	var salt string
+	if passphrase != nil {
+		salt = *passphrase
+	}
+	wallet, err := hub.Import(mnemonic, salt, password)
+	if err != nil {
+		return rawWallet{}, err
+	}
+	status, _ := wallet.Status()
+	return rawWallet{
+		URL:      wallet.URL().String(),
+		Status:   status,
+		Accounts: wallet.Accounts(),
+	}, nil
+}
+
+// fetchHub retrieves the mnemonic wallet hub from the account manager.
+func fetchHub(am *accounts.Manager) (*hdwallet.Hub, error) {
+	if hubs := am.Backends(hdwallet.HubType); len(hubs) > 0 {
+		return hubs[0].(*hdwallet.Hub), nil
+	}
+	return nil, errors.New("mnemonic wallets not used")
+}
+
 // UnlockAccount will unlock the account associated with the given address with
 // the given password for duration seconds. If duration is nil it will use a
 // default of 300 seconds. It returns an indication if the account was unlocked.
@@ -719,16 +751,7 @@ func (s *BlockChainAPI) GetProof(ctx context.Context, address common.Address, st
 		}
 		// Create the proofs for the storageKeys.
 		for i, key := range keys {
-			// Output key encoding is a bit special: if the input was a 32-byte hash, it is
-			// returned as such. Otherwise, we apply the QUANTITY encoding mandated by the
-			// JSON-RPC spec for getProof. This behavior exists to preserve backwards
-			// compatibility with older client versions.
-			var outputKey string
-			if keyLengths[i] != 32 {
-				outputKey = hexutil.EncodeBig(key.Big())
-			} else {
-				outputKey = hexutil.Encode(key[:])
-			}
+			outputKey := encodeProofKey(key, keyLengths[i])
 			if storageTrie == nil {
 				storageProof[i] = StorageResult{outputKey, &hexutil.Big{}, []string{}}
 				continue
@@ -762,6 +785,120 @@ func (s *BlockChainAPI) GetProof(ctx context.Context, address common.Address, st
 	}, statedb.Error()
 }
 
+// MultiProofRequest selects an account and its storage slots to prove.
+type MultiProofRequest struct {
+	Address     common.Address `json:"address"`
+	StorageKeys []string       `json:"storageKeys"`
+}
+
+// MultiProofResult is the result of GetMultiProof. Each proof contains the nodes
+// of a trie on the paths to all the requested keys, each node only once.
+type MultiProofResult struct {
+	AccountProof []string             `json:"accountProof"`
+	Accounts     []MultiAccountResult `json:"accounts"`
+}
+
+type MultiAccountResult struct {
+	Address      common.Address       `json:"address"`
+	Balance      *hexutil.Big         `json:"balance"`
+	CodeHash     common.Hash          `json:"codeHash"`
+	Nonce        hexutil.Uint64       `json:"nonce"`
+	StorageHash  common.Hash          `json:"storageHash"`
+	StorageProof []string             `json:"storageProof"`
+	Storage      []MultiStorageResult `json:"storage"`
+}
+
+type MultiStorageResult struct {
+	Key   string       `json:"key"`
+	Value *hexutil.Big `json:"value"`
+}
+
+// GetMultiProof returns the values of multiple accounts and their storage slots
+// along with a Merkle-multiproof of the account trie and of each storage trie,
+// instead of an individual proof per account and slot.
+func (s *BlockChainAPI) GetMultiProof(ctx context.Context, requests []MultiProofRequest, blockNrOrHash rpc.BlockNumberOrHash) (*MultiProofResult, error) {
+	var (
+		keys       = make([][]common.Hash, len(requests))
+		keyLengths = make([][]int, len(requests))
+	)
+	// Deserialize all keys. This prevents state access on invalid input.
+	for i, req := range requests {
+		keys[i] = make([]common.Hash, len(req.StorageKeys))
+		keyLengths[i] = make([]int, len(req.StorageKeys))
+		for j, hexKey := range req.StorageKeys {
+			var err error
+			keys[i][j], keyLengths[i][j], err = decodeHash(hexKey)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	statedb, header, err := s.b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
+	if statedb == nil || err != nil {
+		return nil, err
+	}
+	accounts := make([]MultiAccountResult, len(requests))
+	for i, req := range requests {
+		storageRoot := statedb.GetStorageRoot(req.Address)
+		storage := make([]MultiStorageResult, len(keys[i]))
+		storageProof := proofList{}
+
+		hashes := make([][]byte, len(keys[i]))
+		for j, key := range keys[i] {
+			hashes[j] = crypto.Keccak256(key.Bytes())
+			value := (*hexutil.Big)(statedb.GetState(req.Address, key).Big())
+			storage[j] = MultiStorageResult{encodeProofKey(key, keyLengths[i][j]), value}
+		}
+		if len(keys[i]) > 0 && storageRoot != types.EmptyRootHash && storageRoot != (common.Hash{}) {
+			id := trie.StorageTrieID(header.Root, crypto.Keccak256Hash(req.Address.Bytes()), storageRoot)
+			st, err := trie.NewStateTrie(id, statedb.Database().TrieDB())
+			if err != nil {
+				return nil, err
+			}
+			if err := st.ProveMulti(hashes, &storageProof); err != nil {
+				return nil, err
+			}
+		}
+		accounts[i] = MultiAccountResult{
+			Address:      req.Address,
+			Balance:      (*hexutil.Big)(statedb.GetBalance(req.Address).ToBig()),
+			CodeHash:     statedb.GetCodeHash(req.Address),
+			Nonce:        hexutil.Uint64(statedb.GetNonce(req.Address)),
+			StorageHash:  storageRoot,
+			StorageProof: storageProof,
+			Storage:      storage,
+		}
+	}
+	// Create the accountProof.
+	tr, err := trie.NewStateTrie(trie.StateTrieID(header.Root), statedb.Database().TrieDB())
+	if err != nil {
+		return nil, err
+	}
+	hashes := make([][]byte, len(requests))
+	for i, req := range requests {
+		hashes[i] = crypto.Keccak256(req.Address.Bytes())
+	}
+	accountProof := proofList{}
+	if err := tr.ProveMulti(hashes, &accountProof); err != nil {
+		return nil, err
+	}
+	return &MultiProofResult{
+		AccountProof: accountProof,
+		Accounts:     accounts,
+	}, statedb.Error()
+}
+
+// encodeProofKey encodes a storage key of a proof. Output key encoding is a bit
+// special: if the input was a 32-byte hash, it is returned as such. Otherwise, we
+// apply the QUANTITY encoding mandated by the JSON-RPC spec for getProof. This
+// behavior exists to preserve backwards compatibility with older client versions.
+func encodeProofKey(key common.Hash, inputLength int) string {
+	if inputLength != 32 {
+		return hexutil.EncodeBig(key.Big())
+	}
+	return hexutil.Encode(key[:])
+}
+
 // decodeHash parses a hex-encoded 32-byte hash. The input may optionally
 // be prefixed by 0x and can have a byte length up to 32.
 func decodeHash(s string) (h common.Hash, inputLength int, err error) {
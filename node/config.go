@@ -81,6 +81,13 @@ type Config struct {
 	// ExternalSigner specifies an external URI for a clef-type signer.
 	ExternalSigner string `toml:",omitempty"`
 
+	// Web3Signer specifies the URL of a remote signer speaking the Web3Signer
+	// HTTP signing protocol, and the TLS files to connect to it with.
+	Web3Signer        string `toml:",omitempty"`
+	Web3SignerTLSCert string `toml:",omitempty"`
+	Web3SignerTLSKey  string `toml:",omitempty"`
+	Web3SignerTLSCA   string `toml:",omitempty"`
+
 	// UseLightweightKDF lowers the memory and CPU requirements of the key store
 	// scrypt KDF at the expense of security.
 	UseLightweightKDF bool `toml:",omitempty"`
@@ -213,6 +220,10 @@ type Config struct {
 	EnablePersonal bool `toml:"-"`
 
 	DBEngine string `toml:",omitempty"`
+
+	// FreezerChecksums enables checksums of the items in newly created
+	// ancient stores.
+	FreezerChecksums bool `toml:",omitempty"`
 }
 
 // IPCEndpoint resolves an IPC endpoint based on a configured value, taking into
@@ -771,6 +771,7 @@ func (n *Node) OpenDatabaseWithFreezer(name string, cache, handles int, ancient
 			Cache:             cache,
 			Handles:           handles,
 			ReadOnly:          readonly,
+			Checksums:         n.config.FreezerChecksums,
 		})
 	}
 
@@ -811,6 +812,15 @@ func (db *closeTrackingDB) Close() error {
 	return db.Database.Close()
 }
 
+// Checkpoint creates a consistent copy of the wrapped database, if supported.
+func (db *closeTrackingDB) Checkpoint(dir string) error {
+	cp, ok := db.Database.(ethdb.Checkpointer)
+	if !ok {
+		return errors.New("database does not support checkpoints")
+	}
+	return cp.Checkpoint(dir)
+}
+
 // wrapDatabase ensures the database will be auto-closed when Node is closed.
 func (n *Node) wrapDatabase(db ethdb.Database) ethdb.Database {
 	wrapper := &closeTrackingDB{db, n}
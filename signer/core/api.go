@@ -27,6 +27,7 @@ import (
 	"reflect"
 
 	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/hdwallet"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
 	"github.com/ethereum/go-ethereum/accounts/scwallet"
 	"github.com/ethereum/go-ethereum/accounts/usbwallet"
@@ -46,7 +47,7 @@ const (
 	// ExternalAPIVersion -- see extapi_changelog.md
 	ExternalAPIVersion = "6.1.0"
 	// InternalAPIVersion -- see intapi_changelog.md
-	InternalAPIVersion = "7.0.1"
+	InternalAPIVersion = "7.1.0"
 )
 
 // ExternalAPI defines the external API through which signing requests are made.
@@ -141,6 +142,13 @@ func StartClefAccountManager(ksLocation string, nousb, lightKDF bool, scpath str
 	// support password based accounts
 	if len(ksLocation) > 0 {
 		backends = append(backends, keystore.NewKeyStore(ksLocation, n, p))
+
+		// support mnemonic based accounts stored alongside
+		if hdhub, err := hdwallet.NewHub(ksLocation, n, p); err != nil {
+			log.Warn(fmt.Sprintf("Failed to start mnemonic wallet hub, disabling: %v", err))
+		} else {
+			backends = append(backends, hdhub)
+		}
 	}
 	if !nousb {
 		// Start a USB hub for Ledger hardware wallets
@@ -242,6 +250,7 @@ type (
 		Messages    []*apitypes.NameValueType `json:"messages"`
 		Callinfo    []apitypes.ValidationInfo `json:"call_info"`
 		Hash        hexutil.Bytes             `json:"hash"`
+		TypedData   *apitypes.TypedData       `json:"typed_data,omitempty"`
 		Meta        Metadata                  `json:"meta"`
 	}
 	SignDataResponse struct {
@@ -36,7 +36,10 @@ import (
 	"github.com/ethereum/go-ethereum/crypto"
 )
 
-var typedDataReferenceTypeRegexp = regexp.MustCompile(`^[A-Za-z](\w*)(\[\])?$`)
+var (
+	typedDataReferenceTypeRegexp = regexp.MustCompile(`^[A-Za-z](\w*)$`)
+	typedDataArraySuffixRegexp   = regexp.MustCompile(`^(\[([1-9]\d*)?\])*$`)
+)
 
 type ValidationInfo struct {
 	Typ     string `json:"type"`
@@ -210,16 +213,39 @@ type Type struct {
 }
 
 func (t *Type) isArray() bool {
-	return strings.HasSuffix(t.Type, "[]")
+	return strings.HasSuffix(t.Type, "]")
 }
 
-// typeName returns the canonical name of the type. If the type is 'Person[]', then
-// this method returns 'Person'
+// typeName returns the canonical name of the type. If the type is 'Person[]' or
+// 'Person[2][]', then this method returns 'Person'
 func (t *Type) typeName() string {
-	if strings.HasSuffix(t.Type, "[]") {
-		return strings.TrimSuffix(t.Type, "[]")
+	return baseTypeName(t.Type)
+}
+
+// baseTypeName strips all array dimensions from a type name.
+func baseTypeName(typ string) string {
+	if i := strings.IndexByte(typ, '['); i >= 0 {
+		return typ[:i]
+	}
+	return typ
+}
+
+// parseArrayType splits an array type into the type of its elements and its
+// length, which is -1 for dynamically sized arrays. The outermost dimension of
+// multidimensional arrays is the last one, i.e. 'uint8[2][]' is a dynamic array
+// of 'uint8[2]' elements.
+func parseArrayType(typ string) (elemType string, length int, err error) {
+	i := strings.LastIndexByte(typ, '[')
+	if i < 0 || !strings.HasSuffix(typ, "]") {
+		return "", 0, fmt.Errorf("type '%s' is not an array", typ)
 	}
-	return t.Type
+	length = -1
+	if size := typ[i+1 : len(typ)-1]; size != "" {
+		if length, err = strconv.Atoi(size); err != nil || length <= 0 {
+			return "", 0, fmt.Errorf("invalid array size in type '%s'", typ)
+		}
+	}
+	return typ[:i], length, nil
 }
 
 type Types map[string][]Type
@@ -238,6 +264,130 @@ type TypedDataDomain struct {
 	ChainId           *math.HexOrDecimal256 `json:"chainId"`
 	VerifyingContract string                `json:"verifyingContract"`
 	Salt              string                `json:"salt"`
+
+	// Extensions are the domain fields not defined by EIP-712 itself, e.g. the
+	// ones added by the extensions listed by EIP-5267.
+	Extensions map[string]interface{} `json:"-"`
+}
+
+// EIP-5267 bits of the domain fields set, as returned by eip712Domain().
+const (
+	DomainFieldName              = 1 << iota // The domain has a name
+	DomainFieldVersion                       // The domain has a version
+	DomainFieldChainId                       // The domain has a chain id
+	DomainFieldVerifyingContract             // The domain has a verifying contract
+	DomainFieldSalt                          // The domain has a salt
+)
+
+// domainFieldNames are the names of the domain fields defined by EIP-712, in the
+// order of the EIP-5267 field bits.
+var domainFieldNames = []string{"name", "version", "chainId", "verifyingContract", "salt"}
+
+// domainFieldTypes are the types of the domain fields defined by EIP-712.
+var domainFieldTypes = []string{"string", "string", "uint256", "address", "bytes32"}
+
+// NewEIP5267Domain creates the domain of a contract from the values returned by
+// its EIP-5267 eip712Domain() method, only setting the fields flagged as used.
+// Domains using extensions are rejected, as their fields can't be retrieved
+// without knowing the extensions.
+func NewEIP5267Domain(fields byte, name, version string, chainId *big.Int, verifyingContract common.Address, salt common.Hash, extensions []*big.Int) (*TypedDataDomain, error) {
+	if len(extensions) > 0 {
+		return nil, fmt.Errorf("unsupported domain extensions %v", extensions)
+	}
+	if fields>>len(domainFieldNames) != 0 {
+		return nil, fmt.Errorf("unknown domain fields %#x", fields)
+	}
+	domain := new(TypedDataDomain)
+	if fields&DomainFieldName != 0 {
+		domain.Name = name
+	}
+	if fields&DomainFieldVersion != 0 {
+		domain.Version = version
+	}
+	if fields&DomainFieldChainId != 0 {
+		if chainId == nil {
+			return nil, errors.New("missing domain chain id")
+		}
+		domain.ChainId = (*math.HexOrDecimal256)(new(big.Int).Set(chainId))
+	}
+	if fields&DomainFieldVerifyingContract != 0 {
+		domain.VerifyingContract = verifyingContract.Hex()
+	}
+	if fields&DomainFieldSalt != 0 {
+		domain.Salt = salt.Hex()
+	}
+	return domain, nil
+}
+
+// UnmarshalJSON parses a domain, keeping the fields not defined by EIP-712 as
+// extensions.
+func (domain *TypedDataDomain) UnmarshalJSON(input []byte) error {
+	type plainDomain TypedDataDomain
+	var dec plainDomain
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(input, &fields); err != nil {
+		return err
+	}
+	for _, name := range domainFieldNames {
+		delete(fields, name)
+	}
+	if len(fields) > 0 {
+		dec.Extensions = fields
+	}
+	*domain = TypedDataDomain(dec)
+	return nil
+}
+
+// MarshalJSON encodes a domain along with its extension fields.
+func (domain TypedDataDomain) MarshalJSON() ([]byte, error) {
+	type plainDomain TypedDataDomain
+	enc, err := json.Marshal(plainDomain(domain))
+	if err != nil || len(domain.Extensions) == 0 {
+		return enc, err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(enc, &fields); err != nil {
+		return nil, err
+	}
+	for name, value := range domain.Extensions {
+		if _, ok := fields[name]; !ok {
+			fields[name] = value
+		}
+	}
+	return json.Marshal(fields)
+}
+
+// Fields returns the EIP-5267 bitmap of the domain fields which are set.
+func (domain *TypedDataDomain) Fields() byte {
+	var (
+		fields byte
+		values = domain.Map()
+	)
+	for i, name := range domainFieldNames {
+		if _, ok := values[name]; ok {
+			fields |= 1 << i
+		}
+	}
+	return fields
+}
+
+// Types returns the EIP712Domain type of the domain, consisting of the fields
+// defined by EIP-712 which are set, in their canonical order. Extension fields
+// need to be typed by the caller.
+func (domain *TypedDataDomain) Types() []Type {
+	var (
+		fields = domain.Fields()
+		types  []Type
+	)
+	for i, name := range domainFieldNames {
+		if fields&(1<<i) != 0 {
+			types = append(types, Type{Name: name, Type: domainFieldTypes[i]})
+		}
+	}
+	return types
 }
 
 // TypedDataAndHash is a helper function that calculates a hash for typed data conforming to EIP-712.
@@ -270,7 +420,7 @@ func (typedData *TypedData) HashStruct(primaryType string, data TypedDataMessage
 
 // Dependencies returns an array of custom types ordered by their hierarchical reference tree
 func (typedData *TypedData) Dependencies(primaryType string, found []string) []string {
-	primaryType = strings.TrimSuffix(primaryType, "[]")
+	primaryType = baseTypeName(primaryType)
 	includes := func(arr []string, str string) bool {
 		for _, obj := range arr {
 			if obj == str {
@@ -353,56 +503,53 @@ func (typedData *TypedData) EncodeData(primaryType string, data map[string]inter
 
 	// Add field contents. Structs and arrays have special handlers.
 	for _, field := range typedData.Types[primaryType] {
-		encType := field.Type
-		encValue := data[field.Name]
-		if encType[len(encType)-1:] == "]" {
-			arrayValue, err := convertDataToSlice(encValue)
-			if err != nil {
-				return nil, dataMismatchError(encType, encValue)
-			}
-
-			arrayBuffer := bytes.Buffer{}
-			parsedType := strings.Split(encType, "[")[0]
-			for _, item := range arrayValue {
-				if typedData.Types[parsedType] != nil {
-					mapValue, ok := item.(map[string]interface{})
-					if !ok {
-						return nil, dataMismatchError(parsedType, item)
-					}
-					encodedData, err := typedData.EncodeData(parsedType, mapValue, depth+1)
-					if err != nil {
-						return nil, err
-					}
-					arrayBuffer.Write(crypto.Keccak256(encodedData))
-				} else {
-					bytesValue, err := typedData.EncodePrimitiveValue(parsedType, item, depth)
-					if err != nil {
-						return nil, err
-					}
-					arrayBuffer.Write(bytesValue)
-				}
-			}
+		encodedValue, err := typedData.encodeValue(field.Type, data[field.Name], depth)
+		if err != nil {
+			return nil, err
+		}
+		buffer.Write(encodedValue)
+	}
+	return buffer.Bytes(), nil
+}
 
-			buffer.Write(crypto.Keccak256(arrayBuffer.Bytes()))
-		} else if typedData.Types[field.Type] != nil {
-			mapValue, ok := encValue.(map[string]interface{})
-			if !ok {
-				return nil, dataMismatchError(encType, encValue)
-			}
-			encodedData, err := typedData.EncodeData(field.Type, mapValue, depth+1)
-			if err != nil {
-				return nil, err
-			}
-			buffer.Write(crypto.Keccak256(encodedData))
-		} else {
-			byteValue, err := typedData.EncodePrimitiveValue(encType, encValue, depth)
+// encodeValue encodes a member of a struct. Structs are encoded as their hash,
+// arrays as the hash of the concatenated encodings of their elements, which may
+// be arrays or structs themselves.
+func (typedData *TypedData) encodeValue(encType string, encValue interface{}, depth int) ([]byte, error) {
+	if strings.HasSuffix(encType, "]") {
+		elemType, length, err := parseArrayType(encType)
+		if err != nil {
+			return nil, err
+		}
+		arrayValue, err := convertDataToSlice(encValue)
+		if err != nil {
+			return nil, dataMismatchError(encType, encValue)
+		}
+		if length >= 0 && len(arrayValue) != length {
+			return nil, fmt.Errorf("provided array of length %d doesn't match type '%s'", len(arrayValue), encType)
+		}
+		arrayBuffer := bytes.Buffer{}
+		for _, item := range arrayValue {
+			encodedItem, err := typedData.encodeValue(elemType, item, depth+1)
 			if err != nil {
 				return nil, err
 			}
-			buffer.Write(byteValue)
+			arrayBuffer.Write(encodedItem)
 		}
+		return crypto.Keccak256(arrayBuffer.Bytes()), nil
 	}
-	return buffer.Bytes(), nil
+	if typedData.Types[encType] != nil {
+		mapValue, ok := encValue.(map[string]interface{})
+		if !ok {
+			return nil, dataMismatchError(encType, encValue)
+		}
+		encodedData, err := typedData.EncodeData(encType, mapValue, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		return crypto.Keccak256(encodedData), nil
+	}
+	return typedData.EncodePrimitiveValue(encType, encValue, depth)
 }
 
 // Attempt to parse bytes in different formats: byte array, hex string, hexutil.Bytes.
@@ -627,51 +774,50 @@ func (typedData *TypedData) formatData(primaryType string, data map[string]inter
 
 	// Add field contents. Structs and arrays have special handlers.
 	for _, field := range typedData.Types[primaryType] {
-		encName := field.Name
-		encValue := data[encName]
-		item := &NameValueType{
-			Name: encName,
-			Typ:  field.Type,
-		}
-		if field.isArray() {
-			arrayValue, _ := convertDataToSlice(encValue)
-			parsedType := field.typeName()
-			for _, v := range arrayValue {
-				if typedData.Types[parsedType] != nil {
-					mapValue, _ := v.(map[string]interface{})
-					mapOutput, err := typedData.formatData(parsedType, mapValue)
-					if err != nil {
-						return nil, err
-					}
-					item.Value = mapOutput
-				} else {
-					primitiveOutput, err := formatPrimitiveValue(field.Type, encValue)
-					if err != nil {
-						return nil, err
-					}
-					item.Value = primitiveOutput
-				}
-			}
-		} else if typedData.Types[field.Type] != nil {
-			if mapValue, ok := encValue.(map[string]interface{}); ok {
-				mapOutput, err := typedData.formatData(field.Type, mapValue)
-				if err != nil {
-					return nil, err
-				}
-				item.Value = mapOutput
-			} else {
-				item.Value = "<nil>"
-			}
-		} else {
-			primitiveOutput, err := formatPrimitiveValue(field.Type, encValue)
+		value, err := typedData.formatValue(field.Type, data[field.Name])
+		if err != nil {
+			return nil, err
+		}
+		output = append(output, &NameValueType{
+			Name:  field.Name,
+			Value: value,
+			Typ:   field.Type,
+		})
+	}
+	return output, nil
+}
+
+// formatValue formats a member of a struct. Structs and arrays are formatted as
+// a list of their members and elements, which may be structs or arrays themselves.
+func (typedData *TypedData) formatValue(encType string, encValue interface{}) (interface{}, error) {
+	if strings.HasSuffix(encType, "]") {
+		elemType, _, err := parseArrayType(encType)
+		if err != nil {
+			return nil, err
+		}
+		arrayValue, _ := convertDataToSlice(encValue)
+		output := make([]*NameValueType, 0, len(arrayValue))
+		for i, item := range arrayValue {
+			value, err := typedData.formatValue(elemType, item)
 			if err != nil {
 				return nil, err
 			}
-			item.Value = primitiveOutput
+			output = append(output, &NameValueType{
+				Name:  fmt.Sprintf("[%d]", i),
+				Value: value,
+				Typ:   elemType,
+			})
 		}
-		output = append(output, item)
+		return output, nil
 	}
-	return output, nil
+	if typedData.Types[encType] != nil {
+		mapValue, ok := encValue.(map[string]interface{})
+		if !ok {
+			return "<nil>", nil
+		}
+		return typedData.formatData(encType, mapValue)
+	}
+	return formatPrimitiveValue(encType, encValue)
 }
 
 func formatPrimitiveValue(encType string, encValue interface{}) (string, error) {
@@ -688,10 +834,13 @@ func formatPrimitiveValue(encType string, encValue interface{}) (string, error)
 		} else {
 			return fmt.Sprintf("%t", boolValue), nil
 		}
-	case "bytes", "string":
+	case "string":
 		return fmt.Sprintf("%s", encValue), nil
 	}
 	if strings.HasPrefix(encType, "bytes") {
+		if bytesValue, ok := parseBytes(encValue); ok {
+			return hexutil.Encode(bytesValue), nil
+		}
 		return fmt.Sprintf("%s", encValue), nil
 	}
 	if strings.HasPrefix(encType, "uint") || strings.HasPrefix(encType, "int") {
@@ -720,14 +869,18 @@ func (t Types) validate() error {
 			if typeKey == typeObj.Type {
 				return fmt.Errorf("type %q cannot reference itself", typeObj.Type)
 			}
-			if isPrimitiveTypeValid(typeObj.Type) {
+			typeName := typeObj.typeName()
+			if !typedDataArraySuffixRegexp.MatchString(typeObj.Type[len(typeName):]) {
+				return fmt.Errorf("invalid array type %q", typeObj.Type)
+			}
+			if isPrimitiveTypeValid(typeName) {
 				continue
 			}
 			// Must be reference type
-			if _, exist := t[typeObj.typeName()]; !exist {
+			if _, exist := t[typeName]; !exist {
 				return fmt.Errorf("reference type %q is undefined", typeObj.Type)
 			}
-			if !typedDataReferenceTypeRegexp.MatchString(typeObj.Type) {
+			if !typedDataReferenceTypeRegexp.MatchString(typeName) {
 				return fmt.Errorf("unknown reference type %q", typeObj.Type)
 			}
 		}
@@ -773,7 +926,7 @@ func isPrimitiveTypeValid(primitiveType string) bool {
 // validate checks if the given domain is valid, i.e. contains at least
 // the minimum viable keys and values
 func (domain *TypedDataDomain) validate() error {
-	if domain.ChainId == nil && len(domain.Name) == 0 && len(domain.Version) == 0 && len(domain.VerifyingContract) == 0 && len(domain.Salt) == 0 {
+	if domain.ChainId == nil && len(domain.Name) == 0 && len(domain.Version) == 0 && len(domain.VerifyingContract) == 0 && len(domain.Salt) == 0 && len(domain.Extensions) == 0 {
 		return errors.New("domain is undefined")
 	}
 
@@ -803,6 +956,12 @@ func (domain *TypedDataDomain) Map() map[string]interface{} {
 	if len(domain.Salt) > 0 {
 		dataMap["salt"] = domain.Salt
 	}
+
+	for name, value := range domain.Extensions {
+		if _, ok := dataMap[name]; !ok {
+			dataMap[name] = value
+		}
+	}
 	return dataMap
 }
 
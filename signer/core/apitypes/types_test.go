@@ -16,7 +16,14 @@
 
 package apitypes
 
-import "testing"
+import (
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
 
 func TestIsPrimitive(t *testing.T) {
 	t.Parallel()
@@ -39,3 +46,91 @@ func TestIsPrimitive(t *testing.T) {
 		}
 	}
 }
+
+func TestParseArrayType(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		typ      string
+		elemType string
+		length   int
+		fail     bool
+	}{
+		{typ: "uint256[]", elemType: "uint256", length: -1},
+		{typ: "Person[3]", elemType: "Person", length: 3},
+		{typ: "bytes32[2][]", elemType: "bytes32[2]", length: -1},
+		{typ: "address[][4]", elemType: "address[]", length: 4},
+		{typ: "uint256", fail: true},
+		{typ: "uint256[0]", fail: true},
+		{typ: "uint256[-1]", fail: true},
+		{typ: "uint256[x]", fail: true},
+	}
+	for i, tt := range tests {
+		elemType, length, err := parseArrayType(tt.typ)
+		if tt.fail {
+			if err == nil {
+				t.Errorf("test %d: expected '%v' to not be a valid array", i, tt.typ)
+			}
+			continue
+		}
+		if err != nil || elemType != tt.elemType || length != tt.length {
+			t.Errorf("test %d: have %v/%d (%v), want %v/%d", i, elemType, length, err, tt.elemType, tt.length)
+		}
+	}
+}
+
+func TestDomainExtensions(t *testing.T) {
+	t.Parallel()
+	input := `{"name":"Governor","chainId":"0x1","deployment":"7","network":"mainnet"}`
+
+	var domain TypedDataDomain
+	if err := json.Unmarshal([]byte(input), &domain); err != nil {
+		t.Fatalf("unmarshalling failed: %v", err)
+	}
+	want := map[string]interface{}{"deployment": "7", "network": "mainnet"}
+	if !reflect.DeepEqual(domain.Extensions, want) {
+		t.Fatalf("extensions mismatch: have %v, want %v", domain.Extensions, want)
+	}
+	if m := domain.Map(); m["network"] != "mainnet" || m["name"] != "Governor" {
+		t.Errorf("domain map mismatch: %v", m)
+	}
+	blob, err := json.Marshal(domain)
+	if err != nil {
+		t.Fatalf("marshalling failed: %v", err)
+	}
+	var dec TypedDataDomain
+	if err := json.Unmarshal(blob, &dec); err != nil {
+		t.Fatalf("unmarshalling failed: %v", err)
+	}
+	if !reflect.DeepEqual(dec, domain) {
+		t.Errorf("roundtrip mismatch: have %+v, want %+v", dec, domain)
+	}
+}
+
+func TestEIP5267Domain(t *testing.T) {
+	t.Parallel()
+	var (
+		contract = common.HexToAddress("0x5FbDB2315678afecb367f032d93F642f64180aa3")
+		salt     = common.Hash{0x01}
+	)
+	// Name, chain id and verifying contract set (0x0d)
+	domain, err := NewEIP5267Domain(0x0d, "Governor", "ignored", big.NewInt(1), contract, salt, nil)
+	if err != nil {
+		t.Fatalf("failed to create domain: %v", err)
+	}
+	if domain.Version != "" || domain.Salt != "" || domain.VerifyingContract != contract.Hex() || (*big.Int)(domain.ChainId).Int64() != 1 {
+		t.Errorf("domain mismatch: %+v", domain)
+	}
+	if fields := domain.Fields(); fields != 0x0d {
+		t.Errorf("fields mismatch: have %#x, want 0x0d", fields)
+	}
+	want := []Type{{Name: "name", Type: "string"}, {Name: "chainId", Type: "uint256"}, {Name: "verifyingContract", Type: "address"}}
+	if types := domain.Types(); !reflect.DeepEqual(types, want) {
+		t.Errorf("types mismatch: have %v, want %v", types, want)
+	}
+	if _, err := NewEIP5267Domain(0x01, "Governor", "", nil, contract, salt, []*big.Int{big.NewInt(1)}); err == nil {
+		t.Error("domain with extensions accepted")
+	}
+	if _, err := NewEIP5267Domain(0x20, "", "", nil, contract, salt, nil); err == nil {
+		t.Error("domain with unknown fields accepted")
+	}
+}
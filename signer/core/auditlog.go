@@ -114,6 +114,11 @@ func (l *AuditLogger) Version(ctx context.Context) (string, error) {
 	return data, err
 }
 
+// Logger returns the logger the audit log is written with.
+func (l *AuditLogger) Logger() log.Logger {
+	return l.log
+}
+
 func NewAuditLogger(path string, api ExternalAPI) (*AuditLogger, error) {
 	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
 	if err != nil {
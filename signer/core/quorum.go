@@ -0,0 +1,376 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+var (
+	// ErrUnknownRequest is returned if a pending request is not known, e.g.
+	// because it has already been decided.
+	ErrUnknownRequest = errors.New("unknown pending request")
+
+	// ErrNotApprover is returned if an approval is signed by an unknown key.
+	ErrNotApprover = errors.New("approval not signed by an approver")
+
+	// ErrAlreadyVoted is returned if an approver votes twice on a request.
+	ErrAlreadyVoted = errors.New("approver already voted")
+)
+
+// Outcomes of the requests pending approval of a quorum.
+const (
+	QuorumApproved  = "approved"
+	QuorumRejected  = "rejected"
+	QuorumTimeout   = "timeout"
+	QuorumCancelled = "cancelled"
+)
+
+// Approver is a party whose approval counts towards the quorum of a QuorumUI.
+// Approvers are prompted through their own UI channel, and/or approve requests
+// by signing their approval message with their key.
+type Approver struct {
+	Name    string          // Name of the approver in the listings and audit log
+	UI      UIClientAPI     // UI channel to prompt the approver through, if any
+	Address *common.Address // Address of the key approvals are signed with, if any
+}
+
+// PendingRequest is a signing request awaiting the approval of a quorum.
+type PendingRequest struct {
+	ID         uint64      `json:"id"`
+	Kind       string      `json:"kind"`    // Kind of the request, transaction or data
+	Request    interface{} `json:"request"` // The SignTxRequest or SignDataRequest
+	Digest     common.Hash `json:"digest"`  // Unique digest of the request, for approval messages
+	Deadline   time.Time   `json:"deadline"`
+	Approvals  []string    `json:"approvals"`  // Names of the approvers who approved
+	Rejections []string    `json:"rejections"` // Names of the approvers who rejected
+}
+
+// ApprovalMessage returns the text an approver signs, as specified by
+// accounts.TextHash, to approve the request.
+func (r *PendingRequest) ApprovalMessage() string {
+	return fmt.Sprintf("Approve clef request %d with digest %x", r.ID, r.Digest)
+}
+
+// pendingRequest is the state of a request awaiting approval.
+type pendingRequest struct {
+	PendingRequest
+	votes   map[int]bool  // Votes of the approvers by index
+	outcome string        // Outcome of the request, empty while pending
+	done    chan struct{} // Closed when the outcome is decided
+}
+
+// QuorumUI provides an implementation of UIClientAPI which holds signing requests
+// pending until a quorum of M out of N approvers approves them, rejecting them if
+// that doesn't happen in time. Other requests are handled by the next UI, which is
+// also able to list and cancel the pending requests through the UIServerAPI.
+type QuorumUI struct {
+	next      UIClientAPI // The UI handling other requests
+	approvers []Approver
+	threshold int
+	timeout   time.Duration
+	audit     log.Logger // Logger to record the votes and outcomes in
+
+	pending map[uint64]*pendingRequest
+	nextID  uint64
+	lock    sync.Mutex
+}
+
+// NewQuorumUI creates a UI requiring threshold out of the given approvers to
+// approve each signing request within the timeout.
+func NewQuorumUI(next UIClientAPI, approvers []Approver, threshold int, timeout time.Duration) (*QuorumUI, error) {
+	if threshold < 1 || threshold > len(approvers) {
+		return nil, fmt.Errorf("invalid threshold %d of %d approvers", threshold, len(approvers))
+	}
+	if timeout <= 0 {
+		return nil, fmt.Errorf("invalid timeout %v", timeout)
+	}
+	names := make(map[string]struct{})
+	for i, approver := range approvers {
+		if approver.Name == "" {
+			return nil, fmt.Errorf("approver %d: missing name", i)
+		}
+		if _, ok := names[approver.Name]; ok {
+			return nil, fmt.Errorf("approver %d: duplicate name %q", i, approver.Name)
+		}
+		names[approver.Name] = struct{}{}
+
+		if approver.UI == nil && approver.Address == nil {
+			return nil, fmt.Errorf("approver %q: neither UI nor address set", approver.Name)
+		}
+	}
+	return &QuorumUI{
+		next:      next,
+		approvers: approvers,
+		threshold: threshold,
+		timeout:   timeout,
+		audit:     log.Root(),
+		pending:   make(map[uint64]*pendingRequest),
+	}, nil
+}
+
+// SetAuditLogger sets the logger the votes and outcomes are recorded in.
+func (q *QuorumUI) SetAuditLogger(logger log.Logger) {
+	q.audit = logger
+}
+
+// Pending returns the requests currently awaiting approval.
+func (q *QuorumUI) Pending() []PendingRequest {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	pending := make([]PendingRequest, 0, len(q.pending))
+	for _, req := range q.pending {
+		cpy := req.PendingRequest
+		cpy.Approvals = append([]string{}, req.Approvals...)
+		cpy.Rejections = append([]string{}, req.Rejections...)
+		pending = append(pending, cpy)
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].ID < pending[j].ID })
+	return pending
+}
+
+// Approve records the approval of a pending request, signed by an approver over
+// the request's approval message.
+func (q *QuorumUI) Approve(id uint64, signature []byte) error {
+	q.lock.Lock()
+	req, ok := q.pending[id]
+	q.lock.Unlock()
+	if !ok {
+		return ErrUnknownRequest
+	}
+	if len(signature) != crypto.SignatureLength {
+		return fmt.Errorf("signature must be %d bytes long", crypto.SignatureLength)
+	}
+	sig := common.CopyBytes(signature)
+	if sig[crypto.RecoveryIDOffset] == 27 || sig[crypto.RecoveryIDOffset] == 28 {
+		sig[crypto.RecoveryIDOffset] -= 27 // Transform yellow paper V from 27/28 to 0/1
+	}
+	pubkey, err := crypto.SigToPub(accounts.TextHash([]byte(req.ApprovalMessage())), sig)
+	if err != nil {
+		return err
+	}
+	signer := crypto.PubkeyToAddress(*pubkey)
+	for i, approver := range q.approvers {
+		if approver.Address != nil && *approver.Address == signer {
+			return q.vote(req, i, true, "signature")
+		}
+	}
+	return ErrNotApprover
+}
+
+// Cancel rejects a pending request without waiting for the approvers.
+func (q *QuorumUI) Cancel(id uint64) error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	req, ok := q.pending[id]
+	if !ok {
+		return ErrUnknownRequest
+	}
+	q.decide(req, QuorumCancelled)
+	return nil
+}
+
+// await holds a request pending until its outcome is decided, prompting the
+// approvers having a UI channel. It returns whether the request was approved.
+func (q *QuorumUI) await(kind string, request interface{}, prompt func(ui UIClientAPI) (bool, error)) bool {
+	blob, err := json.Marshal(request)
+	if err != nil {
+		log.Warn("Failed marshalling request", "err", err)
+		return false
+	}
+	// Salt the digest, so approvals can never be replayed on other requests
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		log.Warn("Failed generating salt", "err", err)
+		return false
+	}
+	q.lock.Lock()
+	q.nextID++
+	req := &pendingRequest{
+		PendingRequest: PendingRequest{
+			ID:       q.nextID,
+			Kind:     kind,
+			Request:  request,
+			Digest:   crypto.Keccak256Hash(salt, blob),
+			Deadline: time.Now().Add(q.timeout),
+		},
+		votes: make(map[int]bool),
+		done:  make(chan struct{}),
+	}
+	q.pending[req.ID] = req
+	q.lock.Unlock()
+
+	q.audit.Info("Quorum", "type", "pending", "id", req.ID, "kind", kind, "digest", req.Digest,
+		"threshold", q.threshold, "approvers", len(q.approvers), "deadline", req.Deadline)
+
+	for i, approver := range q.approvers {
+		if approver.UI == nil {
+			continue
+		}
+		go func(i int, approver Approver) {
+			approved, err := prompt(approver.UI)
+			if err != nil {
+				log.Warn("Failed to prompt approver", "approver", approver.Name, "id", req.ID, "err", err)
+				return
+			}
+			if err := q.vote(req, i, approved, "ui"); err != nil {
+				log.Debug("Discarded approver vote", "approver", approver.Name, "id", req.ID, "err", err)
+			}
+		}(i, approver)
+	}
+	timer := time.NewTimer(q.timeout)
+	defer timer.Stop()
+
+	select {
+	case <-req.done:
+	case <-timer.C:
+		q.lock.Lock()
+		q.decide(req, QuorumTimeout)
+		q.lock.Unlock()
+	}
+	q.lock.Lock()
+	delete(q.pending, req.ID)
+	outcome := req.outcome
+	q.lock.Unlock()
+
+	q.audit.Info("Quorum", "type", "decision", "id", req.ID, "outcome", outcome,
+		"approvals", req.Approvals, "rejections", req.Rejections)
+	return outcome == QuorumApproved
+}
+
+// vote records the vote of an approver on a pending request, deciding it once
+// the quorum is reached or can't be reached anymore.
+func (q *QuorumUI) vote(req *pendingRequest, approver int, approved bool, channel string) error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if req.outcome != "" {
+		return ErrUnknownRequest
+	}
+	if _, ok := req.votes[approver]; ok {
+		return ErrAlreadyVoted
+	}
+	req.votes[approver] = approved
+
+	name := q.approvers[approver].Name
+	if approved {
+		req.Approvals = append(req.Approvals, name)
+	} else {
+		req.Rejections = append(req.Rejections, name)
+	}
+	q.audit.Info("Quorum", "type", "vote", "id", req.ID, "approver", name, "approved", approved, "channel", channel)
+
+	switch {
+	case len(req.Approvals) >= q.threshold:
+		q.decide(req, QuorumApproved)
+	case len(req.Rejections) > len(q.approvers)-q.threshold:
+		q.decide(req, QuorumRejected)
+	}
+	return nil
+}
+
+// decide sets the outcome of a pending request, unless already decided. The
+// lock must be held.
+func (q *QuorumUI) decide(req *pendingRequest, outcome string) {
+	if req.outcome == "" {
+		req.outcome = outcome
+		close(req.done)
+	}
+}
+
+func (q *QuorumUI) ApproveTx(request *SignTxRequest) (SignTxResponse, error) {
+	original, err := json.Marshal(request.Transaction)
+	if err != nil {
+		return SignTxResponse{Approved: false}, err
+	}
+	approved := q.await("transaction", request, func(ui UIClientAPI) (bool, error) {
+		// Each approver gets its own copy, and may not modify the transaction
+		req := *request
+		res, err := ui.ApproveTx(&req)
+		if err != nil || !res.Approved {
+			return false, err
+		}
+		modified, err := json.Marshal(res.Transaction)
+		if err != nil || !bytes.Equal(original, modified) {
+			return false, nil
+		}
+		return true, nil
+	})
+	if !approved {
+		return SignTxResponse{Approved: false}, nil
+	}
+	return SignTxResponse{Transaction: request.Transaction, Approved: true}, nil
+}
+
+func (q *QuorumUI) ApproveSignData(request *SignDataRequest) (SignDataResponse, error) {
+	approved := q.await("data", request, func(ui UIClientAPI) (bool, error) {
+		req := *request
+		res, err := ui.ApproveSignData(&req)
+		return res.Approved, err
+	})
+	return SignDataResponse{Approved: approved}, nil
+}
+
+func (q *QuorumUI) ApproveListing(request *ListRequest) (ListResponse, error) {
+	return q.next.ApproveListing(request)
+}
+
+func (q *QuorumUI) ApproveNewAccount(request *NewAccountRequest) (NewAccountResponse, error) {
+	return q.next.ApproveNewAccount(request)
+}
+
+func (q *QuorumUI) ShowError(message string) {
+	q.next.ShowError(message)
+}
+
+func (q *QuorumUI) ShowInfo(message string) {
+	q.next.ShowInfo(message)
+}
+
+func (q *QuorumUI) OnApprovedTx(tx ethapi.SignTransactionResult) {
+	q.next.OnApprovedTx(tx)
+}
+
+func (q *QuorumUI) OnSignerStartup(info StartupInfo) {
+	q.next.OnSignerStartup(info)
+}
+
+func (q *QuorumUI) OnInputRequired(info UserInputRequest) (UserInputResponse, error) {
+	return q.next.OnInputRequired(info)
+}
+
+// RegisterUIServer makes the pending requests available through the UIServerAPI
+// of the next UI. The approvers' UIs are not given access to it.
+func (q *QuorumUI) RegisterUIServer(api *UIServerAPI) {
+	api.quorum = q
+	q.next.RegisterUIServer(api)
+}
@@ -0,0 +1,193 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core_test
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core"
+)
+
+// approverUI is a UIClientAPI voting on the requests it's prompted with.
+type approverUI struct {
+	core.UIClientAPI
+	approve bool
+	modify  bool // Whether to modify the transactions it approves
+}
+
+func (ui *approverUI) ApproveTx(request *core.SignTxRequest) (core.SignTxResponse, error) {
+	tx := request.Transaction
+	if ui.modify {
+		tx.Value = hexutil.Big(*big.NewInt(1))
+	}
+	return core.SignTxResponse{Transaction: tx, Approved: ui.approve}, nil
+}
+
+func (ui *approverUI) ApproveSignData(request *core.SignDataRequest) (core.SignDataResponse, error) {
+	return core.SignDataResponse{Approved: ui.approve}, nil
+}
+
+func newTestQuorum(t *testing.T, approvers []core.Approver, threshold int, timeout time.Duration) *core.QuorumUI {
+	quorum, err := core.NewQuorumUI(&headlessUi{}, approvers, threshold, timeout)
+	if err != nil {
+		t.Fatalf("failed to create quorum: %v", err)
+	}
+	return quorum
+}
+
+func TestQuorumConfig(t *testing.T) {
+	addr := common.Address{1}
+	tests := []struct {
+		approvers []core.Approver
+		threshold int
+	}{
+		{[]core.Approver{{Name: "a", Address: &addr}}, 0},
+		{[]core.Approver{{Name: "a", Address: &addr}}, 2},
+		{[]core.Approver{{Address: &addr}}, 1},
+		{[]core.Approver{{Name: "a", Address: &addr}, {Name: "a", Address: &addr}}, 1},
+		{[]core.Approver{{Name: "a"}}, 1},
+	}
+	for i, tt := range tests {
+		if _, err := core.NewQuorumUI(&headlessUi{}, tt.approvers, tt.threshold, time.Minute); err == nil {
+			t.Errorf("test %d: invalid quorum accepted", i)
+		}
+	}
+}
+
+func TestQuorumUIApproval(t *testing.T) {
+	tx := &core.SignTxRequest{Transaction: mkTestTx(common.NewMixedcaseAddress(common.Address{1}))}
+	tests := []struct {
+		approvers []*approverUI
+		approved  bool
+	}{
+		{[]*approverUI{{approve: true}, {approve: false}, {approve: true}}, true},
+		{[]*approverUI{{approve: true}, {approve: false}, {approve: false}}, false},
+		{[]*approverUI{{approve: true}, {approve: true, modify: true}, {approve: false}}, false},
+	}
+	for i, tt := range tests {
+		approvers := make([]core.Approver, len(tt.approvers))
+		for j, ui := range tt.approvers {
+			approvers[j] = core.Approver{Name: string(rune('a' + j)), UI: ui}
+		}
+		quorum := newTestQuorum(t, approvers, 2, time.Minute)
+
+		res, err := quorum.ApproveTx(tx)
+		if err != nil {
+			t.Fatalf("test %d: approval failed: %v", i, err)
+		}
+		if res.Approved != tt.approved {
+			t.Errorf("test %d: approval mismatch: have %v, want %v", i, res.Approved, tt.approved)
+		}
+		if pending := quorum.Pending(); len(pending) != 0 {
+			t.Errorf("test %d: requests left pending: %v", i, pending)
+		}
+	}
+}
+
+func TestQuorumSignedApproval(t *testing.T) {
+	var (
+		keys      = make([]*ecdsa.PrivateKey, 3)
+		approvers = make([]core.Approver, 2)
+	)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+	}
+	for i := range approvers {
+		addr := crypto.PubkeyToAddress(keys[i].PublicKey)
+		approvers[i] = core.Approver{Name: string(rune('a' + i)), Address: &addr}
+	}
+	quorum := newTestQuorum(t, approvers, 2, time.Minute)
+
+	result := make(chan bool)
+	go func() {
+		res, _ := quorum.ApproveSignData(&core.SignDataRequest{ContentType: accounts.MimetypeTextPlain})
+		result <- res.Approved
+	}()
+	req := waitPending(t, quorum)
+	sign := func(key *ecdsa.PrivateKey) []byte {
+		sig, _ := crypto.Sign(accounts.TextHash([]byte(req.ApprovalMessage())), key)
+		sig[crypto.RecoveryIDOffset] += 27
+		return sig
+	}
+	if err := quorum.Approve(req.ID, sign(keys[2])); err != core.ErrNotApprover {
+		t.Errorf("outsider approval error mismatch: have %v, want %v", err, core.ErrNotApprover)
+	}
+	if err := quorum.Approve(req.ID+1, sign(keys[0])); err != core.ErrUnknownRequest {
+		t.Errorf("unknown request error mismatch: have %v, want %v", err, core.ErrUnknownRequest)
+	}
+	if err := quorum.Approve(req.ID, sign(keys[0])); err != nil {
+		t.Fatalf("approval failed: %v", err)
+	}
+	if err := quorum.Approve(req.ID, sign(keys[0])); err != core.ErrAlreadyVoted {
+		t.Errorf("repeated approval error mismatch: have %v, want %v", err, core.ErrAlreadyVoted)
+	}
+	if pending := quorum.Pending(); len(pending) != 1 || len(pending[0].Approvals) != 1 {
+		t.Fatalf("pending request mismatch: %v", pending)
+	}
+	if err := quorum.Approve(req.ID, sign(keys[1])); err != nil {
+		t.Fatalf("approval failed: %v", err)
+	}
+	if !<-result {
+		t.Error("request not approved by quorum")
+	}
+}
+
+func TestQuorumTimeoutAndCancel(t *testing.T) {
+	addr := common.Address{1}
+	quorum := newTestQuorum(t, []core.Approver{{Name: "a", Address: &addr}}, 1, 50*time.Millisecond)
+
+	if res, _ := quorum.ApproveSignData(&core.SignDataRequest{}); res.Approved {
+		t.Error("request approved after timeout")
+	}
+	quorum = newTestQuorum(t, []core.Approver{{Name: "a", Address: &addr}}, 1, time.Minute)
+
+	result := make(chan bool)
+	go func() {
+		res, _ := quorum.ApproveSignData(&core.SignDataRequest{})
+		result <- res.Approved
+	}()
+	req := waitPending(t, quorum)
+	if err := quorum.Cancel(req.ID); err != nil {
+		t.Fatalf("cancel failed: %v", err)
+	}
+	if <-result {
+		t.Error("cancelled request approved")
+	}
+	if err := quorum.Cancel(req.ID); !errors.Is(err, core.ErrUnknownRequest) {
+		t.Errorf("repeated cancel error mismatch: have %v, want %v", err, core.ErrUnknownRequest)
+	}
+}
+
+// waitPending waits for a request to become pending on the quorum.
+func waitPending(t *testing.T, quorum *core.QuorumUI) core.PendingRequest {
+	for i := 0; i < 100; i++ {
+		if pending := quorum.Pending(); len(pending) > 0 {
+			return pending[0]
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("no pending request")
+	return core.PendingRequest{}
+}
@@ -1014,3 +1014,95 @@ func TestComplexTypedDataWithLowercaseReftype(t *testing.T) {
 		t.Fatalf("Error, got %x, wanted %x", sighash, expSigHash)
 	}
 }
+
+// TestJsonFilesHashes checks the hashes of the typed data in the test files
+// against the ones computed by an independent EIP-712 implementation.
+func TestJsonFilesHashes(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		file     string
+		dataHash string
+		sigHash  string
+	}{
+		{"arrays-2.json", "0xf21d3aa19277079b95ab9e9343b3a721d96833c158090b283ccb2f965a3bc712", "0xfd7edc569534d5d5f106a2915ce9aa76b48f69e1a5e4ae0a92bfffb363f9553a"},
+		{"permit2-batch.json", "0x7f8aee60c4c7939077633a4aa86046668a364f30f2a3fc046e067ea80a49df91", "0xefac0d504399306e7057812db0ddf40c4b28de5a7afff1abd2ab824d8ac35e63"},
+		{"domain-salt.json", "0x3bbf3c0cf3e156101a704a41216d1d699e634687544174c2d59530c5504484ef", "0x7be6ac35dd8bac84231a5bbeaefaa0c41859933827744f845f2df42c58165a00"},
+		{"domain-extension.json", "0x7c3aa54fda83001e1acf9694767c19e9d982171b4f922e7232a35a3e9fc76397", "0x6c7061a8b69740717e3e87a970df632307fffb16231c57ea32e60656ed68edd8"},
+	}
+	for _, tt := range tests {
+		data, err := os.ReadFile(path.Join("testdata", tt.file))
+		if err != nil {
+			t.Fatalf("%v: failed to read file: %v", tt.file, err)
+		}
+		var typedData apitypes.TypedData
+		if err := json.Unmarshal(data, &typedData); err != nil {
+			t.Fatalf("%v: json unmarshalling failed: %v", tt.file, err)
+		}
+		dataHash, sigHash, err := sign(typedData)
+		if err != nil {
+			t.Fatalf("%v: hashing failed: %v", tt.file, err)
+		}
+		if have := hexutil.Encode(dataHash); have != tt.dataHash {
+			t.Errorf("%v: data hash mismatch: have %v, want %v", tt.file, have, tt.dataHash)
+		}
+		if have := hexutil.Encode(sigHash); have != tt.sigHash {
+			t.Errorf("%v: signing hash mismatch: have %v, want %v", tt.file, have, tt.sigHash)
+		}
+	}
+}
+
+func TestPermit2TypeHash(t *testing.T) {
+	t.Parallel()
+	data, err := os.ReadFile(path.Join("testdata", "permit2-batch.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var typedData apitypes.TypedData
+	if err := json.Unmarshal(data, &typedData); err != nil {
+		t.Fatal(err)
+	}
+	// Type hashes as defined by the Permit2 contract
+	if have, want := typedData.TypeHash("PermitBatch").String(), "0xaf1b0d30d2cab0380e68f0689007e3254993c596f2fdd0aaa7f4d04f79440863"; have != want {
+		t.Errorf("PermitBatch type hash mismatch: have %v, want %v", have, want)
+	}
+	if have, want := typedData.TypeHash("PermitDetails").String(), "0x65626cad6cb96493bf6f5ebea28756c966f023ab9e8a83a7101849d5573b3678"; have != want {
+		t.Errorf("PermitDetails type hash mismatch: have %v, want %v", have, want)
+	}
+}
+
+func TestFormatNestedArrays(t *testing.T) {
+	t.Parallel()
+	data, err := os.ReadFile(path.Join("testdata", "arrays-2.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var typedData apitypes.TypedData
+	if err := json.Unmarshal(data, &typedData); err != nil {
+		t.Fatal(err)
+	}
+	formatted, err := typedData.Format()
+	if err != nil {
+		t.Fatalf("formatting failed: %v", err)
+	}
+	// Look up the second item of the third pair: message.pairs[2][1].name
+	order := formatted[1].Value.([]*apitypes.NameValueType)
+	pairs := order[1].Value.([]*apitypes.NameValueType)
+	if len(pairs) != 3 {
+		t.Fatalf("pair count mismatch: have %d, want 3", len(pairs))
+	}
+	pair := pairs[2].Value.([]*apitypes.NameValueType)
+	if pair[1].Name != "[1]" || pair[1].Typ != "Item" {
+		t.Fatalf("pair item mismatch: have %s [%s]", pair[1].Name, pair[1].Typ)
+	}
+	if name := pair[1].Value.([]*apitypes.NameValueType)[0]; name.Value != "f" {
+		t.Errorf("item name mismatch: have %v, want f", name.Value)
+	}
+	// Primitive arrays are rendered element by element
+	grid := order[2].Value.([]*apitypes.NameValueType)
+	if row := grid[1].Value.([]*apitypes.NameValueType); row[0].Typ != "uint256" || row[0].Value != "3 (0x3)" {
+		t.Errorf("grid value mismatch: have %v [%s]", row[0].Value, row[0].Typ)
+	}
+	if pprint := formatted[1].Pprint(0); !strings.Contains(pprint, "[1] [Item]") {
+		t.Errorf("pretty printed nested array missing: %s", pprint)
+	}
+}
@@ -37,6 +37,16 @@ func NewStdIOUI() *StdIOUI {
 	return ui
 }
 
+// DialUI connects to an external UI listening on the given endpoint, speaking the
+// same ui_ protocol as UIs attached over stdio.
+func DialUI(endpoint string) (*StdIOUI, error) {
+	client, err := rpc.Dial(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return &StdIOUI{client: client}, nil
+}
+
 func (ui *StdIOUI) RegisterUIServer(api *UIServerAPI) {
 	ui.client.RegisterName("clef", api)
 }
@@ -25,8 +25,10 @@ import (
 	"os"
 
 	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/hdwallet"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/crypto"
 )
@@ -41,11 +43,12 @@ import (
 type UIServerAPI struct {
 	extApi *SignerAPI
 	am     *accounts.Manager
+	quorum *QuorumUI // Quorum of approvers signing requests are pending on, if any
 }
 
 // NewUIServerAPI creates a new UIServerAPI
 func NewUIServerAPI(extapi *SignerAPI) *UIServerAPI {
-	return &UIServerAPI{extapi, extapi.am}
+	return &UIServerAPI{extApi: extapi, am: extapi.am}
 }
 
 // List available accounts. As opposed to the external API definition, this method delivers
@@ -199,6 +202,27 @@ func (api *UIServerAPI) Import(ctx context.Context, keyJSON json.RawMessage, old
 	return be[0].(*keystore.KeyStore).Import(keyJSON, oldPassphrase, newPassphrase)
 }
 
+// ImportMnemonic stores a BIP-39 mnemonic and optional passphrase into a new HD
+// wallet in the keystore directory, encrypting it with the given password. It
+// returns the account at the default derivation path.
+// Example call (should fail on password too short)
+// {"jsonrpc":"2.0","method":"clef_importMnemonic","params":["abandon ... about", "", "test"], "id":6}
+func (api *UIServerAPI) ImportMnemonic(ctx context.Context, mnemonic, passphrase, password string) (accounts.Account, error) {
+	be := api.am.Backends(hdwallet.HubType)
+
+	if len(be) == 0 {
+		return accounts.Account{}, errors.New("mnemonic wallets not supported")
+	}
+	if err := ValidatePasswordFormat(password); err != nil {
+		return accounts.Account{}, fmt.Errorf("password requirements not met: %v", err)
+	}
+	wallet, err := be[0].(*hdwallet.Hub).Import(mnemonic, passphrase, password)
+	if err != nil {
+		return accounts.Account{}, err
+	}
+	return wallet.Accounts()[0], nil
+}
+
 // New creates a new password protected Account. The private key is protected with
 // the given password. Users are responsible to backup the private key that is stored
 // in the keystore location that was specified when this API was created.
@@ -209,6 +233,43 @@ func (api *UIServerAPI) New(ctx context.Context) (common.Address, error) {
 	return api.extApi.newAccount()
 }
 
+// errNoQuorum is returned by the pending request methods if quorum approval is
+// not enabled.
+var errNoQuorum = errors.New("quorum approval not enabled")
+
+// ListPendingRequests returns the signing requests awaiting the approval of the
+// quorum of approvers.
+// Example call
+// {"jsonrpc":"2.0","method":"clef_listPendingRequests","params":[], "id":7}
+func (api *UIServerAPI) ListPendingRequests() ([]PendingRequest, error) {
+	if api.quorum == nil {
+		return nil, errNoQuorum
+	}
+	return api.quorum.Pending(), nil
+}
+
+// ApprovePendingRequest submits the approval of a pending request, signed by an
+// approver over the approval message of the request.
+// Example call
+// {"jsonrpc":"2.0","method":"clef_approvePendingRequest","params":[1, "0x..."], "id":8}
+func (api *UIServerAPI) ApprovePendingRequest(id uint64, signature hexutil.Bytes) error {
+	if api.quorum == nil {
+		return errNoQuorum
+	}
+	return api.quorum.Approve(id, signature)
+}
+
+// CancelPendingRequest rejects a pending request without waiting for the
+// approvers.
+// Example call
+// {"jsonrpc":"2.0","method":"clef_cancelPendingRequest","params":[1], "id":9}
+func (api *UIServerAPI) CancelPendingRequest(id uint64) error {
+	if api.quorum == nil {
+		return errNoQuorum
+	}
+	return api.quorum.Cancel(id)
+}
+
 // Other methods to be added, not yet implemented are:
 // - Ruleset interaction: add rules, attest rulefiles
 // - Store metadata about accounts, e.g. naming of accounts
@@ -0,0 +1,382 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/signer/core"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/ethereum/go-ethereum/signer/storage"
+	"golang.org/x/exp/slices"
+)
+
+// dayFormat is the format of the days the daily limits are accounted in.
+const dayFormat = "2006-01-02"
+
+// SelectorDB resolves 4byte method identifiers into method signatures. It is
+// implemented by the fourbyte database.
+type SelectorDB interface {
+	Selector(id []byte) (string, error)
+}
+
+// Decision is the outcome of evaluating a request against a policy.
+type Decision struct {
+	Rule   string `json:"rule,omitempty"`   // Name of the matched rule, empty if none matched
+	Action Action `json:"action"`           // Action to take on the request
+	Method string `json:"method,omitempty"` // Signature of the called method, if known
+	Reason string `json:"reason,omitempty"` // Explanation if the action differs from the rule's
+
+	rule *Rule
+}
+
+// Engine provides an implementation of UIClientAPI that decides requests with a
+// declarative policy, forwarding the ones requiring manual processing to the
+// next handler.
+type Engine struct {
+	next      core.UIClientAPI // The next handler, for manual processing
+	policy    *Policy
+	selectors SelectorDB      // Database to decode method selectors with, optional
+	storage   storage.Storage // Storage of the amounts spent under daily limits
+	audit     log.Logger      // Logger to record the decisions in
+	now       func() time.Time
+
+	lock sync.Mutex // Serializes the evaluation and accounting of daily limits
+}
+
+// NewEngine creates a policy engine deciding requests with the given policy.
+func NewEngine(next core.UIClientAPI, policy *Policy, selectors SelectorDB, store storage.Storage) *Engine {
+	return &Engine{
+		next:      next,
+		policy:    policy,
+		selectors: selectors,
+		storage:   store,
+		audit:     log.Root(),
+		now:       time.Now,
+	}
+}
+
+// SetAuditLogger sets the logger the decisions are recorded in.
+func (e *Engine) SetAuditLogger(logger log.Logger) {
+	e.audit = logger
+}
+
+// EvaluateTx evaluates a transaction signing request at the given time, without
+// accounting its value in the daily limits.
+func (e *Engine) EvaluateTx(req *core.SignTxRequest, now time.Time) (*Decision, error) {
+	var (
+		tx    = &req.Transaction
+		from  = tx.From.Address()
+		value = tx.Value.ToInt()
+		day   = now.UTC().Format(dayFormat)
+	)
+	var data []byte
+	if tx.Input != nil {
+		data = *tx.Input
+	} else if tx.Data != nil {
+		data = *tx.Data
+	}
+	var id []byte
+	var method string
+	if len(data) >= 4 {
+		id = data[:4]
+		if e.selectors != nil {
+			method, _ = e.selectors.Selector(id)
+		}
+	}
+	for _, rule := range e.policy.Rules {
+		if !rule.applies(RequestTransaction, from, now) || len(rule.ContentTypes) > 0 || len(rule.Domains) > 0 {
+			continue
+		}
+		if rule.to != nil {
+			if tx.To == nil {
+				continue
+			}
+			if _, ok := rule.to[tx.To.Address()]; !ok {
+				continue
+			}
+		}
+		if rule.MaxValue != nil && value.Cmp(&rule.MaxValue.Int) > 0 {
+			continue
+		}
+		if rule.selectors != nil && !rule.matchMethod(id, method) {
+			continue
+		}
+		if rule.DailyLimit != nil {
+			spent, err := e.spent(rule, from, day)
+			if err != nil {
+				return nil, err
+			}
+			if spent.Add(spent, value).Cmp(&rule.DailyLimit.Int) > 0 {
+				continue
+			}
+		}
+		return newDecision(rule, method, req.Callinfo), nil
+	}
+	return &Decision{Action: e.policy.Default, Method: method}, nil
+}
+
+// EvaluateData evaluates a data signing request at the given time.
+func (e *Engine) EvaluateData(req *core.SignDataRequest, now time.Time) (*Decision, error) {
+	from := req.Address.Address()
+	for _, rule := range e.policy.Rules {
+		if !rule.applies(RequestData, from, now) || rule.txOnly() {
+			continue
+		}
+		if len(rule.ContentTypes) > 0 && !slices.Contains(rule.ContentTypes, req.ContentType) {
+			continue
+		}
+		if len(rule.Domains) > 0 && (req.TypedData == nil || !rule.matchDomain(&req.TypedData.Domain)) {
+			continue
+		}
+		return newDecision(rule, "", req.Callinfo), nil
+	}
+	return &Decision{Action: e.policy.Default}, nil
+}
+
+// EvaluateListing evaluates an account listing request at the given time. The
+// signers of an approving rule, if any, restrict the accounts listed.
+func (e *Engine) EvaluateListing(req *core.ListRequest, now time.Time) (*Decision, error) {
+	for _, rule := range e.policy.Rules {
+		if !rule.applies(RequestListing, common.Address{}, now) || rule.txOnly() || len(rule.ContentTypes) > 0 || len(rule.Domains) > 0 {
+			continue
+		}
+		return newDecision(rule, "", nil), nil
+	}
+	return &Decision{Action: e.policy.Default}, nil
+}
+
+// newDecision creates the decision of a matched rule. Requests carrying warnings
+// are never approved automatically.
+func newDecision(rule *Rule, method string, callinfo []apitypes.ValidationInfo) *Decision {
+	decision := &Decision{Rule: rule.Name, Action: rule.Action, Method: method, rule: rule}
+	if rule.Action == ActionApprove {
+		msgs := apitypes.ValidationMessages{Messages: callinfo}
+		if err := msgs.GetWarnings(); err != nil {
+			decision.Action = ActionManual
+			decision.Reason = err.Error()
+		}
+	}
+	return decision
+}
+
+// applies returns whether the request kind, signer and time match the rule. The
+// signer is not checked for listings.
+func (r *Rule) applies(kind RequestKind, from common.Address, now time.Time) bool {
+	if len(r.Requests) > 0 && !slices.Contains(r.Requests, kind) {
+		return false
+	}
+	if kind != RequestListing && r.from != nil {
+		if _, ok := r.from[from]; !ok {
+			return false
+		}
+	}
+	return r.Time == nil || r.Time.contains(now)
+}
+
+// txOnly returns whether the rule has conditions only applying to transactions.
+func (r *Rule) txOnly() bool {
+	return r.to != nil || r.MaxValue != nil || r.DailyLimit != nil || r.selectors != nil
+}
+
+// matchMethod returns whether the called method is allowed by the rule, either
+// by its identifier or by the name of its signature.
+func (r *Rule) matchMethod(id []byte, signature string) bool {
+	if len(id) < 4 {
+		return false
+	}
+	if _, ok := r.selectors[[4]byte(id)]; ok {
+		return true
+	}
+	if i := strings.IndexByte(signature, '('); i > 0 {
+		if _, ok := r.names[signature[:i]]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// matchDomain returns whether the EIP-712 domain is allowed by the rule.
+func (r *Rule) matchDomain(domain *apitypes.TypedDataDomain) bool {
+	for _, allowed := range r.Domains {
+		if allowed.Name != "" && allowed.Name != domain.Name {
+			continue
+		}
+		if allowed.Version != "" && allowed.Version != domain.Version {
+			continue
+		}
+		if allowed.ChainID != nil {
+			if domain.ChainId == nil || (*big.Int)(domain.ChainId).Cmp(new(big.Int).SetUint64(*allowed.ChainID)) != 0 {
+				continue
+			}
+		}
+		if allowed.VerifyingContract != nil {
+			if !common.IsHexAddress(domain.VerifyingContract) || common.HexToAddress(domain.VerifyingContract) != *allowed.VerifyingContract {
+				continue
+			}
+		}
+		if allowed.Salt != "" && !strings.EqualFold(allowed.Salt, domain.Salt) {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// spent returns the value approved by the rule for the signer on the given day.
+func (e *Engine) spent(rule *Rule, from common.Address, day string) (*big.Int, error) {
+	val, err := e.storage.Get(spentKey(rule, from, day))
+	if errors.Is(err, storage.ErrNotFound) {
+		return new(big.Int), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load daily spend: %v", err)
+	}
+	spent, ok := new(big.Int).SetString(val, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid daily spend %q", val)
+	}
+	return spent, nil
+}
+
+// spentKey returns the storage key of the value approved by a rule for a signer
+// on the given day.
+func spentKey(rule *Rule, from common.Address, day string) string {
+	return fmt.Sprintf("policy/%s/%s/%s", rule.Name, from.Hex(), day)
+}
+
+func (e *Engine) ApproveTx(request *core.SignTxRequest) (core.SignTxResponse, error) {
+	e.lock.Lock()
+	now := e.now()
+	decision, err := e.EvaluateTx(request, now)
+	if err == nil && decision.Action == ActionApprove && decision.rule.DailyLimit != nil {
+		// Account the value at approval, as the signing outcome isn't known yet
+		var (
+			from  = request.Transaction.From.Address()
+			day   = now.UTC().Format(dayFormat)
+			spent *big.Int
+		)
+		if spent, err = e.spent(decision.rule, from, day); err == nil {
+			spent.Add(spent, request.Transaction.Value.ToInt())
+			e.storage.Put(spentKey(decision.rule, from, day), spent.String())
+		}
+	}
+	e.lock.Unlock()
+
+	if err != nil {
+		log.Info("Policy evaluation error, going to manual", "error", err)
+		return e.next.ApproveTx(request)
+	}
+	e.record("ApproveTx", request.Meta, decision, "from", request.Transaction.From.Address())
+	switch decision.Action {
+	case ActionApprove:
+		return core.SignTxResponse{Transaction: request.Transaction, Approved: true}, nil
+	case ActionReject:
+		return core.SignTxResponse{Approved: false}, nil
+	}
+	return e.next.ApproveTx(request)
+}
+
+func (e *Engine) ApproveSignData(request *core.SignDataRequest) (core.SignDataResponse, error) {
+	decision, err := e.EvaluateData(request, e.now())
+	if err != nil {
+		log.Info("Policy evaluation error, going to manual", "error", err)
+		return e.next.ApproveSignData(request)
+	}
+	e.record("ApproveSignData", request.Meta, decision, "from", request.Address.Address(), "content-type", request.ContentType)
+	switch decision.Action {
+	case ActionApprove:
+		return core.SignDataResponse{Approved: true}, nil
+	case ActionReject:
+		return core.SignDataResponse{Approved: false}, nil
+	}
+	return e.next.ApproveSignData(request)
+}
+
+func (e *Engine) ApproveListing(request *core.ListRequest) (core.ListResponse, error) {
+	decision, err := e.EvaluateListing(request, e.now())
+	if err != nil {
+		log.Info("Policy evaluation error, going to manual", "error", err)
+		return e.next.ApproveListing(request)
+	}
+	e.record("ApproveListing", request.Meta, decision)
+	switch decision.Action {
+	case ActionApprove:
+		listed := request.Accounts
+		if decision.rule.from != nil {
+			listed = nil
+			for _, account := range request.Accounts {
+				if _, ok := decision.rule.from[account.Address]; ok {
+					listed = append(listed, account)
+				}
+			}
+		}
+		return core.ListResponse{Accounts: listed}, nil
+	case ActionReject:
+		return core.ListResponse{}, nil
+	}
+	return e.next.ApproveListing(request)
+}
+
+// record logs a decision in the audit log.
+func (e *Engine) record(method string, meta core.Metadata, decision *Decision, ctx ...interface{}) {
+	ctx = append([]interface{}{"type", "policy", "metadata", meta.String(), "rule", decision.Rule, "action", decision.Action}, ctx...)
+	if decision.Method != "" {
+		ctx = append(ctx, "method", decision.Method)
+	}
+	if decision.Reason != "" {
+		ctx = append(ctx, "reason", decision.Reason)
+	}
+	e.audit.Info(method, ctx...)
+}
+
+func (e *Engine) ApproveNewAccount(request *core.NewAccountRequest) (core.NewAccountResponse, error) {
+	// This cannot be handled by policies, requires setting a password
+	return e.next.ApproveNewAccount(request)
+}
+
+func (e *Engine) ShowError(message string) {
+	e.next.ShowError(message)
+}
+
+func (e *Engine) ShowInfo(message string) {
+	e.next.ShowInfo(message)
+}
+
+func (e *Engine) OnApprovedTx(tx ethapi.SignTransactionResult) {
+	e.next.OnApprovedTx(tx)
+}
+
+func (e *Engine) OnSignerStartup(info core.StartupInfo) {
+	e.next.OnSignerStartup(info)
+}
+
+func (e *Engine) OnInputRequired(info core.UserInputRequest) (core.UserInputResponse, error) {
+	return e.next.OnInputRequired(info)
+}
+
+func (e *Engine) RegisterUIServer(api *core.UIServerAPI) {
+	e.next.RegisterUIServer(api)
+}
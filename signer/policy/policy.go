@@ -0,0 +1,348 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package policy implements a declarative alternative to the javascript rules of
+// clef, approving or rejecting requests based on a list of typed rules.
+//
+// A policy is a YAML (or JSON) document listing rules, which are evaluated in
+// order against every request. The first rule whose conditions all hold decides
+// the request, and requests not matched by any rule are handled by the default
+// action:
+//
+//	default: manual
+//	rules:
+//	  - name: payroll
+//	    action: approve
+//	    requests: [transaction]
+//	    from: [0x8a8eafb1cf62bfbeb1741769dae1a9dd47996192]
+//	    methods: ["transfer(address,uint256)"]
+//	    dailyLimit: 10 ether
+//	    time: {days: [mon, tue, wed, thu, fri], start: "09:00", end: "17:00", location: Europe/Berlin}
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"gopkg.in/yaml.v3"
+)
+
+// Action is the outcome of a policy rule.
+type Action string
+
+const (
+	ActionApprove Action = "approve" // Approve the request without user interaction
+	ActionReject  Action = "reject"  // Reject the request without user interaction
+	ActionManual  Action = "manual"  // Forward the request to the user
+)
+
+// RequestKind is a kind of request a policy rule applies to.
+type RequestKind string
+
+const (
+	RequestTransaction RequestKind = "transaction" // Transaction signing requests
+	RequestData        RequestKind = "data"        // Data, text and typed data signing requests
+	RequestListing     RequestKind = "listing"     // Account listing requests
+)
+
+// Policy is a declarative set of rules to decide requests with.
+type Policy struct {
+	Default Action  `yaml:"default"` // Action if no rule matches, manual if unset
+	Rules   []*Rule `yaml:"rules"`   // Rules in evaluation order
+}
+
+// Rule is a named set of conditions, all of which must hold for the rule's
+// action to be taken. Unset conditions always hold. Conditions which don't apply
+// to a kind of request (e.g. a recipient for data signing) never hold for it.
+type Rule struct {
+	Name     string        `yaml:"name"`
+	Action   Action        `yaml:"action"`
+	Requests []RequestKind `yaml:"requests"` // Kinds of requests the rule applies to, all if unset
+
+	From       []common.Address `yaml:"from"`       // Allowed signers, or the listed accounts for listings
+	To         []common.Address `yaml:"to"`         // Allowed transaction recipients
+	MaxValue   *Amount          `yaml:"maxValue"`   // Maximum value of a single transaction
+	DailyLimit *Amount          `yaml:"dailyLimit"` // Maximum value approved per signer and UTC day
+	Methods    []string         `yaml:"methods"`    // Allowed methods by selector, signature or name
+	Time       *TimeWindow      `yaml:"time"`       // Time window the rule applies in
+
+	ContentTypes []string `yaml:"contentTypes"` // Allowed content types of data signing
+	Domains      []Domain `yaml:"domains"`      // Allowed EIP-712 domains of typed data signing
+
+	from      map[common.Address]struct{}
+	to        map[common.Address]struct{}
+	selectors map[[4]byte]struct{}
+	names     map[string]struct{}
+}
+
+// Amount is an amount of wei, which can be specified in wei, gwei or ether.
+type Amount struct {
+	big.Int
+}
+
+// UnmarshalText parses an amount such as "1000", "20 gwei" or "1.5 ether".
+func (a *Amount) UnmarshalText(input []byte) error {
+	fields := strings.Fields(string(input))
+	if len(fields) == 0 || len(fields) > 2 {
+		return fmt.Errorf("invalid amount %q", input)
+	}
+	unit := big.NewInt(1)
+	if len(fields) == 2 {
+		switch strings.ToLower(fields[1]) {
+		case "wei":
+		case "gwei":
+			unit.SetUint64(params.GWei)
+		case "ether":
+			unit.SetUint64(params.Ether)
+		default:
+			return fmt.Errorf("invalid amount unit %q", fields[1])
+		}
+	}
+	value, ok := new(big.Rat).SetString(fields[0])
+	if !ok || value.Sign() < 0 {
+		return fmt.Errorf("invalid amount %q", input)
+	}
+	value.Mul(value, new(big.Rat).SetInt(unit))
+	if !value.IsInt() {
+		return fmt.Errorf("fractional wei amount %q", input)
+	}
+	a.Set(value.Num())
+	return nil
+}
+
+// TimeWindow is a daily time window, e.g. the business hours on weekdays.
+type TimeWindow struct {
+	Days     []string `yaml:"days"`     // Days of the week (mon, tue, ...), all if unset
+	Start    string   `yaml:"start"`    // Start of the window (HH:MM), inclusive
+	End      string   `yaml:"end"`      // End of the window (HH:MM), exclusive
+	Location string   `yaml:"location"` // IANA time zone of the window, UTC if unset
+
+	days       [7]bool
+	start, end int // Minutes since midnight
+	loc        *time.Location
+}
+
+// weekdays maps the day names of time windows to weekdays.
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// Domain is an EIP-712 domain allowed for typed data signing. Unset fields match
+// any value.
+type Domain struct {
+	Name              string          `yaml:"name"`
+	Version           string          `yaml:"version"`
+	ChainID           *uint64         `yaml:"chainId"`
+	VerifyingContract *common.Address `yaml:"verifyingContract"`
+	Salt              string          `yaml:"salt"`
+}
+
+// Load reads and parses the policy stored in the given file.
+func Load(path string) (*Policy, error) {
+	blob, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(blob)
+}
+
+// Parse parses and validates a YAML or JSON encoded policy.
+func Parse(blob []byte) (*Policy, error) {
+	policy := new(Policy)
+	if err := yaml.Unmarshal(blob, policy); err != nil {
+		return nil, err
+	}
+	if err := policy.init(); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// init validates the policy and prepares its rules for evaluation.
+func (p *Policy) init() error {
+	switch p.Default {
+	case "":
+		p.Default = ActionManual
+	case ActionApprove:
+		return errors.New("default action can't be approve")
+	case ActionReject, ActionManual:
+	default:
+		return fmt.Errorf("invalid default action %q", p.Default)
+	}
+	names := make(map[string]struct{})
+	for i, rule := range p.Rules {
+		if rule == nil {
+			return fmt.Errorf("rule %d: empty rule", i)
+		}
+		if rule.Name == "" {
+			return fmt.Errorf("rule %d: missing name", i)
+		}
+		if _, ok := names[rule.Name]; ok {
+			return fmt.Errorf("rule %d: duplicate name %q", i, rule.Name)
+		}
+		names[rule.Name] = struct{}{}
+
+		if err := rule.init(); err != nil {
+			return fmt.Errorf("rule %q: %v", rule.Name, err)
+		}
+	}
+	return nil
+}
+
+// init validates the rule and prepares its conditions for evaluation.
+func (r *Rule) init() error {
+	switch r.Action {
+	case ActionApprove, ActionReject, ActionManual:
+	default:
+		return fmt.Errorf("invalid action %q", r.Action)
+	}
+	for _, kind := range r.Requests {
+		switch kind {
+		case RequestTransaction, RequestData, RequestListing:
+		default:
+			return fmt.Errorf("invalid request kind %q", kind)
+		}
+	}
+	if r.DailyLimit != nil && r.Action != ActionApprove {
+		return errors.New("daily limit requires approve action")
+	}
+	if len(r.From) > 0 {
+		r.from = make(map[common.Address]struct{})
+		for _, addr := range r.From {
+			r.from[addr] = struct{}{}
+		}
+	}
+	if len(r.To) > 0 {
+		r.to = make(map[common.Address]struct{})
+		for _, addr := range r.To {
+			r.to[addr] = struct{}{}
+		}
+	}
+	if len(r.Methods) > 0 {
+		r.selectors = make(map[[4]byte]struct{})
+		r.names = make(map[string]struct{})
+	}
+	for _, method := range r.Methods {
+		switch {
+		case strings.HasPrefix(method, "0x"):
+			id, err := hexutil.Decode(method)
+			if err != nil || len(id) != 4 {
+				return fmt.Errorf("invalid method selector %q", method)
+			}
+			r.selectors[[4]byte(id)] = struct{}{}
+
+		case strings.Contains(method, "("):
+			selector, err := abi.ParseSelector(method)
+			if err != nil {
+				return fmt.Errorf("invalid method signature %q: %v", method, err)
+			}
+			r.selectors[[4]byte(crypto.Keccak256([]byte(canonicalSignature(selector)))[:4])] = struct{}{}
+
+		default:
+			r.names[method] = struct{}{}
+		}
+	}
+	if r.Time != nil {
+		if err := r.Time.init(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// init validates the time window and parses its boundaries.
+func (w *TimeWindow) init() error {
+	if len(w.Days) == 0 {
+		w.days = [7]bool{true, true, true, true, true, true, true}
+	}
+	for _, day := range w.Days {
+		weekday, ok := weekdays[strings.ToLower(day)]
+		if !ok {
+			return fmt.Errorf("invalid day %q", day)
+		}
+		w.days[weekday] = true
+	}
+	var err error
+	if w.start, err = parseClock(w.Start, 0); err != nil {
+		return err
+	}
+	if w.end, err = parseClock(w.End, 24*60); err != nil {
+		return err
+	}
+	if w.loc, err = time.LoadLocation(w.Location); err != nil {
+		return fmt.Errorf("invalid location %q: %v", w.Location, err)
+	}
+	return nil
+}
+
+// contains returns whether the given time is inside the window. Windows ending
+// before they start span midnight, and belong to the day they start on.
+func (w *TimeWindow) contains(t time.Time) bool {
+	t = t.In(w.loc)
+	minute := t.Hour()*60 + t.Minute()
+	if w.start <= w.end {
+		return w.days[t.Weekday()] && minute >= w.start && minute < w.end
+	}
+	if minute >= w.start {
+		return w.days[t.Weekday()]
+	}
+	return minute < w.end && w.days[(t.Weekday()+6)%7]
+}
+
+// parseClock parses a HH:MM time of the day into minutes since midnight.
+func parseClock(clock string, fallback int) (int, error) {
+	if clock == "" {
+		return fallback, nil
+	}
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q", clock)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// canonicalSignature returns the canonical signature of a parsed method selector
+// used to compute its 4byte identifier.
+func canonicalSignature(selector abi.SelectorMarshaling) string {
+	args := make([]string, len(selector.Inputs))
+	for i, arg := range selector.Inputs {
+		args[i] = canonicalType(arg)
+	}
+	return fmt.Sprintf("%s(%s)", selector.Name, strings.Join(args, ","))
+}
+
+// canonicalType returns the canonical name of a parsed argument type, expanding
+// tuples into their components.
+func canonicalType(arg abi.ArgumentMarshaling) string {
+	if !strings.HasPrefix(arg.Type, "tuple") {
+		return arg.Type
+	}
+	components := make([]string, len(arg.Components))
+	for i, component := range arg.Components {
+		components[i] = canonicalType(component)
+	}
+	return "(" + strings.Join(components, ",") + ")" + strings.TrimPrefix(arg.Type, "tuple")
+}
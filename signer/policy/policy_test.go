@@ -0,0 +1,272 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package policy
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/signer/core"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/ethereum/go-ethereum/signer/storage"
+)
+
+var (
+	treasury = common.HexToAddress("0x8a8eafb1cf62bfbeb1741769dae1a9dd47996192")
+	token    = common.HexToAddress("0xd9145cce52d386f254917e481eb44e9943f39138")
+	exchange = common.HexToAddress("0x000000000022d473030f116ddee9f6b43ac78ba3")
+)
+
+const testPolicy = `
+default: reject
+rules:
+  - name: no-transactions-at-night
+    action: reject
+    requests: [transaction]
+    time: {start: "22:00", end: "06:00"}
+  - name: token-transfers
+    action: approve
+    from: [0x8a8eafb1cf62bfbeb1741769dae1a9dd47996192]
+    to: [0xd9145cce52d386f254917e481eb44e9943f39138]
+    methods: ["transfer(address,uint256)", approve]
+  - name: payments
+    action: approve
+    from: [0x8a8eafb1cf62bfbeb1741769dae1a9dd47996192]
+    maxValue: 1 ether
+    dailyLimit: 1.5 ether
+    time: {days: [mon, tue, wed, thu, fri], start: "09:00", end: "17:00", location: Europe/Berlin}
+  - name: permits
+    action: approve
+    requests: [data]
+    domains:
+      - {name: Permit2, chainId: 1, verifyingContract: 0x000000000022d473030f116ddee9f6b43ac78ba3}
+  - name: texts
+    action: manual
+    contentTypes: [text/plain]
+  - name: list-treasury
+    action: approve
+    requests: [listing]
+    from: [0x8a8eafb1cf62bfbeb1741769dae1a9dd47996192]
+`
+
+// manualUI is a UIClientAPI recording the requests forwarded to it and
+// approving them.
+type manualUI struct {
+	core.UIClientAPI
+	forwarded int
+}
+
+func (ui *manualUI) ApproveTx(request *core.SignTxRequest) (core.SignTxResponse, error) {
+	ui.forwarded++
+	return core.SignTxResponse{Transaction: request.Transaction, Approved: true}, nil
+}
+
+func (ui *manualUI) ApproveSignData(request *core.SignDataRequest) (core.SignDataResponse, error) {
+	ui.forwarded++
+	return core.SignDataResponse{Approved: true}, nil
+}
+
+// testSelectors is a selector database knowing the ERC-20 approve method.
+type testSelectors struct{}
+
+func (testSelectors) Selector(id []byte) (string, error) {
+	if hexutil.Encode(id) == "0x095ea7b3" {
+		return "approve(address,uint256)", nil
+	}
+	return "", nil
+}
+
+func newTestEngine(t *testing.T) (*Engine, *manualUI) {
+	policy, err := Parse([]byte(testPolicy))
+	if err != nil {
+		t.Fatalf("failed to parse policy: %v", err)
+	}
+	ui := new(manualUI)
+	return NewEngine(ui, policy, testSelectors{}, storage.NewEphemeralStorage()), ui
+}
+
+func txRequest(to *common.Address, value *big.Int, data string) *core.SignTxRequest {
+	req := &core.SignTxRequest{
+		Transaction: apitypes.SendTxArgs{
+			From:  common.NewMixedcaseAddress(treasury),
+			Value: hexutil.Big(*value),
+		},
+	}
+	if to != nil {
+		addr := common.NewMixedcaseAddress(*to)
+		req.Transaction.To = &addr
+	}
+	if data != "" {
+		input := hexutil.Bytes(common.FromHex(data))
+		req.Transaction.Input = &input
+	}
+	return req
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		policy string
+		err    string
+	}{
+		{`default: approve`, "default action can't be approve"},
+		{`rules: [{action: approve}]`, "rule 0: missing name"},
+		{`rules: [{name: a, action: approve}, {name: a, action: reject}]`, `rule 1: duplicate name "a"`},
+		{`rules: [{name: a, action: allow}]`, `rule "a": invalid action "allow"`},
+		{`rules: [{name: a, action: approve, requests: [sign]}]`, `rule "a": invalid request kind "sign"`},
+		{`rules: [{name: a, action: reject, dailyLimit: 1}]`, `rule "a": daily limit requires approve action`},
+		{`rules: [{name: a, action: approve, methods: ["0x1234"]}]`, `rule "a": invalid method selector "0x1234"`},
+		{`rules: [{name: a, action: approve, time: {days: [someday]}}]`, `rule "a": invalid day "someday"`},
+		{`rules: [{name: a, action: approve, time: {start: "25:00"}}]`, `rule "a": invalid time of day "25:00"`},
+		{`rules: [{name: a, action: approve, maxValue: 1.5 wei}]`, "fractional wei amount"},
+		{`{"rules": [{"name": "a", "action": "approve", "maxValue": "20 gwei"}]}`, ""},
+	}
+	for i, tt := range tests {
+		_, err := Parse([]byte(tt.policy))
+		if tt.err == "" {
+			if err != nil {
+				t.Errorf("test %d: unexpected error: %v", i, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("test %d: error mismatch: have %v, want %q", i, err, tt.err)
+		}
+	}
+}
+
+func TestTransactions(t *testing.T) {
+	engine, _ := newTestEngine(t)
+	var (
+		monday   = time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC) // 11:00 in Berlin
+		saturday = time.Date(2024, 3, 9, 10, 0, 0, 0, time.UTC)
+		night    = time.Date(2024, 3, 4, 23, 0, 0, 0, time.UTC)
+		ether    = big.NewInt(params.Ether)
+		transfer = "0xa9059cbb000000000000000000000000000000000000000000000000000000000000000100000000000000000000000000000000000000000000000000000000000000ff"
+	)
+	tests := []struct {
+		req    *core.SignTxRequest
+		now    time.Time
+		rule   string
+		action Action
+	}{
+		{txRequest(&token, new(big.Int), transfer), monday, "token-transfers", ActionApprove},
+		{txRequest(&token, new(big.Int), transfer), night, "no-transactions-at-night", ActionReject},
+		{txRequest(&token, new(big.Int), "0x095ea7b3"), saturday, "token-transfers", ActionApprove},
+		{txRequest(&token, new(big.Int), "0x23b872dd"), saturday, "", ActionReject},
+		{txRequest(&exchange, ether, ""), monday, "payments", ActionApprove},
+		{txRequest(&exchange, ether, ""), saturday, "", ActionReject},
+		{txRequest(&exchange, new(big.Int).Add(ether, common.Big1), ""), monday, "", ActionReject},
+		{txRequest(nil, new(big.Int), "0x6080"), monday, "payments", ActionApprove},
+	}
+	for i, tt := range tests {
+		decision, err := engine.EvaluateTx(tt.req, tt.now)
+		if err != nil {
+			t.Fatalf("test %d: evaluation failed: %v", i, err)
+		}
+		if decision.Rule != tt.rule || decision.Action != tt.action {
+			t.Errorf("test %d: decision mismatch: have %s/%s, want %s/%s", i, decision.Rule, decision.Action, tt.rule, tt.action)
+		}
+	}
+	// Requests carrying warnings are forwarded to the user
+	req := txRequest(&token, new(big.Int), transfer)
+	req.Callinfo = []apitypes.ValidationInfo{{Typ: apitypes.WARN, Message: "suspicious"}}
+	if decision, _ := engine.EvaluateTx(req, monday); decision.Action != ActionManual || decision.Rule != "token-transfers" {
+		t.Errorf("warning decision mismatch: have %s/%s, want token-transfers/manual", decision.Rule, decision.Action)
+	}
+}
+
+func TestDailyLimit(t *testing.T) {
+	engine, _ := newTestEngine(t)
+
+	now := time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC)
+	engine.now = func() time.Time { return now }
+
+	payment := txRequest(&exchange, new(big.Int).Mul(big.NewInt(6), big.NewInt(params.Ether/10)), "")
+	for i, approved := range []bool{true, true, false} {
+		res, err := engine.ApproveTx(payment)
+		if err != nil {
+			t.Fatalf("payment %d: approval failed: %v", i, err)
+		}
+		if res.Approved != approved {
+			t.Errorf("payment %d: approval mismatch: have %v, want %v", i, res.Approved, approved)
+		}
+	}
+	// The limit resets on the next day
+	now = now.Add(24 * time.Hour)
+	if res, _ := engine.ApproveTx(payment); !res.Approved {
+		t.Error("payment rejected on the next day")
+	}
+}
+
+func TestSignData(t *testing.T) {
+	engine, ui := newTestEngine(t)
+
+	permit := func(chainID int64, contract common.Address) *core.SignDataRequest {
+		return &core.SignDataRequest{
+			ContentType: apitypes.DataTyped.Mime,
+			Address:     common.NewMixedcaseAddress(treasury),
+			TypedData: &apitypes.TypedData{Domain: apitypes.TypedDataDomain{
+				Name:              "Permit2",
+				ChainId:           math.NewHexOrDecimal256(chainID),
+				VerifyingContract: contract.Hex(),
+			}},
+		}
+	}
+	tests := []struct {
+		req      *core.SignDataRequest
+		approved bool
+		manual   bool
+	}{
+		{permit(1, exchange), true, false},
+		{permit(5, exchange), false, false},
+		{permit(1, token), false, false},
+		{&core.SignDataRequest{ContentType: accounts.MimetypeTextPlain, Address: common.NewMixedcaseAddress(token)}, true, true},
+	}
+	for i, tt := range tests {
+		forwarded := ui.forwarded
+		res, err := engine.ApproveSignData(tt.req)
+		if err != nil {
+			t.Fatalf("test %d: approval failed: %v", i, err)
+		}
+		if res.Approved != tt.approved {
+			t.Errorf("test %d: approval mismatch: have %v, want %v", i, res.Approved, tt.approved)
+		}
+		if manual := ui.forwarded > forwarded; manual != tt.manual {
+			t.Errorf("test %d: forwarding mismatch: have %v, want %v", i, manual, tt.manual)
+		}
+	}
+}
+
+func TestListing(t *testing.T) {
+	engine, _ := newTestEngine(t)
+	engine.now = func() time.Time { return time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC) }
+
+	res, err := engine.ApproveListing(&core.ListRequest{Accounts: []accounts.Account{{Address: token}, {Address: treasury}}})
+	if err != nil {
+		t.Fatalf("listing failed: %v", err)
+	}
+	if len(res.Accounts) != 1 || res.Accounts[0].Address != treasury {
+		t.Errorf("listed accounts mismatch: %v", res.Accounts)
+	}
+}
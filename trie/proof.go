@@ -20,6 +20,7 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"slices"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethdb"
@@ -140,6 +141,127 @@ func VerifyProof(rootHash common.Hash, key []byte, proofDb ethdb.KeyValueReader)
 	}
 }
 
+// ProveMulti constructs a merkle proof for a set of keys. The result contains
+// all encoded nodes on the paths to the values at the keys, each of them only
+// once, even if it is shared by the paths of multiple keys. Like for Prove, the
+// absence of keys not contained in the trie is proven.
+func (t *Trie) ProveMulti(keys [][]byte, proofDb ethdb.KeyValueWriter) error {
+	// Short circuit if the trie is already committed and not usable.
+	if t.committed {
+		return ErrCommitted
+	}
+	// Sort and deduplicate the keys, so that each subtrie is visited once.
+	hexKeys := make([][]byte, 0, len(keys))
+	for _, key := range keys {
+		hexKeys = append(hexKeys, keybytesToHex(key))
+	}
+	slices.SortFunc(hexKeys, bytes.Compare)
+	hexKeys = slices.CompactFunc(hexKeys, bytes.Equal)
+
+	hasher := newHasher(false)
+	defer returnHasherToPool(hasher)
+
+	return t.proveMulti(t.root, nil, hexKeys, true, hasher, proofDb)
+}
+
+// proveMulti collects the nodes of the subtrie at the given path on the paths
+// to the given sorted keys, relative to the subtrie.
+func (t *Trie) proveMulti(n node, prefix []byte, keys [][]byte, root bool, hasher *hasher, proofDb ethdb.KeyValueWriter) error {
+	if n, ok := n.(hashNode); ok {
+		// Retrieve the specified node from the underlying node reader,
+		// without tracking it, see Prove.
+		blob, err := t.reader.node(prefix, common.BytesToHash(n))
+		if err != nil {
+			log.Error("Unhandled trie error in Trie.ProveMulti", "err", err)
+			return err
+		}
+		return t.proveMulti(mustDecodeNodeUnsafe(n, blob), prefix, keys, root, hasher, proofDb)
+	}
+	switch n := n.(type) {
+	case *shortNode, *fullNode:
+		// If the node's database encoding is a hash (or is the root node),
+		// it becomes a proof element.
+		collapsed, hn := hasher.proofHash(n)
+		if hash, ok := hn.(hashNode); ok || root {
+			enc := nodeToBytes(collapsed)
+			if !ok {
+				hash = hasher.hashData(enc)
+			}
+			proofDb.Put(hash, enc)
+		}
+	}
+	switch n := n.(type) {
+	case *shortNode:
+		// Descend with the keys going through the node, the node itself
+		// proves the absence of the others.
+		var matching [][]byte
+		for _, key := range keys {
+			if len(key) >= len(n.Key) && bytes.Equal(n.Key, key[:len(n.Key)]) {
+				matching = append(matching, key[len(n.Key):])
+			}
+		}
+		if len(matching) == 0 {
+			return nil
+		}
+		return t.proveMulti(n.Val, append(prefix, n.Key...), matching, false, hasher, proofDb)
+
+	case *fullNode:
+		// Descend into each child with the keys going through it, the keys
+		// being sorted by their first nibble.
+		for len(keys) > 0 {
+			if len(keys[0]) == 0 {
+				keys = keys[1:]
+				continue
+			}
+			var (
+				nibble = keys[0][0]
+				group  [][]byte
+			)
+			for len(keys) > 0 && len(keys[0]) > 0 && keys[0][0] == nibble {
+				group = append(group, keys[0][1:])
+				keys = keys[1:]
+			}
+			if child := n.Children[nibble]; child != nil {
+				childPrefix := append(append([]byte{}, prefix...), nibble)
+				if err := t.proveMulti(child, childPrefix, group, false, hasher, proofDb); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+
+	case valueNode, nil:
+		return nil
+
+	default:
+		panic(fmt.Sprintf("%T: invalid node: %v", n, n))
+	}
+}
+
+// ProveMulti constructs a merkle proof for a set of keys. The result contains
+// all encoded nodes on the paths to the values at the keys, each of them only
+// once, even if it is shared by the paths of multiple keys. Like for Prove, the
+// absence of keys not contained in the trie is proven.
+func (t *StateTrie) ProveMulti(keys [][]byte, proofDb ethdb.KeyValueWriter) error {
+	return t.trie.ProveMulti(keys, proofDb)
+}
+
+// VerifyMultiProof checks a merkle proof for a set of keys, as created by
+// ProveMulti. It returns the values of the keys in a trie with the given root
+// hash, nil for the absent ones, or an error if the proof contains invalid
+// trie nodes or misses any node on the path to one of the keys.
+func VerifyMultiProof(rootHash common.Hash, keys [][]byte, proofDb ethdb.KeyValueReader) ([][]byte, error) {
+	values := make([][]byte, len(keys))
+	for i, key := range keys {
+		value, err := VerifyProof(rootHash, key, proofDb)
+		if err != nil {
+			return nil, fmt.Errorf("key %x: %v", key, err)
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
 // proofToPath converts a merkle proof to trie node path. The main purpose of
 // this function is recovering a node path from the merkle proof stream. All
 // necessary nodes will be resolved and leave the remaining as hashnode.
@@ -26,8 +26,10 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/trie/trienode"
 	"golang.org/x/exp/slices"
 )
 
@@ -165,6 +167,83 @@ func TestMissingKeyProof(t *testing.T) {
 	}
 }
 
+// Tests that multiproofs prove existing and missing keys, and contain exactly
+// the union of the nodes of the individual proofs, both for in-memory and for
+// resolved tries.
+func TestMultiProof(t *testing.T) {
+	db := newTestDatabase(rawdb.NewMemoryDatabase(), rawdb.PathScheme)
+	trie, vals := randomTrie(500)
+	testMultiProof(t, trie, vals)
+
+	// Reopen the trie from the database, so that nodes need to be resolved
+	trie = NewEmpty(db)
+	for _, kv := range vals {
+		trie.MustUpdate(kv.k, kv.v)
+	}
+	root, nodes, _ := trie.Commit(false)
+	db.Update(root, types.EmptyRootHash, trienode.NewWithNodeSet(nodes))
+	trie, _ = New(TrieID(root), db)
+	testMultiProof(t, trie, vals)
+}
+
+func testMultiProof(t *testing.T, trie *Trie, vals map[string]*kv) {
+	var (
+		root = trie.Hash()
+		keys [][]byte
+		want [][]byte
+	)
+	for _, kv := range vals {
+		if len(keys) == 50 {
+			break
+		}
+		keys = append(keys, kv.k)
+		want = append(want, kv.v)
+	}
+	for i := 0; i < 10; i++ {
+		keys = append(keys, randBytes(32))
+		want = append(want, nil)
+	}
+	keys = append(keys, keys[0]) // Duplicate key
+	want = append(want, want[0])
+
+	proof := memorydb.New()
+	if err := trie.ProveMulti(keys, proof); err != nil {
+		t.Fatalf("failed to create multiproof: %v", err)
+	}
+	union := memorydb.New()
+	for _, key := range keys {
+		trie.Prove(key, union)
+	}
+	if proof.Len() != union.Len() {
+		t.Fatalf("multiproof size mismatch: have %d nodes, want %d", proof.Len(), union.Len())
+	}
+	it := union.NewIterator(nil, nil)
+	for it.Next() {
+		if ok, _ := proof.Has(it.Key()); !ok {
+			t.Fatalf("multiproof misses node %x", it.Key())
+		}
+	}
+	it.Release()
+
+	values, err := VerifyMultiProof(root, keys, proof)
+	if err != nil {
+		t.Fatalf("failed to verify multiproof: %v", err)
+	}
+	for i, value := range values {
+		if !bytes.Equal(value, want[i]) {
+			t.Fatalf("value mismatch for key %x: have %x, want %x", keys[i], value, want[i])
+		}
+	}
+	// Remove a node and check that verification fails
+	it = proof.NewIterator(nil, nil)
+	it.Next()
+	proof.Delete(it.Key())
+	it.Release()
+	if _, err := VerifyMultiProof(root, keys, proof); err == nil {
+		t.Fatal("incomplete multiproof verified")
+	}
+}
+
 // TestRangeProof tests normal range proof with both edge proofs
 // as the existent proof. The test cases are generated randomly.
 func TestRangeProof(t *testing.T) {
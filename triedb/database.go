@@ -231,6 +231,19 @@ func (db *Database) Cap(limit common.StorageSize) error {
 	return hdb.Cap(limit)
 }
 
+// SetFlushHook installs a hook invoked with the hash of every trie node about to
+// be flushed from memory to disk, or removes it if nil.
+//
+// It's only supported by hash-based database and will return an error for others.
+func (db *Database) SetFlushHook(hook func(hash common.Hash)) error {
+	hdb, ok := db.backend.(*hashdb.Database)
+	if !ok {
+		return errors.New("not supported")
+	}
+	hdb.SetFlushHook(hook)
+	return nil
+}
+
 // Reference adds a new reference from a parent node to a child node. This function
 // is used to add reference between internal trie node and external node(e.g. storage
 // trie root), all internal trie nodes are referenced together by database itself.
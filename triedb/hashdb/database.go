@@ -102,6 +102,8 @@ type Database struct {
 	dirtiesSize  common.StorageSize // Storage size of the dirty node cache (exc. metadata)
 	childrenSize common.StorageSize // Storage size of the external children tracking
 
+	onFlush func(hash common.Hash) // Hook invoked for each node before being flushed to disk
+
 	lock sync.RWMutex
 }
 
@@ -350,6 +352,9 @@ func (db *Database) Cap(limit common.StorageSize) error {
 	for size > limit && oldest != (common.Hash{}) {
 		// Fetch the oldest referenced node and push into the batch
 		node := db.dirties[oldest]
+		if db.onFlush != nil {
+			db.onFlush(oldest)
+		}
 		rawdb.WriteLegacyTrieNode(batch, oldest, node.node)
 
 		// If we exceeded the ideal batch size, commit and reset
@@ -402,6 +407,16 @@ func (db *Database) Cap(limit common.StorageSize) error {
 	return nil
 }
 
+// SetFlushHook installs a hook invoked with the hash of every node about to be
+// flushed to disk, or removes it if nil. The hook is invoked before the node is
+// written out, with the database lock held.
+func (db *Database) SetFlushHook(hook func(hash common.Hash)) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	db.onFlush = hook
+}
+
 // Commit iterates over all the children of a particular node, writes them out
 // to disk, forcefully tearing down all references in both directions. As a side
 // effect, all pre-images accumulated up to this point are also written.
@@ -472,6 +487,9 @@ func (db *Database) commit(hash common.Hash, batch ethdb.Batch, uncacher *cleane
 	if err != nil {
 		return err
 	}
+	if db.onFlush != nil {
+		db.onFlush(hash)
+	}
 	// If we've reached an optimal batch size, commit and start over
 	rawdb.WriteLegacyTrieNode(batch, hash, node.node)
 	if batch.ValueSize() >= ethdb.IdealBatchSize {